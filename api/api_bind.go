@@ -641,6 +641,8 @@ func Bind(e *echo.Echo, _myDice *dice.DiceManager) {
 	e.POST(prefix+"/configs/custom_reply/file_upload", customReplyFileUpload)
 	e.GET(prefix+"/configs/custom_reply/debug_mode", customReplyDebugModeGet)
 	e.POST(prefix+"/configs/custom_reply/debug_mode", customReplyDebugModeSet)
+	e.POST(prefix+"/configs/custom_reply/simulate", customReplySimulate)
+	e.GET(prefix+"/configs/custom_reply/schema", customReplySchema)
 
 	e.GET(prefix+"/dice/config/get", DiceConfig)
 	e.POST(prefix+"/dice/config/set", DiceConfigSet)
@@ -670,10 +672,25 @@ func Bind(e *echo.Echo, _myDice *dice.DiceManager) {
 	e.POST(prefix+"/backup/delete", backupDelete)
 	e.POST(prefix+"/backup/batch_delete", backupBatchDelete)
 
+	// 供 Prometheus 等抓取，使用约定俗成的路径
+	e.GET("/metrics", metricsGet)
+	e.GET(prefix+"/metrics", metricsGet)
+
+	e.GET(prefix+"/cluster/status", clusterStatus)
+	e.POST(prefix+"/cluster/config_set", clusterConfigSet)
+
 	e.GET(prefix+"/group/list", groupList)
 	e.POST(prefix+"/group/set_one", groupSetOne)
 	e.POST(prefix+"/group/quit_one", groupQuit)
 
+	e.GET(prefix+"/schedule/list", scheduleList)
+	e.POST(prefix+"/schedule/delete", scheduleDelete)
+
+	e.GET(prefix+"/stat/get", rollStatGet)
+
+	e.GET(prefix+"/ratelimit/policy_list", rateLimitPolicyList)
+	e.POST(prefix+"/ratelimit/policy_set", rateLimitPolicySet)
+
 	e.GET(prefix+"/banconfig/list", banMapList)
 	e.GET(prefix+"/banconfig/get", banConfigGet)
 	e.POST(prefix+"/banconfig/set", banConfigSet)
@@ -683,6 +700,14 @@ func Bind(e *echo.Echo, _myDice *dice.DiceManager) {
 	// e.POST(prefix+"/banconfig/map_set", banMapSet)
 	e.GET(prefix+"/banconfig/export", banExport)
 	e.POST(prefix+"/banconfig/import", banImport)
+	e.GET(prefix+"/banconfig/federation/get", banFederationGet)
+	e.POST(prefix+"/banconfig/federation/set", banFederationSet)
+	e.POST(prefix+"/banconfig/federation/sync", banFederationSync)
+	e.GET(prefix+"/banconfig/federation/entries", banFederationEntries)
+	e.GET(prefix+"/banconfig/appeal/list", banAppealList)
+	e.GET(prefix+"/banconfig/appeal/history", banAppealHistory)
+	e.POST(prefix+"/banconfig/appeal/handle", banAppealHandle)
+	e.GET(prefix+"/ban/feed", banFeed)
 
 	e.GET(prefix+"/deck/list", deckList)
 	e.POST(prefix+"/deck/reload", deckReload)
@@ -727,12 +752,27 @@ func Bind(e *echo.Echo, _myDice *dice.DiceManager) {
 	e.GET(prefix+"/story/logs/page", storyGetLogPage)
 	e.GET(prefix+"/story/items", storyGetItems)
 	e.GET(prefix+"/story/items/page", storyGetItemPage)
+	e.POST(prefix+"/story/items/hide", storyHideItems)
+	e.POST(prefix+"/story/items/rename", storyRenameItems)
+	e.POST(prefix+"/story/items/narration", storyInsertNarration)
+	e.POST(prefix+"/story/items/merge", storyMergeItems)
+	e.POST(prefix+"/story/items/move", storyMoveItem)
+	e.POST(prefix+"/story/items/undo", storyUndoItemsEdit)
+	e.GET(prefix+"/story/items/journal", storyGetItemsJournal)
+	e.GET(prefix+"/story/log", storyExportLog)
+	e.GET(prefix+"/story/share/:token", storyShareView)
 	e.DELETE(prefix+"/story/log", storyDelLog)
 	e.POST(prefix+"/story/uploadLog", storyUploadLog)
 	e.GET(prefix+"/story/backup/list", storyGetLogBackupList)
 	e.GET(prefix+"/story/backup/download", storyDownloadLogBackup)
 	e.POST(prefix+"/story/backup/batch_delete", storyBatchDeleteLogBackup)
 
+	e.POST(prefix+"/character/import/preview", characterImportPreview)
+	e.POST(prefix+"/character/import", characterImport)
+	e.GET(prefix+"/character/export", characterExport)
+	e.POST(prefix+"/character/snapshot/load", characterLoadSnapshot)
+	e.GET(prefix+"/character/journal", characterJournal)
+
 	e.POST(prefix+"/tool/onebot", onebotTool)
 	e.GET(prefix+"/utils/ga/:uid", getGithubAvatar)
 	e.GET(prefix+"/utils/news", getNews)
@@ -753,6 +793,8 @@ func Bind(e *echo.Echo, _myDice *dice.DiceManager) {
 	e.GET(prefix+"/censor/files/template/toml", censorGetTomlFileTemplate)
 	e.GET(prefix+"/censor/files/template/txt", censorGetTxtFileTemplate)
 	e.GET(prefix+"/censor/logs/page", censorGetLogPage)
+	e.GET(prefix+"/censor/group", censorGetGroupLevels)
+	e.POST(prefix+"/censor/group", censorSetGroupLevel)
 
 	e.GET(prefix+"/resource/page", resourceGetList)
 	e.GET(prefix+"/resource/download", resourceDownload)
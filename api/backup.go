@@ -175,6 +175,9 @@ type backupConfig struct {
 	BackupCleanKeepDur   string `json:"backupCleanKeepDur"`
 	BackupCleanTrigger   int    `json:"backupCleanTrigger"`
 	BackupCleanCron      string `json:"backupCleanCron"`
+
+	AttrsJournalKeepCount int    `json:"attrsJournalKeepCount"`
+	AttrsJournalKeepDur   string `json:"attrsJournalKeepDur"`
 }
 
 func backupConfigGet(c echo.Context) error {
@@ -187,6 +190,8 @@ func backupConfigGet(c echo.Context) error {
 	bc.BackupCleanKeepDur = dm.BackupCleanKeepDur.String()
 	bc.BackupCleanTrigger = int(dm.BackupCleanTrigger)
 	bc.BackupCleanCron = dm.BackupCleanCron
+	bc.AttrsJournalKeepCount = dm.AttrsJournalKeepCount
+	bc.AttrsJournalKeepDur = dm.AttrsJournalKeepDur.String()
 	return c.JSON(http.StatusOK, bc)
 }
 
@@ -230,8 +235,20 @@ func backupConfigSave(c echo.Context) error {
 		}
 	}
 
+	if v.AttrsJournalKeepCount != 0 {
+		dm.AttrsJournalKeepCount = v.AttrsJournalKeepCount
+	}
+	if len(v.AttrsJournalKeepDur) > 0 {
+		if dur, err := time.ParseDuration(v.AttrsJournalKeepDur); err == nil {
+			dm.AttrsJournalKeepDur = dur
+		} else {
+			myDice.Logger.Errorf("设定的属性变更记录保留时间有误: %q %v", v.AttrsJournalKeepDur, err)
+		}
+	}
+
 	dm.ResetAutoBackup()
 	dm.ResetBackupClean()
+	dm.ResetAttrsJournalClean()
 	dm.Save()
 	return c.String(http.StatusOK, "")
 }
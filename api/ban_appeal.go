@@ -0,0 +1,65 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+
+	"sealdice-core/dice/service"
+)
+
+// banAppealList 查看申诉列表，可按状态或ID筛选
+func banAppealList(c echo.Context) error {
+	if !doAuth(c) {
+		return c.JSON(http.StatusForbidden, nil)
+	}
+	items, err := service.BanAppealList(myDice.DBOperator, c.QueryParam("state"), c.QueryParam("userId"))
+	if err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	return Success(&c, Response{"items": items})
+}
+
+func banAppealHistory(c echo.Context) error {
+	if !doAuth(c) {
+		return c.JSON(http.StatusForbidden, nil)
+	}
+	id, err := strconv.ParseUint(c.QueryParam("id"), 10, 64)
+	if err != nil {
+		return Error(&c, "无效的申诉编号", Response{})
+	}
+	appeal, err := service.BanAppealGet(myDice.DBOperator, id)
+	if err != nil || appeal == nil {
+		return Error(&c, "找不到该申诉", Response{})
+	}
+	items, err := service.BanAppealHistoryList(myDice.DBOperator, id)
+	if err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	return Success(&c, Response{"appeal": appeal, "items": items})
+}
+
+// banAppealHandle 通过或驳回申诉，通过时 reduce 不大于 0 则解除禁止
+func banAppealHandle(c echo.Context) error {
+	if !doAuth(c) {
+		return c.JSON(http.StatusForbidden, nil)
+	}
+	if dm.JustForTest {
+		return Success(&c, Response{"testMode": true})
+	}
+	var v struct {
+		ID     uint64 `json:"id"`
+		Accept bool   `json:"accept"`
+		Reduce int64  `json:"reduce"`
+		Note   string `json:"note"`
+	}
+	if err := c.Bind(&v); err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	appeal, err := myDice.BanAppealHandle(v.ID, v.Accept, v.Reduce, "网页端", v.Note)
+	if err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	return Success(&c, Response{"appeal": appeal})
+}
@@ -0,0 +1,110 @@
+package api
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	"sealdice-core/dice"
+	"sealdice-core/dice/service"
+)
+
+// banFeed 供其他骰子订阅的签名黑名单。需骰主开启，设置了口令时以 Authorization: Bearer <口令> 访问
+func banFeed(c echo.Context) error {
+	banList := myDice.Config.BanList
+	if !banList.FederationFeedEnable {
+		return c.JSON(http.StatusNotFound, nil)
+	}
+	if banList.FederationFeedToken != "" {
+		token, _ := strings.CutPrefix(c.Request().Header.Get(echo.HeaderAuthorization), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(banList.FederationFeedToken)) != 1 {
+			return c.JSON(http.StatusForbidden, nil)
+		}
+	}
+	feed, err := banList.FederationFeed()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, nil)
+	}
+	c.Response().Header().Set("Cache-Control", "no-store")
+	return c.JSON(http.StatusOK, feed)
+}
+
+func banFederationGet(c echo.Context) error {
+	if !doAuth(c) {
+		return c.JSON(http.StatusForbidden, nil)
+	}
+	banList := myDice.Config.BanList
+	return Success(&c, Response{
+		"publicKey":   banList.FederationPublicKey(),
+		"feedEnable":  banList.FederationFeedEnable,
+		"feedToken":   banList.FederationFeedToken,
+		"syncMinutes": banList.FederationSyncMinutes,
+		"sources":     banList.FederationSourcesCopy(),
+	})
+}
+
+func banFederationSet(c echo.Context) error {
+	if !doAuth(c) {
+		return c.JSON(http.StatusForbidden, nil)
+	}
+	if dm.JustForTest {
+		return Success(&c, Response{"testMode": true})
+	}
+	var v struct {
+		FeedEnable  bool                        `json:"feedEnable"`
+		FeedToken   string                      `json:"feedToken"`
+		SyncMinutes int64                       `json:"syncMinutes"`
+		Sources     []*dice.BanFederationSource `json:"sources"`
+	}
+	if err := c.Bind(&v); err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	banList := myDice.Config.BanList
+	if err := banList.SetFederationSources(v.Sources); err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	banList.FederationFeedEnable = v.FeedEnable
+	banList.FederationFeedToken = v.FeedToken
+	if v.SyncMinutes >= 0 {
+		banList.FederationSyncMinutes = v.SyncMinutes
+	}
+	myDice.MarkModified()
+	return Success(&c, Response{"sources": banList.FederationSourcesCopy()})
+}
+
+// banFederationSync 立即同步指定来源，name 为空时同步全部启用的来源
+func banFederationSync(c echo.Context) error {
+	if !doAuth(c) {
+		return c.JSON(http.StatusForbidden, nil)
+	}
+	if dm.JustForTest {
+		return Success(&c, Response{"testMode": true})
+	}
+	var v struct {
+		Name string `json:"name"`
+	}
+	if err := c.Bind(&v); err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	banList := myDice.Config.BanList
+	if v.Name == "" {
+		banList.FederationSyncAll()
+	} else if err := banList.FederationSync(v.Name); err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	return Success(&c, Response{"sources": banList.FederationSourcesCopy()})
+}
+
+// banFederationEntries 查看联合黑名单来源记录，可按来源或ID筛选
+func banFederationEntries(c echo.Context) error {
+	if !doAuth(c) {
+		return c.JSON(http.StatusForbidden, nil)
+	}
+	items, err := service.BanFederationEntryList(myDice.DBOperator, c.QueryParam("source"), c.QueryParam("userId"))
+	if err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	return Success(&c, Response{"items": items})
+}
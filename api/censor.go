@@ -356,8 +356,16 @@ func censorGetWords(c echo.Context) error {
 		data = append(data, word)
 	}
 	sort.Sort(data)
+
+	regex := make(SensitiveWords, 0, len(myDice.CensorManager.Censor.RegexRules))
+	for pattern, level := range myDice.CensorManager.Censor.RegexRules {
+		regex = append(regex, &SensitiveWord{Main: pattern, Level: level})
+	}
+	sort.Sort(regex)
 	return Success(&c, Response{
-		"data": data,
+		"data":  data,
+		"regex": regex,
+		"allow": myDice.CensorManager.Censor.AllowWords,
 	})
 }
 
@@ -370,8 +378,10 @@ func censorGetWordFiles(c echo.Context) error {
 	files := myDice.CensorManager.SensitiveWordsFiles
 
 	type file struct {
-		Key   string              `json:"key"`
-		Count *censor.FileCounter `json:"count"`
+		Key        string              `json:"key"`
+		Count      *censor.FileCounter `json:"count"`
+		RegexCount int                 `json:"regexCount"`
+		AllowCount int                 `json:"allowCount"`
 
 		FileType string `json:"fileType"`
 		Name     string `json:"name"`
@@ -383,14 +393,16 @@ func censorGetWordFiles(c echo.Context) error {
 	var res []file
 	for _, f := range files {
 		res = append(res, file{
-			Key:      f.Key,
-			Count:    f.FileCounter,
-			FileType: f.FileType,
-			Name:     f.Name,
-			Author:   strings.Join(f.Authors, " / "),
-			Version:  f.Version,
-			Desc:     f.Desc,
-			License:  f.License,
+			Key:        f.Key,
+			Count:      f.FileCounter,
+			RegexCount: f.RegexCount,
+			AllowCount: f.AllowCount,
+			FileType:   f.FileType,
+			Name:       f.Name,
+			Author:     strings.Join(f.Authors, " / "),
+			Version:    f.Version,
+			Desc:       f.Desc,
+			License:    f.License,
 		})
 	}
 
@@ -472,6 +484,12 @@ func censorGetTomlFileTemplate(c echo.Context) error {
 			Warning: []string{"警告级词汇1", "警告级词汇2"},
 			Danger:  []string{"危险级词汇1", "危险级词汇2"},
 		},
+		Regex: censor.TomlRegex{
+			Warning: []string{`加群\d{5,}`},
+		},
+		Allow: map[string][]string{
+			"提醒级词汇1": {"包含提醒级词汇1但不应拦截的短语"},
+		},
 	}
 	temp, _ := os.CreateTemp("", "词库模板-*.toml")
 	writer := bufio.NewWriter(temp)
@@ -541,3 +559,51 @@ func censorGetLogPage(c echo.Context) error {
 		"pageSize": len(page),
 	})
 }
+
+// censorGetGroupLevels 列出单独设置了起效级别的群
+func censorGetGroupLevels(c echo.Context) error {
+	if !doAuth(c) {
+		return c.NoContent(http.StatusForbidden)
+	}
+	type item struct {
+		GroupID   string       `json:"groupId"`
+		GroupName string       `json:"groupName"`
+		Level     censor.Level `json:"level"`
+	}
+	items := []item{}
+	myDice.ImSession.ServiceAtNew.Range(func(key string, group *dice.GroupInfo) bool {
+		if group != nil && group.CensorLevel > censor.Ignore {
+			items = append(items, item{GroupID: group.GroupID, GroupName: group.GroupName, Level: group.CensorLevel})
+		}
+		return true
+	})
+	sort.Slice(items, func(i, j int) bool { return items[i].GroupID < items[j].GroupID })
+	return Success(&c, Response{"data": items})
+}
+
+// censorSetGroupLevel 设置群内拦截起效级别，level 为 0 时恢复默认。该设置只能放宽本群的拦截，不能加严
+func censorSetGroupLevel(c echo.Context) error {
+	if !doAuth(c) {
+		return c.NoContent(http.StatusForbidden)
+	}
+	if dm.JustForTest {
+		return Error(&c, "展示模式不支持该操作", Response{"testMode": true})
+	}
+	v := struct {
+		GroupID string       `json:"groupId"`
+		Level   censor.Level `json:"level"`
+	}{}
+	if err := c.Bind(&v); err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	if v.Level < censor.Ignore || v.Level > censor.Danger {
+		return Error(&c, "无效的级别", Response{})
+	}
+	group, ok := myDice.ImSession.ServiceAtNew.Load(v.GroupID)
+	if !ok {
+		return Error(&c, "找不到该群", Response{})
+	}
+	group.CensorLevel = v.Level
+	group.MarkDirty(myDice)
+	return Success(&c, Response{})
+}
@@ -0,0 +1,207 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+
+	"sealdice-core/dice"
+	"sealdice-core/dice/service"
+)
+
+type charImportRequest struct {
+	System  string `json:"system"`
+	Format  string `json:"format"`
+	Data    string `json:"data"`
+	UserID  string `json:"userId"`
+	Name    string `json:"name"`
+	GroupID string `json:"groupId"` // 可选，填写时导入后在该群绑卡
+}
+
+// loadTemplate 取得规则模板，未指定规则时使用群组当前规则
+func (v *charImportRequest) loadTemplate() (*dice.GameSystemTemplate, error) {
+	system := v.System
+	if system == "" && v.GroupID != "" {
+		if group, ok := myDice.ImSession.ServiceAtNew.Load(v.GroupID); ok {
+			system = group.System
+		}
+	}
+	tmpl, ok := myDice.GameSystemMap.Load(system)
+	if !ok {
+		return nil, errors.New("规则模板不存在")
+	}
+	return tmpl, nil
+}
+
+// characterImportPreview 预览导入结果，返回已映射的属性和未能识别的字段，不写入数据
+func characterImportPreview(c echo.Context) error {
+	if !doAuth(c) {
+		return c.JSON(http.StatusForbidden, nil)
+	}
+	var v charImportRequest
+	if err := c.Bind(&v); err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	tmpl, err := v.loadTemplate()
+	if err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	ret, err := dice.CharImportPreview(tmpl, []byte(v.Data), v.Format)
+	if err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	return Success(&c, Response{"result": ret})
+}
+
+func characterImport(c echo.Context) error {
+	if !doAuth(c) {
+		return c.JSON(http.StatusForbidden, nil)
+	}
+	if dm.JustForTest {
+		return Success(&c, Response{"testMode": true})
+	}
+	var v charImportRequest
+	if err := c.Bind(&v); err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	if v.UserID == "" {
+		return Error(&c, "未指定用户", Response{})
+	}
+	tmpl, err := v.loadTemplate()
+	if err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	ret, err := dice.CharImportPreview(tmpl, []byte(v.Data), v.Format)
+	if err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	item, err := dice.CharImportCommit(myDice, v.UserID, tmpl, ret, v.Name)
+	if err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	if v.GroupID != "" {
+		if err = myDice.AttrsManager.CharBind(item.Id, v.GroupID, v.UserID); err != nil {
+			return Error(&c, err.Error(), Response{})
+		}
+	}
+	return Success(&c, Response{"id": item.Id, "name": item.Name, "result": ret})
+}
+
+// characterIDFromQuery 按 id、userId+name(角色卡) 或 groupId+userId(群内当前卡) 取得角色ID
+func characterIDFromQuery(c echo.Context) (string, error) {
+	if id := c.QueryParam("id"); id != "" {
+		return id, nil
+	}
+	userID, name, groupID := c.QueryParam("userId"), c.QueryParam("name"), c.QueryParam("groupId")
+	switch {
+	case userID != "" && name != "":
+		id, _ := myDice.AttrsManager.CharIdGetByName(userID, name)
+		if id == "" {
+			return "", errors.New("角色不存在")
+		}
+		return id, nil
+	case userID != "" && groupID != "":
+		attrs, err := myDice.AttrsManager.Load(groupID, userID)
+		if err != nil {
+			return "", err
+		}
+		return attrs.ID, nil
+	default:
+		return "", errors.New("未指定角色")
+	}
+}
+
+// characterExport 导出角色快照
+func characterExport(c echo.Context) error {
+	if !doAuth(c) {
+		return c.JSON(http.StatusForbidden, nil)
+	}
+	id, err := characterIDFromQuery(c)
+	if err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	attrs, err := myDice.AttrsManager.LoadById(id)
+	if err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+
+	snap := dice.CharSnapshotExport(attrs)
+	if c.QueryParam("format") != "yaml" {
+		return Success(&c, Response{"snapshot": snap})
+	}
+	data, err := snap.Marshal("yaml")
+	if err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	return c.Blob(http.StatusOK, "application/yaml; charset=utf-8", data)
+}
+
+func characterLoadSnapshot(c echo.Context) error {
+	if !doAuth(c) {
+		return c.JSON(http.StatusForbidden, nil)
+	}
+	if dm.JustForTest {
+		return Success(&c, Response{"testMode": true})
+	}
+	v := struct {
+		UserID    string `json:"userId"`
+		Data      string `json:"data"`
+		Name      string `json:"name"`
+		Overwrite bool   `json:"overwrite"`
+		GroupID   string `json:"groupId"` // 可选，填写时恢复后在该群绑卡
+	}{}
+	if err := c.Bind(&v); err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	if v.UserID == "" {
+		return Error(&c, "未指定用户", Response{})
+	}
+	snap, err := dice.ParseCharSnapshot([]byte(v.Data))
+	if err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	item, err := dice.CharSnapshotRestore(myDice, v.UserID, snap, v.Name, v.Overwrite)
+	if err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	if v.GroupID != "" {
+		if err = myDice.AttrsManager.CharBind(item.Id, v.GroupID, v.UserID); err != nil {
+			return Error(&c, err.Error(), Response{})
+		}
+	}
+	return Success(&c, Response{"id": item.Id, "name": item.Name})
+}
+
+// characterJournal 分页查看角色的属性变更记录，新的在前
+func characterJournal(c echo.Context) error {
+	if !doAuth(c) {
+		return c.JSON(http.StatusForbidden, nil)
+	}
+	id, err := characterIDFromQuery(c)
+	if err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	pageSize, _ := strconv.Atoi(c.QueryParam("pageSize"))
+	if pageSize <= 0 || pageSize > 200 {
+		pageSize = 20
+	}
+	pageNum, _ := strconv.Atoi(c.QueryParam("pageNum"))
+	if pageNum <= 0 {
+		pageNum = 1
+	}
+
+	if err = myDice.AttrsManager.JournalFlush(nil); err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	entries, total, err := service.AttrsJournalList(myDice.DBOperator, id, pageSize, (pageNum-1)*pageSize)
+	if err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	items := make([]Response, 0, len(entries))
+	for _, e := range entries {
+		items = append(items, Response{"entry": e, "text": dice.FormatAttrsJournal(e)})
+	}
+	return Success(&c, Response{"data": items, "total": total, "pageNum": pageNum, "pageSize": pageSize})
+}
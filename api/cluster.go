@@ -0,0 +1,45 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"sealdice-core/dice"
+)
+
+// clusterStatus 集群配置与各节点状态，未开启集群模式时 status 为空
+func clusterStatus(c echo.Context) error {
+	if !doAuth(c) {
+		return c.JSON(http.StatusForbidden, nil)
+	}
+	res := Response{"config": dm.ClusterConfig, "status": nil}
+	if dm.Cluster != nil {
+		status, err := dm.Cluster.Status()
+		if err != nil {
+			return Error(&c, err.Error(), Response{})
+		}
+		res["status"] = status
+	}
+	return Success(&c, res)
+}
+
+// clusterConfigSet 修改集群配置，重启后生效
+func clusterConfigSet(c echo.Context) error {
+	if !doAuth(c) {
+		return c.JSON(http.StatusForbidden, nil)
+	}
+	if dm.JustForTest {
+		return Success(&c, Response{"testMode": true})
+	}
+	var v dice.ClusterConfig
+	if err := c.Bind(&v); err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	if v.PollInterval < 0 || v.LeaseTTL < 0 {
+		return Error(&c, "间隔与租约时长不能为负数", Response{})
+	}
+	dm.ClusterConfig = v
+	dm.Save()
+	return Success(&c, Response{"needRestart": true})
+}
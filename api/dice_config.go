@@ -182,6 +182,18 @@ func DiceConfigSet(c echo.Context) error {
 		}
 	}
 
+	if val, ok := jsonMap["rngMode"]; ok {
+		if v, ok := val.(string); ok && (v == "" || dice.GetRandomProvider(v) != nil) {
+			config.RNGMode = v
+		}
+	}
+
+	if val, ok := jsonMap["scheduleMaxPerGroup"]; ok {
+		if v, ok := val.(float64); ok && v >= 1 {
+			config.ScheduleMaxPerGroup = int64(v)
+		}
+	}
+
 	if val, ok := jsonMap["cocCardMergeForward"]; ok {
 		if b, ok2 := val.(bool); ok2 {
 			config.CocCardMergeForward = b
@@ -381,6 +393,27 @@ func DiceConfigSet(c echo.Context) error {
 		}
 	}
 
+	if val, ok := jsonMap["logShareLocalPrefer"]; ok {
+		config.LogShareLocalPrefer = val.(bool)
+	}
+
+	if val, ok := jsonMap["logShareBaseUrl"]; ok {
+		config.LogShareBaseURL = strings.TrimRight(strings.TrimSpace(val.(string)), "/")
+	}
+
+	if val, ok := jsonMap["logShareExpireHours"]; ok {
+		hours, ok := val.(float64)
+		if !ok {
+			if v, ok := val.(string); ok {
+				hours, _ = strconv.ParseFloat(v, 64)
+			}
+		}
+		config.LogShareExpireHours = int(hours)
+		if config.LogShareExpireHours <= 0 {
+			config.LogShareExpireHours = dice.DefaultConfig.LogShareExpireHours
+		}
+	}
+
 	if val, ok := jsonMap["customReplyConfigEnable"]; ok {
 		config.CustomReplyConfigEnable = val.(bool)
 	}
@@ -3,6 +3,8 @@ package api
 import (
 	"mime/multipart"
 	"net/http"
+	"path/filepath"
+	"strings"
 
 	"github.com/labstack/echo/v4"
 
@@ -70,6 +72,7 @@ func helpDocUpload(c echo.Context) error {
 		return Error(&c, "不能为内置分组", Response{})
 	}
 	files := form.File["files"]
+	hasMarkdown := false
 	for _, file := range files {
 		var src multipart.File
 		src, err = file.Open()
@@ -84,9 +87,21 @@ func helpDocUpload(c echo.Context) error {
 		if err != nil {
 			return Error(&c, "上传文件 "+file.Filename+" 失败："+err.Error(), Response{})
 		}
+		if strings.EqualFold(filepath.Ext(file.Filename), ".md") {
+			hasMarkdown = true
+		}
 	}
 
-	return Success(&c, Response{"result": true})
+	resp := Response{"result": true}
+	if hasMarkdown {
+		// Markdown 帮助包返回校验报告(重复标题、失效链接等)
+		report, reportErr := dm.Help.ValidateHelpDocGroup(group)
+		if reportErr != nil {
+			return Error(&c, "校验 Markdown 帮助包失败："+reportErr.Error(), Response{})
+		}
+		resp["report"] = report
+	}
+	return Success(&c, resp)
 }
 
 func helpDocDelete(c echo.Context) error {
@@ -0,0 +1,30 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	"sealdice-core/utils/metrics"
+)
+
+// metricsGet 以 Prometheus 文本格式输出运行指标，Accept 中声明 OpenMetrics 时改用 OpenMetrics 格式。
+// 除 token 头与参数外，也接受抓取器常用的 Authorization: Bearer <token>
+func metricsGet(c echo.Context) error {
+	if !doAuth(c) {
+		token, ok := strings.CutPrefix(c.Request().Header.Get(echo.HeaderAuthorization), "Bearer ")
+		if !ok || !myDice.Parent.AccessTokens.Exists(token) {
+			return c.JSON(http.StatusForbidden, nil)
+		}
+	}
+
+	openMetrics := strings.Contains(c.Request().Header.Get(echo.HeaderAccept), "application/openmetrics-text")
+	contentType := metrics.ContentTypeText
+	if openMetrics {
+		contentType = metrics.ContentTypeOpenMetrics
+	}
+	c.Response().Header().Set(echo.HeaderContentType, contentType)
+	c.Response().WriteHeader(http.StatusOK)
+	return dm.WriteMetrics(c.Response(), openMetrics)
+}
@@ -1,10 +1,12 @@
 package api
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/labstack/echo/v4"
 
+	"sealdice-core/dice"
 	"sealdice-core/dice/sealpack"
 )
 
@@ -120,7 +122,7 @@ func packageInstallFromUpload(c echo.Context) error {
 // packagePreviewFromUpload 从请求体流式上传扩展包并返回包内容预览。
 // POST /package/preview-upload
 // 请求体: application/octet-stream 的 .sealpack 文件内容
-// 返回: { data: PackageUploadPreview, result: true }
+// 返回: { data: PackageUploadPreview, result: true }，其中 plan 为依赖的安装计划
 func packagePreviewFromUpload(c echo.Context) error {
 	if !doAuth(c) {
 		return c.JSON(http.StatusForbidden, "auth")
@@ -187,8 +189,10 @@ func packageInstallFromURL(c echo.Context) error {
 //   - "full": 完全删除（默认）
 //   - "keep_data": 保留用户数据
 //   - "disable": 仅禁用，不删除文件
+//   - cascade: bool - 以相同模式一并卸载依赖此包的扩展包
 //
-// 返回: { message: string, result: true }
+// 返回: { message: string, dependents?: []string, result: true }
+// 有已启用的扩展包依赖此包且未指定 cascade 时失败，并在 dependents 中列出这些包
 // 注意: 卸载后需要调用 js/reload 清理内存中的 JS 扩展
 func packageUninstall(c echo.Context) error {
 	if !doAuth(c) {
@@ -201,8 +205,9 @@ func packageUninstall(c echo.Context) error {
 	}
 
 	var params struct {
-		ID   string                 `json:"id"`
-		Mode sealpack.UninstallMode `json:"mode"` // full, keep_data, disable
+		ID      string                 `json:"id"`
+		Mode    sealpack.UninstallMode `json:"mode"`    // full, keep_data, disable
+		Cascade bool                   `json:"cascade"` // 一并卸载依赖此包的扩展包
 	}
 	err := c.Bind(&params)
 	if err != nil {
@@ -214,8 +219,25 @@ func packageUninstall(c echo.Context) error {
 		params.Mode = sealpack.UninstallModeFull
 	}
 
+	if params.Cascade {
+		dependents, cascadeErr := myDice.PackageManager.UninstallCascade(params.ID, params.Mode)
+		if cascadeErr != nil {
+			return Error(&c, cascadeErr.Error(), Response{})
+		}
+		return Success(&c, Response{
+			"message":    "扩展包卸载成功",
+			"dependents": dependents,
+		})
+	}
+
 	err = myDice.PackageManager.Uninstall(params.ID, params.Mode)
 	if err != nil {
+		var dependentsErr *dice.DependentsError
+		if errors.As(err, &dependentsErr) {
+			return Error(&c, err.Error(), Response{
+				"dependents": dependentsErr.Dependents,
+			})
+		}
 		return Error(&c, err.Error(), Response{})
 	}
 
@@ -263,7 +285,8 @@ func packageEnable(c echo.Context) error {
 
 // packageDisable 禁用扩展包
 // POST /package/disable
-// 参数: { id: string }
+// 参数: { id: string, cascade?: bool }
+// 有已启用的扩展包依赖此包时返回 dependents 列表，cascade 为 true 时一并禁用它们
 // 返回: { data: DisableResult, result: true }
 // 注意: 禁用后需要重载才能从内存中移除资源
 func packageDisable(c echo.Context) error {
@@ -277,15 +300,27 @@ func packageDisable(c echo.Context) error {
 	}
 
 	var params struct {
-		ID string `json:"id"`
+		ID      string `json:"id"`
+		Cascade bool   `json:"cascade"`
 	}
 	err := c.Bind(&params)
 	if err != nil {
 		return Error(&c, err.Error(), Response{})
 	}
 
-	result, err := myDice.PackageManager.Disable(params.ID)
+	var result *sealpack.OperationResult
+	if params.Cascade {
+		result, err = myDice.PackageManager.DisableCascade(params.ID)
+	} else {
+		result, err = myDice.PackageManager.Disable(params.ID)
+	}
 	if err != nil {
+		var dependentsErr *dice.DependentsError
+		if errors.As(err, &dependentsErr) {
+			return Error(&c, err.Error(), Response{
+				"dependents": dependentsErr.Dependents,
+			})
+		}
 		return Error(&c, err.Error(), Response{})
 	}
 
@@ -0,0 +1,62 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"sealdice-core/dice"
+)
+
+// rateLimitPolicyList 列出频率限制策略。configured 为骰主配置的策略，
+// effective 另含扩展声明的策略，其中 extName 不为空的项可以保存为同名策略进行覆盖
+func rateLimitPolicyList(c echo.Context) error {
+	if !doAuth(c) {
+		return c.JSON(http.StatusForbidden, nil)
+	}
+	configured := myDice.Config.RateLimitPolicies
+	if configured == nil {
+		configured = []*dice.RateLimitPolicy{}
+	}
+	return Success(&c, Response{
+		"configured": configured,
+		"effective":  myDice.RateLimitPolicies(),
+	})
+}
+
+// rateLimitPolicySet 整体替换骰主配置的策略
+func rateLimitPolicySet(c echo.Context) error {
+	if !doAuth(c) {
+		return c.JSON(http.StatusForbidden, nil)
+	}
+	if dm.JustForTest {
+		return Success(&c, Response{"testMode": true})
+	}
+	var v struct {
+		Policies []*dice.RateLimitPolicy `json:"policies"`
+	}
+	if err := c.Bind(&v); err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	names := map[string]bool{}
+	policies := make([]*dice.RateLimitPolicy, 0, len(v.Policies))
+	for _, p := range v.Policies {
+		if p == nil {
+			continue
+		}
+		if err := p.Parse(); err != nil {
+			return Error(&c, err.Error(), Response{})
+		}
+		if names[p.Name] {
+			return Error(&c, "策略名重复: "+p.Name, Response{})
+		}
+		names[p.Name] = true
+		p.ExtName = ""
+		policies = append(policies, p)
+	}
+
+	myDice.Config.RateLimitPolicies = policies
+	myDice.MarkModified()
+	myDice.Parent.Save()
+	return Success(&c, Response{})
+}
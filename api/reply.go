@@ -1,6 +1,7 @@
 package api
 
 import (
+	"fmt"
 	"io"
 	"mime/multipart"
 	"net/http"
@@ -11,6 +12,7 @@ import (
 	"github.com/labstack/echo/v4"
 
 	"sealdice-core/dice"
+	"sealdice-core/dice/service"
 )
 
 const customReplyPackageWarning = "此文件来自扩展包运行时缓存，重装、刷新缓存或升级扩展包时可能被覆盖"
@@ -389,3 +391,91 @@ func customReplyDebugModeSet(c echo.Context) error {
 		"value": myDice.Config.ReplyDebugMode,
 	})
 }
+
+// customReplySchema 返回支持的条件与动作类型，供编辑器生成表单
+func customReplySchema(c echo.Context) error {
+	if !doAuth(c) {
+		return c.JSON(http.StatusForbidden, nil)
+	}
+	conditions, results := dice.ReplySchema()
+	return c.JSON(http.StatusOK, map[string]any{
+		"conditions": conditions,
+		"results":    results,
+	})
+}
+
+// customReplySimulateMaxMessages 单次模拟的消息条数上限
+const customReplySimulateMaxMessages = 2000
+
+// customReplySimulate 将跑团日志或粘贴的语料逐条送入自定义回复判定，不会真正发送
+func customReplySimulate(c echo.Context) error {
+	if !doAuth(c) {
+		return c.JSON(http.StatusForbidden, nil)
+	}
+
+	v := struct {
+		Filename  string                      `json:"filename"`
+		PackageID string                      `json:"packageId"`
+		Config    *dice.ReplyConfig           `json:"config"` // 编辑中尚未保存的回复文件，优先于 filename
+		Messages  []dice.ReplySimulateMessage `json:"messages"`
+		Text      string                      `json:"text"` // 粘贴的语料，每行一条消息
+		LogGroup  string                      `json:"logGroupId"`
+		LogName   string                      `json:"logName"`
+	}{}
+	if err := c.Bind(&v); err != nil {
+		return c.String(430, err.Error())
+	}
+
+	var rcs []*dice.ReplyConfig
+	switch {
+	case v.Config != nil:
+		v.Config.Clean()
+		rcs = append(rcs, v.Config)
+	case v.Filename != "":
+		for _, rc := range myDice.CustomReplyConfig {
+			if rc != nil && rc.PackageID == v.PackageID && strings.EqualFold(rc.Filename, v.Filename) {
+				rcs = append(rcs, rc)
+				break
+			}
+		}
+		if len(rcs) == 0 {
+			return c.JSON(http.StatusNotFound, map[string]interface{}{
+				"success": false,
+				"message": "自定义回复文件不存在",
+			})
+		}
+	}
+
+	msgs := v.Messages
+	for _, line := range strings.Split(v.Text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			msgs = append(msgs, dice.ReplySimulateMessage{Message: line})
+		}
+	}
+	if v.LogGroup != "" && v.LogName != "" {
+		lines, err := service.LogGetAllLines(myDice.DBOperator, v.LogGroup, v.LogName)
+		if err != nil {
+			return c.String(http.StatusInternalServerError, err.Error())
+		}
+		for _, line := range lines {
+			if !line.IsDice {
+				msgs = append(msgs, dice.ReplySimulateMessage{Nickname: line.Nickname, Message: line.Message})
+			}
+		}
+	}
+	if len(msgs) > customReplySimulateMaxMessages {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"success": false,
+			"message": fmt.Sprintf("消息过多，单次最多模拟%d条", customReplySimulateMaxMessages),
+		})
+	}
+
+	results, err := myDice.ReplySimulate(rcs, msgs)
+	if err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"results": results,
+	})
+}
@@ -0,0 +1,31 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"sealdice-core/dice"
+	"sealdice-core/model"
+)
+
+// rollStatGet 查看检定统计，scopeType 为 user/char/group，scopeId 为对应的用户ID、属性ID或群号
+func rollStatGet(c echo.Context) error {
+	if !doAuth(c) {
+		return c.JSON(http.StatusForbidden, nil)
+	}
+	scopeType, scopeID := c.QueryParam("scopeType"), c.QueryParam("scopeId")
+	switch scopeType {
+	case model.RollStatScopeUser, model.RollStatScopeChar, model.RollStatScopeGroup:
+	default:
+		return Error(&c, "scopeType 无效", Response{})
+	}
+	if scopeID == "" {
+		return Error(&c, "scopeId 不能为空", Response{})
+	}
+	report, err := dice.RollStatReportGet(myDice, scopeType, scopeID)
+	if err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	return Success(&c, Response{"item": report})
+}
@@ -0,0 +1,39 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// scheduleList 查看定时任务，可用 groupId 参数筛选单个群
+func scheduleList(c echo.Context) error {
+	if !doAuth(c) {
+		return c.JSON(http.StatusForbidden, nil)
+	}
+	items, err := myDice.Schedule.Overview(c.QueryParam("groupId"))
+	if err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	return Success(&c, Response{"items": items})
+}
+
+// scheduleDelete 删除定时任务
+func scheduleDelete(c echo.Context) error {
+	if !doAuth(c) {
+		return c.JSON(http.StatusForbidden, nil)
+	}
+	if dm.JustForTest {
+		return Success(&c, Response{"testMode": true})
+	}
+	var v struct {
+		ID uint64 `json:"id"`
+	}
+	if err := c.Bind(&v); err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	if err := myDice.Schedule.Delete(v.ID); err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	return Success(&c, Response{})
+}
@@ -4,12 +4,15 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/labstack/echo/v4"
 
 	"sealdice-core/dice"
 	"sealdice-core/dice/service"
+	"sealdice-core/dice/storylog"
 	"sealdice-core/model"
 )
 
@@ -104,6 +107,138 @@ func storyGetItemPage(c echo.Context) error {
 	return c.JSON(http.StatusOK, lines)
 }
 
+func storyHideItems(c echo.Context) error {
+	if !doAuth(c) {
+		return c.JSON(http.StatusForbidden, nil)
+	}
+	v := struct {
+		GroupID string   `json:"groupId"`
+		LogName string   `json:"logName"`
+		IDs     []uint64 `json:"ids"`
+		Hidden  bool     `json:"hidden"`
+	}{}
+	if err := c.Bind(&v); err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	changed, err := service.LogSetItemsHidden(myDice.DBOperator, v.GroupID, v.LogName, v.IDs, v.Hidden)
+	if err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	return Success(&c, Response{"changed": changed})
+}
+
+func storyRenameItems(c echo.Context) error {
+	if !doAuth(c) {
+		return c.JSON(http.StatusForbidden, nil)
+	}
+	v := struct {
+		GroupID  string `json:"groupId"`
+		LogName  string `json:"logName"`
+		IMUserID string `json:"imUserId"`
+		Nickname string `json:"nickname"`
+	}{}
+	if err := c.Bind(&v); err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	changed, err := service.LogRemapNickname(myDice.DBOperator, v.GroupID, v.LogName, v.IMUserID, v.Nickname)
+	if err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	return Success(&c, Response{"changed": changed})
+}
+
+func storyInsertNarration(c echo.Context) error {
+	if !doAuth(c) {
+		return c.JSON(http.StatusForbidden, nil)
+	}
+	v := struct {
+		GroupID  string `json:"groupId"`
+		LogName  string `json:"logName"`
+		AfterID  uint64 `json:"afterId"`
+		Nickname string `json:"nickname"`
+		Message  string `json:"message"`
+	}{}
+	if err := c.Bind(&v); err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	if v.Nickname == "" {
+		v.Nickname = "KP"
+	}
+	item, err := service.LogInsertNarration(myDice.DBOperator, v.GroupID, v.LogName, v.AfterID, v.Nickname, v.Message)
+	if err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	return Success(&c, Response{"item": item})
+}
+
+func storyMergeItems(c echo.Context) error {
+	if !doAuth(c) {
+		return c.JSON(http.StatusForbidden, nil)
+	}
+	v := struct {
+		GroupID string   `json:"groupId"`
+		LogName string   `json:"logName"`
+		IDs     []uint64 `json:"ids"`
+	}{}
+	if err := c.Bind(&v); err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	item, err := service.LogMergeItems(myDice.DBOperator, v.GroupID, v.LogName, v.IDs)
+	if err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	return Success(&c, Response{"item": item})
+}
+
+func storyMoveItem(c echo.Context) error {
+	if !doAuth(c) {
+		return c.JSON(http.StatusForbidden, nil)
+	}
+	v := struct {
+		GroupID string `json:"groupId"`
+		LogName string `json:"logName"`
+		ID      uint64 `json:"id"`
+		AfterID uint64 `json:"afterId"`
+	}{}
+	if err := c.Bind(&v); err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	id, err := service.LogMoveItem(myDice.DBOperator, v.GroupID, v.LogName, v.ID, v.AfterID)
+	if err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	return Success(&c, Response{"id": id})
+}
+
+func storyUndoItemsEdit(c echo.Context) error {
+	if !doAuth(c) {
+		return c.JSON(http.StatusForbidden, nil)
+	}
+	v := struct {
+		GroupID string `json:"groupId"`
+		LogName string `json:"logName"`
+	}{}
+	if err := c.Bind(&v); err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	entry, err := service.LogEditUndo(myDice.DBOperator, v.GroupID, v.LogName)
+	if err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	return Success(&c, Response{"undone": entry})
+}
+
+func storyGetItemsJournal(c echo.Context) error {
+	if !doAuth(c) {
+		return c.JSON(http.StatusForbidden, nil)
+	}
+	entries, err := service.LogEditJournalList(myDice.DBOperator, c.QueryParam("groupId"), c.QueryParam("logName"))
+	if err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	return Success(&c, Response{"data": entries})
+}
+
 func storyDelLog(c echo.Context) error {
 	if !doAuth(c) {
 		return c.JSON(http.StatusForbidden, nil)
@@ -125,6 +260,49 @@ func storyDelLog(c echo.Context) error {
 	return c.JSON(http.StatusOK, true)
 }
 
+// storyExportLog 离线导出日志，format 可选 txt/html/md/jsonl/docx
+func storyExportLog(c echo.Context) error {
+	if !doAuth(c) {
+		return c.JSON(http.StatusForbidden, nil)
+	}
+	groupID := c.QueryParam("groupId")
+	name := c.QueryParam("name")
+	format, err := storylog.ParseExportFormat(c.QueryParam("format"))
+	if err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+
+	ctx := &dice.MsgContext{
+		Dice:     myDice,
+		EndPoint: myDice.UIEndpoint,
+	}
+	path, _, err := dice.GetLogExport(ctx, groupID, name, name, format)
+	if err != nil {
+		return Error(&c, err.Error(), Response{})
+	}
+	defer os.Remove(path)
+	return c.Attachment(path, filepath.Base(path))
+}
+
+// storyShareView 本地日志查看页，凭签名链接访问，不需要登录
+func storyShareView(c echo.Context) error {
+	page, err := dice.LogRenderSharePage(myDice, c.Param("token"))
+	if err != nil {
+		switch {
+		case errors.Is(err, storylog.ErrShareTokenExpired):
+			return c.String(http.StatusGone, err.Error())
+		case errors.Is(err, storylog.ErrShareTokenInvalid):
+			return c.String(http.StatusForbidden, err.Error())
+		case errors.Is(err, service.ErrLogNotFound):
+			return c.String(http.StatusNotFound, "日志不存在或已被删除")
+		default:
+			myDice.Logger.Error("storyShareView", err)
+			return c.String(http.StatusInternalServerError, err.Error())
+		}
+	}
+	return c.HTMLBlob(http.StatusOK, page)
+}
+
 func storyUploadLog(c echo.Context) error {
 	if !doAuth(c) {
 		return c.JSON(http.StatusForbidden, nil)
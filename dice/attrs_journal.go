@@ -0,0 +1,231 @@
+package dice
+
+import (
+	"strings"
+	"time"
+
+	ds "github.com/sealdice/dicescript"
+
+	"sealdice-core/dice/service"
+	"sealdice-core/logger"
+	"sealdice-core/model"
+)
+
+// 角色属性变更记录：AttributesItem 的 Store/Delete/Clear 先将变更暂存在角色上，
+// 记录时带上最近一次经 LoadByCtx 取用该角色的指令ID、群号与操作者，
+// 指令执行完毕或定期保存时写入数据库。撤销通过追加反向记录完成，记录本身只追加不修改。
+
+func journalEncode(v *ds.VMValue) string {
+	if v == nil {
+		return ""
+	}
+	data, err := v.ToJSON()
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+func journalDecode(s string) *ds.VMValue {
+	if s == "" {
+		return nil
+	}
+	v, err := ds.VMValueFromJSON([]byte(s))
+	if err != nil {
+		return nil
+	}
+	return v
+}
+
+// journalDisplay 将记录中的值转为便于阅读的文本
+func journalDisplay(s string) string {
+	if v := journalDecode(s); v != nil {
+		return v.ToString()
+	}
+	return "(无)"
+}
+
+// journalStamp 记下当前取用角色的指令，之后的变更记录归属于该指令
+func (i *AttributesItem) journalStamp(ctx *MsgContext) {
+	i.journalLock.Lock()
+	defer i.journalLock.Unlock()
+	i.journalCommand = &model.AttrsJournal{CommandID: ctx.CommandID}
+	if ctx.Group != nil {
+		i.journalCommand.GroupID = ctx.Group.GroupID
+	}
+	if ctx.Player != nil {
+		i.journalCommand.UserID = ctx.Player.UserID
+	}
+}
+
+func (i *AttributesItem) journalRecord(name string, oldVal *ds.VMValue, newVal *ds.VMValue) {
+	if name == attrsTemplateVersionKey {
+		return
+	}
+	oldText, newText := journalEncode(oldVal), journalEncode(newVal)
+	if oldText == newText {
+		return
+	}
+
+	entry := &model.AttrsJournal{
+		AttrsID:   i.ID,
+		Attr:      name,
+		OldValue:  oldText,
+		NewValue:  newText,
+		CreatedAt: time.Now().Unix(),
+	}
+	i.journalLock.Lock()
+	if c := i.journalCommand; c != nil {
+		entry.CommandID, entry.GroupID, entry.UserID = c.CommandID, c.GroupID, c.UserID
+	}
+	i.journalPending = append(i.journalPending, entry)
+	i.journalLock.Unlock()
+	i.journal.journalDirty.Store(i.ID, i)
+}
+
+// journalTake 取出暂存的记录，commandID 非零时一并清除该指令留下的归属
+func (i *AttributesItem) journalTake(commandID int64) []*model.AttrsJournal {
+	i.journalLock.Lock()
+	defer i.journalLock.Unlock()
+	entries := i.journalPending
+	i.journalPending = nil
+	if commandID != 0 && i.journalCommand != nil && i.journalCommand.CommandID == commandID {
+		i.journalCommand = nil
+	}
+	return entries
+}
+
+// JournalFlush 写入暂存的属性变更记录，ctx 为刚执行完毕的指令，可为空
+func (am *AttrsManager) JournalFlush(ctx *MsgContext) error {
+	var commandID int64
+	if ctx != nil {
+		commandID = ctx.CommandID
+	}
+	am.journalFlushLock.Lock()
+	defer am.journalFlushLock.Unlock()
+
+	var entries []*model.AttrsJournal
+	am.journalDirty.Range(func(key string, item *AttributesItem) bool {
+		am.journalDirty.Delete(key)
+		entries = append(entries, item.journalTake(commandID)...)
+		return true
+	})
+	return service.AttrsJournalAppend(am.db, entries)
+}
+
+// JournalUndo 撤销角色最近 n 次属性变更，返回被撤销的记录
+func (am *AttrsManager) JournalUndo(ctx *MsgContext, attrs *AttributesItem, n int) ([]*model.AttrsJournal, error) {
+	if err := am.JournalFlush(nil); err != nil {
+		return nil, err
+	}
+	changes, err := service.AttrsJournalLastChanges(am.db, attrs.ID, n)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().Unix()
+	var undone, reverts []*model.AttrsJournal
+	for _, change := range changes {
+		for _, e := range change {
+			// 直接操作 valueMap，撤销本身以反向记录的形式写入，不再经过 journalRecord
+			cur, _ := attrs.valueMap.Load(e.Attr)
+			if old := journalDecode(e.OldValue); old != nil {
+				attrs.valueMap.Store(e.Attr, old)
+			} else {
+				attrs.valueMap.Delete(e.Attr)
+			}
+			undone = append(undone, e)
+
+			revert := &model.AttrsJournal{
+				AttrsID:   attrs.ID,
+				Attr:      e.Attr,
+				OldValue:  journalEncode(cur),
+				NewValue:  e.OldValue,
+				RevertOf:  e.ID,
+				CreatedAt: now,
+			}
+			if ctx != nil {
+				revert.CommandID = ctx.CommandID
+				if ctx.Group != nil {
+					revert.GroupID = ctx.Group.GroupID
+				}
+				if ctx.Player != nil {
+					revert.UserID = ctx.Player.UserID
+				}
+			}
+			reverts = append(reverts, revert)
+		}
+	}
+	attrs.SetModified()
+	return undone, service.AttrsJournalAppend(am.db, reverts)
+}
+
+// FormatAttrsJournal 单条记录的展示文本
+func FormatAttrsJournal(e *model.AttrsJournal) string {
+	var sb strings.Builder
+	sb.WriteString(time.Unix(e.CreatedAt, 0).Format("01-02 15:04"))
+	sb.WriteString(" ")
+	sb.WriteString(e.Attr)
+	sb.WriteString(": ")
+	sb.WriteString(journalDisplay(e.OldValue))
+	sb.WriteString(" ➯ ")
+	sb.WriteString(journalDisplay(e.NewValue))
+	if e.RevertOf != 0 {
+		sb.WriteString(" (撤销)")
+	}
+	return sb.String()
+}
+
+// DefaultAttrsJournalKeepCount 未配置时每个角色保留的属性变更记录条数
+const DefaultAttrsJournalKeepCount = 100
+
+// ResetAttrsJournalClean 重新设定每日清理属性变更记录的定时任务
+func (dm *DiceManager) ResetAttrsJournalClean() {
+	log := logger.M()
+	if dm.attrsJournalCronID > 0 {
+		dm.Cron.Remove(dm.attrsJournalCronID)
+		dm.attrsJournalCronID = 0
+	}
+	if dm.AttrsJournalKeepCount <= 0 && dm.AttrsJournalKeepDur <= 0 {
+		return
+	}
+
+	var err error
+	dm.attrsJournalCronID, err = dm.Cron.AddFunc("@daily", func() {
+		if !dm.Cluster.IsLeader() {
+			return
+		}
+		dm.AttrsJournalClean()
+	})
+	if err != nil {
+		log.Errorf("设定属性变更记录清理任务失败: %v", err)
+	}
+}
+
+// AttrsJournalClean 按属性变更记录的保留设置删除过期记录
+func (dm *DiceManager) AttrsJournalClean() {
+	log := logger.M()
+	for _, d := range dm.Dice {
+		if d.DBOperator == nil {
+			continue
+		}
+		var deleted int64
+		if dm.AttrsJournalKeepCount > 0 {
+			n, err := service.AttrsJournalPruneKeep(d.DBOperator, dm.AttrsJournalKeepCount)
+			if err != nil {
+				log.Errorf("清理属性变更记录失败: %v", err)
+				continue
+			}
+			deleted += n
+		}
+		if dm.AttrsJournalKeepDur > 0 {
+			n, err := service.AttrsJournalPruneBefore(d.DBOperator, time.Now().Add(-dm.AttrsJournalKeepDur).Unix())
+			if err != nil {
+				log.Errorf("清理属性变更记录失败: %v", err)
+				continue
+			}
+			deleted += n
+		}
+		log.Infof("清理属性变更记录 %d 条", deleted)
+	}
+}
@@ -0,0 +1,88 @@
+//nolint:testpackage
+package dice
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"sealdice-core/dice/service"
+	"sealdice-core/model"
+	"sealdice-core/utils/constant"
+)
+
+func TestExecuteNew_StHistoryAndUndo(t *testing.T) {
+	d, ep, adapter, cleanup := newExecuteNewTestDice(t)
+	defer cleanup()
+	db := d.DBOperator.GetDataDB(constant.WRITE)
+	if err := db.AutoMigrate(&model.AttributesItemModel{}, &model.AttrsJournal{}); err != nil {
+		t.Fatalf("AutoMigrate: %v", err)
+	}
+
+	groupID := "QQ-Group:555"
+	send := func(text string) string {
+		d.ImSession.ExecuteNew(ep, newGroupMsg(groupID, "QQ:999", text))
+		reply, ok := adapter.waitForMsg(2 * time.Second)
+		if !ok {
+			t.Fatalf("timeout waiting for reply to %q", text)
+		}
+		return reply
+	}
+
+	send(".st 力量60")
+	send(".st 力量70 体质50")
+	if reply := send(".st history"); !strings.Contains(reply, "力量: 60 ➯ 70") || !strings.Contains(reply, "体质: (无) ➯ 50") {
+		t.Fatalf("unexpected history: %s", reply)
+	}
+
+	if reply := send(".st undo"); !strings.Contains(reply, "力量: 70 ➯ 60") {
+		t.Fatalf("unexpected undo reply: %s", reply)
+	}
+	attrs, err := d.AttrsManager.Load(groupID, "QQ:999")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := attrs.LoadX("力量"); v == nil || v.ToString() != "60" {
+		t.Fatalf("力量 = %v", v)
+	}
+	if _, exists := attrs.LoadX("体质"); exists {
+		t.Fatal("体质 should be removed by undo")
+	}
+
+	// 撤销记录本身不会再被撤销，继续撤销会回到最初的录入
+	send(".st undo")
+	if _, exists := attrs.LoadX("力量"); exists {
+		t.Fatal("力量 should be removed by second undo")
+	}
+	if reply := send(".st undo"); !strings.Contains(reply, "没有可撤销") {
+		t.Fatalf("unexpected reply: %s", reply)
+	}
+}
+
+func TestAttrsJournalCleanUsesJournalSetting(t *testing.T) {
+	d, _, _, cleanup := newExecuteNewTestDice(t)
+	defer cleanup()
+	db := d.DBOperator.GetDataDB(constant.WRITE)
+	if err := db.AutoMigrate(&model.AttrsJournal{}); err != nil {
+		t.Fatalf("AutoMigrate: %v", err)
+	}
+	var entries []*model.AttrsJournal
+	for range 5 {
+		entries = append(entries, &model.AttrsJournal{AttrsID: "a", Attr: "力量"})
+	}
+	if err := service.AttrsJournalAppend(d.DBOperator, entries); err != nil {
+		t.Fatal(err)
+	}
+
+	// 备份清理关闭、且前一个骰子没有数据库时，依然按记录自己的设置清理
+	dm := &DiceManager{
+		Dice:                  []*Dice{{}, d},
+		BackupCleanStrategy:   BackupCleanStrategyDisabled,
+		AttrsJournalKeepCount: 2,
+	}
+	dm.AttrsJournalClean()
+
+	if _, total, err := service.AttrsJournalList(d.DBOperator, "a", 10, 0); err != nil || total != 2 {
+		t.Fatalf("expected 2 entries left, got %d (%v)", total, err)
+	}
+}
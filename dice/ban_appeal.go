@@ -0,0 +1,288 @@
+package dice
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-module/carbon"
+
+	"sealdice-core/dice/service"
+	"sealdice-core/model"
+)
+
+const banAppealReasonMaxLen = 200
+
+var banAppealStateText = map[string]string{
+	model.BanAppealPending:  "待处理",
+	model.BanAppealAccepted: "已通过",
+	model.BanAppealRejected: "已驳回",
+}
+
+// Pardon 降低怒气值并按阈值重新评定等级，reduce 不大于 0 时清零并解除禁止。信任用户等级不变
+func (i *BanListInfo) Pardon(uid string, reduce int64, place string, reason string) (*BanListInfoItem, bool) {
+	v, ok := i.GetByID(uid)
+	if !ok {
+		return nil, false
+	}
+	if reduce <= 0 || reduce >= v.Score {
+		v.Score = 0
+	} else {
+		v.Score -= reduce
+	}
+	if v.Rank != BanRankTrusted {
+		oldRank := v.Rank
+		switch {
+		case v.Score >= i.ThresholdBan:
+			v.Rank = BanRankBanned
+		case v.Score >= i.ThresholdWarn:
+			v.Rank = BanRankWarn
+		default:
+			v.Rank = BanRankNormal
+		}
+		if oldRank != v.Rank {
+			v.BanUpdatedAt = time.Now().Unix()
+		}
+	}
+	v.Places = append(v.Places, place)
+	v.Reasons = append(v.Reasons, reason)
+	v.Times = append(v.Times, time.Now().Unix())
+	v.UpdatedAt = time.Now().Unix()
+	return v, true
+}
+
+// banAppealSubmit 提交申诉，返回给申诉人的回复，submitted 表示是否新建了申诉
+func banAppealSubmit(ctx *MsgContext, msg *Message, reason string) (text string, submitted bool) {
+	d := ctx.Dice
+	banList := d.Config.BanList
+	uid := msg.Sender.UserID
+
+	item, ok := banList.GetByID(uid)
+	if !ok || (item.Rank != BanRankBanned && item.Rank != BanRankWarn) {
+		return "你不在黑名单或警告名单中，无需申诉", false
+	}
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return "请写明申诉理由，如: .appeal 误触了指令，以后会注意", false
+	}
+	if r := []rune(reason); len(r) > banAppealReasonMaxLen {
+		reason = string(r[:banAppealReasonMaxLen])
+	}
+
+	history, err := service.BanAppealList(d.DBOperator, "", uid)
+	if err != nil {
+		d.Logger.Errorf("读取申诉记录出错: %v", err)
+		return "申诉提交失败，请稍后再试", false
+	}
+	if len(history) > 0 {
+		last := history[0]
+		if last.State == model.BanAppealPending {
+			return fmt.Sprintf("你的申诉#%d正在等待处理，请耐心等待", last.ID), false
+		}
+		cooldown := banList.BlacklistedHelpMasterCooldown()
+		if wait := time.Until(time.Unix(last.UpdatedAt, 0).Add(cooldown)); wait > 0 {
+			return fmt.Sprintf("上一次申诉已被处理，请在%d分钟后再提交", int64(wait.Minutes())+1), false
+		}
+	}
+
+	appeal := &model.BanAppeal{
+		UserID:     uid,
+		Nickname:   msg.Sender.Nickname,
+		Reason:     reason,
+		Rank:       int(item.Rank),
+		Score:      item.Score,
+		EndpointID: ctx.EndPoint.UserID,
+	}
+	if msg.MessageType == "group" {
+		appeal.GroupID = msg.GroupID
+	}
+	if err := service.BanAppealCreate(d.DBOperator, appeal); err != nil {
+		d.Logger.Errorf("保存申诉出错: %v", err)
+		return "申诉提交失败，请稍后再试", false
+	}
+
+	notice := fmt.Sprintf("收到黑名单申诉#%d: <%s>(%s)\n当前: %s 怒气值%d\n理由: %s\n原记录: %s\n处理: .ban appeal accept/reject %d [<备注>]",
+		appeal.ID, appeal.Nickname, uid, BanRankText[item.Rank], item.Score, reason, strings.Join(item.Reasons, ","), appeal.ID)
+	d.Logger.Info(notice)
+	ctx.Notice(notice)
+	return fmt.Sprintf("申诉#%d已提交，骰主处理后会通知你", appeal.ID), true
+}
+
+// tryHandleBlacklistedAppeal 被拒绝回复的黑名单用户仍可使用 .appeal，除提交成功外的回复受冷却限制以免被用来刷屏。
+// 未开启“拒绝回复”时黑名单用户的消息照常处理，.appeal 作为普通指令生效；
+// 群内触发退群类拉黑行为时不响应申诉，此时需私聊骰子申诉
+func tryHandleBlacklistedAppeal(ctx *MsgContext, msg *Message, now time.Time) bool {
+	var cmdArgs *CmdArgs
+	if len(msg.Segment) > 0 {
+		cmdArgs = CommandParseNew(ctx, msg)
+	} else {
+		cmdArgs = CommandParse(msg.Message, []string{"appeal"}, ctx.Dice.CommandPrefix, msg.Platform, false)
+	}
+	if cmdArgs == nil || !strings.EqualFold(cmdArgs.Command, "appeal") {
+		return false
+	}
+	// 提交成功总是回复，重复申诉等提示受冷却限制
+	text, submitted := banAppealSubmit(ctx, msg, cmdArgs.CleanArgs)
+	if submitted || ctx.Dice.Config.BanList.CanReplyBlacklistedAppeal(msg.Sender.UserID, now) {
+		ReplyToSender(ctx, msg, text)
+	}
+	return true
+}
+
+// BanAppealHandle 处理申诉。通过时按 reduce 降低怒气值，reduce 不大于 0 则解除禁止；结果会通知申诉人
+func (d *Dice) BanAppealHandle(id uint64, accept bool, reduce int64, handler string, note string) (*model.BanAppeal, error) {
+	appeal, err := service.BanAppealGet(d.DBOperator, id)
+	if err != nil {
+		return nil, err
+	}
+	if appeal == nil || appeal.State != model.BanAppealPending {
+		return nil, service.ErrBanAppealHandled
+	}
+
+	state := model.BanAppealRejected
+	if accept {
+		state = model.BanAppealAccepted
+	}
+	if err := service.BanAppealResolve(d.DBOperator, id, state, handler, note); err != nil {
+		return nil, err
+	}
+	appeal.State, appeal.Handler, appeal.Note = state, handler, note
+
+	var text string
+	if accept {
+		banList := d.Config.BanList
+		reason := fmt.Sprintf("申诉#%d通过", id)
+		if note != "" {
+			reason += ": " + note
+		}
+		if v, ok := banList.Pardon(appeal.UserID, reduce, "申诉", reason); ok {
+			banList.SaveChanged(d)
+			text = fmt.Sprintf("你的申诉#%d已通过，当前: %s 怒气值%d", id, BanRankText[v.Rank], v.Score)
+		} else {
+			text = fmt.Sprintf("你的申诉#%d已通过", id)
+		}
+	} else {
+		text = fmt.Sprintf("你的申诉#%d已被驳回", id)
+	}
+	if note != "" {
+		text += "\n备注: " + note
+	}
+	d.Logger.Infof("申诉#%d(%s)由%s处理: %s", id, appeal.UserID, handler, banAppealStateText[state])
+
+	if ep := d.banAppealEndpoint(appeal); ep != nil {
+		ctx := &MsgContext{Dice: d, EndPoint: ep, Session: d.ImSession}
+		ReplyPerson(ctx, &Message{Sender: SenderBase{UserID: appeal.UserID}}, text)
+	}
+	return appeal, nil
+}
+
+func (d *Dice) banAppealEndpoint(appeal *model.BanAppeal) *EndPointInfo {
+	if d.ImSession == nil {
+		return nil
+	}
+	var fallback *EndPointInfo
+	for _, ep := range d.ImSession.EndPoints {
+		if !ep.Enable {
+			continue
+		}
+		if ep.UserID == appeal.EndpointID {
+			return ep
+		}
+		if fallback == nil && strings.HasPrefix(appeal.UserID, ep.Platform+":") {
+			fallback = ep
+		}
+	}
+	return fallback
+}
+
+func banAppealText(a *model.BanAppeal) string {
+	return fmt.Sprintf("#%d [%s] <%s>(%s) %s 理由: %s",
+		a.ID, banAppealStateText[a.State], a.Nickname, a.UserID,
+		carbon.CreateFromTimestamp(a.CreatedAt).ToDateTimeString(), a.Reason)
+}
+
+// banAppealCommand .ban appeal 子指令，调用前已检查权限
+func banAppealCommand(ctx *MsgContext, msg *Message, cmdArgs *CmdArgs) CmdExecuteResult {
+	d := ctx.Dice
+	switch strings.ToLower(cmdArgs.GetArgN(2)) {
+	case "", "list":
+		state := model.BanAppealPending
+		if cmdArgs.IsArgEqual(3, "all") {
+			state = ""
+		}
+		items, err := service.BanAppealList(d.DBOperator, state, "")
+		if err != nil {
+			ReplyToSender(ctx, msg, "读取申诉出错: "+err.Error())
+			break
+		}
+		if len(items) == 0 {
+			ReplyToSender(ctx, msg, "当前没有待处理的申诉")
+			break
+		}
+		var texts []string
+		for idx, a := range items {
+			if idx >= 20 {
+				texts = append(texts, fmt.Sprintf("...共%d条", len(items)))
+				break
+			}
+			texts = append(texts, banAppealText(a))
+		}
+		ReplyToSender(ctx, msg, "申诉列表:\n"+strings.Join(texts, "\n"))
+	case "show":
+		id, _ := strconv.ParseUint(cmdArgs.GetArgN(3), 10, 64)
+		appeal, err := service.BanAppealGet(d.DBOperator, id)
+		if err != nil || appeal == nil {
+			ReplyToSender(ctx, msg, "找不到该申诉")
+			break
+		}
+		text := banAppealText(appeal)
+		history, _ := service.BanAppealHistoryList(d.DBOperator, id)
+		for _, h := range history {
+			text += fmt.Sprintf("\n%s %s %s %s",
+				carbon.CreateFromTimestamp(h.CreatedAt).ToDateTimeString(), banAppealStateText[h.State], h.Operator, h.Note)
+		}
+		ReplyToSender(ctx, msg, text)
+	case "accept", "reject":
+		id, err := strconv.ParseUint(cmdArgs.GetArgN(3), 10, 64)
+		if err != nil {
+			return CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+		}
+		var reduce int64
+		if kw := cmdArgs.GetKwarg("reduce"); kw != nil {
+			reduce, _ = strconv.ParseInt(kw.Value, 10, 64)
+		}
+		note := strings.Join(cmdArgs.Args[min(3, len(cmdArgs.Args)):], " ")
+		accept := cmdArgs.IsArgEqual(2, "accept")
+		appeal, err := d.BanAppealHandle(id, accept, reduce, msg.Sender.UserID, note)
+		if err != nil {
+			if errors.Is(err, service.ErrBanAppealHandled) {
+				ReplyToSender(ctx, msg, err.Error())
+			} else {
+				ReplyToSender(ctx, msg, "处理申诉出错: "+err.Error())
+			}
+			break
+		}
+		ReplyToSender(ctx, msg, fmt.Sprintf("申诉#%d %s", appeal.ID, banAppealStateText[appeal.State]))
+	default:
+		return CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+	}
+	return CmdExecuteResult{Matched: true, Solved: true}
+}
+
+const helpAppeal = `.appeal <理由> // 被拉黑或警告时向骰主提交申诉，若在群内无响应请私聊骰子`
+
+var cmdAppeal = &CmdItemInfo{
+	Name:      "appeal",
+	ShortHelp: helpAppeal,
+	Help:      "黑名单申诉:\n" + helpAppeal,
+	Solve: func(ctx *MsgContext, msg *Message, cmdArgs *CmdArgs) CmdExecuteResult {
+		if cmdArgs.IsArgEqual(1, "help") {
+			return CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+		}
+		text, _ := banAppealSubmit(ctx, msg, cmdArgs.CleanArgs)
+		ReplyToSender(ctx, msg, text)
+		return CmdExecuteResult{Matched: true, Solved: true}
+	},
+}
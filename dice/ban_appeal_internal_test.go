@@ -0,0 +1,71 @@
+//nolint:testpackage
+package dice
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"sealdice-core/dice/service"
+	"sealdice-core/model"
+	"sealdice-core/utils/constant"
+)
+
+func TestBanAppealAcceptRemovesBan(t *testing.T) {
+	d, ep, adapter, cleanup := newExecuteNewTestDice(t)
+	defer cleanup()
+	if err := d.DBOperator.GetDataDB(constant.WRITE).AutoMigrate(&model.BanInfo{}, &model.BanAppeal{}, &model.BanAppealHistory{}); err != nil {
+		t.Fatal(err)
+	}
+	banList := d.Config.BanList
+	banList.BanBehaviorRefuseReply = true
+	d.DiceMasters = []string{"QQ:55555"}
+	uid := "QQ:777"
+	banList.AddScoreBase(uid, banList.ThresholdBan, "测试", "刷屏", nil)
+	if item, _ := banList.GetByID(uid); item.Rank != BanRankBanned {
+		t.Fatalf("user should be banned: %+v", item)
+	}
+
+	send := func(sender, text string) string {
+		d.ImSession.ExecuteNew(ep, newPrivateMsg(sender, text))
+		reply, ok := adapter.waitForMsg(2 * time.Second)
+		if !ok {
+			t.Fatalf("no reply to %q", text)
+		}
+		return reply
+	}
+
+	// 被拉黑后普通指令不回复，但申诉可以提交
+	if reply := send(uid, ".appeal"); !strings.Contains(reply, "申诉理由") {
+		t.Fatalf("empty reason should be rejected: %q", reply)
+	}
+	if reply := send(uid, ".appeal 误触了指令"); !strings.Contains(reply, "#1") {
+		t.Fatalf("unexpected submit reply: %q", reply)
+	}
+	// 缺少理由的提示已占用冷却，重复申诉的提示不再回复，以免被用来刷屏
+	d.ImSession.ExecuteNew(ep, newPrivateMsg(uid, ".appeal 再申诉一次"))
+	if reply, ok := adapter.waitForMsg(500 * time.Millisecond); ok {
+		t.Fatalf("repeated appeal should be throttled: %q", reply)
+	}
+	if appeals, _ := service.BanAppealList(d.DBOperator, "", uid); len(appeals) != 1 {
+		t.Fatalf("repeated appeal should not be saved: %d", len(appeals))
+	}
+
+	// 先私聊通知申诉人，再回复骰主
+	if reply := send("QQ:55555", ".ban appeal accept 1 下不为例"); !strings.Contains(reply, "你的申诉#1已通过") || !strings.Contains(reply, "下不为例") {
+		t.Fatalf("appellant should be notified: %q", reply)
+	}
+	if reply, _ := adapter.waitForMsg(2 * time.Second); !strings.Contains(reply, "申诉#1 已通过") {
+		t.Fatalf("unexpected accept reply: %q", reply)
+	}
+	if item, _ := banList.GetByID(uid); item.Rank != BanRankNormal || item.Score != 0 {
+		t.Fatalf("ban should be lifted: %+v", item)
+	}
+	appeal, err := service.BanAppealGet(d.DBOperator, 1)
+	if err != nil || appeal.State != model.BanAppealAccepted || appeal.Handler != "QQ:55555" {
+		t.Fatalf("unexpected appeal: %+v %v", appeal, err)
+	}
+	if _, err = d.BanAppealHandle(1, false, 0, "QQ:55555", ""); err == nil {
+		t.Fatal("handled appeal should not be handled again")
+	}
+}
@@ -0,0 +1,423 @@
+package dice
+
+import (
+	"crypto/ed25519"
+	crand "crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"sealdice-core/dice/service"
+	"sealdice-core/model"
+)
+
+const (
+	BanFederationApplied   = "applied"   // 已按权重计入本地怒气值
+	BanFederationIgnored   = "ignored"   // 本地信任该ID，不采纳
+	BanFederationWithdrawn = "withdrawn" // 来源已撤销
+
+	banFederationReasonPrefix = "联合黑名单"
+	banFederationFeedMaxAge   = 7 * 24 * time.Hour
+)
+
+// BanFederationSource 订阅的其他骰子的黑名单
+type BanFederationSource struct {
+	Name      string  `json:"name"      yaml:"name"`
+	URL       string  `json:"url"       yaml:"url"`       // 对方的 /sd-api/ban/feed 地址
+	Token     string  `json:"token"     yaml:"token"`     // 对方设置了订阅口令时填写
+	PublicKey string  `json:"publicKey" yaml:"publicKey"` // 对方公钥(base64)，为空时首次同步记录
+	Weight    float64 `json:"weight"    yaml:"weight"`    // 信任权重，对方禁止/警告时计入 权重×本地阈值 的怒气值，0 为仅记录
+	Enable    bool    `json:"enable"    yaml:"enable"`
+
+	LastSyncAt int64  `json:"lastSyncAt" yaml:"lastSyncAt"`
+	LastFeedAt int64  `json:"lastFeedAt" yaml:"lastFeedAt"` // 已同步内容的生成时间，更旧的内容会被拒绝
+	LastError  string `json:"lastError"  yaml:"-"`
+}
+
+// BanFeedItem 黑名单订阅中的一项
+type BanFeedItem struct {
+	ID      string      `json:"id"`
+	Name    string      `json:"name"`
+	Rank    BanRankType `json:"rank"`
+	Reasons []string    `json:"reasons"`
+	BanTime int64       `json:"banTime"`
+}
+
+type BanFeedPayload struct {
+	Issuer      string         `json:"issuer"`
+	GeneratedAt int64          `json:"generatedAt"`
+	Items       []*BanFeedItem `json:"items"`
+}
+
+// BanFeed 签名后的黑名单订阅内容，Payload 为 BanFeedPayload 的 JSON(base64)，Signature 为对其的 ed25519 签名
+type BanFeed struct {
+	PublicKey string `json:"publicKey"`
+	Payload   string `json:"payload"`
+	Signature string `json:"signature"`
+}
+
+// federationKey 读取或生成本骰的签名密钥，保存在数据目录下
+func (i *BanListInfo) federationKey() ed25519.PrivateKey {
+	i.federationMu.Lock()
+	defer i.federationMu.Unlock()
+	if i.federationPrivKey != nil {
+		return i.federationPrivKey
+	}
+
+	var path string
+	if d := i.Parent; d != nil && d.BaseConfig.DataDir != "" {
+		path = filepath.Join(d.BaseConfig.DataDir, "ban_federation.key")
+		if data, err := os.ReadFile(path); err == nil {
+			if seed, err := hex.DecodeString(strings.TrimSpace(string(data))); err == nil && len(seed) == ed25519.SeedSize {
+				i.federationPrivKey = ed25519.NewKeyFromSeed(seed)
+				return i.federationPrivKey
+			}
+		}
+	}
+
+	_, key, _ := ed25519.GenerateKey(crand.Reader)
+	if path != "" {
+		if err := os.WriteFile(path, []byte(hex.EncodeToString(key.Seed())), 0o600); err != nil {
+			i.Parent.Logger.Errorf("保存联合黑名单密钥失败: %v", err)
+		}
+	}
+	i.federationPrivKey = key
+	return key
+}
+
+// FederationPublicKey 本骰的公钥，供订阅方核对
+func (i *BanListInfo) FederationPublicKey() string {
+	return base64.StdEncoding.EncodeToString(i.federationKey().Public().(ed25519.PublicKey))
+}
+
+// isFederatedOnly 条目的原因全部来自联合黑名单，这样的条目不会再次分享出去
+func isFederatedOnly(v *BanListInfoItem) bool {
+	for _, r := range v.Reasons {
+		if !strings.HasPrefix(r, banFederationReasonPrefix) {
+			return false
+		}
+	}
+	return true
+}
+
+// FederationFeed 生成签名的黑名单订阅，包含本地产生的警告与禁止条目
+func (i *BanListInfo) FederationFeed() (*BanFeed, error) {
+	payload := BanFeedPayload{GeneratedAt: time.Now().Unix(), Items: []*BanFeedItem{}}
+	if i.Parent != nil {
+		payload.Issuer = i.Parent.BaseConfig.Name
+	}
+	i.Map.Range(func(k string, v *BanListInfoItem) bool {
+		if (v.Rank == BanRankBanned || v.Rank == BanRankWarn) && !isFederatedOnly(v) {
+			var reasons []string
+			for _, r := range v.Reasons {
+				if !strings.HasPrefix(r, banFederationReasonPrefix) {
+					reasons = append(reasons, r)
+				}
+			}
+			payload.Items = append(payload.Items, &BanFeedItem{ID: k, Name: v.Name, Rank: v.Rank, Reasons: reasons, BanTime: v.BanTime})
+		}
+		return true
+	})
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	key := i.federationKey()
+	return &BanFeed{
+		PublicKey: base64.StdEncoding.EncodeToString(key.Public().(ed25519.PublicKey)),
+		Payload:   base64.StdEncoding.EncodeToString(data),
+		Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(key, data)),
+	}, nil
+}
+
+// Verify 按给定公钥校验签名并解出内容，publicKey 为空时使用订阅内附带的公钥
+func (f *BanFeed) Verify(publicKey string) (*BanFeedPayload, error) {
+	if publicKey == "" {
+		publicKey = f.PublicKey
+	}
+	if publicKey != f.PublicKey {
+		return nil, errors.New("公钥与订阅时记录的不一致")
+	}
+	pub, err := base64.StdEncoding.DecodeString(publicKey)
+	if err != nil || len(pub) != ed25519.PublicKeySize {
+		return nil, errors.New("公钥格式错误")
+	}
+	data, err := base64.StdEncoding.DecodeString(f.Payload)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := base64.StdEncoding.DecodeString(f.Signature)
+	if err != nil || !ed25519.Verify(pub, data, sig) {
+		return nil, errors.New("签名校验失败")
+	}
+	var payload BanFeedPayload
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return nil, err
+	}
+	return &payload, nil
+}
+
+// FederationSourcesCopy 当前的订阅列表副本
+func (i *BanListInfo) FederationSourcesCopy() []BanFederationSource {
+	i.federationMu.Lock()
+	defer i.federationMu.Unlock()
+	ret := make([]BanFederationSource, 0, len(i.FederationSources))
+	for _, s := range i.FederationSources {
+		ret = append(ret, *s)
+	}
+	return ret
+}
+
+// SetFederationSources 替换订阅列表，保留同名来源的同步状态
+func (i *BanListInfo) SetFederationSources(sources []*BanFederationSource) error {
+	names := map[string]bool{}
+	for _, s := range sources {
+		s.Name = strings.TrimSpace(s.Name)
+		if s.Name == "" || names[s.Name] {
+			return fmt.Errorf("来源名为空或重复: %q", s.Name)
+		}
+		names[s.Name] = true
+		if !strings.HasPrefix(s.URL, "http://") && !strings.HasPrefix(s.URL, "https://") {
+			return fmt.Errorf("来源<%s>的地址无效", s.Name)
+		}
+		if s.Weight < 0 {
+			return fmt.Errorf("来源<%s>的权重不能为负", s.Name)
+		}
+	}
+
+	i.federationMu.Lock()
+	defer i.federationMu.Unlock()
+	for _, s := range sources {
+		for _, old := range i.FederationSources {
+			if old.Name == s.Name && old.URL == s.URL {
+				s.LastSyncAt, s.LastFeedAt, s.LastError = old.LastSyncAt, old.LastFeedAt, old.LastError
+				if s.PublicKey == "" {
+					s.PublicKey = old.PublicKey
+				}
+			}
+		}
+	}
+	i.FederationSources = sources
+	return nil
+}
+
+func (i *BanListInfo) fetchFederationFeed(src *BanFederationSource) (*BanFeed, error) {
+	req, err := http.NewRequest(http.MethodGet, src.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if src.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+src.Token)
+	}
+	client := http.Client{Timeout: 15 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("对方返回 %s", resp.Status)
+	}
+	var feed BanFeed
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 32<<20)).Decode(&feed); err != nil {
+		return nil, err
+	}
+	return &feed, nil
+}
+
+// federationSyncDue 是否到了定时同步的时间。集群模式下只由主节点同步，避免同一来源的怒气值被各节点重复计入
+func (i *BanListInfo) federationSyncDue(now time.Time) bool {
+	if i.Parent == nil || i.FederationSyncMinutes <= 0 || !i.Parent.clusterManager().IsLeader() {
+		return false
+	}
+	return now.Sub(i.federationLastSync) >= time.Duration(i.FederationSyncMinutes)*time.Minute
+}
+
+// FederationSyncAll 同步全部启用的来源
+func (i *BanListInfo) FederationSyncAll() {
+	for _, s := range i.FederationSourcesCopy() {
+		if s.Enable {
+			_ = i.FederationSync(s.Name)
+		}
+	}
+}
+
+// FederationSync 拉取并应用一个来源。冲突规则:
+//   - 本地信任的ID不受任何来源影响，仅记录为 ignored
+//   - 来源禁止/警告时计入 权重×本地禁止/警告阈值 的怒气值，等级提升时只补足差额
+//   - 来源撤销时扣回计入的怒气值；若本地已禁止则维持现状，由骰主手动处理
+func (i *BanListInfo) FederationSync(name string) error {
+	i.federationSyncMu.Lock()
+	defer i.federationSyncMu.Unlock()
+
+	i.federationMu.Lock()
+	var src *BanFederationSource
+	for _, s := range i.FederationSources {
+		if s.Name == name {
+			cp := *s
+			src = &cp
+		}
+	}
+	i.federationMu.Unlock()
+	if src == nil {
+		return fmt.Errorf("未找到来源<%s>", name)
+	}
+
+	payload, err := i.fetchAndVerify(src)
+	if err == nil {
+		err = i.applyFederationPayload(src, payload)
+	}
+
+	i.federationMu.Lock()
+	for _, s := range i.FederationSources {
+		if s.Name == name && s.URL == src.URL {
+			s.LastSyncAt = time.Now().Unix()
+			s.LastError = ""
+			if err != nil {
+				s.LastError = err.Error()
+			} else {
+				s.LastFeedAt = payload.GeneratedAt
+				s.PublicKey = src.PublicKey
+			}
+		}
+	}
+	i.federationMu.Unlock()
+	if d := i.Parent; d != nil {
+		if err != nil {
+			d.Logger.Warnf("联合黑名单<%s>同步失败: %v", name, err)
+		}
+		d.MarkModified()
+	}
+	return err
+}
+
+func (i *BanListInfo) fetchAndVerify(src *BanFederationSource) (*BanFeedPayload, error) {
+	feed, err := i.fetchFederationFeed(src)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := feed.Verify(src.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	if src.PublicKey == "" {
+		src.PublicKey = feed.PublicKey
+	}
+	if payload.GeneratedAt < src.LastFeedAt {
+		return nil, errors.New("订阅内容比上次同步的更旧，可能是重放")
+	}
+	if time.Since(time.Unix(payload.GeneratedAt, 0)) > banFederationFeedMaxAge {
+		return nil, errors.New("订阅内容已过期")
+	}
+	return payload, nil
+}
+
+// federationCtx 为计分准备上下文，优先使用同平台的在线帐号，以便执行删除好友等惩罚
+func (i *BanListInfo) federationCtx(uid string) *MsgContext {
+	d := i.Parent
+	if d == nil {
+		return nil
+	}
+	if d.ImSession == nil {
+		return &MsgContext{Dice: d}
+	}
+	platform := strings.Replace(strings.Split(uid, ":")[0], "-Group", "", 1)
+	for _, ep := range d.ImSession.EndPoints {
+		if ep.Platform == platform && ep.Enable {
+			return &MsgContext{Dice: d, EndPoint: ep, Session: d.ImSession}
+		}
+	}
+	return &MsgContext{Dice: d, Session: d.ImSession}
+}
+
+func (i *BanListInfo) applyFederationPayload(src *BanFederationSource, payload *BanFeedPayload) error {
+	d := i.Parent
+	entries, err := service.BanFederationEntryList(d.DBOperator, src.Name, "")
+	if err != nil {
+		return err
+	}
+	existing := map[string]*model.BanFederationEntry{}
+	for _, e := range entries {
+		existing[e.UserID] = e
+	}
+
+	place := banFederationReasonPrefix + ":" + src.Name
+	seen := map[string]bool{}
+	for _, item := range payload.Items {
+		var threshold int64
+		switch item.Rank {
+		case BanRankBanned:
+			threshold = i.ThresholdBan
+		case BanRankWarn:
+			threshold = i.ThresholdWarn
+		default:
+			continue
+		}
+		if item.ID == "" || seen[item.ID] {
+			continue
+		}
+		seen[item.ID] = true
+
+		e := existing[item.ID]
+		if e == nil {
+			e = &model.BanFederationEntry{Source: src.Name, UserID: item.ID}
+		}
+		e.Name = item.Name
+		e.RemoteRank = int(item.Rank)
+		e.Reasons = strings.Join(item.Reasons, ",")
+
+		if local, ok := i.GetByID(item.ID); ok && local.Rank == BanRankTrusted {
+			e.Status = BanFederationIgnored
+		} else {
+			target := int64(src.Weight * float64(threshold))
+			if e.Status != BanFederationApplied {
+				e.Score = 0
+			}
+			if delta := target - e.Score; delta > 0 {
+				reason := fmt.Sprintf("%s<%s>: %s", banFederationReasonPrefix, src.Name, e.Reasons)
+				oldRank := i.NoticeCheckPrepare(item.ID)
+				v := i.AddScoreBase(item.ID, delta, place, reason, i.federationCtx(item.ID))
+				if v.Name == "" || v.Name == item.ID {
+					v.Name = item.Name
+				}
+				if v.Rank != oldRank {
+					d.Logger.Infof("联合黑名单<%s>: %s", src.Name, v.toText(d))
+				}
+				e.Score = target
+			}
+			e.Status = BanFederationApplied
+		}
+		if err := service.BanFederationEntrySave(d.DBOperator, e); err != nil {
+			return err
+		}
+	}
+
+	for uid, e := range existing {
+		if seen[uid] || e.Status == BanFederationWithdrawn {
+			continue
+		}
+		if e.Status == BanFederationApplied && e.Score > 0 {
+			if local, ok := i.GetByID(uid); ok && (local.Rank == BanRankNormal || local.Rank == BanRankWarn) {
+				reason := fmt.Sprintf("%s<%s>: 已撤销", banFederationReasonPrefix, src.Name)
+				i.AddScoreBase(uid, -e.Score, place, reason, i.federationCtx(uid))
+			}
+		}
+		e.Status = BanFederationWithdrawn
+		e.Score = 0
+		if err := service.BanFederationEntrySave(d.DBOperator, e); err != nil {
+			return err
+		}
+	}
+
+	i.SaveChanged(d)
+	return nil
+}
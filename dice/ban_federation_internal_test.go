@@ -0,0 +1,158 @@
+//nolint:testpackage
+package dice
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"sealdice-core/dice/service"
+	"sealdice-core/model"
+	"sealdice-core/utils/constant"
+)
+
+func newBanFederationPeer(t *testing.T) (*BanListInfo, *httptest.Server) {
+	peer := &BanListInfo{}
+	peer.Init()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		feed, err := peer.FederationFeed()
+		if err != nil {
+			t.Error(err)
+		}
+		_ = json.NewEncoder(w).Encode(feed)
+	}))
+	t.Cleanup(srv.Close)
+	return peer, srv
+}
+
+func TestBanFederationSync(t *testing.T) {
+	d, _, _, cleanup := newExecuteNewTestDice(t)
+	defer cleanup()
+	if err := d.DBOperator.GetDataDB(constant.WRITE).AutoMigrate(&model.BanInfo{}, &model.BanFederationEntry{}); err != nil {
+		t.Fatal(err)
+	}
+	banList := d.Config.BanList
+
+	peer, srv := newBanFederationPeer(t)
+	peer.Map.Store("QQ:1", &BanListInfoItem{ID: "QQ:1", Rank: BanRankBanned, Reasons: []string{"刷屏"}})
+	peer.Map.Store("QQ:2", &BanListInfoItem{ID: "QQ:2", Rank: BanRankWarn, Reasons: []string{"禁言骰子"}})
+	peer.Map.Store("QQ:3", &BanListInfoItem{ID: "QQ:3", Rank: BanRankBanned, Reasons: []string{"联合黑名单<别处>: 刷屏"}})
+	peer.Map.Store("QQ:4", &BanListInfoItem{ID: "QQ:4", Rank: BanRankBanned, Reasons: []string{"踢出骰子"}})
+	banList.SetTrustByID("QQ:4", "海豹后台", "骰主后台设置")
+
+	err := banList.SetFederationSources([]*BanFederationSource{{Name: "peer", URL: srv.URL, Token: "secret", Weight: 1, Enable: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 2 { // 重复同步不会重复计分
+		if err := banList.FederationSync("peer"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if v, _ := banList.GetByID("QQ:1"); v == nil || v.Rank != BanRankBanned || v.Score != banList.ThresholdBan {
+		t.Fatalf("QQ:1 should be banned once: %+v", v)
+	}
+	if v, _ := banList.GetByID("QQ:2"); v == nil || v.Rank != BanRankWarn || v.Score != banList.ThresholdWarn {
+		t.Fatalf("QQ:2 should be warned: %+v", v)
+	}
+	if _, ok := banList.GetByID("QQ:3"); ok {
+		t.Fatal("entries learned from federation must not be re-shared")
+	}
+	if v, _ := banList.GetByID("QQ:4"); v.Rank != BanRankTrusted || v.Score != 0 {
+		t.Fatalf("local trust should override federation: %+v", v)
+	}
+	entries, _ := service.BanFederationEntryList(d.DBOperator, "peer", "QQ:4")
+	if len(entries) != 1 || entries[0].Status != BanFederationIgnored {
+		t.Fatalf("unexpected audit entry: %+v", entries)
+	}
+	if src := banList.FederationSourcesCopy()[0]; src.PublicKey != peer.FederationPublicKey() || src.LastError != "" {
+		t.Fatalf("public key should be pinned on first sync: %+v", src)
+	}
+
+	// 来源撤销警告后扣回怒气值
+	peer.Map.Delete("QQ:2")
+	if err := banList.FederationSync("peer"); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := banList.GetByID("QQ:2"); v.Rank != BanRankNormal || v.Score != 0 {
+		t.Fatalf("QQ:2 should be restored after withdrawal: %+v", v)
+	}
+	entries, _ = service.BanFederationEntryList(d.DBOperator, "peer", "QQ:2")
+	if len(entries) != 1 || entries[0].Status != BanFederationWithdrawn {
+		t.Fatalf("unexpected audit entry: %+v", entries)
+	}
+}
+
+func TestBanFederationRejectsUntrustedFeed(t *testing.T) {
+	d, _, _, cleanup := newExecuteNewTestDice(t)
+	defer cleanup()
+	if err := d.DBOperator.GetDataDB(constant.WRITE).AutoMigrate(&model.BanInfo{}, &model.BanFederationEntry{}); err != nil {
+		t.Fatal(err)
+	}
+	banList := d.Config.BanList
+
+	peer, srv := newBanFederationPeer(t)
+	peer.Map.Store("QQ:1", &BanListInfoItem{ID: "QQ:1", Rank: BanRankBanned, Reasons: []string{"刷屏"}})
+
+	// 公钥与订阅时记录的不一致
+	other := &BanListInfo{}
+	other.Init()
+	err := banList.SetFederationSources([]*BanFederationSource{
+		{Name: "pinned", URL: srv.URL, Token: "secret", PublicKey: other.FederationPublicKey(), Weight: 1, Enable: true},
+		{Name: "no-token", URL: srv.URL, Weight: 1, Enable: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	banList.FederationSyncAll()
+	for _, src := range banList.FederationSourcesCopy() {
+		if src.LastError == "" {
+			t.Fatalf("source %s should fail", src.Name)
+		}
+	}
+	if _, ok := banList.GetByID("QQ:1"); ok {
+		t.Fatal("rejected feed must not change the local list")
+	}
+
+	// 篡改内容后签名失效
+	feed, _ := peer.FederationFeed()
+	forged, _ := other.FederationFeed()
+	feed.Payload = forged.Payload
+	if _, err := feed.Verify(""); err == nil {
+		t.Fatal("tampered payload should fail verification")
+	}
+}
+
+func TestBanFederationSyncOnlyOnLeader(t *testing.T) {
+	d, _, _, cleanup := newExecuteNewTestDice(t)
+	defer cleanup()
+	banList := d.Config.BanList
+	now := time.Now()
+	if !banList.federationSyncDue(now) {
+		t.Fatal("standalone node should sync")
+	}
+
+	c := newClusterTestManager(t, d)
+	if banList.federationSyncDue(now) {
+		t.Fatal("follower should not sync")
+	}
+	c.leader.Store(true)
+	if !banList.federationSyncDue(now) {
+		t.Fatal("leader should sync")
+	}
+
+	// 没有 IM 会话时也能准备计分上下文
+	session := d.ImSession
+	d.ImSession = nil
+	defer func() { d.ImSession = session }()
+	if ctx := banList.federationCtx("QQ:1"); ctx == nil || ctx.Dice != d {
+		t.Fatalf("unexpected ctx: %+v", ctx)
+	}
+}
@@ -21,7 +21,9 @@ import (
 	"github.com/samber/lo"
 	ds "github.com/sealdice/dicescript"
 
+	"sealdice-core/dice/charimport"
 	"sealdice-core/dice/docengine"
+	"sealdice-core/model"
 )
 
 type dismissConfirmState struct {
@@ -301,6 +303,10 @@ func (d *Dice) registerCoreCommands() {
 		".ban list ban/warn/trust //只显示被禁用/被警告/信任用户\n" +
 		".ban trust <统一ID> //添加信任\n" +
 		".ban query <统一ID> //查看指定用户拉黑情况\n" +
+		".ban appeal list [all] //查看待处理/全部申诉\n" +
+		".ban appeal show <编号> //查看申诉及处理记录\n" +
+		".ban appeal accept <编号> [<备注>] [--reduce=<怒气值>] //通过申诉，不指定 reduce 时解除拉黑\n" +
+		".ban appeal reject <编号> [<备注>] //驳回申诉\n" +
 		".ban help //查看帮助\n" +
 		"// 统一ID示例: QQ:12345、QQ-Group:12345"
 	cmdBlack := &CmdItemInfo{
@@ -345,7 +351,11 @@ func (d *Dice) registerCoreCommands() {
 					reason = "骰主指令"
 				}
 				(&d.Config).BanList.AddScoreBase(uid, (&d.Config).BanList.ThresholdBan, "骰主指令", reason, ctx)
-				ReplyToSender(ctx, msg, fmt.Sprintf("已将用户/群组 %s 加入黑名单，原因: %s", uid, reason))
+				text := fmt.Sprintf("已将用户/群组 %s 加入黑名单，原因: %s", uid, reason)
+				if ctx.EndPoint.Platform == "QQ" && !strings.Contains(uid, "-Group:") && !ctx.EndPoint.Capabilities().DeleteFriend {
+					text += "\n注意: 当前适配器不支持删除好友，对方仍会留在好友列表中"
+				}
+				ReplyToSender(ctx, msg, text)
 			case "rm", "del":
 				uid = getID()
 				if uid == "" {
@@ -430,6 +440,8 @@ func (d *Dice) registerCoreCommands() {
 					)
 				}
 				ReplyToSender(ctx, msg, text.String())
+			case "appeal":
+				return banAppealCommand(ctx, msg, cmdArgs)
 			default:
 				return CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
 			}
@@ -438,6 +450,8 @@ func (d *Dice) registerCoreCommands() {
 	}
 	d.CmdMap["black"] = cmdBlack
 	d.CmdMap["ban"] = cmdBlack
+	d.CmdMap["appeal"] = cmdAppeal
+	d.CmdMap["申诉"] = cmdAppeal
 
 	helpForFind := ".find/查询 <关键字> // 查找文档。关键字可以多个，用空格分割\n" +
 		".find #<分组> <关键字> // 查找指定分组下的文档。关键字可以多个，用空格分割\n" +
@@ -2261,6 +2275,9 @@ func (d *Dice) registerCoreCommands() {
 
 	helpCh := ".pc new <角色名> // 新建角色并绑卡\n" +
 		".pc tag [<角色名> | <角色序号>] // 当前群绑卡/解除绑卡(不填角色名)\n" +
+		".pc import [--format=<格式>] [--name=<角色名>] // 附带角色卡文件或换行后粘贴 JSON/CSV，导入为新角色并绑卡\n" +
+		".pc export [<角色名> | <角色序号>] [--yaml] [--sheet] // 导出角色快照，--sheet 附带排版好的属性表(仅当前角色)\n" +
+		".pc load-snapshot [--name=<角色名>] [--overwrite] // 附带快照文件或换行后粘贴快照，恢复为角色并绑卡\n" +
 		".pc untagAll [<角色名> | <角色序号>] // 全部群解绑(不填即当前卡)\n" +
 		".pc list // 列出当前角色和序号\n" +
 		".pc rename <新角色名> // 将当前绑定角色改名\n" +
@@ -2276,7 +2293,7 @@ func (d *Dice) registerCoreCommands() {
 		ShortHelp: helpCh,
 		Help:      "角色管理:\n" + helpCh,
 		Solve: func(ctx *MsgContext, msg *Message, cmdArgs *CmdArgs) (result CmdExecuteResult) {
-			cmdArgs.ChopPrefixToArgsWith("list", "lst", "load", "save", "del", "rm", "new", "tag", "untagAll", "rename")
+			cmdArgs.ChopPrefixToArgsWith("list", "lst", "load-snapshot", "load", "save", "del", "rm", "new", "tag", "untagAll", "rename", "import", "export")
 			val1 := cmdArgs.GetArgN(1)
 			am := d.AttrsManager
 
@@ -2379,6 +2396,150 @@ func (d *Dice) registerCoreCommands() {
 					ReplyToSender(ctx, msg, DiceFormatTmpl(ctx, "核心:角色管理_新建_已存在"))
 				}
 
+				if ctx.Player.AutoSetNameTemplate != "" {
+					_, _ = SetPlayerGroupCardByTemplate(ctx, ctx.Player.AutoSetNameTemplate)
+				}
+				return CmdExecuteResult{Matched: true, Solved: true}
+			case "import":
+				var format, name string
+				if kw := cmdArgs.GetKwarg("format"); kw != nil {
+					format = kw.Value
+				}
+				if kw := cmdArgs.GetKwarg("name"); kw != nil {
+					name = kw.Value
+				}
+
+				doImport := func() (*model.AttributesItemModel, *charimport.Result, error) {
+					data, attached, err := pcReadAttachment(msg)
+					if err != nil {
+						return nil, nil, err
+					}
+					if !attached {
+						data = pcPastedData(cmdArgs.RawArgs)
+					}
+					tmpl := ctx.Group.GetCharTemplate(d)
+					ret, err := CharImportPreview(tmpl, data, format)
+					if err != nil {
+						return nil, nil, err
+					}
+					item, err := CharImportCommit(d, ctx.Player.UserID, tmpl, ret, name)
+					return item, ret, err
+				}
+				item, ret, err := doImport()
+				if err != nil {
+					VarSetValueStr(ctx, "$t错误", err.Error())
+					ReplyToSender(ctx, msg, DiceFormatTmpl(ctx, "核心:角色管理_导入失败"))
+					return CmdExecuteResult{Matched: true, Solved: true}
+				}
+
+				lo.Must0(am.CharBind(item.Id, ctx.Group.GroupID, ctx.Player.UserID))
+				setCurPlayerName(item.Name)
+
+				var unmapped []string
+				for index, f := range ret.Unmapped {
+					if index >= 10 {
+						unmapped = append(unmapped, "等")
+						break
+					}
+					unmapped = append(unmapped, f.Path)
+				}
+				VarSetValueStr(ctx, "$t角色名", item.Name)
+				VarSetValueStr(ctx, "$t导入格式", ret.Format)
+				VarSetValueInt64(ctx, "$t导入属性数", int64(len(ret.Attrs)))
+				VarSetValueInt64(ctx, "$t未导入字段数", int64(len(ret.Unmapped)))
+				VarSetValueStr(ctx, "$t未导入字段", strings.Join(unmapped, "、"))
+				ReplyToSender(ctx, msg, DiceFormatTmpl(ctx, "核心:角色管理_导入成功"))
+
+				if ctx.Player.AutoSetNameTemplate != "" {
+					_, _ = SetPlayerGroupCardByTemplate(ctx, ctx.Player.AutoSetNameTemplate)
+				}
+				return CmdExecuteResult{Matched: true, Solved: true}
+			case "export":
+				attrs := lo.Must(am.LoadByCtx(ctx))
+				name := cmdArgs.GetArgN(2)
+				isCurrent := name == ""
+				if name != "" {
+					charId := ""
+					if index, err := strconv.Atoi(name); err == nil && index > 0 {
+						if items, _ := am.GetCharacterList(ctx.Player.UserID); index <= len(items) {
+							charId = items[index-1].Id
+						}
+					}
+					if charId == "" {
+						charId, _ = am.CharIdGetByName(ctx.Player.UserID, name)
+					}
+					if charId == "" {
+						ReplyToSender(ctx, msg, DiceFormatTmpl(ctx, "核心:角色管理_角色不存在"))
+						return CmdExecuteResult{Matched: true, Solved: true}
+					}
+					isCurrent = charId == getBindingId()
+					attrs = lo.Must(am.LoadById(charId))
+				}
+
+				snap := CharSnapshotExport(attrs)
+				if snap.Name == "" {
+					snap.Name = ctx.Player.Name
+				}
+				tmpl := ctx.Group.GetCharTemplate(d)
+				if snap.SheetType == "" {
+					snap.SheetType = tmpl.Name
+				} else if t, ok := d.GameSystemMap.Load(snap.SheetType); ok {
+					tmpl = t
+				}
+				doExport := func() ([]byte, error) {
+					if isCurrent && cmdArgs.GetKwarg("sheet") != nil {
+						sheet, err := charSnapshotSheet(ctx, tmpl)
+						if err != nil {
+							return nil, err
+						}
+						snap.Sheet = sheet
+					}
+					format := "json"
+					if cmdArgs.GetKwarg("yaml") != nil {
+						format = "yaml"
+					}
+					return snap.Marshal(format)
+				}
+				data, err := doExport()
+				if err != nil {
+					VarSetValueStr(ctx, "$t错误", err.Error())
+					ReplyToSender(ctx, msg, DiceFormatTmpl(ctx, "核心:角色管理_快照_导出失败"))
+					return CmdExecuteResult{Matched: true, Solved: true}
+				}
+				ReplyToSender(ctx, msg, string(data))
+				return CmdExecuteResult{Matched: true, Solved: true}
+			case "load-snapshot":
+				var name string
+				if kw := cmdArgs.GetKwarg("name"); kw != nil {
+					name = kw.Value
+				}
+
+				doRestore := func() (*model.AttributesItemModel, error) {
+					data, attached, err := pcReadAttachment(msg)
+					if err != nil {
+						return nil, err
+					}
+					if !attached {
+						data = pcPastedData(cmdArgs.RawArgs)
+					}
+					snap, err := ParseCharSnapshot(data)
+					if err != nil {
+						return nil, err
+					}
+					return CharSnapshotRestore(d, ctx.Player.UserID, snap, name, cmdArgs.GetKwarg("overwrite") != nil)
+				}
+				item, err := doRestore()
+				if err != nil {
+					VarSetValueStr(ctx, "$t错误", err.Error())
+					ReplyToSender(ctx, msg, DiceFormatTmpl(ctx, "核心:角色管理_快照_恢复失败"))
+					return CmdExecuteResult{Matched: true, Solved: true}
+				}
+
+				lo.Must0(am.CharBind(item.Id, ctx.Group.GroupID, ctx.Player.UserID))
+				setCurPlayerName(item.Name)
+				VarSetValueStr(ctx, "$t角色名", item.Name)
+				ReplyToSender(ctx, msg, DiceFormatTmpl(ctx, "核心:角色管理_快照_恢复成功"))
+
 				if ctx.Player.AutoSetNameTemplate != "" {
 					_, _ = SetPlayerGroupCardByTemplate(ctx, ctx.Player.AutoSetNameTemplate)
 				}
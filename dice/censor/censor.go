@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
 	"time"
 
@@ -53,8 +54,19 @@ type Censor struct {
 	FilterRegexStr string // 过滤字符正则
 
 	SensitiveKeys map[string]WordInfo
-	t             *trie
-	filterRegex   *regexp.Regexp
+	RegexRules    map[string]Level    // 正则规则，出现相同规则时按最高级别判断
+	AllowWords    map[string][]string // 放行短语，键为敏感词或正则规则，命中处被放行短语完整覆盖时不算命中
+
+	t           *trie
+	filterRegex *regexp.Regexp
+	regexRules  []regexRule
+	allowWords  map[string][]string
+}
+
+type regexRule struct {
+	pattern string
+	level   Level
+	re      *regexp.Regexp
 }
 
 type Reason int
@@ -75,6 +87,8 @@ type WordFile struct {
 	Key         string
 	Path        string
 	FileCounter *FileCounter
+	RegexCount  int // 正则规则数量，已计入 FileCounter
+	AllowCount  int // 设置了放行短语的词汇数量
 
 	FileType   string
 	Name       string
@@ -166,9 +180,19 @@ type TomlWords struct {
 	Danger  []string `comment:"危险级词表"                                toml:"danger"`
 }
 
+type TomlRegex struct {
+	Ignore  []string `comment:"忽略级正则，没有实际作用" toml:"ignore"`
+	Notice  []string `comment:"提醒级正则"       toml:"notice"`
+	Caution []string `comment:"注意级正则"       toml:"caution"`
+	Warning []string `comment:"警告级正则"       toml:"warning"`
+	Danger  []string `comment:"危险级正则"       toml:"danger"`
+}
+
 type TomlCensorWordFile struct {
-	Meta  TomlMeta  `comment:"元信息，用于填写一些额外的展示内容"                                   toml:"meta"`
-	Words TomlWords `comment:"词表，出现相同词汇时按最高级别判断"                                   toml:"words"`
+	Meta  TomlMeta            `comment:"元信息，用于填写一些额外的展示内容"                                   toml:"meta"`
+	Words TomlWords           `comment:"词表，出现相同词汇时按最高级别判断"                                   toml:"words"`
+	Regex TomlRegex           `comment:"正则规则，使用 Go 正则语法，在去除零宽字符、形近字符替换后的文本上匹配"            toml:"regex"`
+	Allow map[string][]string `comment:"放行短语，键为词表中的词汇或正则规则，值为包含该词但不应拦截的短语"              toml:"allow"`
 }
 
 func (c *Censor) tryPreloadTomlFile(path string) (*WordFile, error) {
@@ -186,7 +210,36 @@ func (c *Censor) tryPreloadTomlFile(path string) (*WordFile, error) {
 		return nil, err
 	}
 
+	// 先检查全部正则，避免词库只加载了一半
+	regexLevels := []struct {
+		patterns []string
+		level    Level
+	}{
+		{tomlFile.Regex.Ignore, Ignore},
+		{tomlFile.Regex.Notice, Notice},
+		{tomlFile.Regex.Caution, Caution},
+		{tomlFile.Regex.Warning, Warning},
+		{tomlFile.Regex.Danger, Danger},
+	}
+	for _, item := range regexLevels {
+		for _, pattern := range item.patterns {
+			if _, err = regexp.Compile(pattern); err != nil {
+				return nil, err
+			}
+		}
+	}
+
 	var counter FileCounter
+	var regexCount int
+	for _, item := range regexLevels {
+		for _, pattern := range item.patterns {
+			c.addRegex(pattern, item.level, &counter)
+			regexCount++
+		}
+	}
+	for word, phrases := range tomlFile.Allow {
+		c.addAllow(word, phrases)
+	}
 	for _, word := range tomlFile.Words.Ignore {
 		c.addWord(word, Ignore, &counter)
 	}
@@ -215,6 +268,8 @@ func (c *Censor) tryPreloadTomlFile(path string) (*WordFile, error) {
 		Key:         generateFileKey(),
 		Path:        path,
 		FileCounter: &counter,
+		RegexCount:  regexCount,
+		AllowCount:  len(tomlFile.Allow),
 		FileType:    "toml",
 		Name:        meta.Name,
 		Authors:     meta.Authors,
@@ -227,7 +282,12 @@ func (c *Censor) tryPreloadTomlFile(path string) (*WordFile, error) {
 }
 
 func (c *Censor) addWord(word string, level Level, counter *FileCounter) {
-	key := strings.ToLower(strings.TrimSpace(word))
+	origin := strings.ToLower(strings.TrimSpace(word))
+	// 与检查文本一样做全角、形近字符等规范化，否则含这些字符的词汇永远无法命中
+	key := normalizeWord(word, c.CaseSensitive, nil)
+	if key == "" {
+		return
+	}
 	counter[level]++
 	if c.CaseSensitive {
 		c.SensitiveKeys[key] = WordInfo{Level: level}
@@ -235,7 +295,7 @@ func (c *Censor) addWord(word string, level Level, counter *FileCounter) {
 		if c.MatchPinyin {
 			// 拼音必须大小写不敏感
 			w := strings.ToLower(key)
-			c.SensitiveKeys[w] = WordInfo{Level: level, Origin: key, Reason: IgnoreCase}
+			c.SensitiveKeys[w] = WordInfo{Level: level, Origin: origin, Reason: IgnoreCase}
 
 			pys := pinyin.LazyPinyin(w, pinyin.Args{
 				Style: pinyin.Normal,
@@ -244,13 +304,29 @@ func (c *Censor) addWord(word string, level Level, counter *FileCounter) {
 				},
 			})
 			pyStr := strings.Join(pys, "")
-			c.SensitiveKeys[strings.ToLower(pyStr)] = WordInfo{Level: level, Origin: key, Reason: PinYin}
+			c.SensitiveKeys[strings.ToLower(pyStr)] = WordInfo{Level: level, Origin: origin, Reason: PinYin}
 		} else {
-			c.SensitiveKeys[strings.ToLower(key)] = WordInfo{Level: level, Origin: key, Reason: IgnoreCase}
+			c.SensitiveKeys[key] = WordInfo{Level: level, Origin: origin, Reason: IgnoreCase}
 		}
 	}
 }
 
+func (c *Censor) addRegex(pattern string, level Level, counter *FileCounter) {
+	if c.RegexRules == nil {
+		c.RegexRules = make(map[string]Level)
+	}
+	counter[level]++
+	c.RegexRules[pattern] = HigherLevel(c.RegexRules[pattern], level)
+}
+
+func (c *Censor) addAllow(word string, phrases []string) {
+	if c.AllowWords == nil {
+		c.AllowWords = make(map[string][]string)
+	}
+	key := strings.TrimSpace(word)
+	c.AllowWords[key] = append(c.AllowWords[key], phrases...)
+}
+
 func (c *Censor) Load() (err error) {
 	if c.FilterRegexStr != "" {
 		c.filterRegex = regexp.MustCompile(c.FilterRegexStr)
@@ -260,34 +336,171 @@ func (c *Censor) Load() (err error) {
 
 	c.t = newTire()
 	if c.SensitiveKeys != nil {
+		// 词汇按检查文本的方式规范化(含过滤正则)后再插入，SensitiveKeys 同步换成规范化后的键以便命中时查找
+		keys := make(map[string]WordInfo, len(c.SensitiveKeys))
 		for key, wordInfo := range c.SensitiveKeys {
-			c.t.Insert(key, wordInfo.Level)
+			nk := normalizeWord(key, c.CaseSensitive, c.filterRegex)
+			if nk == "" {
+				continue
+			}
+			if old, ok := keys[nk]; ok && old.Level >= wordInfo.Level {
+				continue
+			}
+			if wordInfo.Origin == "" && nk != key {
+				wordInfo.Origin = key
+			}
+			keys[nk] = wordInfo
+			c.t.Insert(nk, wordInfo.Level)
 		}
+		c.SensitiveKeys = keys
 	}
-	return nil
+
+	c.regexRules = nil
+	for pattern, level := range c.RegexRules {
+		expr := pattern
+		if !c.CaseSensitive {
+			expr = "(?i)" + expr
+		}
+		re, e := regexp.Compile(expr)
+		if e != nil {
+			err = e
+			continue
+		}
+		c.regexRules = append(c.regexRules, regexRule{pattern: pattern, level: level, re: re})
+	}
+
+	c.allowWords = make(map[string][]string, len(c.AllowWords))
+	for word, phrases := range c.AllowWords {
+		// 词表中的词汇均以小写保存，这里统一按小写对应
+		key := strings.ToLower(word)
+		for _, phrase := range phrases {
+			if p := normalizeWord(phrase, c.CaseSensitive, c.filterRegex); p != "" {
+				c.allowWords[key] = append(c.allowWords[key], p)
+			}
+		}
+	}
+	return err
+}
+
+// MatchSpan 一处命中。Start 与 End 为命中片段在被检查文本中的字符下标，左闭右开
+type MatchSpan struct {
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+	Text  string `json:"text"`  // 原文中的命中片段
+	Word  string `json:"word"`  // 对应的敏感词或正则规则
+	Level Level  `json:"level"` // 命中级别
 }
 
 type CheckResult struct {
 	HighestLevel   Level
 	SensitiveWords map[string]Level
+	Spans          []MatchSpan
 }
 
 func (c *Censor) Check(content string) CheckResult {
-	if c.filterRegex != nil {
-		content = c.filterRegex.ReplaceAllString(content, "")
-	}
-	sensitiveKeys := c.t.Match(content)
+	text := normalize(content, c.CaseSensitive, c.filterRegex)
+	origin := []rune(content)
+	normalized := string(text.runes)
 	sensitiveWords := make(map[string]Level)
 	highestLevel := Ignore
-	for key, level := range sensitiveKeys {
+	var spans []MatchSpan
+
+	allowed := make(map[string][][2]int)
+	isAllowed := func(word string, start int, end int) bool {
+		word = strings.ToLower(word)
+		phrases := c.allowWords[word]
+		if len(phrases) == 0 {
+			return false
+		}
+		ranges, ok := allowed[word]
+		if !ok {
+			offsets := runeOffsets(normalized)
+			for _, phrase := range phrases {
+				for from := 0; from < len(normalized); {
+					idx := strings.Index(normalized[from:], phrase)
+					if idx < 0 {
+						break
+					}
+					b := from + idx
+					ranges = append(ranges, [2]int{offsets[b], offsets[b+len(phrase)]})
+					from = b + len(string([]rune(normalized[b:])[0]))
+				}
+			}
+			allowed[word] = ranges
+		}
+		for _, r := range ranges {
+			if r[0] <= start && end <= r[1] {
+				return true
+			}
+		}
+		return false
+	}
+	addSpan := func(word string, start int, end int, level Level) {
+		spanStart, spanEnd := text.index[start], text.index[end-1]+1
+		spans = append(spans, MatchSpan{
+			Start: spanStart,
+			End:   spanEnd,
+			Text:  string(origin[spanStart:spanEnd]),
+			Word:  word,
+			Level: level,
+		})
 		highestLevel = HigherLevel(highestLevel, level)
-		wordInfo := c.SensitiveKeys[key]
-		sensitiveWords[wordInfo.Origin] = wordInfo.Level
 	}
+
+	if c.t != nil {
+		for _, hit := range c.t.MatchRunes(text.runes) {
+			wordInfo := c.SensitiveKeys[hit.content]
+			word := wordInfo.Origin
+			if word == "" {
+				word = hit.content
+			}
+			if isAllowed(word, hit.start, hit.end) {
+				continue
+			}
+			addSpan(word, hit.start, hit.end, hit.level)
+			sensitiveWords[word] = wordInfo.Level
+		}
+	}
+
+	if len(c.regexRules) > 0 {
+		offsets := runeOffsets(normalized)
+		for _, rule := range c.regexRules {
+			for _, loc := range rule.re.FindAllStringIndex(normalized, -1) {
+				start, end := offsets[loc[0]], offsets[loc[1]]
+				if start == end || isAllowed(rule.pattern, start, end) {
+					continue
+				}
+				addSpan(rule.pattern, start, end, rule.level)
+				sensitiveWords[rule.pattern] = HigherLevel(sensitiveWords[rule.pattern], rule.level)
+			}
+		}
+	}
+
+	sort.SliceStable(spans, func(i, j int) bool {
+		return spans[i].Start < spans[j].Start
+	})
 	return CheckResult{
 		HighestLevel:   highestLevel,
 		SensitiveWords: sensitiveWords,
+		Spans:          spans,
+	}
+}
+
+// AtLeast 只保留不低于 level 的命中，用于群内单独设置的起效级别
+func (r CheckResult) AtLeast(level Level) CheckResult {
+	res := CheckResult{SensitiveWords: make(map[string]Level)}
+	for word, l := range r.SensitiveWords {
+		if l >= level {
+			res.SensitiveWords[word] = l
+		}
+	}
+	for _, span := range r.Spans {
+		if span.Level >= level {
+			res.Spans = append(res.Spans, span)
+			res.HighestLevel = HigherLevel(res.HighestLevel, span.Level)
+		}
 	}
+	return res
 }
 
 func generateFileKey() string {
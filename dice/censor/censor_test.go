@@ -2,6 +2,8 @@
 package censor
 
 import (
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 )
@@ -152,6 +154,103 @@ func TestCensor_addWord_CaseInsensitive(t *testing.T) {
 	}
 }
 
+func TestCensor_Check_NormalizeSpans(t *testing.T) {
+	c := newTestCensor(map[string]Level{"badword": Danger})
+
+	// 零宽字符、全角字母与西里尔字母都应被还原
+	content := "前缀 Ｂ\u200bаdwоrd 后缀"
+	result := c.Check(content)
+	if result.HighestLevel != Danger || len(result.Spans) != 1 {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	span := result.Spans[0]
+	runes := []rune(content)
+	if span.Word != "badword" || span.Text != string(runes[span.Start:span.End]) || span.Start != 3 || span.End != len(runes)-3 {
+		t.Errorf("unexpected span: %+v", span)
+	}
+}
+
+func TestCensor_NormalizedWordList(t *testing.T) {
+	c := &Censor{SensitiveKeys: make(map[string]WordInfo)}
+	var counter FileCounter
+	// 词表中的全角、西里尔、希腊字母按与检查文本相同的方式规范化
+	c.addWord("ＢＡＤ", Danger, &counter)
+	c.addWord("сат", Warning, &counter)
+	c.SensitiveKeys["αpple"] = WordInfo{Level: Notice, Origin: "αpple"}
+	if err := c.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	result := c.Check("bad cat apple")
+	if result.HighestLevel != Danger || len(result.Spans) != 3 {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	for _, word := range []string{"ｂａｄ", "сат", "αpple"} {
+		if _, ok := result.SensitiveWords[word]; !ok {
+			t.Errorf("expected %q in %v", word, result.SensitiveWords)
+		}
+	}
+	if _, ok := c.SensitiveKeys["apple"]; !ok {
+		t.Errorf("SensitiveKeys should use normalized keys: %v", c.SensitiveKeys)
+	}
+}
+
+func TestCensor_Check_RegexAndAllow(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "words.toml")
+	file := `[words]
+warning = ["鸡"]
+
+[regex]
+danger = ["加群\\d{5,}"]
+
+[allow]
+"鸡" = ["鸡蛋", "小鸡炖蘑菇"]
+`
+	if err := os.WriteFile(path, []byte(file), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	c := &Censor{SensitiveKeys: make(map[string]WordInfo)}
+	info, err := c.PreloadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.RegexCount != 1 || info.AllowCount != 1 || info.FileCounter[Danger] != 1 {
+		t.Fatalf("unexpected file info: %+v", info)
+	}
+	if err = c.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	if result := c.Check("今天吃鸡蛋和小鸡炖蘑菇"); result.HighestLevel != Ignore {
+		t.Errorf("allowed phrases should not hit: %+v", result)
+	}
+	result := c.Check("鸡蛋里挑骨头的鸡")
+	if result.HighestLevel != Warning || len(result.Spans) != 1 || result.Spans[0].Start != 7 {
+		t.Errorf("word outside allowed phrase should hit: %+v", result)
+	}
+	result = c.Check("速来加群１２３４５６")
+	if result.HighestLevel != Danger || len(result.Spans) != 1 || result.Spans[0].Text != "加群１２３４５６" {
+		t.Errorf("regex should match normalized text: %+v", result)
+	}
+	if result.AtLeast(Danger).HighestLevel != Danger || c.Check("鸡").AtLeast(Danger).HighestLevel != Ignore {
+		t.Error("AtLeast should drop lower levels")
+	}
+}
+
+func TestCensor_PreloadFile_InvalidRegex(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "words.toml")
+	if err := os.WriteFile(path, []byte("[words]\nnotice = [\"a\"]\n[regex]\nnotice = [\"(\"]\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	c := &Censor{SensitiveKeys: make(map[string]WordInfo)}
+	if _, err := c.PreloadFile(path); err == nil {
+		t.Fatal("invalid regex should fail")
+	}
+	if len(c.SensitiveKeys) != 0 {
+		t.Errorf("nothing should be loaded from a bad file: %v", c.SensitiveKeys)
+	}
+}
+
 // --- Benchmark tests ---
 
 func BenchmarkTrie_Insert(b *testing.B) {
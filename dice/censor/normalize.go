@@ -0,0 +1,96 @@
+package censor
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// homoglyphs 常被用来绕过检查的形近字符，统一换成对应的拉丁字母
+var homoglyphs = map[rune]rune{
+	// 西里尔字母
+	'а': 'a', 'в': 'b', 'е': 'e', 'к': 'k', 'м': 'm', 'н': 'h', 'о': 'o', 'р': 'p',
+	'с': 'c', 'т': 't', 'у': 'y', 'х': 'x', 'і': 'i', 'ј': 'j', 'ѕ': 's', 'ԁ': 'd',
+	'А': 'A', 'В': 'B', 'Е': 'E', 'К': 'K', 'М': 'M', 'Н': 'H', 'О': 'O', 'Р': 'P',
+	'С': 'C', 'Т': 'T', 'У': 'Y', 'Х': 'X', 'І': 'I', 'Ј': 'J', 'Ѕ': 'S',
+	// 希腊字母
+	'α': 'a', 'ο': 'o', 'ν': 'v', 'ι': 'i', 'κ': 'k', 'ρ': 'p', 'τ': 't', 'υ': 'u',
+	'Α': 'A', 'Β': 'B', 'Ε': 'E', 'Ζ': 'Z', 'Η': 'H', 'Ι': 'I', 'Κ': 'K', 'Μ': 'M',
+	'Ν': 'N', 'Ο': 'O', 'Ρ': 'P', 'Τ': 'T', 'Υ': 'Y', 'Χ': 'X',
+}
+
+// normalizeRune 全角转半角、形近字符替换，返回 false 表示该字符应被去掉（零宽字符等不可见格式字符）
+func normalizeRune(r rune, caseSensitive bool) (rune, bool) {
+	if unicode.Is(unicode.Cf, r) {
+		return 0, false
+	}
+	switch {
+	case r == '　':
+		r = ' '
+	case r >= '！' && r <= '～':
+		r -= 0xFEE0
+	}
+	if v, ok := homoglyphs[r]; ok {
+		r = v
+	}
+	if !caseSensitive {
+		r = unicode.ToLower(r)
+	}
+	return r, true
+}
+
+// normalizedText 规范化后的文本，index 记录每个字符在原文中的位置
+type normalizedText struct {
+	runes []rune
+	index []int
+}
+
+func normalize(content string, caseSensitive bool, filter *regexp.Regexp) normalizedText {
+	var n normalizedText
+	for i, r := range []rune(content) {
+		if v, ok := normalizeRune(r, caseSensitive); ok {
+			n.runes = append(n.runes, v)
+			n.index = append(n.index, i)
+		}
+	}
+	if filter == nil {
+		return n
+	}
+
+	// 过滤正则在规范化后的文本上执行，去掉命中的字符
+	s := string(n.runes)
+	locs := filter.FindAllStringIndex(s, -1)
+	if len(locs) == 0 {
+		return n
+	}
+	var filtered normalizedText
+	pos, li := 0, 0
+	for i, r := range n.runes {
+		for li < len(locs) && locs[li][1] <= pos {
+			li++
+		}
+		if li >= len(locs) || pos < locs[li][0] {
+			filtered.runes = append(filtered.runes, r)
+			filtered.index = append(filtered.index, n.index[i])
+		}
+		pos += len(string(r))
+	}
+	return filtered
+}
+
+// normalizeWord 以与检查文本相同的方式规范化词汇，用于放行短语
+func normalizeWord(word string, caseSensitive bool, filter *regexp.Regexp) string {
+	return string(normalize(strings.TrimSpace(word), caseSensitive, filter).runes)
+}
+
+// runeOffsets 字节下标到字符下标的对照，用于换算正则的匹配位置
+func runeOffsets(s string) []int {
+	offsets := make([]int, len(s)+1)
+	n := 0
+	for b := range s {
+		offsets[b] = n
+		n++
+	}
+	offsets[len(s)] = n
+	return offsets
+}
@@ -50,13 +50,32 @@ func (t *trie) Insert(key string, level Level) {
 	t.size++
 }
 
+// trieHit 一次命中，start 与 end 为字符下标
+type trieHit struct {
+	start   int
+	end     int
+	content string
+	level   Level
+}
+
 func (t *trie) Match(text string) (sensitiveWords map[string]Level) {
 	if t.root == nil {
 		return nil
 	}
 
 	sensitiveWords = map[string]Level{}
-	chars := []rune(text)
+	for _, hit := range t.MatchRunes([]rune(text)) {
+		sensitiveWords[hit.content] = hit.level
+	}
+	return sensitiveWords
+}
+
+// MatchRunes 返回每个起始位置上最短的命中
+func (t *trie) MatchRunes(chars []rune) (hits []trieHit) {
+	if t.root == nil {
+		return nil
+	}
+
 	for i := range chars {
 		cur := t.root.findChild(chars[i])
 		if cur == nil {
@@ -66,14 +85,13 @@ func (t *trie) Match(text string) (sensitiveWords map[string]Level) {
 		j := i + 1
 		for ; cur != nil && j < len(chars); j++ {
 			if cur.end {
-				sensitiveWords[cur.content] = cur.level
 				break
 			}
 			cur = cur.findChild(chars[j])
 		}
-		if cur != nil && j == len(chars) && cur.end {
-			sensitiveWords[cur.content] = cur.level
+		if cur != nil && cur.end {
+			hits = append(hits, trieHit{start: i, end: j, content: cur.content, level: cur.level})
 		}
 	}
-	return sensitiveWords
+	return hits
 }
@@ -0,0 +1,170 @@
+package dice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	ds "github.com/sealdice/dicescript"
+
+	"sealdice-core/dice/charimport"
+	"sealdice-core/message"
+	"sealdice-core/model"
+)
+
+const (
+	charImportMaxSize = 1 << 20 // 导入的角色卡文件最大 1MiB
+	charImportTimeout = 10 * time.Second
+)
+
+var (
+	ErrCharImportUnsupported  = errors.New("当前规则模板未声明可导入的角色卡格式")
+	ErrCharImportNameRequired = errors.New("未能从角色卡中读取角色名，请用 --name= 指定")
+	ErrCharImportNameExists   = errors.New("同名角色已存在")
+	ErrCharImportTooLarge     = errors.New("角色卡文件过大")
+)
+
+// charImportResolver 通过模板的别名表将外部字段名转为正式属性名
+func charImportResolver(tmpl *GameSystemTemplate) charimport.Resolver {
+	return func(name string) (string, bool) {
+		name = strings.TrimSpace(name)
+		canonical := tmpl.GetAlias(name)
+		if _, ok := tmpl.AliasMap.Load(strings.ToLower(canonical)); ok {
+			return canonical, true
+		}
+		if _, ok := tmpl.Attrs.Defaults[canonical]; ok {
+			return canonical, true
+		}
+		if _, ok := tmpl.Attrs.DefaultsComputed[canonical]; ok {
+			return canonical, true
+		}
+		return canonical, false
+	}
+}
+
+// CharImportPreview 解析外部角色卡并按模板映射，不写入任何数据。format 为空时自动识别
+func CharImportPreview(tmpl *GameSystemTemplate, data []byte, format string) (*charimport.Result, error) {
+	if tmpl == nil || tmpl.GameSystemTemplateV2 == nil || len(tmpl.Commands.Import) == 0 {
+		return nil, ErrCharImportUnsupported
+	}
+	tmpl.Init()
+
+	source := ""
+	if format != "" {
+		m, ok := tmpl.Commands.Import[format]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", charimport.ErrUnknownFormat, format)
+		}
+		source = m.Source
+		if source == "" {
+			source = charimport.SourceJSON
+		}
+	}
+
+	fields, source, err := charimport.Parse(data, source)
+	if err != nil {
+		return nil, err
+	}
+	if format == "" {
+		var ok bool
+		if format, ok = charimport.Detect(fields, source, tmpl.Commands.Import); !ok {
+			return nil, charimport.ErrUnknownFormat
+		}
+	}
+	return charimport.Apply(fields, format, tmpl.Commands.Import[format], charImportResolver(tmpl)), nil
+}
+
+// charImportValue 数字按数值存储，其余按字符串存储
+func charImportValue(s string) *ds.VMValue {
+	if v, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return ds.NewIntVal(ds.IntType(v))
+	}
+	if v, err := strconv.ParseFloat(s, 64); err == nil {
+		return ds.NewFloatVal(v)
+	}
+	return ds.NewStrVal(s)
+}
+
+// CharImportCommit 按预览结果为用户新建角色卡，name 为空时使用角色卡中的角色名
+func CharImportCommit(d *Dice, userID string, tmpl *GameSystemTemplate, ret *charimport.Result, name string) (*model.AttributesItemModel, error) {
+	if name = strings.TrimSpace(name); name == "" {
+		name = ret.Name
+	}
+	if name == "" {
+		return nil, ErrCharImportNameRequired
+	}
+	am := d.AttrsManager
+	if am.CharCheckExists(userID, name) {
+		return nil, ErrCharImportNameExists
+	}
+
+	item, err := am.CharNew(userID, name, tmpl.Name)
+	if err != nil {
+		return nil, err
+	}
+	attrs, err := am.LoadById(item.Id)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range ret.Attrs {
+		attrs.Store(f.Attr, charImportValue(f.Value))
+	}
+	writeAttrsTemplateVersion(attrs, tmpl.Version)
+	return item, nil
+}
+
+// pcReadAttachment 读取消息中附带的第一个文件，用于 .pc import 与 .pc load-snapshot
+func pcReadAttachment(msg *Message) ([]byte, bool, error) {
+	for _, elem := range msg.Segment {
+		file, ok := elem.(*message.FileElement)
+		if !ok {
+			continue
+		}
+
+		reader := file.Stream
+		if reader == nil {
+			if file.URL == "" {
+				continue
+			}
+			c, cancel := context.WithTimeout(context.Background(), charImportTimeout)
+			defer cancel()
+			req, err := http.NewRequestWithContext(c, http.MethodGet, file.URL, nil)
+			if err != nil {
+				return nil, true, err
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				return nil, true, err
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				return nil, true, fmt.Errorf("下载附件失败: %s", resp.Status)
+			}
+			reader = resp.Body
+		}
+
+		data, err := io.ReadAll(io.LimitReader(reader, charImportMaxSize+1))
+		if err != nil {
+			return nil, true, err
+		}
+		if len(data) > charImportMaxSize {
+			return nil, true, ErrCharImportTooLarge
+		}
+		return data, true, nil
+	}
+	return nil, false, nil
+}
+
+// pcPastedData 取出指令后粘贴的内容：首行有 { 时从 { 开始，否则为指令行之后的各行
+func pcPastedData(rawArgs string) []byte {
+	first, rest, _ := strings.Cut(rawArgs, "\n")
+	if index := strings.Index(first, "{"); index >= 0 {
+		return []byte(rawArgs[index:])
+	}
+	return []byte(rest)
+}
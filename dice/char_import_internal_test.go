@@ -0,0 +1,74 @@
+//nolint:testpackage
+package dice
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"sealdice-core/model"
+	"sealdice-core/utils/constant"
+)
+
+func TestCharImportPreviewFoundry(t *testing.T) {
+	tmpl, err := loadBuiltinTemplate("dnd5e.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := `{"name": "Tav", "system": {"abilities": {"str": {"value": 15, "proficient": 1}, "dex": {"value": 14}},
+		"attributes": {"hp": {"value": 12, "max": 12}, "prof": 2}, "details": {"biography": {"value": "..."}},
+		"skills": {"ath": {"value": 1}}}}`
+	ret, err := CharImportPreview(tmpl, []byte(data), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ret.Format != "foundry" || ret.Name != "Tav" {
+		t.Fatalf("unexpected result: %+v", ret)
+	}
+	attrs := map[string]string{}
+	for _, a := range ret.Attrs {
+		attrs[a.Attr] = a.Value
+	}
+	if attrs["力量"] != "15" || attrs["$stp_力量"] != "1" || attrs["hpmax"] != "12" || attrs["熟练"] != "2" {
+		t.Fatalf("unexpected attrs: %v", attrs)
+	}
+	// 技能只有熟练倍率，不直接导入
+	if len(ret.Unmapped) != 2 || ret.Unmapped[0].Path != "system.details.biography.value" || ret.Unmapped[1].Path != "system.skills.ath.value" {
+		t.Fatalf("unexpected unmapped: %+v", ret.Unmapped)
+	}
+
+	if _, err = CharImportPreview(tmpl, []byte(data), "nope"); err == nil {
+		t.Fatal("expected error for unknown format")
+	}
+}
+
+func TestExecuteNew_PcImportCSV(t *testing.T) {
+	d, ep, adapter, cleanup := newExecuteNewTestDice(t)
+	defer cleanup()
+
+	if err := d.DBOperator.GetDataDB(constant.WRITE).AutoMigrate(&model.AttributesItemModel{}); err != nil {
+		t.Fatalf("AutoMigrate: %v", err)
+	}
+
+	groupID := "QQ-Group:333"
+	d.ImSession.ExecuteNew(ep, newGroupMsg(groupID, "QQ:999", ".pc import --name=调查员\n力量,60\n侦察,70\n头发颜色,黑"))
+	reply, ok := adapter.waitForMsg(2 * time.Second)
+	if !ok {
+		t.Fatal("timeout waiting for reply")
+	}
+	if !strings.Contains(reply, "调查员") || !strings.Contains(reply, "共2项属性") || !strings.Contains(reply, "头发颜色") {
+		t.Fatalf("unexpected reply: %s", reply)
+	}
+
+	charID, _ := d.AttrsManager.CharIdGetByName("QQ:999", "调查员")
+	attrs, err := d.AttrsManager.LoadById(charID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := attrs.LoadX("侦查"); v == nil || v.ToString() != "70" {
+		t.Fatalf("侦查 = %v", v)
+	}
+	if bound, _ := d.AttrsManager.CharGetBindingId(groupID, "QQ:999"); bound != charID {
+		t.Fatalf("card not bound: %s != %s", bound, charID)
+	}
+}
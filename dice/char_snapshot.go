@@ -0,0 +1,163 @@
+package dice
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	ds "github.com/sealdice/dicescript"
+	"gopkg.in/yaml.v3"
+
+	"sealdice-core/model"
+)
+
+const (
+	charSnapshotFormat  = "sealdice-character"
+	charSnapshotVersion = 1
+)
+
+var ErrCharSnapshotInvalid = errors.New("不是有效的角色快照")
+
+// CharSnapshot 可在骰子之间迁移的角色卡快照
+type CharSnapshot struct {
+	Format          string         `json:"format"                    yaml:"format"`
+	Version         int            `json:"version"                   yaml:"version"`
+	Name            string         `json:"name"                      yaml:"name"`
+	SheetType       string         `json:"sheetType"                 yaml:"sheetType"`                 // 规则模板名
+	TemplateVersion string         `json:"templateVersion,omitempty" yaml:"templateVersion,omitempty"` // 导出时角色卡的模板版本($ver)
+	ExportedAt      int64          `json:"exportedAt"                yaml:"exportedAt"`
+	Attrs           map[string]any `json:"attrs"                     yaml:"attrs"`           // 计算属性以 &(表达式) 形式保存
+	Sheet           string         `json:"sheet,omitempty"           yaml:"sheet,omitempty"` // 可选，按模板 st show 设置排版的属性表
+}
+
+// CharSnapshotExport 将角色卡转换为快照，$ver 单独记录在 TemplateVersion 中
+func CharSnapshotExport(attrs *AttributesItem) *CharSnapshot {
+	snap := &CharSnapshot{
+		Format:     charSnapshotFormat,
+		Version:    charSnapshotVersion,
+		Name:       attrs.Name,
+		SheetType:  attrs.SheetType,
+		ExportedAt: time.Now().Unix(),
+		Attrs:      map[string]any{},
+	}
+	attrs.Range(func(key string, value *ds.VMValue) bool {
+		if key == attrsTemplateVersionKey {
+			snap.TemplateVersion = value.ToString()
+			return true
+		}
+		snap.Attrs[key] = vmValueToAny(value)
+		return true
+	})
+	return snap
+}
+
+// Marshal 按 json 或 yaml 输出快照
+func (s *CharSnapshot) Marshal(format string) ([]byte, error) {
+	if format == "yaml" {
+		return yaml.Marshal(s)
+	}
+	return json.Marshal(s)
+}
+
+// ParseCharSnapshot 读取 JSON 或 YAML 格式的快照
+func ParseCharSnapshot(data []byte) (*CharSnapshot, error) {
+	data = bytes.TrimSpace(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")))
+	if len(data) == 0 {
+		return nil, ErrCharSnapshotInvalid
+	}
+
+	snap := &CharSnapshot{}
+	var err error
+	if data[0] == '{' {
+		err = json.Unmarshal(data, snap)
+	} else {
+		err = yaml.Unmarshal(data, snap)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if snap.Format != charSnapshotFormat || snap.Version < 1 {
+		return nil, ErrCharSnapshotInvalid
+	}
+	if snap.Version > charSnapshotVersion {
+		return nil, errors.New("快照版本过新，请升级骰子后再导入")
+	}
+	return snap, nil
+}
+
+// CharSnapshotRestore 将快照恢复为用户的角色卡。name 为空时使用快照中的角色名；
+// 同名角色已存在时，overwrite 为 true 则清空并覆盖原角色，否则报错
+func CharSnapshotRestore(d *Dice, userID string, snap *CharSnapshot, name string, overwrite bool) (*model.AttributesItemModel, error) {
+	if name = strings.TrimSpace(name); name == "" {
+		name = strings.TrimSpace(snap.Name)
+	}
+	if name == "" {
+		return nil, ErrCharImportNameRequired
+	}
+
+	values := make(map[string]*ds.VMValue, len(snap.Attrs))
+	for key, raw := range snap.Attrs {
+		v, err := anyToVMValue(raw)
+		if err != nil {
+			return nil, errors.New("属性 " + key + " 无法恢复: " + err.Error())
+		}
+		values[key] = v
+	}
+
+	am := d.AttrsManager
+	var item *model.AttributesItemModel
+	if am.CharCheckExists(userID, name) {
+		if !overwrite {
+			return nil, ErrCharImportNameExists
+		}
+		id, err := am.CharIdGetByName(userID, name)
+		if err != nil {
+			return nil, err
+		}
+		item = &model.AttributesItemModel{Id: id, Name: name, SheetType: snap.SheetType}
+	} else {
+		var err error
+		if item, err = am.CharNew(userID, name, snap.SheetType); err != nil {
+			return nil, err
+		}
+	}
+
+	attrs, err := am.LoadById(item.Id)
+	if err != nil {
+		return nil, err
+	}
+	attrs.Clear()
+	attrs.SetSheetType(snap.SheetType)
+	for key, v := range values {
+		attrs.Store(key, v)
+	}
+	writeAttrsTemplateVersion(attrs, snap.TemplateVersion)
+	return item, nil
+}
+
+// charSnapshotSheet 按模板的 st show 设置排版当前角色的属性表
+func charSnapshotSheet(mctx *MsgContext, tmpl *GameSystemTemplate) (string, error) {
+	mctx.SystemTemplate = tmpl
+	mctx.Eval(tmpl.InitScript, nil)
+	items, _, err := cmdStGetItemsForShow(mctx, tmpl, nil, 0, &CmdStOverrideInfo{})
+	if err != nil {
+		return "", err
+	}
+
+	itemsPerLine := tmpl.Commands.St.Show.ItemsPerLine
+	if itemsPerLine <= 1 {
+		itemsPerLine = 4
+	}
+	var sb strings.Builder
+	for index, i := range items {
+		sb.WriteString(i)
+		if (index+1)%itemsPerLine == 0 {
+			sb.WriteString("\n")
+		} else {
+			sb.WriteString("\t")
+		}
+	}
+	return strings.TrimSpace(sb.String()), nil
+}
@@ -0,0 +1,96 @@
+//nolint:testpackage
+package dice
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	ds "github.com/sealdice/dicescript"
+
+	"sealdice-core/model"
+	"sealdice-core/utils/constant"
+)
+
+func TestCharSnapshotRoundTrip(t *testing.T) {
+	attrs := &AttributesItem{valueMap: &ds.ValueMap{}, Name: "张三", SheetType: "coc7"}
+	attrs.Store("力量", ds.NewIntVal(60))
+	attrs.Store("备注", ds.NewStrVal("左撇子"))
+	attrs.Store("伤害加值", ds.NewComputedVal("力量/10"))
+	writeAttrsTemplateVersion(attrs, "1.1.0")
+
+	snap := CharSnapshotExport(attrs)
+	if snap.TemplateVersion != "1.1.0" || snap.Attrs[attrsTemplateVersionKey] != nil {
+		t.Fatalf("unexpected version handling: %+v", snap)
+	}
+	for _, format := range []string{"json", "yaml"} {
+		data, err := snap.Marshal(format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		parsed, err := ParseCharSnapshot(data)
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		if parsed.Name != "张三" || parsed.SheetType != "coc7" || parsed.Attrs["伤害加值"] != "&(力量/10)" {
+			t.Fatalf("%s: unexpected snapshot %+v", format, parsed)
+		}
+	}
+
+	if _, err := ParseCharSnapshot([]byte(`{"name": "x"}`)); err != ErrCharSnapshotInvalid {
+		t.Fatalf("expected ErrCharSnapshotInvalid, got %v", err)
+	}
+}
+
+func TestExecuteNew_PcExportAndLoadSnapshot(t *testing.T) {
+	d, ep, adapter, cleanup := newExecuteNewTestDice(t)
+	defer cleanup()
+	if err := d.DBOperator.GetDataDB(constant.WRITE).AutoMigrate(&model.AttributesItemModel{}); err != nil {
+		t.Fatalf("AutoMigrate: %v", err)
+	}
+
+	groupID := "QQ-Group:444"
+	send := func(text string) string {
+		d.ImSession.ExecuteNew(ep, newGroupMsg(groupID, "QQ:999", text))
+		reply, ok := adapter.waitForMsg(2 * time.Second)
+		if !ok {
+			t.Fatalf("timeout waiting for reply to %q", text)
+		}
+		return reply
+	}
+
+	send(".pc new 张三")
+	send(".st 力量60 敏捷70")
+	exported := send(".pc export")
+	if !strings.Contains(exported, `"name":"张三"`) || !strings.Contains(exported, `"力量":60`) {
+		t.Fatalf("unexpected export: %s", exported)
+	}
+
+	if reply := send(".pc load-snapshot\n" + exported); !strings.Contains(reply, "同名角色已存在") {
+		t.Fatalf("expected name conflict, got: %s", reply)
+	}
+	if reply := send(".pc load-snapshot --name=张三备份\n" + exported); !strings.Contains(reply, "张三备份") {
+		t.Fatalf("unexpected restore reply: %s", reply)
+	}
+
+	charID, _ := d.AttrsManager.CharIdGetByName("QQ:999", "张三备份")
+	attrs, err := d.AttrsManager.LoadById(charID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := attrs.LoadX("敏捷"); v == nil || v.ToString() != "70" || attrs.SheetType != "coc7" {
+		t.Fatalf("unexpected restored attrs: %v %s", v, attrs.SheetType)
+	}
+	if reply := send(".pc export --yaml --sheet"); !strings.Contains(reply, "sheet:") || !strings.Contains(reply, "敏捷:70") {
+		t.Fatalf("unexpected yaml export: %s", reply)
+	}
+
+	// 模板卡求值出错时回复错误信息，而不是让指令崩溃
+	tmpl, _ := d.GameSystemMap.Load("coc7")
+	showKeyAs := tmpl.Commands.St.Show.ShowKeyAs
+	defer func() { tmpl.Commands.St.Show.ShowKeyAs = showKeyAs }()
+	tmpl.Commands.St.Show.ShowKeyAs = map[string]string{"力量": "{1+}"}
+	if reply := send(".pc export --sheet"); !strings.Contains(reply, "导出角色快照失败") {
+		t.Fatalf("expected export failure, got: %s", reply)
+	}
+}
@@ -0,0 +1,305 @@
+// Package charimport 将外部角色卡(JSON/CSV)展开为字段，再按规则模板中声明的映射转换为属性
+package charimport
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	SourceJSON = "json"
+	SourceCSV  = "csv"
+)
+
+var (
+	ErrEmptyData     = errors.New("角色卡内容为空")
+	ErrUnknownFormat = errors.New("无法识别角色卡格式")
+	ErrBadCSV        = errors.New("CSV 需为“属性,数值”两列，或首行为属性名、第二行为数值")
+)
+
+// Mapping 规则模板中声明的一种外部格式
+type Mapping struct {
+	Source     string            `json:"source"     yaml:"source"`     // json 或 csv，默认 json
+	Detect     []string          `json:"detect"     yaml:"detect"`     // 存在这些字段时自动识别为该格式
+	NameFields []string          `json:"nameFields" yaml:"nameFields"` // 角色名所在字段，按顺序取第一个非空值
+	Fields     map[string]string `json:"fields"     yaml:"fields"`     // 字段路径 -> 属性名，路径中的 * 匹配一级，属性名写 * 表示取匹配到的部分
+}
+
+// Field 展开后的一个字段，Path 以 . 分隔
+type Field struct {
+	Path  string `json:"path"`
+	Value string `json:"value"`
+}
+
+// MappedField 已映射到属性的字段
+type MappedField struct {
+	Path  string `json:"path"`
+	Attr  string `json:"attr"`
+	Value string `json:"value"`
+}
+
+// Result 导入预览，确认后才写入角色卡
+type Result struct {
+	Format   string         `json:"format"`
+	Name     string         `json:"name"`
+	Attrs    []*MappedField `json:"attrs"`
+	Unmapped []*Field       `json:"unmapped"`
+}
+
+// Resolver 将属性名转换为模板中的正式名称，known 表示模板中确有此属性
+type Resolver func(name string) (canonical string, known bool)
+
+// Parse 按来源类型展开数据，source 为空时自动判断
+func Parse(data []byte, source string) ([]*Field, string, error) {
+	data = bytes.TrimSpace(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")))
+	if len(data) == 0 {
+		return nil, "", ErrEmptyData
+	}
+	if source == "" {
+		source = SourceCSV
+		if data[0] == '{' || data[0] == '[' {
+			source = SourceJSON
+		}
+	}
+	switch source {
+	case SourceJSON:
+		fields, err := FlattenJSON(data)
+		return fields, source, err
+	case SourceCSV:
+		fields, err := FlattenCSV(data)
+		return fields, source, err
+	default:
+		return nil, "", ErrUnknownFormat
+	}
+}
+
+// FlattenJSON 展开 JSON。对象数组中带 name 字段的元素以 name 作为路径，便于按技能名映射
+func FlattenJSON(data []byte) ([]*Field, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	var fields []*Field
+	flattenValue(&fields, "", v)
+	return fields, nil
+}
+
+func joinPath(prefix, key string) string {
+	key = strings.ReplaceAll(key, ".", "_")
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+func flattenValue(fields *[]*Field, path string, v interface{}) {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			flattenValue(fields, joinPath(path, k), val[k])
+		}
+	case []interface{}:
+		for index, item := range val {
+			key := strconv.Itoa(index)
+			if m, ok := item.(map[string]interface{}); ok {
+				if name, ok := m["name"].(string); ok && strings.TrimSpace(name) != "" {
+					key = strings.TrimSpace(name)
+				}
+			}
+			flattenValue(fields, joinPath(path, key), item)
+		}
+	case nil:
+	case json.Number:
+		*fields = append(*fields, &Field{Path: path, Value: val.String()})
+	case string:
+		*fields = append(*fields, &Field{Path: path, Value: val})
+	default:
+		*fields = append(*fields, &Field{Path: path, Value: fmt.Sprint(val)})
+	}
+}
+
+// FlattenCSV 支持两种排布：每行“属性,数值”；或首行为属性名、第二行为数值
+func FlattenCSV(data []byte) ([]*Field, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	var fields []*Field
+	if len(records) == 2 && len(records[0]) > 2 {
+		for index, key := range records[0] {
+			if index >= len(records[1]) {
+				break
+			}
+			if key = strings.TrimSpace(key); key != "" {
+				fields = append(fields, &Field{Path: key, Value: strings.TrimSpace(records[1][index])})
+			}
+		}
+		return fields, nil
+	}
+	for _, row := range records {
+		if len(row) == 0 || (len(row) == 1 && strings.TrimSpace(row[0]) == "") {
+			continue
+		}
+		if len(row) < 2 {
+			return nil, ErrBadCSV
+		}
+		if key := strings.TrimSpace(row[0]); key != "" {
+			fields = append(fields, &Field{Path: key, Value: strings.TrimSpace(row[1])})
+		}
+	}
+	return fields, nil
+}
+
+func hasPath(fields []*Field, path string) bool {
+	for _, f := range fields {
+		if f.Path == path || strings.HasPrefix(f.Path, path+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// Detect 根据 Detect 字段选出匹配的格式名，CSV 选用第一个 source 为 csv 的格式
+func Detect(fields []*Field, source string, mappings map[string]Mapping) (string, bool) {
+	names := make([]string, 0, len(mappings))
+	for name := range mappings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		m := mappings[name]
+		mSource := m.Source
+		if mSource == "" {
+			mSource = SourceJSON
+		}
+		if mSource != source {
+			continue
+		}
+		if source == SourceCSV && len(m.Detect) == 0 {
+			return name, true
+		}
+		if len(m.Detect) == 0 {
+			continue
+		}
+		matched := true
+		for _, path := range m.Detect {
+			if !hasPath(fields, path) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// matchPattern 按 . 分段匹配，* 匹配任意一段，返回 * 匹配到的内容
+func matchPattern(pattern, path string) (string, bool) {
+	ps := strings.Split(pattern, ".")
+	ss := strings.Split(path, ".")
+	if len(ps) != len(ss) {
+		return "", false
+	}
+	var captured string
+	for index, p := range ps {
+		switch {
+		case p == "*":
+			captured = ss[index]
+		case !strings.EqualFold(p, ss[index]):
+			return "", false
+		}
+	}
+	return captured, true
+}
+
+// Apply 将字段按映射转换为属性。精确路径优先于通配；通配得到的属性名需为模板中已知的属性
+func Apply(fields []*Field, format string, m Mapping, resolve Resolver) *Result {
+	ret := &Result{Format: format}
+
+	patterns := make([]string, 0, len(m.Fields))
+	for pattern := range m.Fields {
+		patterns = append(patterns, pattern)
+	}
+	// 通配少的规则优先，同等情况下按字典序保证结果稳定
+	sort.Slice(patterns, func(i, j int) bool {
+		ci, cj := strings.Count(patterns[i], "*"), strings.Count(patterns[j], "*")
+		if ci != cj {
+			return ci < cj
+		}
+		return patterns[i] < patterns[j]
+	})
+
+	nameRank := len(m.NameFields)
+	seen := map[string]int{}
+	for _, f := range fields {
+		if rank := indexOfFold(m.NameFields, f.Path); rank >= 0 {
+			if rank < nameRank && strings.TrimSpace(f.Value) != "" {
+				ret.Name = strings.TrimSpace(f.Value)
+				nameRank = rank
+			}
+			continue
+		}
+
+		attr := ""
+		for _, pattern := range patterns {
+			captured, ok := matchPattern(pattern, f.Path)
+			if !ok {
+				continue
+			}
+			target := m.Fields[pattern]
+			if target == "*" {
+				name, known := resolve(captured)
+				if !known {
+					continue
+				}
+				attr = name
+			} else {
+				attr, _ = resolve(target)
+			}
+			break
+		}
+
+		if attr == "" || f.Value == "" {
+			ret.Unmapped = append(ret.Unmapped, f)
+			continue
+		}
+		// 同一属性被多个字段映射时，后出现的覆盖前者
+		if index, exists := seen[attr]; exists {
+			ret.Unmapped = append(ret.Unmapped, &Field{Path: ret.Attrs[index].Path, Value: ret.Attrs[index].Value})
+			ret.Attrs[index] = &MappedField{Path: f.Path, Attr: attr, Value: f.Value}
+			continue
+		}
+		seen[attr] = len(ret.Attrs)
+		ret.Attrs = append(ret.Attrs, &MappedField{Path: f.Path, Attr: attr, Value: f.Value})
+	}
+	return ret
+}
+
+func indexOfFold(lst []string, s string) int {
+	for index, i := range lst {
+		if strings.EqualFold(i, s) {
+			return index
+		}
+	}
+	return -1
+}
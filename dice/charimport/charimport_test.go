@@ -0,0 +1,117 @@
+package charimport
+
+import (
+	"strings"
+	"testing"
+)
+
+func testResolver(known ...string) Resolver {
+	return func(name string) (string, bool) {
+		for _, k := range known {
+			if strings.EqualFold(k, name) {
+				return k, true
+			}
+		}
+		return name, false
+	}
+}
+
+func TestParseJSONWithNamedArray(t *testing.T) {
+	data := []byte("\xef\xbb\xbf" + `{"name": "Alice", "items": [{"name": "Dodge", "value": 40}, {"value": 5}], "a.b": true}`)
+	fields, source, err := Parse(data, "")
+	if err != nil || source != SourceJSON {
+		t.Fatalf("Parse: %v, source %s", err, source)
+	}
+	got := map[string]string{}
+	for _, f := range fields {
+		got[f.Path] = f.Value
+	}
+	want := map[string]string{
+		"name":              "Alice",
+		"items.Dodge.name":  "Dodge",
+		"items.Dodge.value": "40",
+		"items.1.value":     "5",
+		"a_b":               "true",
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("field %s = %q, want %q", k, got[k], v)
+		}
+	}
+}
+
+func TestParseCSVLayouts(t *testing.T) {
+	rows, _, err := Parse([]byte("力量,50\n敏捷, 60\n"), "")
+	if err != nil || len(rows) != 2 || rows[1].Path != "敏捷" || rows[1].Value != "60" {
+		t.Fatalf("row layout: %v %+v", err, rows)
+	}
+	cols, _, err := Parse([]byte("name,力量,敏捷\n张三,50,60"), SourceCSV)
+	if err != nil || len(cols) != 3 || cols[0].Value != "张三" || cols[2].Value != "60" {
+		t.Fatalf("column layout: %v %+v", err, cols)
+	}
+	if _, _, err = Parse([]byte("力量\n敏捷"), SourceCSV); err != ErrBadCSV {
+		t.Fatalf("expected ErrBadCSV, got %v", err)
+	}
+	if _, _, err = Parse([]byte("  "), ""); err != ErrEmptyData {
+		t.Fatalf("expected ErrEmptyData, got %v", err)
+	}
+}
+
+func TestDetectAndApply(t *testing.T) {
+	mappings := map[string]Mapping{
+		"foundry": {
+			Detect:     []string{"system.abilities"},
+			NameFields: []string{"name"},
+			Fields: map[string]string{
+				"system.abilities.str.value": "力量",
+				"system.abilities.*.value":   "*",
+			},
+		},
+		"csv": {Source: SourceCSV, Fields: map[string]string{"*": "*"}},
+	}
+	fields, source, err := Parse([]byte(`{"name": "Bob", "system": {"abilities": {"str": {"value": 16}, "dex": {"value": 12}, "xyz": {"value": 1}}}}`), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	format, ok := Detect(fields, source, mappings)
+	if !ok || format != "foundry" {
+		t.Fatalf("Detect = %s, %v", format, ok)
+	}
+
+	ret := Apply(fields, format, mappings[format], testResolver("力量", "dex"))
+	if ret.Name != "Bob" {
+		t.Errorf("name = %q", ret.Name)
+	}
+	attrs := map[string]string{}
+	for _, a := range ret.Attrs {
+		attrs[a.Attr] = a.Value
+	}
+	if len(attrs) != 2 || attrs["力量"] != "16" || attrs["dex"] != "12" {
+		t.Errorf("attrs = %v", attrs)
+	}
+	if len(ret.Unmapped) != 1 || ret.Unmapped[0].Path != "system.abilities.xyz.value" {
+		t.Errorf("unmapped = %+v", ret.Unmapped)
+	}
+
+	csvFields, _, _ := Parse([]byte("a,1\nb,2"), "")
+	if format, ok = Detect(csvFields, SourceCSV, mappings); !ok || format != "csv" {
+		t.Fatalf("Detect csv = %s, %v", format, ok)
+	}
+}
+
+func TestApplyDuplicateAttrKeepsLast(t *testing.T) {
+	fields := []*Field{{Path: "hp", Value: "10"}, {Path: "生命值", Value: "12"}}
+	resolve := func(name string) (string, bool) {
+		if name == "hp" {
+			return "生命值", true
+		}
+		return name, name == "生命值"
+	}
+	ret := Apply(fields, "csv", Mapping{Fields: map[string]string{"*": "*"}}, resolve)
+	if len(ret.Attrs) != 1 || ret.Attrs[0].Value != "12" {
+		t.Fatalf("attrs = %+v", ret.Attrs)
+	}
+	if len(ret.Unmapped) != 1 || ret.Unmapped[0].Path != "hp" {
+		t.Fatalf("unmapped = %+v", ret.Unmapped)
+	}
+}
@@ -0,0 +1,534 @@
+package dice
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	ds "github.com/sealdice/dicescript"
+
+	"sealdice-core/dice/service"
+	"sealdice-core/logger"
+	"sealdice-core/model"
+	"sealdice-core/utils"
+	"sealdice-core/utils/constant"
+	"sealdice-core/utils/dboperator/engine"
+	"sealdice-core/utils/dboperator/engine/pgsql"
+)
+
+// 集群模式：多个海豹进程(各自登录不同帐号)共用同一个 MySQL/PostgreSQL 数据库。
+// 群组与属性以行版本做乐观锁，写入成功后发布变更通知，其他节点轮询通知并让对应缓存失效；
+// PostgreSQL 额外使用 LISTEN/NOTIFY 立即唤醒轮询。自动备份、备份清理等定时任务只由持有租约的主节点执行。
+
+const (
+	ClusterEventGroup = "group"
+	ClusterEventAttrs = "attrs"
+	ClusterEventBan   = "ban"
+	// ClusterEventSchedule 定时任务增删，各节点据此更新计划，接任主节点时不会遗漏
+	ClusterEventSchedule = "schedule"
+
+	clusterLeaderLease   = "leader"
+	clusterNotifyChannel = "sealdice_cluster"
+	clusterEventBatch    = 200
+	clusterEventKeep     = 10 * time.Minute
+	clusterNodeKeep      = time.Hour
+	clusterSaveRetries   = 3 // 保存群组冲突时合并重试的次数
+)
+
+var ErrClusterUnsupportedDB = errors.New("集群模式需要使用 MySQL 或 PostgreSQL 数据库")
+
+// ClusterConfig 集群模式配置，保存在 dice.yaml 中
+type ClusterConfig struct {
+	Enable       bool   `json:"enable"       yaml:"enable"`
+	NodeName     string `json:"nodeName"     yaml:"nodeName"`     // 节点名，仅用于展示，默认为主机名
+	PollInterval int    `json:"pollInterval" yaml:"pollInterval"` // 检查变更通知的间隔(秒)，默认 2
+	LeaseTTL     int    `json:"leaseTtl"     yaml:"leaseTtl"`     // 主节点租约时长(秒)，默认 15
+}
+
+type ClusterManager struct {
+	NodeID    string
+	NodeName  string
+	StartedAt int64
+
+	dm           *DiceManager
+	db           engine.DatabaseOperator
+	pollInterval time.Duration
+	leaseTTL     time.Duration
+	leader       atomic.Bool
+	lastEventID  uint64
+	pollLock     sync.Mutex
+	wake         chan struct{}
+	cancel       context.CancelFunc
+	wg           sync.WaitGroup
+}
+
+// ClusterStatus 供 API 展示的集群状态
+type ClusterStatus struct {
+	NodeID   string               `json:"nodeId"`
+	IsLeader bool                 `json:"isLeader"`
+	Leader   string               `json:"leader"`
+	Nodes    []*model.ClusterNode `json:"nodes"`
+}
+
+// NewClusterManager 创建集群管理器，并记下当前最新的变更通知，之后的通知才需要处理
+func NewClusterManager(dm *DiceManager, cfg ClusterConfig) (*ClusterManager, error) {
+	db := dm.Operator
+	if db == nil || (db.Type() != constant.MYSQL && db.Type() != constant.POSTGRESQL) {
+		return nil, ErrClusterUnsupportedDB
+	}
+
+	name := cfg.NodeName
+	if name == "" {
+		name, _ = os.Hostname()
+	}
+	c := &ClusterManager{
+		NodeID:       utils.NewID(),
+		NodeName:     name,
+		StartedAt:    time.Now().Unix(),
+		dm:           dm,
+		db:           db,
+		pollInterval: 2 * time.Second,
+		leaseTTL:     15 * time.Second,
+		wake:         make(chan struct{}, 1),
+	}
+	if cfg.PollInterval > 0 {
+		c.pollInterval = time.Duration(cfg.PollInterval) * time.Second
+	}
+	if cfg.LeaseTTL > 0 {
+		c.leaseTTL = time.Duration(cfg.LeaseTTL) * time.Second
+	}
+	// 续期间隔需明显短于租约时长
+	if c.pollInterval > c.leaseTTL/3 {
+		c.pollInterval = c.leaseTTL / 3
+	}
+
+	var err error
+	if c.lastEventID, err = service.ClusterEventLatestID(db); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Start 登记节点并开始轮询
+func (c *ClusterManager) Start() {
+	for _, d := range c.dm.Dice {
+		if d.AttrsManager != nil {
+			d.AttrsManager.cluster = c
+		}
+	}
+	c.tick()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c.cancel = cancel
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		ticker := time.NewTicker(c.pollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				c.tick()
+			case <-c.wake:
+				c.poll()
+			}
+		}
+	}()
+
+	if pg, ok := c.db.(*pgsql.PGSQLEngine); ok {
+		c.wg.Add(1)
+		go func() {
+			defer c.wg.Done()
+			c.listen(ctx, pg.DSN)
+		}()
+	}
+	logger.M().Infof("集群模式已启动，节点 %s(%s)", c.NodeName, c.NodeID)
+}
+
+// Stop 停止轮询，放弃租约并注销节点
+func (c *ClusterManager) Stop() {
+	if c == nil || c.cancel == nil {
+		return
+	}
+	c.cancel()
+	c.wg.Wait()
+	if c.leader.Load() {
+		_ = service.ClusterLeaseRelease(c.db, clusterLeaderLease, c.NodeID)
+		c.leader.Store(false)
+	}
+	_ = service.ClusterNodeRemove(c.db, c.NodeID)
+}
+
+// IsLeader 是否由本节点执行定时任务。未开启集群模式时总是 true
+func (c *ClusterManager) IsLeader() bool {
+	if c == nil {
+		return true
+	}
+	return c.leader.Load()
+}
+
+// Publish 通知其他节点某项数据已变更。未开启集群模式时什么也不做
+func (c *ClusterManager) Publish(kind string, key string) {
+	if c == nil {
+		return
+	}
+	err := service.ClusterEventPublish(c.db, &model.ClusterEvent{NodeID: c.NodeID, Kind: kind, Key: key})
+	if err != nil {
+		logger.M().Warnf("发布集群变更通知失败 %s %s: %v", kind, key, err)
+		return
+	}
+	if c.db.Type() == constant.POSTGRESQL {
+		_ = c.db.GetDataDB(constant.WRITE).Exec("SELECT pg_notify(?, ?)", clusterNotifyChannel, c.NodeID).Error
+	}
+}
+
+// Status 当前集群状态
+func (c *ClusterManager) Status() (*ClusterStatus, error) {
+	nodes, err := service.ClusterNodeList(c.db)
+	if err != nil {
+		return nil, err
+	}
+	status := &ClusterStatus{NodeID: c.NodeID, IsLeader: c.IsLeader(), Nodes: nodes}
+	lease, err := service.ClusterLeaseGet(c.db, clusterLeaderLease)
+	if err != nil {
+		return nil, err
+	}
+	if lease != nil && lease.ExpiresAt > time.Now().UnixMilli() {
+		status.Leader = lease.Holder
+	}
+	return status, nil
+}
+
+func (c *ClusterManager) tick() {
+	log := logger.M()
+	now := time.Now().Unix()
+	err := service.ClusterNodeHeartbeat(c.db, &model.ClusterNode{
+		ID:          c.NodeID,
+		Name:        c.NodeName,
+		StartedAt:   c.StartedAt,
+		HeartbeatAt: now,
+	})
+	if err != nil {
+		log.Warnf("集群心跳失败: %v", err)
+	}
+
+	isLeader, err := service.ClusterLeaseAcquire(c.db, clusterLeaderLease, c.NodeID, c.leaseTTL)
+	if err != nil {
+		log.Warnf("集群租约续期失败: %v", err)
+		isLeader = false
+	}
+	if c.leader.Swap(isLeader) != isLeader {
+		if isLeader {
+			log.Info("本节点成为集群主节点，开始执行定时任务")
+		} else {
+			log.Info("本节点不再是集群主节点")
+		}
+	}
+
+	c.poll()
+
+	if isLeader {
+		_, _ = service.ClusterEventPruneBefore(c.db, time.Now().Add(-clusterEventKeep).Unix())
+		_, _ = service.ClusterNodePruneBefore(c.db, time.Now().Add(-clusterNodeKeep).Unix())
+	}
+}
+
+// poll 读取并处理其他节点发布的变更通知
+func (c *ClusterManager) poll() {
+	c.pollLock.Lock()
+	defer c.pollLock.Unlock()
+	for {
+		events, err := service.ClusterEventsAfter(c.db, c.lastEventID, clusterEventBatch)
+		if err != nil {
+			logger.M().Warnf("读取集群变更通知失败: %v", err)
+			return
+		}
+		for _, e := range events {
+			c.lastEventID = e.ID
+			if e.NodeID != c.NodeID {
+				c.apply(e)
+			}
+		}
+		if len(events) < clusterEventBatch {
+			return
+		}
+	}
+}
+
+func (c *ClusterManager) apply(e *model.ClusterEvent) {
+	for _, d := range c.dm.Dice {
+		switch e.Kind {
+		case ClusterEventGroup:
+			d.clusterReloadGroup(e.Key, false)
+		case ClusterEventAttrs:
+			if d.AttrsManager != nil {
+				d.AttrsManager.clusterInvalidate(e.Key)
+			}
+		case ClusterEventBan:
+			d.clusterReloadBan(e.Key)
+		case ClusterEventSchedule:
+			if d.Schedule != nil {
+				d.Schedule.clusterReload(e.Key)
+			}
+		}
+	}
+}
+
+// listen 使用 PostgreSQL 的 LISTEN/NOTIFY 在其他节点写入后立即唤醒轮询，断线后自动重连
+func (c *ClusterManager) listen(ctx context.Context, dsn string) {
+	log := logger.M()
+	for ctx.Err() == nil {
+		conn, err := pgx.Connect(ctx, dsn)
+		if err == nil {
+			_, err = conn.Exec(ctx, "LISTEN "+clusterNotifyChannel)
+			for err == nil {
+				var n *pgconn.Notification
+				n, err = conn.WaitForNotification(ctx)
+				if err == nil && n.Payload != c.NodeID {
+					select {
+					case c.wake <- struct{}{}:
+					default:
+					}
+				}
+			}
+			_ = conn.Close(context.Background())
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		log.Warnf("集群通知监听中断，稍后重连: %v", err)
+		select {
+		case <-ctx.Done():
+		case <-time.After(5 * time.Second):
+		}
+	}
+}
+
+// clusterManager 未开启集群模式或测试环境中返回 nil
+func (d *Dice) clusterManager() *ClusterManager {
+	if d.Parent == nil {
+		return nil
+	}
+	return d.Parent.Cluster
+}
+
+// clusterSaveGroup 以行版本为条件保存群组。冲突时以上次加载或保存的数据为基准，把本地改动过的字段合并到
+// 其他节点写入的数据上再重试；没有基准或多次重试仍冲突时，放弃本地改动并重新加载
+func (d *Dice) clusterSaveGroup(c *ClusterManager, groupInfo *GroupInfo, data []byte) error {
+	version := groupInfo.Version
+	for attempt := 0; ; attempt++ {
+		newVersion, err := service.GroupInfoSaveVersioned(d.DBOperator, groupInfo.GroupID, groupInfo.UpdatedAtTime, version, data)
+		if err == nil {
+			if attempt > 0 {
+				// 合并后的数据含有其他节点的改动，同步到内存
+				if merged, errParse := groupInfoFromData(groupInfo.GroupID, newVersion, data); errParse == nil {
+					groupInfo.clusterApply(merged)
+				}
+			}
+			groupInfo.Version = newVersion
+			groupInfo.clusterBase = data
+			c.Publish(ClusterEventGroup, groupInfo.GroupID)
+			return nil
+		}
+		if !errors.Is(err, service.ErrVersionConflict) {
+			return err
+		}
+
+		row, err := service.GroupInfoGet(d.DBOperator, groupInfo.GroupID)
+		if err != nil {
+			return err
+		}
+		var merged []byte
+		if row != nil && groupInfo.clusterBase != nil && attempt < clusterSaveRetries {
+			merged, err = mergeGroupData(groupInfo.clusterBase, data, row.Data)
+		}
+		if merged == nil || err != nil {
+			d.Logger.Warnf("群组 %s 已被其他节点修改且无法合并，放弃本地改动并重新加载", groupInfo.GroupID)
+			d.clusterReloadGroup(groupInfo.GroupID, true)
+			return nil
+		}
+		d.Logger.Infof("群组 %s 已被其他节点修改，合并本地改动后重新保存", groupInfo.GroupID)
+		data, version = merged, row.Version
+	}
+}
+
+// mergeGroupData 按顶层字段三方合并群组数据：相对 base 被本地改动过的字段以本地为准，其余采用 remote
+func mergeGroupData(base, local, remote []byte) ([]byte, error) {
+	var b, l, r map[string]json.RawMessage
+	if err := json.Unmarshal(base, &b); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(local, &l); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(remote, &r); err != nil {
+		return nil, err
+	}
+	if r == nil {
+		r = map[string]json.RawMessage{}
+	}
+	for k, v := range l {
+		if old, ok := b[k]; !ok || !bytes.Equal(old, v) {
+			r[k] = v
+		}
+	}
+	for k := range b {
+		if _, ok := l[k]; !ok {
+			delete(r, k)
+		}
+	}
+	return json.Marshal(r)
+}
+
+// clusterReloadGroup 从数据库重新加载群组。force 为 false 时，有未保存改动或已是最新版本则跳过
+func (d *Dice) clusterReloadGroup(groupID string, force bool) {
+	if d.ImSession == nil || d.ImSession.ServiceAtNew == nil {
+		return
+	}
+	if !force && d.DirtyGroups != nil {
+		if _, dirty := d.DirtyGroups.Load(groupID); dirty {
+			return
+		}
+	}
+	row, err := service.GroupInfoGet(d.DBOperator, groupID)
+	if err != nil {
+		d.Logger.Warnf("重新加载群组 %s 失败: %v", groupID, err)
+		return
+	}
+	if row == nil {
+		return
+	}
+	cur, exists := d.ImSession.ServiceAtNew.Load(groupID)
+	if exists && !force && cur.Version >= row.Version {
+		return
+	}
+	groupInfo, err := groupInfoFromData(groupID, row.Version, row.Data)
+	if err != nil {
+		d.Logger.Warnf("重新加载群组 %s 失败: %v", groupID, err)
+		return
+	}
+	if exists {
+		// 就地更新，仍持有旧指针的代码也能看到新数据；群员等运行时状态保持不变
+		cur.clusterApply(groupInfo)
+		cur.Version = row.Version
+		cur.clusterBase = row.Data
+		return
+	}
+	groupInfo.clusterBase = row.Data
+	d.ImSession.ServiceAtNew.Store(groupID, groupInfo)
+}
+
+// clusterApply 将从数据库读出的群组数据复制到当前对象，只复制会持久化的字段
+func (g *GroupInfo) clusterApply(src *GroupInfo) {
+	g.extInitMu.Lock()
+	g.activatedExtList = src.activatedExtList
+	g.InactivatedExtSet = src.InactivatedExtSet
+	g.ExtAppliedVersion = src.ExtAppliedVersion
+	// 扩展列表需要重新与已注册的扩展对应
+	atomic.StoreInt64(&g.ExtAppliedTime, 0)
+	g.extInitMu.Unlock()
+
+	g.Active = src.Active
+	g.GuildID = src.GuildID
+	g.ChannelID = src.ChannelID
+	g.GroupName = src.GroupName
+	g.DiceIDActiveMap = src.DiceIDActiveMap
+	g.DiceIDExistsMap = src.DiceIDExistsMap
+	g.BotList = src.BotList
+	g.DiceSideNum = src.DiceSideNum
+	g.DiceSideExpr = src.DiceSideExpr
+	g.System = src.System
+	g.HelpPackages = src.HelpPackages
+	g.CocRuleIndex = src.CocRuleIndex
+	g.LogCurName = src.LogCurName
+	g.LogOn = src.LogOn
+	g.RecentDiceSendTime = src.RecentDiceSendTime
+	g.ShowGroupWelcome = src.ShowGroupWelcome
+	g.GroupWelcomeMessage = src.GroupWelcomeMessage
+	g.EnteredTime = src.EnteredTime
+	g.InviteUserID = src.InviteUserID
+	g.DefaultHelpGroup = src.DefaultHelpGroup
+	g.CensorLevel = src.CensorLevel
+	g.PlayerGroups = src.PlayerGroups
+
+	rngStateLock.Lock()
+	g.RNG = src.RNG
+	rngStateLock.Unlock()
+}
+
+// clusterReloadBan 从数据库重新加载黑名单项，本地有未保存改动时跳过
+func (d *Dice) clusterReloadBan(id string) {
+	banMap := d.Config.BanList.Map
+	if banMap == nil {
+		return
+	}
+	if cur, ok := banMap.Load(id); ok && cur.UpdatedAt != 0 {
+		return
+	}
+	row, err := service.BanItemGet(d.DBOperator, id)
+	if err != nil {
+		d.Logger.Warnf("重新加载黑名单 %s 失败: %v", id, err)
+		return
+	}
+	if row == nil {
+		banMap.Delete(id)
+		return
+	}
+	var v BanListInfoItem
+	if err = json.Unmarshal(row.Data, &v); err == nil {
+		v.BanUpdatedAt = int64(row.BanUpdatedAt)
+		banMap.Store(id, &v)
+	}
+}
+
+// clusterSave 逐条以行版本为条件保存属性，冲突的条目从缓存中移除，下次使用时读取其他节点写入的数据
+func (am *AttrsManager) clusterSave(items []*AttributesItem) error {
+	var firstErr error
+	for _, i := range items {
+		// 持锁取得一致的快照，写入期间的新改动会让 modSeq 变化，不会被误标为已保存
+		i.lock.Lock()
+		rawData, err := ds.NewDictVal(i.valueMap).V().ToJSON()
+		name, sheetType, baseVersion, seq := i.Name, i.SheetType, i.version, i.modSeq
+		i.lock.Unlock()
+		if err != nil {
+			am.logger.Errorf("序列化属性数据出错 %s: %v", i.ID, err)
+			continue
+		}
+		version, err := service.AttrsPutByIdVersioned(am.db, i.ID, rawData, name, sheetType, baseVersion)
+		if errors.Is(err, service.ErrVersionConflict) {
+			am.logger.Warnf("属性 %s 已被其他节点修改，放弃本地改动", i.ID)
+			am.m.Delete(i.ID)
+			continue
+		}
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		i.lock.Lock()
+		i.version = version
+		if i.modSeq == seq {
+			i.IsSaved = true
+		}
+		i.lock.Unlock()
+		am.cluster.Publish(ClusterEventAttrs, i.ID)
+	}
+	return firstErr
+}
+
+// clusterInvalidate 其他节点修改了属性，丢弃本地已保存的缓存
+func (am *AttrsManager) clusterInvalidate(id string) {
+	if i, ok := am.m.Load(id); ok && i.IsSaved {
+		am.m.Delete(id)
+	}
+}
@@ -0,0 +1,155 @@
+//nolint:testpackage
+package dice
+
+import (
+	"strings"
+	"testing"
+
+	ds "github.com/sealdice/dicescript"
+
+	"sealdice-core/dice/service"
+	"sealdice-core/model"
+	"sealdice-core/utils/constant"
+)
+
+// newClusterTestManager 绕过数据库类型检查，在测试用的 SQLite 上模拟集群节点
+func newClusterTestManager(t *testing.T, d *Dice) *ClusterManager {
+	t.Helper()
+	err := d.DBOperator.GetDataDB(constant.WRITE).AutoMigrate(
+		&model.ClusterNode{}, &model.ClusterLease{}, &model.ClusterEvent{},
+		&model.GroupInfo{}, &model.AttributesItemModel{}, &model.BanInfo{},
+	)
+	if err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	c := &ClusterManager{NodeID: "self", dm: d.Parent, db: d.DBOperator}
+	d.Parent.Cluster = c
+	d.AttrsManager.cluster = c
+	t.Cleanup(func() { d.AttrsManager.cluster = nil })
+	return c
+}
+
+func peerPublish(t *testing.T, d *Dice, kind string, key string) {
+	t.Helper()
+	err := service.ClusterEventPublish(d.DBOperator, &model.ClusterEvent{NodeID: "peer", Kind: kind, Key: key})
+	if err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+}
+
+func attrsJSON(t *testing.T, hp int) []byte {
+	t.Helper()
+	m := &ds.ValueMap{}
+	m.Store("hp", ds.NewIntVal(ds.IntType(hp)))
+	data, err := ds.NewDictVal(m).V().ToJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestClusterPeerChangesInvalidateCaches(t *testing.T) {
+	d, _, _, cleanup := newExecuteNewTestDice(t)
+	defer cleanup()
+	c := newClusterTestManager(t, d)
+	op := d.DBOperator
+	groupID := "QQ-Group:1"
+
+	if _, err := service.GroupInfoSaveVersioned(op, groupID, 1, 0, []byte(`{"groupName":"旧群名"}`)); err != nil {
+		t.Fatal(err)
+	}
+	d.clusterReloadGroup(groupID, true)
+	group, _ := d.ImSession.ServiceAtNew.Load(groupID)
+	group.Players = new(SyncMap[string, *GroupPlayerInfo])
+	group.Players.Store("QQ:1", &GroupPlayerInfo{Name: "玩家"})
+
+	attrs, _ := d.AttrsManager.LoadById("c1")
+	attrs.Store("hp", ds.NewIntVal(1))
+	if err := d.AttrsManager.CheckForSave(); err != nil || attrs.version != 1 {
+		t.Fatalf("save attrs: version %d, %v", attrs.version, err)
+	}
+
+	// 其他节点修改数据后发布通知
+	if _, err := service.GroupInfoSaveVersioned(op, groupID, 2, 1, []byte(`{"groupName":"新群名"}`)); err != nil {
+		t.Fatal(err)
+	}
+	peerPublish(t, d, ClusterEventGroup, groupID)
+	if _, err := service.AttrsPutByIdVersioned(op, "c1", attrsJSON(t, 2), "", "", 1); err != nil {
+		t.Fatal(err)
+	}
+	peerPublish(t, d, ClusterEventAttrs, "c1")
+	if err := service.BanItemSave(op, "QQ:666", 1, 1, []byte(`{"ID":"QQ:666","rank":-30}`)); err != nil {
+		t.Fatal(err)
+	}
+	peerPublish(t, d, ClusterEventBan, "QQ:666")
+
+	c.poll()
+
+	group, _ = d.ImSession.ServiceAtNew.Load(groupID)
+	if group.GroupName != "新群名" || group.Version != 2 {
+		t.Fatalf("group not reloaded: %s v%d", group.GroupName, group.Version)
+	}
+	if _, ok := group.Players.Load("QQ:1"); !ok {
+		t.Fatal("players should survive a reload")
+	}
+	attrs, _ = d.AttrsManager.LoadById("c1")
+	if v := attrs.Load("hp"); v == nil || v.ToString() != "2" || attrs.version != 2 {
+		t.Fatalf("attrs not reloaded: %v v%d", v, attrs.version)
+	}
+	if _, ok := d.Config.BanList.Map.Load("QQ:666"); !ok {
+		t.Fatal("ban item not reloaded")
+	}
+}
+
+func TestClusterSaveConflictMergesLocalChanges(t *testing.T) {
+	d, _, _, cleanup := newExecuteNewTestDice(t)
+	defer cleanup()
+	c := newClusterTestManager(t, d)
+	op := d.DBOperator
+	groupID := "QQ-Group:2"
+
+	if _, err := service.GroupInfoSaveVersioned(op, groupID, 1, 0, []byte(`{"groupName":"A","system":"coc7"}`)); err != nil {
+		t.Fatal(err)
+	}
+	d.clusterReloadGroup(groupID, true)
+	group, _ := d.ImSession.ServiceAtNew.Load(groupID)
+	if _, err := service.GroupInfoSaveVersioned(op, groupID, 2, 1, []byte(`{"groupName":"A","system":"dnd5e"}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	// 本地仍持有版本 1，保存时发现冲突：本地改的群名与其他节点改的规则都应保留
+	group.GroupName = "C"
+	group.UpdatedAtTime = 3
+	if err := d.clusterSaveGroup(c, group, []byte(`{"groupName":"C","system":"coc7"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if group.GroupName != "C" || group.System != "dnd5e" || group.Version != 3 {
+		t.Fatalf("expected merged data, got %s %s v%d", group.GroupName, group.System, group.Version)
+	}
+	row, _ := service.GroupInfoGet(op, groupID)
+	if !strings.Contains(string(row.Data), `"groupName":"C"`) || !strings.Contains(string(row.Data), `"system":"dnd5e"`) {
+		t.Fatalf("unexpected saved data: %s", row.Data)
+	}
+
+	// 其他节点的修改就地写入已有对象，持有旧指针的代码也能看到
+	if _, err := service.GroupInfoSaveVersioned(op, groupID, 4, 3, []byte(`{"groupName":"D"}`)); err != nil {
+		t.Fatal(err)
+	}
+	d.clusterReloadGroup(groupID, false)
+	if cur, _ := d.ImSession.ServiceAtNew.Load(groupID); cur != group || group.GroupName != "D" || group.Version != 4 {
+		t.Fatalf("group should be updated in place: %s v%d", group.GroupName, group.Version)
+	}
+
+	attrs, _ := d.AttrsManager.LoadById("c2")
+	attrs.Store("hp", ds.NewIntVal(1))
+	if _, err := service.AttrsPutByIdVersioned(op, "c2", attrsJSON(t, 5), "", "", 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.AttrsManager.CheckForSave(); err != nil {
+		t.Fatal(err)
+	}
+	attrs, _ = d.AttrsManager.LoadById("c2")
+	if v := attrs.Load("hp"); v == nil || v.ToString() != "5" {
+		t.Fatalf("expected peer attrs, got %v", v)
+	}
+}
@@ -344,7 +344,7 @@ func (cmdArgs *CmdArgs) commandParseNew(ctx *MsgContext, msg *Message, isParseEx
 
 	// === 第二步：解析@信息 ===
 	// 分析消息段中的@元素，设置机器人被@状态
-	parseAtInfo(cmdArgs, msg, ctx.EndPoint.UserID)
+	parseAtInfo(cmdArgs, msg, selfUIDForMessage(ctx.EndPoint, msg))
 
 	// === 第三步：处理特殊执行次数和命令前缀 ===
 	restText := strings.TrimSpace(rawCmd)
@@ -492,6 +492,18 @@ func extractResultFromSegments(segments []message.IMessageElement) string {
 	return cqMessage.String()
 }
 
+// selfUIDForMessage 返回这条消息所在平台上骰子自己的账号，用于判断是否被@
+// QQ频道使用临时的 TmpUID，官方频道的账号前缀与机器人本体不同
+func selfUIDForMessage(ep *EndPointInfo, msg *Message) string {
+	if msg.TmpUID != "" {
+		return msg.TmpUID
+	}
+	if msg.Platform == "OpenQQCH" {
+		return "OpenQQCH:" + strings.TrimPrefix(ep.UserID, "OpenQQ:")
+	}
+	return ep.UserID
+}
+
 // parseAtInfo 解析@信息，设置相关的@状态标志
 func parseAtInfo(cmdArgs *CmdArgs, msg *Message, botUserID string) {
 	// 初始化@状态
@@ -713,28 +725,36 @@ func CQParse(cmd string) *CQCommand {
 	}
 }
 
-func AtParse(cmd string, prefix string) (string, []*AtInfo) {
+// atPatternForPlatform 各平台文本消息中@的写法，第一个分组为被@的账号，未知平台返回 nil
+func atPatternForPlatform(platform string) *regexp.Regexp {
 	// gocq的@:		[CQ:at,qq=3604749540]
 	// discordGo的@:	<@1048209604938563736>
-	ret := make([]*AtInfo, 0)
-	re := regexp.MustCompile("")
-	switch prefix {
+	switch platform {
 	case "QQ":
-		re = regexp.MustCompile(`\[CQ:at,qq=(\d+)(?:,name=(?:.*?))?\]`)
+		return regexp.MustCompile(`\[CQ:at,qq=(\d+)(?:,name=(?:.*?))?\]`)
 	case "OpenQQ", "OpenQQCH":
-		re = regexp.MustCompile(`<@!?(\S+?)>`)
+		return regexp.MustCompile(`<@!?(\S+?)>`)
 	case "DISCORD":
-		re = regexp.MustCompile(`<@(\d+?)>`)
+		return regexp.MustCompile(`<@(\d+?)>`)
 	case "KOOK":
-		re = regexp.MustCompile(`\(met\)(\d+?)\(met\)`)
+		return regexp.MustCompile(`\(met\)(\d+?)\(met\)`)
 	case "TG":
-		re = regexp.MustCompile(`tg:\/\/user\?id=(\d+)`)
+		return regexp.MustCompile(`tg:\/\/user\?id=(\d+)`)
 	case "DODO":
-		re = regexp.MustCompile(`<@\!(\d+?)>`)
+		return regexp.MustCompile(`<@\!(\d+?)>`)
 	case "SLACK":
-		re = regexp.MustCompile(`<@(.+?)>`)
+		return regexp.MustCompile(`<@(.+?)>`)
 	case "SEALCHAT":
-		re = regexp.MustCompile(`<@(\S+?)>`)
+		return regexp.MustCompile(`<@(\S+?)>`)
+	}
+	return nil
+}
+
+func AtParse(cmd string, prefix string) (string, []*AtInfo) {
+	ret := make([]*AtInfo, 0)
+	re := atPatternForPlatform(prefix)
+	if re == nil {
+		return cmd, ret
 	}
 
 	m := re.FindAllStringSubmatch(cmd, -1)
@@ -0,0 +1,461 @@
+package dice
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+
+	ds "github.com/sealdice/dicescript"
+)
+
+// 战斗轮数据存放于群属性中，沿用旧版先攻列表的键名，以便升级后保留原有数据
+const (
+	combatListKey    = "riList"
+	combatTurnKey    = "$g回合数"
+	combatInitValKey = "$g当前回合先攻值"
+	combatRoundKey   = "$g战斗轮数"
+)
+
+const NULL_INIT_VAL = math.MaxInt32 // 不使用 MAX_INT64 以保证 JS 环境使用时不会出现潜在问题
+
+// CombatCondition 单位身上的状态，Rounds 为剩余轮数，0 表示持续到手动移除
+type CombatCondition struct {
+	Name   string `jsbind:"name"   json:"name"`
+	Rounds int64  `jsbind:"rounds" json:"rounds"`
+}
+
+// Combatant 先攻列表中的一个单位
+type Combatant struct {
+	Name       string             `jsbind:"name"       json:"name"`
+	Val        int64              `jsbind:"val"        json:"val"` // 先攻值
+	Detail     string             `jsbind:"detail"     json:"detail"`
+	UID        string             `jsbind:"uid"        json:"uid"` // 对应的玩家，NPC 为空
+	Conditions []*CombatCondition `jsbind:"conditions" json:"conditions"`
+}
+
+type CombatantList []*Combatant
+
+func (lst CombatantList) Len() int {
+	return len(lst)
+}
+func (lst CombatantList) Swap(i, j int) {
+	lst[i], lst[j] = lst[j], lst[i]
+}
+func (lst CombatantList) Less(i, j int) bool {
+	if lst[i].Val == lst[j].Val {
+		return lst[i].Name > lst[j].Name
+	}
+	return lst[i].Val > lst[j].Val
+}
+
+func (lst CombatantList) GetExists(name string) *Combatant {
+	for _, i := range lst {
+		if i.Name == name {
+			return i
+		}
+	}
+	return nil
+}
+
+/**
+Note(Szzrain) at 2025-3-23:
+这里解释一下战斗轮机制运行的原理。
+1，首先，在先攻列表为空时，添加任何单位会将 {$g当前回合先攻值} 设定为 INT_MAX 但如果先攻列表不为空，那么添加任何单位都不会更改 {$g当前回合先攻值}
+2. 只有在进行 .init ed 时才会将 {$g当前回合先攻值} 设定为新单位的先攻值
+3. 在进行 .init del 时，如果删除的单位是当前回合的单位，那么 {$g当前回合先攻值} 会设定为下一个单位的先攻值，如果删除的单位不是当前回合的单位，那么 {$g当前回合先攻值} 不会改变
+4. 在新一轮时，会将 {$g当前回合先攻值} 设定为下一个单位的先攻值，而不是 INT_MAX
+5. 在进行 .init clr 时，会将 {$g当前回合先攻值} 设定为 INT_MAX 如果 .init del 删除了最后一个单位，那么 {$g当前回合先攻值} 会设定为 INT_MAX
+6. {$g回合数} 会记录当前是第几回合，用于先攻值相同时的辅助处理。但是在添加/删除单位时 {$g当前回合先攻值} 的判断优先度更高
+*/
+
+// CombatTracker 与规则无关的战斗轮追踪器，每个群一份，存放在群属性中，重启后依然保留
+type CombatTracker struct {
+	GroupID    string        `jsbind:"groupId"    json:"groupId"`
+	Round      int64         `jsbind:"round"      json:"round"`      // 战斗轮数，从 1 开始，列表为空时为 0
+	Turn       int64         `jsbind:"turn"       json:"turn"`       // 当前行动单位的下标，即 $g回合数
+	CurInitVal int64         `jsbind:"curInitVal" json:"curInitVal"` // 当前回合先攻值
+	Combatants CombatantList `jsbind:"combatants" json:"combatants"`
+}
+
+// combatLock 保护战斗轮的“读取-修改-写回”，避免同时进行的 .ri 互相覆盖
+var combatLock sync.Mutex
+
+// LoadCombatTracker 从群属性中加载战斗轮，只用于读取，修改请使用 UpdateCombatTracker
+func LoadCombatTracker(d *Dice, group *GroupInfo) *CombatTracker {
+	combatLock.Lock()
+	defer combatLock.Unlock()
+	return loadCombatTracker(d, group.GroupID)
+}
+
+// UpdateCombatTracker 在锁内加载战斗轮并交给 fn 修改，fn 返回 true 时写回群属性
+func UpdateCombatTracker(d *Dice, group *GroupInfo, fn func(t *CombatTracker) bool) *CombatTracker {
+	combatLock.Lock()
+	defer combatLock.Unlock()
+	t := loadCombatTracker(d, group.GroupID)
+	if fn(t) {
+		t.save(d)
+	}
+	return t
+}
+
+func loadCombatTracker(d *Dice, groupID string) *CombatTracker {
+	t := &CombatTracker{GroupID: groupID, CurInitVal: NULL_INIT_VAL}
+	attrs, err := d.AttrsManager.LoadById(groupID)
+	if err != nil {
+		return t
+	}
+
+	lst := attrs.Load(combatListKey)
+	turn := attrs.Load(combatTurnKey)
+	initVal := attrs.Load(combatInitValKey)
+	round := attrs.Load(combatRoundKey)
+
+	readInt := func(v *ds.VMValue, def int64) int64 {
+		if v == nil {
+			return def
+		}
+		ret, ok := v.ReadInt()
+		if !ok {
+			return def
+		}
+		return int64(ret)
+	}
+	t.Turn = readInt(turn, 0)
+	t.CurInitVal = readInt(initVal, NULL_INIT_VAL)
+	t.Round = readInt(round, 0)
+
+	if lst == nil || lst.TypeId != ds.VMTypeArray {
+		return t
+	}
+	for _, i := range lst.MustReadArray().List {
+		if i.TypeId != ds.VMTypeDict {
+			continue
+		}
+		dd := i.MustReadDictData()
+		readStr := func(key string) string {
+			v, ok := dd.Dict.Load(key)
+			if !ok {
+				return ""
+			}
+			return v.ToString()
+		}
+		readDictInt := func(key string) int64 {
+			v, _ := dd.Dict.Load(key)
+			return readInt(v, 0)
+		}
+
+		c := &Combatant{
+			Name:   readStr("name"),
+			Val:    readDictInt("val"),
+			UID:    readStr("uid"),
+			Detail: readStr("detail"),
+		}
+		if conds, ok := dd.Dict.Load("conditions"); ok && conds.TypeId == ds.VMTypeArray {
+			for _, j := range conds.MustReadArray().List {
+				if j.TypeId != ds.VMTypeDict {
+					continue
+				}
+				cd := j.MustReadDictData()
+				name, _ := cd.Dict.Load("name")
+				rounds, _ := cd.Dict.Load("rounds")
+				if name == nil {
+					continue
+				}
+				c.Conditions = append(c.Conditions, &CombatCondition{Name: name.ToString(), Rounds: readInt(rounds, 0)})
+			}
+		}
+		t.Combatants = append(t.Combatants, c)
+	}
+
+	// 旧版先攻列表没有记录战斗轮数
+	if t.Round == 0 && len(t.Combatants) > 0 {
+		t.Round = 1
+	}
+	return t
+}
+
+// save 写入群属性，调用方需持有 combatLock
+func (t *CombatTracker) save(d *Dice) {
+	attrs, err := d.AttrsManager.LoadById(t.GroupID)
+	if err != nil {
+		return
+	}
+
+	lst := ds.NewArrayVal()
+	ad := lst.MustReadArray()
+	for _, i := range t.Combatants {
+		conds := ds.NewArrayVal()
+		cd := conds.MustReadArray()
+		for _, c := range i.Conditions {
+			v := ds.NewDictValWithArrayMust(
+				ds.NewStrVal("name"), ds.NewStrVal(c.Name),
+				ds.NewStrVal("rounds"), ds.NewIntVal(ds.IntType(c.Rounds)),
+			)
+			cd.List = append(cd.List, v.V())
+		}
+		v := ds.NewDictValWithArrayMust(
+			ds.NewStrVal("name"), ds.NewStrVal(i.Name),
+			ds.NewStrVal("val"), ds.NewIntVal(ds.IntType(i.Val)),
+			ds.NewStrVal("uid"), ds.NewStrVal(i.UID),
+			ds.NewStrVal("detail"), ds.NewStrVal(i.Detail),
+			ds.NewStrVal("conditions"), conds,
+		)
+		ad.List = append(ad.List, v.V())
+	}
+
+	attrs.Store(combatListKey, lst)
+	attrs.Store(combatTurnKey, ds.NewIntVal(ds.IntType(t.Turn)))
+	attrs.Store(combatInitValKey, ds.NewIntVal(ds.IntType(t.CurInitVal)))
+	attrs.Store(combatRoundKey, ds.NewIntVal(ds.IntType(t.Round)))
+}
+
+// Current 当前行动的单位，列表为空时返回 nil
+func (t *CombatTracker) Current() *Combatant {
+	if len(t.Combatants) == 0 {
+		return nil
+	}
+	if t.Turn < 0 || t.Turn >= int64(len(t.Combatants)) {
+		return t.Combatants[0]
+	}
+	return t.Combatants[t.Turn]
+}
+
+// Add 加入单位，已存在的单位只更新先攻值
+func (t *CombatTracker) Add(items ...*Combatant) {
+	if len(t.Combatants) == 0 {
+		t.CurInitVal = NULL_INIT_VAL
+		if t.Round == 0 {
+			t.Round = 1
+		}
+	}
+	for _, i := range items {
+		if item := t.Combatants.GetExists(i.Name); item != nil {
+			item.Val = i.Val
+			continue
+		}
+		if i.Val > t.CurInitVal {
+			// 当前先攻值不变，修改回合数
+			t.Turn++
+		}
+		t.Combatants = append(t.Combatants, i)
+	}
+	sort.Sort(t.Combatants)
+}
+
+// Remove 移除单位。若当前行动的单位被移除，返回该单位
+func (t *CombatTracker) Remove(names ...string) (removed CombatantList, current *Combatant) {
+	if len(t.Combatants) == 0 {
+		return nil, nil
+	}
+	turn := t.Turn % int64(len(t.Combatants))
+	if turn < 0 {
+		turn = 0
+	}
+	toDeleted := map[string]bool{}
+	for _, i := range names {
+		toDeleted[i] = true
+	}
+
+	var newList CombatantList
+	preCurrent := int64(0) // 每有一个在当前单位前面的单位被删除, 当前单位下标需要减 1
+	for index, i := range t.Combatants {
+		if !toDeleted[i.Name] {
+			newList = append(newList, i)
+		} else {
+			removed = append(removed, i)
+			if int64(index) < turn {
+				preCurrent++
+			}
+		}
+	}
+	cur := t.Combatants[turn]
+
+	turn -= preCurrent
+	wrapped := turn >= int64(len(newList))
+	if wrapped {
+		turn = 0
+	}
+	t.Turn = turn
+	if len(removed) == 0 {
+		return nil, nil
+	}
+
+	t.Combatants = newList
+	if toDeleted[cur.Name] {
+		current = cur
+		if len(newList) == 0 {
+			t.CurInitVal = NULL_INIT_VAL
+			t.Round = 0
+		} else {
+			t.CurInitVal = newList[turn].Val
+			if wrapped {
+				t.Round++
+			}
+		}
+	}
+	return removed, current
+}
+
+// Next 结束当前单位的回合，返回结束回合的单位，以及其身上到期的状态
+func (t *CombatTracker) Next() (ended *Combatant, expired []*CombatCondition) {
+	l := int64(len(t.Combatants))
+	if l == 0 {
+		return nil, nil
+	}
+	turn := t.Turn
+	if turn < 0 || turn >= l {
+		turn = 0
+	}
+	ended = t.Combatants[turn]
+	expired = ended.tickConditions()
+
+	t.Turn = (turn + 1) % l
+	if t.Turn == 0 {
+		t.Round++
+	}
+	t.CurInitVal = t.Combatants[t.Turn].Val
+	return ended, expired
+}
+
+// Clear 清空列表并重置战斗轮
+func (t *CombatTracker) Clear() {
+	t.Combatants = nil
+	t.Turn = 0
+	t.Round = 0
+	t.CurInitVal = NULL_INIT_VAL
+}
+
+// SetCondition 为单位附加状态，同名状态会刷新持续轮数
+func (t *CombatTracker) SetCondition(name string, condition string, rounds int64) bool {
+	c := t.Combatants.GetExists(name)
+	if c == nil {
+		return false
+	}
+	if rounds < 0 {
+		rounds = 0
+	}
+	for _, i := range c.Conditions {
+		if i.Name == condition {
+			i.Rounds = rounds
+			return true
+		}
+	}
+	c.Conditions = append(c.Conditions, &CombatCondition{Name: condition, Rounds: rounds})
+	return true
+}
+
+// RemoveCondition 移除单位身上的状态
+func (t *CombatTracker) RemoveCondition(name string, condition string) bool {
+	c := t.Combatants.GetExists(name)
+	if c == nil {
+		return false
+	}
+	for index, i := range c.Conditions {
+		if i.Name == condition {
+			c.Conditions = append(c.Conditions[:index], c.Conditions[index+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// tickConditions 单位回合结束时，有时限的状态剩余轮数减一
+func (c *Combatant) tickConditions() (expired []*CombatCondition) {
+	var kept []*CombatCondition
+	for _, i := range c.Conditions {
+		if i.Rounds > 0 {
+			i.Rounds--
+			if i.Rounds == 0 {
+				expired = append(expired, i)
+				continue
+			}
+		}
+		kept = append(kept, i)
+	}
+	c.Conditions = kept
+	return expired
+}
+
+func conditionNames(conds []*CombatCondition) string {
+	names := make([]string, 0, len(conds))
+	for _, i := range conds {
+		names = append(names, i.Name)
+	}
+	return strings.Join(names, "、")
+}
+
+// combatInitRule 从当前群的规则模板读取先攻规则
+func combatInitRule(ctx *MsgContext) InitConfig {
+	var rule InitConfig
+	if tmpl := ctx.Group.GetCharTemplate(ctx.Dice); tmpl != nil && tmpl.GameSystemTemplateV2 != nil {
+		rule = tmpl.Commands.Init
+	}
+	if rule.Dice == "" {
+		rule.Dice = "d20"
+	}
+	if rule.Expr == "" {
+		rule.Expr = rule.Dice
+	}
+	return rule
+}
+
+// combatantExtraText 列表中单位后附加的生命值与状态
+func combatantExtraText(ctx *MsgContext, tmpl *GameSystemTemplate, c *Combatant) string {
+	var parts []string
+	if c.UID != "" && tmpl != nil && tmpl.GameSystemTemplateV2 != nil && tmpl.Commands.Init.HP != "" {
+		if attrs, err := ctx.Dice.AttrsManager.Load(ctx.Group.GroupID, c.UID); err == nil && attrs != nil {
+			if hp := attrs.Load(tmpl.GetAlias(tmpl.Commands.Init.HP)); hp != nil {
+				hpText := "HP" + hp.ToString()
+				if tmpl.Commands.Init.HPMax != "" {
+					if hpMax := attrs.Load(tmpl.GetAlias(tmpl.Commands.Init.HPMax)); hpMax != nil {
+						hpText += "/" + hpMax.ToString()
+					}
+				}
+				parts = append(parts, hpText)
+			}
+		}
+	}
+	if len(c.Conditions) > 0 {
+		conds := make([]string, 0, len(c.Conditions))
+		for _, i := range c.Conditions {
+			if i.Rounds > 0 {
+				conds = append(conds, fmt.Sprintf("%s(%d轮)", i.Name, i.Rounds))
+			} else {
+				conds = append(conds, i.Name)
+			}
+		}
+		parts = append(parts, "["+strings.Join(conds, ", ")+"]")
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return " " + strings.Join(parts, " ")
+}
+
+// setInitNextRoundVars 设置回合切换时的文本变量，需先调用 Next 或 Remove
+func setInitNextRoundVars(ctx *MsgContext, t *CombatTracker) {
+	lst := t.Combatants
+	l := int64(len(lst))
+	round := t.Turn
+	VarSetValueInt64(ctx, "$t战斗轮数", t.Round)
+	if round == 0 {
+		VarSetValueStr(ctx, "$t新轮开始提示", DiceFormatTmpl(ctx, "DND:先攻_新轮开始提示"))
+		VarSetValueStr(ctx, "$t当前回合角色名", lst[l-1].Name)
+		VarSetValueStr(ctx, "$t当前回合at", AtBuild(lst[l-1].UID))
+	} else {
+		VarSetValueStr(ctx, "$t新轮开始提示", "")
+		VarSetValueStr(ctx, "$t当前回合角色名", lst[round-1].Name)
+		VarSetValueStr(ctx, "$t当前回合at", AtBuild(lst[round-1].UID))
+	}
+	VarSetValueStr(ctx, "$t下一回合角色名", lst[round].Name)
+	VarSetValueStr(ctx, "$t下一回合at", AtBuild(lst[round].UID))
+
+	nextRound := round + 1
+	if l <= nextRound || nextRound < 0 {
+		nextRound = 0
+	}
+	VarSetValueStr(ctx, "$t下下一回合角色名", lst[nextRound].Name)
+	VarSetValueStr(ctx, "$t下下一回合at", AtBuild(lst[nextRound].UID))
+}
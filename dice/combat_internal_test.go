@@ -0,0 +1,164 @@
+//nolint:testpackage
+package dice
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCombatTrackerNextTicksConditions(t *testing.T) {
+	tracker := &CombatTracker{GroupID: "QQ-Group:1", CurInitVal: NULL_INIT_VAL}
+	tracker.Add(&Combatant{Name: "狼", Val: 10}, &Combatant{Name: "哥布林", Val: 15})
+	if tracker.Round != 1 || tracker.Current().Name != "哥布林" {
+		t.Fatalf("unexpected start: round %d, current %v", tracker.Round, tracker.Current())
+	}
+
+	if !tracker.SetCondition("哥布林", "中毒", 1) || !tracker.SetCondition("哥布林", "倒地", 0) {
+		t.Fatal("SetCondition failed")
+	}
+	if tracker.SetCondition("不存在", "中毒", 1) {
+		t.Fatal("SetCondition should fail for unknown combatant")
+	}
+
+	ended, expired := tracker.Next()
+	if ended.Name != "哥布林" || len(expired) != 1 || expired[0].Name != "中毒" {
+		t.Fatalf("ended %v, expired %v", ended, expired)
+	}
+	if len(ended.Conditions) != 1 || ended.Conditions[0].Name != "倒地" {
+		t.Fatalf("conditions left: %v", ended.Conditions)
+	}
+	if tracker.Current().Name != "狼" || tracker.CurInitVal != 10 || tracker.Round != 1 {
+		t.Fatalf("unexpected turn: %+v", tracker)
+	}
+
+	tracker.Next()
+	if tracker.Turn != 0 || tracker.Round != 2 {
+		t.Fatalf("expected round 2 at turn 0, got %+v", tracker)
+	}
+}
+
+func TestCombatTrackerAddAndRemove(t *testing.T) {
+	tracker := &CombatTracker{GroupID: "QQ-Group:1", CurInitVal: NULL_INIT_VAL}
+	tracker.Add(&Combatant{Name: "A", Val: 20}, &Combatant{Name: "B", Val: 10})
+	tracker.Next() // 轮到 B
+
+	// 先攻高于当前单位的新单位排在前面，当前单位不变
+	tracker.Add(&Combatant{Name: "C", Val: 15})
+	if tracker.Current().Name != "B" {
+		t.Fatalf("current should stay B, got %s", tracker.Current().Name)
+	}
+
+	removed, current := tracker.Remove("B")
+	if len(removed) != 1 || current == nil || current.Name != "B" {
+		t.Fatalf("removed %v, current %v", removed, current)
+	}
+	if tracker.Turn != 0 || tracker.Round != 2 || tracker.Current().Name != "A" {
+		t.Fatalf("expected wrap to A in round 2, got %+v", tracker)
+	}
+
+	removed, current = tracker.Remove("C")
+	if len(removed) != 1 || current != nil {
+		t.Fatalf("removed %v, current %v", removed, current)
+	}
+	if removed, _ = tracker.Remove("nobody"); len(removed) != 0 {
+		t.Fatalf("unexpected removal: %v", removed)
+	}
+
+	tracker.Clear()
+	if tracker.Current() != nil || tracker.Round != 0 || tracker.CurInitVal != NULL_INIT_VAL {
+		t.Fatalf("clear failed: %+v", tracker)
+	}
+}
+
+func TestExecuteNew_CombatTrackerPersists(t *testing.T) {
+	d, ep, adapter, cleanup := newExecuteNewTestDice(t)
+	defer cleanup()
+
+	groupID := "QQ-Group:222"
+	send := func(text string) string {
+		d.ImSession.ExecuteNew(ep, newGroupMsg(groupID, "QQ:999", text))
+		reply, ok := adapter.waitForMsg(2 * time.Second)
+		if !ok {
+			t.Fatalf("timeout waiting for reply to %q", text)
+		}
+		return reply
+	}
+
+	send(".ri 15 哥布林, 10 狼")
+	if reply := send(".init cond 哥布林 中毒 1"); !strings.Contains(reply, "中毒」，持续1轮") {
+		t.Fatalf("unexpected cond reply: %s", reply)
+	}
+	reply := send(".init next")
+	if !strings.Contains(reply, "狼") || !strings.Contains(reply, "状态已结束：中毒") {
+		t.Fatalf("unexpected next reply: %s", reply)
+	}
+
+	group, _ := d.ImSession.ServiceAtNew.Load(groupID)
+	tracker := LoadCombatTracker(d, group)
+	if len(tracker.Combatants) != 2 || tracker.Current().Name != "狼" || tracker.Round != 1 {
+		t.Fatalf("unexpected tracker: %+v", tracker)
+	}
+	if reply = send(".init"); !strings.Contains(reply, "第1轮，当前回合：狼") {
+		t.Fatalf("unexpected list: %s", reply)
+	}
+}
+
+func TestUpdateCombatTrackerConcurrentAdds(t *testing.T) {
+	d, _, _, cleanup := newExecuteNewTestDice(t)
+	defer cleanup()
+
+	group := &GroupInfo{GroupID: "QQ-Group:223"}
+	var wg sync.WaitGroup
+	for i := range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			UpdateCombatTracker(d, group, func(t *CombatTracker) bool {
+				t.Add(&Combatant{Name: fmt.Sprintf("单位%d", i), Val: int64(i)})
+				return true
+			})
+		}()
+	}
+	wg.Wait()
+
+	if n := len(LoadCombatTracker(d, group).Combatants); n != 20 {
+		t.Fatalf("expected 20 combatants, got %d", n)
+	}
+}
+
+// 先攻列表不依赖 dnd5e 扩展，由模板是否声明 init 决定
+func TestExecuteNew_CombatFollowsTemplateInit(t *testing.T) {
+	d, ep, adapter, cleanup := newExecuteNewTestDice(t)
+	defer cleanup()
+
+	groupID := "QQ-Group:223"
+	send := func(text string) string {
+		d.ImSession.ExecuteNew(ep, newGroupMsg(groupID, "QQ:999", text))
+		reply, ok := adapter.waitForMsg(2 * time.Second)
+		if !ok {
+			t.Fatalf("timeout waiting for reply to %q", text)
+		}
+		return reply
+	}
+
+	send(".ri 15 哥布林")
+	group, _ := d.ImSession.ServiceAtNew.Load(groupID)
+	group.System = "coc7"
+	group.ExtInactiveByName("dnd5e")
+	if reply := send(".ri 12 狼"); !strings.Contains(reply, "狼") {
+		t.Fatalf("coc7 group should have ri without dnd5e: %s", reply)
+	}
+
+	tmpl, err := LoadGameSystemTemplateFromBytes([]byte(checkTestCoc6Template), "yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.GameSystemTemplateAdd(tmpl)
+	group.System = "coc6-lite"
+	if reply := send(".init"); !strings.Contains(reply, "没有提供先攻列表") {
+		t.Fatalf("template without init should not provide the tracker: %s", reply)
+	}
+}
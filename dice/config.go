@@ -618,6 +618,18 @@ func setupBaseTextTemplate(d *Dice) {
 			"属性设置_保存提醒": {
 				{`{ $t当前绑定角色 ? '[√] 已绑卡' : '' }`, 1},
 			},
+			"属性设置_历史": {
+				{"{$t玩家}最近{$t数量}条属性变更:\n{$t记录列表}", 1},
+			},
+			"属性设置_历史_无记录": {
+				{`{$t玩家}暂无属性变更记录`, 1},
+			},
+			"属性设置_撤销": {
+				{"{$t玩家}已撤销以下属性变更:\n{$t变更列表}\n{COC:属性设置_保存提醒}", 1},
+			},
+			"属性设置_撤销_无记录": {
+				{`{$t玩家}没有可撤销的属性变更`, 1},
+			},
 			// -------------------- st end --------------------------
 
 			// -------------------- en --------------------------
@@ -756,6 +768,21 @@ func setupBaseTextTemplate(d *Dice) {
 			"先攻_新轮开始提示": {
 				{"新的一轮开始了！\n", 1},
 			},
+			"先攻_单位不存在": {
+				{`先攻列表中没有找到【{$t目标}】`, 1},
+			},
+			"先攻_状态_附加": {
+				{"{$t玩家}为【{$t目标}】附加了状态「{$t状态}」{% $t持续轮数 > 0 ? `，持续{$t持续轮数}轮` %}", 1},
+			},
+			"先攻_状态_移除": {
+				{`【{$t目标}】的状态「{$t状态}」已移除`, 1},
+			},
+			"先攻_状态_不存在": {
+				{`【{$t目标}】身上没有状态「{$t状态}」`, 1},
+			},
+			"先攻_状态_结束": {
+				{`【{$t目标}】的状态已结束：{$t状态列表}`, 1},
+			},
 			"死亡豁免_D20_附加语": {
 				{`你觉得你还可以抢救一下！HP回复1点！`, 1},
 			},
@@ -793,10 +820,10 @@ func setupBaseTextTemplate(d *Dice) {
 				{`\n`, 1},
 			},
 			"检定_单项结果文本": {
-				{`{$t检定过程文本} = {$t检定结果}`, 1},
+				{`{$t检定过程文本} = {$t检定结果} {$t判定结果}`, 1},
 			},
 			"检定": {
-				{`{$t玩家}的"{$t技能}"检定（DND5E）结果为: {$t检定过程文本} = {$t检定结果}`, 1},
+				{`{$t玩家}的"{$t技能}"检定（DND5E）结果为: {$t检定过程文本} = {$t检定结果} {$t判定结果}`, 1},
 			},
 			"检定_多轮": {
 				{`对{$t玩家}的"{$t技能}"进行了{$t次数}次检定（DND5E），结果为:\n{$t结果文本}`, 1},
@@ -945,6 +972,21 @@ func setupBaseTextTemplate(d *Dice) {
 			"角色管理_删除成功_当前卡": {
 				{"由于你删除的角色是当前角色，昵称和属性将被一同清空", 1},
 			},
+			"角色管理_导入成功": {
+				{"已从{$t导入格式}格式导入角色并自动绑定: {$t角色名}，共{$t导入属性数}项属性{% $t未导入字段数 > 0 ? `\n未能识别的字段({$t未导入字段数}): {$t未导入字段}` %}", 1},
+			},
+			"角色管理_导入失败": {
+				{"导入角色失败: {$t错误}", 1},
+			},
+			"角色管理_快照_恢复成功": {
+				{"已从快照恢复角色并自动绑定: {$t角色名}", 1},
+			},
+			"角色管理_快照_恢复失败": {
+				{"恢复角色快照失败: {$t错误}", 1},
+			},
+			"角色管理_快照_导出失败": {
+				{"导出角色快照失败: {$t错误}", 1},
+			},
 			// -------------------- pc end --------------------------
 			"提示_私聊不可用": {
 				{"该指令只在群组中可用", 1},
@@ -991,6 +1033,9 @@ func setupBaseTextTemplate(d *Dice) {
 			"刷屏_警告内容_群组": {
 				{"警告：该群组内指令频率过高，请注意。", 1},
 			},
+			"刷屏_冷却提示": {
+				{"指令「{$t指令}」使用过于频繁，请{$t冷却秒数}秒后再试。", 1},
+			},
 			"快捷指令_新增": {
 				{`已成功定义指令「{$t指令}」的{$t指令来源}快捷方式「{$t快捷指令名}」，触发方式：\n.&{$t快捷指令名} 或\n.a {$t快捷指令名}`, 1},
 			},
@@ -1086,6 +1131,87 @@ func setupBaseTextTemplate(d *Dice) {
 			"校验_成功": {
 				{"本次生成的神秘海豹码：\n{$t校验码}", 1},
 			},
+			"定时_新增提醒": {
+				{"已设置提醒 #{$t任务ID}，将于 {$t触发时间} 提醒：{$t内容}", 1},
+			},
+			"定时_新增公告": {
+				{"已设置周期公告 #{$t任务ID}（{$t周期}），下次发送于 {$t触发时间}", 1},
+			},
+			"定时_触发提醒": {
+				{"【提醒】{$t内容}{$t报名情况}", 1},
+			},
+			"定时_触发公告": {
+				{"【公告】{$t内容}{$t报名情况}", 1},
+			},
+			"定时_数量上限": {
+				{"本群的定时任务已达上限({$t上限}个)，请先删除不需要的任务", 1},
+			},
+			"报名_已登记": {
+				{"{$t玩家}已登记 #{$t任务ID}「{$t内容}」：{$t报名}", 1},
+			},
+			"骰池_V5": {
+				{`{$t玩家}的"{$t原因}"检定(V5): {$t骰池明细} = {$t骰池结果}成功{$t难度文本} {$t骰池判定}`, 1},
+			},
+			"骰池_V5_大成功": {
+				{`大成功！`, 1},
+			},
+			"骰池_V5_混乱大成功": {
+				{`混乱大成功！野兽借着你的手达成了目的`, 1},
+			},
+			"骰池_V5_成功": {
+				{`成功`, 1},
+			},
+			"骰池_V5_失败": {
+				{`失败`, 1},
+			},
+			"骰池_V5_兽性失败": {
+				{`兽性失败！饥渴驱使着你`, 1},
+			},
+			"骰池_V5_完全失败": {
+				{`完全失败`, 1},
+			},
+			"骰池_暗影狂奔": {
+				{`{$t玩家}的"{$t原因}"检定(暗影狂奔): {$t骰池明细} = {$t骰池结果}命中{$t难度文本} {$t骰池判定}`, 1},
+			},
+			"骰池_暗影狂奔_成功": {
+				{`成功`, 1},
+			},
+			"骰池_暗影狂奔_失败": {
+				{`失败`, 1},
+			},
+			"骰池_暗影狂奔_故障": {
+				{`故障！`, 1},
+			},
+			"骰池_暗影狂奔_严重故障": {
+				{`严重故障！`, 1},
+			},
+			"骰池_暗夜刀锋": {
+				{`{$t玩家}的"{$t原因}"行动(暗夜刀锋{$t局势效果}): {$t骰池明细} = {$t骰池结果} {$t骰池判定}`, 1},
+			},
+			"骰池_暗夜刀锋_大成功": {
+				{`大成功！效果提升一级`, 1},
+			},
+			"骰池_暗夜刀锋_成功": {
+				{`完全成功`, 1},
+			},
+			"骰池_暗夜刀锋_部分成功": {
+				{`部分成功，但要付出代价`, 1},
+			},
+			"骰池_暗夜刀锋_失败": {
+				{`失败，事情变得更糟了`, 1},
+			},
+			"骰池_PbtA": {
+				{`{$t玩家}的"{$t原因}"行动(PbtA): {$t骰池明细} = {$t骰池结果} {$t骰池判定}`, 1},
+			},
+			"骰池_PbtA_强成功": {
+				{`强成功(10+)`, 1},
+			},
+			"骰池_PbtA_弱成功": {
+				{`弱成功(7-9)`, 1},
+			},
+			"骰池_PbtA_失败": {
+				{`失败(6-)`, 1},
+			},
 		},
 		"日志": {
 			"记录_新建": {
@@ -1158,6 +1284,9 @@ func setupBaseTextTemplate(d *Dice) {
 			"记录_上传_成功": {
 				{`跑团日志已上传服务器，链接如下：{$t日志链接}`, 1},
 			},
+			"记录_本地链接": {
+				{`跑团日志可通过以下链接查看，{$t链接有效期}前有效：{$t日志链接}`, 1},
+			},
 			"记录_上传_失败": {
 				{`跑团日志上传失败：{$t错误原因}\n若未出现线上日志地址，可换时间重试，或联系骰主在data/default/log-exports路径下取出日志\n文件名: 群号_日志名_随机数.zip\n注意此文件log end/get后才会生成`, 1},
 			},
@@ -1171,6 +1300,9 @@ func setupBaseTextTemplate(d *Dice) {
 			"记录_导出_成功": {
 				{`日志文件《{$t文件名字}》已上传至群文件，请自行到群文件查看。`, 1},
 			},
+			"记录_导出_不支持发送文件": {
+				{`当前平台不支持发送文件，日志《{$t文件名字}》未能送出。可附上邮箱重新导出，或使用.log get获取在线链接`, 1},
+			},
 		},
 	}
 
@@ -1329,6 +1461,20 @@ func setupBaseTextTemplate(d *Dice) {
 				SubType:         ".st hp+1",
 				ExampleCommands: []string{".st hp70", ".st hp+1"},
 			},
+			"属性设置_历史": {
+				SubType: ".st history",
+				Vars:    []string{"$t玩家", "$t数量", "$t记录列表"},
+			},
+			"属性设置_历史_无记录": {
+				SubType: ".st history",
+			},
+			"属性设置_撤销": {
+				SubType: ".st undo",
+				Vars:    []string{"$t玩家", "$t数量", "$t变更列表"},
+			},
+			"属性设置_撤销_无记录": {
+				SubType: ".st undo",
+			},
 			// -------------------- st end --------------------------
 
 			// -------------------- en --------------------------
@@ -1454,10 +1600,31 @@ func setupBaseTextTemplate(d *Dice) {
 			"先攻_下一回合": {
 				SubType: ".init ed",
 				Vars: []string{"$t当前回合角色名", "$t当前回合at", "$t新轮开始提示", "$t下一回合角色名",
-					"$t下一回合at", "$t下下一回合角色名", "$t下下一回合at"},
+					"$t下一回合at", "$t下下一回合角色名", "$t下下一回合at", "$t战斗轮数"},
 			},
 			"先攻_新轮开始提示": {
 				SubType: ".init ed",
+				Vars:    []string{"$t战斗轮数"},
+			},
+			"先攻_单位不存在": {
+				SubType: ".init cond",
+				Vars:    []string{"$t目标"},
+			},
+			"先攻_状态_附加": {
+				SubType: ".init cond",
+				Vars:    []string{"$t目标", "$t状态", "$t持续轮数"},
+			},
+			"先攻_状态_移除": {
+				SubType: ".init cond rm",
+				Vars:    []string{"$t目标", "$t状态"},
+			},
+			"先攻_状态_不存在": {
+				SubType: ".init cond rm",
+				Vars:    []string{"$t目标", "$t状态"},
+			},
+			"先攻_状态_结束": {
+				SubType: ".init ed",
+				Vars:    []string{"$t目标", "$t状态列表"},
 			},
 			"先攻_移除_前缀": {
 				SubType: ".init rm",
@@ -1516,11 +1683,11 @@ func setupBaseTextTemplate(d *Dice) {
 			},
 			"检定_单项结果文本": {
 				SubType: ".rc",
-				Vars:    []string{"$t骰子出目", "$t检定过程文本", "$t检定结果"},
+				Vars:    []string{"$t骰子出目", "$t检定过程文本", "$t检定结果", "$t判定结果", "$tDC"},
 			},
 			"检定": {
 				SubType: ".rc 力量",
-				Vars:    []string{"$t骰子出目", "$t检定过程文本", "$t检定结果"},
+				Vars:    []string{"$t骰子出目", "$t检定过程文本", "$t检定结果", "$t判定结果", "$tDC"},
 			},
 			"检定_多轮": {
 				SubType: ".rc 3#力量",
@@ -1684,6 +1851,26 @@ func setupBaseTextTemplate(d *Dice) {
 			"角色管理_删除成功_当前卡": {
 				SubType: ".pc rm",
 			},
+			"角色管理_导入成功": {
+				SubType: ".pc import",
+				Vars:    []string{"$t角色名", "$t导入格式", "$t导入属性数", "$t未导入字段数", "$t未导入字段"},
+			},
+			"角色管理_导入失败": {
+				SubType: ".pc import",
+				Vars:    []string{"$t错误"},
+			},
+			"角色管理_快照_恢复成功": {
+				SubType: ".pc load-snapshot",
+				Vars:    []string{"$t角色名"},
+			},
+			"角色管理_快照_恢复失败": {
+				SubType: ".pc load-snapshot",
+				Vars:    []string{"$t错误"},
+			},
+			"角色管理_快照_导出失败": {
+				SubType: ".pc export",
+				Vars:    []string{"$t错误"},
+			},
 			// -------------------- pc end --------------------------
 			"提示_私聊不可用": {
 				SubType: "通用",
@@ -1730,6 +1917,10 @@ func setupBaseTextTemplate(d *Dice) {
 			"刷屏_警告内容_群组": {
 				SubType: "刷屏",
 			},
+			"刷屏_冷却提示": {
+				SubType: "刷屏",
+				Vars:    []string{"$t指令", "$t冷却秒数", "$t策略"},
+			},
 			"快捷指令_新增": {
 				SubType: ".alias",
 			},
@@ -1828,6 +2019,97 @@ func setupBaseTextTemplate(d *Dice) {
 			"校验_成功": {
 				SubType: ".check",
 			},
+			"定时_新增提醒": {
+				SubType: ".remind",
+				Vars:    []string{"$t任务ID", "$t触发时间", "$t内容"},
+			},
+			"定时_新增公告": {
+				SubType: ".schedule",
+				Vars:    []string{"$t任务ID", "$t周期", "$t触发时间", "$t内容"},
+			},
+			"定时_触发提醒": {
+				SubType: ".remind",
+				Vars:    []string{"$t任务ID", "$t内容", "$t报名情况"},
+			},
+			"定时_触发公告": {
+				SubType: ".schedule",
+				Vars:    []string{"$t任务ID", "$t周期", "$t内容", "$t报名情况"},
+			},
+			"定时_数量上限": {
+				SubType: ".remind/.schedule",
+				Vars:    []string{"$t上限"},
+			},
+			"报名_已登记": {
+				SubType: ".rsvp",
+				Vars:    []string{"$t任务ID", "$t内容", "$t报名"},
+			},
+			"骰池_V5": {
+				SubType: ".v5",
+				Vars:    []string{"$t玩家", "$t原因", "$t骰池明细", "$t骰池结果", "$t骰池判定", "$t难度文本", "$t饥渴"},
+			},
+			"骰池_V5_大成功": {
+				SubType: ".v5",
+			},
+			"骰池_V5_混乱大成功": {
+				SubType: ".v5",
+			},
+			"骰池_V5_成功": {
+				SubType: ".v5",
+			},
+			"骰池_V5_失败": {
+				SubType: ".v5",
+			},
+			"骰池_V5_兽性失败": {
+				SubType: ".v5",
+			},
+			"骰池_V5_完全失败": {
+				SubType: ".v5",
+			},
+			"骰池_暗影狂奔": {
+				SubType: ".sr",
+				Vars:    []string{"$t玩家", "$t原因", "$t骰池明细", "$t骰池结果", "$t骰池判定", "$t难度文本"},
+			},
+			"骰池_暗影狂奔_成功": {
+				SubType: ".sr",
+			},
+			"骰池_暗影狂奔_失败": {
+				SubType: ".sr",
+			},
+			"骰池_暗影狂奔_故障": {
+				SubType: ".sr",
+			},
+			"骰池_暗影狂奔_严重故障": {
+				SubType: ".sr",
+			},
+			"骰池_暗夜刀锋": {
+				SubType: ".bitd",
+				Vars:    []string{"$t玩家", "$t原因", "$t骰池明细", "$t骰池结果", "$t骰池判定", "$t局势效果"},
+			},
+			"骰池_暗夜刀锋_大成功": {
+				SubType: ".bitd",
+			},
+			"骰池_暗夜刀锋_成功": {
+				SubType: ".bitd",
+			},
+			"骰池_暗夜刀锋_部分成功": {
+				SubType: ".bitd",
+			},
+			"骰池_暗夜刀锋_失败": {
+				SubType: ".bitd",
+			},
+			"骰池_PbtA": {
+				SubType: ".pbta",
+				Vars:    []string{"$t玩家", "$t原因", "$t骰池明细", "$t骰池结果", "$t骰池判定"},
+			},
+			"骰池_PbtA_强成功": {
+				SubType: ".pbta",
+			},
+			"骰池_PbtA_弱成功": {
+				SubType: ".pbta",
+			},
+			"骰池_PbtA_失败": {
+				SubType: ".pbta",
+			},
 		},
 		"日志": {
 			"记录_新建": {
@@ -1916,6 +2198,10 @@ func setupBaseTextTemplate(d *Dice) {
 				SubType: ".log end",
 				Vars:    []string{"$t错误原因"},
 			},
+			"记录_本地链接": {
+				SubType: ".log get",
+				Vars:    []string{"$t日志链接", "$t链接有效期"},
+			},
 
 			// 1.5.0+
 			"名片_自动设置": {
@@ -1927,6 +2213,10 @@ func setupBaseTextTemplate(d *Dice) {
 			"记录_导出_成功": {
 				SubType: ".log export",
 			},
+			"记录_导出_不支持发送文件": {
+				SubType: ".log export",
+				Vars:    []string{"$t文件名字"},
+			},
 		},
 	}
 	d.TextMapRaw = texts
@@ -2121,6 +2411,46 @@ func getNumVal(i interface{}) uint {
 	}
 }
 
+// groupInfoFromData 解析数据库中的群组信息并补全空字段
+func groupInfoFromData(id string, version int64, data []byte) (*GroupInfo, error) {
+	var groupInfo GroupInfo
+	if err := json.Unmarshal(data, &groupInfo); err != nil {
+		return nil, err
+	}
+	groupInfo.GroupID = id
+	groupInfo.UpdatedAtTime = 0
+	groupInfo.Version = version
+
+	// 初始化 nil 字段（加载时初始化，避免单独遍历）
+	if groupInfo.DiceIDActiveMap == nil {
+		groupInfo.DiceIDActiveMap = new(SyncMap[string, bool])
+	}
+	if groupInfo.DiceIDExistsMap == nil {
+		groupInfo.DiceIDExistsMap = new(SyncMap[string, bool])
+	}
+	if groupInfo.BotList == nil {
+		groupInfo.BotList = new(SyncMap[string, bool])
+	}
+	if groupInfo.InactivatedExtSet == nil {
+		groupInfo.InactivatedExtSet = StringSet{}
+	}
+
+	// 找出其中以群号开头的，这是1.2版本的bug
+	var toDelete []string
+	if groupInfo.DiceIDExistsMap != nil {
+		groupInfo.DiceIDExistsMap.Range(func(key string, value bool) bool {
+			if strings.HasPrefix(key, "QQ-Group:") {
+				toDelete = append(toDelete, key)
+			}
+			return true
+		})
+		for _, i := range toDelete {
+			groupInfo.DiceIDExistsMap.Delete(i)
+		}
+	}
+	return &groupInfo, nil
+}
+
 func (d *Dice) loads() {
 	config := NewConfig(d)
 	missingPlatformConfigInServe := false
@@ -2159,41 +2489,13 @@ func (d *Dice) loads() {
 		// 装载ServiceAtNew
 		// Pinenutn: So,我还是不知道ServiceAtNew到底是个什么鬼东西……太反直觉了……
 		d.ImSession.ServiceAtNew = new(SyncMap[string, *GroupInfo])
-		err = service.GroupInfoListGet(d.DBOperator, func(id string, updatedAt int64, data []byte) {
-			var groupInfo GroupInfo
-			err = json.Unmarshal(data, &groupInfo)
-			if err == nil {
-				groupInfo.GroupID = id
-				groupInfo.UpdatedAtTime = 0
-
-				// 初始化 nil 字段（加载时初始化，避免单独遍历）
-				if groupInfo.DiceIDActiveMap == nil {
-					groupInfo.DiceIDActiveMap = new(SyncMap[string, bool])
-				}
-				if groupInfo.DiceIDExistsMap == nil {
-					groupInfo.DiceIDExistsMap = new(SyncMap[string, bool])
-				}
-				if groupInfo.BotList == nil {
-					groupInfo.BotList = new(SyncMap[string, bool])
+		err = service.GroupInfoListGet(d.DBOperator, func(id string, updatedAt int64, version int64, data []byte) {
+			groupInfo, errParse := groupInfoFromData(id, version, data)
+			if errParse == nil {
+				if d.clusterManager() != nil {
+					groupInfo.clusterBase = append([]byte(nil), data...)
 				}
-				if groupInfo.InactivatedExtSet == nil {
-					groupInfo.InactivatedExtSet = StringSet{}
-				}
-
-				// 找出其中以群号开头的，这是1.2版本的bug
-				var toDelete []string
-				if groupInfo.DiceIDExistsMap != nil {
-					groupInfo.DiceIDExistsMap.Range(func(key string, value bool) bool {
-						if strings.HasPrefix(key, "QQ-Group:") {
-							toDelete = append(toDelete, key)
-						}
-						return true
-					})
-					for _, i := range toDelete {
-						groupInfo.DiceIDExistsMap.Delete(i)
-					}
-				}
-				d.ImSession.ServiceAtNew.Store(id, &groupInfo)
+				d.ImSession.ServiceAtNew.Store(id, groupInfo)
 			} else {
 				d.Logger.Errorf("加载群信息失败: %s", id)
 			}
@@ -2587,7 +2889,11 @@ func (d *Dice) Save(isAuto bool) {
 				groupSavedCount++
 				data, err := json.Marshal(groupInfo)
 				if err == nil {
-					err := service.GroupInfoSave(d.DBOperator, groupInfo.GroupID, groupInfo.UpdatedAtTime, data)
+					if c := d.clusterManager(); c != nil {
+						err = d.clusterSaveGroup(c, groupInfo, data)
+					} else {
+						err = service.GroupInfoSave(d.DBOperator, groupInfo.GroupID, groupInfo.UpdatedAtTime, data)
+					}
 					if err != nil {
 						d.Logger.Warnf("保存群组数据失败 %v : %v", groupInfo.GroupID, err.Error())
 					}
@@ -2608,6 +2914,8 @@ func (d *Dice) Save(isAuto bool) {
 	}
 	attrsDuration := time.Since(attrsStartTime)
 
+	_ = d.RollStatFlush()
+
 	// 保存黑名单数据
 	// TODO: 增加更新时间检测
 	// model.BanMapSet(d.DBData, d.Config.BanList.MapToJSON())
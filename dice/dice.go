@@ -28,6 +28,7 @@ import (
 
 	"sealdice-core/dice/events"
 	"sealdice-core/logger"
+	"sealdice-core/model"
 	"sealdice-core/utils/dboperator/engine"
 	"sealdice-core/utils/public_dice"
 )
@@ -115,6 +116,9 @@ type ExtInfo struct {
 	IsWrapper  bool   `json:"-" yaml:"-"` // 是否为 Wrapper ExtInfo (代理对象)
 	TargetName string `json:"-" yaml:"-"` // Wrapper 代理的真实扩展名
 	IsDeleted  bool   `json:"-" yaml:"-"` // Wrapper 已被删除（脚本已删除，落盘时过滤）
+
+	RateLimitPolicies []*RateLimitPolicy `json:"-" yaml:"-"` // 扩展声明的指令开销，见 RegisterRateLimitPolicy
+	rateLimitMu       sync.RWMutex
 }
 
 // RootConfig TODO：历史遗留问题，由于不输出DICE日志效果过差，已经抹除日志输出选项，剩余两个选项，私以为可以想办法也抹除掉。
@@ -286,6 +290,19 @@ type Dice struct {
 
 	/* 保存优化 */
 	DirtyGroups *SyncMap[string, int64] `json:"-" yaml:"-"` // 脏群组列表：groupID -> UpdatedAtTime
+
+	/* 定时任务 */
+	Schedule *ScheduleManager `json:"-" yaml:"-"`
+
+	/* 频率限制策略 */
+	rateLimitLock     sync.Mutex
+	rateLimitBuckets  map[string]*rateLimitBucket
+	rateLimitPrunedAt time.Time
+
+	/* 检定统计，暂存增量，随 Save 批量写入 */
+	rollStatLock     sync.Mutex
+	rollStatPending  map[string]*model.RollStat
+	rollStatOutcomes map[string]*model.RollStatOutcome
 }
 
 func (d *Dice) MarkModified() {
@@ -351,6 +368,7 @@ func (d *Dice) Init(operator engine.DatabaseOperator, uiWriter *logger.UIWriter)
 	d.loadAdvanced()
 	(&d.Config).BanList.Loads()
 	(&d.Config).BanList.AfterLoads()
+	d.Schedule.Load()
 	d.IsAlreadyLoadConfig = true
 
 	if d.Config.EnableCensor {
@@ -889,6 +907,7 @@ func (d *Dice) GameSystemTemplateAddEx(tmpl *GameSystemTemplate, overwrite bool)
 	if !exists || overwrite {
 		tmpl.Init()
 		d.GameSystemMap.Store(tmpl.Name, tmpl)
+		d.registerCheckCommands(tmpl)
 		return true
 	}
 	return false
@@ -23,6 +23,11 @@ type AttrsManager struct {
 	cancel context.CancelFunc
 	wg     sync.WaitGroup
 	m      SyncMap[string, *AttributesItem]
+
+	journalDirty     SyncMap[string, *AttributesItem] // 有尚未写入的属性变更记录的角色
+	journalFlushLock sync.Mutex                       // 保证 JournalFlush 返回时此前的记录均已落盘
+
+	cluster *ClusterManager // 集群模式下非空，见 cluster.go
 }
 
 func (am *AttrsManager) Stop() {
@@ -37,7 +42,11 @@ func (am *AttrsManager) LoadByCtx(ctx *MsgContext) (*AttributesItem, error) {
 	if ctx.IsCompatibilityTest {
 		return am.LoadByIdDirect(ctx.Group.GroupID, ctx.Player.UserID)
 	}
-	return am.Load(ctx.Group.GroupID, ctx.Player.UserID)
+	attrs, err := am.Load(ctx.Group.GroupID, ctx.Player.UserID)
+	if err == nil {
+		attrs.journalStamp(ctx)
+	}
+	return attrs, err
 }
 
 func (am *AttrsManager) Load(groupId string, userId string) (*AttributesItem, error) {
@@ -58,7 +67,11 @@ func (am *AttrsManager) Load(groupId string, userId string) (*AttributesItem, er
 		id = gid
 	}
 
-	return am.LoadById(id)
+	item, err := am.LoadById(id)
+	if err == nil {
+		item.journal = am // 群内默认卡没有类型标记，在此开启变更记录
+	}
+	return item, err
 }
 
 // LoadByIdDirect 直接使用组合ID加载数据，跳过绑定查询（用于兼容性测试）
@@ -107,6 +120,7 @@ func (am *AttrsManager) CharDelete(id string) error {
 	}
 	// 从缓存中删除
 	am.m.Delete(id)
+	am.cluster.Publish(ClusterEventAttrs, id)
 	return nil
 }
 
@@ -143,6 +157,10 @@ func (am *AttrsManager) LoadById(id string) (*AttributesItem, error) {
 					SheetType:    data.SheetType,
 					LastUsedTime: time.Now().Unix(),
 					IsSaved:      true,
+					version:      data.Version,
+				}
+				if data.AttrsType == service.AttrsTypeCharacter {
+					i.journal = am
 				}
 				am.m.Store(id, i)
 				return i, nil
@@ -212,6 +230,21 @@ func (am *AttrsManager) CheckForSave() error {
 		// 尚未初始化
 		return errors.New("数据库尚未初始化")
 	}
+	// 未经指令产生的变更(如API、定时任务)在此写入
+	if err := am.JournalFlush(nil); err != nil {
+		log.Errorf("写入属性变更记录出错: %v", err)
+	}
+	if am.cluster != nil {
+		var items []*AttributesItem
+		am.m.Range(func(key string, value *AttributesItem) bool {
+			if !value.IsSaved {
+				items = append(items, value)
+			}
+			return true
+		})
+		return am.clusterSave(items)
+	}
+
 	var resultList []*service.AttributesBatchUpsertModel
 	prepareToSave := map[string]int{}
 	am.m.Range(func(key string, value *AttributesItem) bool {
@@ -257,6 +290,29 @@ func (am *AttrsManager) CheckAndFreeUnused() error {
 
 	prepareToFree := map[string]int{}
 	currentTime := time.Now()
+	if am.cluster != nil {
+		var items []*AttributesItem
+		am.m.Range(func(key string, value *AttributesItem) bool {
+			if currentTime.Sub(time.Unix(value.LastUsedTime, 0)) > 10*time.Minute && currentTime.Sub(time.Unix(value.LastModifiedTime, 0)) > 10*time.Minute {
+				items = append(items, value)
+			}
+			return true
+		})
+		var unsaved []*AttributesItem
+		for _, i := range items {
+			if !i.IsSaved {
+				unsaved = append(unsaved, i)
+			}
+		}
+		err := am.clusterSave(unsaved)
+		for _, i := range items {
+			if i.IsSaved {
+				am.m.Delete(i.ID)
+			}
+		}
+		return err
+	}
+
 	var resultList []*service.AttributesBatchUpsertModel
 	am.m.Range(func(key string, value *AttributesItem) bool {
 		lastUsedTime := time.Unix(value.LastUsedTime, 0)
@@ -352,6 +408,23 @@ type AttributesItem struct {
 	IsSaved          bool
 	Name             string
 	SheetType        string
+
+	journal        *AttrsManager // 非空时记录属性变更，见 attrs_journal.go
+	journalLock    sync.Mutex
+	journalCommand *model.AttrsJournal // 变更归属的指令，仅使用其中的指令ID、群号与操作者
+	journalPending []*model.AttrsJournal
+
+	version int64 // 数据库行版本，集群模式下保存时用于检测冲突
+
+	lock   sync.Mutex // 保护 modSeq、version 与 IsSaved，使序列化与标记已保存之间的改动不会丢失
+	modSeq uint64     // 每次修改递增
+}
+
+// markModified 记录一次修改，调用时需持有 i.lock
+func (i *AttributesItem) markModified(now int64) {
+	i.modSeq++
+	i.LastModifiedTime = now
+	i.IsSaved = false
 }
 
 func (i *AttributesItem) SaveToDB(db engine.DatabaseOperator) {
@@ -394,28 +467,48 @@ func (i *AttributesItem) LoadX(name string) (*ds.VMValue, bool) {
 }
 
 func (i *AttributesItem) Delete(name string) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	if i.journal != nil {
+		old, _ := i.valueMap.Load(name)
+		i.journalRecord(name, old, nil)
+	}
 	i.valueMap.Delete(name)
+	i.modSeq++
 	i.LastModifiedTime = time.Now().Unix()
 }
 
 func (i *AttributesItem) SetModified() {
-	i.LastModifiedTime = time.Now().Unix()
-	i.IsSaved = false
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	i.markModified(time.Now().Unix())
 }
 
 func (i *AttributesItem) Store(name string, value *ds.VMValue) {
 	now := time.Now().Unix()
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	if i.journal != nil {
+		old, _ := i.valueMap.Load(name)
+		i.journalRecord(name, old, value)
+	}
 	i.valueMap.Store(name, value)
-	i.LastModifiedTime = now
+	i.markModified(now)
 	i.LastUsedTime = now
-	i.IsSaved = false
 }
 
 func (i *AttributesItem) Clear() int {
+	i.lock.Lock()
+	defer i.lock.Unlock()
 	size := i.valueMap.Length()
+	if i.journal != nil {
+		i.valueMap.Range(func(key string, value *ds.VMValue) bool {
+			i.journalRecord(key, value, nil)
+			return true
+		})
+	}
 	i.valueMap.Clear()
-	i.LastModifiedTime = time.Now().Unix()
-	i.IsSaved = false
+	i.markModified(time.Now().Unix())
 	return size
 }
 
@@ -454,9 +547,10 @@ func (i *AttributesItem) Range(f func(key string, value *ds.VMValue) bool) {
 }
 
 func (i *AttributesItem) SetSheetType(system string) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
 	i.SheetType = system
-	i.LastModifiedTime = time.Now().Unix()
-	i.IsSaved = false
+	i.markModified(time.Now().Unix())
 }
 
 func (i *AttributesItem) Len() int {
@@ -1,6 +1,7 @@
 package dice
 
 import (
+	"crypto/ed25519"
 	"encoding/json"
 	"fmt"
 	"strings"
@@ -77,9 +78,19 @@ type BanListInfo struct {
 	JointScorePercentOfGroup   float64 `json:"jointScorePercentOfGroup"   yaml:"jointScorePercentOfGroup"`   // 群组连带责任
 	JointScorePercentOfInviter float64 `json:"jointScorePercentOfInviter" yaml:"jointScorePercentOfInviter"` // 邀请人连带责任
 
+	FederationFeedEnable  bool                   `json:"federationFeedEnable"  yaml:"federationFeedEnable"`  // 联合黑名单: 允许其他骰子订阅本骰黑名单
+	FederationFeedToken   string                 `json:"federationFeedToken"   yaml:"federationFeedToken"`   // 联合黑名单: 订阅口令，为空时不校验
+	FederationSyncMinutes int64                  `json:"federationSyncMinutes" yaml:"federationSyncMinutes"` // 联合黑名单: 同步间隔(分)
+	FederationSources     []*BanFederationSource `json:"-"                     yaml:"federationSources"`     // 联合黑名单: 订阅的来源
+
 	helpMasterReplyMu sync.Mutex           `json:"-" yaml:"-"`
 	helpMasterReplyAt map[string]time.Time `json:"-" yaml:"-"`
 	cronID            cron.EntryID         `json:"-" yaml:"-"`
+
+	federationMu       sync.Mutex         `json:"-" yaml:"-"`
+	federationSyncMu   sync.Mutex         `json:"-" yaml:"-"`
+	federationPrivKey  ed25519.PrivateKey `json:"-" yaml:"-"`
+	federationLastSync time.Time          `json:"-" yaml:"-"`
 }
 
 func (i *BanListInfo) Init() {
@@ -99,6 +110,7 @@ func (i *BanListInfo) Init() {
 
 	i.JointScorePercentOfGroup = 0.5
 	i.JointScorePercentOfInviter = 0.3
+	i.FederationSyncMinutes = 30
 	i.Map = new(SyncMap[string, *BanListInfoItem])
 	i.helpMasterReplyAt = map[string]time.Time{}
 }
@@ -119,6 +131,15 @@ func (i *BanListInfo) cleanupBlacklistedHelpMasterReplyAtLocked(now time.Time, c
 }
 
 func (i *BanListInfo) CanReplyBlacklistedHelpMaster(userID string, now time.Time) bool {
+	return i.canReplyBlacklisted(userID, now)
+}
+
+// CanReplyBlacklistedAppeal 黑名单用户的 .appeal 与 .help 骰主 共用冷却时长，但分别计时
+func (i *BanListInfo) CanReplyBlacklistedAppeal(userID string, now time.Time) bool {
+	return i.canReplyBlacklisted("appeal:"+userID, now)
+}
+
+func (i *BanListInfo) canReplyBlacklisted(key string, now time.Time) bool {
 	i.helpMasterReplyMu.Lock()
 	defer i.helpMasterReplyMu.Unlock()
 
@@ -128,11 +149,11 @@ func (i *BanListInfo) CanReplyBlacklistedHelpMaster(userID string, now time.Time
 	cooldown := i.BlacklistedHelpMasterCooldown()
 	i.cleanupBlacklistedHelpMasterReplyAtLocked(now, cooldown)
 
-	if lastReplyAt, ok := i.helpMasterReplyAt[userID]; ok && now.Sub(lastReplyAt) < cooldown {
+	if lastReplyAt, ok := i.helpMasterReplyAt[key]; ok && now.Sub(lastReplyAt) < cooldown {
 		return false
 	}
 
-	i.helpMasterReplyAt[userID] = now
+	i.helpMasterReplyAt[key] = now
 	return true
 }
 
@@ -150,6 +171,11 @@ func (i *BanListInfo) AfterLoads() {
 		if d.DBOperator == nil {
 			return
 		}
+		if i.federationSyncDue(time.Now()) {
+			i.federationLastSync = time.Now()
+			go i.FederationSyncAll()
+		}
+
 		var toDelete []string
 		(&d.Config).BanList.Map.Range(func(k string, v *BanListInfoItem) bool {
 			if v.Rank == BanRankNormal || v.Rank == BanRankWarn {
@@ -208,18 +234,11 @@ func (i *BanListInfo) AddScoreBase(uid string, score int64, place string, reason
 			v.Rank = BanRankBanned
 			v.BanTime = time.Now().Unix()
 
-			if ctx.EndPoint.Platform == "QQ" {
-				switch adapter := ctx.EndPoint.Adapter.(type) {
-				case *PlatformAdapterGocq:
-					adapter.DeleteFriend(ctx, place)
-				case *PlatformAdapterWalleQ:
-					adapter.DeleteFriend(ctx, place)
-				case *PlatformAdapterRed:
-					log.Warn("qq red 适配器不支持删除好友")
-				case *PlatformAdapterOfficialQQ:
-					log.Warn("official qq 适配器不支持删除好友")
-				default:
-					log.Error("unknown qq adapter")
+			if ctx != nil && ctx.EndPoint != nil && ctx.EndPoint.Platform == "QQ" {
+				if deleter, ok := ctx.EndPoint.Adapter.(FriendDeleter); ok && ctx.EndPoint.Capabilities().DeleteFriend {
+					deleter.DeleteFriend(ctx, place)
+				} else {
+					log.Warnf("%s 适配器不支持删除好友", ctx.EndPoint.ProtocolType)
 				}
 			}
 		}
@@ -453,7 +472,9 @@ func (i *BanListInfo) SaveChanged(d *Dice) {
 		if v.UpdatedAt != 0 {
 			data, err := json.Marshal(v)
 			if err == nil {
-				_ = service.BanItemSave(d.DBOperator, k, v.UpdatedAt, v.BanUpdatedAt, data)
+				if service.BanItemSave(d.DBOperator, k, v.UpdatedAt, v.BanUpdatedAt, data) == nil {
+					d.clusterManager().Publish(ClusterEventBan, k)
+				}
 				v.UpdatedAt = 0
 			}
 		}
@@ -463,5 +484,7 @@ func (i *BanListInfo) SaveChanged(d *Dice) {
 
 func (i *BanListInfo) DeleteByID(d *Dice, id string) {
 	i.Map.Delete(id)
-	_ = service.BanItemDel(d.DBOperator, id)
+	if service.BanItemDel(d.DBOperator, id) == nil {
+		d.clusterManager().Publish(ClusterEventBan, id)
+	}
 }
@@ -85,6 +85,8 @@ func (cm *CensorManager) Load(d *Dice) {
 	fileDir := "./data/censor"
 	cm.IsLoading = true
 	cm.Censor.SensitiveKeys = make(map[string]censor.WordInfo)
+	cm.Censor.RegexRules = make(map[string]censor.Level)
+	cm.Censor.AllowWords = make(map[string][]string)
 	_ = os.MkdirAll(fileDir, 0o755)
 	_ = filepath.Walk(fileDir, func(path string, info fs.FileInfo, err error) error {
 		if !info.IsDir() && (filepath.Ext(path) == ".txt" || filepath.Ext(path) == ".toml") {
@@ -92,6 +94,7 @@ func (cm *CensorManager) Load(d *Dice) {
 			fileInfo, e := cm.Censor.PreloadFile(path)
 			if e != nil {
 				log.Errorf("censor: unable to read %s, %v", path, e)
+				return nil
 			}
 			if cm.SensitiveWordsFiles == nil {
 				cm.SensitiveWordsFiles = make(map[string]*censor.WordFile)
@@ -112,9 +115,13 @@ func (cm *CensorManager) Check(ctx *MsgContext, msg *Message, checkContent strin
 		return nil, errors.New("censor is loading")
 	}
 	res := cm.Censor.Check(checkContent)
+	if level := cm.groupLevel(ctx, msg); level > censor.Ignore {
+		res = res.AtLeast(level)
+	}
 	if !ctx.Censored && res.HighestLevel > censor.Ignore {
+		metricCensorHits.Inc(censorLevelName(res.HighestLevel))
 		// 敏感词命中记录保存
-		service.CensorAppend(cm.DB, ctx.MessageType, msg.Sender.UserID, msg.GroupID, msg.Message, res.SensitiveWords, int(res.HighestLevel))
+		service.CensorAppend(cm.DB, ctx.MessageType, msg.Sender.UserID, msg.GroupID, msg.Message, res.SensitiveWords, res.Spans, int(res.HighestLevel))
 	}
 	count := service.CensorCount(cm.DB, msg.Sender.UserID)
 
@@ -130,6 +137,25 @@ func (cm *CensorManager) Check(ctx *MsgContext, msg *Message, checkContent strin
 	}, nil
 }
 
+// groupLevel 群内单独设置的起效级别，低于该级别的命中不做处理。
+// 群设置只会过滤掉较低级别的命中，是否处理仍由全局的阈值与处理方式决定，因此只能放宽、不能加严
+func (cm *CensorManager) groupLevel(ctx *MsgContext, msg *Message) censor.Level {
+	if msg.MessageType != "group" {
+		return censor.Ignore
+	}
+	group := ctx.Group
+	if group == nil || group.GroupID != msg.GroupID {
+		if ctx.Session == nil {
+			return censor.Ignore
+		}
+		group, _ = ctx.Session.ServiceAtNew.Load(msg.GroupID)
+	}
+	if group == nil {
+		return censor.Ignore
+	}
+	return group.CensorLevel
+}
+
 type MsgCheckResult struct {
 	UserID            string
 	Level             censor.Level
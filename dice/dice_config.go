@@ -1,6 +1,7 @@
 package dice
 
 import (
+	"slices"
 	"time"
 
 	"github.com/robfig/cron/v3"
@@ -66,6 +67,19 @@ func (c *Config) LoadYamlConfig(data []byte) error {
 		return err
 	}
 	c.migrateOld2Version1()
+	if c.ScheduleMaxPerGroup == 0 {
+		c.ScheduleMaxPerGroup = DefaultConfig.ScheduleMaxPerGroup
+	}
+	c.RateLimitPolicies = slices.DeleteFunc(c.RateLimitPolicies, func(p *RateLimitPolicy) bool {
+		if p == nil {
+			return true
+		}
+		if errParse := p.Parse(); errParse != nil {
+			c.d.Logger.Errorf("频率限制策略无效，已忽略: %v", errParse)
+			return true
+		}
+		return false
+	})
 	return nil
 }
 
@@ -183,6 +197,8 @@ type RateLimitConfig struct {
 	GroupReplenishRate       rate.Limit `json:"-"                     yaml:"-"`                     // 群组刷屏警告速率
 	PersonalBurst            int64      `json:"personalBurst"         yaml:"personalBurst"`         // 个人自定义上限
 	GroupBurst               int64      `json:"groupBurst"            yaml:"groupBurst"`            // 群组自定义上限
+
+	RateLimitPolicies []*RateLimitPolicy `json:"-" yaml:"rateLimitPolicies"` // 按指令、扩展、群组等细分的频率限制策略，与刷屏警告独立生效
 }
 
 type QuitInactiveConfig struct {
@@ -198,10 +214,12 @@ type QuitInactiveConfig struct {
 }
 
 type ExtConfig struct {
-	DefaultCocRuleIndex int64 `jsbind:"defaultCocRuleIndex" json:"-" yaml:"defaultCocRuleIndex"`                   // 默认coc index
-	MaxExecuteTime      int64 `jsbind:"maxExecuteTime"      json:"-" yaml:"maxExecuteTime"`                        // 最大骰点次数
-	MaxCocCardGen       int64 `jsbind:"maxCocCardGen"       json:"-" yaml:"maxCocCardGen"`                         // 最大coc制卡数
-	CocCardMergeForward bool  `jsbind:"cocCardMergeForward" json:"cocCardMergeForward" yaml:"cocCardMergeForward"` // COC制卡是否使用合并转发（默认关闭）
+	DefaultCocRuleIndex int64  `jsbind:"defaultCocRuleIndex" json:"-" yaml:"defaultCocRuleIndex"`                   // 默认coc index
+	MaxExecuteTime      int64  `jsbind:"maxExecuteTime"      json:"-" yaml:"maxExecuteTime"`                        // 最大骰点次数
+	MaxCocCardGen       int64  `jsbind:"maxCocCardGen"       json:"-" yaml:"maxCocCardGen"`                         // 最大coc制卡数
+	CocCardMergeForward bool   `jsbind:"cocCardMergeForward" json:"cocCardMergeForward" yaml:"cocCardMergeForward"` // COC制卡是否使用合并转发（默认关闭）
+	ScheduleMaxPerGroup int64  `json:"scheduleMaxPerGroup" yaml:"scheduleMaxPerGroup"`                              // 每个群最多的定时任务数
+	RNGMode             string `json:"rngMode" yaml:"rngMode"`                                                      // 默认随机源模式，群内可用 .rng 单独设置
 
 	ExtDefaultSettings []*ExtDefaultSettingItem `json:"extDefaultSettings" yaml:"extDefaultSettings"` // 新群扩展按此顺序加载
 }
@@ -218,6 +236,11 @@ type JsConfig struct {
 type StoryLogConfig struct {
 	LogSizeNoticeEnable bool `json:"logSizeNoticeEnable" yaml:"logSizeNoticeEnable"` // 开启日志数量提示
 	LogSizeNoticeCount  int  `json:"logSizeNoticeCount"  yaml:"LogSizeNoticeCount"`  // 日志数量提示阈值，默认500
+
+	LogShareLocalPrefer bool   `json:"logShareLocalPrefer" yaml:"logShareLocalPrefer"` // .log get 优先回复本地查看链接，不上传日志
+	LogShareBaseURL     string `json:"logShareBaseUrl"     yaml:"logShareBaseUrl"`     // 本地查看链接的外部访问地址，如 http://example.com:3211
+	LogShareExpireHours int    `json:"logShareExpireHours" yaml:"logShareExpireHours"` // 本地查看链接有效期(小时)，默认72
+	LogShareSecret      string `json:"-"                   yaml:"logShareSecret"`      // 本地查看链接签名密钥，首次使用时生成
 }
 
 type MailConfig struct {
@@ -68,6 +68,7 @@ var DefaultConfig = Config{
 		MaxExecuteTime:      12,
 		MaxCocCardGen:       5,
 		CocCardMergeForward: false,
+		ScheduleMaxPerGroup: 10,
 		ExtDefaultSettings:  make([]*ExtDefaultSettingItem, 0),
 	},
 	BanConfig{
@@ -80,6 +81,7 @@ var DefaultConfig = Config{
 	StoryLogConfig{
 		LogSizeNoticeEnable: true,
 		LogSizeNoticeCount:  500,
+		LogShareExpireHours: 72,
 	},
 	MailConfig{
 		MailEnable:   false,
@@ -70,6 +70,8 @@ type HelpManager struct {
 	Config *HelpConfig
 
 	docIDs []string
+	// markdownPacks Load 期间按目录缓存的 Markdown 帮助包
+	markdownPacks map[string]*helpMarkdownPack
 }
 
 type EngineType int
@@ -83,9 +85,15 @@ const (
 const HelpConfigFilename = "help_config.yaml"
 
 type HelpConfig struct {
+	// Aliases 分组别名，同时作为搜索时的同义词表
 	Aliases map[string][]string `json:"aliases" yaml:"aliases"`
+	// Fuzziness 搜索时模糊匹配的编辑距离，0 为默认值，负数关闭模糊匹配，最大为 2
+	Fuzziness int `json:"fuzziness" yaml:"fuzziness,omitempty"`
 }
 
+// 未配置时搜索允许的编辑距离
+const defaultHelpFuzziness = 1
+
 type HelpDocFormat struct {
 	Mod     string            `json:"mod"`
 	Author  string            `json:"author"`
@@ -160,12 +168,17 @@ func (m *HelpManager) Load(dice *Dice, internalCmdMap CmdMapCls, extList []*ExtI
 	}
 
 	indexFreshlyCreated := m.loadSearchEngine()
+	m.applySearchOptions()
 	if metaTrusted && indexFreshlyCreated {
 		log.Warnf("[帮助文档] 检测到 Bleve 索引已重新创建，将忽略旧 meta 并执行全量重建")
 	}
 	indexMeta, _ = reconcileHelpIndexMeta(indexMeta, metaTrusted, indexFreshlyCreated)
 
 	m.docIDs = make([]string, 0)
+	m.markdownPacks = nil
+	defer func() {
+		m.markdownPacks = nil
+	}()
 
 	newMeta := newEmptyHelpIndexMeta()
 
@@ -215,6 +228,8 @@ func (m *HelpManager) Load(dice *Dice, internalCmdMap CmdMapCls, extList []*ExtI
 .dnd // dnd5版任务做成
 .pc new <角色名> // 创建角色并自动绑卡，无角色名则为当前
 .pc tag <角色名> // 当前群绑卡/解除绑卡(不填角色名)
+.pc import // 附带角色卡文件或粘贴 JSON/CSV，导入为新角色
+.pc export // 导出角色快照，可用 .pc load-snapshot 在其他骰子恢复
 .pc save <角色名> // 保存角色[不绑卡时需要手动保存]，无角色名则为当前
 .pc load <角色名> // 加载角色[不绑卡]，无角色名则为当前
 .pc list //列出当前角色
@@ -260,7 +275,7 @@ func (m *HelpManager) Load(dice *Dice, internalCmdMap CmdMapCls, extList []*ExtI
 				return
 			}
 			filePath := filepath.Clean(d.Path)
-			hash, size, hashErr := computeHelpFileHash(filePath)
+			hash, size, hashErr := m.computeHelpDocHash(filePath)
 			if hashErr != nil {
 				d.LoadStatus = LoadError
 				return
@@ -304,7 +319,7 @@ func (m *HelpManager) Load(dice *Dice, internalCmdMap CmdMapCls, extList []*ExtI
 					return nil
 				}
 				filePath := filepath.Clean(d.Path)
-				hash, size, hashErr := computeHelpFileHash(filePath)
+				hash, size, hashErr := m.computeHelpDocHash(filePath)
 				if hashErr != nil {
 					d.LoadStatus = LoadError
 					return hashErr
@@ -401,6 +416,22 @@ func (m *HelpManager) refreshHelpGroupAliases(config HelpConfig) {
 			}
 		}
 	}
+	m.applySearchOptions()
+}
+
+// applySearchOptions 将帮助文档配置中的同义词与模糊匹配设置下发给搜索引擎
+func (m *HelpManager) applySearchOptions() {
+	if m.searchEngine == nil {
+		return
+	}
+	opts := docengine.SearchOptions{Fuzziness: defaultHelpFuzziness}
+	if m.Config != nil {
+		opts.Synonyms = m.Config.Aliases
+		if m.Config.Fuzziness != 0 {
+			opts.Fuzziness = max(m.Config.Fuzziness, 0)
+		}
+	}
+	m.searchEngine.SetSearchOptions(opts)
 }
 
 func (m *HelpManager) SaveHelpConfig(config *HelpConfig) error {
@@ -422,7 +453,7 @@ func (m *HelpManager) loadHelpDoc(group string, path string) bool {
 	log := logger.M()
 	fileExt := filepath.Ext(path)
 
-	switch fileExt {
+	switch strings.ToLower(fileExt) {
 	case ".json":
 		m.LoadingFn = path
 		data := HelpDocFormat{}
@@ -496,6 +527,9 @@ func (m *HelpManager) loadHelpDoc(group string, path string) bool {
 			log.Error("HelpManager.loadHelpDoc", err)
 		}
 		return true
+	case helpMarkdownExt:
+		m.LoadingFn = path
+		return m.loadHelpMarkdown(group, path)
 	}
 	return false
 }
@@ -956,15 +990,20 @@ func buildPackageHelpDocTree(files []PackageContentFile) []*HelpDoc {
 	return roots
 }
 
-func (m *HelpManager) UploadHelpDoc(src io.Reader, group string, name string) error {
-	name = strings.ReplaceAll(name, "/", "_")
-	name = strings.ReplaceAll(name, "\\", "_")
+func helpDocUploadGroup(group string) string {
 	group = strings.ReplaceAll(group, "/", "_")
 	group = strings.ReplaceAll(group, "\\", "_")
 	if group == "default" {
 		// 默认组直接上传到helpdoc文件夹中
 		group = ""
 	}
+	return group
+}
+
+func (m *HelpManager) UploadHelpDoc(src io.Reader, group string, name string) error {
+	name = strings.ReplaceAll(name, "/", "_")
+	name = strings.ReplaceAll(name, "\\", "_")
+	group = helpDocUploadGroup(group)
 
 	dirPath := filepath.Join("./data/helpdoc", group)
 	err := os.MkdirAll(dirPath, 0755)
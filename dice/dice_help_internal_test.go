@@ -3,6 +3,7 @@ package dice
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"os"
 	"testing"
 
@@ -76,6 +77,8 @@ func (f *fakeHelpSearchEngine) AddItem(docengine.HelpTextItem) (string, error) {
 
 func (f *fakeHelpSearchEngine) AddItemApply(bool) error { return nil }
 
+func (f *fakeHelpSearchEngine) SetSearchOptions(docengine.SearchOptions) {}
+
 func (f *fakeHelpSearchEngine) Search([]string, string, bool, int, int, string) (*docengine.GeneralSearchResult, int, int, int, error) {
 	return nil, 0, 0, 0, nil
 }
@@ -198,3 +201,100 @@ func TestReconcileHelpIndexMeta(t *testing.T) {
 		}
 	})
 }
+
+// helpSearchRelevanceCases 基于内置帮助文档的搜索相关性用例，want 需要出现在前 top 条结果中
+var helpSearchRelevanceCases = []struct {
+	query string
+	want  string
+	top   int
+}{
+	{"骰点", "骰点", 1},
+	{"骰點", "骰点", 1},      // 繁体
+	{"擴展", "扩展", 1},      // 繁体
+	{"paotuan", "跑团", 1}, // 拼音
+	{"泡团", "跑团", 1},      // 同音错字
+	{"TRPG", "跑团", 1},    // 同义词
+	{"setcok", "setcoc", 1},
+	{"drwa", "draw", 1},
+	{"draw", "draw", 1},
+	{"sc", "sc", 1},
+	{"暗夜刀锋", "bitd", 1},
+	{"牌堆", "draw", 3},
+	{"先攻", "init", 3},
+}
+
+func newHelpSearchFixture(tb testing.TB) (*HelpManager, func()) {
+	tb.Helper()
+	d, _, _, cleanup := newExecuteNewTestDice(tb)
+	tb.Chdir(tb.TempDir())
+
+	m := &HelpManager{Config: &HelpConfig{Aliases: map[string][]string{"跑团": {"TRPG"}}}}
+	d.Parent.Help = m
+	m.Load(nil, d.CmdMap, d.ExtList)
+	if !m.IsAvailable() {
+		cleanup()
+		tb.Fatal("help search engine unavailable")
+	}
+	return m, cleanup
+}
+
+// helpSearchMisses 返回未命中的用例
+func helpSearchMisses(tb testing.TB, m *HelpManager) []string {
+	var misses []string
+	for _, c := range helpSearchRelevanceCases {
+		res, _, _, _, err := m.searchEngine.Search(nil, c.query, false, c.top, 1, "")
+		if err != nil {
+			tb.Fatalf("Search(%q) error = %v", c.query, err)
+		}
+		found := false
+		titles := make([]string, 0, len(res.Hits))
+		for _, hit := range res.Hits {
+			title := fmt.Sprintf("%v", hit.Fields["title"])
+			titles = append(titles, title)
+			found = found || title == c.want
+		}
+		if !found {
+			misses = append(misses, fmt.Sprintf("%q: want %q in top %d, got %v", c.query, c.want, c.top, titles))
+		}
+	}
+	return misses
+}
+
+func TestHelpSearchRelevance(t *testing.T) {
+	m, cleanup := newHelpSearchFixture(t)
+	defer cleanup()
+
+	for _, miss := range helpSearchMisses(t, m) {
+		t.Error(miss)
+	}
+
+	// 关闭模糊匹配后拼写错误不再命中
+	m.Config.Fuzziness = -1
+	m.applySearchOptions()
+	res, _, _, _, err := m.searchEngine.Search(nil, "setcok", true, 5, 1, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Hits) != 0 {
+		t.Fatalf("fuzzy matching should be disabled, got %d hits", len(res.Hits))
+	}
+}
+
+// BenchmarkHelpSearch 在内置帮助文档上测量搜索耗时，同时报告相关性用例的命中率
+func BenchmarkHelpSearch(b *testing.B) {
+	m, cleanup := newHelpSearchFixture(b)
+	defer cleanup()
+
+	misses := helpSearchMisses(b, m)
+	for _, miss := range misses {
+		b.Error(miss)
+	}
+
+	for i := 0; b.Loop(); i++ {
+		c := helpSearchRelevanceCases[i%len(helpSearchRelevanceCases)]
+		if _, _, _, _, err := m.searchEngine.Search(nil, c.query, false, 10, 1, ""); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(len(helpSearchRelevanceCases)-len(misses))/float64(len(helpSearchRelevanceCases)), "hit-rate")
+}
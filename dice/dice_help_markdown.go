@@ -0,0 +1,433 @@
+package dice
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/cespare/xxhash/v2"
+	"gopkg.in/yaml.v3"
+
+	"sealdice-core/dice/docengine"
+	"sealdice-core/logger"
+)
+
+// Markdown 帮助包：同一目录下的 .md 文件构成一个帮助包
+// 每个标题(# ~ ######)对应一个词条，正文到下一个标题为止
+// 文件开头的 front-matter 提供包名、版本、分组与别名，正文中的 [[词条]] / [[词条|显示文字]] 会被解析为包内词条引用
+
+const helpMarkdownExt = ".md"
+
+// HelpMarkdownFrontMatter .md 帮助文件开头 --- 包裹的 yaml 元数据
+type HelpMarkdownFrontMatter struct {
+	Pack    string              `yaml:"pack"`
+	Version string              `yaml:"version"`
+	Author  string              `yaml:"author"`
+	Brief   string              `yaml:"brief"`
+	Group   string              `yaml:"group"`
+	Aliases map[string][]string `yaml:"aliases"`
+}
+
+type HelpMarkdownEntry struct {
+	Title   string
+	Aliases []string
+	Level   int
+	Line    int
+	Content string
+
+	body     string
+	children []*HelpMarkdownEntry
+	skipped  bool
+}
+
+type HelpMarkdownFile struct {
+	Path        string
+	FrontMatter HelpMarkdownFrontMatter
+	Entries     []*HelpMarkdownEntry
+}
+
+// PackageName 词条所属的包名，带版本时为 包名@版本
+func (f *HelpMarkdownFile) PackageName() string {
+	name := f.FrontMatter.Pack
+	if name == "" {
+		name = strings.TrimSuffix(filepath.Base(f.Path), filepath.Ext(f.Path))
+	}
+	if f.FrontMatter.Version != "" {
+		name += "@" + f.FrontMatter.Version
+	}
+	return name
+}
+
+type HelpMarkdownLocation struct {
+	File string `json:"file"`
+	Line int    `json:"line"`
+}
+
+func (l HelpMarkdownLocation) String() string {
+	return fmt.Sprintf("%s:%d", filepath.Base(l.File), l.Line)
+}
+
+type HelpMarkdownDuplicate struct {
+	Title     string                 `json:"title"`
+	Locations []HelpMarkdownLocation `json:"locations"`
+}
+
+type HelpMarkdownBrokenLink struct {
+	Target   string               `json:"target"`
+	Location HelpMarkdownLocation `json:"location"`
+}
+
+// HelpMarkdownReport 导入 Markdown 帮助包时的校验报告
+type HelpMarkdownReport struct {
+	Dir         string                   `json:"dir"`
+	Packs       []string                 `json:"packs"`
+	Files       int                      `json:"files"`
+	Entries     int                      `json:"entries"`
+	Duplicates  []HelpMarkdownDuplicate  `json:"duplicates"`
+	BrokenLinks []HelpMarkdownBrokenLink `json:"brokenLinks"`
+	Errors      []string                 `json:"errors"`
+}
+
+func (r *HelpMarkdownReport) HasProblem() bool {
+	return len(r.Duplicates) > 0 || len(r.BrokenLinks) > 0 || len(r.Errors) > 0
+}
+
+func (r *HelpMarkdownReport) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s: %d个文件，%d个词条", r.Dir, r.Files, r.Entries)
+	for _, dup := range r.Duplicates {
+		locs := make([]string, len(dup.Locations))
+		for i, loc := range dup.Locations {
+			locs[i] = loc.String()
+		}
+		fmt.Fprintf(&sb, "\n重复标题 %s: %s", dup.Title, strings.Join(locs, ", "))
+	}
+	for _, link := range r.BrokenLinks {
+		fmt.Fprintf(&sb, "\n未找到链接目标 [[%s]]: %s", link.Target, link.Location)
+	}
+	for _, e := range r.Errors {
+		sb.WriteString("\n")
+		sb.WriteString(e)
+	}
+	return sb.String()
+}
+
+// helpMarkdownPack 一个目录下全部 .md 文件的解析结果
+type helpMarkdownPack struct {
+	Hash   uint64
+	Files  map[string]*HelpMarkdownFile
+	Report *HelpMarkdownReport
+}
+
+var (
+	helpMarkdownHeadingRe = regexp.MustCompile(`^(#{1,6})\s+(.+?)\s*#*\s*$`)
+	helpMarkdownLinkRe    = regexp.MustCompile(`\[\[([^\[\]\n|]+)(?:\|([^\[\]\n]+))?\]\]`)
+	helpMarkdownRefRe     = regexp.MustCompile(`\{[^}\n]+\}`)
+)
+
+func helpMarkdownKey(title string) string {
+	return strings.ToLower(strings.TrimSpace(title))
+}
+
+// splitHelpMarkdownFrontMatter 拆出开头的 front-matter，返回元数据、正文与正文起始行号
+func splitHelpMarkdownFrontMatter(data []byte) (HelpMarkdownFrontMatter, string, int, error) {
+	var fm HelpMarkdownFrontMatter
+	text := strings.ReplaceAll(string(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))), "\r\n", "\n")
+	lines := strings.Split(text, "\n")
+	if strings.TrimSpace(lines[0]) != "---" {
+		return fm, text, 1, nil
+	}
+	for i := 1; i < len(lines); i++ {
+		if strings.TrimSpace(lines[i]) != "---" {
+			continue
+		}
+		if err := yaml.Unmarshal([]byte(strings.Join(lines[1:i], "\n")), &fm); err != nil {
+			return fm, "", 1, fmt.Errorf("front-matter 格式错误: %w", err)
+		}
+		return fm, strings.Join(lines[i+1:], "\n"), i + 2, nil
+	}
+	return fm, "", 1, errors.New("front-matter 缺少结束标记 ---")
+}
+
+// ParseHelpMarkdown 解析单个 .md 帮助文件，词条正文中的链接尚未解析
+func ParseHelpMarkdown(path string, data []byte) (*HelpMarkdownFile, error) {
+	fm, body, lineNo, err := splitHelpMarkdownFrontMatter(data)
+	file := &HelpMarkdownFile{Path: path, FrontMatter: fm}
+	if err != nil {
+		return file, err
+	}
+	if fm.Version != "" {
+		if _, err = semver.NewVersion(fm.Version); err != nil {
+			return file, fmt.Errorf("版本号 %s 不是合法的语义化版本", fm.Version)
+		}
+	}
+
+	var (
+		current *HelpMarkdownEntry
+		parents []*HelpMarkdownEntry
+		buf     strings.Builder
+		fence   string
+	)
+	flush := func() {
+		if current != nil {
+			current.body = strings.Trim(buf.String(), "\n")
+		}
+		buf.Reset()
+	}
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for ; scanner.Scan(); lineNo++ {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+		// 代码块中的 # 不是标题
+		if fence != "" {
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+			}
+		} else if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			fence = trimmed[:3]
+		} else if m := helpMarkdownHeadingRe.FindStringSubmatch(line); m != nil {
+			flush()
+			current = &HelpMarkdownEntry{Title: m[2], Level: len(m[1]), Line: lineNo}
+			for len(parents) > 0 && parents[len(parents)-1].Level >= current.Level {
+				parents = parents[:len(parents)-1]
+			}
+			if len(parents) > 0 {
+				parent := parents[len(parents)-1]
+				parent.children = append(parent.children, current)
+			}
+			parents = append(parents, current)
+			file.Entries = append(file.Entries, current)
+			continue
+		}
+		if current != nil {
+			buf.WriteString(line)
+			buf.WriteByte('\n')
+		}
+	}
+	flush()
+	if err = scanner.Err(); err != nil {
+		return file, err
+	}
+
+	// 既无正文也无子条目的标题不作为词条，倒序处理以便先确定子条目
+	for i := len(file.Entries) - 1; i >= 0; i-- {
+		entry := file.Entries[i]
+		entry.children = slices.DeleteFunc(entry.children, func(c *HelpMarkdownEntry) bool { return c.skipped })
+		entry.skipped = entry.body == "" && len(entry.children) == 0
+		entry.Aliases = fm.Aliases[entry.Title]
+	}
+	file.Entries = slices.DeleteFunc(file.Entries, func(e *HelpMarkdownEntry) bool { return e.skipped })
+	return file, nil
+}
+
+// renderHelpMarkdownEntry 生成词条正文：转义花括号，解析 [[链接]] 并在末尾列出相关词条
+func renderHelpMarkdownEntry(entry *HelpMarkdownEntry, resolve func(string) (string, bool), broken func(string)) string {
+	// 花括号在帮助文档中表示嵌入其他词条，md 中的花括号按原文显示
+	text := helpMarkdownRefRe.ReplaceAllStringFunc(entry.body, func(s string) string {
+		return `\` + s
+	})
+	var related []string
+	text = helpMarkdownLinkRe.ReplaceAllStringFunc(text, func(s string) string {
+		m := helpMarkdownLinkRe.FindStringSubmatch(s)
+		target := strings.TrimSpace(m[1])
+		shown := strings.TrimSpace(m[2])
+		if shown == "" {
+			shown = target
+		}
+		if title, ok := resolve(target); ok {
+			if !slices.Contains(related, title) {
+				related = append(related, title)
+			}
+		} else {
+			broken(target)
+		}
+		return "【" + shown + "】"
+	})
+	if len(entry.children) > 0 {
+		if text != "" {
+			text += "\n"
+		}
+		titles := make([]string, len(entry.children))
+		for i, c := range entry.children {
+			titles[i] = c.Title
+		}
+		text += "子条目: " + strings.Join(titles, "、")
+	}
+	if len(related) > 0 {
+		text += "\n相关词条: " + strings.Join(related, "、")
+	}
+	return text
+}
+
+// loadHelpMarkdownPack 解析目录下的全部 .md 文件，解析包内链接并生成校验报告
+// 返回的 Hash 涵盖目录内全部 .md 文件，任一文件变动都会使整个包重新索引，以保证链接解析结果一致
+func loadHelpMarkdownPack(dir string) (*helpMarkdownPack, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	pack := &helpMarkdownPack{
+		Files:  make(map[string]*HelpMarkdownFile),
+		Report: &HelpMarkdownReport{Dir: dir},
+	}
+	report := pack.Report
+	h := xxhash.New()
+	var files []*HelpMarkdownFile
+	for _, e := range entries {
+		if e.IsDir() || strings.HasPrefix(e.Name(), ".") || !strings.EqualFold(filepath.Ext(e.Name()), helpMarkdownExt) {
+			continue
+		}
+		p := filepath.Join(dir, e.Name())
+		data, readErr := os.ReadFile(p)
+		if readErr != nil {
+			return nil, readErr
+		}
+		_, _ = h.WriteString(e.Name())
+		_, _ = h.Write(data)
+
+		file, parseErr := ParseHelpMarkdown(p, data)
+		if parseErr != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("%s: %v", e.Name(), parseErr))
+		}
+		pack.Files[p] = file
+		files = append(files, file)
+		report.Files++
+		report.Entries += len(file.Entries)
+		if name := file.PackageName(); !slices.Contains(report.Packs, name) {
+			report.Packs = append(report.Packs, name)
+		}
+	}
+	pack.Hash = h.Sum64()
+
+	// 建立标题与别名索引，同时检查重复
+	index := make(map[string]string)
+	seen := make(map[string]*HelpMarkdownDuplicate)
+	var dupOrder []string
+	for _, file := range files {
+		for _, entry := range file.Entries {
+			loc := HelpMarkdownLocation{File: file.Path, Line: entry.Line}
+			for _, name := range append([]string{entry.Title}, entry.Aliases...) {
+				key := helpMarkdownKey(name)
+				if key == "" {
+					continue
+				}
+				if dup, ok := seen[key]; ok {
+					if len(dup.Locations) == 1 {
+						dupOrder = append(dupOrder, key)
+					}
+					dup.Locations = append(dup.Locations, loc)
+					continue
+				}
+				seen[key] = &HelpMarkdownDuplicate{Title: name, Locations: []HelpMarkdownLocation{loc}}
+				index[key] = entry.Title
+			}
+		}
+	}
+	for _, key := range dupOrder {
+		report.Duplicates = append(report.Duplicates, *seen[key])
+	}
+
+	resolve := func(target string) (string, bool) {
+		title, ok := index[helpMarkdownKey(target)]
+		return title, ok
+	}
+	for _, file := range files {
+		for _, entry := range file.Entries {
+			loc := HelpMarkdownLocation{File: file.Path, Line: entry.Line}
+			entry.Content = renderHelpMarkdownEntry(entry, resolve, func(target string) {
+				report.BrokenLinks = append(report.BrokenLinks, HelpMarkdownBrokenLink{Target: target, Location: loc})
+			})
+		}
+	}
+	sort.Strings(report.Packs)
+	return pack, nil
+}
+
+// ValidateHelpMarkdownPack 对目录下的 Markdown 帮助包生成校验报告，目录中没有 .md 文件时返回 nil
+func ValidateHelpMarkdownPack(dir string) (*HelpMarkdownReport, error) {
+	pack, err := loadHelpMarkdownPack(dir)
+	if err != nil {
+		return nil, err
+	}
+	if pack.Report.Files == 0 {
+		return nil, nil //nolint:nilnil
+	}
+	return pack.Report, nil
+}
+
+// markdownPack 取得 Load 期间缓存的帮助包，同一目录只解析一次
+func (m *HelpManager) markdownPack(dir string) (*helpMarkdownPack, error) {
+	dir = filepath.Clean(dir)
+	if pack, ok := m.markdownPacks[dir]; ok {
+		return pack, nil
+	}
+	pack, err := loadHelpMarkdownPack(dir)
+	if err != nil {
+		return nil, err
+	}
+	if m.markdownPacks == nil {
+		m.markdownPacks = make(map[string]*helpMarkdownPack)
+	}
+	m.markdownPacks[dir] = pack
+	if pack.Report.HasProblem() {
+		logger.M().Warnf("[帮助文档] Markdown 帮助包校验: %s", pack.Report)
+	}
+	return pack, nil
+}
+
+// computeHelpDocHash 计算帮助文件的索引 hash，.md 文件使用所在帮助包的整体 hash
+func (m *HelpManager) computeHelpDocHash(filePath string) (uint64, int64, error) {
+	hash, size, err := computeHelpFileHash(filePath)
+	if err != nil || !strings.EqualFold(filepath.Ext(filePath), helpMarkdownExt) {
+		return hash, size, err
+	}
+	pack, err := m.markdownPack(filepath.Dir(filePath))
+	if err != nil {
+		return 0, 0, err
+	}
+	return pack.Hash, size, nil
+}
+
+func (m *HelpManager) loadHelpMarkdown(group string, path string) bool {
+	pack, err := m.markdownPack(filepath.Dir(path))
+	if err != nil {
+		logger.M().Error("HelpManager.loadHelpDoc", err)
+		return false
+	}
+	file := pack.Files[filepath.Clean(path)]
+	if file == nil {
+		return false
+	}
+	if file.FrontMatter.Group != "" {
+		group = file.FrontMatter.Group
+	}
+	packageName := file.PackageName()
+	for _, entry := range file.Entries {
+		title := entry.Title
+		if len(entry.Aliases) > 0 {
+			title += "/" + strings.Join(entry.Aliases, "/")
+		}
+		_ = m.AddItem(docengine.HelpTextItem{
+			Group:       group,
+			From:        path,
+			Title:       title,
+			Content:     entry.Content,
+			PackageName: packageName,
+		})
+	}
+	return true
+}
+
+// ValidateHelpDocGroup 校验上传目标分组中的 Markdown 帮助包，用于上传后返回导入报告
+func (m *HelpManager) ValidateHelpDocGroup(group string) (*HelpMarkdownReport, error) {
+	return ValidateHelpMarkdownPack(filepath.Join("./data/helpdoc", helpDocUploadGroup(group)))
+}
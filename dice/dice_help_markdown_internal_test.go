@@ -0,0 +1,142 @@
+package dice
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"sealdice-core/dice/docengine"
+)
+
+type recordingHelpSearchEngine struct {
+	fakeHelpSearchEngine
+	items []docengine.HelpTextItem
+}
+
+func (r *recordingHelpSearchEngine) AddItem(item docengine.HelpTextItem) (string, error) {
+	r.items = append(r.items, item)
+	return "", nil
+}
+
+func TestParseHelpMarkdown(t *testing.T) {
+	data := "---\npack: 规则书\nversion: 1.2.0\naliases:\n  SAN检定: [理智检定, sc]\n---\n" +
+		"前言不会成为词条\n" +
+		"# 第一章\n" +
+		"## SAN检定\n目睹恐怖之物时进行。\n```\n# 代码块里的井号\n```\n" +
+		"## 空标题\n" +
+		"### 空子标题\n" +
+		"## 疯狂\n临时疯狂与永久疯狂。\n"
+	file, err := ParseHelpMarkdown("rules.md", []byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if file.PackageName() != "规则书@1.2.0" {
+		t.Fatalf("PackageName() = %q", file.PackageName())
+	}
+	titles := make([]string, len(file.Entries))
+	for i, e := range file.Entries {
+		titles[i] = e.Title
+	}
+	if strings.Join(titles, ",") != "第一章,SAN检定,疯狂" {
+		t.Fatalf("entries = %v", titles)
+	}
+	san := file.Entries[1]
+	if san.Line != 9 || strings.Join(san.Aliases, ",") != "理智检定,sc" || !strings.Contains(san.body, "# 代码块里的井号") {
+		t.Fatalf("unexpected entry: %+v", san)
+	}
+	if len(file.Entries[0].children) != 2 {
+		t.Fatalf("empty headings should not be listed as children: %d", len(file.Entries[0].children))
+	}
+
+	if _, err = ParseHelpMarkdown("bad.md", []byte("---\nversion: 一点二\n---\n# A\nB")); err == nil {
+		t.Fatal("invalid version should be rejected")
+	}
+	if _, err = ParseHelpMarkdown("bad.md", []byte("---\npack: A\n# A\nB")); err == nil {
+		t.Fatal("unterminated front-matter should be rejected")
+	}
+}
+
+func TestHelpMarkdownPack(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("a.md", "---\npack: 规则书\ngroup: coc\naliases:\n  SAN检定: [理智检定]\n---\n"+
+		"# SAN检定\n失败时见[[疯狂]]，公式{a}。\n"+
+		"# 重复\n一\n")
+	write("b.md", "# 疯狂\n由[[理智检定|SAN检定]]触发，参见[[不存在]]。\n"+
+		"# 重复\n二\n")
+	write("notes.txt", "# 不是帮助文件\n")
+
+	report, err := ValidateHelpMarkdownPack(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Files != 2 || report.Entries != 4 {
+		t.Fatalf("unexpected report: %s", report)
+	}
+	if len(report.Duplicates) != 1 || report.Duplicates[0].Title != "重复" || len(report.Duplicates[0].Locations) != 2 {
+		t.Fatalf("unexpected duplicates: %+v", report.Duplicates)
+	}
+	if len(report.BrokenLinks) != 1 || report.BrokenLinks[0].Target != "不存在" || report.BrokenLinks[0].Location.String() != "b.md:1" {
+		t.Fatalf("unexpected broken links: %+v", report.BrokenLinks)
+	}
+
+	engine := &recordingHelpSearchEngine{}
+	m := &HelpManager{searchEngine: engine}
+	if !m.loadHelpDoc("default", filepath.Join(dir, "a.md")) || !m.loadHelpDoc("default", filepath.Join(dir, "b.md")) {
+		t.Fatal("markdown help doc should load")
+	}
+	if len(engine.items) != 4 {
+		t.Fatalf("items = %d, want 4", len(engine.items))
+	}
+	san := engine.items[0]
+	if san.Title != "SAN检定/理智检定" || san.Group != "coc" || san.PackageName != "规则书" {
+		t.Fatalf("unexpected item: %+v", san)
+	}
+	if san.Content != "失败时见【疯狂】，公式\\{a}。\n相关词条: 疯狂" {
+		t.Fatalf("unexpected content: %q", san.Content)
+	}
+	if got := m.GetContent(&san, 0); !strings.Contains(got, "公式{a}") {
+		t.Fatalf("braces should be shown literally: %q", got)
+	}
+	if madness := engine.items[2]; madness.Group != "default" || madness.PackageName != "b" ||
+		madness.Content != "由【SAN检定】触发，参见【不存在】。\n相关词条: SAN检定" {
+		t.Fatalf("unexpected item: %+v", madness)
+	}
+
+	// 包内任一文件变动都会改变其他 .md 文件的索引 hash
+	before, _, err := m.computeHelpDocHash(filepath.Join(dir, "a.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	write("b.md", "# 疯狂\n改名了\n")
+	m.markdownPacks = nil
+	after, _, err := m.computeHelpDocHash(filepath.Join(dir, "a.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if before == after {
+		t.Fatal("pack hash should change when a sibling file changes")
+	}
+}
+
+// 扩展名大小写不同的 .MD 文件同样会被索引
+func TestHelpMarkdownUpperCaseExt(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "c.MD"), []byte("# 疯狂\n一\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	engine := &recordingHelpSearchEngine{}
+	m := &HelpManager{searchEngine: engine}
+	if !m.loadHelpDoc("default", filepath.Join(dir, "c.MD")) {
+		t.Fatal("markdown help doc with upper-case extension should load")
+	}
+	if len(engine.items) != 1 || engine.items[0].Title != "疯狂" {
+		t.Fatalf("unexpected items: %+v", engine.items)
+	}
+}
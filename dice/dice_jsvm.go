@@ -411,6 +411,19 @@ func (d *Dice) JsInit() {
 			}
 			d.ConfigManager.UnregisterConfig(ei.Name, key...)
 		})
+		// registerCommandCost 声明指令开销，如 seal.ext.registerCommandCost(ext, ["draw"], "@every 10s", 4, 2, "user", "")
+		// 表示每 10 秒回复 1 个令牌，桶容量 4，每次执行 draw 消耗 2 个
+		_ = ext.Set("registerCommandCost", func(ei *ExtInfo, commands []string, rateStr string, burst int64, cost int64, scope string, message string) error {
+			return ei.RegisterRateLimitPolicy(&RateLimitPolicy{
+				Enable:   true,
+				Commands: commands,
+				Scope:    scope,
+				RateStr:  rateStr,
+				Burst:    burst,
+				Cost:     cost,
+				Message:  message,
+			})
+		})
 
 		_ = ext.Set("registerTask", func(ei *ExtInfo, taskType string, value string, fn func(taskCtx JsScriptTaskCtx), key string, desc string, group string) *JsScriptTask {
 			if ei.dice == nil {
@@ -540,6 +553,97 @@ func (d *Dice) JsInit() {
 		})
 		_ = seal.Set("deck", deck)
 
+		// 先攻列表与战斗轮，与 .ri/.init 共用同一份数据
+		combat := vm.NewObject()
+		combatGroup := func(ctx *MsgContext) (*GroupInfo, error) {
+			if ctx == nil || ctx.Group == nil {
+				return nil, errors.New("ctx 中没有群组信息")
+			}
+			return ctx.Group, nil
+		}
+		_ = combat.Set("get", func(ctx *MsgContext) (*CombatTracker, error) {
+			group, err := combatGroup(ctx)
+			if err != nil {
+				return nil, err
+			}
+			return LoadCombatTracker(d, group), nil
+		})
+		_ = combat.Set("add", func(ctx *MsgContext, name string, val int64, uid string) error {
+			group, err := combatGroup(ctx)
+			if err != nil {
+				return err
+			}
+			UpdateCombatTracker(d, group, func(t *CombatTracker) bool {
+				t.Add(&Combatant{Name: name, Val: val, UID: uid})
+				return true
+			})
+			return nil
+		})
+		_ = combat.Set("remove", func(ctx *MsgContext, name string) (bool, error) {
+			group, err := combatGroup(ctx)
+			if err != nil {
+				return false, err
+			}
+			var ok bool
+			UpdateCombatTracker(d, group, func(t *CombatTracker) bool {
+				removed, _ := t.Remove(name)
+				ok = len(removed) > 0
+				return ok
+			})
+			return ok, nil
+		})
+		_ = combat.Set("next", func(ctx *MsgContext) (*Combatant, error) {
+			group, err := combatGroup(ctx)
+			if err != nil {
+				return nil, err
+			}
+			var ended *Combatant
+			t := UpdateCombatTracker(d, group, func(t *CombatTracker) bool {
+				ended, _ = t.Next()
+				return ended != nil
+			})
+			if ended == nil {
+				return nil, nil
+			}
+			return t.Current(), nil
+		})
+		_ = combat.Set("setCondition", func(ctx *MsgContext, name string, condition string, rounds int64) (bool, error) {
+			group, err := combatGroup(ctx)
+			if err != nil {
+				return false, err
+			}
+			var ok bool
+			UpdateCombatTracker(d, group, func(t *CombatTracker) bool {
+				ok = t.SetCondition(name, condition, rounds)
+				return ok
+			})
+			return ok, nil
+		})
+		_ = combat.Set("removeCondition", func(ctx *MsgContext, name string, condition string) (bool, error) {
+			group, err := combatGroup(ctx)
+			if err != nil {
+				return false, err
+			}
+			var ok bool
+			UpdateCombatTracker(d, group, func(t *CombatTracker) bool {
+				ok = t.RemoveCondition(name, condition)
+				return ok
+			})
+			return ok, nil
+		})
+		_ = combat.Set("clear", func(ctx *MsgContext) error {
+			group, err := combatGroup(ctx)
+			if err != nil {
+				return err
+			}
+			UpdateCombatTracker(d, group, func(t *CombatTracker) bool {
+				t.Clear()
+				return true
+			})
+			return nil
+		})
+		_ = seal.Set("combat", combat)
+
 		_ = seal.Set("replyGroup", ReplyGroup)
 		_ = seal.Set("replyPerson", ReplyPerson)
 		_ = seal.Set("replyToSender", ReplyToSender)
@@ -654,8 +758,10 @@ func (d *Dice) JsInit() {
 		//  }
 		// }
 		// `)
-		_, _ = vm.RunString(`Object.freeze(seal);Object.freeze(seal.deck);Object.freeze(seal.coc);Object.freeze(seal.ext);Object.freeze(seal.vars);`)
+		_, _ = vm.RunString(`Object.freeze(seal);Object.freeze(seal.deck);Object.freeze(seal.combat);Object.freeze(seal.coc);Object.freeze(seal.ext);Object.freeze(seal.vars);`)
 	})
+	started := make(chan struct{})
+	queued := loop.RunOnLoop(func(*goja.Runtime) { close(started) })
 	go func() {
 		defer func() {
 			if r := recover(); r != nil {
@@ -665,6 +771,13 @@ func (d *Dice) JsInit() {
 		loop.StartInForeground()
 	}()
 	// loop.Start()
+	// 等 loop 真正跑起来再返回，否则紧接着的 Terminate 会先于启动执行而失效，loop 将一直运行下去
+	if queued {
+		select {
+		case <-started:
+		case <-time.After(5 * time.Second):
+		}
+	}
 	(&d.Config).JsEnable = true
 	d.Logger.Info("已加载JS环境")
 	d.MarkModified()
@@ -3,6 +3,7 @@ package dice //nolint:testpackage
 import (
 	"testing"
 
+	"go.uber.org/goleak"
 	"go.uber.org/zap"
 )
 
@@ -47,3 +48,29 @@ func TestJsInit_WhenExtLoopManagerNil_DoesNotPanic(t *testing.T) {
 		t.Fatalf("expected JsEnable to be true after JsInit")
 	}
 }
+
+func TestJsInit_ShutdownRightAfterInitStopsLoop(t *testing.T) {
+	ignore := goleak.IgnoreCurrent()
+	d := &Dice{
+		Logger: zap.NewNop().Sugar(),
+		BaseConfig: BaseConfig{
+			DataDir: t.TempDir(),
+		},
+		ImSession: &IMSession{
+			ServiceAtNew: new(SyncMap[string, *GroupInfo]),
+			EndPoints:    []*EndPointInfo{},
+		},
+		DirtyGroups:  new(SyncMap[string, int64]),
+		AttrsManager: &AttrsManager{},
+	}
+
+	d.JsInit()
+	// 初始化后立即关闭，loop 必须随之退出而不是残留在后台
+	if d.JsScriptCron != nil {
+		d.JsScriptCron.Stop()
+		d.JsScriptCron = nil
+	}
+	d.ExtLoopManager.SetLoop(nil)
+
+	goleak.VerifyNone(t, ignore)
+}
@@ -63,6 +63,11 @@ type DiceManager struct { //nolint:revive
 	BackupCleanCron      string              // 如果使用cron触发, 表达式
 	backupCleanCronID    cron.EntryID
 
+	// 属性变更记录清理配置，与备份清理互相独立，两项都为 0 时使用默认值，负数表示不按该项清理
+	AttrsJournalKeepCount int           // 每个角色保留的记录条数
+	AttrsJournalKeepDur   time.Duration // 保留的时间
+	attrsJournalCronID    cron.EntryID
+
 	AppBootTime      int64
 	AppVersionCode   int64
 	AppVersionOnline *VersionInfo
@@ -84,6 +89,9 @@ type DiceManager struct { //nolint:revive
 
 	ContainerMode bool          // 容器模式：禁用内置适配器，不允许使用内置Lagrange和旧的内置Gocq
 	CleanupFlag   atomic.Uint32 // 1 为正在清理，0为普通状态
+
+	ClusterConfig ClusterConfig   // 集群模式配置
+	Cluster       *ClusterManager // 集群模式未开启时为 nil
 }
 
 type Configs struct { //nolint:revive
@@ -108,8 +116,15 @@ type Configs struct { //nolint:revive
 		Cron      string `yaml:"cron"`
 	} `yaml:"backupClean"`
 
+	AttrsJournal struct {
+		KeepCount int   `yaml:"keepCount"`
+		KeepDur   int64 `yaml:"keepDur"`
+	} `yaml:"attrsJournal"`
+
 	ServiceName string `yaml:"serviceName"`
 
+	Cluster ClusterConfig `yaml:"cluster"`
+
 	ConfigVersion int `yaml:"configVersion"`
 }
 
@@ -218,6 +233,13 @@ func (dm *DiceManager) LoadDice() {
 	dm.BackupCleanKeepDur = time.Duration(dc.BackupClean.KeepDur)
 	dm.BackupCleanTrigger = BackupCleanTrigger(dc.BackupClean.Trigger)
 	dm.BackupCleanCron = dc.BackupClean.Cron
+	dm.AttrsJournalKeepCount = dc.AttrsJournal.KeepCount
+	dm.AttrsJournalKeepDur = time.Duration(dc.AttrsJournal.KeepDur)
+	if dm.AttrsJournalKeepCount == 0 && dm.AttrsJournalKeepDur == 0 {
+		// 从旧版升级
+		dm.AttrsJournalKeepCount = DefaultAttrsJournalKeepCount
+	}
+	dm.ClusterConfig = dc.Cluster
 
 	for _, i := range dc.AccessTokens {
 		dm.AccessTokens.Store(i, true)
@@ -246,7 +268,10 @@ func (dm *DiceManager) Save() {
 	dc.BackupClean.KeepDur = int64(dm.BackupCleanKeepDur)
 	dc.BackupClean.Trigger = int(dm.BackupCleanTrigger)
 	dc.BackupClean.Cron = dm.BackupCleanCron
+	dc.AttrsJournal.KeepCount = dm.AttrsJournalKeepCount
+	dc.AttrsJournal.KeepDur = int64(dm.AttrsJournalKeepDur)
 	dc.ServiceName = dm.ServiceName
+	dc.Cluster = dm.ClusterConfig
 	dc.ConfigVersion = 9914
 
 	dm.AccessTokens.Range(func(k string, v bool) bool {
@@ -280,11 +305,24 @@ func (dm *DiceManager) InitDice(writer *logger.UIWriter) {
 		dm.progressExitGroupWin = g
 	}
 
+	// 先记下最新的变更通知，加载数据期间其他节点的改动也会被处理
+	if dm.ClusterConfig.Enable {
+		dm.Cluster, err = NewClusterManager(dm, dm.ClusterConfig)
+		if err != nil {
+			log.Errorf("集群模式启动失败，将以单机模式运行: %v", err)
+			dm.Cluster = nil
+		}
+	}
+
 	for _, i := range dm.Dice {
 		i.Parent = dm
 		i.Init(dm.Operator, writer)
 	}
 
+	if dm.Cluster != nil {
+		dm.Cluster.Start()
+	}
+
 	go func() {
 		defer func() {
 			if r := recover(); r != nil {
@@ -300,6 +338,7 @@ func (dm *DiceManager) InitDice(writer *logger.UIWriter) {
 
 	dm.ResetAutoBackup()
 	dm.ResetBackupClean()
+	dm.ResetAttrsJournalClean()
 }
 
 func (dm *DiceManager) ResetAutoBackup() {
@@ -311,6 +350,9 @@ func (dm *DiceManager) ResetAutoBackup() {
 	if dm.AutoBackupEnable {
 		var err error
 		dm.backupEntryID, err = dm.Cron.AddFunc(dm.AutoBackupTime, func() {
+			if !dm.Cluster.IsLeader() {
+				return
+			}
 			errBackup := dm.BackupAuto()
 			if errBackup != nil {
 				log.Errorf("自动备份失败: %v", errBackup)
@@ -337,6 +379,9 @@ func (dm *DiceManager) ResetBackupClean() {
 	if (dm.BackupCleanTrigger & BackupCleanTriggerCron) > 0 {
 		var err error
 		dm.backupCleanCronID, err = dm.Cron.AddFunc(dm.BackupCleanCron, func() {
+			if !dm.Cluster.IsLeader() {
+				return
+			}
 			errBackup := dm.BackupClean(false)
 			if errBackup != nil {
 				log.Errorf("定时清理备份失败: %v", errBackup)
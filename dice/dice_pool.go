@@ -0,0 +1,477 @@
+package dice
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	ds "github.com/sealdice/dicescript"
+	rand2 "golang.org/x/exp/rand" //nolint:staticcheck // against my better judgment, but this was mandated due to a strongly held opinion from you know who
+)
+
+// 骰池系统，同时是文本模板 其它:骰池_<系统> 中的系统名
+const (
+	DicePoolV5        = "V5"
+	DicePoolShadowrun = "暗影狂奔"
+	DicePoolBlades    = "暗夜刀锋"
+	DicePoolPbtA      = "PbtA"
+)
+
+// 骰池结果分类，对应文本模板 其它:骰池_<系统>_<结果>
+const (
+	DicePoolCritical       = "大成功"
+	DicePoolMessyCritical  = "混乱大成功"
+	DicePoolSuccess        = "成功"
+	DicePoolPartial        = "部分成功"
+	DicePoolFailure        = "失败"
+	DicePoolBestialFailure = "兽性失败"
+	DicePoolTotalFailure   = "完全失败"
+	DicePoolGlitch         = "故障"
+	DicePoolCriticalGlitch = "严重故障"
+	DicePoolStrongHit      = "强成功"
+	DicePoolWeakHit        = "弱成功"
+)
+
+// dicePoolMax 单次骰池的最大骰数
+const dicePoolMax = 100
+
+// DicePoolResult 一次骰池检定的结果
+type DicePoolResult struct {
+	System  string  `json:"system"`
+	Dice    []int64 `json:"dice"`
+	Hunger  []int64 `json:"hunger,omitempty"` // V5 饥渴骰
+	Value   int64   `json:"value"`            // V5 为成功数，暗影狂奔为命中数，暗夜刀锋为采用的出目，PbtA 为总值
+	Outcome string  `json:"outcome"`
+
+	Critical bool `json:"critical,omitempty"` // V5 出现一对 10
+	Glitch   bool `json:"glitch,omitempty"`   // 暗影狂奔一半以上的骰子为 1
+	ZeroPool bool `json:"zeroPool,omitempty"` // 暗夜刀锋骰池为 0 时骰 2d6 取低
+}
+
+func dicePoolJoin(nums []int64, mark func(int64) bool) string {
+	parts := make([]string, 0, len(nums))
+	for _, n := range nums {
+		s := strconv.FormatInt(n, 10)
+		if mark != nil && mark(n) {
+			s += "*"
+		}
+		parts = append(parts, s)
+	}
+	return strings.Join(parts, ",")
+}
+
+// Detail 骰子明细，计为成功的骰子带有 * 标记
+func (r *DicePoolResult) Detail() string {
+	switch r.System {
+	case DicePoolV5:
+		text := "{" + dicePoolJoin(r.Dice, func(n int64) bool { return n >= 6 }) + "}"
+		if len(r.Hunger) > 0 {
+			text += " 饥渴{" + dicePoolJoin(r.Hunger, func(n int64) bool { return n >= 6 }) + "}"
+		}
+		return text
+	case DicePoolShadowrun:
+		return "{" + dicePoolJoin(r.Dice, func(n int64) bool { return n >= 5 }) + "}"
+	case DicePoolBlades:
+		if r.ZeroPool {
+			return "{" + dicePoolJoin(r.Dice, nil) + "}取低"
+		}
+		return "{" + dicePoolJoin(r.Dice, nil) + "}取高"
+	case DicePoolPbtA:
+		stat := r.Value
+		parts := make([]string, 0, len(r.Dice))
+		for _, n := range r.Dice {
+			stat -= n
+			parts = append(parts, strconv.FormatInt(n, 10))
+		}
+		text := strings.Join(parts, "+")
+		if stat != 0 {
+			text += fmt.Sprintf("%+d", stat)
+		}
+		return text
+	}
+	return dicePoolJoin(r.Dice, nil)
+}
+
+// ClassifyV5 吸血鬼：避世血族 第五版。6 以上为成功，每一对 10 额外计 2 个成功并视为大成功，
+// 其中有饥渴骰的 10 时为混乱大成功；未达到难度且饥渴骰出现 1 时为兽性失败，没有成功时为完全失败。
+// difficulty 为 0 时不与难度比较
+func ClassifyV5(dice []int64, hunger []int64, difficulty int64) *DicePoolResult {
+	r := &DicePoolResult{System: DicePoolV5, Dice: dice, Hunger: hunger}
+	tens, hungerTen, hungerOne := 0, false, false
+	for _, n := range dice {
+		if n >= 6 {
+			r.Value++
+		}
+		if n == 10 {
+			tens++
+		}
+	}
+	for _, n := range hunger {
+		if n >= 6 {
+			r.Value++
+		}
+		switch n {
+		case 10:
+			tens++
+			hungerTen = true
+		case 1:
+			hungerOne = true
+		}
+	}
+	r.Value += int64(tens/2) * 2
+	r.Critical = tens >= 2
+
+	passed := r.Value > 0
+	if difficulty > 0 {
+		passed = r.Value >= difficulty
+	}
+	switch {
+	case passed && r.Critical && hungerTen:
+		r.Outcome = DicePoolMessyCritical
+	case passed && r.Critical:
+		r.Outcome = DicePoolCritical
+	case passed:
+		r.Outcome = DicePoolSuccess
+	case hungerOne:
+		r.Outcome = DicePoolBestialFailure
+	case r.Value == 0:
+		r.Outcome = DicePoolTotalFailure
+	default:
+		r.Outcome = DicePoolFailure
+	}
+	return r
+}
+
+// ClassifyShadowrun 暗影狂奔。5、6 为命中，超过一半的骰子为 1 时为故障，同时没有命中时为严重故障。
+// limit 大于 0 时命中数不超过上限(第五版)，threshold 大于 0 时命中数需要达到阈值
+func ClassifyShadowrun(dice []int64, limit int64, threshold int64) *DicePoolResult {
+	r := &DicePoolResult{System: DicePoolShadowrun, Dice: dice}
+	ones := 0
+	for _, n := range dice {
+		if n >= 5 {
+			r.Value++
+		}
+		if n == 1 {
+			ones++
+		}
+	}
+	if limit > 0 && r.Value > limit {
+		r.Value = limit
+	}
+	r.Glitch = ones*2 > len(dice)
+
+	passed := r.Value > 0
+	if threshold > 0 {
+		passed = r.Value >= threshold
+	}
+	switch {
+	case r.Glitch && r.Value == 0:
+		r.Outcome = DicePoolCriticalGlitch
+	case r.Glitch:
+		r.Outcome = DicePoolGlitch
+	case passed:
+		r.Outcome = DicePoolSuccess
+	default:
+		r.Outcome = DicePoolFailure
+	}
+	return r
+}
+
+// ClassifyBlades 暗夜刀锋。取最高的出目，6 为完全成功，多个 6 为大成功，4、5 为部分成功，其余为失败；
+// 骰池为 0 时骰两颗取低，不会出现大成功
+func ClassifyBlades(dice []int64, zeroPool bool) *DicePoolResult {
+	r := &DicePoolResult{System: DicePoolBlades, Dice: dice, ZeroPool: zeroPool}
+	if len(dice) == 0 {
+		r.Outcome = DicePoolFailure
+		return r
+	}
+	sixes := 0
+	for _, n := range dice {
+		if n == 6 {
+			sixes++
+		}
+	}
+	if zeroPool {
+		r.Value = slices.Min(dice)
+	} else {
+		r.Value = slices.Max(dice)
+	}
+	switch {
+	case !zeroPool && sixes >= 2:
+		r.Outcome = DicePoolCritical
+	case r.Value == 6:
+		r.Outcome = DicePoolSuccess
+	case r.Value >= 4:
+		r.Outcome = DicePoolPartial
+	default:
+		r.Outcome = DicePoolFailure
+	}
+	return r
+}
+
+// ClassifyPbtA 基于启示录的游戏。2d6 加属性，10 以上为强成功，7-9 为弱成功，6 以下为失败
+func ClassifyPbtA(dice []int64, stat int64) *DicePoolResult {
+	r := &DicePoolResult{System: DicePoolPbtA, Dice: dice, Value: stat}
+	for _, n := range dice {
+		r.Value += n
+	}
+	switch {
+	case r.Value >= 10:
+		r.Outcome = DicePoolStrongHit
+	case r.Value >= 7:
+		r.Outcome = DicePoolWeakHit
+	default:
+		r.Outcome = DicePoolFailure
+	}
+	return r
+}
+
+func dicePoolRoll(randSrc *rand2.PCGSource, count int64, points int64) []int64 {
+	nums := make([]int64, 0, count)
+	for range count {
+		nums = append(nums, DiceRoll64x(randSrc, points))
+	}
+	return nums
+}
+
+func dicePoolCheckSize(pool int64, allowZero bool) error {
+	if pool > dicePoolMax {
+		return fmt.Errorf("骰池最多为%d颗骰子", dicePoolMax)
+	}
+	if pool < 0 || (pool == 0 && !allowZero) {
+		return errors.New("骰池至少为1颗骰子")
+	}
+	return nil
+}
+
+// DicePoolRollV5 骰 pool 颗 d10，其中 hunger 颗为饥渴骰
+func DicePoolRollV5(randSrc *rand2.PCGSource, pool int64, hunger int64, difficulty int64) (*DicePoolResult, error) {
+	if err := dicePoolCheckSize(pool, false); err != nil {
+		return nil, err
+	}
+	hunger = min(max(hunger, 0), pool)
+	dice := dicePoolRoll(randSrc, pool-hunger, 10)
+	return ClassifyV5(dice, dicePoolRoll(randSrc, hunger, 10), difficulty), nil
+}
+
+// DicePoolRollShadowrun 骰 pool 颗 d6 计算命中
+func DicePoolRollShadowrun(randSrc *rand2.PCGSource, pool int64, limit int64, threshold int64) (*DicePoolResult, error) {
+	if err := dicePoolCheckSize(pool, false); err != nil {
+		return nil, err
+	}
+	return ClassifyShadowrun(dicePoolRoll(randSrc, pool, 6), limit, threshold), nil
+}
+
+// DicePoolRollBlades 骰 pool 颗 d6 取最高，骰池为 0 时骰 2d6 取低
+func DicePoolRollBlades(randSrc *rand2.PCGSource, pool int64) (*DicePoolResult, error) {
+	if err := dicePoolCheckSize(pool, true); err != nil {
+		return nil, err
+	}
+	if pool == 0 {
+		return ClassifyBlades(dicePoolRoll(randSrc, 2, 6), true), nil
+	}
+	return ClassifyBlades(dicePoolRoll(randSrc, pool, 6), false), nil
+}
+
+// DicePoolRollPbtA 骰 2d6 加属性
+func DicePoolRollPbtA(randSrc *rand2.PCGSource, stat int64) (*DicePoolResult, error) {
+	return ClassifyPbtA(dicePoolRoll(randSrc, 2, 6), stat), nil
+}
+
+// dicePoolOutcomeText 结果分类的文本
+func dicePoolOutcomeText(ctx *MsgContext, r *DicePoolResult) string {
+	return DiceFormatTmpl(ctx, fmt.Sprintf("其它:骰池_%s_%s", r.System, r.Outcome))
+}
+
+// dicePoolSetVars 将骰池结果写入临时变量，供文本模板使用
+func dicePoolSetVars(ctx *MsgContext, r *DicePoolResult) {
+	VarSetValueStr(ctx, "$t骰池明细", r.Detail())
+	VarSetValueInt64(ctx, "$t骰池结果", r.Value)
+	VarSetValueStr(ctx, "$t骰池判定", dicePoolOutcomeText(ctx, r))
+}
+
+// 暗夜刀锋的局势与效果，只用于展示
+var (
+	dicePoolBladesPositions = map[string]string{
+		"受控": "受控", "controlled": "受控",
+		"冒险": "冒险", "risky": "冒险",
+		"绝望": "绝望", "desperate": "绝望",
+	}
+	dicePoolBladesEffects = map[string]string{
+		"有限": "有限", "limited": "有限",
+		"标准": "标准", "standard": "标准",
+		"极大": "极大", "great": "极大",
+	}
+)
+
+var reDicePoolOption = regexp.MustCompile(`(?i)^(h|饥渴|dc|难度|l|上限|t|阈值)\s*(\d+)`)
+
+// dicePoolArgs 骰池指令的参数: 骰池表达式与其后的选项，剩余部分作为原因
+type dicePoolArgs struct {
+	Pool      int64
+	Hunger    int64
+	HasHunger bool
+	DC        int64
+	Limit     int64
+	Position  string
+	Effect    string
+	Reason    string
+	Expr      string // 骰池表达式原文
+}
+
+// parseDicePoolArgs 解析如 “力量+运动 h2 dc3 撬锁” 的参数，表达式为空时骰池为 0
+func parseDicePoolArgs(ctx *MsgContext, text string) (*dicePoolArgs, error) {
+	args := &dicePoolArgs{}
+	rest := strings.TrimSpace(text)
+	if rest != "" && !reDicePoolOption.MatchString(rest) {
+		r := ctx.Eval(rest, nil)
+		if r.vm.Error != nil {
+			return nil, fmt.Errorf("无法解析表达式: %s", rest)
+		}
+		v, ok := r.ReadInt()
+		if !ok {
+			return nil, fmt.Errorf("骰池非数字类型: %s", rest)
+		}
+		args.Pool = int64(v)
+		args.Expr = strings.TrimSpace(r.vm.Matched)
+		rest = strings.TrimSpace(r.vm.RestInput)
+	}
+
+	for rest != "" {
+		if m := reDicePoolOption.FindStringSubmatch(rest); m != nil {
+			n, _ := strconv.ParseInt(m[2], 10, 64)
+			switch strings.ToLower(m[1]) {
+			case "h", "饥渴":
+				args.Hunger, args.HasHunger = n, true
+			case "dc", "难度", "t", "阈值":
+				args.DC = n
+			case "l", "上限":
+				args.Limit = n
+			}
+			rest = strings.TrimSpace(rest[len(m[0]):])
+			continue
+		}
+		word, after, _ := strings.Cut(rest, " ")
+		if v, ok := dicePoolBladesPositions[strings.ToLower(word)]; ok {
+			args.Position = v
+		} else if v, ok := dicePoolBladesEffects[strings.ToLower(word)]; ok {
+			args.Effect = v
+		} else {
+			break
+		}
+		rest = strings.TrimSpace(after)
+	}
+	args.Reason = rest
+	return args, nil
+}
+
+// newDicePoolObject 骰池函数，如 pool.v5(7, 2, 3)，返回成功数等数值，并设置 $t骰池明细 与 $t骰池判定
+func newDicePoolObject(ctx *MsgContext) *ds.VMValue {
+	intParam := func(params []*ds.VMValue, i int) int64 {
+		if i >= len(params) {
+			return 0
+		}
+		v, _ := params[i].ReadInt()
+		return int64(v)
+	}
+	method := func(name string, params []string, roll func(vm *ds.Context, params []*ds.VMValue) (*DicePoolResult, error)) *ds.VMValue {
+		defaults := make([]*ds.VMValue, len(params))
+		for i := 1; i < len(params); i++ {
+			defaults[i] = ds.NewIntVal(0)
+		}
+		return ds.NewNativeFunctionVal(&ds.NativeFunctionData{
+			Name:     "pool." + name,
+			Params:   params,
+			Defaults: defaults,
+			NativeFunc: func(vm *ds.Context, _ *ds.VMValue, params []*ds.VMValue) *ds.VMValue {
+				for _, p := range params {
+					if p.TypeId != ds.VMTypeInt {
+						vm.Error = errors.New("骰池参数需要为整数")
+						return nil
+					}
+				}
+				r, err := roll(vm, params)
+				if err != nil {
+					vm.Error = err
+					return nil
+				}
+				dicePoolSetVars(ctx, r)
+				return ds.NewIntVal(ds.IntType(r.Value))
+			},
+		})
+	}
+	methods := map[string]*ds.VMValue{
+		"v5": method("v5", []string{"pool", "hunger", "difficulty"}, func(vm *ds.Context, params []*ds.VMValue) (*DicePoolResult, error) {
+			return DicePoolRollV5(vm.RandSrc, intParam(params, 0), intParam(params, 1), intParam(params, 2))
+		}),
+		"sr": method("sr", []string{"pool", "limit", "threshold"}, func(vm *ds.Context, params []*ds.VMValue) (*DicePoolResult, error) {
+			return DicePoolRollShadowrun(vm.RandSrc, intParam(params, 0), intParam(params, 1), intParam(params, 2))
+		}),
+		"bitd": method("bitd", []string{"pool"}, func(vm *ds.Context, params []*ds.VMValue) (*DicePoolResult, error) {
+			return DicePoolRollBlades(vm.RandSrc, intParam(params, 0))
+		}),
+		"pbta": method("pbta", []string{"stat"}, func(vm *ds.Context, params []*ds.VMValue) (*DicePoolResult, error) {
+			return DicePoolRollPbtA(vm.RandSrc, intParam(params, 0))
+		}),
+	}
+	names := []string{"v5", "sr", "bitd", "pbta"}
+
+	return ds.NewNativeObjectVal(&ds.NativeObjectData{
+		Name: "pool",
+		AttrGet: func(vm *ds.Context, name string) *ds.VMValue {
+			return methods[name]
+		},
+		DirFunc: func(vm *ds.Context) []*ds.VMValue {
+			out := make([]*ds.VMValue, 0, len(names))
+			for _, name := range names {
+				out = append(out, ds.NewStrVal(name))
+			}
+			return out
+		},
+		ToString: func(vm *ds.Context) string {
+			return "pool"
+		},
+	})
+}
+
+// dicePoolSolve 骰池指令的通用流程，required 为真时参数为空显示帮助
+func dicePoolSolve(ctx *MsgContext, msg *Message, cmdArgs *CmdArgs, system string, required bool,
+	roll func(mctx *MsgContext, args *dicePoolArgs) (*DicePoolResult, error)) CmdExecuteResult {
+	if cmdArgs.IsArgEqual(1, "help") || (required && len(cmdArgs.Args) == 0) {
+		return CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+	}
+	mctx := GetCtxProxyFirst(ctx, cmdArgs)
+	mctx.DelegateText = ctx.DelegateText
+	if tmpl := mctx.Group.GetCharTemplate(mctx.Dice); tmpl != nil {
+		mctx.SystemTemplate = tmpl
+	}
+	mctx.CreateVmIfNotExists()
+
+	args, err := parseDicePoolArgs(mctx, cmdArgs.CleanArgs)
+	if err != nil {
+		ReplyToSender(ctx, msg, err.Error())
+		return CmdExecuteResult{Matched: true, Solved: true}
+	}
+	r, err := roll(mctx, args)
+	if err != nil {
+		ReplyToSender(ctx, msg, err.Error())
+		return CmdExecuteResult{Matched: true, Solved: true}
+	}
+
+	reason := args.Reason
+	if reason == "" {
+		reason = args.Expr
+	}
+	dicePoolSetVars(mctx, r)
+	VarSetValueStr(mctx, "$t原因", LimitCommandReasonText(reason))
+	if args.DC > 0 {
+		VarSetValueStr(mctx, "$t难度文本", fmt.Sprintf("，难度%d", args.DC))
+	} else {
+		VarSetValueStr(mctx, "$t难度文本", "")
+	}
+	ReplyToSender(ctx, msg, mctx.DelegateText+DiceFormatTmpl(mctx, "其它:骰池_"+system))
+	return CmdExecuteResult{Matched: true, Solved: true}
+}
@@ -0,0 +1,204 @@
+//nolint:testpackage
+package dice
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClassifyV5(t *testing.T) {
+	cases := []struct {
+		name       string
+		dice       []int64
+		hunger     []int64
+		difficulty int64
+		value      int64
+		outcome    string
+	}{
+		{"success", []int64{6, 2, 7}, []int64{3}, 2, 2, DicePoolSuccess},
+		{"failure", []int64{6, 2, 3}, []int64{4}, 2, 1, DicePoolFailure},
+		{"total failure", []int64{2, 3, 5}, nil, 1, 0, DicePoolTotalFailure},
+		{"bestial failure", []int64{6, 2}, []int64{1}, 3, 1, DicePoolBestialFailure},
+		{"bestial beats total failure", []int64{2}, []int64{1, 5}, 1, 0, DicePoolBestialFailure},
+		{"critical", []int64{10, 10, 3}, []int64{2}, 3, 4, DicePoolCritical},
+		{"messy critical", []int64{10, 4}, []int64{10}, 3, 4, DicePoolMessyCritical},
+		{"three tens count one pair", []int64{10, 10, 10}, nil, 5, 5, DicePoolCritical},
+		{"critical still fails difficulty", []int64{10, 10}, []int64{1}, 5, 4, DicePoolBestialFailure},
+		{"no difficulty", []int64{7}, nil, 0, 1, DicePoolSuccess},
+	}
+	for _, c := range cases {
+		r := ClassifyV5(c.dice, c.hunger, c.difficulty)
+		if r.Value != c.value || r.Outcome != c.outcome {
+			t.Errorf("%s: got %d %s, want %d %s", c.name, r.Value, r.Outcome, c.value, c.outcome)
+		}
+	}
+	if d := ClassifyV5([]int64{6, 2}, []int64{10}, 0).Detail(); d != "{6*,2} 饥渴{10*}" {
+		t.Errorf("unexpected detail: %q", d)
+	}
+}
+
+func TestClassifyShadowrun(t *testing.T) {
+	cases := []struct {
+		name      string
+		dice      []int64
+		limit     int64
+		threshold int64
+		value     int64
+		outcome   string
+	}{
+		{"hits", []int64{5, 6, 2, 3}, 0, 0, 2, DicePoolSuccess},
+		{"no hits", []int64{2, 3, 4}, 0, 0, 0, DicePoolFailure},
+		{"threshold", []int64{5, 2, 3}, 0, 2, 1, DicePoolFailure},
+		{"limit", []int64{5, 6, 6, 5}, 2, 0, 2, DicePoolSuccess},
+		{"half ones is not a glitch", []int64{1, 1, 5, 3}, 0, 0, 1, DicePoolSuccess},
+		{"glitch", []int64{1, 1, 5}, 0, 0, 1, DicePoolGlitch},
+		{"critical glitch", []int64{1, 1, 3}, 0, 0, 0, DicePoolCriticalGlitch},
+	}
+	for _, c := range cases {
+		r := ClassifyShadowrun(c.dice, c.limit, c.threshold)
+		if r.Value != c.value || r.Outcome != c.outcome {
+			t.Errorf("%s: got %d %s, want %d %s", c.name, r.Value, r.Outcome, c.value, c.outcome)
+		}
+	}
+}
+
+func TestClassifyBlades(t *testing.T) {
+	cases := []struct {
+		name     string
+		dice     []int64
+		zeroPool bool
+		value    int64
+		outcome  string
+	}{
+		{"critical", []int64{6, 6, 2}, false, 6, DicePoolCritical},
+		{"full success", []int64{6, 3}, false, 6, DicePoolSuccess},
+		{"partial", []int64{4, 5, 1}, false, 5, DicePoolPartial},
+		{"bad outcome", []int64{1, 3}, false, 3, DicePoolFailure},
+		{"zero pool takes lowest", []int64{6, 3}, true, 3, DicePoolFailure},
+		{"zero pool has no critical", []int64{6, 6}, true, 6, DicePoolSuccess},
+	}
+	for _, c := range cases {
+		r := ClassifyBlades(c.dice, c.zeroPool)
+		if r.Value != c.value || r.Outcome != c.outcome {
+			t.Errorf("%s: got %d %s, want %d %s", c.name, r.Value, r.Outcome, c.value, c.outcome)
+		}
+	}
+}
+
+func TestClassifyPbtA(t *testing.T) {
+	cases := []struct {
+		dice    []int64
+		stat    int64
+		value   int64
+		outcome string
+	}{
+		{[]int64{6, 4}, 0, 10, DicePoolStrongHit},
+		{[]int64{3, 4}, 2, 9, DicePoolWeakHit},
+		{[]int64{3, 4}, 0, 7, DicePoolWeakHit},
+		{[]int64{3, 4}, -1, 6, DicePoolFailure},
+	}
+	for _, c := range cases {
+		r := ClassifyPbtA(c.dice, c.stat)
+		if r.Value != c.value || r.Outcome != c.outcome {
+			t.Errorf("%v%+d: got %d %s, want %d %s", c.dice, c.stat, r.Value, r.Outcome, c.value, c.outcome)
+		}
+	}
+	if d := ClassifyPbtA([]int64{3, 4}, -1).Detail(); d != "3+4-1" {
+		t.Errorf("unexpected detail: %q", d)
+	}
+}
+
+func TestDicePoolRoll(t *testing.T) {
+	r, err := DicePoolRollV5(nil, 5, 9, 0)
+	if err != nil || len(r.Dice) != 0 || len(r.Hunger) != 5 {
+		t.Fatalf("hunger should be capped by pool: %+v %v", r, err)
+	}
+	if _, err = DicePoolRollShadowrun(nil, 0, 0, 0); err == nil {
+		t.Fatal("empty pool should be rejected")
+	}
+	if _, err = DicePoolRollV5(nil, dicePoolMax+1, 0, 0); err == nil {
+		t.Fatal("oversized pool should be rejected")
+	}
+	if r, err = DicePoolRollBlades(nil, 0); err != nil || !r.ZeroPool || len(r.Dice) != 2 {
+		t.Fatalf("zero pool should roll two dice: %+v %v", r, err)
+	}
+}
+
+func TestParseDicePoolArgs(t *testing.T) {
+	d, ep, _, cleanup := newExecuteNewTestDice(t)
+	defer cleanup()
+	ctx := CreateTempCtx(ep, newGroupMsg("QQ-Group:310", "QQ:410", ".v5"))
+	ctx.Dice = d
+
+	args, err := parseDicePoolArgs(ctx, "3+4 h2 dc3 撬开保险箱")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if args.Pool != 7 || !args.HasHunger || args.Hunger != 2 || args.DC != 3 || args.Reason != "撬开保险箱" {
+		t.Fatalf("unexpected args: %+v", args)
+	}
+	if args, _ = parseDicePoolArgs(ctx, "2 risky 极大 翻墙"); args.Position != "冒险" || args.Effect != "极大" || args.Reason != "翻墙" {
+		t.Fatalf("unexpected args: %+v", args)
+	}
+	if args, _ = parseDicePoolArgs(ctx, "l4"); args.Pool != 0 || args.Limit != 4 {
+		t.Fatalf("unexpected args: %+v", args)
+	}
+}
+
+func TestDicePoolScriptAndCommands(t *testing.T) {
+	d, ep, adapter, cleanup := newExecuteNewTestDice(t)
+	defer cleanup()
+	ctx := CreateTempCtx(ep, newGroupMsg("QQ-Group:311", "QQ:411", ".v5"))
+	ctx.Dice = d
+
+	r := ctx.Eval("pool.sr(6, 2)", nil)
+	if r.vm.Error != nil {
+		t.Fatal(r.vm.Error)
+	}
+	if hits, ok := r.ReadInt(); !ok || hits < 0 || hits > 2 {
+		t.Fatalf("hits should be limited: %v", r.ToString())
+	}
+	if detail, _ := VarGetValueStr(ctx, "$t骰池明细"); strings.Count(detail, ",") != 5 {
+		t.Fatalf("unexpected detail: %q", detail)
+	}
+	if r = ctx.Eval("pool.v5(0)", nil); r.vm.Error == nil {
+		t.Fatal("empty pool should fail in script")
+	}
+
+	for _, system := range []string{"V5", "暗影狂奔", "暗夜刀锋", "PbtA"} {
+		if _, ok := ctx.Dice.TextMap["其它:骰池_"+system]; !ok {
+			t.Fatalf("text template for %s missing", system)
+		}
+	}
+	for _, name := range []string{"vtm5", "shadowrun", "bitd", "pbta"} {
+		if tmpl, _ := d.GameSystemMap.Load(name); tmpl == nil {
+			t.Fatalf("template %s should be registered", name)
+		}
+	}
+	if tmpl, _ := d.GameSystemMap.Load("vtm5"); tmpl.GetAlias("Hunger") != "饥渴" {
+		t.Fatal("vtm5 alias should resolve")
+	}
+
+	send := func(text string) string {
+		d.ImSession.ExecuteNew(ep, newGroupMsg("QQ-Group:311", "QQ:411", text))
+		reply, ok := adapter.waitForMsg(2 * time.Second)
+		if !ok {
+			t.Fatalf("no reply to %q", text)
+		}
+		return reply
+	}
+	send(".bot on")
+	if reply := send(".v5 5 h2 dc3 撬锁"); !strings.Contains(reply, `"撬锁"检定(V5)`) || !strings.Contains(reply, "饥渴{") || !strings.Contains(reply, "难度3") {
+		t.Fatalf("unexpected v5 reply: %q", reply)
+	}
+	if reply := send(".bitd 0 绝望 翻墙"); !strings.Contains(reply, "暗夜刀锋，绝望") || !strings.Contains(reply, "取低") {
+		t.Fatalf("unexpected bitd reply: %q", reply)
+	}
+	if reply := send(".pbta 1 顶住压力"); !strings.Contains(reply, `"顶住压力"行动(PbtA)`) {
+		t.Fatalf("unexpected pbta reply: %q", reply)
+	}
+	if reply := send(".sr 200"); !strings.Contains(reply, "骰池最多") {
+		t.Fatalf("oversized pool should be rejected: %q", reply)
+	}
+}
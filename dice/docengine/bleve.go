@@ -3,9 +3,12 @@ package docengine
 import (
 	"errors"
 	"fmt"
+	"os"
 	"regexp"
+	"slices"
 	"sort"
 	"strconv"
+	"strings"
 	"sync"
 
 	"github.com/blevesearch/bleve/v2"
@@ -32,6 +35,9 @@ type BleveSearchEngine struct {
 	idToNumber map[string]int
 	// numericIDDirty 表示数字ID映射已过期，需要在真正读取时重建
 	numericIDDirty bool
+	// options 搜索选项，synonyms 为展开后的同义词表(归一化词 -> 全部同义词)
+	options  SearchOptions
+	synonyms map[string][]string
 }
 
 var indexDir = "./data/_help_cache/_index"
@@ -39,6 +45,27 @@ var reSpace = regexp.MustCompile(`\s+`)
 
 const deleteSearchBatchSize = 1000
 
+// 索引结构版本，字段映射变化时需要提升，旧版本索引会被删除重建
+const indexMappingVersion = "2"
+
+var indexMappingVersionKey = []byte("_mapping_version")
+
+// 各字段命中时的权重，标题命中优先于正文命中
+const (
+	boostTitle        = 5
+	boostTitleNorm    = 4
+	boostTitlePinyin  = 2
+	boostTitleFuzzy   = 1.5
+	boostContent      = 1
+	boostContentFuzzy = 0.5
+)
+
+// 模糊匹配的最短长度，过短的词容易匹配到大量无关词条
+const fuzzyMinLength = 4
+
+// 同义词展开后最多产生的查询变体数量
+const maxQueryVariants = 16
+
 func (d *BleveSearchEngine) getNextID() string {
 	return ulid.Make().String()
 }
@@ -84,6 +111,15 @@ func (d *BleveSearchEngine) Init() error {
 	// Content才是真正的文档
 	docMapping.AddFieldMappingsAt("content", contentFieldMapping)
 	docMapping.AddFieldMappingsAt("package", keywordMapping)
+	// 以下字段只用于搜索，不需要存储：繁简归一化后的标题与正文，以及标题拼音
+	normMapping := bleve.NewTextFieldMapping()
+	normMapping.Store = false
+	pinyinMapping := bleve.NewTextFieldMapping()
+	pinyinMapping.Store = false
+	pinyinMapping.Analyzer = simple.Name
+	docMapping.AddFieldMappingsAt("title_norm", normMapping)
+	docMapping.AddFieldMappingsAt("content_norm", normMapping)
+	docMapping.AddFieldMappingsAt("title_pinyin", pinyinMapping)
 	mapping.AddDocumentMapping("helpdoc", docMapping)
 	mapping.TypeField = "_type"
 
@@ -92,11 +128,27 @@ func (d *BleveSearchEngine) Init() error {
 	freshlyCreated := false
 
 	i, err = bleve.Open(indexDir)
+	if err == nil {
+		// 字段映射变化后旧索引无法搜索新字段，直接删除重建
+		version, _ := i.GetInternal(indexMappingVersionKey)
+		if string(version) != indexMappingVersion {
+			logger.M().Infof("[帮助文档] 索引结构版本变化(%q -> %q)，将重建索引", version, indexMappingVersion)
+			_ = i.Close()
+			if err = os.RemoveAll(indexDir); err != nil {
+				return err
+			}
+			err = errors.New("index mapping outdated")
+		}
+	}
 	if err != nil {
 		i, err = bleve.New(indexDir, mapping)
 		if err != nil {
 			return err
 		}
+		if err = i.SetInternal(indexMappingVersionKey, []byte(indexMappingVersion)); err != nil {
+			_ = i.Close()
+			return err
+		}
 		freshlyCreated = true
 	}
 
@@ -301,6 +353,10 @@ func (d *BleveSearchEngine) AddItem(item HelpTextItem) (string, error) {
 		"content": item.Content,
 		"package": item.PackageName,
 		"_type":   "helpdoc",
+
+		"title_norm":   NormalizeText(item.Title),
+		"content_norm": NormalizeText(item.Content),
+		"title_pinyin": PinyinTokens(item.Title),
 	}
 	if err := d.batch.Index(id, data); err != nil {
 		return "", err
@@ -354,20 +410,7 @@ func (d *BleveSearchEngine) Search(helpPackages []string, text string, titleOnly
 	d.mu.Lock()
 	defer d.mu.Unlock()
 
-	// 在标题中查找
-	queryTitle := query.NewMatchPhraseQuery(text)
-	queryTitle.SetField("title")
-
-	titleOrContent := bleve.NewDisjunctionQuery(queryTitle)
-
-	// 在正文中查找
-	if !titleOnly {
-		for _, i := range reSpace.Split(text, -1) {
-			queryContent := query.NewMatchPhraseQuery(i)
-			queryContent.SetField("content")
-			titleOrContent.AddQuery(queryContent)
-		}
-	}
+	titleOrContent := d.buildTextQueryLocked(text, titleOnly)
 
 	andQuery := bleve.NewConjunctionQuery(titleOrContent)
 
@@ -417,6 +460,136 @@ func (d *BleveSearchEngine) Search(helpPackages []string, text string, titleOnly
 	return &responseResult, total, pageStart, pageEnd, nil
 }
 
+func (d *BleveSearchEngine) SetSearchOptions(opts SearchOptions) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	opts.Fuzziness = min(max(opts.Fuzziness, 0), 2) // bleve 最多支持编辑距离 2
+	d.options = opts
+	d.synonyms = make(map[string][]string)
+	for key, values := range opts.Synonyms {
+		group := make([]string, 0, len(values)+1)
+		for _, word := range append([]string{key}, values...) {
+			word = strings.TrimSpace(NormalizeText(word))
+			if word != "" && !slices.Contains(group, word) {
+				group = append(group, word)
+			}
+		}
+		for _, word := range group {
+			for _, syn := range group {
+				if syn != word && !slices.Contains(d.synonyms[word], syn) {
+					d.synonyms[word] = append(d.synonyms[word], syn)
+				}
+			}
+		}
+	}
+}
+
+// expandSynonymsLocked 按同义词表展开查询，整句与每个空格分隔的词都会尝试替换
+func (d *BleveSearchEngine) expandSynonymsLocked(text string) []string {
+	variants := []string{text}
+	if len(d.synonyms) == 0 {
+		return variants
+	}
+	norm := strings.TrimSpace(NormalizeText(text))
+	for _, syn := range d.synonyms[norm] {
+		variants = append(variants, syn)
+	}
+	terms := reSpace.Split(norm, -1)
+	if len(terms) < 2 {
+		return variants
+	}
+	for i, term := range terms {
+		for _, syn := range d.synonyms[term] {
+			if len(variants) >= maxQueryVariants {
+				return variants
+			}
+			replaced := slices.Clone(terms)
+			replaced[i] = syn
+			variants = append(variants, strings.Join(replaced, " "))
+		}
+	}
+	return variants
+}
+
+// buildTextQueryLocked 构造标题/正文查询
+// 标题按原文、繁简归一化、拼音三种方式匹配，正文按空格分词匹配；
+// 启用模糊匹配时，拼音与较长的英文词允许一定的编辑距离
+func (d *BleveSearchEngine) buildTextQueryLocked(text string, titleOnly bool) *query.DisjunctionQuery {
+	fuzziness := d.options.Fuzziness
+	phrase := func(text, field string, boost float64) query.Query {
+		q := query.NewMatchPhraseQuery(text)
+		q.SetField(field)
+		q.SetBoost(boost)
+		return q
+	}
+	fuzzy := func(text, field string, boost float64) query.Query {
+		q := query.NewMatchQuery(text)
+		q.SetField(field)
+		q.SetFuzziness(fuzziness)
+		q.SetBoost(boost)
+		return q
+	}
+
+	titleOrContent := bleve.NewDisjunctionQuery()
+	for _, variant := range d.expandSynonymsLocked(text) {
+		norm := strings.TrimSpace(NormalizeText(variant))
+		titleOrContent.AddQuery(phrase(variant, "title", boostTitle))
+		titleOrContent.AddQuery(phrase(norm, "title_norm", boostTitleNorm))
+
+		if key := pinyinQueryKey(norm); key != "" {
+			q := query.NewMatchQuery(key)
+			q.SetField("title_pinyin")
+			q.SetBoost(boostTitlePinyin)
+			if len(key) >= fuzzyMinLength {
+				q.SetFuzziness(fuzziness)
+			}
+			titleOrContent.AddQuery(q)
+			if !hasHanText(norm) && len(key) >= 3 {
+				// 拼音输入往往只打了前半截
+				prefix := query.NewPrefixQuery(key)
+				prefix.SetField("title_pinyin")
+				prefix.SetBoost(boostTitleFuzzy)
+				titleOrContent.AddQuery(prefix)
+			}
+		}
+		if fuzziness > 0 && isFuzzyTerm(norm) {
+			titleOrContent.AddQuery(fuzzy(norm, "title_norm", boostTitleFuzzy))
+		}
+
+		if titleOnly {
+			continue
+		}
+		for _, term := range reSpace.Split(variant, -1) {
+			titleOrContent.AddQuery(phrase(term, "content", boostContent))
+		}
+		for _, term := range reSpace.Split(norm, -1) {
+			titleOrContent.AddQuery(phrase(term, "content_norm", boostContent))
+			if fuzziness > 0 && isFuzzyTerm(term) {
+				titleOrContent.AddQuery(fuzzy(term, "content_norm", boostContentFuzzy))
+			}
+		}
+	}
+	return titleOrContent
+}
+
+// pinyinQueryKey 查询对应的拼音串，只有含汉字或纯字母(视为拼音输入)的查询才做拼音匹配
+func pinyinQueryKey(norm string) string {
+	if hasHanText(norm) {
+		return PinyinKey(norm)
+	}
+	key := strings.ReplaceAll(norm, " ", "")
+	if !isLetterText(key) {
+		return ""
+	}
+	return key
+}
+
+// isFuzzyTerm 只对足够长的纯字母词做模糊匹配，汉字与数字的编辑距离没有意义
+func isFuzzyTerm(term string) bool {
+	return len(term) >= fuzzyMinLength && isLetterText(term)
+}
+
 func (d *BleveSearchEngine) ListAllDocumentIDs() ([]string, error) {
 	d.mu.Lock()
 	defer d.mu.Unlock()
@@ -132,3 +132,83 @@ func TestBleveSearchEngineNumericIDsRebuildLazily(t *testing.T) {
 		t.Fatalf("Search() returned non-numeric ID %q", res.Hits[0].ID)
 	}
 }
+
+func TestBleveSearchEngineRebuildsOutdatedMapping(t *testing.T) {
+	engine := newTestBleveSearchEngine(t)
+	addTestHelpItems(t, engine, 3, "group-a", "from-a", "title-")
+	if err := engine.AddItemApply(true); err != nil {
+		t.Fatalf("AddItemApply(true) error = %v", err)
+	}
+	if err := engine.Index.SetInternal(indexMappingVersionKey, []byte("1")); err != nil {
+		t.Fatalf("SetInternal() error = %v", err)
+	}
+	engine.Close()
+
+	reopened, err := NewBleveSearchEngine()
+	if err != nil {
+		t.Fatalf("NewBleveSearchEngine() error = %v", err)
+	}
+	defer reopened.Close()
+	if !reopened.IndexFreshlyCreated() {
+		t.Fatal("outdated index should be recreated")
+	}
+	ids, err := reopened.ListAllDocumentIDs()
+	if err != nil {
+		t.Fatalf("ListAllDocumentIDs() error = %v", err)
+	}
+	if len(ids) != 0 {
+		t.Fatalf("document count = %d, want 0", len(ids))
+	}
+}
+
+func TestBleveSearchEngineSearchNormalizedAndFuzzy(t *testing.T) {
+	engine := newTestBleveSearchEngine(t)
+	for _, item := range []HelpTextItem{
+		{Group: "coc", Title: "理智检定/SAN", Content: "目睹恐怖之物时进行理智检定"},
+		{Group: "coc", Title: "疯狂", Content: "理智归零后陷入疯狂"},
+		{Group: "coc", Title: "initiative", Content: "先攻顺序"},
+	} {
+		if _, err := engine.AddItem(item); err != nil {
+			t.Fatalf("AddItem() error = %v", err)
+		}
+	}
+	if err := engine.AddItemApply(true); err != nil {
+		t.Fatalf("AddItemApply(true) error = %v", err)
+	}
+	engine.SetSearchOptions(SearchOptions{
+		Synonyms:  map[string][]string{"先攻": {"行动顺序"}},
+		Fuzziness: 1,
+	})
+
+	top := func(text string) string {
+		t.Helper()
+		res, _, _, _, err := engine.Search(nil, text, false, 3, 1, "")
+		if err != nil {
+			t.Fatalf("Search(%q) error = %v", text, err)
+		}
+		if len(res.Hits) == 0 {
+			return ""
+		}
+		return res.Hits[0].Fields["title"].(string)
+	}
+	cases := map[string]string{
+		"理智":         "理智检定/SAN", // 标题命中排在正文命中之前
+		"理智檢定":       "理智检定/SAN",
+		"lizhijiand": "理智检定/SAN",
+		"lzjd":       "理智检定/SAN",
+		"fengkuang":  "疯狂",
+		"initiativ":  "initiative",
+		"initative":  "initiative",
+		"行动顺序":       "initiative",
+	}
+	for text, want := range cases {
+		if got := top(text); got != want {
+			t.Errorf("Search(%q) top = %q, want %q", text, got, want)
+		}
+	}
+
+	engine.SetSearchOptions(SearchOptions{})
+	if got := top("initative"); got != "" {
+		t.Errorf("fuzzy search should be disabled, got %q", got)
+	}
+}
@@ -28,6 +28,14 @@ type HelpTextItem struct {
 	RelatedExt []string
 }
 
+// SearchOptions 搜索行为配置，由帮助文档配置下发
+type SearchOptions struct {
+	// Synonyms 同义词表，同一条目的键与值互为同义词，查询时展开
+	Synonyms map[string][]string
+	// Fuzziness 模糊匹配允许的编辑距离，0 为不启用，最大为 2
+	Fuzziness int
+}
+
 // SearchEngine TODO: 进一步优化结构，封装成通用的搜索
 type SearchEngine interface {
 	GetSuffixText() string
@@ -41,6 +49,8 @@ type SearchEngine interface {
 	AddItem(item HelpTextItem) (string, error)
 	// AddItemApply 提交文档条目
 	AddItemApply(end bool) error
+	// SetSearchOptions 设置同义词、模糊匹配等搜索选项
+	SetSearchOptions(opts SearchOptions)
 	// Search 搜索文档条目
 	Search(helpPackages []string, text string, titleOnly bool, pageSize, pageNum int, group string) (*GeneralSearchResult, int, int, int, error)
 	// GetHelpTextItemByTermTitle 精确查询title，用于嵌套获取数据的情形
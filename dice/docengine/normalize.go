@@ -0,0 +1,146 @@
+package docengine
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/mozillazg/go-pinyin"
+)
+
+// 繁简对照表，按字逐一对应，只收录帮助文档中常见的字
+// 仅用于搜索归一化，不追求完整的繁简转换
+var (
+	traditionalChars = "並亂亞來個們偉偵傑備傭傳債傷僕僱價儀億儘償優儲兇兒內兩冊凍別則剛創劃劇劍劑勁動務" +
+		"勝勞勢勳勵匯區協厲參員問啟單嗎嚇嚴囑國圍園圓圖團執報場塊塵墜墳壓壞壯壽夠夢奪奮妝" +
+		"娛婦媽嬰孫學實寧審寫寬寶將專對導屆屍層屬岡島峽嶺巖帥師帳帶幣幫幹幾庫廁廟廢廣廳張" +
+		"強彈彌彎後徑從復徵惡愛態慮憂憐憑憤憶應懲懶懷懼戀戰戲戶拋挾捨掃掛採揚換損搖搶擁擇" +
+		"擊擋擔據擬擲擴擾攜敗敵數斂斷於時暈暫曆曉書會東條業極構槍槳樂樓標樣樹橋機橢橫檔檢" +
+		"權歎歐歡歲歷歸殘殺毀氈氣決沒沖況淚淨淺渦測湧湯準溫滅滬滯滲滾漁漢漲漸潔潛澀澤濃濕" +
+		"濟濤濱瀉灣為無煙熱燈燒爐爛爭爺牆犧狀獄獎獨獲獵獸現瑪環瓊產畢畫異當痺瘋瘡療癒癢癲" +
+		"發盜盞盡盤眾睜矯砲碩確碼礦祕禍禦禪禮稅種稱穀穌積穩窩窮竄竅竊競筆節範築簡簽籤粵糧" +
+		"紀約紅紋納紐純紗紙級紛細終組絆結絕絡給統絲綁經綜綠維網緊緒線緣編緩練縣縫縮總績織" +
+		"繩繪繼續罰罷羅義習聖聞聯聰聲職聽肅脅脈脫腦腳腸膚膠膽臉臟與舉舊艦艱莊華萬蓋蔣蔥薑" +
+		"薦藉藍藝藥蘇蘋蘭處虛號蝕蟲蠟術衛衝裏補裝裡製複見規視親覺觀觸訂計訊託記訟訪設許訴" +
+		"診註評詛詞詢試詩話該詳誌認語誠誤說課調談請論諸諾謀謊謎謝謹證識譯議護讀變讓讚豎豐" +
+		"豬貓貝負財貢貧貨貪貫責貴買貸費貿賀資賊賜賞賠賢賣賦質賬購賽贈贊贏趕趙趨蹤躍車軌軍" +
+		"軟較載輔輕輩輪輯輸辦辭這週進遊運過達違遠選還邊邏郵鄉鄰醜醫醬釋針鈍鈴鉛銀銅銳銷鋒" +
+		"鋼錄錘錢錦錯鍊鍵鎖鎧鎮鏈鏡鐘鐮鐵鑑鑒鑰鑽長門閃閉開閒間閣閱闆闊關闡陣陰陳陸陽隊階" +
+		"際隨險隱隸隻雖雙雜雞離難雲電霧靂靈靜韋韌韓響頁頂頃項順須預頓領頭頸頻顆題額顏願類" +
+		"顧顯風飄飛飢飯飲飼飽餅養餓餘館馬駐駕駛騎騙騰驅驕驗驚驟骯髒體髮鬆鬍鬚鬥鬧魚魯鮮鳥" +
+		"鳳鳴鴨鴻鵝鹽麗麥麵麼黃點黨齊齒齡龍龐龜"
+	simplifiedChars = "并乱亚来个们伟侦杰备佣传债伤仆雇价仪亿尽偿优储凶儿内两册冻别则刚创划剧剑剂劲动务" +
+		"胜劳势勋励汇区协厉参员问启单吗吓严嘱国围园圆图团执报场块尘坠坟压坏壮寿够梦夺奋妆" +
+		"娱妇妈婴孙学实宁审写宽宝将专对导届尸层属冈岛峡岭岩帅师账带币帮干几库厕庙废广厅张" +
+		"强弹弥弯后径从复征恶爱态虑忧怜凭愤忆应惩懒怀惧恋战戏户抛挟舍扫挂采扬换损摇抢拥择" +
+		"击挡担据拟掷扩扰携败敌数敛断于时晕暂历晓书会东条业极构枪桨乐楼标样树桥机椭横档检" +
+		"权叹欧欢岁历归残杀毁毡气决没冲况泪净浅涡测涌汤准温灭沪滞渗滚渔汉涨渐洁潜涩泽浓湿" +
+		"济涛滨泻湾为无烟热灯烧炉烂争爷墙牺状狱奖独获猎兽现玛环琼产毕画异当痹疯疮疗愈痒癫" +
+		"发盗盏尽盘众睁矫炮硕确码矿秘祸御禅礼税种称谷稣积稳窝穷窜窍窃竞笔节范筑简签签粤粮" +
+		"纪约红纹纳纽纯纱纸级纷细终组绊结绝络给统丝绑经综绿维网紧绪线缘编缓练县缝缩总绩织" +
+		"绳绘继续罚罢罗义习圣闻联聪声职听肃胁脉脱脑脚肠肤胶胆脸脏与举旧舰艰庄华万盖蒋葱姜" +
+		"荐借蓝艺药苏苹兰处虚号蚀虫蜡术卫冲里补装里制复见规视亲觉观触订计讯托记讼访设许诉" +
+		"诊注评诅词询试诗话该详志认语诚误说课调谈请论诸诺谋谎谜谢谨证识译议护读变让赞竖丰" +
+		"猪猫贝负财贡贫货贪贯责贵买贷费贸贺资贼赐赏赔贤卖赋质账购赛赠赞赢赶赵趋踪跃车轨军" +
+		"软较载辅轻辈轮辑输办辞这周进游运过达违远选还边逻邮乡邻丑医酱释针钝铃铅银铜锐销锋" +
+		"钢录锤钱锦错链键锁铠镇链镜钟镰铁鉴鉴钥钻长门闪闭开闲间阁阅板阔关阐阵阴陈陆阳队阶" +
+		"际随险隐隶只虽双杂鸡离难云电雾雳灵静韦韧韩响页顶顷项顺须预顿领头颈频颗题额颜愿类" +
+		"顾显风飘飞饥饭饮饲饱饼养饿余馆马驻驾驶骑骗腾驱骄验惊骤肮脏体发松胡须斗闹鱼鲁鲜鸟" +
+		"凤鸣鸭鸿鹅盐丽麦面么黄点党齐齿龄龙庞龟"
+)
+
+var traditionalToSimplified = func() map[rune]rune {
+	t := []rune(traditionalChars)
+	s := []rune(simplifiedChars)
+	if len(t) != len(s) {
+		panic("docengine: 繁简对照表长度不一致")
+	}
+	m := make(map[rune]rune, len(t))
+	for i, r := range t {
+		m[r] = s[i]
+	}
+	return m
+}()
+
+// NormalizeText 搜索用的文本归一化：繁体转简体、全角转半角、英文转小写
+func NormalizeText(text string) string {
+	return strings.Map(func(r rune) rune {
+		if s, ok := traditionalToSimplified[r]; ok {
+			return s
+		}
+		switch {
+		case r == '\u3000':
+			return ' '
+		case r >= '！' && r <= '～':
+			r -= '！' - '!'
+		}
+		return unicode.ToLower(r)
+	}, text)
+}
+
+var pinyinArgs = pinyin.Args{
+	Style: pinyin.Normal,
+	Fallback: func(r rune, a pinyin.Args) []string {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			return []string{string(r)}
+		}
+		return nil
+	},
+}
+
+// PinyinKey 将文本转为连续的拼音串，汉字转为不带声调的拼音，英文数字原样保留，其余字符丢弃
+// 例如 "SAN检定" => "sanjianding"
+func PinyinKey(text string) string {
+	return strings.Join(pinyin.LazyPinyin(NormalizeText(text), pinyinArgs), "")
+}
+
+// PinyinTokens 生成用于索引的拼音词，按分隔符切段，每段输出完整拼音，纯汉字段额外输出首字母
+// 例如 "SAN检定/理智检定" => "sanjianding lizhijianding lzjd"
+func PinyinTokens(text string) string {
+	segments := strings.FieldsFunc(NormalizeText(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	tokens := make([]string, 0, len(segments)*2)
+	for _, seg := range segments {
+		syllables := pinyin.LazyPinyin(seg, pinyinArgs)
+		if len(syllables) == 0 {
+			continue
+		}
+		tokens = append(tokens, strings.Join(syllables, ""))
+		if len(syllables) > 1 && len(syllables) == len([]rune(seg)) && isHanText(seg) {
+			var initials strings.Builder
+			for _, s := range syllables {
+				initials.WriteByte(s[0])
+			}
+			tokens = append(tokens, initials.String())
+		}
+	}
+	return strings.Join(tokens, " ")
+}
+
+func isHanText(text string) bool {
+	for _, r := range text {
+		if !unicode.Is(unicode.Han, r) {
+			return false
+		}
+	}
+	return text != ""
+}
+
+func hasHanText(text string) bool {
+	for _, r := range text {
+		if unicode.Is(unicode.Han, r) {
+			return true
+		}
+	}
+	return false
+}
+
+// isLetterText 是否为非空的纯英文字母
+func isLetterText(text string) bool {
+	for _, r := range text {
+		if r >= unicode.MaxASCII || !unicode.IsLetter(r) {
+			return false
+		}
+	}
+	return text != ""
+}
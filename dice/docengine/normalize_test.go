@@ -0,0 +1,25 @@
+package docengine //nolint:testpackage // Tests need access to unexported helpers.
+
+import "testing"
+
+func TestNormalizeText(t *testing.T) {
+	cases := map[string]string{
+		"骰點檢定":      "骰点检定",
+		"ＳＡＮ　Ｃｈｅｃｋ": "san check",
+		"理智":        "理智",
+	}
+	for in, want := range cases {
+		if got := NormalizeText(in); got != want {
+			t.Errorf("NormalizeText(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestPinyin(t *testing.T) {
+	if got := PinyinKey("SAN 檢定!"); got != "sanjianding" {
+		t.Errorf("PinyinKey() = %q", got)
+	}
+	if got := PinyinTokens("SAN检定/理智检定"); got != "sanjianding lizhijianding lzjd" {
+		t.Errorf("PinyinTokens() = %q", got)
+	}
+}
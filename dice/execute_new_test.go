@@ -106,6 +106,7 @@ func (m *mockPlatformAdapter) SendSegmentToPerson(_ *MsgContext, _ string, _ []m
 }
 func (m *mockPlatformAdapter) SendFileToPerson(_ *MsgContext, _ string, _ string, _ string) {}
 func (m *mockPlatformAdapter) SendFileToGroup(_ *MsgContext, _ string, _ string, _ string)  {}
+func (m *mockPlatformAdapter) Capabilities() AdapterCapabilities                            { return AdapterCapabilities{} }
 
 // ---------------------------------------------------------------------------
 // mockDatabaseOperator
@@ -150,7 +151,7 @@ func (m *mockDatabaseOperator) Close() {
 //
 // The returned cleanup function must be deferred by every caller to stop the
 // AttrsManager background goroutine and close the database.
-func newExecuteNewTestDice(t *testing.T) (*Dice, *EndPointInfo, *mockPlatformAdapter, func()) {
+func newExecuteNewTestDice(t testing.TB) (*Dice, *EndPointInfo, *mockPlatformAdapter, func()) {
 	t.Helper()
 
 	tmpDir := t.TempDir()
@@ -522,3 +523,25 @@ func TestExecuteNew_MultipleGroups(t *testing.T) {
 		t.Error("GroupInfo not created for group B")
 	}
 }
+
+// TestExecute_ShimBuildsSegments verifies that the legacy Execute entry point
+// converts Message.Message into segments and then behaves like ExecuteNew.
+func TestExecute_ShimBuildsSegments(t *testing.T) {
+	d, ep, adapter, cleanup := newExecuteNewTestDice(t)
+	defer cleanup()
+
+	msg := newGroupMsg("QQ-Group:321", "QQ:999", "[CQ:at,qq=100000] .r 1d6")
+	msg.Segment = nil
+
+	d.ImSession.Execute(ep, msg, true)
+
+	if len(msg.Segment) != 2 {
+		t.Fatalf("expected at + text segments, got %d", len(msg.Segment))
+	}
+	if at, ok := msg.Segment[0].(*message.AtElement); !ok || at.Target != "100000" {
+		t.Fatalf("expected leading at segment, got %#v", msg.Segment[0])
+	}
+	if _, ok := adapter.waitForMsg(2 * time.Second); !ok {
+		t.Fatal("timeout: command sent through Execute was not processed")
+	}
+}
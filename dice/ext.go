@@ -9,6 +9,7 @@ import (
 	"sort"
 	"strings"
 	"sync/atomic"
+	"time"
 
 	"github.com/dop251/goja"
 	"github.com/tidwall/buntdb"
@@ -129,12 +130,15 @@ func (d *Dice) RegisterBuiltinExt() {
 	RegisterBuiltinStory(d)
 	RegisterBuiltinExtExp(d)
 	RegisterBuiltinExtCore(d)
+	RegisterBuiltinExtSchedule(d)
+	RegisterBuiltinExtCheck(d)
+	RegisterBuiltinExtCombat(d)
 
 	d.RegisterBuiltinSystemTemplate()
 }
 
 func (d *Dice) RegisterBuiltinSystemTemplate() {
-	for _, asset := range []string{"coc7.yaml", "dnd5e.yaml"} {
+	for _, asset := range []string{"coc7.yaml", "dnd5e.yaml", "vtm5.yaml", "shadowrun.yaml", "bitd.yaml", "pbta.yaml"} {
 		tmpl, err := loadBuiltinTemplate(asset)
 		if err != nil {
 			if d.Logger != nil {
@@ -330,6 +334,7 @@ func (i *ExtInfo) callWithJsCheck(d *Dice, f func()) {
 				return
 			}
 			waitRun := make(chan int, 1)
+			start := time.Now()
 			loop.RunOnLoop(func(vm *goja.Runtime) {
 				defer func() {
 					if r := recover(); r != nil {
@@ -341,6 +346,7 @@ func (i *ExtInfo) callWithJsCheck(d *Dice, f func()) {
 				f()
 			})
 			<-waitRun
+			observeJsTask("event", start)
 		} else {
 			d.Logger.Infof("当前已关闭js扩展<%v>", i.Name)
 		}
@@ -0,0 +1,103 @@
+package dice
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+const checkExtName = "check"
+
+func checkCommandHelp(name string) string {
+	return fmt.Sprintf(".%s <属性> // .%s 运动\n"+
+		".%s <表达式> DC<难度> [<原因>] // .%s 运动+2 DC15 攀上城墙\n"+
+		".%s <表达式> @某人 // 对某人做检定", name, name, name, name, name)
+}
+
+// newCheckCommand 由模板声明的通用检定指令，按当前群组使用的模板判定
+func newCheckCommand(name string) *CmdItemInfo {
+	help := checkCommandHelp(name)
+	return &CmdItemInfo{
+		Name:          name,
+		ShortHelp:     help,
+		Help:          "通用检定(规则由当前游戏系统模板提供):\n" + help,
+		AllowDelegate: true,
+		Solve: func(ctx *MsgContext, msg *Message, cmdArgs *CmdArgs) CmdExecuteResult {
+			if len(cmdArgs.Args) == 0 || cmdArgs.IsArgEqual(1, "help") {
+				return CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+			}
+			mctx := GetCtxProxyFirst(ctx, cmdArgs)
+			mctx.DelegateText = ctx.DelegateText
+
+			tmpl := mctx.Group.GetCharTemplate(mctx.Dice)
+			if tmpl == nil || !slices.Contains(tmpl.Commands.Check.Commands, name) {
+				system := ""
+				if tmpl != nil {
+					system = tmpl.Name
+				}
+				ReplyToSender(ctx, msg, fmt.Sprintf("当前游戏系统<%s>没有提供 .%s 检定，可以使用 .set 切换系统", system, name))
+				return CmdExecuteResult{Matched: true, Solved: true}
+			}
+			mctx.SystemTemplate = tmpl
+			mctx.CreateVmIfNotExists()
+
+			text, err := templateCheckRoll(mctx, tmpl, cmdArgs.CleanArgs)
+			if err != nil {
+				ReplyToSender(ctx, msg, err.Error())
+				return CmdExecuteResult{Matched: true, Solved: true}
+			}
+			if mctx.DelegateText != "" {
+				text = mctx.DelegateText + text
+			}
+			ReplyToSender(ctx, msg, text)
+			return CmdExecuteResult{Matched: true, Solved: true}
+		},
+	}
+}
+
+// registerCheckCommands 将模板声明的检定指令加入通用检定扩展，已有同名指令时保留原指令
+func (d *Dice) registerCheckCommands(tmpl *GameSystemTemplate) {
+	if tmpl == nil || tmpl.GameSystemTemplateV2 == nil || len(tmpl.Commands.Check.Commands) == 0 {
+		return
+	}
+	ext := d.ExtFind(checkExtName, false)
+	if ext == nil {
+		return
+	}
+	var cmdMap CmdMapCls
+	for _, name := range tmpl.Commands.Check.Commands {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" || ext.CmdMap[name] != nil {
+			continue
+		}
+		if cmdMap == nil {
+			// 指令表会被并发读取，这里复制一份再替换
+			cmdMap = maps.Clone(ext.CmdMap)
+			if cmdMap == nil {
+				cmdMap = CmdMapCls{}
+			}
+		}
+		cmdMap[name] = newCheckCommand(name)
+	}
+	if cmdMap != nil {
+		ext.CmdMap = cmdMap
+	}
+}
+
+func RegisterBuiltinExtCheck(self *Dice) {
+	theExt := &ExtInfo{
+		Name:       checkExtName,
+		Version:    "1.0.0",
+		Brief:      "通用检定，指令与规则由游戏系统模板提供",
+		Author:     "SealDice-Team",
+		AutoActive: false, // 由模板的 relatedExt 在 .set 时开启
+		Official:   true,
+		OnCommandReceived: func(ctx *MsgContext, msg *Message, cmdArgs *CmdArgs) {
+		},
+		GetDescText: GetExtensionDesc,
+		OnLoad:      func() {},
+		CmdMap:      CmdMapCls{},
+	}
+	self.RegisterExtension(theExt)
+}
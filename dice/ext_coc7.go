@@ -212,6 +212,7 @@ func RegisterBuiltinExtCoc7(self *Dice) {
 				var outcome = int64(r1.Value.(ds.IntType))
 				var attrVal = int64(r2.Value.(ds.IntType))
 
+				rollStatLabel(mctx, expr2Text)
 				successRank, criticalSuccessValue := ResultCheck(mctx, cocRule, outcome, attrVal, difficultyRequire)
 				// 根据难度需求，修改判定值
 				checkVal := attrVal
@@ -422,6 +423,11 @@ func RegisterBuiltinExtCoc7(self *Dice) {
 					ruleStr := strings.ReplaceAll(i.Desc, "\n", " ")
 					fmt.Fprintf(&help, ".setcoc %d/%s // %s\n", i.Index, i.Key, ruleStr)
 				}
+				// 模板
+				for _, i := range checkTemplateCocRules(ctx) {
+					ruleStr := strings.ReplaceAll(i.Desc, "\n", " ")
+					fmt.Fprintf(&help, ".setcoc %d/%s // %s\n", i.Index, i.Key, ruleStr)
+				}
 				ReplyToSender(ctx, msg, help.String())
 			case "help":
 				return CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
@@ -435,6 +441,16 @@ func RegisterBuiltinExtCoc7(self *Dice) {
 						return CmdExecuteResult{Matched: true, Solved: true}
 					}
 				}
+				if n != "" {
+					for _, i := range checkTemplateCocRules(ctx) {
+						if strings.EqualFold(i.Key, n) || strconv.Itoa(i.Index) == n {
+							ctx.Group.CocRuleIndex = i.Index
+							text := fmt.Sprintf("已切换房规为%s:\n%s%s", i.Name, i.Desc, suffix)
+							ReplyToSender(ctx, msg, text)
+							return CmdExecuteResult{Matched: true, Solved: true}
+						}
+					}
+				}
 
 				if text, ok := SetCocRuleText[ctx.Group.CocRuleIndex]; ok {
 					VarSetValueStr(ctx, "$t房规文本", text)
@@ -451,6 +467,17 @@ func RegisterBuiltinExtCoc7(self *Dice) {
 							break
 						}
 					}
+					if rule == nil {
+						for _, i := range checkTemplateCocRules(ctx) {
+							if nInt64 == i.Index {
+								rule = &CocRuleInfo{Index: i.Index, Key: i.Key, Name: i.Name, Desc: i.Desc}
+								break
+							}
+						}
+					}
+					if rule == nil {
+						rule = &CocRuleInfo{Index: nInt64, Name: strconv.Itoa(nInt64), Desc: "该房规已不存在，检定时按规则书处理"}
+					}
 
 					VarSetValueStr(ctx, "$t房规文本", rule.Desc)
 					VarSetValueStr(ctx, "$t房规", rule.Name)
@@ -1201,6 +1228,7 @@ func RegisterBuiltinExtCoc7(self *Dice) {
 			}
 
 			// 进行检定
+			rollStatLabel(mctx, "理智")
 			successRank, _ := ResultCheck(mctx, mctx.Group.CocRuleIndex, d100, san, 0)
 			suffix := GetResultText(mctx, successRank, false)
 			suffixShort := GetResultText(mctx, successRank, true)
@@ -1493,17 +1521,28 @@ type CocRuleInfo struct {
 	Check func(ctx *MsgContext, d100 int64, checkValue int64, difficultyRequired int) CocRuleCheckRet `jsbind:"check"`
 }
 
+// ResultCheck 按房规判定一次 d100 检定，检定指令事先标注过技能时计入检定统计
 func ResultCheck(ctx *MsgContext, cocRule int, d100 int64, attrValue int64, difficultyRequired int) (successRank int, criticalSuccessValue int64) {
+	successRank, criticalSuccessValue = resultCheck(ctx, cocRule, d100, attrValue, difficultyRequired)
+	rollStatNote(ctx, d100, successRank, true)
+	return successRank, criticalSuccessValue
+}
+
+func resultCheck(ctx *MsgContext, cocRule int, d100 int64, attrValue int64, difficultyRequired int) (successRank int, criticalSuccessValue int64) {
 	if cocRule >= 20 {
 		d := ctx.Dice
-		val, exists := d.CocExtraRules[cocRule]
-		if !exists {
-			cocRule = 0
-		} else {
+		if val, exists := d.CocExtraRules[cocRule]; exists {
 			ret := val.Check(ctx, d100, attrValue, difficultyRequired)
 			return ret.SuccessRank, ret.CriticalSuccessValue
 		}
 	}
+	// 模板中声明或改写了对应房规时按模板判定，未改写的内置房规直接走下面的 ResultCheckBase
+	if rank, critical, ok := templateResultCheck(ctx, cocRule, d100, attrValue, difficultyRequired); ok {
+		return rank, critical
+	}
+	if cocRule >= 20 {
+		cocRule = 0
+	}
 	return ResultCheckBase(cocRule, d100, attrValue, difficultyRequired)
 }
 
@@ -0,0 +1,413 @@
+package dice
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	ds "github.com/sealdice/dicescript"
+)
+
+var (
+	reCombatInitNum  = regexp.MustCompile(`^(\d+)`)
+	reCombatInitName = regexp.MustCompile(`^([^\s\d,，][^\s,，]*)\s*[,，]?`)
+)
+
+const combatExtName = "combat"
+
+// combatUnavailable 当前群组使用的模板没有声明先攻列表(commands.init)时给出提示并返回 true
+func combatUnavailable(ctx *MsgContext, msg *Message) bool {
+	tmpl := ctx.Group.GetCharTemplate(ctx.Dice)
+	if tmpl != nil && tmpl.GameSystemTemplateV2 != nil && tmpl.Commands.Init.Enabled() {
+		return false
+	}
+	system := ""
+	if tmpl != nil {
+		system = tmpl.Name
+	}
+	ReplyToSender(ctx, msg, fmt.Sprintf("当前游戏系统<%s>没有提供先攻列表，可以使用 .set 切换系统", system))
+	return true
+}
+
+// evalInitVal 计算先攻值，要求结果为整数
+func evalInitVal(ctx *MsgContext, expr string) (val int64, detail string, rest string, ok bool) {
+	r := ctx.Eval(expr, nil)
+	if r.vm.Error != nil || r.TypeId != ds.VMTypeInt {
+		return 0, "", "", false
+	}
+	return int64(r.MustReadInt()), r.vm.GetDetailText(), r.vm.RestInput, true
+}
+
+const helpRi = `.ri 小明 // 格式1，值为D20
+.ri 12 张三 // 格式2，值12(只能写数字)
+.ri +2 李四 // 格式3，值为D20+2
+.ri =D10+3 王五 // 格式4，值为D10+3
+.ri 张三, +2 李四, =D10+3 王五 // 设置全部
+.ri 优势 张三, 劣势-1 李四 // 支持优势劣势
+// D20 为规则模板中的先攻骰，给自己投先攻时使用模板中的先攻表达式`
+
+var cmdRi = &CmdItemInfo{
+	Name:          "ri",
+	ShortHelp:     helpRi,
+	Help:          "先攻设置:\n" + helpRi,
+	AllowDelegate: true,
+	Solve: func(ctx *MsgContext, msg *Message, cmdArgs *CmdArgs) CmdExecuteResult {
+		text := cmdArgs.CleanArgs
+		mctx := GetCtxProxyFirst(ctx, cmdArgs)
+
+		if cmdArgs.IsArgEqual(1, "help") {
+			return CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+		}
+		if combatUnavailable(ctx, msg) {
+			return CmdExecuteResult{Matched: true, Solved: true}
+		}
+
+		rule := combatInitRule(mctx)
+		readOne := func() (int, *Combatant) {
+			text = strings.TrimSpace(text)
+			c := &Combatant{}
+			var exprExists bool
+			var modifier string // 加值部分，给自己投先攻时接在先攻表达式后面重算
+
+			switch {
+			case strings.HasPrefix(text, "+"), strings.HasPrefix(text, "-"),
+				strings.HasPrefix(text, "优势"), strings.HasPrefix(text, "劣势"):
+				// 加值情况1，D20+ / D20- / 优势劣势
+				val, detail, rest, ok := evalInitVal(mctx, rule.Dice+text)
+				if !ok {
+					if strings.HasPrefix(text, "优势") || strings.HasPrefix(text, "劣势") {
+						return 2, c
+					}
+					return 1, c
+				}
+				modifier = text[:len(text)-len(rest)]
+				c.Val, c.Detail = val, detail
+				text = rest
+				exprExists = true
+			case strings.HasPrefix(text, "="):
+				// 加值情况2，=表达式
+				val, detail, rest, ok := evalInitVal(mctx, text[1:])
+				if !ok {
+					return 1, c
+				}
+				c.Val, c.Detail = val, detail
+				text = rest
+				exprExists = true
+			default:
+				// 加值情况3，数字
+				m := reCombatInitNum.FindStringSubmatch(text)
+				if len(m) > 0 {
+					c.Val, _ = strconv.ParseInt(m[0], 10, 64)
+					text = text[len(m[0]):]
+					exprExists = true
+				}
+			}
+
+			// 清理读取了第一项文本之后的空格
+			text = strings.TrimSpace(text)
+
+			if strings.HasPrefix(text, ",") || strings.HasPrefix(text, "，") || text == "" {
+				// 句首有,的话，吃掉
+				text = strings.TrimPrefix(text, ",")
+				text = strings.TrimPrefix(text, "，")
+				// 情况1，名字是自己
+				name := mctx.Player.Name
+				// replace any space or \n with _
+				name = strings.ReplaceAll(name, " ", "_")
+				name = strings.ReplaceAll(name, "\n", "_")
+				c.Name = name
+				c.UID = mctx.Player.UserID
+				// 情况2，名字是自己，使用模板中的先攻表达式
+				switch {
+				case !exprExists:
+					if val, _, _, ok := evalInitVal(mctx, rule.Expr); ok {
+						c.Val = val
+					} else if val, _, _, ok = evalInitVal(mctx, rule.Dice); ok {
+						c.Val = val
+					} else {
+						return 1, c
+					}
+				case modifier != "" && rule.Expr != rule.Dice:
+					if val, detail, _, ok := evalInitVal(mctx, rule.Expr+modifier); ok {
+						c.Val, c.Detail = val, detail
+					}
+				}
+				return 0, c
+			}
+
+			// 情况3: 是名字
+			m := reCombatInitName.FindStringSubmatch(text)
+			if len(m) == 0 {
+				// 不知道是啥，报错
+				return 2, c
+			}
+			c.Name = m[1]
+			text = text[len(m[0]):]
+			if !exprExists {
+				val, _, _, ok := evalInitVal(mctx, rule.Dice)
+				if !ok {
+					return 1, c
+				}
+				c.Val = val
+			}
+			return 0, c
+		}
+
+		solved := true
+		tryOnce := true
+		var items CombatantList
+
+		for tryOnce || text != "" {
+			code, c := readOne()
+			items = append(items, c)
+
+			if code != 0 {
+				solved = false
+				break
+			}
+			tryOnce = false
+		}
+
+		if !solved {
+			ReplyToSender(ctx, msg, DiceFormatTmpl(mctx, "DND:先攻_设置_格式错误"))
+			return CmdExecuteResult{Matched: true, Solved: true}
+		}
+
+		var textOut strings.Builder
+		textOut.WriteString(DiceFormatTmpl(mctx, "DND:先攻_设置_前缀"))
+		sort.Sort(items)
+		for order, i := range items {
+			var detail string
+			if i.Detail != "" {
+				detail = i.Detail + "="
+			}
+			_, _ = fmt.Fprintf(&textOut, "%2d. %s: %s%d\n", order+1, i.Name, detail, i.Val)
+		}
+
+		UpdateCombatTracker(ctx.Dice, ctx.Group, func(t *CombatTracker) bool {
+			t.Add(items...)
+			return true
+		})
+		ReplyToSender(ctx, msg, textOut.String())
+		return CmdExecuteResult{Matched: true, Solved: true}
+	},
+}
+
+var cmdInit = &CmdItemInfo{
+	Name: "init",
+	ShortHelp: ".init // 查看先攻列表\n" +
+		".init del <单位1> <单位2> ... // 从先攻列表中删除\n" +
+		".init set <单位名称> <先攻表达式> // 设置单位的先攻\n" +
+		".init cond <单位名称> <状态> [持续轮数] // 为单位附加状态，不写轮数则持续到手动移除\n" +
+		".init cond rm <单位名称> <状态> // 移除单位的状态\n" +
+		".init clr // 清除先攻列表\n" +
+		".init end/next // 结束一回合，并提醒下一位\n" +
+		".init help // 显示本帮助",
+	Solve: func(ctx *MsgContext, msg *Message, cmdArgs *CmdArgs) CmdExecuteResult {
+		cmdArgs.ChopPrefixToArgsWith("del", "set", "rm", "ed")
+		n := cmdArgs.GetArgN(1)
+		if n != "help" && combatUnavailable(ctx, msg) {
+			return CmdExecuteResult{Matched: true, Solved: true}
+		}
+		switch n {
+		case "", "list":
+			var textOut strings.Builder
+			textOut.WriteString(DiceFormatTmpl(ctx, "DND:先攻_查看_前缀"))
+			tracker := LoadCombatTracker(ctx.Dice, ctx.Group)
+			tmpl := ctx.Group.GetCharTemplate(ctx.Dice)
+
+			for order, i := range tracker.Combatants {
+				_, _ = fmt.Fprintf(&textOut, "%2d. %s: %d%s\n", order+1, i.Name, i.Val, combatantExtraText(ctx, tmpl, i))
+			}
+
+			if current := tracker.Current(); current == nil {
+				textOut.WriteString("- 没有找到任何单位")
+			} else {
+				_, _ = fmt.Fprintf(&textOut, "第%d轮，当前回合：%s", tracker.Round, current.Name)
+			}
+
+			ReplyToSender(ctx, msg, textOut.String())
+		case "ed", "end", "next":
+			var ended *Combatant
+			var expired []*CombatCondition
+			tracker := UpdateCombatTracker(ctx.Dice, ctx.Group, func(t *CombatTracker) bool {
+				ended, expired = t.Next()
+				return ended != nil
+			})
+			if ended == nil {
+				ReplyToSender(ctx, msg, "先攻列表为空")
+				break
+			}
+
+			setInitNextRoundVars(ctx, tracker)
+			textOut := DiceFormatTmpl(ctx, "DND:先攻_下一回合")
+			if len(expired) > 0 {
+				VarSetValueStr(ctx, "$t目标", ended.Name)
+				VarSetValueStr(ctx, "$t状态列表", conditionNames(expired))
+				textOut += "\n" + DiceFormatTmpl(ctx, "DND:先攻_状态_结束")
+			}
+			ReplyToSender(ctx, msg, textOut)
+		case "del", "rm":
+			tryDeleteMembersInInitList := func(deleteNames []string) (textOut strings.Builder, ok bool) {
+				var empty bool
+				var removed CombatantList
+				var current *Combatant
+				tracker := UpdateCombatTracker(ctx.Dice, ctx.Group, func(t *CombatTracker) bool {
+					if len(t.Combatants) == 0 {
+						empty = true
+						return false
+					}
+					removed, current = t.Remove(deleteNames...)
+					return len(removed) > 0
+				})
+				if empty {
+					textOut.WriteString("- 没有找到任何单位[先攻列表为空]\n")
+					return textOut, false
+				}
+				if len(removed) == 0 {
+					textOut.WriteString("- 没有找到任何单位\n")
+					return textOut, false
+				}
+				for index, i := range removed {
+					_, _ = fmt.Fprintf(&textOut, "%2d. %s\n", index+1, i.Name)
+				}
+
+				if current != nil {
+					if len(tracker.Combatants) == 0 {
+						textOut.WriteString(DiceFormatTmpl(ctx, "DND:先攻_清除列表"))
+					} else {
+						setInitNextRoundVars(ctx, tracker)
+						// Note(Xiangze Li): 这是为了让回合结束的角色显示为被删除的角色，而不是当前角色的上一个
+						VarSetValueStr(ctx, "$t当前回合角色名", current.Name)
+						VarSetValueStr(ctx, "$t当前回合at", AtBuild(current.UID))
+						textOut.WriteString(DiceFormatTmpl(ctx, "DND:先攻_下一回合"))
+					}
+				}
+				return textOut, true
+			}
+
+			nameWithSpace, _ := cmdArgs.EatPrefixWith("del", "rm")
+			textOut, ok := tryDeleteMembersInInitList([]string{nameWithSpace})
+			if !ok {
+				textOut, _ = tryDeleteMembersInInitList(cmdArgs.Args[1:])
+			}
+			textToSend := DiceFormatTmpl(ctx, "DND:先攻_移除_前缀") + textOut.String()
+
+			ReplyToSender(ctx, msg, textToSend)
+		case "set":
+			name := cmdArgs.GetArgN(2)
+			arg3 := cmdArgs.GetArgN(3)
+			if name == "" || arg3 == "" {
+				ReplyToSender(ctx, msg, "错误的格式，应为: .init set <单位名称> <先攻表达式>")
+				return CmdExecuteResult{Matched: true, Solved: true}
+			}
+
+			expr := strings.Join(cmdArgs.Args[2:], "")
+			r := ctx.Eval(expr, nil)
+			if r.vm.Error != nil || r.TypeId != ds.VMTypeInt {
+				ReplyToSender(ctx, msg, "错误的格式，应为: .init set <单位名称> <先攻表达式>")
+				return CmdExecuteResult{Matched: true, Solved: true}
+			}
+
+			UpdateCombatTracker(ctx.Dice, ctx.Group, func(t *CombatTracker) bool {
+				t.Add(&Combatant{Name: name, Val: int64(r.MustReadInt())})
+				return true
+			})
+
+			VarSetValueStr(ctx, "$t表达式", expr)
+			VarSetValueStr(ctx, "$t目标", name)
+			VarSetValueStr(ctx, "$t计算过程", r.vm.GetDetailText())
+			VarSetValue(ctx, "$t点数", &r.VMValue)
+			ReplyToSender(ctx, msg, DiceFormatTmpl(ctx, "DND:先攻_设置_指定单位"))
+		case "cond":
+			if cmdArgs.IsArgEqual(2, "rm", "del") {
+				name := cmdArgs.GetArgN(3)
+				condition := cmdArgs.GetArgN(4)
+				if name == "" || condition == "" {
+					ReplyToSender(ctx, msg, "错误的格式，应为: .init cond rm <单位名称> <状态>")
+					break
+				}
+				var exists, removed bool
+				UpdateCombatTracker(ctx.Dice, ctx.Group, func(t *CombatTracker) bool {
+					exists = t.Combatants.GetExists(name) != nil
+					removed = exists && t.RemoveCondition(name, condition)
+					return removed
+				})
+				VarSetValueStr(ctx, "$t目标", name)
+				VarSetValueStr(ctx, "$t状态", condition)
+				if !exists {
+					ReplyToSender(ctx, msg, DiceFormatTmpl(ctx, "DND:先攻_单位不存在"))
+					break
+				}
+				if !removed {
+					ReplyToSender(ctx, msg, DiceFormatTmpl(ctx, "DND:先攻_状态_不存在"))
+					break
+				}
+				ReplyToSender(ctx, msg, DiceFormatTmpl(ctx, "DND:先攻_状态_移除"))
+				break
+			}
+
+			name := cmdArgs.GetArgN(2)
+			condition := cmdArgs.GetArgN(3)
+			if name == "" || condition == "" {
+				ReplyToSender(ctx, msg, "错误的格式，应为: .init cond <单位名称> <状态> [持续轮数]")
+				break
+			}
+			var rounds int64
+			if s := cmdArgs.GetArgN(4); s != "" {
+				var err error
+				rounds, err = strconv.ParseInt(s, 10, 64)
+				if err != nil || rounds < 0 {
+					ReplyToSender(ctx, msg, "错误的格式，应为: .init cond <单位名称> <状态> [持续轮数]")
+					break
+				}
+			}
+
+			var ok bool
+			UpdateCombatTracker(ctx.Dice, ctx.Group, func(t *CombatTracker) bool {
+				ok = t.SetCondition(name, condition, rounds)
+				return ok
+			})
+			VarSetValueStr(ctx, "$t目标", name)
+			VarSetValueStr(ctx, "$t状态", condition)
+			VarSetValueInt64(ctx, "$t持续轮数", rounds)
+			if !ok {
+				ReplyToSender(ctx, msg, DiceFormatTmpl(ctx, "DND:先攻_单位不存在"))
+				break
+			}
+			ReplyToSender(ctx, msg, DiceFormatTmpl(ctx, "DND:先攻_状态_附加"))
+		case "clr", "clear":
+			UpdateCombatTracker(ctx.Dice, ctx.Group, func(t *CombatTracker) bool {
+				t.Clear()
+				return true
+			})
+			ReplyToSender(ctx, msg, DiceFormatTmpl(ctx, "DND:先攻_清除列表"))
+		case "help":
+			return CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+		}
+
+		return CmdExecuteResult{Matched: true, Solved: true}
+	},
+}
+
+func RegisterBuiltinExtCombat(self *Dice) {
+	theExt := &ExtInfo{
+		Name:       combatExtName,
+		Version:    "1.0.0",
+		Brief:      "先攻列表与战斗轮次，在声明了 init 的游戏系统模板中可用",
+		Author:     "SealDice-Team",
+		AutoActive: true,
+		Official:   true,
+		OnCommandReceived: func(ctx *MsgContext, msg *Message, cmdArgs *CmdArgs) {
+		},
+		GetDescText: GetExtensionDesc,
+		OnLoad:      func() {},
+		CmdMap: CmdMapCls{
+			"ri":   cmdRi,
+			"init": cmdInit,
+		},
+	}
+	self.RegisterExtension(theExt)
+}
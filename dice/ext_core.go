@@ -57,6 +57,7 @@ func RegisterBuiltinExtCore(dice *Dice) {
 
 	theExt.CmdMap = map[string]*CmdItemInfo{
 		"team": cmdTeam,
+		"rng":  cmdRng,
 	}
 
 	dice.RegisterExtension(theExt)
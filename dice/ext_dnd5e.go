@@ -8,33 +8,10 @@ import (
 	"sort"
 	"strconv"
 	"strings"
-	"sync"
 
 	ds "github.com/sealdice/dicescript"
 )
 
-type RIListItem struct {
-	name   string
-	val    int64
-	detail string
-	uid    string
-}
-
-type RIList []*RIListItem
-
-func (lst RIList) Len() int {
-	return len(lst)
-}
-func (lst RIList) Swap(i, j int) {
-	lst[i], lst[j] = lst[j], lst[i]
-}
-func (lst RIList) Less(i, j int) bool {
-	if lst[i].val == lst[j].val {
-		return lst[i].name > lst[j].name
-	}
-	return lst[i].val > lst[j].val
-}
-
 var dndAttrParent = map[string]string{
 	"运动": "力量",
 
@@ -60,8 +37,6 @@ var dndAttrParent = map[string]string{
 	"表演": "魅力",
 }
 
-const NULL_INIT_VAL = math.MaxInt32 // 不使用 MAX_INT64 以保证 JS 环境使用时不会出现潜在问题
-
 func getPlayerNameTempFunc(mctx *MsgContext) string {
 	if mctx.Dice.Config.PlayerNameWrapEnable {
 		return fmt.Sprintf("<%s>", mctx.Player.Name)
@@ -144,6 +119,8 @@ func RegisterBuiltinExtDnd5e(self *Dice) {
 	helpSt += ".st clr/clear // 清除属性\n"
 	helpSt += ".st del <属性1> <属性2> ... // 删除属性，可多项，以空格间隔\n"
 	helpSt += ".st export // 导出，包括属性和法术位\n"
+	helpSt += ".st history [<条数>] // 查看最近的属性变更记录\n"
+	helpSt += ".st undo [<次数>] // 撤销最近几次属性变更，默认1次\n"
 	helpSt += ".st help // 帮助\n"
 	helpSt += ".st <属性>:<值> // 设置属性，技能加值会自动计算。例：.st 感知:20 洞悉:3\n"
 	helpSt += ".st <属性>=<值> // 设置属性，等号效果完全相同\n"
@@ -396,6 +373,7 @@ func RegisterBuiltinExtDnd5e(self *Dice) {
 		".rc <属性> // .rc 力量\n" +
 		".rc <属性>豁免 // .rc 力量豁免\n" +
 		".rc <表达式> // .rc 力量+3\n" +
+		".rc <表达式> DC<难度> [<原因>] // .rc 运动+2 DC15 攀上城墙\n" +
 		".rc 3# <表达式> // 多重检定\n" +
 		".rc 优势 <表达式> // .rc 优势 力量+4\n" +
 		".rc 劣势 <表达式> [<原因>] // .rc 劣势 力量+4 推一下试试\n" +
@@ -442,6 +420,13 @@ func RegisterBuiltinExtDnd5e(self *Dice) {
 				if tmpl != nil {
 					mctx.SystemTemplate = tmpl
 				}
+				// 检定规则由模板提供，群组模板没有声明时使用内置的 dnd5e 模板
+				checkTmpl := checkTemplateForExt(mctx, "dnd5e")
+				if checkTmpl == nil {
+					ReplyToSender(mctx, msg, "未找到 dnd5e 检定规则，请检查游戏系统模板")
+					return CmdExecuteResult{Matched: true, Solved: true}
+				}
+				check := &checkTmpl.Commands.Check
 				// 初始化多轮检定结果保存数组
 				textList := make([]string, 0)
 				// 多轮检定判断
@@ -496,10 +481,13 @@ func RegisterBuiltinExtDnd5e(self *Dice) {
 						ReplyToSender(mctx, msg, "无法解析表达式: "+restText)
 						return CmdExecuteResult{Matched: true, Solved: true}
 					}
-					// 拿到执行的结果
-					reason := r2.vm.RestInput
+					// 拿到执行的结果，原因开头的数字或 DC 视为难度值
+					dc, reason, hasDC := checkCutDC(r2.vm.RestInput)
 					if reason == "" {
 						reason = restText
+						if hasDC {
+							reason = strings.TrimSpace(r2.vm.Matched)
+						}
 					}
 					reason = LimitCommandReasonText(reason)
 					modifier, ok := r2.ReadInt()
@@ -520,20 +508,33 @@ func RegisterBuiltinExtDnd5e(self *Dice) {
 					}
 					detail := fmt.Sprintf("%s + %s", diceDetail, modifierDetail)
 					// Pinenutn/bugtower100：猜测这里只是格式化的部分，所以如果做多次检定，这个变量保存最后一次就够了
+					// 检定结果与成败均由模板的检定规则给出
+					VarSetValueInt64(mctx, "$tDC", dc)
+					rollStatLabel(mctx, r2.vm.Matched)
+					checkRet, err := check.Evaluate(mctx, check.Rule(0), int64(d20Result), int64(modifier), 0)
+					if err != nil {
+						ReplyToSender(mctx, msg, fmt.Sprintf("模板<%s>的检定规则执行出错: %v", checkTmpl.Name, err))
+						return CmdExecuteResult{Matched: true, Solved: true}
+					}
 					VarSetValueStr(mctx, "$t技能", reason)
 					VarSetValueStr(mctx, "$t检定过程文本", detail)
-					VarSetValueInt64(mctx, "$t检定结果", int64(d20Result+modifier))
+					VarSetValueInt64(mctx, "$t检定结果", checkRet.Target)
+					VarSetValueStr(mctx, "$t判定结果", check.TierText(mctx, checkRet.Rank))
+					VarSetValueInt64(mctx, "$tSuccessRank", int64(checkRet.Rank))
 					// 添加对应结果文本，若只执行一次，则使用DND检定，否则使用单项文本初始化
+					// 没有难度值且不是天然 20/1 时判定结果为空，去掉末尾多出的空格
 					if round == 1 {
-						textList = append(textList, DiceFormatTmpl(mctx, "DND:检定"))
+						textList = append(textList, strings.TrimSpace(DiceFormatTmpl(mctx, "DND:检定")))
 					} else {
-						textList = append(textList, DiceFormatTmpl(mctx, "DND:检定_单项结果文本"))
+						textList = append(textList, strings.TrimSpace(DiceFormatTmpl(mctx, "DND:检定_单项结果文本")))
 					}
 					// 添加对应commandItems
 					commandItems = append(commandItems, map[string]interface{}{
-						"expr":   expr,
-						"reason": reason,
-						"result": d20Result + modifier,
+						"expr":    expr,
+						"reason":  reason,
+						"result":  checkRet.Target,
+						"outcome": int64(d20Result),
+						"rank":    checkRet.Rank,
 					})
 				}
 				// 拼接文本
@@ -1285,364 +1286,6 @@ func RegisterBuiltinExtDnd5e(self *Dice) {
 			return CmdExecuteResult{Matched: true, Solved: true}
 		},
 	}
-	helpRi := `.ri 小明 // 格式1，值为D20
-.ri 12 张三 // 格式2，值12(只能写数字)
-.ri +2 李四 // 格式3，值为D20+2
-.ri =D10+3 王五 // 格式4，值为D10+3
-.ri 张三, +2 李四, =D10+3 王五 // 设置全部
-.ri 优势 张三, 劣势-1 李四 // 支持优势劣势`
-	cmdRi := &CmdItemInfo{
-		Name:          "ri",
-		ShortHelp:     helpRi,
-		Help:          "先攻设置:\n" + helpRi,
-		AllowDelegate: true,
-		Solve: func(ctx *MsgContext, msg *Message, cmdArgs *CmdArgs) CmdExecuteResult {
-			text := cmdArgs.CleanArgs
-			mctx := GetCtxProxyFirst(ctx, cmdArgs)
-
-			if cmdArgs.IsArgEqual(1, "help") {
-				return CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
-			}
-
-			readOne := func() (int, string, int64, string, string) {
-				text = strings.TrimSpace(text)
-				var name string
-				var val int64
-				var detail string
-				var exprExists bool
-				var uid string
-
-				if strings.HasPrefix(text, "+") {
-					// 加值情况1，D20+
-					r := mctx.Eval("d20"+text, nil)
-					if r.vm.Error != nil {
-						// 情况1，加值输入错误
-						return 1, name, val, detail, ""
-					}
-					detail = r.vm.GetDetailText()
-					val = int64(r.MustReadInt())
-					text = r.vm.RestInput
-					exprExists = true
-				} else if strings.HasPrefix(text, "-") {
-					// 加值情况1.1，D20-
-					r := mctx.Eval("d20"+text, nil)
-					if r.vm.Error != nil {
-						// 情况1，加值输入错误
-						return 1, name, val, detail, ""
-					}
-					detail = r.vm.GetDetailText()
-					val = int64(r.MustReadInt())
-					text = r.vm.RestInput
-					exprExists = true
-				} else if strings.HasPrefix(text, "=") {
-					// 加值情况1，=表达式
-					r := mctx.Eval(text[1:], nil)
-					if r.vm.Error != nil {
-						// 情况1，加值输入错误
-						return 1, name, val, detail, ""
-					}
-					val = int64(r.MustReadInt())
-					detail = r.vm.GetDetailText()
-					text = r.vm.RestInput
-					exprExists = true
-				} else if strings.HasPrefix(text, "优势") || strings.HasPrefix(text, "劣势") {
-					// 优势/劣势
-					r := mctx.Eval("d20"+text, nil)
-					if r.vm.Error != nil {
-						// 优势劣势输入错误
-						return 2, name, val, detail, ""
-					}
-					detail = r.vm.GetDetailText()
-					val = int64(r.MustReadInt())
-					text = r.vm.RestInput
-					exprExists = true
-				} else {
-					// 加值情况3，数字
-					reNum := regexp.MustCompile(`^(\d+)`)
-					m := reNum.FindStringSubmatch(text)
-					if len(m) > 0 {
-						val, _ = strconv.ParseInt(m[0], 10, 64)
-						text = text[len(m[0]):]
-						exprExists = true
-					}
-				}
-
-				// 清理读取了第一项文本之后的空格
-				text = strings.TrimSpace(text)
-
-				if strings.HasPrefix(text, ",") || strings.HasPrefix(text, "，") || text == "" {
-					// 句首有,的话，吃掉
-					text = strings.TrimPrefix(text, ",")
-					text = strings.TrimPrefix(text, "，")
-					// 情况1，名字是自己
-					name = mctx.Player.Name
-					// replace any space or \n with _
-					name = strings.ReplaceAll(name, " ", "_")
-					name = strings.ReplaceAll(name, "\n", "_")
-					// 情况2，名字是自己，没有加值
-					if !exprExists {
-						val = int64(ds.Roll(nil, 20, 0))
-					}
-					uid = mctx.Player.UserID
-					return 0, name, val, detail, uid
-				}
-
-				// 情况3: 是名字
-				reName := regexp.MustCompile(`^([^\s\d,，][^\s,，]*)\s*[,，]?`)
-				m := reName.FindStringSubmatch(text)
-				if len(m) > 0 {
-					name = m[1]
-					text = text[len(m[0]):]
-					if !exprExists {
-						val = int64(ds.Roll(nil, 20, 0))
-					}
-				} else {
-					// 不知道是啥，报错
-					return 2, name, val, detail, ""
-				}
-
-				return 0, name, val, detail, ""
-			}
-
-			solved := true
-			tryOnce := true
-			var items RIList
-
-			for tryOnce || text != "" {
-				code, name, val, detail, uid := readOne()
-				items = append(items, &RIListItem{name, val, detail, uid})
-
-				if code != 0 {
-					solved = false
-					break
-				}
-				tryOnce = false
-			}
-
-			if solved {
-				riList := (RIList{}).LoadByCurGroup(ctx)
-
-				var textOut strings.Builder
-				textOut.WriteString(DiceFormatTmpl(mctx, "DND:先攻_设置_前缀"))
-				sort.Sort(items)
-				if riList.Len() == 0 {
-					VarSetValueInt64(ctx, "$g当前回合先攻值", NULL_INIT_VAL)
-				}
-				for order, i := range items {
-					var detail string
-					if i.detail != "" {
-						detail = i.detail + "="
-					}
-					_, _ = fmt.Fprintf(&textOut, "%2d. %s: %s%d\n", order+1, i.name, detail, i.val)
-
-					item := riList.GetExists(i.name)
-					if item == nil {
-						curInitVal, _ := VarGetValueInt64(ctx, "$g当前回合先攻值")
-						if i.val > curInitVal {
-							round, _ := VarGetValueInt64(ctx, "$g回合数")
-							// 当前先攻值不变，修改回合数
-							VarSetValueInt64(ctx, "$g回合数", round+1)
-						}
-						riList = append(riList, i)
-					} else {
-						item.val = i.val
-					}
-				}
-
-				sort.Sort(riList)
-				riList.SaveToGroup(ctx)
-				ReplyToSender(ctx, msg, textOut.String())
-			} else {
-				ReplyToSender(ctx, msg, DiceFormatTmpl(mctx, "DND:先攻_设置_格式错误"))
-			}
-			return CmdExecuteResult{Matched: true, Solved: true}
-		},
-	}
-
-	/**
-	Note(Szzrain) at 2025-3-23:
-	这里解释一下战斗轮机制运行的原理。
-	1，首先，在先攻列表为空时，添加任何单位会将 {$g当前回合先攻值} 设定为 INT_MAX 但如果先攻列表不为空，那么添加任何单位都不会更改 {$g当前回合先攻值}
-	2. 只有在进行 .init ed 时才会将 {$g当前回合先攻值} 设定为新单位的先攻值
-	3. 在进行 .init del 时，如果删除的单位是当前回合的单位，那么 {$g当前回合先攻值} 会设定为下一个单位的先攻值，如果删除的单位不是当前回合的单位，那么 {$g当前回合先攻值} 不会改变
-	4. 在新一轮时，会将 {$g当前回合先攻值} 设定为下一个单位的先攻值，而不是 INT_MAX
-	5. 在进行 .init clr 时，会将 {$g当前回合先攻值} 设定为 INT_MAX 如果 .init del 删除了最后一个单位，那么 {$g当前回合先攻值} 会设定为 INT_MAX
-	6. {$g回合数} 会记录当前是第几回合，用于先攻值相同时的辅助处理。但是在添加/删除单位时 {$g当前回合先攻值} 的判断优先度更高
-	*/
-	cmdInit := &CmdItemInfo{
-		Name: "init",
-		ShortHelp: ".init // 查看先攻列表\n" +
-			".init del <单位1> <单位2> ... // 从先攻列表中删除\n" +
-			".init set <单位名称> <先攻表达式> // 设置单位的先攻\n" +
-			".init clr // 清除先攻列表\n" +
-			".init end // 结束一回合" +
-			".init help // 显示本帮助",
-		Solve: func(ctx *MsgContext, msg *Message, cmdArgs *CmdArgs) CmdExecuteResult {
-			cmdArgs.ChopPrefixToArgsWith("del", "set", "rm", "ed")
-			n := cmdArgs.GetArgN(1)
-			switch n {
-			case "", "list":
-				var textOut strings.Builder
-				textOut.WriteString(DiceFormatTmpl(ctx, "DND:先攻_查看_前缀"))
-				riList := (RIList{}).LoadByCurGroup(ctx)
-
-				round, _ := VarGetValueInt64(ctx, "$g回合数")
-
-				for order, i := range riList {
-					_, _ = fmt.Fprintf(&textOut, "%2d. %s: %d\n", order+1, i.name, i.val)
-				}
-
-				if len(riList) == 0 {
-					textOut.WriteString("- 没有找到任何单位")
-				} else {
-					if len(riList) <= int(round) || round < 0 {
-						round = 0
-					}
-					rounder := riList[round]
-					_, _ = fmt.Fprintf(&textOut, "当前回合：%s", rounder.name)
-				}
-
-				ReplyToSender(ctx, msg, textOut.String())
-			case "ed", "end":
-				lst := (RIList{}).LoadByCurGroup(ctx)
-				round, _ := VarGetValueInt64(ctx, "$g回合数")
-				if len(lst) == 0 {
-					ReplyToSender(ctx, msg, "先攻列表为空")
-					break
-				}
-				round = (round + 1) % int64(len(lst))
-
-				setInitNextRoundVars(ctx, lst, round)
-				ReplyToSender(ctx, msg, DiceFormatTmpl(ctx, "DND:先攻_下一回合"))
-			case "del", "rm":
-				tryDeleteMembersInInitList := func(deleteNames []string, riList RIList) (newList RIList, textOut strings.Builder, ok bool) {
-					if len(riList) == 0 {
-						textOut.WriteString("- 没有找到任何单位[先攻列表为空]\n")
-						return riList, textOut, false
-					}
-					round, _ := VarGetValueInt64(ctx, "$g回合数")
-					round %= int64(len(riList))
-					toDeleted := map[string]bool{}
-					for _, i := range deleteNames {
-						toDeleted[i] = true
-					}
-
-					delCounter := 0
-
-					preCurrent := 0 // 每有一个在当前单位前面的单位被删除, 当前单位下标需要减 1
-					for index, i := range riList {
-						if !toDeleted[i.name] {
-							newList = append(newList, i)
-						} else {
-							delCounter++
-							_, _ = fmt.Fprintf(&textOut, "%2d. %s\n", delCounter, i.name)
-
-							if int64(index) < round {
-								preCurrent++
-							}
-						}
-					}
-					current := *riList[round]
-					currentDeleted := toDeleted[current.name]
-
-					round -= int64(preCurrent)
-					if round >= int64(len(newList)) {
-						round = 0
-					}
-					VarSetValueInt64(ctx, "$g回合数", round)
-
-					if delCounter == 0 {
-						textOut.WriteString("- 没有找到任何单位\n")
-						return newList, textOut, false
-					}
-
-					newList.SaveToGroup(ctx)
-					if currentDeleted {
-						if len(newList) == 0 {
-							VarSetValueInt64(ctx, "$g当前回合先攻值", NULL_INIT_VAL)
-							textOut.WriteString(DiceFormatTmpl(ctx, "DND:先攻_清除列表"))
-						} else {
-							setInitNextRoundVars(ctx, newList, round)
-							// Note(Xiangze Li): 这是为了让回合结束的角色显示为被删除的角色，而不是当前角色的上一个
-							VarSetValueStr(ctx, "$t当前回合角色名", current.name)
-							VarSetValueStr(ctx, "$t当前回合at", AtBuild(current.uid))
-							textOut.WriteString(DiceFormatTmpl(ctx, "DND:先攻_下一回合"))
-						}
-					}
-					return newList, textOut, true
-				}
-
-				nameWithSpace, _ := cmdArgs.EatPrefixWith("del", "rm")
-				riList := (RIList{}).LoadByCurGroup(ctx)
-				_, textOut, ok := tryDeleteMembersInInitList([]string{nameWithSpace}, riList)
-				if !ok {
-					_, textOut, _ = tryDeleteMembersInInitList(cmdArgs.Args[1:], riList)
-				}
-				textToSend := DiceFormatTmpl(ctx, "DND:先攻_移除_前缀") + textOut.String()
-
-				ReplyToSender(ctx, msg, textToSend)
-			case "set":
-				name := cmdArgs.GetArgN(2)
-				exists := name != ""
-				arg3 := cmdArgs.GetArgN(3)
-				exists2 := arg3 != ""
-				if !exists || !exists2 {
-					ReplyToSender(ctx, msg, "错误的格式，应为: .init set <单位名称> <先攻表达式>")
-					return CmdExecuteResult{Matched: true, Solved: true}
-				}
-
-				expr := strings.Join(cmdArgs.Args[2:], "")
-				r := ctx.Eval(expr, nil)
-				if r.vm.Error != nil || r.TypeId != ds.VMTypeInt {
-					ReplyToSender(ctx, msg, "错误的格式，应为: .init set <单位名称> <先攻表达式>")
-					return CmdExecuteResult{Matched: true, Solved: true}
-				}
-
-				riList := (RIList{}).LoadByCurGroup(ctx)
-				added := false
-				for _, i := range riList {
-					if i.name == name {
-						i.val = int64(r.MustReadInt())
-						added = true
-						break
-					}
-				}
-				if !added {
-					if len(riList) == 0 {
-						VarSetValueInt64(ctx, "$g当前回合先攻值", NULL_INIT_VAL)
-					} else {
-						curInitVal, _ := VarGetValueInt64(ctx, "$g当前回合先攻值")
-						if int64(r.MustReadInt()) > curInitVal {
-							round, _ := VarGetValueInt64(ctx, "$g回合数")
-							// 当前先攻值不变，修改回合数
-							VarSetValueInt64(ctx, "$g回合数", round+1)
-						}
-					}
-					riList = append(riList, &RIListItem{name, int64(r.MustReadInt()), "", ""})
-				}
-				sort.Sort(riList)
-
-				VarSetValueStr(ctx, "$t表达式", expr)
-				VarSetValueStr(ctx, "$t目标", name)
-				VarSetValueStr(ctx, "$t计算过程", r.vm.GetDetailText())
-				VarSetValue(ctx, "$t点数", &r.VMValue)
-				textOut := DiceFormatTmpl(ctx, "DND:先攻_设置_指定单位")
-
-				riList.SaveToGroup(ctx)
-				ReplyToSender(ctx, msg, textOut)
-			case "clr", "clear":
-				(RIList{}).SaveToGroup(ctx)
-				VarSetValueInt64(ctx, "$g当前回合先攻值", NULL_INIT_VAL)
-				ReplyToSender(ctx, msg, DiceFormatTmpl(ctx, "DND:先攻_清除列表"))
-				VarSetValueInt64(ctx, "$g回合数", 0)
-			case "help":
-				return CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
-			}
-
-			return CmdExecuteResult{Matched: true, Solved: true}
-		},
-	}
-
 	theExt := &ExtInfo{
 		Name:       "dnd5e", // 扩展的名称，需要用于开启和关闭指令中，写简短点
 		Version:    "1.0.0",
@@ -1659,8 +1302,6 @@ func RegisterBuiltinExtDnd5e(self *Dice) {
 		CmdMap: CmdMapCls{
 			"dnd":  cmdDnd,
 			"dndx": cmdDnd,
-			"ri":   cmdRi,
-			"init": cmdInit,
 			// "属性":    cmdSt,
 			"st":         cmdSt,
 			"dst":        cmdSt,
@@ -1687,107 +1328,3 @@ func RegisterBuiltinExtDnd5e(self *Dice) {
 
 	self.RegisterExtension(theExt)
 }
-
-var dndRiLock sync.Mutex
-
-// LoadByCurGroup 从群信息中加载
-func (lst RIList) LoadByCurGroup(ctx *MsgContext) RIList {
-	am := ctx.Dice.AttrsManager
-	attrs, _ := am.LoadById(ctx.Group.GroupID)
-
-	dndRiLock.Lock()
-	riList := attrs.Load("riList")
-	if riList == nil || riList.TypeId != ds.VMTypeArray {
-		riList = ds.NewArrayVal()
-		attrs.Store("riList", riList)
-	}
-	dndRiLock.Unlock()
-
-	ret := RIList{}
-	for _, i := range riList.MustReadArray().List {
-		if i.TypeId != ds.VMTypeDict {
-			continue
-		}
-
-		dd := i.MustReadDictData()
-		readStr := func(key string) string {
-			v, ok := dd.Dict.Load(key)
-			if !ok {
-				return ""
-			}
-			return v.ToString()
-		}
-		readInt := func(key string) ds.IntType {
-			v, ok := dd.Dict.Load(key)
-			if !ok {
-				return 0
-			}
-			ret, _ := v.ReadInt()
-			return ret
-		}
-
-		ret = append(ret, &RIListItem{
-			name:   readStr("name"),
-			val:    int64(readInt("val")),
-			uid:    readStr("uid"),
-			detail: readStr("detail"),
-		})
-	}
-
-	return ret
-}
-
-// SaveToGroup 写入群信息中
-func (lst RIList) SaveToGroup(ctx *MsgContext) {
-	am := ctx.Dice.AttrsManager
-	attrs, _ := am.LoadById(ctx.Group.GroupID)
-	riList := ds.NewArrayVal()
-
-	ad := riList.MustReadArray()
-	for _, i := range lst {
-		v := ds.NewDictValWithArrayMust(
-			ds.NewStrVal("name"), ds.NewStrVal(i.name),
-			ds.NewStrVal("val"), ds.NewIntVal(ds.IntType(i.val)),
-			ds.NewStrVal("uid"), ds.NewStrVal(i.uid),
-			ds.NewStrVal("detail"), ds.NewStrVal(i.detail),
-		)
-		ad.List = append(ad.List, v.V())
-	}
-
-	dndRiLock.Lock()
-	attrs.Store("riList", riList)
-	dndRiLock.Unlock()
-}
-
-func (lst RIList) GetExists(name string) *RIListItem {
-	for _, i := range lst {
-		if i.name == name {
-			return i
-		}
-	}
-	return nil
-}
-
-func setInitNextRoundVars(ctx *MsgContext, lst RIList, round int64) {
-	l := len(lst)
-	if round == 0 {
-		VarSetValueStr(ctx, "$t新轮开始提示", DiceFormatTmpl(ctx, "DND:先攻_新轮开始提示"))
-		VarSetValueStr(ctx, "$t当前回合角色名", lst[l-1].name)
-		VarSetValueStr(ctx, "$t当前回合at", AtBuild(lst[l-1].uid))
-	} else {
-		VarSetValueStr(ctx, "$t新轮开始提示", "")
-		VarSetValueStr(ctx, "$t当前回合角色名", lst[round-1].name)
-		VarSetValueStr(ctx, "$t当前回合at", AtBuild(lst[round-1].uid))
-	}
-	VarSetValueInt64(ctx, "$g当前回合先攻值", lst[round].val)
-	VarSetValueStr(ctx, "$t下一回合角色名", lst[round].name)
-	VarSetValueStr(ctx, "$t下一回合at", AtBuild(lst[round].uid))
-
-	nextRound := round + 1
-	if l <= int(nextRound) || nextRound < 0 {
-		nextRound = 0
-	}
-	VarSetValueStr(ctx, "$t下下一回合角色名", lst[nextRound].name)
-	VarSetValueStr(ctx, "$t下下一回合at", AtBuild(lst[nextRound].uid))
-	VarSetValueInt64(ctx, "$g回合数", round)
-}
@@ -453,7 +453,16 @@ func RegisterBuiltinExtFun(self *Dice) {
 					}
 
 					text += cmdArgs.CleanArgs
-					if strings.Contains(uid, "Group") {
+					isGroup := strings.Contains(uid, "Group")
+					platform := strings.Split(strings.SplitN(uid, ":", 2)[0], "-")[0]
+					if platform != ctx.EndPoint.Platform {
+						// 骰主帐号不在当前平台，当前适配器发不过去，改由对应平台的帐号转达
+						if !CrossMsgBySearch(ctx.Session, platform, uid, text, !isGroup) {
+							ctx.Dice.Logger.Warnf("留言未能送达骰主 %s：没有可用的 %s 平台帐号", uid, platform)
+						}
+						continue
+					}
+					if isGroup {
 						ctx.EndPoint.Adapter.SendToGroup(ctx, uid, text, "")
 					} else {
 						ctx.EndPoint.Adapter.SendToPerson(ctx, uid, text, "")
@@ -1038,6 +1047,80 @@ func RegisterBuiltinExtFun(self *Dice) {
 		},
 	}
 
+	helpV5 := `.v5 <骰池> [h<饥渴骰>] [dc<难度>] [<原因>] // .v5 力量+运动 h2 dc3 撬锁
+.v5 7 // 骰7颗d10，使用vtm5模板时饥渴骰默认为角色的饥渴值`
+	cmdV5 := CmdItemInfo{
+		Name:          "v5",
+		ShortHelp:     helpV5,
+		Help:          "骰池(吸血鬼：避世血族 第五版):\n" + helpV5,
+		AllowDelegate: true,
+		Solve: func(ctx *MsgContext, msg *Message, cmdArgs *CmdArgs) CmdExecuteResult {
+			return dicePoolSolve(ctx, msg, cmdArgs, DicePoolV5, true, func(mctx *MsgContext, args *dicePoolArgs) (*DicePoolResult, error) {
+				if !args.HasHunger && mctx.SystemTemplate != nil && mctx.SystemTemplate.Name == "vtm5" {
+					if v, ok := mctx.Eval("饥渴", nil).ReadInt(); ok {
+						args.Hunger = int64(v)
+					}
+				}
+				VarSetValueInt64(mctx, "$t饥渴", args.Hunger)
+				return DicePoolRollV5(mctx.vm.RandSrc, args.Pool, args.Hunger, args.DC)
+			})
+		},
+	}
+
+	helpSR := `.sr <骰池> [l<上限>] [t<阈值>] [<原因>] // .sr 敏捷+枪械 t2 射击
+.sr 12 l5 // 命中数不超过上限5(第五版)`
+	cmdSR := CmdItemInfo{
+		Name:          "sr",
+		ShortHelp:     helpSR,
+		Help:          "骰池(暗影狂奔):\n" + helpSR,
+		AllowDelegate: true,
+		Solve: func(ctx *MsgContext, msg *Message, cmdArgs *CmdArgs) CmdExecuteResult {
+			return dicePoolSolve(ctx, msg, cmdArgs, DicePoolShadowrun, true, func(mctx *MsgContext, args *dicePoolArgs) (*DicePoolResult, error) {
+				return DicePoolRollShadowrun(mctx.vm.RandSrc, args.Pool, args.Limit, args.DC)
+			})
+		},
+	}
+
+	helpBitD := `.bitd <骰池> [<局势>] [<效果>] [<原因>] // .bitd 潜行+1 冒险 标准 翻进庄园
+.bitd 0 // 骰池为0时骰2颗取低
+// 局势: 受控/冒险/绝望，效果: 有限/标准/极大，仅用于展示`
+	cmdBitD := CmdItemInfo{
+		Name:          "bitd",
+		ShortHelp:     helpBitD,
+		Help:          "骰池(暗夜刀锋):\n" + helpBitD,
+		AllowDelegate: true,
+		Solve: func(ctx *MsgContext, msg *Message, cmdArgs *CmdArgs) CmdExecuteResult {
+			return dicePoolSolve(ctx, msg, cmdArgs, DicePoolBlades, true, func(mctx *MsgContext, args *dicePoolArgs) (*DicePoolResult, error) {
+				var parts []string
+				for _, i := range []string{args.Position, args.Effect} {
+					if i != "" {
+						parts = append(parts, i)
+					}
+				}
+				if len(parts) > 0 {
+					VarSetValueStr(mctx, "$t局势效果", "，"+strings.Join(parts, "/"))
+				} else {
+					VarSetValueStr(mctx, "$t局势效果", "")
+				}
+				return DicePoolRollBlades(mctx.vm.RandSrc, args.Pool)
+			})
+		},
+	}
+
+	helpPbtA := `.pbta <属性> [<原因>] // .pbta 冷静 顶住压力
+.pbta // 骰2d6，不加属性`
+	cmdPbtA := CmdItemInfo{
+		Name:          "pbta",
+		ShortHelp:     helpPbtA,
+		Help:          "骰点(基于启示录的游戏 PbtA):\n" + helpPbtA,
+		AllowDelegate: true,
+		Solve: func(ctx *MsgContext, msg *Message, cmdArgs *CmdArgs) CmdExecuteResult {
+			return dicePoolSolve(ctx, msg, cmdArgs, DicePoolPbtA, false, func(mctx *MsgContext, args *dicePoolArgs) (*DicePoolResult, error) {
+				return DicePoolRollPbtA(mctx.vm.RandSrc, args.Pool)
+			})
+		},
+	}
+
 	textHelp := ".text <文本模板> // 文本指令，例: .text 看看手气: {1d16}"
 	cmdText := CmdItemInfo{
 		Name:      "text",
@@ -1401,6 +1484,10 @@ func RegisterBuiltinExtFun(self *Dice) {
 			"dxh":     &cmdDX,
 			"wh":      &cmdWW,
 			"wwh":     &cmdWW,
+			"v5":      &cmdV5,
+			"sr":      &cmdSR,
+			"bitd":    &cmdBitD,
+			"pbta":    &cmdPbtA,
 			"jsr":     &cmdJsr,
 			"drl":     &cmdDrl,
 			"drlh":    &cmdDrl,
@@ -1,16 +1,21 @@
 package dice
 
 import (
+	"bytes"
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"regexp"
 	"runtime"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/golang-module/carbon"
@@ -22,6 +27,7 @@ import (
 	"sealdice-core/dice/service"
 	"sealdice-core/dice/storylog"
 	"sealdice-core/model"
+	"sealdice-core/utils/dboperator/engine"
 )
 
 var ErrGroupCardOverlong = errors.New("群名片长度超过限制")
@@ -135,7 +141,8 @@ func RegisterBuiltinExtLog(self *Dice) {
 .log list <群号> // 查看指定群的日志列表(无法取得日志时，找骰主做这个操作)
 .log masterget <群号> <日志名> // 重新上传日志，并获取链接(无法取得日志时，找骰主做这个操作)
 .log export <日志名> // 直接取得日志txt(服务出问题或有其他需要时使用)
-.log export <日志名> <邮箱地址> // 通过邮件取得日志txt，多个邮箱用空格隔开`
+.log export <日志名> <邮箱地址> // 通过邮件取得日志txt，多个邮箱用空格隔开
+.log export <日志名> --format html // 导出为离线文件，可选 txt/html/md/jsonl/docx`
 
 	// const txtLogTip = "若未出现线上日志地址，可换时间获取，或联系骰主在data/default/log-exports路径下取出日志\n文件名: 群号_日志名_随机数.zip\n注意此文件log end/get后才会生成"
 
@@ -204,6 +211,27 @@ func RegisterBuiltinExtLog(self *Dice) {
 						return
 					}
 				}
+				if ctx.Dice.Config.LogShareLocalPrefer {
+					link, expiresAt, notice, err := logShareLocal(ctx, gid, lname)
+					if err == nil {
+						VarSetValueStr(ctx, "$t日志链接", link)
+						VarSetValueStr(ctx, "$t链接有效期", expiresAt.Format("2006-01-02 15:04"))
+						tmpl := DiceFormatTmpl(ctx, "日志:记录_本地链接")
+						if notice != "" {
+							tmpl += "\n" + notice
+						}
+						ReplyToSenderRaw(ctx, msg, tmpl, "skip")
+						return
+					}
+					VarSetValueStr(ctx, "$t错误原因", err.Error())
+					tmpl := DiceFormatTmpl(ctx, "日志:记录_上传_失败")
+					if strings.Contains(err.Error(), "此log不存在") || strings.Contains(err.Error(), "名字是否正确") {
+						tmpl += logKeyHintText()
+					}
+					ReplyToSenderRaw(ctx, msg, tmpl, "skip")
+					return
+				}
+
 				unofficial, fn, notice, err := logSendToBackend(ctx, gid, lname, true)
 				if err != nil {
 					reason := strings.TrimPrefix(err.Error(), "#")
@@ -490,6 +518,12 @@ func RegisterBuiltinExtLog(self *Dice) {
 					return CmdExecuteResult{Matched: true, Solved: true}
 				}
 
+				format, err := logExportFormatFromArgs(cmdArgs)
+				if err != nil {
+					ReplyToSenderRaw(ctx, msg, err.Error(), "skip")
+					return CmdExecuteResult{Matched: true, Solved: true}
+				}
+
 				logName := group.LogCurName
 				if newName := cmdArgs.GetArgN(2); newName != "" {
 					logName = newName
@@ -509,7 +543,7 @@ func RegisterBuiltinExtLog(self *Dice) {
 				VarSetValueStr(ctx, "$t日期", now.ToShortDateString())
 				VarSetValueStr(ctx, "$t时间", now.ToShortTimeString())
 				logFileNamePrefix := DiceFormatTmpl(ctx, "日志:记录_导出_文件名前缀")
-				logFile, notice, err := GetLogTxt(ctx, group.GroupID, logName, logFileNamePrefix)
+				logFile, notice, err := GetLogExport(ctx, group.GroupID, logName, logFileNamePrefix, format)
 				if err != nil {
 					reply := err.Error()
 					if strings.Contains(reply, "此log不存在") || strings.Contains(reply, "名字是否正确") {
@@ -550,6 +584,12 @@ func RegisterBuiltinExtLog(self *Dice) {
 					ReplyToSenderRaw(ctx, msg, DiceFormat(ctx, "{核心:骰子名字}未配置邮箱，将直接发送记录文件"), "skip")
 				}
 
+				VarSetValueStr(ctx, "$t文件名字", logFileNamePrefix)
+				if !ctx.EndPoint.Capabilities().SendFile {
+					ReplyToSenderRaw(ctx, msg, DiceFormatTmpl(ctx, "日志:记录_导出_不支持发送文件"), "skip")
+					return CmdExecuteResult{Matched: true, Solved: true}
+				}
+
 				var uri string
 				if runtime.GOOS == "windows" {
 					uri = "files:///" + logFile
@@ -557,7 +597,6 @@ func RegisterBuiltinExtLog(self *Dice) {
 					uri = "files://" + logFile
 				}
 				SendFileToSenderRaw(ctx, msg, uri, "skip")
-				VarSetValueStr(ctx, "$t文件名字", logFileNamePrefix)
 				reply := DiceFormatTmpl(ctx, "日志:记录_导出_成功")
 				if notice != "" {
 					reply += "\n" + notice
@@ -572,6 +611,7 @@ func RegisterBuiltinExtLog(self *Dice) {
 
 	helpStat := `.stat log [<日志名>] // 查看当前或指定日志的骰点统计
 .stat log [<日志名>] --all // 查看全团
+` + helpStatRolls + `
 .stat help // 帮助
 `
 	cmdStat := &CmdItemInfo{
@@ -626,6 +666,8 @@ func RegisterBuiltinExtLog(self *Dice) {
 				if err != nil || len(items) == 0 {
 					ReplyToSender(ctx, msg, "没有发现可供统计的信息，请确保记录名正确，且有进行骰点/检定行为")
 				}
+			case "me", "group":
+				return cmdStatRolls(ctx, msg, cmdArgs)
 			default:
 				return CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
 			}
@@ -897,7 +939,7 @@ func RegisterBuiltinExtLog(self *Dice) {
 						Message:     msg.Message,
 						IsDice:      true,
 						CommandID:   ctx.CommandID,
-						CommandInfo: ctx.CommandInfo,
+						CommandInfo: logCommandInfo(ctx),
 						RawMsgID:    msg.RawID,
 					}
 
@@ -927,7 +969,7 @@ func RegisterBuiltinExtLog(self *Dice) {
 						Message:     msg.Message,
 						IsDice:      true,
 						CommandID:   ctx.CommandID,
-						CommandInfo: ctx.CommandInfo,
+						CommandInfo: logCommandInfo(ctx),
 						RawMsgID:    msg.RawID,
 					}
 					LogAppend(ctx, groupInfo.GroupID, groupInfo.LogCurName, &a)
@@ -1060,9 +1102,41 @@ func LogEditByID(ctx *MsgContext, groupID, logName, content string, messageID in
 	return true
 }
 
+// logExportFormatFromArgs 读取 --format=html 或 --format html，后一种写法会从参数列表中移除格式名
+func logExportFormatFromArgs(cmdArgs *CmdArgs) (storylog.ExportFormat, error) {
+	kw := cmdArgs.GetKwarg("format")
+	if kw == nil {
+		return storylog.ExportFormatTxt, nil
+	}
+	if kw.ValueExists {
+		return storylog.ParseExportFormat(kw.Value)
+	}
+
+	tokens := reSpace.Split(strings.TrimSpace(cmdArgs.RawArgs), -1)
+	for i := 0; i+1 < len(tokens); i++ {
+		if tokens[i] != "--format" {
+			continue
+		}
+		value := tokens[i+1]
+		for j := len(cmdArgs.Args) - 1; j > 0; j-- {
+			if cmdArgs.Args[j] == value {
+				cmdArgs.Args = append(cmdArgs.Args[:j], cmdArgs.Args[j+1:]...)
+				break
+			}
+		}
+		return storylog.ParseExportFormat(value)
+	}
+	return "", storylog.ErrUnknownExportFormat
+}
+
 func GetLogTxt(ctx *MsgContext, groupID string, logName string, fileNamePrefix string) (string, string, error) {
+	return GetLogExport(ctx, groupID, logName, fileNamePrefix, storylog.ExportFormatTxt)
+}
+
+// GetLogExport 将日志按指定格式导出到临时文件，返回文件路径，调用方负责删除
+func GetLogExport(ctx *MsgContext, groupID string, logName string, fileNamePrefix string, format storylog.ExportFormat) (string, string, error) {
 	// 创建临时文件
-	tempPattern, notice := storylog.BuildTempPattern(fileNamePrefix)
+	tempPattern, notice := storylog.BuildExportTempPattern(fileNamePrefix, format)
 	tempLog, err := os.CreateTemp("", tempPattern)
 	if err != nil {
 		return "", notice, errors.New("log导出出现未知错误")
@@ -1074,6 +1148,31 @@ func GetLogTxt(ctx *MsgContext, groupID string, logName string, fileNamePrefix s
 		}
 	}()
 
+	counter, err := writeLogExport(ctx.Dice.DBOperator, tempLog, groupID, logName, format)
+	if err != nil {
+		return "", notice, err
+	}
+	// 2. 确保文件指针回到开头
+	if _, err := tempLog.Seek(0, 0); err != nil {
+		return "", notice, fmt.Errorf("重置文件指针失败: %w", err)
+	}
+
+	// 如果没有任何数据，返回错误
+	if counter == 0 {
+		return "", notice, errors.New("此log不存在，或条目数为空，名字是否正确？")
+	}
+
+	return tempLog.Name(), notice, nil
+}
+
+// writeLogExport 以游标分页逐批读取日志并写出，限时10秒，返回写出的条数
+func writeLogExport(operator engine.DatabaseOperator, w io.Writer, groupID string, logName string, format storylog.ExportFormat) (int, error) {
+	exporter, err := storylog.NewExporter(w, format, logName)
+	if err != nil {
+		return 0, fmt.Errorf("创建日志导出器失败: %w", err)
+	}
+	syncer, _ := w.(interface{ Sync() error })
+
 	counter := 0
 	currentCursor := paginator.Cursor{} // 初始游标为空
 
@@ -1093,31 +1192,31 @@ func GetLogTxt(ctx *MsgContext, groupID string, logName string, fileNamePrefix s
 				return
 			default:
 				// 获取当前游标对应的数据
-				cursorLines, cursor, err := service.LogGetCursorLines(ctx.Dice.DBOperator, groupID, logName, currentCursor)
+				cursorLines, cursor, err := service.LogGetCursorLines(operator, groupID, logName, currentCursor)
 				if err != nil {
 					resultCh <- err
 					return
 				}
 
 				// 写入当前批次的数据
-				for _, line := range cursorLines {
-					timeTxt := time.Unix(line.Time, 0).Format("2006-01-02 15:04:05")
-					text := fmt.Sprintf("%s(%v) %s\n%s\n\n", line.Nickname, line.IMUserID, timeTxt, line.Message)
-					if _, err = tempLog.WriteString(text); err != nil {
-						resultCh <- fmt.Errorf("写入日志导出临时文件失败: %w", err)
+				for i := range cursorLines {
+					if err = exporter.WriteItem(&cursorLines[i]); err != nil {
+						resultCh <- fmt.Errorf("写入日志导出内容失败: %w", err)
 						return
 					}
 					counter++
 				}
 				// ========== 新增：每批写入后强制同步 ==========
-				if err := tempLog.Sync(); err != nil { // 确保批次数据落盘
-					resultCh <- fmt.Errorf("批次同步失败: %w", err)
-					return
+				if syncer != nil {
+					if err := syncer.Sync(); err != nil { // 确保批次数据落盘
+						resultCh <- fmt.Errorf("批次同步失败: %w", err)
+						return
+					}
 				}
 
 				// 如果没有下一页，则成功完成
 				if cursor.After == nil {
-					resultCh <- nil
+					resultCh <- exporter.Close()
 					return
 				}
 
@@ -1128,20 +1227,8 @@ func GetLogTxt(ctx *MsgContext, groupID string, logName string, fileNamePrefix s
 	}()
 
 	// 等待 goroutine 完成或超时
-	if err := <-resultCh; err != nil {
-		return "", notice, err
-	}
-	// 2. 确保文件指针回到开头
-	if _, err := tempLog.Seek(0, 0); err != nil {
-		return "", notice, fmt.Errorf("重置文件指针失败: %w", err)
-	}
-
-	// 如果没有任何数据，返回错误
-	if counter == 0 {
-		return "", notice, errors.New("此log不存在，或条目数为空，名字是否正确？")
-	}
-
-	return tempLog.Name(), notice, nil
+	err = <-resultCh
+	return counter, err
 }
 
 func LogSendToBackend(ctx *MsgContext, groupID string, logName string) (bool, string, string, error) {
@@ -1208,6 +1295,80 @@ func logSendToBackend(ctx *MsgContext, groupID string, logName string, skipResol
 	return unofficial, url, notice, nil
 }
 
+// logShareLocal 不上传日志，只做本地备份并生成本地查看链接
+func logShareLocal(ctx *MsgContext, groupID string, logName string) (string, time.Time, string, error) {
+	dice := ctx.Dice
+	notice, err := storylog.Backup(storylog.UploadEnv{
+		Dir:     filepath.Join(dice.BaseConfig.DataDir, "log-exports"),
+		Db:      dice.DBOperator,
+		Log:     dice.Logger,
+		LogName: logName,
+		GroupID: groupID,
+	})
+	if err != nil {
+		return "", time.Time{}, notice, err
+	}
+	link, expiresAt, err := LogShareLocalURL(dice, groupID, logName)
+	return link, expiresAt, notice, err
+}
+
+var logShareSecretMu sync.Mutex
+
+// logShareSecret 取得本地查看链接的签名密钥，没有则生成并保存到配置中
+func logShareSecret(d *Dice) []byte {
+	logShareSecretMu.Lock()
+	defer logShareSecretMu.Unlock()
+	if d.Config.LogShareSecret == "" {
+		buf := make([]byte, 32)
+		_, _ = rand.Read(buf)
+		d.Config.LogShareSecret = hex.EncodeToString(buf)
+		d.MarkModified()
+	}
+	return []byte(d.Config.LogShareSecret)
+}
+
+// LogShareLocalURL 生成由内置 web 服务提供的只读日志查看链接，返回链接与过期时间
+func LogShareLocalURL(d *Dice, groupID string, logName string) (string, time.Time, error) {
+	base := strings.TrimRight(d.Config.LogShareBaseURL, "/")
+	if base == "" {
+		return "", time.Time{}, errors.New("骰主未配置日志本地查看地址，无法生成本地链接")
+	}
+	hours := d.Config.LogShareExpireHours
+	if hours <= 0 {
+		hours = DefaultConfig.LogShareExpireHours
+	}
+	expiresAt := time.Now().Add(time.Duration(hours) * time.Hour)
+	token, err := storylog.SignShareToken(logShareSecret(d), storylog.ShareClaims{
+		GroupID:   groupID,
+		LogName:   logName,
+		ExpiresAt: expiresAt.Unix(),
+	})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return base + "/sd-api/story/share/" + token, expiresAt, nil
+}
+
+// LogRenderSharePage 校验本地查看链接并直接从 log_items 渲染 HTML 页面
+func LogRenderSharePage(d *Dice, token string) ([]byte, error) {
+	if d.Config.LogShareSecret == "" {
+		return nil, storylog.ErrShareTokenInvalid
+	}
+	claims, err := storylog.VerifyShareToken([]byte(d.Config.LogShareSecret), token, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	counter, err := writeLogExport(d.DBOperator, &buf, claims.GroupID, claims.LogName, storylog.ExportFormatHTML)
+	if err != nil {
+		return nil, err
+	}
+	if counter == 0 {
+		return nil, service.ErrLogNotFound
+	}
+	return buf.Bytes(), nil
+}
+
 // LogRollBriefByPCV2 根据log生成骰点简报 采用gjson进行解析 拒绝一次性加载所有数据库数据
 func LogRollBriefByPCV2(ctx *MsgContext, items []string, showAll bool, name string) string {
 	pcInfo := map[string]map[string]int{}
@@ -1215,13 +1376,7 @@ func LogRollBriefByPCV2(ctx *MsgContext, items []string, showAll bool, name stri
 	tmpl := ctx.Group.GetCharTemplate(ctx.Dice)
 
 	getName := func(s string) string {
-		re := regexp.MustCompile(`^([^\d\s]+)(\d+)?$`)
-		m := re.FindStringSubmatch(s)
-		if len(m) > 0 {
-			s = m[1]
-		}
-
-		return tmpl.GetAlias(s)
+		return rollStatSkillName(tmpl, s)
 	}
 
 	for _, i := range items {
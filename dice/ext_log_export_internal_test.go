@@ -0,0 +1,105 @@
+//nolint:testpackage
+package dice
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"sealdice-core/dice/storylog"
+)
+
+func TestLogExportFormatFromArgs(t *testing.T) {
+	cases := []struct {
+		raw      string
+		want     storylog.ExportFormat
+		wantArgs []string
+	}{
+		{"export mylog", storylog.ExportFormatTxt, []string{"export", "mylog"}},
+		{"export mylog --format=html", storylog.ExportFormatHTML, []string{"export", "mylog"}},
+		{"export mylog --format docx", storylog.ExportFormatDocx, []string{"export", "mylog"}},
+		{"export --format md", storylog.ExportFormatMarkdown, []string{"export"}},
+	}
+	for _, c := range cases {
+		cmdArgs := ArgsParse(c.raw)
+		cmdArgs.RawArgs = c.raw
+		got, err := logExportFormatFromArgs(cmdArgs)
+		if err != nil || got != c.want {
+			t.Fatalf("%q: got %q, %v; want %q", c.raw, got, err, c.want)
+		}
+		if strings.Join(cmdArgs.Args, " ") != strings.Join(c.wantArgs, " ") {
+			t.Fatalf("%q: args = %v, want %v", c.raw, cmdArgs.Args, c.wantArgs)
+		}
+	}
+
+	cmdArgs := ArgsParse("export mylog --format")
+	cmdArgs.RawArgs = "export mylog --format"
+	if _, err := logExportFormatFromArgs(cmdArgs); err == nil {
+		t.Fatal("expected error for missing format value")
+	}
+}
+
+func TestGetLogExportWritesHTML(t *testing.T) {
+	mockDB := newLogAliasTestDB(t)
+	groupID := "QQ-Group:1001"
+	appendTestLog(t, mockDB, groupID, "export-log")
+
+	ctx := &MsgContext{Dice: &Dice{DBOperator: mockDB}}
+	path, _, err := GetLogExport(ctx, groupID, "export-log", "export-log", storylog.ExportFormatHTML)
+	if err != nil {
+		t.Fatalf("GetLogExport: %v", err)
+	}
+	defer os.Remove(path)
+
+	if !strings.HasSuffix(path, ".html") {
+		t.Fatalf("path %q should end with .html", path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if !strings.Contains(string(data), "<!DOCTYPE html>") || !strings.Contains(string(data), "tester") {
+		t.Fatalf("unexpected export: %s", data)
+	}
+}
+
+func TestLogShareLocalURLRendersPage(t *testing.T) {
+	mockDB := newLogAliasTestDB(t)
+	groupID := "QQ-Group:1001"
+	appendTestLog(t, mockDB, groupID, "share-log")
+
+	d := &Dice{DBOperator: mockDB}
+	if _, _, err := LogShareLocalURL(d, groupID, "share-log"); err == nil {
+		t.Fatal("expected error without base url")
+	}
+
+	d.Config.LogShareBaseURL = "http://example.com:3211/"
+	link, expiresAt, err := LogShareLocalURL(d, groupID, "share-log")
+	if err != nil {
+		t.Fatalf("LogShareLocalURL: %v", err)
+	}
+	if d.Config.LogShareSecret == "" {
+		t.Fatal("secret should be generated on first use")
+	}
+	if time.Until(expiresAt) < 71*time.Hour {
+		t.Fatalf("expiresAt %v should default to 72 hours", expiresAt)
+	}
+	const prefix = "http://example.com:3211/sd-api/story/share/"
+	if !strings.HasPrefix(link, prefix) {
+		t.Fatalf("link = %q", link)
+	}
+
+	page, err := LogRenderSharePage(d, strings.TrimPrefix(link, prefix))
+	if err != nil {
+		t.Fatalf("LogRenderSharePage: %v", err)
+	}
+	if !strings.Contains(string(page), "share-log") || !strings.Contains(string(page), "tester") {
+		t.Fatalf("unexpected page: %s", page)
+	}
+
+	if _, err = LogRenderSharePage(d, "bad.token"); !errors.Is(err, storylog.ErrShareTokenInvalid) {
+		t.Fatalf("bad token: err = %v", err)
+	}
+}
@@ -179,7 +179,6 @@ func RegisterBuiltinExtReply(dice *Dice) {
 			if !ctx.Dice.Config.CustomReplyConfigEnable {
 				return
 			}
-			executed := false
 			log := ctx.Dice.Logger
 
 			cleanText, _ := AtParse(msg.Message, "")
@@ -193,87 +192,47 @@ func RegisterBuiltinExtReply(dice *Dice) {
 			// 在判定条件前，先设置一轮变量，以免条件中的变量出问题
 			SetTempVars(ctx, msg.Sender.Nickname)
 
+			enabled := make([]*ReplyConfig, 0, len(rcs))
 			for _, rc := range rcs {
-				if executed {
-					break
+				if rc.Enable {
+					enabled = append(enabled, rc)
 				}
-				if !rc.Enable {
-					continue
-				}
-				condIndex := -1
-				defer func() {
-					if r := recover(); r != nil {
-						log.Errorf("异常: %v 堆栈: %v", r, string(debug.Stack()))
-						if condIndex != -1 {
-							ReplyToSender(ctx, msg, fmt.Sprintf(
-								"自定义回复匹配成功(序号%d)，但回复内容触发异常，请联系骰主修改:\n%s",
-								condIndex, DiceFormatTmpl(ctx, "核心:骰子执行异常")))
-						}
-					}
-				}()
-
-				{
-					commonCondOK := true
-					for _, cond := range rc.Conditions {
-						if !cond.Check(ctx, msg, nil, cleanText) {
-							commonCondOK = false
-							break
-						}
-					}
-					if !commonCondOK {
-						continue
-					}
-				}
-
-				checkInCoolDown := func() bool {
-					lastTime := ctx.Group.LastCustomReplyTime
-					now := float64(time.Now().UnixMilli()) / 1000
-					interval := rc.Interval
-					if interval < 2 {
-						interval = 2
-					}
-
-					if now-lastTime < interval {
-						return true // 未达到冷却，退出
+			}
+			condIndex := -1
+			defer func() {
+				if r := recover(); r != nil {
+					log.Errorf("异常: %v 堆栈: %v", r, string(debug.Stack()))
+					if condIndex != -1 {
+						ReplyToSender(ctx, msg, fmt.Sprintf(
+							"自定义回复匹配成功(序号%d)，但回复内容触发异常，请联系骰主修改:\n%s",
+							condIndex, DiceFormatTmpl(ctx, "核心:骰子执行异常")))
 					}
-					ctx.Group.LastCustomReplyTime = now
-					return false
 				}
+			}()
 
-				for index, i := range rc.Items {
-					if !i.Enable {
-						continue
-					}
-
-					checkTrue := true
-					for _, i := range i.Conditions {
-						if !i.Check(ctx, msg, nil, cleanText) {
-							checkTrue = false
-							break
-						}
-					}
-
-					condIndex = index
-					if len(i.Conditions) == 0 || !checkTrue {
-						continue
-					}
-
-					if checkInCoolDown() {
-						// 仍在冷却，拒绝回复
-						log.Infof("自定义回复[%s]: 条件满足，但正处于冷却", rc.Filename)
-						return
-					}
-					log.Infof("自定义回复[%s]: 条件满足", rc.Filename)
+			rc, index := replyMatch(ctx, msg, enabled, cleanText, nil)
+			if rc == nil {
+				return
+			}
+			condIndex = index
 
-					SetTempVars(ctx, msg.Sender.Nickname)
-					VarSetValueStr(ctx, "$tMsgID", fmt.Sprintf("%v", msg.RawID))
-					for _, j := range i.Results {
-						j.Execute(ctx, msg, nil)
-					}
-					executed = true
-					break
-				}
+			lastTime := ctx.Group.LastCustomReplyTime
+			now := float64(time.Now().UnixMilli()) / 1000
+			interval := rc.Interval
+			if interval < 2 {
+				interval = 2
+			}
+			if now-lastTime < interval {
+				// 仍在冷却，拒绝回复
+				log.Infof("自定义回复[%s]: 条件满足，但正处于冷却", rc.Filename)
+				return
 			}
+			ctx.Group.LastCustomReplyTime = now
+			log.Infof("自定义回复[%s]: 条件满足", rc.Filename)
+
+			SetTempVars(ctx, msg.Sender.Nickname)
+			VarSetValueStr(ctx, "$tMsgID", fmt.Sprintf("%v", msg.RawID))
+			rc.ExecuteItem(ctx, msg, cleanText, rc.Items[index])
 		},
 		GetDescText: GetExtensionDesc,
 		CmdMap:      CmdMapCls{},
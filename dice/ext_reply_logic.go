@@ -3,9 +3,11 @@ package dice
 import (
 	"encoding/json"
 	"fmt"
+	"math/rand/v2"
 	"os"
 	"reflect"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -190,6 +192,192 @@ func (m *ReplyConditionExprTrue) Check(ctx *MsgContext, _ *Message, _ *CmdArgs,
 	return r.AsBool()
 }
 
+// ReplyConditionPrivilege 发送者权限 // privilege
+type ReplyConditionPrivilege struct {
+	CondType string `json:"condType" yaml:"condType"`
+	MatchOp  string `json:"matchOp"  yaml:"matchOp"` // ge或le
+	Value    int    `json:"value"    yaml:"value"`   // 权限等级，如 40 邀请者、50 群管理、60 群主、100 骰主
+}
+
+func (m *ReplyConditionPrivilege) Clean() {
+	if m.MatchOp != "ge" && m.MatchOp != "le" {
+		m.MatchOp = "ge"
+	}
+}
+
+func (m *ReplyConditionPrivilege) Check(ctx *MsgContext, _ *Message, _ *CmdArgs, _ string) bool {
+	if m.MatchOp == "le" {
+		return ctx.PrivilegeLevel <= m.Value
+	}
+	return ctx.PrivilegeLevel >= m.Value
+}
+
+// replyMatchList 列表命中判定，exclude 为真时取反
+func replyMatchList(values []string, target string, exclude bool) bool {
+	in := false
+	for _, v := range values {
+		if strings.EqualFold(v, target) {
+			in = true
+			break
+		}
+	}
+	return in != exclude
+}
+
+func replyCleanList(values []string) []string {
+	ret := values[:0]
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			ret = append(ret, v)
+		}
+	}
+	return ret
+}
+
+// ReplyConditionGroupID 群号 // groupId
+type ReplyConditionGroupID struct {
+	CondType string   `json:"condType" yaml:"condType"`
+	Exclude  bool     `json:"exclude"  yaml:"exclude"` // 为真时列表中的群不触发
+	Value    []string `json:"value"    yaml:"value"`   // 如 QQ-Group:12345，私聊没有群号
+}
+
+func (m *ReplyConditionGroupID) Clean() {
+	m.Value = replyCleanList(m.Value)
+}
+
+func (m *ReplyConditionGroupID) Check(_ *MsgContext, msg *Message, _ *CmdArgs, _ string) bool {
+	return replyMatchList(m.Value, msg.GroupID, m.Exclude)
+}
+
+// ReplyConditionPlatform 平台 // platform
+type ReplyConditionPlatform struct {
+	CondType string   `json:"condType" yaml:"condType"`
+	Exclude  bool     `json:"exclude"  yaml:"exclude"` // 为真时列表中的平台不触发
+	Value    []string `json:"value"    yaml:"value"`   // 如 QQ、KOOK、DISCORD
+}
+
+func (m *ReplyConditionPlatform) Clean() {
+	m.Value = replyCleanList(m.Value)
+}
+
+func (m *ReplyConditionPlatform) Check(_ *MsgContext, msg *Message, _ *CmdArgs, _ string) bool {
+	return replyMatchList(m.Value, msg.Platform, m.Exclude)
+}
+
+// 这个是用于测试时间段条件的测试桩
+var replyNowFn = time.Now
+
+// ReplyConditionTimeWindow 时间段 // timeWindow
+type ReplyConditionTimeWindow struct {
+	CondType string `json:"condType" yaml:"condType"`
+	Start    string `json:"start"    yaml:"start"`    // 如 08:00
+	End      string `json:"end"      yaml:"end"`      // 如 23:30，早于开始时间时表示跨过午夜，与开始时间相同表示全天
+	Weekdays []int  `json:"weekdays" yaml:"weekdays"` // 0 为周日，留空为每天
+}
+
+func (m *ReplyConditionTimeWindow) Clean() {
+	m.Start = strings.TrimSpace(m.Start)
+	m.End = strings.TrimSpace(m.End)
+}
+
+// parseClockMinutes 将 HH:MM 转为当天的分钟数
+func parseClockMinutes(s string) (int, bool) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, false
+	}
+	return t.Hour()*60 + t.Minute(), true
+}
+
+func (m *ReplyConditionTimeWindow) Check(ctx *MsgContext, _ *Message, _ *CmdArgs, _ string) bool {
+	start, ok1 := parseClockMinutes(m.Start)
+	end, ok2 := parseClockMinutes(m.End)
+	if !ok1 || !ok2 {
+		ctx.Dice.Logger.Warnf("自定义回复时间段格式错误: start=%q end=%q", m.Start, m.End)
+		return false
+	}
+
+	now := replyNowFn()
+	cur := now.Hour()*60 + now.Minute()
+	weekday := int(now.Weekday())
+	if start > end && cur < end {
+		// 跨午夜的后半段属于前一天开始的时间段
+		weekday = (weekday + 6) % 7
+	}
+	if len(m.Weekdays) > 0 {
+		found := false
+		for _, w := range m.Weekdays {
+			if w == weekday {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	switch {
+	case start == end:
+		return true
+	case start < end:
+		return cur >= start && cur < end
+	default:
+		return cur >= start || cur < end
+	}
+}
+
+// ReplyConditionUserCooldown 每人冷却 // userCooldown
+// 回复项触发后记录触发者，在间隔内同一用户不再满足此条件
+type ReplyConditionUserCooldown struct {
+	CondType string  `json:"condType" yaml:"condType"`
+	Value    float64 `json:"value"    yaml:"value"` // 间隔秒数
+
+	lastTime SyncMap[string, int64]
+}
+
+func (m *ReplyConditionUserCooldown) Clean() {
+	if m.Value < 0 {
+		m.Value = 0
+	}
+}
+
+func (m *ReplyConditionUserCooldown) Check(_ *MsgContext, msg *Message, _ *CmdArgs, _ string) bool {
+	last, ok := m.lastTime.Load(msg.Sender.UserID)
+	if !ok {
+		return true
+	}
+	return float64(replyNowFn().UnixMilli()-last) >= m.Value*1000
+}
+
+// Fired 回复项触发后调用
+func (m *ReplyConditionUserCooldown) Fired(_ *MsgContext, msg *Message) {
+	now := replyNowFn().UnixMilli()
+	m.lastTime.Store(msg.Sender.UserID, now)
+
+	// 顺便清理已过期的记录，避免无限增长
+	m.lastTime.Range(func(key string, value int64) bool {
+		if float64(now-value) >= m.Value*1000 {
+			m.lastTime.Delete(key)
+		}
+		return true
+	})
+}
+
+// ReplyConditionProbability 概率 // probability
+type ReplyConditionProbability struct {
+	CondType string  `json:"condType" yaml:"condType"`
+	Value    float64 `json:"value"    yaml:"value"` // 触发概率，百分比
+}
+
+func (m *ReplyConditionProbability) Clean() {
+	m.Value = max(0, min(100, m.Value))
+}
+
+func (m *ReplyConditionProbability) Check(_ *MsgContext, _ *Message, _ *CmdArgs, _ string) bool {
+	return rand.Float64()*100 < m.Value
+}
+
 // ReplyResultReplyToSender replyToSender
 type ReplyResultReplyToSender struct {
 	ResultType string               `json:"resultType" yaml:"resultType"`
@@ -277,6 +465,10 @@ type ReplyResultRunText struct {
 	Message    string  `json:"message"    yaml:"message"`
 }
 
+func (m *ReplyResultRunText) Clean() {
+	m.Message = strings.TrimSpace(m.Message)
+}
+
 func (m *ReplyResultRunText) Execute(ctx *MsgContext, _ *Message, _ *CmdArgs) {
 	time.Sleep(time.Duration(m.Delay * float64(time.Second)))
 	flags := RollExtraFlags{
@@ -286,6 +478,229 @@ func (m *ReplyResultRunText) Execute(ctx *MsgContext, _ *Message, _ *CmdArgs) {
 	_, _, _ = DiceExprTextBase(ctx, m.Message, flags)
 }
 
+// ReplyResultDrawDeck 抽取牌堆并回复 drawDeck
+type ReplyResultDrawDeck struct {
+	ResultType string  `json:"resultType" yaml:"resultType"`
+	Delay      float64 `json:"delay"      yaml:"delay"`
+	Deck       string  `json:"deck"       yaml:"deck"` // 牌组名，同 .draw
+}
+
+func (m *ReplyResultDrawDeck) Clean() {
+	m.Deck = strings.TrimSpace(m.Deck)
+}
+
+func (m *ReplyResultDrawDeck) Execute(ctx *MsgContext, msg *Message, _ *CmdArgs) {
+	time.Sleep(time.Duration(m.Delay * float64(time.Second)))
+	exists, text, err := deckDraw(ctx, m.Deck, true)
+	if !exists {
+		ctx.Dice.Logger.Warnf("自定义回复抽取牌堆失败，找不到牌组: %s", m.Deck)
+		return
+	}
+	if err != nil {
+		ctx.Dice.Logger.Warnf("自定义回复抽取牌堆失败: deck=%s err=%v", m.Deck, err)
+		return
+	}
+	ReplyToSender(ctx, msg, text)
+}
+
+// ReplyResultSetVar 设置变量 setVar
+type ReplyResultSetVar struct {
+	ResultType string  `json:"resultType" yaml:"resultType"`
+	Delay      float64 `json:"delay"      yaml:"delay"`
+	Name       string  `json:"name"       yaml:"name"`  // 变量名，如 $m计数、$g开关
+	Value      string  `json:"value"      yaml:"value"` // 表达式，求值结果写入变量
+}
+
+func (m *ReplyResultSetVar) Clean() {
+	m.Name = strings.TrimSpace(m.Name)
+	m.Value = strings.TrimSpace(m.Value)
+}
+
+func (m *ReplyResultSetVar) Execute(ctx *MsgContext, _ *Message, _ *CmdArgs) {
+	time.Sleep(time.Duration(m.Delay * float64(time.Second)))
+	m.apply(ctx)
+}
+
+func (m *ReplyResultSetVar) apply(ctx *MsgContext) {
+	if m.Name == "" {
+		return
+	}
+	flags := RollExtraFlags{
+		V2Only: true,
+		V1Only: ctx.Dice.getTargetVmEngineVersion(VMVersionReply) == "v1",
+	}
+	r, _, err := replyExprEvalFn(ctx, m.Value, flags)
+	if err != nil || r == nil || r.VMValue == nil {
+		ctx.Dice.Logger.Warnf("自定义回复设置变量失败: name=%q expr=%q err=%v", m.Name, m.Value, err)
+		return
+	}
+	VarSetValue(ctx, m.Name, r.VMValue)
+}
+
+// ReplyResultRecallMessage 撤回触发回复的消息 recallMessage
+type ReplyResultRecallMessage struct {
+	ResultType string  `json:"resultType" yaml:"resultType"`
+	Delay      float64 `json:"delay"      yaml:"delay"`
+}
+
+func (m *ReplyResultRecallMessage) Clean() {}
+
+func (m *ReplyResultRecallMessage) Execute(ctx *MsgContext, msg *Message, _ *CmdArgs) {
+	time.Sleep(time.Duration(m.Delay * float64(time.Second)))
+	if !ctx.EndPoint.Capabilities().RecallMessage {
+		ctx.Dice.Logger.Warnf("当前平台(%s)不支持撤回消息，已忽略", ctx.EndPoint.Platform)
+		return
+	}
+	if msg.RawID == nil {
+		return
+	}
+	ctx.EndPoint.Adapter.RecallMessage(ctx, fmt.Sprintf("%v", msg.RawID))
+}
+
+// ReplyResultMemberBan 禁言或踢出发送者，仅群聊有效，不会处置骰主、信任用户与群管理 memberBan
+type ReplyResultMemberBan struct {
+	ResultType string  `json:"resultType" yaml:"resultType"`
+	Delay      float64 `json:"delay"      yaml:"delay"`
+	Kick       bool    `json:"kick"       yaml:"kick"`     // 为真时踢出，否则禁言
+	Duration   int64   `json:"duration"   yaml:"duration"` // 禁言秒数
+}
+
+func (m *ReplyResultMemberBan) Clean() {
+	if m.Duration < 0 {
+		m.Duration = 0
+	}
+}
+
+func (m *ReplyResultMemberBan) Execute(ctx *MsgContext, msg *Message, _ *CmdArgs) {
+	time.Sleep(time.Duration(m.Delay * float64(time.Second)))
+	if msg.MessageType != "group" {
+		return
+	}
+	if ctx.PrivilegeLevel >= 50 || ctx.GroupRoleLevel >= 50 {
+		ctx.Dice.Logger.Infof("自定义回复: <%s>(%s)是骰主或管理，不执行禁言/踢出", msg.Sender.Nickname, msg.Sender.UserID)
+		return
+	}
+	if m.Kick {
+		MemberKick(ctx, msg.GroupID, msg.Sender.UserID)
+	} else {
+		MemberBan(ctx, msg.GroupID, msg.Sender.UserID, m.Duration)
+	}
+}
+
+// ReplyResultChain 接着执行同一文件中的另一个回复项 chain
+// 由 ReplyConfig.ExecuteItem 展开，自身的 Execute 只负责延迟
+type ReplyResultChain struct {
+	ResultType      string  `json:"resultType"      yaml:"resultType"`
+	Delay           float64 `json:"delay"           yaml:"delay"`
+	Index           int     `json:"index"           yaml:"index"`           // 回复项序号，从 0 开始
+	CheckConditions bool    `json:"checkConditions" yaml:"checkConditions"` // 为真时需满足目标回复项的条件才执行
+}
+
+func (m *ReplyResultChain) Clean() {}
+
+func (m *ReplyResultChain) Execute(_ *MsgContext, _ *Message, _ *CmdArgs) {
+	time.Sleep(time.Duration(m.Delay * float64(time.Second)))
+}
+
+// replyChainMaxDepth 回复项连锁的最大层数，防止互相连锁造成死循环
+const replyChainMaxDepth = 5
+
+// replyFiredCondition 回复项触发后需要记录状态的条件，如每人冷却
+type replyFiredCondition interface {
+	Fired(ctx *MsgContext, msg *Message)
+}
+
+// replyCheckConditions 按顺序判定全部条件，遇到不满足的即停止
+func replyCheckConditions(ctx *MsgContext, msg *Message, conds ReplyConditions, cleanText string) bool {
+	_, ok := replyCheckConditionsN(ctx, msg, conds, cleanText)
+	return ok
+}
+
+// replyCheckConditionsN 同 replyCheckConditions，同时返回实际判定了的条件数
+func replyCheckConditionsN(ctx *MsgContext, msg *Message, conds ReplyConditions, cleanText string) (int, bool) {
+	for n, c := range conds {
+		if !c.Check(ctx, msg, nil, cleanText) {
+			return n + 1, false
+		}
+	}
+	return len(conds), true
+}
+
+// replyMatchTrace 记录匹配过程的回调，供模拟使用。checked 为实际判定了的条件数
+type replyMatchTrace struct {
+	file func(rc *ReplyConfig, checked int, ok bool)
+	item func(index int, item *ReplyItem, checked int, ok bool)
+}
+
+// replyMatch 按顺序判定回复文件的条件与其中各回复项的条件，返回第一个满足的回复项，没有时 rc 为 nil。
+// 是否启用由调用方筛选；没有条件的回复项不会被触发。实际回复与模拟共用这一流程
+func replyMatch(ctx *MsgContext, msg *Message, rcs []*ReplyConfig, cleanText string, trace *replyMatchTrace) (rc *ReplyConfig, index int) {
+	for _, rc := range rcs {
+		checked, ok := replyCheckConditionsN(ctx, msg, rc.Conditions, cleanText)
+		if trace != nil {
+			trace.file(rc, checked, ok)
+		}
+		if !ok {
+			continue
+		}
+		for index, i := range rc.Items {
+			if !i.Enable {
+				continue
+			}
+			checked, ok = replyCheckConditionsN(ctx, msg, i.Conditions, cleanText)
+			ok = ok && len(i.Conditions) > 0
+			if trace != nil {
+				trace.item(index, i, checked, ok)
+			}
+			if ok {
+				return rc, index
+			}
+		}
+	}
+	return nil, -1
+}
+
+// ExecuteItem 执行回复项的全部动作，并展开其中的连锁
+func (c *ReplyConfig) ExecuteItem(ctx *MsgContext, msg *Message, cleanText string, item *ReplyItem) {
+	c.executeItem(ctx, msg, cleanText, item, 0)
+}
+
+func (c *ReplyConfig) executeItem(ctx *MsgContext, msg *Message, cleanText string, item *ReplyItem, depth int) {
+	for _, cond := range item.Conditions {
+		if f, ok := cond.(replyFiredCondition); ok {
+			f.Fired(ctx, msg)
+		}
+	}
+	for _, j := range item.Results {
+		j.Execute(ctx, msg, nil)
+		chain, ok := j.(*ReplyResultChain)
+		if !ok {
+			continue
+		}
+		next := c.chainTarget(ctx, chain, depth)
+		if next == nil {
+			continue
+		}
+		if chain.CheckConditions && !replyCheckConditions(ctx, msg, next.Conditions, cleanText) {
+			continue
+		}
+		c.executeItem(ctx, msg, cleanText, next, depth+1)
+	}
+}
+
+// chainTarget 连锁的目标回复项，不存在、未启用或超出层数时返回 nil
+func (c *ReplyConfig) chainTarget(ctx *MsgContext, chain *ReplyResultChain, depth int) *ReplyItem {
+	if depth >= replyChainMaxDepth {
+		ctx.Dice.Logger.Warnf("自定义回复[%s]: 连锁超过%d层，已停止", c.Filename, replyChainMaxDepth)
+		return nil
+	}
+	if chain.Index < 0 || chain.Index >= len(c.Items) || !c.Items[chain.Index].Enable {
+		ctx.Dice.Logger.Warnf("自定义回复[%s]: 连锁的回复项#%d不存在或未启用", c.Filename, chain.Index)
+		return nil
+	}
+	return c.Items[chain.Index]
+}
+
 type ReplyConditions []ReplyConditionBase
 
 var _ json.Unmarshaler = (*ReplyConditions)(nil)
@@ -359,14 +774,83 @@ func (c *ReplyConfig) Clean() {
 	}
 }
 
+// replyConditionTypes 条件类型 condType 与结构体的对应
+var replyConditionTypes = map[string]reflect.Type{
+	"textMatch":    reflect.TypeOf(ReplyConditionTextMatch{}),
+	"exprTrue":     reflect.TypeOf(ReplyConditionExprTrue{}),
+	"textLenLimit": reflect.TypeOf(ReplyConditionTextLenLimit{}),
+	"privilege":    reflect.TypeOf(ReplyConditionPrivilege{}),
+	"groupId":      reflect.TypeOf(ReplyConditionGroupID{}),
+	"platform":     reflect.TypeOf(ReplyConditionPlatform{}),
+	"timeWindow":   reflect.TypeOf(ReplyConditionTimeWindow{}),
+	"userCooldown": reflect.TypeOf(ReplyConditionUserCooldown{}),
+	"probability":  reflect.TypeOf(ReplyConditionProbability{}),
+}
+
+// replyResultTypes 动作类型 resultType 与结构体的对应
+var replyResultTypes = map[string]reflect.Type{
+	"replyPrivate":  reflect.TypeOf(ReplyResultReplyPrivate{}),
+	"replyGroup":    reflect.TypeOf(ReplyResultReplyGroup{}),
+	"replyToSender": reflect.TypeOf(ReplyResultReplyToSender{}),
+	"runText":       reflect.TypeOf(ReplyResultRunText{}),
+	"drawDeck":      reflect.TypeOf(ReplyResultDrawDeck{}),
+	"setVar":        reflect.TypeOf(ReplyResultSetVar{}),
+	"recallMessage": reflect.TypeOf(ReplyResultRecallMessage{}),
+	"memberBan":     reflect.TypeOf(ReplyResultMemberBan{}),
+	"chain":         reflect.TypeOf(ReplyResultChain{}),
+}
+
+// ReplySchemaField 条件或动作的一个字段，供编辑器生成表单
+type ReplySchemaField struct {
+	Key  string `json:"key"`
+	Kind string `json:"kind"` // string int float bool list object
+}
+
+// ReplySchemaType 一种条件或动作
+type ReplySchemaType struct {
+	Type   string             `json:"type"`
+	Fields []ReplySchemaField `json:"fields"`
+}
+
+func replySchemaTypes(types map[string]reflect.Type, typeKey string) []ReplySchemaType {
+	ret := make([]ReplySchemaType, 0, len(types))
+	for name, t := range types {
+		item := ReplySchemaType{Type: name, Fields: []ReplySchemaField{}}
+		for i := range t.NumField() {
+			f := t.Field(i)
+			key, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if !f.IsExported() || key == "" || key == typeKey {
+				continue
+			}
+			kind := "object"
+			switch f.Type.Kind() {
+			case reflect.String:
+				kind = "string"
+			case reflect.Int, reflect.Int64:
+				kind = "int"
+			case reflect.Float64:
+				kind = "float"
+			case reflect.Bool:
+				kind = "bool"
+			case reflect.Slice:
+				kind = "list"
+			}
+			item.Fields = append(item.Fields, ReplySchemaField{Key: key, Kind: kind})
+		}
+		ret = append(ret, item)
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Type < ret[j].Type })
+	return ret
+}
+
+// ReplySchema 当前支持的条件与动作类型及其字段
+func ReplySchema() (conditions []ReplySchemaType, results []ReplySchemaType) {
+	return replySchemaTypes(replyConditionTypes, "condType"), replySchemaTypes(replyResultTypes, "resultType")
+}
+
 func (cond *ReplyConditions) UnmarshalJSON(data []byte) error {
 	var err error
 	var cs = []any{}
-	typeMap := map[string]reflect.Type{
-		"textMatch":    reflect.TypeOf(ReplyConditionTextMatch{}),
-		"exprTrue":     reflect.TypeOf(ReplyConditionExprTrue{}),
-		"textLenLimit": reflect.TypeOf(ReplyConditionTextLenLimit{}),
-	}
 
 	if err = json.Unmarshal(data, &cs); err != nil {
 		return err
@@ -380,7 +864,7 @@ func (cond *ReplyConditions) UnmarshalJSON(data []byte) error {
 			continue
 		}
 		typeName, _ := condRawMap["condType"].(string)
-		theType := typeMap[typeName]
+		theType := replyConditionTypes[typeName]
 		if theType != nil {
 			val, err := tryUnmarshalYAML(condRaw, theType)
 			if err != nil {
@@ -397,11 +881,6 @@ func (cond *ReplyConditions) UnmarshalJSON(data []byte) error {
 func (cond *ReplyConditions) UnmarshalYAML(data *yaml.Node) error {
 	var err error
 	var cs = []any{}
-	typeMap := map[string]reflect.Type{
-		"textMatch":    reflect.TypeOf(ReplyConditionTextMatch{}),
-		"exprTrue":     reflect.TypeOf(ReplyConditionExprTrue{}),
-		"textLenLimit": reflect.TypeOf(ReplyConditionTextLenLimit{}),
-	}
 
 	// HACK: 用更加符合 yaml 库原生设计的方式重新实现
 	if err = data.Decode(&cs); err != nil {
@@ -416,7 +895,7 @@ func (cond *ReplyConditions) UnmarshalYAML(data *yaml.Node) error {
 			continue
 		}
 		typeName, _ := condRawMap["condType"].(string)
-		theType := typeMap[typeName]
+		theType := replyConditionTypes[typeName]
 		if theType != nil {
 			val, err := tryUnmarshalYAML(condRaw, theType)
 			if err != nil {
@@ -458,18 +937,11 @@ func (ri *ReplyItem) UnmarshalJSON(data []byte) error {
 			return err
 		}
 
-		typeMap := map[string]reflect.Type{
-			"replyPrivate":  reflect.TypeOf(ReplyResultReplyPrivate{}),
-			"replyGroup":    reflect.TypeOf(ReplyResultReplyGroup{}),
-			"replyToSender": reflect.TypeOf(ReplyResultReplyToSender{}),
-			"runText":       reflect.TypeOf(ReplyResultRunText{}),
-		}
-
 		for _, i := range rs {
 			m, ok := i.(map[string]interface{})
 			if ok && m["resultType"] != nil {
 				name, _ := m["resultType"].(string)
-				theType := typeMap[name]
+				theType := replyResultTypes[name]
 				if theType != nil {
 					val, err := tryUnmarshalJSON(i, theType)
 					if err != nil {
@@ -513,18 +985,11 @@ func (ri *ReplyItem) UnmarshalYAML(value *yaml.Node) error {
 			return err
 		}
 
-		typeMap := map[string]reflect.Type{
-			"replyPrivate":  reflect.TypeOf(ReplyResultReplyPrivate{}),
-			"replyGroup":    reflect.TypeOf(ReplyResultReplyGroup{}),
-			"replyToSender": reflect.TypeOf(ReplyResultReplyToSender{}),
-			"runText":       reflect.TypeOf(ReplyResultRunText{}),
-		}
-
 		for _, i := range rs {
 			m, ok := i.(map[string]interface{})
 			if ok && m["resultType"] != nil {
 				name, _ := m["resultType"].(string)
-				theType := typeMap[name]
+				theType := replyResultTypes[name]
 				if theType != nil {
 					val, err := tryUnmarshalYAML(i, theType)
 					if err != nil {
@@ -0,0 +1,236 @@
+//nolint:testpackage
+package dice
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const replyLogicTestFile = `
+enable: true
+items:
+  - enable: true
+    conditions:
+      - condType: privilege
+        matchOp: ge
+        value: 50
+      - condType: groupId
+        exclude: true
+        value: [" QQ-Group:1 ", ""]
+      - condType: platform
+        value: [qq]
+      - condType: timeWindow
+        start: "22:00"
+        end: "02:00"
+      - condType: userCooldown
+        value: 60
+      - condType: probability
+        value: 150
+    results:
+      - resultType: drawDeck
+        deck: 塔罗牌
+      - resultType: setVar
+        name: $m计数
+        value: $m计数 + 1
+      - resultType: recallMessage
+      - resultType: memberBan
+        duration: 600
+      - resultType: chain
+        index: 1
+        checkConditions: true
+  - enable: true
+    conditions:
+      - condType: exprTrue
+        value: "1"
+    results:
+      - resultType: replyToSender
+        message:
+          - ["第二项", 1]
+`
+
+func TestReplyItemUnmarshalNewTypes(t *testing.T) {
+	rc := &ReplyConfig{}
+	if err := yaml.Unmarshal([]byte(replyLogicTestFile), rc); err != nil {
+		t.Fatal(err)
+	}
+	rc.Clean()
+
+	conds := rc.Items[0].Conditions
+	if len(conds) != 6 {
+		t.Fatalf("expected 6 conditions, got %d", len(conds))
+	}
+	if g, ok := conds[1].(*ReplyConditionGroupID); !ok || !g.Exclude || len(g.Value) != 1 || g.Value[0] != "QQ-Group:1" {
+		t.Fatalf("unexpected groupId condition: %+v", conds[1])
+	}
+	if p, ok := conds[5].(*ReplyConditionProbability); !ok || p.Value != 100 {
+		t.Fatalf("probability should be clamped: %+v", conds[5])
+	}
+
+	results := rc.Items[0].Results
+	if len(results) != 5 {
+		t.Fatalf("expected 5 results, got %d", len(results))
+	}
+	if c, ok := results[4].(*ReplyResultChain); !ok || c.Index != 1 || !c.CheckConditions {
+		t.Fatalf("unexpected chain result: %+v", results[4])
+	}
+
+	// JSON 与 YAML 使用同一套类型表
+	rc2 := &ReplyConfig{}
+	buf, _ := yaml.Marshal(rc)
+	if err := yaml.Unmarshal(buf, rc2); err != nil || len(rc2.Items[0].Results) != 5 {
+		t.Fatalf("round trip failed: %v", err)
+	}
+}
+
+func TestReplyConditionTimeWindow(t *testing.T) {
+	defer func() { replyNowFn = time.Now }()
+	ctx := &MsgContext{}
+
+	cases := []struct {
+		start, end string
+		weekdays   []int
+		now        string
+		want       bool
+	}{
+		{"08:00", "12:00", nil, "2024-01-01 08:00", true},
+		{"08:00", "12:00", nil, "2024-01-01 12:00", false},
+		{"22:00", "02:00", nil, "2024-01-01 23:30", true},
+		{"22:00", "02:00", nil, "2024-01-01 01:59", true},
+		{"22:00", "02:00", nil, "2024-01-01 12:00", false},
+		{"00:00", "00:00", nil, "2024-01-01 12:00", true},
+		// 2024-01-01 为周一，跨午夜的后半段算作周日开始的时间段
+		{"22:00", "02:00", []int{0}, "2024-01-01 01:00", true},
+		{"22:00", "02:00", []int{1}, "2024-01-01 01:00", false},
+	}
+	for _, c := range cases {
+		now, _ := time.ParseInLocation("2006-01-02 15:04", c.now, time.Local)
+		replyNowFn = func() time.Time { return now }
+		cond := &ReplyConditionTimeWindow{Start: c.start, End: c.end, Weekdays: c.weekdays}
+		if got := cond.Check(ctx, nil, nil, ""); got != c.want {
+			t.Errorf("%s-%s %v at %s: got %v", c.start, c.end, c.weekdays, c.now, got)
+		}
+	}
+}
+
+func TestReplyConditionUserCooldown(t *testing.T) {
+	defer func() { replyNowFn = time.Now }()
+	now := time.Unix(1700000000, 0)
+	replyNowFn = func() time.Time { return now }
+
+	cond := &ReplyConditionUserCooldown{Value: 60}
+	a := &Message{Sender: SenderBase{UserID: "QQ:1"}}
+	b := &Message{Sender: SenderBase{UserID: "QQ:2"}}
+	if !cond.Check(nil, a, nil, "") {
+		t.Fatal("first trigger should pass")
+	}
+	cond.Fired(nil, a)
+	if cond.Check(nil, a, nil, "") || !cond.Check(nil, b, nil, "") {
+		t.Fatal("cooldown should only apply to the user who fired")
+	}
+	now = now.Add(time.Minute)
+	if !cond.Check(nil, a, nil, "") {
+		t.Fatal("cooldown should expire")
+	}
+}
+
+func TestReplyConfigExecuteItemChain(t *testing.T) {
+	d, ep, adapter, cleanup := newExecuteNewTestDice(t)
+	defer cleanup()
+
+	rc := &ReplyConfig{Filename: "chain.yaml"}
+	if err := yaml.Unmarshal([]byte(`
+items:
+  - enable: true
+    conditions:
+      - condType: textMatch
+        matchType: matchExact
+        value: 你好
+    results:
+      - resultType: chain
+        index: 1
+  - enable: true
+    conditions:
+      - condType: exprTrue
+        value: "0"
+    results:
+      - resultType: replyToSender
+        message:
+          - ["连锁", 1]
+      - resultType: chain
+        index: 1
+`), rc); err != nil {
+		t.Fatal(err)
+	}
+
+	msg := newGroupMsg("QQ-Group:200", "QQ:300", "你好")
+	ctx := CreateTempCtx(ep, msg)
+	ctx.Dice = d
+	rc.ExecuteItem(ctx, msg, "你好", rc.Items[0])
+
+	// 未要求判定条件时直接执行目标项，自我连锁在层数上限处停止
+	for i := 0; i < replyChainMaxDepth; i++ {
+		text, ok := adapter.waitForMsg(time.Second)
+		if !ok || text != "连锁" {
+			t.Fatalf("reply %d: got %q %v", i, text, ok)
+		}
+	}
+	if text, ok := adapter.waitForMsg(200 * time.Millisecond); ok {
+		t.Fatalf("chain should stop at max depth, got %q", text)
+	}
+}
+
+func TestReplySchema(t *testing.T) {
+	conditions, results := ReplySchema()
+	if len(conditions) != len(replyConditionTypes) || len(results) != len(replyResultTypes) {
+		t.Fatal("schema should list every registered type")
+	}
+	for _, r := range results {
+		if r.Type != "memberBan" {
+			continue
+		}
+		keys := []string{}
+		for _, f := range r.Fields {
+			keys = append(keys, f.Key+":"+f.Kind)
+		}
+		if strings.Join(keys, ",") != "delay:float,kick:bool,duration:int" {
+			t.Fatalf("unexpected memberBan fields: %v", keys)
+		}
+	}
+}
+
+type memberKickRecorder struct {
+	*mockPlatformAdapter
+	kicked []string
+}
+
+func (m *memberKickRecorder) MemberKick(_ string, userID string) { m.kicked = append(m.kicked, userID) }
+func (m *memberKickRecorder) Capabilities() AdapterCapabilities {
+	return AdapterCapabilities{MemberKick: true}
+}
+
+func TestReplyResultMemberBanSkipsPrivileged(t *testing.T) {
+	d, ep, adapter, cleanup := newExecuteNewTestDice(t)
+	defer cleanup()
+	rec := &memberKickRecorder{mockPlatformAdapter: adapter}
+	ep.Adapter = rec
+	defer func() { ep.Adapter = adapter }()
+
+	m := &ReplyResultMemberBan{Kick: true}
+	for _, level := range []int{100, 70, 60, 50} {
+		ctx := &MsgContext{Dice: d, EndPoint: ep, PrivilegeLevel: level}
+		m.Execute(ctx, newGroupMsg("QQ-Group:1", "QQ:1", "x"), nil)
+	}
+	// 被拉黑的群管理权限为 -30，仍按群内身份保护
+	m.Execute(&MsgContext{Dice: d, EndPoint: ep, PrivilegeLevel: -30, GroupRoleLevel: 50}, newGroupMsg("QQ-Group:1", "QQ:1", "x"), nil)
+	if len(rec.kicked) != 0 {
+		t.Fatalf("privileged members should not be kicked: %v", rec.kicked)
+	}
+
+	m.Execute(&MsgContext{Dice: d, EndPoint: ep, PrivilegeLevel: 40}, newGroupMsg("QQ-Group:1", "QQ:2", "x"), nil)
+	if len(rec.kicked) != 1 || rec.kicked[0] != "QQ:2" {
+		t.Fatalf("ordinary member should be kicked: %v", rec.kicked)
+	}
+}
@@ -0,0 +1,693 @@
+package dice
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+
+	"sealdice-core/dice/service"
+	"sealdice-core/model"
+)
+
+const (
+	ScheduleKindRemind   = "remind"
+	ScheduleKindSchedule = "schedule"
+)
+
+// scheduleMaxAhead 单次提醒最远可设置的时间
+const scheduleMaxAhead = 366 * 24 * time.Hour
+
+var (
+	reScheduleDuration = regexp.MustCompile(`^(?:\d+[dhm])+$`)
+	reScheduleClock    = regexp.MustCompile(`^(\d{1,2})[:：](\d{2})$`)
+	reScheduleDate     = regexp.MustCompile(`^(?:(\d{4})[-/])?(\d{1,2})[-/](\d{1,2})$`)
+)
+
+var scheduleWeekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "sunday": time.Sunday, "0": time.Sunday, "7": time.Sunday, "日": time.Sunday, "周日": time.Sunday, "天": time.Sunday,
+	"mon": time.Monday, "monday": time.Monday, "1": time.Monday, "一": time.Monday, "周一": time.Monday,
+	"tue": time.Tuesday, "tuesday": time.Tuesday, "2": time.Tuesday, "二": time.Tuesday, "周二": time.Tuesday,
+	"wed": time.Wednesday, "wednesday": time.Wednesday, "3": time.Wednesday, "三": time.Wednesday, "周三": time.Wednesday,
+	"thu": time.Thursday, "thursday": time.Thursday, "4": time.Thursday, "四": time.Thursday, "周四": time.Thursday,
+	"fri": time.Friday, "friday": time.Friday, "5": time.Friday, "五": time.Friday, "周五": time.Friday,
+	"sat": time.Saturday, "saturday": time.Saturday, "6": time.Saturday, "六": time.Saturday, "周六": time.Saturday,
+}
+
+var scheduleRSVPAnswers = map[string]string{
+	"yes": "yes", "y": "yes", "来": "yes", "参加": "yes",
+	"no": "no", "n": "no", "不来": "no", "请假": "no",
+	"maybe": "maybe", "m": "maybe", "待定": "maybe",
+}
+
+var scheduleRSVPText = map[string]string{"yes": "参加", "no": "不参加", "maybe": "待定"}
+
+// scheduleOnce 只触发一次的 cron 计划
+type scheduleOnce time.Time
+
+func (s scheduleOnce) Next(t time.Time) time.Time {
+	at := time.Time(s)
+	if at.After(t) {
+		return at
+	}
+	return time.Time{}
+}
+
+// ScheduleManager 管理各群的定时提醒与周期公告，任务存于数据库，由 Dice.Cron 触发。
+// 集群模式下每个节点都加载全部任务，但只有主节点发送，避免重复提醒。
+// 报名只支持 .rsvp 指令登记，表情回应(reaction)报名不在支持范围内：各平台的回应事件不经过统一的消息流程，
+// 发出的公告也拿不到消息 ID 以关联回应
+type ScheduleManager struct {
+	d       *Dice
+	lock    sync.Mutex
+	entries map[uint64]cron.EntryID
+}
+
+// ScheduleOverviewItem 供后台查看的任务信息
+type ScheduleOverviewItem struct {
+	*model.ScheduleJob
+	NextAt int64          `json:"nextAt"`
+	RSVP   map[string]int `json:"rsvpCount"`
+}
+
+func NewScheduleManager(d *Dice) *ScheduleManager {
+	return &ScheduleManager{d: d, entries: map[uint64]cron.EntryID{}}
+}
+
+// Load 启动时从数据库恢复任务。停机期间错过的提醒会在一分钟后补发
+func (m *ScheduleManager) Load() {
+	jobs, err := service.ScheduleJobList(m.d.DBOperator, "")
+	if err != nil {
+		m.d.Logger.Errorf("读取定时任务失败: %v", err)
+		return
+	}
+	for _, job := range jobs {
+		if err = m.add(job); err != nil {
+			m.d.Logger.Errorf("恢复定时任务 #%d 失败: %v", job.ID, err)
+		}
+	}
+}
+
+func scheduleFor(job *model.ScheduleJob) (cron.Schedule, error) {
+	if job.Kind == ScheduleKindSchedule {
+		return cron.ParseStandard(job.Spec)
+	}
+	at := time.Unix(job.FireAt, 0)
+	if minAt := time.Now().Add(time.Minute); at.Before(minAt) {
+		at = minAt
+	}
+	return scheduleOnce(at), nil
+}
+
+func (m *ScheduleManager) add(job *model.ScheduleJob) error {
+	sched, err := scheduleFor(job)
+	if err != nil {
+		return err
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if old, ok := m.entries[job.ID]; ok && m.d.Cron != nil {
+		m.d.Cron.Remove(old)
+	}
+	if m.d.Cron == nil {
+		return nil
+	}
+	id := job.ID
+	m.entries[id] = m.d.Cron.Schedule(sched, cron.FuncJob(func() { m.fire(id) }))
+	return nil
+}
+
+func (m *ScheduleManager) remove(id uint64) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if entry, ok := m.entries[id]; ok {
+		if m.d.Cron != nil {
+			m.d.Cron.Remove(entry)
+		}
+		delete(m.entries, id)
+	}
+}
+
+// Create 新建任务并加入计划，超出群内数量上限时返回错误
+func (m *ScheduleManager) Create(job *model.ScheduleJob) error {
+	if limit := m.d.Config.ScheduleMaxPerGroup; limit > 0 {
+		count, err := service.ScheduleJobCount(m.d.DBOperator, job.GroupID)
+		if err != nil {
+			return err
+		}
+		if count >= limit {
+			return errScheduleLimit
+		}
+	}
+	if _, err := scheduleFor(job); err != nil {
+		return err
+	}
+	if err := service.ScheduleJobCreate(m.d.DBOperator, job); err != nil {
+		return err
+	}
+	if err := m.add(job); err != nil {
+		return err
+	}
+	m.d.clusterManager().Publish(ClusterEventSchedule, strconv.FormatUint(job.ID, 10))
+	return nil
+}
+
+var errScheduleLimit = errors.New("定时任务数量已达上限")
+
+// Delete 删除任务，同时移出计划
+func (m *ScheduleManager) Delete(id uint64) error {
+	m.remove(id)
+	if err := service.ScheduleJobDelete(m.d.DBOperator, id); err != nil {
+		return err
+	}
+	m.d.clusterManager().Publish(ClusterEventSchedule, strconv.FormatUint(id, 10))
+	return nil
+}
+
+// clusterReload 其他节点增删了任务，按数据库中的记录更新本地计划
+func (m *ScheduleManager) clusterReload(key string) {
+	id, err := strconv.ParseUint(key, 10, 64)
+	if err != nil {
+		return
+	}
+	job, err := service.ScheduleJobGet(m.d.DBOperator, id)
+	if err != nil {
+		m.d.Logger.Warnf("重新加载定时任务 #%d 失败: %v", id, err)
+		return
+	}
+	if job == nil {
+		m.remove(id)
+		return
+	}
+	if err = m.add(job); err != nil {
+		m.d.Logger.Warnf("重新加载定时任务 #%d 失败: %v", id, err)
+	}
+}
+
+// NextAt 任务的下次触发时间
+func (m *ScheduleManager) NextAt(job *model.ScheduleJob) time.Time {
+	if job.Kind != ScheduleKindSchedule {
+		return time.Unix(job.FireAt, 0)
+	}
+	sched, err := cron.ParseStandard(job.Spec)
+	if err != nil {
+		return time.Time{}
+	}
+	return sched.Next(time.Now())
+}
+
+// Overview 列出任务及报名统计，groupID 为空时列出全部
+func (m *ScheduleManager) Overview(groupID string) ([]*ScheduleOverviewItem, error) {
+	jobs, err := service.ScheduleJobList(m.d.DBOperator, groupID)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]*ScheduleOverviewItem, 0, len(jobs))
+	for _, job := range jobs {
+		item := &ScheduleOverviewItem{ScheduleJob: job, NextAt: m.NextAt(job).Unix(), RSVP: map[string]int{}}
+		if job.RSVP {
+			rsvps, errList := service.ScheduleRSVPList(m.d.DBOperator, job.ID)
+			if errList != nil {
+				return nil, errList
+			}
+			for _, r := range rsvps {
+				item.RSVP[r.Answer]++
+			}
+		}
+		items = append(items, item)
+	}
+	sort.SliceStable(items, func(i, j int) bool { return items[i].NextAt < items[j].NextAt })
+	return items, nil
+}
+
+// endpointFor 只使用创建任务时的骰子帐号发送，其他帐号未必在群内
+func (m *ScheduleManager) endpointFor(job *model.ScheduleJob) *EndPointInfo {
+	if m.d.ImSession == nil {
+		return nil
+	}
+	for _, ep := range m.d.ImSession.EndPoints {
+		if ep.Enable && ep.UserID == job.EndpointID {
+			return ep
+		}
+	}
+	return nil
+}
+
+func (m *ScheduleManager) fire(id uint64) {
+	d := m.d
+	if !d.clusterManager().IsLeader() {
+		return
+	}
+	job, err := service.ScheduleJobGet(d.DBOperator, id)
+	if err != nil || job == nil {
+		m.remove(id)
+		return
+	}
+	defer func() {
+		if job.Kind == ScheduleKindRemind {
+			if errDel := m.Delete(job.ID); errDel != nil {
+				d.Logger.Errorf("删除已触发的提醒 #%d 失败: %v", job.ID, errDel)
+			}
+		} else if job.RSVP {
+			// 周期公告每次触发后开始新一轮报名
+			if errClear := service.ScheduleRSVPClear(d.DBOperator, job.ID); errClear != nil {
+				d.Logger.Errorf("清空报名记录 #%d 失败: %v", job.ID, errClear)
+			}
+		}
+	}()
+
+	ep := m.endpointFor(job)
+	if ep == nil {
+		d.Logger.Warnf("定时任务 #%d 的骰子帐号 %s 不可用，跳过", job.ID, job.EndpointID)
+		return
+	}
+	if group, ok := d.ImSession.ServiceAtNew.Load(job.GroupID); ok && group.ExtGetActive("schedule") == nil {
+		d.Logger.Infof("群(%s)已关闭 schedule 扩展，跳过定时任务 #%d", job.GroupID, job.ID)
+		return
+	}
+
+	msg := &Message{
+		MessageType: "group",
+		GroupID:     job.GroupID,
+		Platform:    ep.Platform,
+		Sender:      SenderBase{UserID: job.CreatorID, Nickname: job.Creator},
+	}
+	ctx := CreateTempCtx(ep, msg)
+	// 定时消息不计入创建者的刷屏统计
+	ctx.SpamCheckedPerson = true
+	ctx.SpamCheckedGroup = true
+	m.setVars(ctx, job)
+	VarSetValueStr(ctx, "$t报名情况", m.rsvpSummary(job))
+	key := "其它:定时_触发提醒"
+	if job.Kind == ScheduleKindSchedule {
+		key = "其它:定时_触发公告"
+	}
+	ReplyGroup(ctx, msg, DiceFormatTmpl(ctx, key))
+}
+
+func (m *ScheduleManager) setVars(ctx *MsgContext, job *model.ScheduleJob) {
+	VarSetValueInt64(ctx, "$t任务ID", int64(job.ID))
+	VarSetValueStr(ctx, "$t内容", job.Content)
+	VarSetValueStr(ctx, "$t触发时间", m.NextAt(job).Format("2006-01-02 15:04"))
+	VarSetValueStr(ctx, "$t周期", scheduleSpecText(job.Spec))
+}
+
+func (m *ScheduleManager) rsvpSummary(job *model.ScheduleJob) string {
+	if !job.RSVP {
+		return ""
+	}
+	rsvps, err := service.ScheduleRSVPList(m.d.DBOperator, job.ID)
+	if err != nil {
+		return ""
+	}
+	groups := map[string][]string{}
+	for _, r := range rsvps {
+		groups[r.Answer] = append(groups[r.Answer], r.Name)
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "\n报名(#%d，.rsvp yes/no/maybe %d):", job.ID, job.ID)
+	for _, answer := range []string{"yes", "maybe", "no"} {
+		fmt.Fprintf(&sb, "\n%s %d人", scheduleRSVPText[answer], len(groups[answer]))
+		if len(groups[answer]) > 0 {
+			sb.WriteString(": " + strings.Join(groups[answer], "、"))
+		}
+	}
+	return sb.String()
+}
+
+// scheduleSpecText 将 cron 表达式还原为便于阅读的说明
+func scheduleSpecText(spec string) string {
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return spec
+	}
+	clock := fmt.Sprintf("%s:%02s", fields[1], fields[0])
+	switch {
+	case fields[2] == "*" && fields[4] == "*":
+		return "每天 " + clock
+	case fields[2] == "*":
+		names := []string{"日", "一", "二", "三", "四", "五", "六"}
+		if n, err := strconv.Atoi(fields[4]); err == nil && n >= 0 && n < 7 {
+			return "每周" + names[n] + " " + clock
+		}
+	case fields[4] == "*":
+		return "每月" + fields[2] + "日 " + clock
+	}
+	return spec
+}
+
+func parseScheduleClock(s string) (int, int, bool) {
+	m := reScheduleClock.FindStringSubmatch(s)
+	if m == nil {
+		return 0, 0, false
+	}
+	hour, _ := strconv.Atoi(m[1])
+	minute, _ := strconv.Atoi(m[2])
+	if hour > 23 || minute > 59 {
+		return 0, 0, false
+	}
+	return hour, minute, true
+}
+
+// parseRemindTime 解析提醒时间，返回时间与占用的参数个数。支持:
+// 30m / 2h / 1d12h 这样的相对时间；20:00 (今天，已过则为明天)；10-20 20:00 或 2026-10-20 20:00
+func parseRemindTime(args []string, now time.Time) (time.Time, int, bool) {
+	if len(args) == 0 {
+		return time.Time{}, 0, false
+	}
+	first := strings.ToLower(args[0])
+	if reScheduleDuration.MatchString(first) {
+		var total time.Duration
+		num := 0
+		for _, c := range first {
+			switch c {
+			case 'd':
+				total += time.Duration(num) * 24 * time.Hour
+				num = 0
+			case 'h':
+				total += time.Duration(num) * time.Hour
+				num = 0
+			case 'm':
+				total += time.Duration(num) * time.Minute
+				num = 0
+			default:
+				num = num*10 + int(c-'0')
+			}
+		}
+		return now.Add(total), 1, total > 0
+	}
+	if hour, minute, ok := parseScheduleClock(first); ok {
+		at := time.Date(now.Year(), now.Month(), now.Day(), hour, minute, 0, 0, now.Location())
+		if !at.After(now) {
+			at = at.AddDate(0, 0, 1)
+		}
+		return at, 1, true
+	}
+	if m := reScheduleDate.FindStringSubmatch(first); m != nil && len(args) > 1 {
+		hour, minute, ok := parseScheduleClock(args[1])
+		if !ok {
+			return time.Time{}, 0, false
+		}
+		year := now.Year()
+		if m[1] != "" {
+			year, _ = strconv.Atoi(m[1])
+		}
+		month, _ := strconv.Atoi(m[2])
+		day, _ := strconv.Atoi(m[3])
+		at := time.Date(year, time.Month(month), day, hour, minute, 0, 0, now.Location())
+		if at.Month() != time.Month(month) || at.Day() != day {
+			return time.Time{}, 0, false
+		}
+		if m[1] == "" && !at.After(now) {
+			at = at.AddDate(1, 0, 0)
+		}
+		return at, 2, true
+	}
+	return time.Time{}, 0, false
+}
+
+// parseScheduleSpec 解析周期，返回 cron 表达式与占用的参数个数。支持:
+// daily 20:00；weekly sat 19:30；monthly 1 20:00
+func parseScheduleSpec(args []string) (string, int, bool) {
+	if len(args) < 2 {
+		return "", 0, false
+	}
+	switch strings.ToLower(args[0]) {
+	case "daily", "每天":
+		if hour, minute, ok := parseScheduleClock(args[1]); ok {
+			return fmt.Sprintf("%d %d * * *", minute, hour), 2, true
+		}
+	case "weekly", "每周":
+		if len(args) < 3 {
+			return "", 0, false
+		}
+		weekday, ok := scheduleWeekdays[strings.ToLower(args[1])]
+		if !ok {
+			return "", 0, false
+		}
+		if hour, minute, ok := parseScheduleClock(args[2]); ok {
+			return fmt.Sprintf("%d %d * * %d", minute, hour, weekday), 3, true
+		}
+	case "monthly", "每月":
+		if len(args) < 3 {
+			return "", 0, false
+		}
+		day, err := strconv.Atoi(args[1])
+		if err != nil || day < 1 || day > 31 {
+			return "", 0, false
+		}
+		if hour, minute, ok := parseScheduleClock(args[2]); ok {
+			return fmt.Sprintf("%d %d %d * *", minute, hour, day), 3, true
+		}
+	}
+	return "", 0, false
+}
+
+// nextRSVPJob 找出群内最近一次触发的报名任务
+func (m *ScheduleManager) nextRSVPJob(groupID string) (*model.ScheduleJob, error) {
+	items, err := m.Overview(groupID)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range items {
+		if item.ScheduleJob.RSVP {
+			return item.ScheduleJob, nil
+		}
+	}
+	return nil, nil
+}
+
+func scheduleListText(m *ScheduleManager, groupID string) string {
+	items, err := m.Overview(groupID)
+	if err != nil {
+		return "读取定时任务失败: " + err.Error()
+	}
+	if len(items) == 0 {
+		return "本群没有定时任务"
+	}
+	var sb strings.Builder
+	sb.WriteString("本群的定时任务:")
+	for _, item := range items {
+		kind := "提醒"
+		if item.Kind == ScheduleKindSchedule {
+			kind = scheduleSpecText(item.Spec)
+		}
+		fmt.Fprintf(&sb, "\n#%d [%s] 下次 %s: %s", item.ID, kind, time.Unix(item.NextAt, 0).Format("01-02 15:04"), item.Content)
+		if item.ScheduleJob.RSVP {
+			fmt.Fprintf(&sb, " (报名 %d/%d/%d)", item.RSVP["yes"], item.RSVP["maybe"], item.RSVP["no"])
+		}
+	}
+	return sb.String()
+}
+
+// scheduleDelete 仅创建者或群管理以上可删除
+func scheduleDelete(ctx *MsgContext, msg *Message, m *ScheduleManager, idText string) string {
+	id, err := strconv.ParseUint(strings.TrimPrefix(idText, "#"), 10, 64)
+	if err != nil {
+		return "请指定要删除的任务编号，如 .remind del 3"
+	}
+	job, err := service.ScheduleJobGet(ctx.Dice.DBOperator, id)
+	if err != nil || job == nil || job.GroupID != msg.GroupID {
+		return fmt.Sprintf("本群没有编号为 #%d 的任务", id)
+	}
+	if job.CreatorID != msg.Sender.UserID && ctx.PrivilegeLevel < 50 {
+		return "只有任务的创建者或群管理可以删除"
+	}
+	if err = m.Delete(id); err != nil {
+		return "删除失败: " + err.Error()
+	}
+	return fmt.Sprintf("已删除任务 #%d", id)
+}
+
+func scheduleCreateReply(ctx *MsgContext, msg *Message, m *ScheduleManager, job *model.ScheduleJob) {
+	job.GroupID = msg.GroupID
+	job.EndpointID = ctx.EndPoint.UserID
+	job.CreatorID = msg.Sender.UserID
+	job.Creator = ctx.Player.Name
+	if err := m.Create(job); err != nil {
+		if errors.Is(err, errScheduleLimit) {
+			VarSetValueInt64(ctx, "$t上限", ctx.Dice.Config.ScheduleMaxPerGroup)
+			ReplyToSender(ctx, msg, DiceFormatTmpl(ctx, "其它:定时_数量上限"))
+			return
+		}
+		ReplyToSender(ctx, msg, "创建定时任务失败: "+err.Error())
+		return
+	}
+	m.setVars(ctx, job)
+	key := "其它:定时_新增提醒"
+	if job.Kind == ScheduleKindSchedule {
+		key = "其它:定时_新增公告"
+	}
+	ReplyToSender(ctx, msg, DiceFormatTmpl(ctx, key))
+}
+
+func RegisterBuiltinExtSchedule(self *Dice) {
+	self.Schedule = NewScheduleManager(self)
+
+	helpRemind := `.remind 2026-10-20 20:00 下次跑团 // 指定日期时间提醒
+.remind 10-20 20:00 内容 // 省略年份
+.remind 20:00 内容 // 今天(已过则明天)
+.remind 30m 内容 // 相对时间，支持 d/h/m，如 1d12h
+.remind ... --rsvp // 附带报名，成员可用 .rsvp 登记(不支持表情回应)
+.remind list // 列出本群任务
+.remind del <编号> // 删除任务`
+	cmdRemind := &CmdItemInfo{
+		Name:              "remind",
+		ShortHelp:         helpRemind,
+		Help:              "定时提醒:\n" + helpRemind,
+		DisabledInPrivate: true,
+		Solve: func(ctx *MsgContext, msg *Message, cmdArgs *CmdArgs) CmdExecuteResult {
+			m := ctx.Dice.Schedule
+			switch {
+			case len(cmdArgs.Args) == 0, cmdArgs.IsArgEqual(1, "help"):
+				return CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+			case cmdArgs.IsArgEqual(1, "list"):
+				ReplyToSender(ctx, msg, scheduleListText(m, msg.GroupID))
+				return CmdExecuteResult{Matched: true, Solved: true}
+			case cmdArgs.IsArgEqual(1, "del", "rm"):
+				ReplyToSender(ctx, msg, scheduleDelete(ctx, msg, m, cmdArgs.GetArgN(2)))
+				return CmdExecuteResult{Matched: true, Solved: true}
+			}
+
+			now := time.Now()
+			at, n, ok := parseRemindTime(cmdArgs.Args, now)
+			if !ok || !at.After(now) || at.Sub(now) > scheduleMaxAhead {
+				ReplyToSender(ctx, msg, "无法识别提醒时间，或时间不在未来一年内")
+				return CmdExecuteResult{Matched: true, Solved: true}
+			}
+			content := strings.TrimSpace(strings.Join(cmdArgs.Args[n:], " "))
+			if content == "" {
+				return CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+			}
+			scheduleCreateReply(ctx, msg, m, &model.ScheduleJob{
+				Kind:    ScheduleKindRemind,
+				FireAt:  at.Unix(),
+				Content: content,
+				RSVP:    cmdArgs.GetKwarg("rsvp") != nil,
+			})
+			return CmdExecuteResult{Matched: true, Solved: true}
+		},
+	}
+
+	helpSchedule := `.schedule daily 20:00 内容 // 每天
+.schedule weekly sat 19:30 内容 // 每周，星期可写 mon~sun、1~7 或 六
+.schedule monthly 1 20:00 内容 // 每月
+.schedule ... --rsvp // 附带报名，每次公告后重新开始登记
+.schedule list // 列出本群任务
+.schedule del <编号> // 删除任务`
+	cmdSchedule := &CmdItemInfo{
+		Name:              "schedule",
+		ShortHelp:         helpSchedule,
+		Help:              "周期公告:\n" + helpSchedule,
+		DisabledInPrivate: true,
+		Solve: func(ctx *MsgContext, msg *Message, cmdArgs *CmdArgs) CmdExecuteResult {
+			m := ctx.Dice.Schedule
+			switch {
+			case len(cmdArgs.Args) == 0, cmdArgs.IsArgEqual(1, "help"):
+				return CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+			case cmdArgs.IsArgEqual(1, "list"):
+				ReplyToSender(ctx, msg, scheduleListText(m, msg.GroupID))
+				return CmdExecuteResult{Matched: true, Solved: true}
+			case cmdArgs.IsArgEqual(1, "del", "rm"):
+				ReplyToSender(ctx, msg, scheduleDelete(ctx, msg, m, cmdArgs.GetArgN(2)))
+				return CmdExecuteResult{Matched: true, Solved: true}
+			}
+
+			spec, n, ok := parseScheduleSpec(cmdArgs.Args)
+			if !ok {
+				return CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+			}
+			content := strings.TrimSpace(strings.Join(cmdArgs.Args[n:], " "))
+			if content == "" {
+				return CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+			}
+			scheduleCreateReply(ctx, msg, m, &model.ScheduleJob{
+				Kind:    ScheduleKindSchedule,
+				Spec:    spec,
+				Content: content,
+				RSVP:    cmdArgs.GetKwarg("rsvp") != nil,
+			})
+			return CmdExecuteResult{Matched: true, Solved: true}
+		},
+	}
+
+	helpRSVP := `.rsvp yes/no/maybe // 为本群最近的报名任务登记
+.rsvp yes <编号> // 为指定任务登记
+.rsvp list [编号] // 查看报名情况`
+	cmdRSVP := &CmdItemInfo{
+		Name:              "rsvp",
+		ShortHelp:         helpRSVP,
+		Help:              "报名:\n" + helpRSVP,
+		DisabledInPrivate: true,
+		Solve: func(ctx *MsgContext, msg *Message, cmdArgs *CmdArgs) CmdExecuteResult {
+			m := ctx.Dice.Schedule
+			if cmdArgs.IsArgEqual(1, "help") {
+				return CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+			}
+			sub := strings.ToLower(cmdArgs.GetArgN(1))
+			answer, isAnswer := scheduleRSVPAnswers[sub]
+			if sub != "" && sub != "list" && !isAnswer {
+				return CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+			}
+
+			var job *model.ScheduleJob
+			var err error
+			if idText := cmdArgs.GetArgN(2); idText != "" {
+				id, errParse := strconv.ParseUint(strings.TrimPrefix(idText, "#"), 10, 64)
+				if errParse == nil {
+					job, err = service.ScheduleJobGet(ctx.Dice.DBOperator, id)
+				}
+			} else {
+				job, err = m.nextRSVPJob(msg.GroupID)
+			}
+			if err != nil || job == nil || job.GroupID != msg.GroupID || !job.RSVP {
+				ReplyToSender(ctx, msg, "本群没有找到可以报名的任务")
+				return CmdExecuteResult{Matched: true, Solved: true}
+			}
+
+			if isAnswer {
+				err = service.ScheduleRSVPSet(ctx.Dice.DBOperator, &model.ScheduleRSVP{
+					JobID:  job.ID,
+					UserID: msg.Sender.UserID,
+					Name:   ctx.Player.Name,
+					Answer: answer,
+				})
+				if err != nil {
+					ReplyToSender(ctx, msg, "登记失败: "+err.Error())
+					return CmdExecuteResult{Matched: true, Solved: true}
+				}
+				m.setVars(ctx, job)
+				VarSetValueStr(ctx, "$t报名", scheduleRSVPText[answer])
+				ReplyToSender(ctx, msg, DiceFormatTmpl(ctx, "其它:报名_已登记"))
+				return CmdExecuteResult{Matched: true, Solved: true}
+			}
+			ReplyToSender(ctx, msg, fmt.Sprintf("#%d %s", job.ID, job.Content)+m.rsvpSummary(job))
+			return CmdExecuteResult{Matched: true, Solved: true}
+		},
+	}
+
+	theExt := &ExtInfo{
+		Name:       "schedule",
+		Version:    "1.0.0",
+		Brief:      "定时提醒、周期公告与报名",
+		Author:     "SealDice-Team",
+		AutoActive: true,
+		Official:   true,
+		OnCommandReceived: func(ctx *MsgContext, msg *Message, cmdArgs *CmdArgs) {
+		},
+		GetDescText: GetExtensionDesc,
+		OnLoad:      func() {},
+		CmdMap: CmdMapCls{
+			"remind":   cmdRemind,
+			"schedule": cmdSchedule,
+			"rsvp":     cmdRSVP,
+		},
+	}
+	self.RegisterExtension(theExt)
+}
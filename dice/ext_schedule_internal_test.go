@@ -0,0 +1,166 @@
+//nolint:testpackage
+package dice
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"sealdice-core/dice/service"
+	"sealdice-core/model"
+	"sealdice-core/utils/constant"
+)
+
+func TestParseRemindTime(t *testing.T) {
+	now := time.Date(2026, 10, 18, 21, 0, 0, 0, time.Local)
+	cases := []struct {
+		args []string
+		want time.Time
+		n    int
+	}{
+		{[]string{"30m", "x"}, now.Add(30 * time.Minute), 1},
+		{[]string{"1d2h"}, now.Add(26 * time.Hour), 1},
+		{[]string{"22:30"}, time.Date(2026, 10, 18, 22, 30, 0, 0, time.Local), 1},
+		{[]string{"20:00"}, time.Date(2026, 10, 19, 20, 0, 0, 0, time.Local), 1},
+		{[]string{"2026-10-20", "20:00"}, time.Date(2026, 10, 20, 20, 0, 0, 0, time.Local), 2},
+		{[]string{"1-5", "08:15"}, time.Date(2027, 1, 5, 8, 15, 0, 0, time.Local), 2},
+	}
+	for _, c := range cases {
+		got, n, ok := parseRemindTime(c.args, now)
+		if !ok || !got.Equal(c.want) || n != c.n {
+			t.Errorf("%v: got %v/%d/%v, want %v/%d", c.args, got, n, ok, c.want, c.n)
+		}
+	}
+	for _, bad := range [][]string{{"2026-02-30", "20:00"}, {"25:00"}, {"明天"}} {
+		if _, _, ok := parseRemindTime(bad, now); ok {
+			t.Errorf("%v should be rejected", bad)
+		}
+	}
+
+	spec, n, ok := parseScheduleSpec([]string{"weekly", "sat", "19:30", "跑团"})
+	if !ok || spec != "30 19 * * 6" || n != 3 || scheduleSpecText(spec) != "每周六 19:30" {
+		t.Fatalf("unexpected spec: %q %d %v", spec, n, ok)
+	}
+}
+
+func TestScheduleRemindWithRSVP(t *testing.T) {
+	d, ep, adapter, cleanup := newExecuteNewTestDice(t)
+	defer cleanup()
+	if err := d.DBOperator.GetDataDB(constant.WRITE).AutoMigrate(&model.ScheduleJob{}, &model.ScheduleRSVP{}); err != nil {
+		t.Fatal(err)
+	}
+	groupID := "QQ-Group:111"
+
+	d.ImSession.ExecuteNew(ep, newGroupMsg(groupID, "QQ:999", ".remind 30m 下次跑团 --rsvp"))
+	if text, ok := adapter.waitForMsg(2 * time.Second); !ok || !strings.Contains(text, "已设置提醒") {
+		t.Fatalf("unexpected reply: %q", text)
+	}
+	jobs, _ := service.ScheduleJobList(d.DBOperator, groupID)
+	if len(jobs) != 1 || !jobs[0].RSVP || jobs[0].Content != "下次跑团" || jobs[0].EndpointID != ep.UserID {
+		t.Fatalf("unexpected jobs: %+v", jobs)
+	}
+
+	d.ImSession.ExecuteNew(ep, newGroupMsg(groupID, "QQ:998", ".rsvp yes"))
+	if text, ok := adapter.waitForMsg(2 * time.Second); !ok || !strings.Contains(text, "参加") {
+		t.Fatalf("unexpected rsvp reply: %q", text)
+	}
+
+	d.Schedule.fire(jobs[0].ID)
+	text, ok := adapter.waitForMsg(2 * time.Second)
+	if !ok || !strings.Contains(text, "下次跑团") || !strings.Contains(text, "参加 1人") {
+		t.Fatalf("unexpected fire message: %q", text)
+	}
+	if job, _ := service.ScheduleJobGet(d.DBOperator, jobs[0].ID); job != nil {
+		t.Fatal("remind should be removed after firing")
+	}
+}
+
+func TestScheduleLimitPerGroup(t *testing.T) {
+	d, ep, adapter, cleanup := newExecuteNewTestDice(t)
+	defer cleanup()
+	if err := d.DBOperator.GetDataDB(constant.WRITE).AutoMigrate(&model.ScheduleJob{}, &model.ScheduleRSVP{}); err != nil {
+		t.Fatal(err)
+	}
+	d.Config.ScheduleMaxPerGroup = 1
+
+	d.ImSession.ExecuteNew(ep, newGroupMsg("QQ-Group:111", "QQ:999", ".schedule weekly sat 19:30 周六团"))
+	if text, ok := adapter.waitForMsg(2 * time.Second); !ok || !strings.Contains(text, "每周六 19:30") {
+		t.Fatalf("unexpected reply: %q", text)
+	}
+	d.ImSession.ExecuteNew(ep, newGroupMsg("QQ-Group:111", "QQ:999", ".schedule daily 20:00 每日"))
+	if text, ok := adapter.waitForMsg(2 * time.Second); !ok || !strings.Contains(text, "上限") {
+		t.Fatalf("expected limit reply, got %q", text)
+	}
+	if d.Schedule.entries[1] == 0 {
+		t.Fatal("job should be scheduled on the cron")
+	}
+}
+
+func TestScheduleFiresOnlyOnLeaderWithOwnEndpoint(t *testing.T) {
+	d, ep, adapter, cleanup := newExecuteNewTestDice(t)
+	defer cleanup()
+	if err := d.DBOperator.GetDataDB(constant.WRITE).AutoMigrate(&model.ScheduleJob{}, &model.ScheduleRSVP{}); err != nil {
+		t.Fatal(err)
+	}
+	c := newClusterTestManager(t, d)
+	groupID := "QQ-Group:111"
+
+	d.ImSession.ExecuteNew(ep, newGroupMsg(groupID, "QQ:999", ".remind 30m 下次跑团"))
+	if _, ok := adapter.waitForMsg(2 * time.Second); !ok {
+		t.Fatal("expected create reply")
+	}
+	jobs, _ := service.ScheduleJobList(d.DBOperator, groupID)
+	if len(jobs) != 1 {
+		t.Fatalf("unexpected jobs: %+v", jobs)
+	}
+
+	// 非主节点不发送，也不删除任务
+	d.Schedule.fire(jobs[0].ID)
+	if text, ok := adapter.waitForMsg(200 * time.Millisecond); ok {
+		t.Fatalf("follower should not fire: %q", text)
+	}
+	if job, _ := service.ScheduleJobGet(d.DBOperator, jobs[0].ID); job == nil {
+		t.Fatal("follower should not delete the job")
+	}
+
+	// 创建任务的帐号不在本节点时，不借用其他帐号发送
+	c.leader.Store(true)
+	jobs[0].EndpointID = "QQ:other"
+	if d.Schedule.endpointFor(jobs[0]) != nil {
+		t.Fatal("should not fall back to another endpoint")
+	}
+
+	d.Schedule.fire(jobs[0].ID)
+	if text, ok := adapter.waitForMsg(2 * time.Second); !ok || !strings.Contains(text, "下次跑团") {
+		t.Fatalf("leader should fire: %q", text)
+	}
+}
+
+func TestScheduleClusterEventReloadsJobs(t *testing.T) {
+	d, _, _, cleanup := newExecuteNewTestDice(t)
+	defer cleanup()
+	if err := d.DBOperator.GetDataDB(constant.WRITE).AutoMigrate(&model.ScheduleJob{}, &model.ScheduleRSVP{}); err != nil {
+		t.Fatal(err)
+	}
+	c := newClusterTestManager(t, d)
+
+	// 其他节点创建的任务
+	job := &model.ScheduleJob{Kind: ScheduleKindSchedule, Spec: "0 20 * * *", GroupID: "QQ-Group:111", Content: "每日"}
+	if err := service.ScheduleJobCreate(d.DBOperator, job); err != nil {
+		t.Fatal(err)
+	}
+	peerPublish(t, d, ClusterEventSchedule, "1")
+	c.poll()
+	if d.Schedule.entries[job.ID] == 0 {
+		t.Fatal("peer job should be scheduled locally")
+	}
+
+	if err := service.ScheduleJobDelete(d.DBOperator, job.ID); err != nil {
+		t.Fatal(err)
+	}
+	peerPublish(t, d, ClusterEventSchedule, "1")
+	c.poll()
+	if _, ok := d.Schedule.entries[job.ID]; ok {
+		t.Fatal("deleted peer job should be removed")
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/samber/lo"
 	ds "github.com/sealdice/dicescript"
 
+	"sealdice-core/dice/service"
 	"sealdice-core/logger"
 )
 
@@ -488,6 +489,8 @@ func getCmdStBase(soi CmdStOverrideInfo) *CmdItemInfo {
 		helpSt += ".st fmt // 强制转卡为当前规则(改变卡片类型，转换同义词)\n"
 		helpSt += ".st del <属性1> <属性2> ... // 删除属性，可多项，以空格间隔\n"
 		helpSt += ".st export // 导出\n"
+		helpSt += ".st history [<条数>] // 查看最近的属性变更记录\n"
+		helpSt += ".st undo [<次数>] // 撤销最近几次属性变更，默认1次\n"
 		helpSt += ".st help // 帮助\n"
 		helpSt += ".st <属性><值> // 例：.st 敏捷50 力量3d6*5\n"
 		helpSt += ".st &<属性>=<式子> // 例：.st &手枪=1d6\n"
@@ -503,7 +506,7 @@ func getCmdStBase(soi CmdStOverrideInfo) *CmdItemInfo {
 		Help:          soi.HelpPrefix + helpSt,
 		AllowDelegate: true,
 		Solve: func(ctx *MsgContext, msg *Message, cmdArgs *CmdArgs) CmdExecuteResult {
-			cmdArgs.ChopPrefixToArgsWith("del", "rm", "show", "list", "export")
+			cmdArgs.ChopPrefixToArgsWith("del", "rm", "show", "list", "export", "history", "undo")
 			dice := ctx.Dice
 			val := cmdArgs.GetArgN(1)
 			mctx := GetCtxProxyFirst(ctx, cmdArgs)
@@ -618,6 +621,54 @@ func getCmdStBase(soi CmdStOverrideInfo) *CmdItemInfo {
 
 				ReplyToSender(mctx, msg, out)
 
+			case "history":
+				limit := 10
+				if n, err := strconv.Atoi(cmdArgs.GetArgN(2)); err == nil && n > 0 {
+					limit = min(n, 50)
+				}
+				if err := dice.AttrsManager.JournalFlush(nil); err != nil {
+					ReplyToSender(mctx, msg, err.Error())
+					return CmdExecuteResult{Matched: true, Solved: true}
+				}
+				entries, _, err := service.AttrsJournalList(dice.DBOperator, attrs.ID, limit, 0)
+				if err != nil {
+					ReplyToSender(mctx, msg, err.Error())
+					return CmdExecuteResult{Matched: true, Solved: true}
+				}
+				if len(entries) == 0 {
+					ReplyToSender(mctx, msg, DiceFormatTmpl(mctx, "COC:属性设置_历史_无记录"))
+					return CmdExecuteResult{Matched: true, Solved: true}
+				}
+				lines := make([]string, 0, len(entries))
+				for _, e := range entries {
+					lines = append(lines, FormatAttrsJournal(e))
+				}
+				VarSetValueStr(mctx, "$t记录列表", strings.Join(lines, "\n"))
+				VarSetValueInt64(mctx, "$t数量", int64(len(entries)))
+				ReplyToSender(mctx, msg, DiceFormatTmpl(mctx, "COC:属性设置_历史"))
+
+			case "undo":
+				n := 1
+				if v, err := strconv.Atoi(cmdArgs.GetArgN(2)); err == nil && v > 0 {
+					n = min(v, 20)
+				}
+				undone, err := dice.AttrsManager.JournalUndo(mctx, attrs, n)
+				if errors.Is(err, service.ErrAttrsJournalNothingToUndo) {
+					ReplyToSender(mctx, msg, DiceFormatTmpl(mctx, "COC:属性设置_撤销_无记录"))
+					return CmdExecuteResult{Matched: true, Solved: true}
+				}
+				if err != nil {
+					ReplyToSender(mctx, msg, err.Error())
+					return CmdExecuteResult{Matched: true, Solved: true}
+				}
+				lines := make([]string, 0, len(undone))
+				for _, e := range undone {
+					lines = append(lines, fmt.Sprintf("%s: %s ➯ %s", e.Attr, journalDisplay(e.NewValue), journalDisplay(e.OldValue)))
+				}
+				VarSetValueStr(mctx, "$t变更列表", strings.Join(lines, "\n"))
+				VarSetValueInt64(mctx, "$t数量", int64(len(undone)))
+				ReplyToSender(mctx, msg, DiceFormatTmpl(mctx, "COC:属性设置_撤销"))
+
 			case "del", "rm":
 				var nums []string
 				var failed []string
@@ -0,0 +1,371 @@
+package dice
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	ds "github.com/sealdice/dicescript"
+)
+
+// CheckConfig 模板声明的检定规则，其中的表达式均为 dicescript。
+// 判定时依次求出大成功值、判定值、大失败值与成功等级，表达式中可以读取以下临时变量:
+// $t出目 检定骰出目，$t属性值 属性或技能数值，$t难度 难度要求(0 普通 2 困难 3 极难 4 大成功)，$tDC 指令中给出的难度值，
+// 以及已求出的 $t大成功值、$t判定值、$t大失败值、$t成功等级
+type CheckConfig struct {
+	Commands []string    `yaml:"commands"` // 由通用检定扩展(check)提供的指令名，系统自带扩展已有检定指令时无需填写
+	Dice     string      `yaml:"dice"`     // 检定骰，如 d100、d20
+	Critical string      `yaml:"critical"` // 大成功值
+	Fumble   string      `yaml:"fumble"`   // 大失败值
+	Target   string      `yaml:"target"`   // 判定值，留空时为属性值
+	Rank     string      `yaml:"rank"`     // 成功等级: 4 大成功 3 极难成功 2 困难成功 1 成功 0 无判定 -1 失败 -2 大失败
+	Rules    []CheckRule `yaml:"rules"`    // 房规，按序号选用，找不到时使用第一条
+	Tiers    []CheckTier `yaml:"tiers"`    // 成功等级对应的文本
+	Output   string      `yaml:"output"`   // 通用检定指令的回复文本，留空使用默认格式
+}
+
+// CheckRule 一条房规，留空的表达式沿用 CheckConfig 中的同名项
+type CheckRule struct {
+	Index    int    `yaml:"index"` // 序号，对应 .setcoc
+	Key      string `yaml:"key"`   // 也可以用键名选用，如 dg
+	Name     string `yaml:"name"`
+	Desc     string `yaml:"desc"`
+	Critical string `yaml:"critical"`
+	Fumble   string `yaml:"fumble"`
+	Target   string `yaml:"target"`
+	Rank     string `yaml:"rank"` // 在 CheckConfig.Rank 的结果($t成功等级)上改判
+}
+
+// CheckTier 成功等级的文本
+type CheckTier struct {
+	Rank    int    `yaml:"rank"`
+	Name    string `yaml:"name"`    // 如 大成功
+	TextKey string `yaml:"textKey"` // 文本模板的键，如 COC:判定_大成功
+	Text    string `yaml:"text"`    // 没有文本模板键时使用，可以包含表达式，都为空时使用 Name
+}
+
+// CheckOutcome 一次检定的判定结果
+type CheckOutcome struct {
+	Outcome  int64 `json:"outcome"`
+	Critical int64 `json:"critical"`
+	Fumble   int64 `json:"fumble"`
+	Target   int64 `json:"target"`
+	Rank     int   `json:"rank"`
+}
+
+// Enabled 模板是否声明了检定规则
+func (c *CheckConfig) Enabled() bool {
+	return c != nil && strings.TrimSpace(c.Rank) != ""
+}
+
+// Rule 按序号查找房规，找不到时返回第一条，没有房规时返回 nil
+func (c *CheckConfig) Rule(index int) *CheckRule {
+	if c == nil || len(c.Rules) == 0 {
+		return nil
+	}
+	if r := c.FindRule(index, ""); r != nil {
+		return r
+	}
+	return &c.Rules[0]
+}
+
+// FindRule 按序号或键名精确查找房规，key 为空时只比较序号
+func (c *CheckConfig) FindRule(index int, key string) *CheckRule {
+	if c == nil {
+		return nil
+	}
+	for i := range c.Rules {
+		r := &c.Rules[i]
+		if r.Index == index || (key != "" && strings.EqualFold(r.Key, key)) {
+			return r
+		}
+	}
+	return nil
+}
+
+func checkEvalInt(ctx *MsgContext, name string, expr string) (int64, error) {
+	r := ctx.Eval(expr, nil)
+	if r.vm.Error != nil {
+		return 0, fmt.Errorf("%s表达式 %q 出错: %w", name, expr, r.vm.Error)
+	}
+	v, ok := r.ReadInt()
+	if !ok {
+		return 0, fmt.Errorf("%s表达式 %q 的结果不是整数: %s", name, expr, r.ToString())
+	}
+	return int64(v), nil
+}
+
+// Evaluate 按房规判定一次检定，rule 为 nil 时只使用 CheckConfig 中的表达式。检定指令事先标注过技能时计入检定统计
+func (c *CheckConfig) Evaluate(ctx *MsgContext, rule *CheckRule, outcome int64, attrValue int64, difficulty int) (*CheckOutcome, error) {
+	if !c.Enabled() {
+		return nil, errors.New("模板没有声明检定规则")
+	}
+	pick := func(ruleExpr, base string) string {
+		if ruleExpr != "" {
+			return ruleExpr
+		}
+		return base
+	}
+	var ruleRank string
+	critical, fumble, target := c.Critical, c.Fumble, c.Target
+	if rule != nil {
+		critical = pick(rule.Critical, critical)
+		fumble = pick(rule.Fumble, fumble)
+		target = pick(rule.Target, target)
+		ruleRank = rule.Rank
+	}
+
+	ret := &CheckOutcome{Outcome: outcome, Target: attrValue}
+	VarSetValueInt64(ctx, "$t出目", outcome)
+	VarSetValueInt64(ctx, "$t属性值", attrValue)
+	VarSetValueInt64(ctx, "$t难度", int64(difficulty))
+
+	var err error
+	steps := []struct {
+		name string
+		expr string
+		key  string
+		out  *int64
+	}{
+		{"大成功值", critical, "$t大成功值", &ret.Critical},
+		{"判定值", target, "$t判定值", &ret.Target},
+		{"大失败值", fumble, "$t大失败值", &ret.Fumble},
+	}
+	for _, s := range steps {
+		if s.expr != "" {
+			if *s.out, err = checkEvalInt(ctx, s.name, s.expr); err != nil {
+				return nil, err
+			}
+		}
+		VarSetValueInt64(ctx, s.key, *s.out)
+	}
+
+	rank, err := checkEvalInt(ctx, "成功等级", c.Rank)
+	if err != nil {
+		return nil, err
+	}
+	if ruleRank != "" {
+		VarSetValueInt64(ctx, "$t成功等级", rank)
+		if rank, err = checkEvalInt(ctx, "成功等级", ruleRank); err != nil {
+			return nil, err
+		}
+	}
+	ret.Rank = int(rank)
+	VarSetValueInt64(ctx, "$t成功等级", rank)
+	rollStatNote(ctx, outcome, ret.Rank, strings.EqualFold(c.Dice, "d100"))
+	return ret, nil
+}
+
+// TierText 成功等级对应的文本，没有声明时返回空字符串
+func (c *CheckConfig) TierText(ctx *MsgContext, rank int) string {
+	if c == nil {
+		return ""
+	}
+	for _, t := range c.Tiers {
+		if t.Rank != rank {
+			continue
+		}
+		switch {
+		case t.TextKey != "":
+			return DiceFormatTmpl(ctx, t.TextKey)
+		case t.Text != "":
+			text, err := DiceFormatV2(ctx, t.Text)
+			if err != nil {
+				return t.Text
+			}
+			return text
+		default:
+			return t.Name
+		}
+	}
+	return ""
+}
+
+// checkTemplateForExt 提供系统扩展检定规则的模板: 群组当前模板关联该扩展且声明了检定规则时优先使用，否则使用与扩展同名的内置模板
+func checkTemplateForExt(ctx *MsgContext, extName string) *GameSystemTemplate {
+	tmpl := ctx.SystemTemplate
+	if tmpl == nil && ctx.Group != nil && ctx.Dice != nil {
+		tmpl = ctx.Group.GetCharTemplate(ctx.Dice)
+	}
+	if tmpl != nil && tmpl.GameSystemTemplateV2 != nil && tmpl.Commands.Check.Enabled() &&
+		slices.Contains(tmpl.Commands.Set.RelatedExt, extName) {
+		return tmpl
+	}
+	if ctx.Dice == nil || ctx.Dice.GameSystemMap == nil {
+		return nil
+	}
+	if tmpl, _ := ctx.Dice.GameSystemMap.Load(extName); tmpl != nil && tmpl.Commands.Check.Enabled() {
+		return tmpl
+	}
+	return nil
+}
+
+// checkTemplateForCoc 提供 coc7 检定规则的模板
+func checkTemplateForCoc(ctx *MsgContext) *GameSystemTemplate {
+	return checkTemplateForExt(ctx, "coc7")
+}
+
+// checkTemplateCocRules 模板中声明的、内置房规(0-5、dg)以外的 coc 房规
+func checkTemplateCocRules(ctx *MsgContext) []CheckRule {
+	tmpl := checkTemplateForCoc(ctx)
+	if tmpl == nil {
+		return nil
+	}
+	var rules []CheckRule
+	for _, r := range tmpl.Commands.Check.Rules {
+		if _, builtin := SetCocRuleText[r.Index]; !builtin {
+			rules = append(rules, r)
+		}
+	}
+	return rules
+}
+
+var (
+	builtinCocCheckOnce sync.Once
+	builtinCocCheck     *CheckConfig
+)
+
+// checkRuleOverridden 内置房规在模板中的写法是否与内置 coc7 模板不同，相同时可以直接使用 ResultCheckBase
+func checkRuleOverridden(check *CheckConfig, rule *CheckRule) bool {
+	builtinCocCheckOnce.Do(func() {
+		if tmpl, err := loadBuiltinTemplate("coc7.yaml"); err == nil && tmpl.GameSystemTemplateV2 != nil {
+			builtinCocCheck = &tmpl.Commands.Check
+		}
+	})
+	base := builtinCocCheck
+	if base == nil {
+		return true
+	}
+	if check.Critical != base.Critical || check.Fumble != base.Fumble || check.Target != base.Target || check.Rank != base.Rank {
+		return true
+	}
+	baseRule := base.FindRule(rule.Index, "")
+	return baseRule == nil || *baseRule != *rule
+}
+
+// templateResultCheck 使用模板中声明的房规判定，模板没有该房规、未改写内置房规或求值出错时返回 false
+func templateResultCheck(ctx *MsgContext, cocRule int, d100 int64, attrValue int64, difficultyRequired int) (int, int64, bool) {
+	tmpl := checkTemplateForCoc(ctx)
+	if tmpl == nil {
+		return 0, 0, false
+	}
+	check := &tmpl.Commands.Check
+	rule := check.FindRule(cocRule, "")
+	if rule == nil {
+		return 0, 0, false
+	}
+	// 未改写的内置房规与 ResultCheckBase 结果一致，不必逐条求值表达式
+	if _, builtin := SetCocRuleText[cocRule]; builtin && !checkRuleOverridden(check, rule) {
+		return 0, 0, false
+	}
+	ret, err := check.Evaluate(ctx, rule, d100, attrValue, difficultyRequired)
+	if err != nil {
+		if ctx.Dice != nil && ctx.Dice.Logger != nil {
+			ctx.Dice.Logger.Warnf("模板<%s>的检定规则执行出错，使用内置规则: %v", tmpl.Name, err)
+		}
+		return 0, 0, false
+	}
+	return ret.Rank, ret.Critical, true
+}
+
+// checkCommandText 通用检定指令未配置回复文本时的默认格式
+const checkCommandText = `{$t玩家}的"{$t原因}"检定: {$t检定过程} = {$t检定结果} {$t判定结果}`
+
+var checkDCPattern = regexp.MustCompile(`(?i)^(?:dc\s*)?(\d+)\b`)
+
+// checkCutDC 剩余部分以数字或 DC 开头时视为难度值，如 .rk 运动+2 DC15 攀上城墙，返回难度值与去掉难度值后的原因
+func checkCutDC(rest string) (int64, string, bool) {
+	rest = strings.TrimSpace(rest)
+	m := checkDCPattern.FindStringSubmatch(rest)
+	if m == nil {
+		return 0, rest, false
+	}
+	dc, _ := strconv.ParseInt(m[1], 10, 64)
+	return dc, strings.TrimSpace(rest[len(m[0]):]), true
+}
+
+// templateCheckRoll 通用检定: 掷检定骰，与属性表达式的结果一同按模板规则判定
+func templateCheckRoll(ctx *MsgContext, tmpl *GameSystemTemplate, restText string) (string, error) {
+	check := &tmpl.Commands.Check
+	diceExpr := check.Dice
+	if diceExpr == "" {
+		diceExpr = "d" + strconv.FormatInt(getDefaultDicePoints(ctx), 10)
+	}
+
+	tmpl.runInitScript(ctx)
+	r := ctx.Eval(diceExpr, nil)
+	if r.vm.Error != nil {
+		return "", fmt.Errorf("检定骰 %q 出错: %w", diceExpr, r.vm.Error)
+	}
+	outcome, ok := r.ReadInt()
+	if !ok {
+		return "", fmt.Errorf("检定骰 %q 的结果不是整数", diceExpr)
+	}
+	diceDetail := r.vm.GetDetailText()
+	if diceDetail == "" {
+		diceDetail = fmt.Sprintf("%d[%s]", outcome, diceExpr)
+	}
+
+	var attrValue ds.IntType
+	var attrDetail, reason, skill string
+	var dc int64
+	hasDC := false
+	if restText != "" {
+		r2 := ctx.Eval(restText, nil)
+		if r2.vm.Error != nil {
+			return "", fmt.Errorf("无法解析表达式: %s", restText)
+		}
+		if attrValue, ok = r2.ReadInt(); !ok {
+			return "", fmt.Errorf("属性非数字类型，无法用于检定: %s", restText)
+		}
+		attrDetail = r2.vm.GetDetailText()
+		if attrDetail == "" {
+			attrDetail = r2.ToString()
+		}
+
+		dc, reason, hasDC = checkCutDC(r2.vm.RestInput)
+		skill = r2.vm.Matched
+		if reason == "" {
+			reason = strings.TrimSpace(r2.vm.Matched)
+		}
+	}
+	VarSetValueInt64(ctx, "$tDC", dc)
+
+	var rule *CheckRule
+	if ctx.Group != nil {
+		rule = check.Rule(ctx.Group.CocRuleIndex)
+	} else {
+		rule = check.Rule(0)
+	}
+	rollStatLabel(ctx, skill)
+	ret, err := check.Evaluate(ctx, rule, int64(outcome), int64(attrValue), 0)
+	if err != nil {
+		return "", err
+	}
+
+	detail := diceDetail
+	if attrDetail != "" {
+		detail += " + " + attrDetail
+	}
+	VarSetValueStr(ctx, "$t原因", LimitCommandReasonText(reason))
+	VarSetValueStr(ctx, "$t检定过程", detail)
+	VarSetValueInt64(ctx, "$t检定结果", int64(outcome+attrValue))
+	if hasDC {
+		VarSetValueStr(ctx, "$tDC文本", strconv.FormatInt(dc, 10))
+	} else {
+		VarSetValueStr(ctx, "$tDC文本", "")
+	}
+	VarSetValueStr(ctx, "$t判定结果", check.TierText(ctx, ret.Rank))
+	VarSetValueInt64(ctx, "$tSuccessRank", int64(ret.Rank))
+
+	output := check.Output
+	if output == "" {
+		output = checkCommandText
+	}
+	text, err := DiceFormatV2(ctx, output)
+	return strings.TrimSpace(text), err
+}
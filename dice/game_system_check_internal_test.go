@@ -0,0 +1,305 @@
+//nolint:testpackage
+package dice
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func newCheckTestCtx(t *testing.T, d *Dice, ep *EndPointInfo) *MsgContext {
+	t.Helper()
+	msg := newGroupMsg("QQ-Group:300", "QQ:400", ".ra 侦查")
+	ctx := CreateTempCtx(ep, msg)
+	ctx.Dice = d
+	ctx.IsCompatibilityTest = true
+	return ctx
+}
+
+// 内置 coc7 模板中声明的房规应与 ResultCheckBase 完全一致
+func TestCoc7TemplateCheckMatchesBuiltin(t *testing.T) {
+	d, ep, _, cleanup := newExecuteNewTestDice(t)
+	defer cleanup()
+	ctx := newCheckTestCtx(t, d, ep)
+
+	tmpl, _ := d.GameSystemMap.Load("coc7")
+	if tmpl == nil || !tmpl.Commands.Check.Enabled() {
+		t.Fatal("coc7 template should declare check rules")
+	}
+	check := &tmpl.Commands.Check
+
+	// 表达式求值需要经过脚本解析，逐一遍历较慢，这里只取各个房规的边界出目
+	attrs := []int64{0, 4, 5, 25, 49, 50, 96, 100}
+	outcomes := func(attr int64) []int64 {
+		var ret []int64
+		seen := map[int64]bool{}
+		for _, v := range []int64{1, 2, 5, 6, 11, 44, 88, 95, 96, 97, 100, attr, attr + 1, attr / 2, attr/2 + 1, attr / 5, attr/5 + 1} {
+			if v >= 1 && v <= 100 && !seen[v] {
+				seen[v] = true
+				ret = append(ret, v)
+			}
+		}
+		return ret
+	}
+	for _, rule := range []int{0, 1, 2, 3, 4, 5, 11} {
+		r := check.FindRule(rule, "")
+		if r == nil {
+			t.Fatalf("rule %d missing", rule)
+		}
+		difficulties := []int{0}
+		if rule == 3 {
+			difficulties = []int{0, 2, 3, 4}
+		}
+		for _, difficulty := range difficulties {
+			for _, attr := range attrs {
+				for _, d100 := range outcomes(attr) {
+					wantRank, wantCritical := ResultCheckBase(rule, d100, attr, difficulty)
+					got, err := check.Evaluate(ctx, r, d100, attr, difficulty)
+					if err != nil {
+						t.Fatalf("rule %d: %v", rule, err)
+					}
+					if got.Rank != wantRank || got.Critical != wantCritical {
+						t.Fatalf("rule %d difficulty %d attr %d d100 %d: got (%d, %d) want (%d, %d)",
+							rule, difficulty, attr, d100, got.Rank, got.Critical, wantRank, wantCritical)
+					}
+				}
+			}
+		}
+	}
+
+	if r := check.FindRule(-1, "DG"); r == nil || r.Index != 11 {
+		t.Fatal("rule should be found by key")
+	}
+	// 文本模板有多个随机候选，只要命中其中之一即可
+	text := check.TierText(ctx, 4)
+	found := false
+	for _, item := range ctx.Dice.TextMapRaw["COC"]["判定_大成功"] {
+		if item[0] == text {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("unexpected tier text: %q", text)
+	}
+}
+
+const checkTestTemplate = `
+name: pf2e-lite
+fullName: 测试用二十面骰系统
+templateVer: '2.0'
+attrs:
+  defaults:
+    运动: 7
+commands:
+  set:
+    diceSides: '20'
+    keys: [pf2e-lite]
+    relatedExt: [check]
+  check:
+    commands: [rk]
+    dice: d20
+    target: $t出目 + $t属性值
+    rank: >-
+      $t出目 == 20 ? 4,
+      $t出目 == 1 ? -2,
+      $tDC == 0 ? 0,
+      $t判定值 >= $tDC ? 1,
+      1 ? -1
+    tiers:
+      - { rank: 4, name: 天然20 }
+      - { rank: 1, text: '成功(DC{$tDC})' }
+      - { rank: -1, name: 失败 }
+    output: '{$t原因}: {$t检定过程}={$t检定结果} {$t判定结果}'
+`
+
+func TestTemplateCheckCommand(t *testing.T) {
+	d, ep, adapter, cleanup := newExecuteNewTestDice(t)
+	defer cleanup()
+
+	tmpl, err := LoadGameSystemTemplateFromBytes([]byte(checkTestTemplate), "yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !d.GameSystemTemplateAdd(tmpl) {
+		t.Fatal("template should be added")
+	}
+	ext := d.ExtFind("check", false)
+	if ext == nil || ext.CmdMap["rk"] == nil {
+		t.Fatal("check command should be registered from template")
+	}
+
+	ctx := newCheckTestCtx(t, d, ep)
+	ctx.Group.System = "pf2e-lite"
+	ctx.SystemTemplate = tmpl
+
+	// 天然 20 与天然 1 单独判定，其余出目都能达到 DC1
+	for range 20 {
+		text, err := templateCheckRoll(ctx, tmpl, "5 DC1 攀爬")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(text, "攀爬: ") {
+			t.Fatalf("unexpected text: %q", text)
+		}
+		rank, _ := VarGetValueInt64(ctx, "$tSuccessRank")
+		outcome, _ := VarGetValueInt64(ctx, "$t出目")
+		switch outcome {
+		case 20:
+			if rank != 4 || !strings.HasSuffix(text, "天然20") {
+				t.Fatalf("natural 20: %q", text)
+			}
+		case 1:
+			if rank != -2 {
+				t.Fatalf("natural 1: %q", text)
+			}
+		default:
+			if rank != 1 || !strings.HasSuffix(text, "成功(DC1)") {
+				t.Fatalf("should succeed: %q", text)
+			}
+		}
+	}
+
+	send := func(text string) string {
+		d.ImSession.ExecuteNew(ep, newGroupMsg("QQ-Group:301", "QQ:401", text))
+		reply, ok := adapter.waitForMsg(2 * time.Second)
+		if !ok {
+			t.Fatalf("no reply to %q", text)
+		}
+		return reply
+	}
+	send(".bot on")
+	if reply := send(".set pf2e-lite"); !strings.Contains(reply, "pf2e-lite") {
+		t.Fatalf("unexpected set reply: %q", reply)
+	}
+	if reply := send(".rk 5 DC100 攀爬"); !strings.HasPrefix(reply, "攀爬: ") {
+		t.Fatalf("unexpected check reply: %q", reply)
+	}
+	if reply := send(".set coc7"); reply == "" {
+		t.Fatal("set coc7 should reply")
+	}
+	if reply := send(".rk 5"); !strings.Contains(reply, "没有提供 .rk 检定") {
+		t.Fatalf("other systems should not provide rk: %q", reply)
+	}
+}
+
+const checkTestCoc6Template = `
+name: coc6-lite
+fullName: 测试用六版规则
+templateVer: '2.0'
+commands:
+  set:
+    diceSides: '100'
+    keys: [coc6-lite]
+    relatedExt: [coc7]
+  check:
+    dice: d100
+    critical: '5'
+    fumble: '96'
+    rank: $t出目 <= $t大成功值 ? 4, $t出目 >= $t大失败值 ? -2, $t出目 <= $t属性值 ? 1, 1 ? -1
+    rules:
+      - { index: 30, key: coc6, name: 六版, desc: 01-05 大成功，96-00 大失败 }
+`
+
+// 关联 coc7 扩展的模板可以提供内置以外的房规
+func TestTemplateCocRule(t *testing.T) {
+	d, ep, _, cleanup := newExecuteNewTestDice(t)
+	defer cleanup()
+
+	tmpl, err := LoadGameSystemTemplateFromBytes([]byte(checkTestCoc6Template), "yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.GameSystemTemplateAdd(tmpl)
+	ctx := newCheckTestCtx(t, d, ep)
+	ctx.Group.System = "coc6-lite"
+
+	rules := checkTemplateCocRules(ctx)
+	if len(rules) != 1 || rules[0].Key != "coc6" {
+		t.Fatalf("unexpected rules: %+v", rules)
+	}
+	for _, c := range []struct {
+		d100, attr int64
+		rank       int
+	}{{3, 50, 4}, {40, 50, 1}, {60, 50, -1}, {97, 99, -2}} {
+		if rank, _ := ResultCheck(ctx, 30, c.d100, c.attr, 0); rank != c.rank {
+			t.Fatalf("d100 %d attr %d: got %d want %d", c.d100, c.attr, rank, c.rank)
+		}
+	}
+	// 未声明的房规按规则书处理
+	if rank, _ := ResultCheck(ctx, 31, 97, 99, 0); rank != 1 {
+		t.Fatalf("unknown rule should fall back to rule 0, got %d", rank)
+	}
+}
+
+const checkTestCocHouseTemplate = `
+name: coc7-house
+fullName: 测试用改写房规
+templateVer: '2.0'
+commands:
+  set:
+    diceSides: '100'
+    keys: [coc7-house]
+    relatedExt: [coc7]
+  check:
+    dice: d100
+    rank: $t出目 <= $t属性值 ? 1, 1 ? -1
+    rules:
+      - { index: 0, name: '0', desc: 没有大成功与大失败 }
+`
+
+// 未改写的内置房规直接使用 ResultCheckBase，改写后按模板判定
+func TestTemplateCocRuleOverride(t *testing.T) {
+	d, ep, _, cleanup := newExecuteNewTestDice(t)
+	defer cleanup()
+
+	coc7, _ := d.GameSystemMap.Load("coc7")
+	check := &coc7.Commands.Check
+	for i := range check.Rules {
+		if checkRuleOverridden(check, &check.Rules[i]) {
+			t.Fatalf("builtin rule %d should not count as overridden", check.Rules[i].Index)
+		}
+	}
+
+	tmpl, err := LoadGameSystemTemplateFromBytes([]byte(checkTestCocHouseTemplate), "yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.GameSystemTemplateAdd(tmpl)
+	ctx := newCheckTestCtx(t, d, ep)
+	ctx.Group.System = "coc7-house"
+	if rank, _ := ResultCheck(ctx, 0, 100, 99, 0); rank != -1 {
+		t.Fatalf("overridden rule 0 should have no fumble, got %d", rank)
+	}
+	if rank, _ := ResultCheck(ctx, 0, 1, 99, 0); rank != 1 {
+		t.Fatalf("overridden rule 0 should have no critical, got %d", rank)
+	}
+}
+
+// dnd5e 的 .rc 由模板检定规则判定，并使用指令中给出的 DC
+func TestDnd5eRcUsesTemplateCheck(t *testing.T) {
+	d, ep, adapter, cleanup := newExecuteNewTestDice(t)
+	defer cleanup()
+
+	groupID := "QQ-Group:301"
+	send := func(text string) string {
+		d.ImSession.ExecuteNew(ep, newGroupMsg(groupID, "QQ:400", text))
+		reply, ok := adapter.waitForMsg(2 * time.Second)
+		if !ok {
+			t.Fatalf("timeout waiting for reply to %q", text)
+		}
+		return reply
+	}
+	send(".set dnd")
+
+	// 天然 1 以外都能达到 DC1，天然 20 以外都达不到 DC100
+	for range 10 {
+		reply := send(".rc 5 DC1 攀爬")
+		if !strings.Contains(reply, "攀爬") || (!strings.HasSuffix(reply, "成功") && !strings.HasSuffix(reply, "大失败")) {
+			t.Fatalf("unexpected DC1 reply: %q", reply)
+		}
+		reply = send(".rc 5 DC100 攀爬")
+		if !strings.HasSuffix(reply, "失败") && !strings.HasSuffix(reply, "大成功") {
+			t.Fatalf("unexpected DC100 reply: %q", reply)
+		}
+	}
+}
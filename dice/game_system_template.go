@@ -14,6 +14,8 @@ import (
 	"github.com/samber/lo"
 	ds "github.com/sealdice/dicescript"
 	"gopkg.in/yaml.v3"
+
+	"sealdice-core/dice/charimport"
 )
 
 // Attrs keeps attribute defaults and computed expressions.
@@ -58,9 +60,12 @@ func writeAttrsTemplateVersion(attrs *AttributesItem, version string) {
 
 // Commands wraps command-related configuration.
 type Commands struct {
-	Set SetConfig `yaml:"set"`
-	Sn  SnConfig  `yaml:"sn"`
-	St  StConfig  `yaml:"st"`
+	Set    SetConfig                     `yaml:"set"`
+	Sn     SnConfig                      `yaml:"sn"`
+	St     StConfig                      `yaml:"st"`
+	Init   InitConfig                    `yaml:"init"`
+	Import map[string]charimport.Mapping `yaml:"import"` // 外部角色卡格式，键为格式名
+	Check  CheckConfig                   `yaml:"check"`  // 检定规则
 }
 
 // SetConfig configures the set command.
@@ -71,6 +76,19 @@ type SetConfig struct {
 	RelatedExt   []string `yaml:"relatedExt"`
 }
 
+// InitConfig configures the ri/init combat tracker.
+type InitConfig struct {
+	Expr  string `yaml:"expr"`  // 给自己投先攻时使用的表达式，可以读取角色属性，如 d20+敏捷调整值
+	Dice  string `yaml:"dice"`  // 给其他单位投先攻、或使用 +2/优势 等写法时的基础骰，默认 d20
+	HP    string `yaml:"hp"`    // 先攻列表中显示的生命值属性，留空则不显示
+	HPMax string `yaml:"hpMax"` // 生命值上限属性
+}
+
+// Enabled 模板是否声明了先攻列表，声明后 .ri/.init 在使用该模板的群内可用
+func (c *InitConfig) Enabled() bool {
+	return c != nil && (c.Expr != "" || c.Dice != "" || c.HP != "")
+}
+
 // SnConfig configures name templates.
 type SnConfig map[string]SnTemplate
 
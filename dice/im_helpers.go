@@ -593,12 +593,28 @@ func SendFileToGroupRaw(ctx *MsgContext, msg *Message, path string, flag string)
 	ctx.EndPoint.Adapter.SendFileToGroup(ctx, msg.GroupID, path, flag)
 }
 
-func MemberBan(ctx *MsgContext, groupID string, userID string, duration int64) {
+// MemberBan 禁言群成员，平台不支持时返回 false
+func MemberBan(ctx *MsgContext, groupID string, userID string, duration int64) bool {
+	if !ctx.EndPoint.Capabilities().MemberBan {
+		if ctx.Dice != nil {
+			ctx.Dice.Logger.Warnf("当前平台(%s)不支持禁言群成员，已忽略对 %s 的禁言", ctx.EndPoint.Platform, userID)
+		}
+		return false
+	}
 	ctx.EndPoint.Adapter.MemberBan(groupID, userID, duration)
+	return true
 }
 
-func MemberKick(ctx *MsgContext, groupID string, userID string) {
+// MemberKick 踢出群成员，平台不支持时返回 false
+func MemberKick(ctx *MsgContext, groupID string, userID string) bool {
+	if !ctx.EndPoint.Capabilities().MemberKick {
+		if ctx.Dice != nil {
+			ctx.Dice.Logger.Warnf("当前平台(%s)不支持踢出群成员，已忽略对 %s 的踢出", ctx.EndPoint.Platform, userID)
+		}
+		return false
+	}
 	ctx.EndPoint.Adapter.MemberKick(groupID, userID)
+	return true
 }
 
 type ByLength []string
@@ -696,16 +712,9 @@ func spamCheckPerson(ctx *MsgContext, msg *Message) bool {
 		return true
 	}
 
-	if ctx.Player.RateLimitWarned {
-		ctx.Dice.Config.BanList.AddScoreByCommandSpam(ctx.Player.UserID, msg.GroupID, ctx)
-	} else {
-		ctx.Player.RateLimitWarned = true
-		replyToSenderRawNoCheck(
-			ctx, msg,
-			DiceFormatTmpl(ctx, "核心:刷屏_警告内容_个人"),
-			"",
-		)
-	}
+	warned := ctx.Player.RateLimitWarned
+	ctx.Player.RateLimitWarned = true
+	rateLimitExceeded(ctx, msg, "player", ctx.Player.UserID, warned, true, DiceFormatTmpl(ctx, "核心:刷屏_警告内容_个人"))
 
 	return true
 }
@@ -753,16 +762,9 @@ func spamCheckGroup(ctx *MsgContext, msg *Message) bool {
 	}
 
 	// If not allow
-	if ctx.Group.RateLimitWarned {
-		ctx.Dice.Config.BanList.AddScoreByCommandSpam(ctx.Group.GroupID, msg.GroupID, ctx)
-	} else {
-		ctx.Group.RateLimitWarned = true
-		replyToSenderRawNoCheck(
-			ctx, msg,
-			DiceFormatTmpl(ctx, "核心:刷屏_警告内容_群组"),
-			"",
-		)
-	}
+	warned := ctx.Group.RateLimitWarned
+	ctx.Group.RateLimitWarned = true
+	rateLimitExceeded(ctx, msg, "group", ctx.Group.GroupID, warned, true, DiceFormatTmpl(ctx, "核心:刷屏_警告内容_群组"))
 
 	return true
 }
@@ -0,0 +1,55 @@
+package dice
+
+import (
+	"regexp"
+
+	"sealdice-core/message"
+)
+
+// TextToSegments 将旧式的文本消息按平台规则转换为消息段
+// CQ 码会被解析为对应的消息段（不会读取或下载资源），各平台特有的@写法会被转换为 AtElement
+func TextToSegments(text string, platform string) []message.IMessageElement {
+	if platform == "QQ" {
+		// QQ 的@本身就是 CQ 码，文本中被转义的字符需要还原
+		return cqStringToSegments(text)
+	}
+	segs := message.ParseCQCode(text)
+	re := atPatternForPlatform(platform)
+	if re == nil {
+		return segs
+	}
+
+	ret := make([]message.IMessageElement, 0, len(segs))
+	for _, seg := range segs {
+		t, ok := seg.(*message.TextElement)
+		if !ok {
+			ret = append(ret, seg)
+			continue
+		}
+		ret = append(ret, splitTextByAt(t.Content, re)...)
+	}
+	return ret
+}
+
+// splitTextByAt 按@的写法切分一段文本，re 的第一个分组为被@的账号
+func splitTextByAt(text string, re *regexp.Regexp) []message.IMessageElement {
+	var ret []message.IMessageElement
+	last := 0
+	for _, m := range re.FindAllStringSubmatchIndex(text, -1) {
+		if m[0] > last {
+			ret = append(ret, &message.TextElement{Content: text[last:m[0]]})
+		}
+		ret = append(ret, &message.AtElement{Target: text[m[2]:m[3]]})
+		last = m[1]
+	}
+	if last < len(text) {
+		ret = append(ret, &message.TextElement{Content: text[last:]})
+	}
+	return ret
+}
+
+// segmentsToText 将消息段还原为 CQ 码文本，供日志、OnMessageSend 等仍然读取 Message.Message 的地方使用
+func segmentsToText(segs []message.IMessageElement) string {
+	_, text := convertSealMsgToMessageChain(segs)
+	return text
+}
@@ -8,7 +8,6 @@ import (
 	"math/rand"
 	"regexp"
 	"runtime/debug"
-	"sort"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -16,6 +15,7 @@ import (
 
 	"go.uber.org/zap"
 
+	"sealdice-core/dice/censor"
 	"sealdice-core/dice/events"
 	"sealdice-core/dice/service"
 	"sealdice-core/logger"
@@ -56,7 +56,7 @@ type Message struct {
 	GroupName           string      `json:"groupName"`
 	TmpUID              string      `json:"-"             yaml:"-"`
 	UITestReplySplitLen *int        `json:"-"             yaml:"-"`
-	// Note(Szzrain): 这里是消息段，为了支持多种消息类型，目前只有 Milky 支持，其他平台也应该尽快迁移支持，并使用 Session.ExecuteNew 方法
+	// Note(Szzrain): 这里是消息段，主要适配器都会直接填写并使用 Session.ExecuteNew 方法，未迁移的适配器经由 Execute 从 Message 转换得到
 	Segment []message.IMessageElement `jsbind:"segment" json:"-" yaml:"-"`
 }
 
@@ -109,10 +109,16 @@ type GroupInfo struct {
 	TmpPlayerNum int64    `json:"tmpPlayerNum" yaml:"-"`
 	TmpExtList   []string `json:"tmpExtList"   yaml:"-"`
 
-	UpdatedAtTime int64 `json:"-" yaml:"-"`
+	UpdatedAtTime int64  `json:"-" yaml:"-"`
+	Version       int64  `json:"-" yaml:"-"` // 数据库行版本，集群模式下保存时用于检测冲突
+	clusterBase   []byte // 集群模式下上次加载或保存的数据，保存冲突时作为合并的基准
 
 	DefaultHelpGroup string `json:"defaultHelpGroup" yaml:"defaultHelpGroup"` // 当前群默认的帮助条目
 
+	RNG *GroupRNGState `json:"rng,omitempty" yaml:"-"` // 群内随机源设置，为空时使用全局配置
+
+	CensorLevel censor.Level `json:"censorLevel,omitempty" yaml:"-"` // 群内拦截起效级别，低于该级别的敏感词命中不做处理，为 0 时不限制。只能让本群放宽拦截，不能拦截全局设置下不处理的内容
+
 	PlayerGroups      *SyncMap[string, []string] `json:"playerGroups"      yaml:"playerGroups"` // 给team指令使用，和玩家、群等信息一样，都来自Players，不会重复存储
 	ExtAppliedVersion int64                      `json:"extAppliedVersion" yaml:"extAppliedVersion"`
 
@@ -237,7 +243,8 @@ type groupInfoAlias GroupInfo
 // 由于 activatedExtList 是私有字段，需要通过此结构体处理
 type groupInfoJSON struct {
 	*groupInfoAlias
-	ActivatedExtList []*ExtInfo `json:"activatedExtList"`
+	ActivatedExtList []*ExtInfo     `json:"activatedExtList"`
+	RNG              *GroupRNGState `json:"rng,omitempty"` // 覆盖 GroupInfo.RNG，序列化的是持锁取得的快照
 }
 
 // MarshalJSON 自定义序列化，处理私有字段 activatedExtList
@@ -253,9 +260,19 @@ func (g *GroupInfo) MarshalJSON() ([]byte, error) {
 	}
 	g.extInitMu.Unlock()
 
+	// 随机源状态在骰点时会被修改，持同一把锁取快照，避免保存到写了一半的状态
+	var rng *GroupRNGState
+	rngStateLock.Lock()
+	if g.RNG != nil {
+		snapshot := *g.RNG
+		rng = &snapshot
+	}
+	rngStateLock.Unlock()
+
 	return json.Marshal(&groupInfoJSON{
 		groupInfoAlias:   (*groupInfoAlias)(g),
 		ActivatedExtList: filteredList,
+		RNG:              rng,
 	})
 }
 
@@ -270,6 +287,9 @@ func (g *GroupInfo) UnmarshalJSON(data []byte) error {
 	g.extInitMu.Lock()
 	g.activatedExtList = temp.ActivatedExtList
 	g.extInitMu.Unlock()
+	rngStateLock.Lock()
+	g.RNG = temp.RNG
+	rngStateLock.Unlock()
 	return nil
 }
 
@@ -386,6 +406,27 @@ type EndPointInfo struct {
 	Adapter PlatformAdapter `json:"adapter" yaml:"adapter"`
 }
 
+type endPointInfoAlias EndPointInfo
+
+// MarshalJSON 附带适配器能力一起输出，供 UI 判断哪些操作可用
+func (ep *EndPointInfo) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&struct {
+		*endPointInfoAlias
+		Capabilities AdapterCapabilities `json:"capabilities"`
+	}{
+		endPointInfoAlias: (*endPointInfoAlias)(ep),
+		Capabilities:      ep.Capabilities(),
+	})
+}
+
+// Capabilities 返回当前适配器的能力集合，适配器未初始化时视为什么都不支持
+func (ep *EndPointInfo) Capabilities() AdapterCapabilities {
+	if ep == nil || ep.Adapter == nil {
+		return AdapterCapabilities{}
+	}
+	return ep.Adapter.Capabilities()
+}
+
 func (ep *EndPointInfo) UnmarshalYAML(value *yaml.Node) error {
 	if ep.Adapter != nil {
 		return value.Decode(ep)
@@ -678,6 +719,11 @@ type MsgContext struct {
 	splitKey   string
 	vm         *ds.Context
 	_v1Rand    *rand2.PCGSource
+	rngRecord  *RNGRecord // 本条消息随机源的可验证信息，写入日志
+	rngInitial []byte     // 随机源的初始状态，用于判断本条消息是否骰过点
+
+	rollStatLabeled bool   // 随后的一次检定判定计入统计
+	rollStatSkill   string // 该次检定的技能名
 }
 
 // fillPrivilege 填写MsgContext中的权限字段, 并返回填写的权限等级
@@ -724,381 +770,22 @@ func (ctx *MsgContext) fillPrivilege(msg *Message) int {
 	return ctx.PrivilegeLevel
 }
 
+// Execute 旧版的字符串消息入口，仅为兼容尚未迁移的适配器保留
+// 没有消息段的消息会先按平台规则把 Message.Message 转换为消息段，之后与 ExecuteNew 走同一套流程
 func (s *IMSession) Execute(ep *EndPointInfo, msg *Message, runInSync bool) {
-	d := s.Parent
-
-	mctx := &MsgContext{}
-	mctx.Dice = d
-	mctx.MessageType = msg.MessageType
-	mctx.IsPrivate = mctx.MessageType == "private"
-	mctx.Session = s
-	mctx.EndPoint = ep
-	mctx.UITestReplySplitLen = msg.UITestReplySplitLen
-	log := d.Logger
-
-	// 处理命令
-	if msg.MessageType == "group" || msg.MessageType == "private" { //nolint:nestif
-		// GroupEnableCheck TODO: 后续看看是否需要
-		groupInfo, ok := s.ServiceAtNew.Load(msg.GroupID)
-		if !ok && msg.GroupID != "" {
-			// 注意: 此处必须开启，不然下面mctx.player取不到
-			autoOn := true
-			if msg.Platform == "QQ-CH" {
-				autoOn = d.Config.QQChannelAutoOn
-			}
-			groupInfo = SetBotOnAtGroup(mctx, msg.GroupID)
-			groupInfo.Active = autoOn
-			groupInfo.DiceIDExistsMap.Store(ep.UserID, true)
-			if msg.GroupName != "" {
-				groupInfo.GroupName = msg.GroupName
-			}
-			groupInfo.MarkDirty(d) // SetBotOnAtGroup 已调用过一次，这里确保后续修改也被标记
-
-			dm := d.Parent
-			groupName := dm.TryGetGroupName(groupInfo.GroupID)
-
-			txt := fmt.Sprintf("自动激活: 发现无记录群组%s(%s)，因为已是群成员，所以自动激活，开启状态: %t", groupName, groupInfo.GroupID, autoOn)
-			if dm.ShouldRefreshGroupInfo(msg.GroupID) {
-				ep.Adapter.GetGroupInfoAsync(msg.GroupID)
-			}
-			log.Info(txt)
-			mctx.Notice(txt)
-
-			if msg.Platform == "QQ" || msg.Platform == "TG" {
-				// ServiceAtNew changed
-				// Pinenutn:这个i不知道是啥，放你一马（
-				activatedList, _ := mctx.Session.ServiceAtNew.Load(msg.GroupID)
-				if ok {
-					for _, wrapper := range activatedList.GetActivatedExtList(mctx.Dice) {
-						ext := wrapper.GetRealExt()
-						if ext == nil {
-							continue
-						}
-						if ext.OnGroupJoined != nil {
-							ext.callWithJsCheck(mctx.Dice, func() {
-								ext.OnGroupJoined(mctx, msg)
-							})
-						}
-					}
-				}
-			}
-		}
-
-		// 当文本可能是在发送命令时，必须加载信息
-		maybeCommand := CommandCheckPrefix(msg.Message, d.CommandPrefix, msg.Platform)
-
-		amIBeMentioned := false
-		if true {
-			// 被@时，必须加载信息
-			// 这段代码重复了，以后重构
-			_, ats := AtParse(msg.Message, msg.Platform)
-			tmpUID := ep.UserID
-			if msg.TmpUID != "" {
-				tmpUID = msg.TmpUID
-			}
-			for _, i := range ats {
-				// 特殊处理 OpenQQ 和 OpenQQCH
-				if i.UserID == tmpUID {
-					amIBeMentioned = true
-					break
-				} else if strings.HasPrefix(i.UserID, "OpenQQ:") ||
-					strings.HasPrefix(i.UserID, "OpenQQCH:") {
-					uid := strings.TrimPrefix(tmpUID, "OpenQQ:")
-					if i.UserID == "OpenQQ:"+uid || i.UserID == "OpenQQCH:"+uid {
-						amIBeMentioned = true
-						break
-					}
-				}
-			}
-		}
-
-		mctx.Group, mctx.Player = GetPlayerInfoBySender(mctx, msg)
-		mctx.IsCurGroupBotOn = msg.MessageType == "group" && mctx.Group.IsActive(mctx)
-
-		if mctx.Group != nil && mctx.Group.System != "" {
-			mctx.SystemTemplate = mctx.Group.GetCharTemplate(d)
-			// tmpl, _ := d.GameSystemMap.Load(group.System)
-			// mctx.SystemTemplate = tmpl
-		}
-
-		if groupInfo != nil && !strings.HasPrefix(groupInfo.GroupID, "UI-Group:") {
-			// 自动激活存在状态
-			if _, exists := groupInfo.DiceIDExistsMap.Load(ep.UserID); !exists {
-				groupInfo.DiceIDExistsMap.Store(ep.UserID, true)
-				groupInfo.MarkDirty(d)
-			}
-		}
-
-		// 权限号设置
-		_ = mctx.fillPrivilege(msg)
-
-		if mctx.Group != nil && mctx.Group.IsActive(mctx) {
-			if mctx.PrivilegeLevel != -30 {
-				for _, wrapper := range mctx.Group.GetActivatedExtList(mctx.Dice) {
-					ext := wrapper.GetRealExt()
-					if ext == nil {
-						continue
-					}
-					if ext.OnMessageReceived != nil {
-						ext.callWithJsCheck(mctx.Dice, func() {
-							ext.OnMessageReceived(mctx, msg)
-						})
-					}
-				}
-			}
-		}
-
-		var cmdLst []string
-		if maybeCommand {
-			// 兼容模式检查已经移除
-			for k := range d.CmdMap {
-				cmdLst = append(cmdLst, k)
-			}
-			// 这里不用group是为了私聊
-			g := mctx.Group
-			if g != nil {
-				for _, wrapper := range g.GetActivatedExtList(d) {
-					for k := range wrapper.GetCmdMap() {
-						cmdLst = append(cmdLst, k)
-					}
-				}
-			}
-			sort.Sort(ByLength(cmdLst))
-		}
-
-		if notReply := checkBan(mctx, msg); notReply {
-			return
-		}
-
-		platformPrefix := msg.Platform
-		cmdArgs := CommandParse(msg.Message, cmdLst, d.CommandPrefix, platformPrefix, false)
-		if cmdArgs != nil {
-			mctx.CommandID = getNextCommandID()
-
-			var tmpUID string
-			if platformPrefix == "OpenQQCH" {
-				// 特殊处理 OpenQQ频道
-				uid := strings.TrimPrefix(ep.UserID, "OpenQQ:")
-				tmpUID = "OpenQQCH:" + uid
-			} else {
-				tmpUID = ep.UserID
-			}
-			if msg.TmpUID != "" {
-				tmpUID = msg.TmpUID
-			}
-
-			// 设置at信息
-			cmdArgs.SetupAtInfo(tmpUID)
-		}
-
-		// 收到群 test(1111) 内 XX(222) 的消息: 好看 (1232611291)
-		if msg.MessageType == "group" {
-			if mctx.CommandID != 0 {
-				// 关闭状态下，如果被@，且是第一个被@的，那么视为开启
-				if !mctx.IsCurGroupBotOn && cmdArgs.AmIBeMentionedFirst {
-					mctx.IsCurGroupBotOn = true
-				}
-
-				log.Infof("收到群(%s)内<%s>(%s)的指令: %s", msg.GroupID, msg.Sender.Nickname, msg.Sender.UserID, msg.Message)
-			} else {
-				doLog := true
-				if d.Config.OnlyLogCommandInGroup {
-					// 检查上级选项
-					doLog = false
-				}
-				if doLog {
-					// 检查QQ频道的独立选项
-					if msg.Platform == "QQ-CH" && (!d.Config.QQChannelLogMessage) {
-						doLog = false
-					}
-				}
-				if doLog {
-					log.Infof("收到群(%s)内<%s>(%s)的消息: %s", msg.GroupID, msg.Sender.Nickname, msg.Sender.UserID, msg.Message)
-					// fmt.Printf("消息长度 %v 内容 %v \n", len(msg.Message), []byte(msg.Message))
-				}
-			}
-		}
-
-		// 敏感词拦截：全部输入
-		if mctx.IsCurGroupBotOn && d.Config.EnableCensor && d.Config.CensorMode == AllInput {
-			hit, words, needToTerminate, _ := d.CensorMsg(mctx, msg, msg.Message, "")
-			if needToTerminate {
-				return
-			}
-			if hit {
-				text := DiceFormatTmpl(mctx, "核心:拦截_完全拦截_收到的所有消息")
-				if text != "" {
-					ReplyToSender(mctx, msg, text)
-				}
-				if msg.MessageType == "group" {
-					log.Infof(
-						"拒绝处理命中敏感词「%s」的内容「%s」- 来自群(%s)内<%s>(%s)",
-						strings.Join(words, "|"),
-						msg.Message, msg.GroupID, msg.Sender.Nickname, msg.Sender.UserID,
-					)
-				} else {
-					log.Infof(
-						"拒绝处理命中敏感词「%s」的内容「%s」- 来自<%s>(%s)",
-						strings.Join(words, "|"),
-						msg.Message,
-						msg.Sender.Nickname,
-						msg.Sender.UserID,
-					)
-				}
-				return
-			}
-		}
-
-		if msg.MessageType == "private" {
-			if mctx.CommandID != 0 {
-				log.Infof("收到<%s>(%s)的私聊指令: %s", msg.Sender.Nickname, msg.Sender.UserID, msg.Message)
-			} else if !d.Config.OnlyLogCommandInPrivate {
-				log.Infof("收到<%s>(%s)的私聊消息: %s", msg.Sender.Nickname, msg.Sender.UserID, msg.Message)
-			}
-		}
-		// Note(Szzrain): 赋值临时变量，不然有些地方没法用
-		SetTempVars(mctx, msg.Sender.Nickname)
-		if cmdArgs != nil {
-			// 收到信息回调
-			f := func() {
-				defer func() {
-					if r := recover(); r != nil {
-						//  + fmt.Sprintf("%s", r)
-						log.Errorf("异常: %v 堆栈: %v", r, string(debug.Stack()))
-						ReplyToSender(mctx, msg, DiceFormatTmpl(mctx, "核心:骰子执行异常"))
-					}
-				}()
-
-				// 敏感词拦截：命令输入
-				if (msg.MessageType == "private" || mctx.IsCurGroupBotOn) && d.Config.EnableCensor && d.Config.CensorMode == OnlyInputCommand {
-					hit, words, needToTerminate, _ := d.CensorMsg(mctx, msg, msg.Message, "")
-					if needToTerminate {
-						return
-					}
-					if hit {
-						text := DiceFormatTmpl(mctx, "核心:拦截_完全拦截_收到的指令")
-						if text != "" {
-							ReplyToSender(mctx, msg, text)
-						}
-						if msg.MessageType == "group" {
-							log.Infof(
-								"拒绝处理命中敏感词「%s」的指令「%s」- 来自群(%s)内<%s>(%s)",
-								strings.Join(words, "|"),
-								msg.Message,
-								msg.GroupID,
-								msg.Sender.Nickname,
-								msg.Sender.UserID,
-							)
-						} else {
-							log.Infof(
-								"拒绝处理命中敏感词「%s」的指令「%s」- 来自<%s>(%s)",
-								strings.Join(words, "|"),
-								msg.Message,
-								msg.Sender.Nickname,
-								msg.Sender.UserID,
-							)
-						}
-						return
-					}
-				}
-
-				if cmdArgs.Command != "botlist" && !cmdArgs.AmIBeMentioned {
-					myuid := ep.UserID
-					// 屏蔽机器人发送的消息
-					if mctx.MessageType == "group" {
-						// fmt.Println("YYYYYYYYY", myuid, mctx.Group != nil)
-						if mctx.Group.BotList.Exists(msg.Sender.UserID) {
-							log.Infof("忽略指令(机器人): 来自群(%s)内<%s>(%s): %s", msg.GroupID, msg.Sender.Nickname, msg.Sender.UserID, msg.Message)
-							return
-						}
-						// 当其他机器人被@，不回应
-						for _, i := range cmdArgs.At {
-							uid := i.UserID
-							if uid == myuid {
-								// 忽略自己
-								continue
-							}
-							if mctx.Group.BotList.Exists(uid) {
-								return
-							}
-						}
-					}
-				}
-
-				ep.TriggerCommand(mctx, msg, cmdArgs)
-			}
-			if runInSync {
-				f()
-			} else {
-				go f()
-			}
-		} else {
-			if mctx.PrivilegeLevel == -30 {
-				// 黑名单用户
-				return
-			}
-
-			// 试图匹配自定义回复
-			isSenderBot := false
-			if mctx.MessageType == "group" {
-				if mctx.Group != nil && mctx.Group.BotList.Exists(msg.Sender.UserID) {
-					isSenderBot = true
-				}
-			}
-
-			if !isSenderBot {
-				if mctx.Group != nil && (mctx.Group.IsActive(mctx) || amIBeMentioned) {
-					for _, wrapper := range mctx.Group.GetActivatedExtList(mctx.Dice) {
-						ext := wrapper.GetRealExt()
-						if ext == nil {
-							continue
-						}
-						i := ext // 保留引用
-						if i.OnNotCommandReceived != nil {
-							notCommandReceiveCall := func() {
-								if i.IsJsExt {
-									// 先判断运行环境
-									loop, err := d.ExtLoopManager.GetLoop(i.JSLoopVersion)
-									if err != nil {
-										// 打个DEBUG日志？
-										mctx.Dice.Logger.Errorf("扩展<%s>运行环境已经过期: %v", i.Name, err)
-										return
-									}
-									waitRun := make(chan int, 1)
-									loop.RunOnLoop(func(runtime *goja.Runtime) {
-										defer func() {
-											if r := recover(); r != nil {
-												mctx.Dice.Logger.Errorf("扩展<%s>处理非指令消息异常: %v 堆栈: %v", i.Name, r, string(debug.Stack()))
-											}
-											waitRun <- 1
-										}()
-
-										i.OnNotCommandReceived(mctx, msg)
-									})
-									<-waitRun
-								} else {
-									i.OnNotCommandReceived(mctx, msg)
-								}
-							}
-
-							if runInSync {
-								notCommandReceiveCall()
-							} else {
-								go notCommandReceiveCall()
-							}
-						}
-					}
-				}
-			}
-		}
+	if len(msg.Segment) == 0 {
+		msg.Segment = TextToSegments(msg.Message, msg.Platform)
 	}
+	s.execute(ep, msg, runInSync)
 }
 
-// ExecuteNew Note(Szzrain): 既不破坏兼容性还要支持新 feature 我真是草了，这里是 copy paste 的代码稍微改了一下，我知道这是在屎山上建房子，但是没办法
-// 只有在 Adapter 内部实现了新的消息段解析才能使用这个方法，即 Message.Segment 有值
+// ExecuteNew 消息处理入口，适配器需要在 Message.Segment 中填好消息段
 // 为了避免破坏兼容性，Message.Message 中的内容不会被解析但仍然会赋值
-// 这个 ExcuteNew 方法优化了对消息段的解析，其他平台应当尽快实现消息段解析并使用这个方法
 func (s *IMSession) ExecuteNew(ep *EndPointInfo, msg *Message) {
+	s.execute(ep, msg, false)
+}
+
+func (s *IMSession) execute(ep *EndPointInfo, msg *Message, runInSync bool) {
 	d := s.Parent
 
 	mctx := &MsgContext{}
@@ -1140,15 +827,17 @@ func (s *IMSession) ExecuteNew(ep *EndPointInfo, msg *Message) {
 		}
 		groupInfo.MarkDirty(d) // SetBotOnAtGroup 已调用过一次，这里确保后续修改也被标记
 
-		// dm := d.Parent
-		// 愚蠢调用，改了
-		// groupName := dm.TryGetGroupName(group.GroupID)
+		dm := d.Parent
 		groupName := msg.GroupName
+		if groupName == "" {
+			// 消息里不带群名的平台，从缓存里取，顺便异步刷新一下群信息
+			groupName = dm.TryGetGroupName(groupInfo.GroupID)
+			if dm.ShouldRefreshGroupInfo(msg.GroupID) {
+				ep.Adapter.GetGroupInfoAsync(msg.GroupID)
+			}
+		}
 
 		txt := fmt.Sprintf("自动激活: 发现无记录群组%s(%s)，因为已是群成员，所以自动激活，开启状态: %t", groupName, groupInfo.GroupID, autoOn)
-		// 意义不明，删掉
-		// 疑似是为了获取群信息然后塞到奇怪的地方
-		// ep.Adapter.GetGroupInfoAsync(msg.GroupID)
 		log.Info(txt)
 		mctx.Notice(txt)
 
@@ -1176,10 +865,11 @@ func (s *IMSession) ExecuteNew(ep *EndPointInfo, msg *Message) {
 
 	// Note(Szzrain): 判断是否被@
 	amIBeMentioned := false
+	selfUID := selfUIDForMessage(ep, msg)
 	for _, elem := range msg.Segment {
 		// 类型断言
 		if e, ok := elem.(*message.AtElement); ok {
-			if msg.Platform+":"+e.Target == ep.UserID {
+			if msg.Platform+":"+e.Target == selfUID {
 				amIBeMentioned = true
 				break
 			}
@@ -1195,7 +885,7 @@ func (s *IMSession) ExecuteNew(ep *EndPointInfo, msg *Message) {
 		// mctx.SystemTemplate = tmpl
 	}
 
-	if groupInfo != nil {
+	if groupInfo != nil && !strings.HasPrefix(groupInfo.GroupID, "UI-Group:") {
 		// 自动激活存在状态
 		if _, exists := groupInfo.DiceIDExistsMap.Load(ep.UserID); !exists {
 			groupInfo.DiceIDExistsMap.Store(ep.UserID, true)
@@ -1228,25 +918,10 @@ func (s *IMSession) ExecuteNew(ep *EndPointInfo, msg *Message) {
 		return
 	}
 
-	// Note(Szzrain): platformPrefix 弃用
-	// platformPrefix := msg.Platform
+	// 设置at信息已经在 CommandParseNew 里面完成
 	cmdArgs := CommandParseNew(mctx, msg)
 	if cmdArgs != nil {
 		mctx.CommandID = getNextCommandID()
-		// var tmpUID string
-		// if platformPrefix == "OpenQQCH" {
-		//	// 特殊处理 OpenQQ频道
-		//	uid := strings.TrimPrefix(ep.UserID, "OpenQQ:")
-		//	tmpUID = "OpenQQCH:" + uid
-		// } else {
-		//	tmpUID = ep.UserID
-		// }
-		// if msg.TmpUID != "" {
-		//	tmpUID = msg.TmpUID
-		// }
-
-		// 设置at信息，这里不再需要，因为已经在 CommandParseNew 里面设置了
-		// cmdArgs.SetupAtInfo(tmpUID)
 	}
 
 	// 收到群 test(1111) 内 XX(222) 的消息: 好看 (1232611291)
@@ -1320,9 +995,15 @@ func (s *IMSession) ExecuteNew(ep *EndPointInfo, msg *Message) {
 	// Note(Szzrain): 赋值临时变量，不然有些地方没法用
 	SetTempVars(mctx, msg.Sender.Nickname)
 	if cmdArgs != nil {
-		go s.PreTriggerCommand(mctx, msg, cmdArgs)
+		if runInSync {
+			s.PreTriggerCommand(mctx, msg, cmdArgs)
+		} else {
+			go s.PreTriggerCommand(mctx, msg, cmdArgs)
+		}
 	} else {
 		// if cmdArgs == nil will execute this block
+		// 指令由 PreTriggerCommand 收尾，这里只处理自定义回复等非指令消息
+		defer mctx.finishRandSource()
 		if mctx.PrivilegeLevel == -30 {
 			// 黑名单用户
 			return
@@ -1354,6 +1035,7 @@ func (s *IMSession) ExecuteNew(ep *EndPointInfo, msg *Message) {
 									return
 								}
 								waitRun := make(chan int, 1)
+								start := time.Now()
 								loop.RunOnLoop(func(runtime *goja.Runtime) {
 									defer func() {
 										if r := recover(); r != nil {
@@ -1364,12 +1046,17 @@ func (s *IMSession) ExecuteNew(ep *EndPointInfo, msg *Message) {
 									i.OnNotCommandReceived(mctx, msg)
 								})
 								<-waitRun
+								observeJsTask("not_command", start)
 							} else {
 								i.OnNotCommandReceived(mctx, msg)
 							}
 						}
 
-						go notCommandReceiveCall()
+						if runInSync {
+							notCommandReceiveCall()
+						} else {
+							go notCommandReceiveCall()
+						}
 					}
 				}
 			}
@@ -1381,6 +1068,7 @@ func (s *IMSession) PreTriggerCommand(mctx *MsgContext, msg *Message, cmdArgs *C
 	d := s.Parent
 	ep := mctx.EndPoint
 	log := d.Logger
+	defer mctx.finishRandSource()
 	defer func() {
 		if r := recover(); r != nil {
 			//  + fmt.Sprintf("%s", r)
@@ -1885,6 +1573,9 @@ func handleBlacklistedUser(ctx *MsgContext, msg *Message, isWhiteGroup bool, now
 	}
 
 	if d.Config.BanList.BanBehaviorRefuseReply {
+		if tryHandleBlacklistedAppeal(ctx, msg, now) {
+			return true
+		}
 		if tryHandleBlacklistedHelpMasterRequest(ctx, msg, now) {
 			return true
 		}
@@ -1999,6 +1690,10 @@ func (s *IMSession) commandSolve(ctx *MsgContext, msg *Message, cmdArgs *CmdArgs
 			return true
 		}
 
+		if rateLimitPolicyCheck(ctx, msg, ext, item) {
+			return true
+		}
+
 		// Note(Szzrain): TODO: 意义不明，需要想办法干掉
 		if item.EnableExecuteTimesParse {
 			cmdArgs.RevokeExecuteTimesParse(ctx, msg)
@@ -2066,6 +1761,7 @@ func (s *IMSession) commandSolve(ctx *MsgContext, msg *Message, cmdArgs *CmdArgs
 				return false
 			}
 			waitRun := make(chan int, 1)
+			start := time.Now()
 			loop.RunOnLoop(func(vm *goja.Runtime) {
 				defer func() {
 					if r := recover(); r != nil {
@@ -2078,9 +1774,20 @@ func (s *IMSession) commandSolve(ctx *MsgContext, msg *Message, cmdArgs *CmdArgs
 				ret = item.Solve(ctx, msg, cmdArgs)
 			})
 			<-waitRun
+			observeJsTask("command", start)
 		} else {
 			ret = item.Solve(ctx, msg, cmdArgs)
 		}
+		if err := s.Parent.AttrsManager.JournalFlush(ctx); err != nil {
+			s.Parent.Logger.Errorf("写入属性变更记录出错: %v", err)
+		}
+		if ret.Solved {
+			extName := "core"
+			if ext != nil {
+				extName = ext.Name
+			}
+			metricCommands.Inc(item.Name, extName)
+		}
 
 		if ret.Solved {
 			if ret.ShowHelp {
@@ -2579,6 +2286,8 @@ func (ctx *MsgContext) ShallowCopy() *MsgContext {
 		UITestReplySplitLen: ctx.UITestReplySplitLen,
 		vm:                  ctx.vm,
 		_v1Rand:             ctx._v1Rand,
+		rngRecord:           ctx.rngRecord,
+		rngInitial:          ctx.rngInitial,
 	}
 	copyCtx.SetSplitKey(ctx.getSplitKey())
 	return copyCtx
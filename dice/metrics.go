@@ -0,0 +1,92 @@
+package dice
+
+import (
+	"io"
+	"strconv"
+	"time"
+
+	"sealdice-core/dice/censor"
+	"sealdice-core/utils/metrics"
+)
+
+var (
+	metricCommands = metrics.Default.NewCounterVec(
+		"sealdice_commands_total",
+		"成功执行的指令次数，按指令名与所属扩展统计",
+		"command", "ext",
+	)
+	metricJsTaskDuration = metrics.Default.NewHistogramVec(
+		"sealdice_js_task_duration_seconds",
+		"JS 扩展任务在事件循环上的耗时(含排队)，kind 为 command/not_command/event",
+		metrics.DefBuckets,
+		"kind",
+	)
+	metricCensorHits = metrics.Default.NewCounterVec(
+		"sealdice_censor_hits_total",
+		"拦截系统命中次数，按命中的最高级别统计",
+		"level",
+	)
+	metricRateLimitWarnings = metrics.Default.NewCounterVec(
+		"sealdice_rate_limit_warnings_total",
+		"刷屏警告次数，scope 为 player/group",
+		"scope",
+	)
+)
+
+// censorLevelNames 指标中使用的级别名，与词表配置中的键一致
+var censorLevelNames = map[censor.Level]string{
+	censor.Ignore:  "ignore",
+	censor.Notice:  "notice",
+	censor.Caution: "caution",
+	censor.Warning: "warning",
+	censor.Danger:  "danger",
+}
+
+func observeJsTask(kind string, start time.Time) {
+	metricJsTaskDuration.Observe(time.Since(start).Seconds(), kind)
+}
+
+// WriteMetrics 输出全部指标。运行时的状态(如连接状态)在调用时采集
+func (dm *DiceManager) WriteMetrics(w io.Writer, openMetrics bool) error {
+	r := metrics.NewRegistry()
+	state := r.NewGaugeVec("sealdice_endpoint_state", "连接状态: 0断开 1已连接 2连接中 3连接失败", "dice", "id", "platform", "user_id")
+	enabled := r.NewGaugeVec("sealdice_endpoint_enabled", "连接是否启用", "dice", "id", "platform", "user_id")
+	groups := r.NewGaugeVec("sealdice_endpoint_groups", "连接所在的群数", "dice", "id", "platform", "user_id")
+	cmds := r.NewCounterVec("sealdice_endpoint_commands_executed_total", "连接累计执行的指令次数", "dice", "id", "platform", "user_id")
+	online := r.NewCounterVec("sealdice_endpoint_online_seconds_total", "连接累计在线时长(秒)", "dice", "id", "platform", "user_id")
+	dirty := r.NewGaugeVec("sealdice_dirty_groups", "等待保存的群组数量", "dice")
+
+	for _, d := range dm.Dice {
+		if d.ImSession != nil {
+			for _, ep := range d.ImSession.EndPoints {
+				labels := []string{d.BaseConfig.Name, ep.ID, ep.Platform, ep.UserID}
+				state.Set(float64(ep.State), labels...)
+				enabledValue := 0.0
+				if ep.Enable {
+					enabledValue = 1
+				}
+				enabled.Set(enabledValue, labels...)
+				groups.Set(float64(ep.GroupNum), labels...)
+				cmds.Add(float64(ep.CmdExecutedNum), labels...)
+				online.Add(float64(ep.OnlineTotalTime), labels...)
+			}
+		}
+		if d.DirtyGroups != nil {
+			n := 0
+			d.DirtyGroups.Range(func(_ string, _ int64) bool {
+				n++
+				return true
+			})
+			dirty.Set(float64(n), d.BaseConfig.Name)
+		}
+	}
+	return metrics.Write(w, openMetrics, metrics.Default, r)
+}
+
+// censorLevelName 未知级别时回退为数字
+func censorLevelName(level censor.Level) string {
+	if name, ok := censorLevelNames[level]; ok {
+		return name
+	}
+	return strconv.Itoa(int(level))
+}
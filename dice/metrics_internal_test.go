@@ -0,0 +1,56 @@
+//nolint:testpackage
+package dice
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteMetrics(t *testing.T) {
+	d, ep, adapter, cleanup := newExecuteNewTestDice(t)
+	defer cleanup()
+
+	cmdName := d.CmdMap["r"].Name
+	before := metricCommands.Value(cmdName, "core")
+	d.ImSession.ExecuteNew(ep, newPrivateMsg("QQ:999", ".r 1d6"))
+	if _, ok := adapter.waitForMsg(2 * time.Second); !ok {
+		t.Fatal("timeout: expected a reply to '.r 1d6'")
+	}
+	if after := metricCommands.Value(cmdName, "core"); after != before+1 {
+		t.Fatalf("command counter: %v -> %v", before, after)
+	}
+
+	ep.State = StateConnected
+	ep.GroupNum = 3
+	d.DirtyGroups = new(SyncMap[string, int64])
+	d.DirtyGroups.Store("QQ-Group:1", 1)
+	d.DirtyGroups.Store("QQ-Group:2", 1)
+
+	var buf bytes.Buffer
+	if err := d.Parent.WriteMetrics(&buf, false); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	labels := `{dice="test",id="test-ep-1",platform="QQ",user_id="QQ:100000"}`
+	for _, want := range []string{
+		"sealdice_endpoint_state" + labels + " 1\n",
+		"sealdice_endpoint_groups" + labels + " 3\n",
+		`sealdice_dirty_groups{dice="test"} 2` + "\n",
+		`sealdice_commands_total{command="` + cmdName + `",ext="core"}`,
+		"# TYPE sealdice_db_query_duration_seconds histogram\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in output:\n%s", want, out)
+		}
+	}
+
+	buf.Reset()
+	if err := d.Parent.WriteMetrics(&buf, true); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(buf.String(), "# EOF\n") || !strings.Contains(buf.String(), "# TYPE sealdice_commands counter\n") {
+		t.Fatalf("unexpected OpenMetrics output:\n%s", buf.String())
+	}
+}
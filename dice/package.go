@@ -36,3 +36,13 @@ func (e *DependencyError) Error() string {
 	}
 	return b.String()
 }
+
+// DependentsError 仍有已启用的扩展包依赖目标包
+type DependentsError struct {
+	PackageID  string   `json:"packageId"`
+	Dependents []string `json:"dependents"`
+}
+
+func (e *DependentsError) Error() string {
+	return "以下已启用的扩展包依赖 " + e.PackageID + ": " + strings.Join(e.Dependents, ", ") + "，可使用级联操作一并处理"
+}
@@ -12,6 +12,7 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
@@ -37,6 +38,9 @@ type PackageManager struct {
 
 	// 反向依赖图: A -> [B, C] 表示 B 和 C 依赖 A
 	reverseDependencyGraph map[string][]string
+
+	// 查询商店中某个包的全部可用版本，为空时使用 StoreManager
+	storeLookup func(id string) ([]*StorePackage, error)
 }
 
 type packageArtifactCandidate struct {
@@ -66,12 +70,13 @@ type PackageRefreshResult struct {
 }
 
 type PackageUploadPreview struct {
-	Manifest        *sealpack.Manifest `json:"manifest"`
-	Files           []string           `json:"files"`
-	FileCount       int                `json:"fileCount"`
-	ContentCounts   map[string]int     `json:"contentCounts"`
-	ExistingVersion string             `json:"existingVersion,omitempty"`
-	InstallAction   string             `json:"installAction"`
+	Manifest        *sealpack.Manifest  `json:"manifest"`
+	Files           []string            `json:"files"`
+	FileCount       int                 `json:"fileCount"`
+	ContentCounts   map[string]int      `json:"contentCounts"`
+	ExistingVersion string              `json:"existingVersion,omitempty"`
+	InstallAction   string              `json:"installAction"`
+	Plan            *PackageInstallPlan `json:"plan,omitempty"`
 }
 
 // NewPackageManager 创建包管理器
@@ -444,11 +449,23 @@ func (pm *PackageManager) stageExtractPackage(pkgPath, pkgID string) (string, er
 }
 
 func (pm *PackageManager) swapInstallDir(stagedDir, finalDir string) error {
+	backupDir, err := pm.replaceInstallDir(stagedDir, finalDir)
+	if err != nil {
+		return err
+	}
+	if backupDir != "" {
+		_ = os.RemoveAll(backupDir)
+	}
+	return nil
+}
+
+// replaceInstallDir 用 stagedDir 替换 finalDir，原目录被移动到返回的备份路径(不存在时为空)
+func (pm *PackageManager) replaceInstallDir(stagedDir, finalDir string) (string, error) {
 	stagedDir = filepath.Clean(stagedDir)
 	finalDir = filepath.Clean(finalDir)
 	if err := os.MkdirAll(filepath.Dir(finalDir), 0o755); err != nil {
 		_ = os.RemoveAll(stagedDir)
-		return err
+		return "", err
 	}
 
 	backupDir := ""
@@ -456,7 +473,7 @@ func (pm *PackageManager) swapInstallDir(stagedDir, finalDir string) error {
 		backupDir = finalDir + ".bak-" + time.Now().Format("20060102150405.000000000")
 		if err := os.Rename(finalDir, backupDir); err != nil {
 			_ = os.RemoveAll(stagedDir)
-			return err
+			return "", err
 		}
 	}
 
@@ -465,12 +482,9 @@ func (pm *PackageManager) swapInstallDir(stagedDir, finalDir string) error {
 			_ = os.Rename(backupDir, finalDir)
 		}
 		_ = os.RemoveAll(stagedDir)
-		return err
-	}
-	if backupDir != "" {
-		_ = os.RemoveAll(backupDir)
+		return "", err
 	}
-	return nil
+	return backupDir, nil
 }
 
 func findCandidateBySourcePath(candidates []*packageArtifactCandidate, sourcePath string) *packageArtifactCandidate {
@@ -703,29 +717,105 @@ func (pm *PackageManager) Install(pkgPath string) error {
 }
 
 func (pm *PackageManager) installFromSource(pkgPath string) error {
-	pm.lock.Lock()
-	defer pm.lock.Unlock()
-
 	archiveInfo, err := sealpack.InspectArchive(pkgPath)
 	if err != nil {
 		return err
 	}
 	manifest := archiveInfo.Manifest
 	pkgID := manifest.Package.ID
-	newVersion, err := semver.NewVersion(manifest.Package.Version)
-	if err != nil {
-		return err
-	}
-
 	if checkErr := sealpack.CheckSealVersion(manifest, VERSION.String()); checkErr != nil {
 		return checkErr
 	}
-	if satisfied, missing := pm.CheckDependencies(manifest); !satisfied {
+
+	// 解析与下载依赖较慢，在持有锁之前完成；安装时再校验一次依赖是否满足
+	plan, err := pm.ResolveInstallPlan(manifest)
+	if err != nil {
+		return err
+	}
+	if !plan.Resolved() {
 		return &DependencyError{
 			PackageID:   pkgID,
-			MissingDeps: missing,
+			MissingDeps: plan.Errors,
 		}
 	}
+	archives, cleanup, err := pm.downloadPlanArchives(plan)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	pm.lock.Lock()
+	defer pm.lock.Unlock()
+
+	tx := &packageInstallTx{}
+	installed := make([]*sealpack.Manifest, 0, len(plan.Steps))
+	for _, step := range plan.Steps {
+		if step.Action == PackageInstallActionKeep {
+			continue
+		}
+		stepPath, stepManifest := pkgPath, manifest
+		if step.ID != pkgID {
+			stepPath = archives[step.ID]
+			stepInfo, inspectErr := sealpack.InspectArchive(stepPath)
+			if inspectErr != nil {
+				tx.rollback()
+				return fmt.Errorf("安装依赖 %s@%s 失败: %w", step.ID, step.Version, inspectErr)
+			}
+			stepManifest = stepInfo.Manifest
+			if stepManifest.Package.ID != step.ID || stepManifest.Package.Version != step.Version {
+				tx.rollback()
+				return fmt.Errorf("安装依赖 %s@%s 失败: 下载到的是 %s@%s", step.ID, step.Version, stepManifest.Package.ID, stepManifest.Package.Version)
+			}
+		}
+		if installErr := pm.installArchiveLocked(stepPath, stepManifest, tx); installErr != nil {
+			tx.rollback()
+			if step.ID != pkgID {
+				return fmt.Errorf("安装依赖 %s@%s 失败: %w", step.ID, step.Version, installErr)
+			}
+			return installErr
+		}
+		installed = append(installed, stepManifest)
+	}
+
+	// 商店信息可能与实际包内容不一致，全部安装完成后以各包的 manifest 为准再检查一次
+	for _, m := range installed {
+		if satisfied, missing := pm.CheckDependencies(m); !satisfied {
+			tx.rollback()
+			return &DependencyError{
+				PackageID:   m.Package.ID,
+				MissingDeps: missing,
+			}
+		}
+	}
+	tx.commit()
+
+	pm.buildDependencyGraph()
+	if err := pm.saveState(); err != nil {
+		pm.parent.Logger.Warnf("failed to save package state: %v", err)
+	}
+	// 已启用的包升级后可能引入新的依赖，这些依赖也需要启用
+	if pkg := pm.packages[pkgID]; pkg != nil && pkg.State == sealpack.PackageStateEnabled {
+		if err := pm.enableDependenciesLocked(pkgID); err != nil {
+			pm.parent.Logger.Warnf("启用扩展包 %s 的依赖失败: %v", pkgID, err)
+		}
+	}
+
+	for _, m := range installed {
+		pm.parent.Logger.Infof("package %s v%s installed", m.Package.Name, m.Package.Version)
+	}
+	return nil
+}
+
+// installArchiveLocked 安装单个扩展包，变更登记到 tx 中以便失败时回滚
+func (pm *PackageManager) installArchiveLocked(pkgPath string, manifest *sealpack.Manifest, tx *packageInstallTx) error {
+	pkgID := manifest.Package.ID
+	newVersion, err := semver.NewVersion(manifest.Package.Version)
+	if err != nil {
+		return err
+	}
+	if checkErr := sealpack.CheckSealVersion(manifest, VERSION.String()); checkErr != nil {
+		return checkErr
+	}
 
 	destDir := pm.getPackageSourceDir(pkgID)
 	if mkdirErr := os.MkdirAll(destDir, 0o755); mkdirErr != nil {
@@ -762,13 +852,35 @@ func (pm *PackageManager) installFromSource(pkgPath string) error {
 		_ = os.RemoveAll(stagedCachePath)
 		return err
 	}
-	if err := pm.swapInstallDir(stagedCachePath, pm.getPackageInstallPath(pkgID)); err != nil {
+	tx.onRollback(func() {
 		_ = os.Remove(destPkgPath)
 		pm.removeEmptyParents(filepath.Dir(destPkgPath), pm.getSourcePackagesPath())
+	})
+	installPath := pm.getPackageInstallPath(pkgID)
+	backupDir, err := pm.replaceInstallDir(stagedCachePath, installPath)
+	if err != nil {
 		return err
 	}
+	tx.onRollback(func() {
+		_ = os.RemoveAll(installPath)
+		if backupDir != "" {
+			_ = os.Rename(backupDir, installPath)
+		} else {
+			pm.removeEmptyParents(filepath.Dir(installPath), pm.getCachePackagesPath())
+		}
+	})
+	if backupDir != "" {
+		tx.onCommit(func() {
+			_ = os.RemoveAll(backupDir)
+		})
+	}
 
 	userDataPath := pm.getUserDataPath(pkgID)
+	if _, statErr := os.Stat(userDataPath); os.IsNotExist(statErr) {
+		tx.onRollback(func() {
+			_ = os.Remove(userDataPath)
+		})
+	}
 	if err := os.MkdirAll(userDataPath, 0o755); err != nil {
 		return err
 	}
@@ -792,25 +904,28 @@ func (pm *PackageManager) installFromSource(pkgPath string) error {
 		Manifest:      manifest,
 		State:         state,
 		InstallTime:   time.Now(),
-		InstallPath:   pm.getPackageInstallPath(pkgID),
+		InstallPath:   installPath,
 		SourcePath:    destPkgPath,
 		UserDataPath:  userDataPath,
 		Config:        config,
 		SourceStatus:  sealpack.PackageSourceStatusPresent,
 		PendingReload: pendingReload,
 	}
+	tx.onRollback(func() {
+		if existing != nil {
+			pm.packages[pkgID] = existing
+		} else {
+			delete(pm.packages, pkgID)
+		}
+	})
 
 	if existing != nil && existing.SourcePath != "" && !samePackagePath(existing.SourcePath, destPkgPath) {
-		_ = os.Remove(existing.SourcePath)
-		pm.removeEmptyParents(filepath.Dir(existing.SourcePath), pm.getSourcePackagesPath())
-	}
-
-	pm.buildDependencyGraph()
-	if err := pm.saveState(); err != nil {
-		pm.parent.Logger.Warnf("failed to save package state: %v", err)
+		oldSourcePath := existing.SourcePath
+		tx.onCommit(func() {
+			_ = os.Remove(oldSourcePath)
+			pm.removeEmptyParents(filepath.Dir(oldSourcePath), pm.getSourcePackagesPath())
+		})
 	}
-
-	pm.parent.Logger.Infof("package %s v%s installed", manifest.Package.Name, manifest.Package.Version)
 	return nil
 }
 
@@ -950,6 +1065,11 @@ func (pm *PackageManager) previewFromSource(pkgPath string) (*PackageUploadPrevi
 		ContentCounts: contentCounts,
 		InstallAction: "install",
 	}
+	plan, err := pm.ResolveInstallPlan(manifest)
+	if err != nil {
+		return nil, err
+	}
+	preview.Plan = plan
 
 	pm.lock.RLock()
 	defer pm.lock.RUnlock()
@@ -1074,28 +1194,60 @@ func packageVersionOf(pkg *sealpack.Instance) string {
 }
 
 // Uninstall 卸载扩展包
+// 仍有已启用的扩展包依赖它时返回 DependentsError，可改用 UninstallCascade
 func (pm *PackageManager) Uninstall(pkgID string, mode sealpack.UninstallMode) error {
 	pm.lock.Lock()
 	defer pm.lock.Unlock()
 
-	pkg, exists := pm.packages[pkgID]
-	if !exists {
+	if _, exists := pm.packages[pkgID]; !exists {
 		return errors.New("扩展包不存在: " + pkgID)
 	}
 
 	// 检查反向依赖
-	if deps := pm.reverseDependencyGraph[pkgID]; len(deps) > 0 {
-		enabledDeps := make([]string, 0)
-		for _, depID := range deps {
-			if depPkg, ok := pm.packages[depID]; ok && depPkg.State == sealpack.PackageStateEnabled {
-				enabledDeps = append(enabledDeps, depID)
-			}
+	if dependents := pm.enabledDependentsLocked(pkgID); len(dependents) > 0 {
+		return &DependentsError{
+			PackageID:  pkgID,
+			Dependents: dependents,
 		}
-		if len(enabledDeps) > 0 {
-			return errors.New("以下扩展包依赖此包: " + strings.Join(enabledDeps, ", "))
+	}
+
+	if err := pm.uninstallInternal(pkgID, mode); err != nil {
+		return err
+	}
+
+	pm.buildDependencyGraph()
+	return pm.saveState()
+}
+
+// UninstallCascade 卸载扩展包，并以相同模式一并卸载直接或间接依赖它的扩展包
+// 返回被级联卸载的扩展包
+func (pm *PackageManager) UninstallCascade(pkgID string, mode sealpack.UninstallMode) ([]string, error) {
+	pm.lock.Lock()
+	defer pm.lock.Unlock()
+
+	if _, exists := pm.packages[pkgID]; !exists {
+		return nil, errors.New("扩展包不存在: " + pkgID)
+	}
+
+	dependents := pm.collectDependentsLocked(pkgID)
+	for _, depID := range append(dependents, pkgID) {
+		if err := pm.uninstallInternal(depID, mode); err != nil {
+			pm.buildDependencyGraph()
+			_ = pm.saveState()
+			return nil, err
 		}
 	}
 
+	pm.buildDependencyGraph()
+	if err := pm.saveState(); err != nil {
+		return nil, err
+	}
+	return dependents, nil
+}
+
+// uninstallInternal 内部卸载逻辑，调用方负责重建依赖图并保存状态
+func (pm *PackageManager) uninstallInternal(pkgID string, mode sealpack.UninstallMode) error {
+	pkg := pm.packages[pkgID]
 	if pkg.State == sealpack.PackageStateEnabled {
 		if _, err := pm.disableInternal(pkgID); err != nil {
 			return err
@@ -1135,11 +1287,6 @@ func (pm *PackageManager) Uninstall(pkgID string, mode sealpack.UninstallMode) e
 		pkg.State = sealpack.PackageStateDisabled
 	}
 
-	pm.buildDependencyGraph()
-	if err := pm.saveState(); err != nil {
-		return err
-	}
-
 	pm.parent.Logger.Infof("扩展包 %s 已卸载 (模式: %s)", pkgID, mode)
 	return nil
 }
@@ -1178,12 +1325,8 @@ func (pm *PackageManager) Enable(pkgID string) (*sealpack.OperationResult, error
 	}
 
 	// 启用依赖的包
-	for depID := range pkg.Manifest.Dependencies {
-		if depPkg, ok := pm.packages[depID]; ok && depPkg.State != sealpack.PackageStateEnabled {
-			if _, err := pm.enableInternal(depID); err != nil {
-				return nil, errors.New("启用依赖包 " + depID + " 失败: " + err.Error())
-			}
-		}
+	if err := pm.enableDependenciesLocked(pkgID); err != nil {
+		return nil, err
 	}
 
 	result, err := pm.enableInternal(pkgID)
@@ -1194,6 +1337,30 @@ func (pm *PackageManager) Enable(pkgID string) (*sealpack.OperationResult, error
 	return result, nil
 }
 
+// enableDependenciesLocked 按依赖顺序启用 pkgID 的全部传递依赖
+func (pm *PackageManager) enableDependenciesLocked(pkgID string) error {
+	order, err := pm.dependencyOrderLocked(pkgID)
+	if err != nil {
+		return err
+	}
+	for _, depID := range order {
+		depPkg, ok := pm.packages[depID]
+		if !ok || depPkg.Manifest == nil || depPkg.State == sealpack.PackageStateEnabled {
+			continue
+		}
+		if satisfied, missing := pm.CheckDependencies(depPkg.Manifest); !satisfied {
+			return &DependencyError{
+				PackageID:   depID,
+				MissingDeps: missing,
+			}
+		}
+		if _, err := pm.enableInternal(depID); err != nil {
+			return errors.New("启用依赖包 " + depID + " 失败: " + err.Error())
+		}
+	}
+	return nil
+}
+
 // enableInternal 内部启用逻辑
 func (pm *PackageManager) enableInternal(pkgID string) (*sealpack.OperationResult, error) {
 	pkg := pm.packages[pkgID]
@@ -1246,15 +1413,10 @@ func (pm *PackageManager) Disable(pkgID string) (*sealpack.OperationResult, erro
 	}
 
 	// 检查反向依赖
-	if deps := pm.reverseDependencyGraph[pkgID]; len(deps) > 0 {
-		enabledDeps := make([]string, 0)
-		for _, depID := range deps {
-			if depPkg, ok := pm.packages[depID]; ok && depPkg.State == sealpack.PackageStateEnabled {
-				enabledDeps = append(enabledDeps, depID)
-			}
-		}
-		if len(enabledDeps) > 0 {
-			return nil, errors.New("以下已启用的扩展包依赖此包: " + strings.Join(enabledDeps, ", "))
+	if dependents := pm.enabledDependentsLocked(pkgID); len(dependents) > 0 {
+		return nil, &DependentsError{
+			PackageID:  pkgID,
+			Dependents: dependents,
 		}
 	}
 
@@ -1266,6 +1428,49 @@ func (pm *PackageManager) Disable(pkgID string) (*sealpack.OperationResult, erro
 	return result, nil
 }
 
+// DisableCascade 禁用扩展包，并一并禁用直接或间接依赖它且已启用的扩展包
+func (pm *PackageManager) DisableCascade(pkgID string) (*sealpack.OperationResult, error) {
+	pm.lock.Lock()
+	defer pm.lock.Unlock()
+
+	pkg, exists := pm.packages[pkgID]
+	if !exists {
+		return nil, errors.New("扩展包不存在: " + pkgID)
+	}
+
+	dependents := pm.enabledDependentsLocked(pkgID)
+	targets := slices.Clone(dependents)
+	if pkg.State == sealpack.PackageStateEnabled {
+		targets = append(targets, pkgID)
+	}
+	if len(targets) == 0 {
+		return &sealpack.OperationResult{
+			Success:      true,
+			Message:      "扩展包已处于禁用状态",
+			ReloadNeeded: false,
+		}, nil
+	}
+
+	merged := &sealpack.OperationResult{Success: true}
+	for _, id := range targets {
+		result, err := pm.disableInternal(id)
+		if err != nil {
+			return nil, err
+		}
+		merged.ReloadNeeded = merged.ReloadNeeded || result.ReloadNeeded
+		for _, hint := range result.ReloadHints {
+			if !slices.Contains(merged.ReloadHints, hint) {
+				merged.ReloadHints = append(merged.ReloadHints, hint)
+			}
+		}
+	}
+	merged.Message = "扩展包已禁用，需要重载相应资源才能生效"
+	if len(dependents) > 0 {
+		merged.Message = "扩展包已禁用，同时禁用了依赖它的扩展包: " + strings.Join(dependents, ", ") + "，需要重载相应资源才能生效"
+	}
+	return merged, nil
+}
+
 // disableInternal 内部禁用逻辑
 func (pm *PackageManager) disableInternal(pkgID string) (*sealpack.OperationResult, error) {
 	pkg := pm.packages[pkgID]
@@ -0,0 +1,537 @@
+package dice
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+
+	"sealdice-core/dice/sealpack"
+)
+
+const (
+	PackageInstallActionInstall = "install"
+	PackageInstallActionUpgrade = "upgrade"
+	PackageInstallActionKeep    = "keep"
+
+	// 回溯求解的步数上限，防止依赖关系过于复杂时卡住
+	maxPackageResolveSteps = 10000
+)
+
+// PackageInstallStep 安装计划中的一步
+type PackageInstallStep struct {
+	ID          string `json:"id"`
+	Version     string `json:"version"`
+	Action      string `json:"action"` // install, upgrade, keep
+	FromVersion string `json:"fromVersion,omitempty"`
+	// 依赖此包的扩展包及其版本约束
+	Requirements map[string]string `json:"requirements,omitempty"`
+
+	source *StorePackage
+}
+
+// PackageInstallPlan 依赖解析结果，Steps 按安装顺序排列，依赖在前，待安装的包在最后
+type PackageInstallPlan struct {
+	Steps  []*PackageInstallStep `json:"steps"`
+	Errors []string              `json:"errors,omitempty"`
+}
+
+// Resolved 依赖是否全部可以满足
+func (p *PackageInstallPlan) Resolved() bool {
+	return len(p.Errors) == 0
+}
+
+type packageResolveCandidate struct {
+	id           string
+	version      *semver.Version
+	dependencies map[string]string
+	installed    bool
+	source       *StorePackage
+}
+
+type packageRequirement struct {
+	by         string
+	constraint string
+}
+
+type packageResolver struct {
+	root      *packageResolveCandidate
+	installed map[string]*packageResolveCandidate
+	lookup    func(id string) ([]*StorePackage, error)
+
+	available    map[string][]*packageResolveCandidate
+	lookupErrs   map[string]error
+	chosen       map[string]*packageResolveCandidate
+	requirements map[string][]packageRequirement
+	// 当前为各包登记依赖约束的版本，以及被选定版本替换掉的旧版本(撤销选择时恢复)
+	active    map[string]*packageResolveCandidate
+	displaced map[string]*packageResolveCandidate
+
+	failures []string
+	steps    int
+}
+
+// lookupStorePackages 查询扩展商店中某个包的全部可用版本
+func (pm *PackageManager) lookupStorePackages(id string) ([]*StorePackage, error) {
+	if pm.storeLookup != nil {
+		return pm.storeLookup(id)
+	}
+	if pm.parent == nil || pm.parent.StoreManager == nil {
+		return nil, nil
+	}
+	return pm.parent.StoreManager.FindPackageVersions(id)
+}
+
+// ResolveInstallPlan 为 manifest 计算完整的安装计划
+// 传递依赖优先使用已安装且满足约束的版本，否则从扩展商店中选择满足全部约束的最高版本；
+// 已安装的其他扩展包对同一依赖的约束也会参与求解。无法满足时 Errors 中给出原因。
+func (pm *PackageManager) ResolveInstallPlan(manifest *sealpack.Manifest) (*PackageInstallPlan, error) {
+	version, err := semver.NewVersion(manifest.Package.Version)
+	if err != nil {
+		return nil, err
+	}
+	root := &packageResolveCandidate{
+		id:           manifest.Package.ID,
+		version:      version,
+		dependencies: manifest.Dependencies,
+	}
+
+	r := &packageResolver{
+		root:         root,
+		installed:    make(map[string]*packageResolveCandidate),
+		lookup:       pm.lookupStorePackages,
+		available:    make(map[string][]*packageResolveCandidate),
+		lookupErrs:   make(map[string]error),
+		chosen:       make(map[string]*packageResolveCandidate),
+		requirements: make(map[string][]packageRequirement),
+		active:       make(map[string]*packageResolveCandidate),
+		displaced:    make(map[string]*packageResolveCandidate),
+	}
+
+	pm.lock.RLock()
+	for pkgID, pkg := range pm.packages {
+		if pkg == nil || pkg.Manifest == nil {
+			continue
+		}
+		installedVersion, parseErr := semver.NewVersion(pkg.Manifest.Package.Version)
+		if parseErr != nil {
+			continue
+		}
+		c := &packageResolveCandidate{
+			id:           pkgID,
+			version:      installedVersion,
+			dependencies: pkg.Manifest.Dependencies,
+			installed:    true,
+		}
+		r.installed[pkgID] = c
+		// 待安装的包会替换旧版本，旧版本的约束不再生效；其他已安装包被升级时，约束也在 choose 中替换
+		if pkgID != root.id {
+			r.register(c)
+		}
+	}
+	pm.lock.RUnlock()
+
+	return r.resolve(), nil
+}
+
+func (r *packageResolver) resolve() *PackageInstallPlan {
+	plan := &PackageInstallPlan{Steps: []*PackageInstallStep{}}
+	if !r.satisfies(r.root) {
+		plan.Errors = []string{r.conflictMessage(r.root.id, r.root)}
+		return plan
+	}
+
+	if !r.solve(r.choose(r.root)) {
+		plan.Errors = r.failures
+		if len(plan.Errors) == 0 {
+			plan.Errors = []string{"无法解析 " + r.root.id + " 的依赖"}
+		}
+		return plan
+	}
+
+	order, err := sortPackageDependencies(r.root.id, func(id string) []string {
+		if c := r.chosen[id]; c != nil {
+			return sortedDependencyIDs(c.dependencies)
+		}
+		return nil
+	})
+	if err != nil {
+		plan.Errors = []string{err.Error()}
+		return plan
+	}
+
+	for _, id := range order {
+		c := r.chosen[id]
+		step := &PackageInstallStep{
+			ID:      id,
+			Version: c.version.Original(),
+			Action:  PackageInstallActionInstall,
+			source:  c.source,
+		}
+		if reqs := r.requirements[id]; len(reqs) > 0 {
+			step.Requirements = make(map[string]string, len(reqs))
+			for _, req := range reqs {
+				step.Requirements[req.by] = req.constraint
+			}
+		}
+		if current, ok := r.installed[id]; ok {
+			step.FromVersion = current.version.Original()
+			step.Action = PackageInstallActionUpgrade
+			if c.installed {
+				step.Action = PackageInstallActionKeep
+			}
+		}
+		plan.Steps = append(plan.Steps, step)
+	}
+	return plan
+}
+
+// solve 依次为 pending 中的包选定版本，失败时回溯到上一个有其他候选的包
+func (r *packageResolver) solve(pending []string) bool {
+	if len(pending) == 0 {
+		return true
+	}
+	r.steps++
+	if r.steps > maxPackageResolveSteps {
+		r.fail("依赖关系过于复杂，已放弃解析")
+		return false
+	}
+
+	id, rest := pending[0], pending[1:]
+	if c := r.chosen[id]; c != nil {
+		if !r.satisfies(c) {
+			r.fail(r.conflictMessage(id, c))
+			return false
+		}
+		return r.solve(rest)
+	}
+
+	candidates := make([]*packageResolveCandidate, 0)
+	current := r.installed[id]
+	if current != nil {
+		candidates = append(candidates, current)
+		if r.try(current, rest) {
+			return true
+		}
+	}
+	// 已安装的版本不满足约束时才查询商店，只考虑比已安装版本更高的版本
+	for _, c := range r.storeCandidates(id) {
+		if r.steps > maxPackageResolveSteps {
+			return false
+		}
+		if current != nil && !c.version.GreaterThan(current.version) {
+			continue
+		}
+		candidates = append(candidates, c)
+		if r.try(c, rest) {
+			return true
+		}
+	}
+
+	if len(candidates) == 0 {
+		msg := "缺少依赖 " + id + " (" + r.requirementText(id) + ")"
+		if err := r.lookupErrs[id]; err != nil {
+			msg += ", 查询扩展商店失败: " + err.Error()
+		} else {
+			msg += ", 扩展商店中也没有找到"
+		}
+		r.fail(msg)
+		return false
+	}
+	versions := make([]string, len(candidates))
+	for i, c := range candidates {
+		versions[i] = c.version.Original()
+	}
+	r.fail("依赖 " + id + " 没有满足 " + r.requirementText(id) + " 的可用版本 (可选: " + strings.Join(versions, ", ") + ")")
+	return false
+}
+
+// try 选定候选版本后继续求解，失败时撤销选择
+func (r *packageResolver) try(c *packageResolveCandidate, rest []string) bool {
+	if !r.satisfies(c) {
+		return false
+	}
+	added := r.choose(c)
+	next := make([]string, 0, len(rest)+len(added))
+	next = append(next, rest...)
+	next = append(next, added...)
+	if r.solve(next) {
+		return true
+	}
+	r.unchoose(c)
+	return false
+}
+
+func (r *packageResolver) storeCandidates(id string) []*packageResolveCandidate {
+	if cached, ok := r.available[id]; ok {
+		return cached
+	}
+	result := make([]*packageResolveCandidate, 0)
+	packages, err := r.lookup(id)
+	if err != nil {
+		r.lookupErrs[id] = err
+	}
+	seen := make(map[string]bool)
+	for _, pkg := range packages {
+		if pkg == nil || pkg.ID != id || pkg.Download.URL == "" {
+			continue
+		}
+		version, parseErr := semver.NewVersion(pkg.Version)
+		if parseErr != nil || seen[version.String()] {
+			continue
+		}
+		if sealpack.CheckSealVersion(&sealpack.Manifest{Package: sealpack.PackageInfo{Seal: pkg.Seal}}, VERSION.String()) != nil {
+			continue
+		}
+		seen[version.String()] = true
+		result = append(result, &packageResolveCandidate{
+			id:           id,
+			version:      version,
+			dependencies: pkg.Dependencies,
+			source:       pkg,
+		})
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].version.GreaterThan(result[j].version)
+	})
+	r.available[id] = result
+	return result
+}
+
+func (r *packageResolver) satisfies(c *packageResolveCandidate) bool {
+	for _, req := range r.requirements[c.id] {
+		ok, err := sealpack.CheckDependencyConstraint(req.constraint, c.version.String())
+		if err != nil || !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// choose 选定候选版本并登记它的依赖约束，替换同一个包已登记的(已安装版本的)约束，返回新增的待解析包
+func (r *packageResolver) choose(c *packageResolveCandidate) []string {
+	r.chosen[c.id] = c
+	if prev := r.active[c.id]; prev != c {
+		if prev != nil {
+			r.unregister(prev)
+		}
+		r.displaced[c.id] = prev
+		r.register(c)
+	}
+	return sortedDependencyIDs(c.dependencies)
+}
+
+// unchoose 撤销 choose，恢复被替换的约束
+func (r *packageResolver) unchoose(c *packageResolveCandidate) {
+	delete(r.chosen, c.id)
+	prev, ok := r.displaced[c.id]
+	if !ok {
+		return
+	}
+	delete(r.displaced, c.id)
+	r.unregister(c)
+	if prev != nil {
+		r.register(prev)
+	}
+}
+
+func (r *packageResolver) register(c *packageResolveCandidate) {
+	r.active[c.id] = c
+	for _, depID := range sortedDependencyIDs(c.dependencies) {
+		r.requirements[depID] = append(r.requirements[depID], packageRequirement{by: c.id, constraint: c.dependencies[depID]})
+	}
+}
+
+func (r *packageResolver) unregister(c *packageResolveCandidate) {
+	delete(r.active, c.id)
+	for depID := range c.dependencies {
+		reqs := r.requirements[depID]
+		for i := len(reqs) - 1; i >= 0; i-- {
+			if reqs[i].by == c.id {
+				r.requirements[depID] = append(reqs[:i:i], reqs[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+func (r *packageResolver) requirementText(id string) string {
+	reqs := r.requirements[id]
+	parts := make([]string, 0, len(reqs))
+	for _, req := range reqs {
+		parts = append(parts, req.by+" 需要 "+req.constraint)
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, "; ")
+}
+
+func (r *packageResolver) conflictMessage(id string, c *packageResolveCandidate) string {
+	return id + "@" + c.version.Original() + " 不满足约束: " + r.requirementText(id)
+}
+
+func (r *packageResolver) fail(msg string) {
+	for _, existing := range r.failures {
+		if existing == msg {
+			return
+		}
+	}
+	r.failures = append(r.failures, msg)
+}
+
+func sortedDependencyIDs(deps map[string]string) []string {
+	ids := make([]string, 0, len(deps))
+	for id := range deps {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// sortPackageDependencies 按依赖顺序返回 root 及其传递依赖(依赖在前，root 在最后)，存在循环依赖时返回错误
+func sortPackageDependencies(root string, deps func(id string) []string) ([]string, error) {
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := make(map[string]int)
+	order := make([]string, 0)
+	path := make([]string, 0)
+
+	var visit func(id string) error
+	visit = func(id string) error {
+		switch state[id] {
+		case visited:
+			return nil
+		case visiting:
+			start := 0
+			for i, p := range path {
+				if p == id {
+					start = i
+					break
+				}
+			}
+			cycle := append(append([]string{}, path[start:]...), id)
+			return errors.New("检测到循环依赖: " + strings.Join(cycle, " -> "))
+		}
+		state[id] = visiting
+		path = append(path, id)
+		for _, depID := range deps(id) {
+			if err := visit(depID); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[id] = visited
+		order = append(order, id)
+		return nil
+	}
+
+	if err := visit(root); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// packageInstallTx 记录一次安装(含依赖)中的变更，任一步失败时按相反顺序撤销，全部成功后再清理旧文件
+type packageInstallTx struct {
+	undo    []func()
+	cleanup []func()
+}
+
+func (tx *packageInstallTx) onRollback(fn func()) {
+	tx.undo = append(tx.undo, fn)
+}
+
+func (tx *packageInstallTx) onCommit(fn func()) {
+	tx.cleanup = append(tx.cleanup, fn)
+}
+
+func (tx *packageInstallTx) rollback() {
+	for i := len(tx.undo) - 1; i >= 0; i-- {
+		tx.undo[i]()
+	}
+	tx.undo = nil
+	tx.cleanup = nil
+}
+
+func (tx *packageInstallTx) commit() {
+	for _, fn := range tx.cleanup {
+		fn()
+	}
+	tx.undo = nil
+	tx.cleanup = nil
+}
+
+// downloadPlanArchives 下载计划中需要从商店安装的包，返回 包ID -> 临时文件 路径
+func (pm *PackageManager) downloadPlanArchives(plan *PackageInstallPlan) (map[string]string, func(), error) {
+	archives := make(map[string]string)
+	cleanup := func() {
+		for _, path := range archives {
+			_ = os.Remove(path)
+		}
+	}
+	for _, step := range plan.Steps {
+		if step.Action == PackageInstallActionKeep || step.source == nil {
+			continue
+		}
+		path, err := pm.prepareDownloadedPackage(step.source.Download.URL, step.source.Download.Hash, "package_dependency_*.sealpack")
+		if err != nil {
+			cleanup()
+			return nil, nil, fmt.Errorf("下载依赖 %s@%s 失败: %w", step.ID, step.Version, err)
+		}
+		archives[step.ID] = path
+	}
+	return archives, cleanup, nil
+}
+
+// collectDependentsLocked 返回直接或间接依赖 pkgID 的已安装扩展包，依赖链末端的包排在前面
+func (pm *PackageManager) collectDependentsLocked(pkgID string) []string {
+	visited := map[string]bool{pkgID: true}
+	result := make([]string, 0)
+	var visit func(id string)
+	visit = func(id string) {
+		dependents := append([]string{}, pm.reverseDependencyGraph[id]...)
+		sort.Strings(dependents)
+		for _, depID := range dependents {
+			if visited[depID] {
+				continue
+			}
+			visited[depID] = true
+			visit(depID)
+			if _, ok := pm.packages[depID]; ok {
+				result = append(result, depID)
+			}
+		}
+	}
+	visit(pkgID)
+	return result
+}
+
+// enabledDependentsLocked 返回直接或间接依赖 pkgID 且处于启用状态的扩展包
+func (pm *PackageManager) enabledDependentsLocked(pkgID string) []string {
+	enabled := make([]string, 0)
+	for _, depID := range pm.collectDependentsLocked(pkgID) {
+		if pm.packages[depID].State == sealpack.PackageStateEnabled {
+			enabled = append(enabled, depID)
+		}
+	}
+	return enabled
+}
+
+// dependencyOrderLocked 返回 pkgID 的全部传递依赖，依赖在前，不含 pkgID 本身
+func (pm *PackageManager) dependencyOrderLocked(pkgID string) ([]string, error) {
+	order, err := sortPackageDependencies(pkgID, func(id string) []string {
+		pkg, ok := pm.packages[id]
+		if !ok || pkg.Manifest == nil {
+			return nil
+		}
+		return sortedDependencyIDs(pkg.Manifest.Dependencies)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return order[:len(order)-1], nil
+}
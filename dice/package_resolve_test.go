@@ -0,0 +1,356 @@
+package dice //nolint:testpackage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"sealdice-core/dice/sealpack"
+)
+
+func TestPackageManagerInstallResolvesTransitiveDependencies(t *testing.T) {
+	_, pm := newTestPackageManager(t)
+	if err := pm.Init(); err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+	store := newTestPackageStore(t, pm)
+	store.add("lib/base", "1.0.0", nil)
+	store.add("lib/base", "1.2.0", nil)
+	store.add("lib/base", "2.0.0", nil)
+	// mid 1.1.0 需要一个不存在的 base 版本，解析时应回溯到 1.0.0
+	store.add("lib/mid", "1.1.0", map[string]string{"lib/base": "^3.0.0"})
+	store.add("lib/mid", "1.0.0", map[string]string{"lib/base": "^1.0.0"})
+
+	archive := createTestSealPack(t, "", "alice/app", "1.0.0", nil, nil, withDependencies(map[string]string{"lib/mid": "^1.0.0"}))
+	preview, err := pm.Preview(archive)
+	if err != nil {
+		t.Fatalf("Preview() error = %v", err)
+	}
+	if !preview.Plan.Resolved() {
+		t.Fatalf("plan errors = %v", preview.Plan.Errors)
+	}
+	if got := formatInstallPlan(preview.Plan); got != "lib/base@1.2.0 install, lib/mid@1.0.0 install, alice/app@1.0.0 install" {
+		t.Fatalf("plan = %s", got)
+	}
+	if req := preview.Plan.Steps[0].Requirements["lib/mid"]; req != "^1.0.0" {
+		t.Fatalf("base requirements = %#v", preview.Plan.Steps[0].Requirements)
+	}
+
+	if err := pm.Install(archive); err != nil {
+		t.Fatalf("Install() error = %v", err)
+	}
+	for id, version := range map[string]string{"lib/base": "1.2.0", "lib/mid": "1.0.0", "alice/app": "1.0.0"} {
+		pkg, ok := pm.Get(id)
+		if !ok || pkg.Manifest.Package.Version != version {
+			t.Fatalf("package %s = %+v, want version %s", id, pkg, version)
+		}
+	}
+
+	if _, err := pm.Enable("alice/app"); err != nil {
+		t.Fatalf("Enable() error = %v", err)
+	}
+	for _, id := range []string{"lib/base", "lib/mid", "alice/app"} {
+		if pkg, _ := pm.Get(id); pkg.State != sealpack.PackageStateEnabled {
+			t.Fatalf("package %s state = %s, want enabled", id, pkg.State)
+		}
+	}
+
+	// 已安装且满足约束的依赖直接沿用，不会从商店重新安装
+	other := createTestSealPack(t, "", "alice/other", "1.0.0", nil, nil, withDependencies(map[string]string{"lib/base": ">=1.0.0"}))
+	plan := previewPlan(t, pm, other)
+	if got := formatInstallPlan(plan); got != "lib/base@1.2.0 keep, alice/other@1.0.0 install" {
+		t.Fatalf("plan = %s", got)
+	}
+}
+
+func TestPackageManagerResolveInstallPlanReportsConflicts(t *testing.T) {
+	_, pm := newTestPackageManager(t)
+	if err := pm.Init(); err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+	store := newTestPackageStore(t, pm)
+	store.add("lib/base", "1.0.0", nil)
+	store.add("lib/base", "2.0.0", nil)
+	store.add("lib/mid", "1.0.0", map[string]string{"lib/base": "^1.0.0"})
+	store.add("lib/x", "1.0.0", map[string]string{"lib/y": "^1.0.0"})
+	store.add("lib/y", "1.0.0", map[string]string{"lib/x": "^1.0.0"})
+
+	conflict := createTestSealPack(t, "", "alice/conflict", "1.0.0", nil, nil, withDependencies(map[string]string{
+		"lib/base": "^2.0.0",
+		"lib/mid":  "^1.0.0",
+	}))
+	plan := previewPlan(t, pm, conflict)
+	if plan.Resolved() || !strings.Contains(strings.Join(plan.Errors, "\n"), "lib/base") {
+		t.Fatalf("plan errors = %v, want lib/base conflict", plan.Errors)
+	}
+	err := pm.Install(conflict)
+	var depErr *DependencyError
+	if !errors.As(err, &depErr) || depErr.PackageID != "alice/conflict" {
+		t.Fatalf("Install() error = %v, want DependencyError", err)
+	}
+	if len(pm.List()) != 0 {
+		t.Fatalf("packages = %d, want none installed", len(pm.List()))
+	}
+
+	missing := createTestSealPack(t, "", "alice/missing", "1.0.0", nil, nil, withDependencies(map[string]string{"lib/nothing": "*"}))
+	if plan := previewPlan(t, pm, missing); plan.Resolved() || !strings.Contains(plan.Errors[0], "缺少依赖 lib/nothing") {
+		t.Fatalf("plan errors = %v, want missing lib/nothing", plan.Errors)
+	}
+
+	cycle := createTestSealPack(t, "", "alice/cycle", "1.0.0", nil, nil, withDependencies(map[string]string{"lib/x": "^1.0.0"}))
+	plan = previewPlan(t, pm, cycle)
+	if plan.Resolved() || plan.Errors[0] != "检测到循环依赖: lib/x -> lib/y -> lib/x" {
+		t.Fatalf("plan errors = %v, want cycle", plan.Errors)
+	}
+}
+
+func TestPackageManagerResolveUpgradedDependencyDropsOldConstraints(t *testing.T) {
+	_, pm := newTestPackageManager(t)
+	if err := pm.Init(); err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+	for _, pack := range []struct {
+		id   string
+		deps map[string]string
+	}{
+		{"lib/base", nil},
+		{"lib/mid", map[string]string{"lib/base": "^1.0.0"}},
+		{"alice/app", map[string]string{"lib/mid": ">=1.0.0"}},
+	} {
+		if err := pm.Install(createTestSealPack(t, "", pack.id, "1.0.0", nil, nil, withDependencies(pack.deps))); err != nil {
+			t.Fatalf("Install(%s) error = %v", pack.id, err)
+		}
+	}
+	store := newTestPackageStore(t, pm)
+	store.add("lib/base", "2.0.0", nil)
+	// 升级后的 mid 改为依赖 base 2.x，旧版本对 base ^1.0.0 的约束应随之失效
+	store.add("lib/mid", "2.0.0", map[string]string{"lib/base": "^2.0.0"})
+
+	archive := createTestSealPack(t, "", "alice/new", "1.0.0", nil, nil, withDependencies(map[string]string{"lib/mid": "^2.0.0"}))
+	plan := previewPlan(t, pm, archive)
+	if !plan.Resolved() {
+		t.Fatalf("plan errors = %v", plan.Errors)
+	}
+	if got := formatInstallPlan(plan); got != "lib/base@2.0.0 upgrade, lib/mid@2.0.0 upgrade, alice/new@1.0.0 install" {
+		t.Fatalf("plan = %s", got)
+	}
+	if reqs := plan.Steps[0].Requirements; len(reqs) != 1 || reqs["lib/mid"] != "^2.0.0" {
+		t.Fatalf("base requirements = %#v", reqs)
+	}
+}
+
+func TestPackageManagerInstallRollsBackDependencies(t *testing.T) {
+	_, pm := newTestPackageManager(t)
+	if err := pm.Init(); err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+	base := createTestSealPack(t, "", "lib/base", "1.0.0", map[string][]string{
+		"scripts": {"scripts/*.js"},
+	}, map[string]string{"scripts/main.js": "// v1"})
+	if err := pm.Install(base); err != nil {
+		t.Fatalf("Install(base) error = %v", err)
+	}
+
+	store := newTestPackageStore(t, pm)
+	store.add("lib/base", "2.0.0", nil)
+	// 商店声明的版本与包内实际版本不一致，安装到这一步时失败
+	store.addArchive("lib/mid", "1.0.0", map[string]string{"lib/base": "^2.0.0"},
+		createTestSealPack(t, filepath.Join(".", "store-bad"), "lib/mid", "1.0.1", nil, nil))
+
+	archive := createTestSealPack(t, "", "alice/app", "1.0.0", nil, nil, withDependencies(map[string]string{"lib/mid": "^1.0.0"}))
+	if err := pm.Install(archive); err == nil || !strings.Contains(err.Error(), "lib/mid") {
+		t.Fatalf("Install() error = %v, want lib/mid failure", err)
+	}
+
+	pkg, ok := pm.Get("lib/base")
+	if !ok || pkg.Manifest.Package.Version != "1.0.0" {
+		t.Fatalf("base = %+v, want 1.0.0 restored", pkg)
+	}
+	if data, err := os.ReadFile(filepath.Join(pkg.InstallPath, "scripts", "main.js")); err != nil || string(data) != "// v1" {
+		t.Fatalf("base install dir = %q, %v; want v1", data, err)
+	}
+	if _, err := os.Stat(pkg.SourcePath); err != nil {
+		t.Fatalf("base source should be kept: %v", err)
+	}
+	if _, err := os.Stat(pm.getPackageSourcePath("lib/base", "2.0.0")); !os.IsNotExist(err) {
+		t.Fatalf("base 2.0.0 source should be removed, stat err = %v", err)
+	}
+	if _, ok := pm.Get("lib/mid"); ok {
+		t.Fatal("lib/mid should not be installed")
+	}
+	if _, ok := pm.Get("alice/app"); ok {
+		t.Fatal("alice/app should not be installed")
+	}
+	entries, _ := os.ReadDir(filepath.Dir(pkg.InstallPath))
+	if len(entries) != 1 {
+		t.Fatalf("cache dir entries = %d, want only the restored base", len(entries))
+	}
+}
+
+func TestPackageManagerDisableAndUninstallCascade(t *testing.T) {
+	_, pm := newTestPackageManager(t)
+	if err := pm.Init(); err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+	for _, pack := range []struct {
+		id   string
+		deps map[string]string
+	}{
+		{"lib/base", nil},
+		{"lib/mid", map[string]string{"lib/base": "^1.0.0"}},
+		{"alice/app", map[string]string{"lib/mid": "^1.0.0"}},
+	} {
+		if err := pm.Install(createTestSealPack(t, "", pack.id, "1.0.0", nil, nil, withDependencies(pack.deps))); err != nil {
+			t.Fatalf("Install(%s) error = %v", pack.id, err)
+		}
+	}
+	if _, err := pm.Enable("alice/app"); err != nil {
+		t.Fatalf("Enable() error = %v", err)
+	}
+
+	_, err := pm.Disable("lib/base")
+	var dependentsErr *DependentsError
+	if !errors.As(err, &dependentsErr) || strings.Join(dependentsErr.Dependents, ",") != "alice/app,lib/mid" {
+		t.Fatalf("Disable() error = %v, want dependents alice/app,lib/mid", err)
+	}
+	if _, err := pm.DisableCascade("lib/base"); err != nil {
+		t.Fatalf("DisableCascade() error = %v", err)
+	}
+	for _, pkg := range pm.List() {
+		if pkg.State != sealpack.PackageStateDisabled {
+			t.Fatalf("package %s state = %s, want disabled", pkg.Manifest.Package.ID, pkg.State)
+		}
+	}
+
+	if _, err := pm.Enable("alice/app"); err != nil {
+		t.Fatalf("Enable() error = %v", err)
+	}
+	if err := pm.Uninstall("lib/mid", sealpack.UninstallModeFull); !errors.As(err, &dependentsErr) {
+		t.Fatalf("Uninstall() error = %v, want DependentsError", err)
+	}
+	dependents, err := pm.UninstallCascade("lib/mid", sealpack.UninstallModeFull)
+	if err != nil {
+		t.Fatalf("UninstallCascade() error = %v", err)
+	}
+	if strings.Join(dependents, ",") != "alice/app" {
+		t.Fatalf("dependents = %v, want alice/app", dependents)
+	}
+	if list := pm.List(); len(list) != 1 || list[0].Manifest.Package.ID != "lib/base" {
+		t.Fatalf("remaining packages = %d, want only lib/base", len(list))
+	}
+}
+
+func TestSortPackageDependencies(t *testing.T) {
+	graph := map[string][]string{
+		"a": {"b", "c"},
+		"b": {"c"},
+	}
+	order, err := sortPackageDependencies("a", func(id string) []string { return graph[id] })
+	if err != nil || strings.Join(order, ",") != "c,b,a" {
+		t.Fatalf("order = %v, err = %v", order, err)
+	}
+	graph["c"] = []string{"a"}
+	if _, err = sortPackageDependencies("a", func(id string) []string { return graph[id] }); err == nil ||
+		err.Error() != "检测到循环依赖: a -> b -> c -> a" {
+		t.Fatalf("err = %v, want cycle", err)
+	}
+}
+
+func withDependencies(deps map[string]string) manifestOption {
+	return func(b *strings.Builder) {
+		if len(deps) == 0 {
+			return
+		}
+		ids := make([]string, 0, len(deps))
+		for id := range deps {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		b.WriteString("\n[dependencies]\n")
+		for _, id := range ids {
+			fmt.Fprintf(b, "%q = %q\n", id, deps[id])
+		}
+	}
+}
+
+// testPackageStore 模拟扩展商店: 通过 httptest 提供下载，并替换包管理器的商店查询
+type testPackageStore struct {
+	t        *testing.T
+	server   *httptest.Server
+	archives map[string][]byte
+	packages []*StorePackage
+}
+
+func newTestPackageStore(t *testing.T, pm *PackageManager) *testPackageStore {
+	t.Helper()
+	store := &testPackageStore{t: t, archives: make(map[string][]byte)}
+	store.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, ok := store.archives[strings.TrimPrefix(r.URL.Path, "/")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(data)
+	}))
+	t.Cleanup(store.server.Close)
+	pm.storeLookup = func(id string) ([]*StorePackage, error) {
+		result := make([]*StorePackage, 0)
+		for _, pkg := range store.packages {
+			if pkg.ID == id {
+				result = append(result, pkg)
+			}
+		}
+		return result, nil
+	}
+	return store
+}
+
+func (s *testPackageStore) add(id, version string, deps map[string]string) {
+	s.t.Helper()
+	s.addArchive(id, version, deps, createTestSealPack(s.t, filepath.Join(".", "store"), id, version, nil, nil, withDependencies(deps)))
+}
+
+func (s *testPackageStore) addArchive(id, version string, deps map[string]string, archivePath string) {
+	s.t.Helper()
+	data, err := os.ReadFile(archivePath)
+	if err != nil {
+		s.t.Fatalf("ReadFile(%s) error = %v", archivePath, err)
+	}
+	name := BuildStorePackageFullID(id, version)
+	s.archives[name] = data
+	sum := sha256.Sum256(data)
+	s.packages = append(s.packages, &StorePackage{
+		ID:           id,
+		Version:      version,
+		Dependencies: deps,
+		Download: StorePackageDownload{
+			URL:  s.server.URL + "/" + name,
+			Hash: map[string]string{"sha256": hex.EncodeToString(sum[:])},
+		},
+	})
+}
+
+func previewPlan(t *testing.T, pm *PackageManager, archive string) *PackageInstallPlan {
+	t.Helper()
+	preview, err := pm.Preview(archive)
+	if err != nil {
+		t.Fatalf("Preview() error = %v", err)
+	}
+	return preview.Plan
+}
+
+func formatInstallPlan(plan *PackageInstallPlan) string {
+	steps := make([]string, len(plan.Steps))
+	for i, step := range plan.Steps {
+		steps[i] = step.ID + "@" + step.Version + " " + step.Action
+	}
+	return strings.Join(steps, ", ")
+}
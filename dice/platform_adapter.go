@@ -23,14 +23,32 @@ type PlatformAdapter interface {
 
 	GetGroupInfoAsync(groupID string)
 
-	// DeleteFriend 删除好友，目前只有 QQ 平台下的 gocq 和 walleq 实现有这个方法
-	// DeleteFriend(ctx *MsgContext, id string)
-
 	// EditMessage replace the content of the message with msgID with message.
 	// Context is retrieved from ctx.
 	EditMessage(ctx *MsgContext, msgID, message string)
 	// RecallMessage recalls the message with msgID. Context is retrieved from ctx.
 	RecallMessage(ctx *MsgContext, msgID string)
+
+	// Capabilities 返回适配器在当前平台上真正可用的能力。
+	// 上面有不少方法在部分平台是空实现，调用前应先通过这里判断。
+	Capabilities() AdapterCapabilities
+}
+
+// FriendDeleter 删除好友，目前只有 QQ 平台下的 gocq 和 walleq 实现有这个方法
+type FriendDeleter interface {
+	DeleteFriend(ctx *MsgContext, id string)
+}
+
+// AdapterCapabilities 适配器能力集合，会随 EndPointInfo 一起下发给 UI 和 JS
+type AdapterCapabilities struct {
+	EditMessage   bool `jsbind:"editMessage"   json:"editMessage"`   // 编辑已发送的消息
+	RecallMessage bool `jsbind:"recallMessage" json:"recallMessage"` // 撤回消息
+	SendFile      bool `jsbind:"sendFile"      json:"sendFile"`      // 发送文件
+	Segment       bool `jsbind:"segment"       json:"segment"`       // 原生支持消息段收发
+	MemberBan     bool `jsbind:"memberBan"     json:"memberBan"`     // 禁言群成员
+	MemberKick    bool `jsbind:"memberKick"    json:"memberKick"`    // 踢出群成员
+	GuildChannel  bool `jsbind:"guildChannel"  json:"guildChannel"`  // 存在服务器-频道两级结构，如 discord、kook
+	DeleteFriend  bool `jsbind:"deleteFriend"  json:"deleteFriend"`  // 删除好友
 }
 
 // 实现检查
@@ -53,4 +71,7 @@ var (
 	_ PlatformAdapter = (*PlatformAdapterOnebot)(nil)
 
 	// _ PlatformAdapter = (*PlatformAdapterLagrangeGo)(nil)
+
+	_ FriendDeleter = (*PlatformAdapterGocq)(nil)
+	_ FriendDeleter = (*PlatformAdapterWalleQ)(nil)
 )
@@ -161,6 +161,10 @@ func (pa *PlatformAdapterDingTalk) EditMessage(_ *MsgContext, _, _ string) {}
 
 func (pa *PlatformAdapterDingTalk) RecallMessage(_ *MsgContext, _ string) {}
 
+func (pa *PlatformAdapterDingTalk) Capabilities() AdapterCapabilities {
+	return AdapterCapabilities{}
+}
+
 func (pa *PlatformAdapterDingTalk) GetGroupInfoAsync(groupID string) {
 
 }
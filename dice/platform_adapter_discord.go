@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -112,7 +113,7 @@ func (pa *PlatformAdapterDiscord) Serve() int {
 		if errConv != nil {
 			return
 		}
-		pa.Session.Execute(pa.EndPoint, msg, false)
+		pa.Session.ExecuteNew(pa.EndPoint, msg)
 	})
 	dg.AddHandler(func(s *discordgo.Session, m *discordgo.MessageDelete) {
 		ch, errChannel := pa.IntentSession.Channel(m.ChannelID)
@@ -318,9 +319,46 @@ func (pa *PlatformAdapterDiscord) SetEnable(enable bool) {
 }
 
 func (pa *PlatformAdapterDiscord) SendSegmentToGroup(ctx *MsgContext, groupID string, msg []message.IMessageElement, flag string) {
+	resp, err := pa.sendSegmentToChannelRaw(groupID, msg)
+	if err != nil {
+		return
+	}
+	pa.Session.OnMessageSend(ctx, &Message{
+		Platform:    "DISCORD",
+		MessageType: "group",
+		Message:     segmentsToText(msg),
+		Segment:     msg,
+		GroupID:     groupID,
+		Sender: SenderBase{
+			UserID:   pa.EndPoint.UserID,
+			Nickname: pa.EndPoint.Nickname,
+		},
+		RawID: resp.ID,
+	}, flag)
 }
 
 func (pa *PlatformAdapterDiscord) SendSegmentToPerson(ctx *MsgContext, userID string, msg []message.IMessageElement, flag string) {
+	is := pa.IntentSession
+	ch, err := is.UserChannelCreate(ExtractDiscordUserID(userID))
+	if err != nil {
+		pa.Session.Parent.Logger.Errorf("创建Discord用户#%s的私聊频道时出错:%s", userID, err)
+		return
+	}
+	resp, err := pa.sendSegmentToChannelRaw(ch.ID, msg)
+	if err != nil {
+		return
+	}
+	pa.Session.OnMessageSend(ctx, &Message{
+		Platform:    "DISCORD",
+		MessageType: "private",
+		Message:     segmentsToText(msg),
+		Segment:     msg,
+		Sender: SenderBase{
+			UserID:   pa.EndPoint.UserID,
+			Nickname: pa.EndPoint.Nickname,
+		},
+		RawID: resp.ID,
+	}, flag)
 }
 
 // SendToPerson 这里发送的是私聊（dm）消息，私信对于discord来说也被视为一个频道
@@ -441,42 +479,56 @@ func (pa *PlatformAdapterDiscord) sendFileToChannelRaw(channelID string, path st
 }
 
 func (pa *PlatformAdapterDiscord) sendToChannelRaw(channelID string, text string) (*discordgo.Message, error) {
-	logger := pa.Session.Parent.Logger
-	elem := message.ConvertStringMessage(text)
+	return pa.sendSegmentToChannelRaw(channelID, message.ConvertStringMessage(text))
+}
+
+func (pa *PlatformAdapterDiscord) sendSegmentToChannelRaw(channelID string, elem []message.IMessageElement) (*discordgo.Message, error) {
 	id := ExtractDiscordChannelID(channelID)
-	var err error
+	var info *discordgo.Message
+	for _, msgSend := range discordBuildMessageSends(elem, id) {
+		resp, err := pa.IntentSession.ChannelMessageSendComplex(id, msgSend)
+		// pa.Session.Parent.Logger.Infof("真的向Discord频道#%s发送消息:%s", id, msgSend.Content)
+		if err != nil {
+			pa.Session.Parent.Logger.Errorf("向Discord频道#%s发送消息时出错:%s", id, err)
+			return nil, err
+		}
+		if !msgSend.TTS {
+			info = resp
+		}
+	}
+	if info == nil {
+		return nil, errors.New("empty message")
+	}
+	return info, nil
+}
+
+// discordBuildMessageSends 将消息段组装为待发送的 discord 消息，TTS 会打断消息单独发送
+func discordBuildMessageSends(elem []message.IMessageElement, channelID string) []*discordgo.MessageSend {
+	var ret []*discordgo.MessageSend
 	msgSend := &discordgo.MessageSend{Content: ""}
+	isEmpty := func(m *discordgo.MessageSend) bool {
+		return m.Content == "" && m.Files == nil && m.Embeds == nil
+	}
+	appendDescription := func(text string) {
+		if msgSend.Embeds != nil {
+			msgSend.Embeds[len(msgSend.Embeds)-1].Description += text
+		} else {
+			msgSend.Embeds = append(msgSend.Embeds, &discordgo.MessageEmbed{
+				Description: text,
+				Type:        discordgo.EmbedTypeArticle,
+			})
+		}
+	}
 	for _, element := range elem {
 		switch e := element.(type) {
 		case *message.TextElement:
 			// msgSend.Content = msgSend.Content + antiMarkdownFormat(e.Content)
-			if msgSend.Embeds != nil {
-				msgSend.Embeds[len(msgSend.Embeds)-1].Description += antiMarkdownFormat(e.Content)
-			} else {
-				msgSend.Embeds = append(msgSend.Embeds, &discordgo.MessageEmbed{
-					Description: antiMarkdownFormat(e.Content),
-					Type:        discordgo.EmbedTypeArticle,
-				})
-			}
+			appendDescription(antiMarkdownFormat(e.Content))
 		case *message.AtElement:
-			if msgSend.Embeds != nil {
-				if e.Target == "all" {
-					msgSend.Embeds[len(msgSend.Embeds)-1].Description += "@everyone "
-				} else {
-					msgSend.Embeds[len(msgSend.Embeds)-1].Description += fmt.Sprintf("<@%s>", e.Target)
-				}
+			if e.Target == "all" {
+				appendDescription("@everyone ")
 			} else {
-				if e.Target == "all" {
-					msgSend.Embeds = append(msgSend.Embeds, &discordgo.MessageEmbed{
-						Description: "@everyone ",
-						Type:        discordgo.EmbedTypeArticle,
-					})
-				} else {
-					msgSend.Embeds = append(msgSend.Embeds, &discordgo.MessageEmbed{
-						Description: fmt.Sprintf("<@%s>", e.Target),
-						Type:        discordgo.EmbedTypeArticle,
-					})
-				}
+				appendDescription(fmt.Sprintf("<@%s>", e.Target))
 			}
 			// Note(Szzrain): Disabled due to Security Concerns
 		// case *message.FileElement:
@@ -485,55 +537,44 @@ func (pa *PlatformAdapterDiscord) sendToChannelRaw(channelID string, text string
 		//		ContentType: e.ContentType,
 		//		Reader:      e.Stream,
 		//	})
-		// _, err = pa.IntentSession.ChannelMessageSendComplex(id, msgSend)
-		// msgSend = &discordgo.MessageSend{Content: ""}
 		case *message.ImageElement:
-			// var files []*discordgo.File
 			f := e.File
-			msgSend.Files = append(msgSend.Files, &discordgo.File{
-				Name:        f.File,
-				ContentType: f.ContentType,
-				Reader:      f.Stream,
-			})
-			// _, err = pa.IntentSession.ChannelMessageSendComplex(id, msgSend)
-			// msgSend = &discordgo.MessageSend{Content: ""}
-		case *message.TTSElement:
-			if msgSend.Content != "" || msgSend.Files != nil || msgSend.Embeds != nil {
-				_, err = pa.IntentSession.ChannelMessageSendComplex(id, msgSend)
+			if f != nil && f.Stream != nil {
+				msgSend.Files = append(msgSend.Files, &discordgo.File{
+					Name:        f.File,
+					ContentType: f.ContentType,
+					Reader:      f.Stream,
+				})
+				continue
+			}
+			// 没有数据流时直接引用图片地址
+			url := e.URL
+			if url == "" && f != nil {
+				url = f.URL
+			}
+			if url != "" {
+				msgSend.Embeds = append(msgSend.Embeds, &discordgo.MessageEmbed{
+					Type:  discordgo.EmbedTypeImage,
+					Image: &discordgo.MessageEmbedImage{URL: url},
+				})
 			}
-			if err != nil {
-				pa.Session.Parent.Logger.Errorf("向Discord频道#%s发送消息时出错:%s", id, err)
-				break
+		case *message.TTSElement:
+			if !isEmpty(msgSend) {
+				ret = append(ret, msgSend)
 			}
 			msgSend = &discordgo.MessageSend{Content: ""}
-			_, err = pa.IntentSession.ChannelMessageSendComplex(id, &discordgo.MessageSend{
+			ret = append(ret, &discordgo.MessageSend{
 				Content: e.Content,
 				TTS:     true,
 			})
 		case *message.ReplyElement:
-			channel, errChannel := pa.IntentSession.Channel(id)
-			if errChannel != nil {
-				logger.Errorf("获取Discord频道信息#%s时出错:%s", id, errChannel.Error())
-				break
-			}
-			ref := &discordgo.MessageReference{MessageID: e.ReplySeq, ChannelID: id, GuildID: channel.GuildID}
-			msgSend.Reference = ref
-		}
-		if err != nil {
-			pa.Session.Parent.Logger.Errorf("向Discord频道#%s发送消息时出错:%s", id, err)
-			return nil, err
+			msgSend.Reference = &discordgo.MessageReference{MessageID: e.ReplySeq, ChannelID: channelID}
 		}
 	}
-	if msgSend.Content != "" || msgSend.Files != nil || msgSend.Embeds != nil {
-		info, err := pa.IntentSession.ChannelMessageSendComplex(id, msgSend)
-		// pa.Session.Parent.Logger.Infof("真的向Discord频道#%s发送消息:%s", id, msgSend.Content)
-		if err != nil {
-			pa.Session.Parent.Logger.Errorf("向Discord频道#%s发送消息时出错:%s", id, err)
-			return nil, err
-		}
-		return info, err
+	if !isEmpty(msgSend) {
+		ret = append(ret, msgSend)
 	}
-	return nil, errors.New("empty message")
+	return ret
 }
 
 // QuitGroup 退出服务器
@@ -591,6 +632,16 @@ func (pa *PlatformAdapterDiscord) RecallMessage(ctx *MsgContext, msgID string) {
 	_ = pa.IntentSession.ChannelMessageDelete(envID, msgID)
 }
 
+func (pa *PlatformAdapterDiscord) Capabilities() AdapterCapabilities {
+	return AdapterCapabilities{
+		EditMessage:   true,
+		RecallMessage: true,
+		SendFile:      true,
+		GuildChannel:  true,
+		Segment:       true,
+	}
+}
+
 // 下面四个函数是格式化和反格式化的
 
 func FormatDiceIDDiscord(diceDiscord string) string {
@@ -623,6 +674,7 @@ func (pa *PlatformAdapterDiscord) toStdMessage(m *discordgo.MessageCreate) (*Mes
 	msg := new(Message)
 	msg.Time = m.Timestamp.Unix()
 	msg.Message = m.Content
+	msg.Segment = discordMessageToSegments(m.Message)
 	msg.RawID = m.ID
 	msg.Platform = "DISCORD"
 	ch, err := pa.IntentSession.Channel(m.ChannelID)
@@ -647,6 +699,32 @@ func (pa *PlatformAdapterDiscord) toStdMessage(m *discordgo.MessageCreate) (*Mes
 	return msg, nil
 }
 
+var discordMentionPattern = regexp.MustCompile(`<@!?(\d+)>`)
+
+// discordMessageToSegments 将 discord 消息转换为消息段，引用、提及和附件会被转为对应的消息段
+func discordMessageToSegments(m *discordgo.Message) []message.IMessageElement {
+	var segs []message.IMessageElement
+	if m.MessageReference != nil && m.MessageReference.MessageID != "" {
+		reply := &message.ReplyElement{ReplySeq: m.MessageReference.MessageID}
+		if m.ReferencedMessage != nil && m.ReferencedMessage.Author != nil {
+			reply.Sender = m.ReferencedMessage.Author.ID
+		}
+		segs = append(segs, reply)
+	}
+	if m.Content != "" {
+		segs = append(segs, splitTextByAt(m.Content, discordMentionPattern)...)
+	}
+	for _, a := range m.Attachments {
+		file := &message.FileElement{ContentType: a.ContentType, File: a.Filename, URL: a.URL}
+		if strings.HasPrefix(a.ContentType, "image/") {
+			segs = append(segs, &message.ImageElement{File: file, URL: a.URL})
+		} else {
+			segs = append(segs, file)
+		}
+	}
+	return segs
+}
+
 func (pa *PlatformAdapterDiscord) checkIfGuildAdmin(m *discordgo.Message) bool {
 	p, err := pa.IntentSession.State.MessagePermissions(m)
 	// pa.Session.Parent.Logger.Info(m.Author.Username, p)
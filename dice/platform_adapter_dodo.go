@@ -388,6 +388,12 @@ func (pa *PlatformAdapterDodo) EditMessage(_ *MsgContext, _, _ string) {}
 
 func (pa *PlatformAdapterDodo) RecallMessage(_ *MsgContext, _ string) {}
 
+func (pa *PlatformAdapterDodo) Capabilities() AdapterCapabilities {
+	return AdapterCapabilities{
+		GuildChannel: true,
+	}
+}
+
 type DoDoTextMessageComponent struct {
 	Type string `json:"type"` // section
 	Text struct {
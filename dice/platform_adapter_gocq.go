@@ -290,6 +290,27 @@ func hasURLScheme(text string) bool {
 	return match
 }
 
+// cqStringToSegments 将字符串格式上报的消息解析为消息段，文本中被转义的字符会被还原
+func cqStringToSegments(raw string) []message.IMessageElement {
+	segs := message.ParseCQCode(raw)
+	for _, seg := range segs {
+		if t, ok := seg.(*message.TextElement); ok {
+			t.Content = message.UnescapeCQText(t.Content)
+		}
+	}
+	return segs
+}
+
+// gocqEventSegments 从原始上报中取出消息段，同时兼容 array 与 string 两种上报格式
+func gocqEventSegments(raw string) []message.IMessageElement {
+	content := gjson.Get(raw, "message")
+	if content.IsArray() {
+		segs, _ := oneBotArrayToSegments(content.Array())
+		return segs
+	}
+	return cqStringToSegments(content.String())
+}
+
 func tryParseOneBot11ArrayMessage(log *zap.SugaredLogger, message string, writeTo *MessageQQ) error {
 	// 不合法的信息体
 	if !gjson.Valid(message) {
@@ -409,10 +430,13 @@ func OneBot11CqMessageToArrayMessage(longText string) []interface{} {
 	return lo.Reverse(arr) //nolint:staticcheck // old code
 }
 
+// SendSegmentToGroup 消息段会先转为 CQ 码，再按当前的上报格式（string/array）发出
 func (pa *PlatformAdapterGocq) SendSegmentToGroup(ctx *MsgContext, groupID string, msg []message.IMessageElement, flag string) {
+	pa.SendToGroup(ctx, groupID, segmentsToText(msg), flag)
 }
 
 func (pa *PlatformAdapterGocq) SendSegmentToPerson(ctx *MsgContext, userID string, msg []message.IMessageElement, flag string) {
+	pa.SendToPerson(ctx, userID, segmentsToText(msg), flag)
 }
 
 func (pa *PlatformAdapterGocq) Serve() int {
@@ -1079,7 +1103,8 @@ func (pa *PlatformAdapterGocq) Serve() int {
 					msg.Sender.Nickname = "未知用户"
 				}
 			}
-			session.Execute(ep, msg, false)
+			msg.Segment = gocqEventSegments(message)
+			session.ExecuteNew(ep, msg)
 		} else {
 			log.Debug("Received message " + message)
 		}
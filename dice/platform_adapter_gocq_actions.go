@@ -761,6 +761,15 @@ func (pa *PlatformAdapterGocq) EditMessage(_ *MsgContext, _, _ string) {}
 
 func (pa *PlatformAdapterGocq) RecallMessage(_ *MsgContext, _ string) {}
 
+func (pa *PlatformAdapterGocq) Capabilities() AdapterCapabilities {
+	return AdapterCapabilities{
+		SendFile:     true,
+		GuildChannel: true,
+		DeleteFriend: true,
+		Segment:      true,
+	}
+}
+
 func (pa *PlatformAdapterGocq) GetLoginInfo() {
 	a, _ := json.Marshal(struct {
 		Action string `json:"action"`
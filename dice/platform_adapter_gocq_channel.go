@@ -86,7 +86,8 @@ func (pa *PlatformAdapterGocq) QQChannelTrySolve(message string) {
 			}
 
 			// fmt.Println("Recieved message1 " + message)
-			session.Execute(ep, msg, false)
+			msg.Segment = cqStringToSegments(msgQQ.Message)
+			session.ExecuteNew(ep, msg)
 		}
 	}
 	// pa.SendToChannelGroup(ctx, msg.GroupId, msg.Message+"asdasd", "")
@@ -104,3 +104,7 @@ func (pa *PlatformAdapterHTTP) MemberKick(_ string, _ string) {}
 func (pa *PlatformAdapterHTTP) EditMessage(_ *MsgContext, _, _ string) {}
 
 func (pa *PlatformAdapterHTTP) RecallMessage(_ *MsgContext, _ string) {}
+
+func (pa *PlatformAdapterHTTP) Capabilities() AdapterCapabilities {
+	return AdapterCapabilities{}
+}
@@ -200,7 +200,9 @@ func (pa *PlatformAdapterKook) Serve() int {
 			return
 		}
 		if ctx.Common.Type == kook.MessageTypeKMarkdown || ctx.Common.Type == kook.MessageTypeImage {
-			pa.Session.Execute(pa.EndPoint, pa.toStdMessage(ctx), false)
+			if msg := pa.toStdMessage(ctx); msg != nil {
+				pa.Session.ExecuteNew(pa.EndPoint, msg)
+			}
 			return
 		}
 	})
@@ -223,8 +225,11 @@ func (pa *PlatformAdapterKook) Serve() int {
 			// }
 		}
 		msg.Message = fmt.Sprintf("[CQ:image,file=someimage,url=%s]", ctx.Common.Content)
+		msg.Segment = []message.IMessageElement{
+			&message.ImageElement{File: &message.FileElement{URL: ctx.Common.Content}, URL: ctx.Common.Content},
+		}
 		msg.Sender = *send
-		pa.Session.Execute(pa.EndPoint, msg, false)
+		pa.Session.ExecuteNew(pa.EndPoint, msg)
 	})
 	s.AddHandler(func(ctx *kook.MessageDeleteContext) {
 		msg := new(Message)
@@ -410,9 +415,52 @@ func (pa *PlatformAdapterKook) SetEnable(enable bool) {
 }
 
 func (pa *PlatformAdapterKook) SendSegmentToGroup(ctx *MsgContext, groupID string, msg []message.IMessageElement, flag string) {
+	if !pa.EndPoint.Enable || pa.IntentSession == nil || pa.EndPoint.State != 1 {
+		return
+	}
+	resp, err := pa.SendSegmentToChannelRaw(ExtractKookChannelID(groupID), msg, false)
+	if err != nil {
+		return
+	}
+	pa.Session.OnMessageSend(ctx, &Message{
+		Platform:    "KOOK",
+		MessageType: "group",
+		Message:     segmentsToText(msg),
+		Segment:     msg,
+		GroupID:     groupID,
+		Sender: SenderBase{
+			UserID:   pa.EndPoint.UserID,
+			Nickname: pa.EndPoint.Nickname,
+		},
+		RawID: resp.MsgID,
+	}, flag)
 }
 
 func (pa *PlatformAdapterKook) SendSegmentToPerson(ctx *MsgContext, userID string, msg []message.IMessageElement, flag string) {
+	log := zap.S().Named(logger.LogKeyAdapter)
+	if !pa.EndPoint.Enable || pa.IntentSession == nil || pa.EndPoint.State != 1 {
+		return
+	}
+	channel, err := pa.IntentSession.UserChatCreate(ExtractKookUserID(userID))
+	if err != nil {
+		log.Errorf("创建Kook用户#%s的私聊频道时出错:%s", userID, err)
+		return
+	}
+	resp, err := pa.SendSegmentToChannelRaw(channel.Code, msg, true)
+	if err != nil {
+		return
+	}
+	pa.Session.OnMessageSend(ctx, &Message{
+		Platform:    "KOOK",
+		MessageType: "private",
+		Message:     segmentsToText(msg),
+		Segment:     msg,
+		Sender: SenderBase{
+			UserID:   pa.EndPoint.UserID,
+			Nickname: pa.EndPoint.Nickname,
+		},
+		RawID: resp.MsgID,
+	}, flag)
 }
 
 func (pa *PlatformAdapterKook) SendToPerson(ctx *MsgContext, userID string, text string, flag string) {
@@ -542,6 +590,16 @@ func (pa *PlatformAdapterKook) RecallMessage(ctx *MsgContext, msgID string) {
 	_ = pa.IntentSession.MessageDelete(msgID)
 }
 
+func (pa *PlatformAdapterKook) Capabilities() AdapterCapabilities {
+	return AdapterCapabilities{
+		EditMessage:   true,
+		RecallMessage: true,
+		SendFile:      true,
+		GuildChannel:  true,
+		Segment:       true,
+	}
+}
+
 func (pa *PlatformAdapterKook) SendFileToChannelRaw(id string, path string, private bool) (*kook.MessageResp, error) {
 	log := zap.S().Named(logger.LogKeyAdapter)
 	bot := pa.IntentSession
@@ -595,10 +653,33 @@ func (pa *PlatformAdapterKook) SendFileToChannelRaw(id string, path string, priv
 }
 
 func (pa *PlatformAdapterKook) SendToChannelRaw(id string, text string, private bool) (*kook.MessageResp, error) {
+	return pa.SendSegmentToChannelRaw(id, message.ConvertStringMessage(text), private)
+}
+
+func (pa *PlatformAdapterKook) SendSegmentToChannelRaw(id string, elem []message.IMessageElement, private bool) (*kook.MessageResp, error) {
 	log := zap.S().Named(logger.LogKeyAdapter)
 	bot := pa.IntentSession
-	elem := message.ConvertStringMessage(text)
-	// var err error
+	msgb, err := kookBuildCardMessage(elem, func(name string, data []byte) (string, error) {
+		assert, err := bot.AssetCreate(name, data)
+		if err != nil {
+			log.Errorf("Kook创建asserts时出错:%s", err)
+		}
+		return assert, err
+	})
+	if err != nil {
+		log.Errorf("Kook创建card时出错:%s", err)
+		return nil, err
+	}
+	// pa.Session.Parent.Logger.Infof("Kook发送消息:%s", msgb.Content)
+	resp, err := pa.MessageCreateRaw(msgb, id, private)
+	if err != nil {
+		log.Errorf("向Kook频道#%s发送消息时出错:%s", id, err)
+	}
+	return resp, err
+}
+
+// kookBuildCardMessage 将消息段组装为 Kook 卡片消息，upload 用于上传图片并返回资源地址
+func kookBuildCardMessage(elem []message.IMessageElement, upload func(name string, data []byte) (string, error)) (kook.MessageCreateBase, error) {
 	streamToByte := func(stream io.Reader) []byte {
 		buf := new(bytes.Buffer)
 		_, err := buf.ReadFrom(stream)
@@ -630,10 +711,21 @@ func (pa *PlatformAdapterKook) SendToChannelRaw(id string, text string, private
 			}
 			card.Modules = append(card.Modules, cardModule)
 		case *message.ImageElement:
-			assert, err := bot.AssetCreate(e.File.File, streamToByte(e.File.Stream))
-			if err != nil {
-				log.Errorf("Kook创建asserts时出错:%s", err)
-				break
+			var src string
+			switch {
+			case e.File != nil && e.File.Stream != nil:
+				assert, err := upload(e.File.File, streamToByte(e.File.Stream))
+				if err != nil {
+					continue
+				}
+				src = assert
+			case e.URL != "":
+				src = e.URL
+			case e.File != nil:
+				src = e.File.URL
+			}
+			if src == "" {
+				continue
 			}
 			cardModule := CardMessageModuleImage{
 				Type: "container",
@@ -641,7 +733,7 @@ func (pa *PlatformAdapterKook) SendToChannelRaw(id string, text string, private
 			cardModule.Elements = append(cardModule.Elements, struct {
 				Type string `json:"type"`
 				Src  string `json:"src"`
-			}{"image", assert})
+			}{"image", src})
 			card.Modules = append(card.Modules, cardModule)
 		// Disabled due to Security Concerns
 		// case *message.FileElement:
@@ -675,16 +767,10 @@ func (pa *PlatformAdapterKook) SendToChannelRaw(id string, text string, private
 	cardArray := []CardMessage{card}
 	sendText, err := json.Marshal(cardArray)
 	if err != nil {
-		log.Errorf("Kook创建card时出错:%s", err)
-		return nil, err
+		return msgb, err
 	}
 	msgb.Content = string(sendText)
-	// pa.Session.Parent.Logger.Infof("Kook发送消息:%s", msgb.Content)
-	resp, err := pa.MessageCreateRaw(msgb, id, private)
-	if err != nil {
-		log.Errorf("向Kook频道#%s发送消息时出错:%s", id, err)
-	}
-	return resp, err
+	return msgb, nil
 }
 
 func antiMarkdownFormat(text string) string {
@@ -824,6 +910,7 @@ func (pa *PlatformAdapterKook) toStdMessage(ctx *kook.KmarkdownMessageContext) *
 	msg.Message = strings.ReplaceAll(msg.Message, `\[`, "[")
 	msg.Message = strings.ReplaceAll(msg.Message, `\]`, "]")
 	msg.Message = html.UnescapeString(msg.Message)
+	msg.Segment = kookTextToSegments(msg.Message, ctx.Extra.Quote)
 	msg.Platform = "KOOK"
 	send := new(SenderBase)
 	send.UserID = FormatDiceIDKook(ctx.Common.AuthorID)
@@ -842,6 +929,19 @@ func (pa *PlatformAdapterKook) toStdMessage(ctx *kook.KmarkdownMessageContext) *
 	return msg
 }
 
+// kookTextToSegments 将解析后的 kmarkdown 文本转换为消息段，(met)id(met) 转为 AtElement，引用转为 ReplyElement
+func kookTextToSegments(text string, quote *kook.Quote) []message.IMessageElement {
+	var segs []message.IMessageElement
+	if quote != nil && quote.ID != "" {
+		reply := &message.ReplyElement{ReplySeq: quote.ID}
+		if quote.Author != nil {
+			reply.Sender = quote.Author.ID
+		}
+		segs = append(segs, reply)
+	}
+	return append(segs, splitTextByAt(text, atPatternForPlatform("KOOK"))...)
+}
+
 func (pa *PlatformAdapterKook) checkIfGuildAdmin(ctx *kook.KmarkdownMessageContext) bool {
 	user, err := pa.IntentSession.UserView(ctx.Common.AuthorID, kook.UserViewWithGuildID(ctx.Extra.GuildID))
 	if err != nil {
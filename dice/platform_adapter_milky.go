@@ -919,6 +919,13 @@ func (pa *PlatformAdapterMilky) EditMessage(_ *MsgContext, _, _ string) {}
 
 func (pa *PlatformAdapterMilky) RecallMessage(_ *MsgContext, _ string) {}
 
+func (pa *PlatformAdapterMilky) Capabilities() AdapterCapabilities {
+	return AdapterCapabilities{
+		SendFile: true,
+		Segment:  true,
+	}
+}
+
 func ExtractQQUserID(id string) string {
 	if strings.HasPrefix(id, "QQ:") {
 		return id[len("QQ:"):]
@@ -267,3 +267,7 @@ func (pa *PlatformAdapterMinecraft) SetGroupCardName(_ *MsgContext, _ string) {}
 func (pa *PlatformAdapterMinecraft) EditMessage(_ *MsgContext, _, _ string) {}
 
 func (pa *PlatformAdapterMinecraft) RecallMessage(_ *MsgContext, _ string) {}
+
+func (pa *PlatformAdapterMinecraft) Capabilities() AdapterCapabilities {
+	return AdapterCapabilities{}
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"path/filepath"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -157,7 +158,7 @@ func (pa *PlatformAdapterOfficialQQ) ChannelAtMessageReceive(event *dto.WSPayloa
 	log := s.Parent.Logger
 	log.Debugf("official qq: 收到文字频道消息：%v, %v", event, data)
 
-	s.Execute(pa.EndPoint, pa.channelMsgToStdMsg(data), false)
+	s.ExecuteNew(pa.EndPoint, pa.channelMsgToStdMsg(data))
 	return nil
 }
 
@@ -167,6 +168,7 @@ func (pa *PlatformAdapterOfficialQQ) channelMsgToStdMsg(msgQQ *dto.WSATMessageDa
 	msg.Time = timestamp.Unix()
 	msg.MessageType = "group"
 	msg.Message = msgQQ.Content
+	msg.Segment = officialQQMessageToSegments((*dto.Message)(msgQQ))
 	msg.RawID = msgQQ.ID
 	msg.Platform = "OpenQQCH"
 	msg.GuildID = formatDiceIDOfficialQQChGuild(msgQQ.GuildID)
@@ -185,7 +187,7 @@ func (pa *PlatformAdapterOfficialQQ) GuildDirectMessageReceive(event *dto.WSPayl
 	log := s.Parent.Logger
 	log.Debugf("official qq: 收到频道私信消息：%v, %v", event, data)
 
-	s.Execute(pa.EndPoint, pa.guildDirectMsgToStdMsg(data), false)
+	s.ExecuteNew(pa.EndPoint, pa.guildDirectMsgToStdMsg(data))
 	return nil
 }
 
@@ -195,6 +197,7 @@ func (pa *PlatformAdapterOfficialQQ) guildDirectMsgToStdMsg(msgQQ *dto.WSDirectM
 	msg.Time = timestamp.Unix()
 	msg.MessageType = "private"
 	msg.Message = msgQQ.Content
+	msg.Segment = officialQQMessageToSegments((*dto.Message)(msgQQ))
 	msg.RawID = msgQQ.ID
 	msg.Platform = "OpenQQCH"
 	// 频道私信需要私信频道的 guild_id 和 channel_id
@@ -213,7 +216,7 @@ func (pa *PlatformAdapterOfficialQQ) GroupAtMessageReceive(event *dto.WSPayload,
 	log := s.Parent.Logger
 	log.Debugf("official qq: 收到群聊消息：%v, %v", event, data)
 
-	s.Execute(pa.EndPoint, pa.groupMsgToStdMsg(data), false)
+	s.ExecuteNew(pa.EndPoint, pa.groupMsgToStdMsg(data))
 	return nil
 }
 
@@ -224,6 +227,7 @@ func (pa *PlatformAdapterOfficialQQ) groupMsgToStdMsg(msgQQ *dto.WSGroupATMessag
 	msg.Time = timestamp.Unix()
 	msg.MessageType = "group"
 	msg.Message = msgQQ.Content
+	msg.Segment = officialQQMessageToSegments((*dto.Message)(msgQQ))
 	msg.RawID = msgQQ.ID
 	msg.Platform = "OpenQQ"
 	msg.GroupID = formatDiceIDOfficialQQGroupOpenID(appID, msgQQ.GroupOpenID)
@@ -234,6 +238,37 @@ func (pa *PlatformAdapterOfficialQQ) groupMsgToStdMsg(msgQQ *dto.WSGroupATMessag
 	}
 	return msg
 }
+
+var officialQQMentionPattern = regexp.MustCompile(`<@!?(\S+?)>`)
+
+// officialQQMessageToSegments 将官方接口的消息转换为消息段，引用、提及和附件会被转为对应的消息段
+func officialQQMessageToSegments(m *dto.Message) []message.IMessageElement {
+	var segs []message.IMessageElement
+	if m.MessageReference != nil && m.MessageReference.MessageID != "" {
+		segs = append(segs, &message.ReplyElement{ReplySeq: m.MessageReference.MessageID})
+	}
+	if m.Content != "" {
+		segs = append(segs, splitTextByAt(m.Content, officialQQMentionPattern)...)
+	}
+	for _, a := range m.Attachments {
+		url := a.URL
+		// 附件地址有时不带协议头
+		if url != "" && !strings.Contains(url, "://") {
+			url = "https://" + url
+		}
+		file := &message.FileElement{ContentType: a.ContentType, File: a.Filename, URL: url}
+		switch {
+		case strings.HasPrefix(a.ContentType, "image"):
+			segs = append(segs, &message.ImageElement{File: file, URL: url})
+		case strings.HasPrefix(a.ContentType, "voice"), strings.HasPrefix(a.ContentType, "audio"):
+			segs = append(segs, &message.RecordElement{File: file})
+		default:
+			segs = append(segs, file)
+		}
+	}
+	return segs
+}
+
 func (pa *PlatformAdapterOfficialQQ) DoRelogin() bool {
 	pa.CancelFunc()
 	pa.Session.Parent.Logger.Infof("正在启用 official qq 服务")
@@ -271,13 +306,26 @@ func (pa *PlatformAdapterOfficialQQ) SetEnable(enable bool) {
 	d.LastUpdatedTime = time.Now().Unix()
 }
 
-func (pa *PlatformAdapterOfficialQQ) SendSegmentToGroup(ctx *MsgContext, groupID string, msg []message.IMessageElement, flag string) {
-}
-
-func (pa *PlatformAdapterOfficialQQ) SendSegmentToPerson(ctx *MsgContext, userID string, msg []message.IMessageElement, flag string) {
+func (pa *PlatformAdapterOfficialQQ) SendSegmentToGroup(ctx *MsgContext, uid string, msg []message.IMessageElement, _ string) {
+	rowID, ok := VarGetValueStr(ctx, "$tMsgID")
+	if !ok {
+		// TODO：允许主动消息发送，并校验频率
+		pa.Session.Parent.Logger.Error("official qq 发送群聊消息失败：无法直接发送消息")
+		return
+	}
+	groupId, idType := pa.mustExtractID(uid)
+	switch idType {
+	case OpenQQGroupOpenid:
+		pa.sendQQGroupMsgRaw(ctx, rowID, groupId, msg)
+	case OpenQQCHChannel:
+		pa.sendQQChannelMsgRaw(ctx, rowID, groupId, msg)
+	default:
+		pa.Session.Parent.Logger.Errorf("official qq 发送群聊消息失败：错误的群聊id[%s]类型-%d", uid, idType)
+		return
+	}
 }
 
-func (pa *PlatformAdapterOfficialQQ) SendToPerson(ctx *MsgContext, uid string, text string, flag string) {
+func (pa *PlatformAdapterOfficialQQ) SendSegmentToPerson(ctx *MsgContext, uid string, msg []message.IMessageElement, _ string) {
 	userID, idType := pa.mustExtractID(uid)
 	if idType != OpenQQCHUser {
 		// 说明不是频道信息
@@ -296,7 +344,11 @@ func (pa *PlatformAdapterOfficialQQ) SendToPerson(ctx *MsgContext, uid string, t
 		guildID = g
 		channelID = c
 	}
-	pa.sendQQGuildDirectMsgRaw(ctx, rowID, guildID, channelID, text)
+	pa.sendQQGuildDirectMsgRaw(ctx, rowID, guildID, channelID, msg)
+}
+
+func (pa *PlatformAdapterOfficialQQ) SendToPerson(ctx *MsgContext, uid string, text string, flag string) {
+	pa.SendSegmentToPerson(ctx, uid, message.ConvertStringMessage(text), flag)
 }
 
 func (pa *PlatformAdapterOfficialQQ) createQQGuildDirectChannel( /* ctx */ _ *MsgContext, guildID, userID string) (string, string, error) {
@@ -318,58 +370,24 @@ func (pa *PlatformAdapterOfficialQQ) createQQGuildDirectChannel( /* ctx */ _ *Ms
 	return info.GuildID, info.ChannelID, nil
 }
 
-func (pa *PlatformAdapterOfficialQQ) sendQQGuildDirectMsgRaw( /* ctx */ _ *MsgContext, rowMsgID string, guildID, channelID string, text string) {
+func (pa *PlatformAdapterOfficialQQ) sendQQGuildDirectMsgRaw( /* ctx */ _ *MsgContext, rowMsgID string, guildID, channelID string, elems []message.IMessageElement) {
 	qctx := context.Background()
-	elems := message.ConvertStringMessage(text)
-	var (
-		content  string
-		toCreate *dto.MessageToCreate
-	)
-
-	for _, elem := range elems {
-		switch e := elem.(type) {
-		case *message.TextElement:
-			content += e.Content
-		case *message.ImageElement:
-		}
-	}
-
 	dMsg := &dto.DirectMessage{
 		GuildID:   guildID,
 		ChannelID: channelID,
 	}
-	toCreate = &dto.MessageToCreate{
-		Content: content,
-		MsgType: 0,
-		MsgID:   rowMsgID,
-	}
+	toCreate := officialQQChannelMessage(elems, rowMsgID)
 	if _, err := pa.Api.PostDirectMessage(qctx, dMsg, toCreate); err != nil {
 		pa.Session.Parent.Logger.Error("official qq 发送频道私信消息失败：" + err.Error())
 	}
 }
 
 func (pa *PlatformAdapterOfficialQQ) SendToGroup(ctx *MsgContext, uid string, text string, flag string) {
-	rowID, ok := VarGetValueStr(ctx, "$tMsgID")
-	if !ok {
-		// TODO：允许主动消息发送，并校验频率
-		pa.Session.Parent.Logger.Error("official qq 发送群聊消息失败：无法直接发送消息")
-		return
-	}
-	groupId, idType := pa.mustExtractID(uid)
-	switch idType {
-	case OpenQQGroupOpenid:
-		pa.sendQQGroupMsgRaw(ctx, rowID, groupId, text)
-	case OpenQQCHChannel:
-		pa.sendQQChannelMsgRaw(ctx, rowID, groupId, text)
-	default:
-		pa.Session.Parent.Logger.Errorf("official qq 发送群聊消息失败：错误的群聊id[%s]类型-%d", uid, idType)
-		return
-	}
+	pa.SendSegmentToGroup(ctx, uid, message.ConvertStringMessage(text), flag)
 }
 
-func (pa *PlatformAdapterOfficialQQ) sendQQGroupMsgRaw( /* ctx */ _ *MsgContext, rowMsgID, groupID string, text string) {
+func (pa *PlatformAdapterOfficialQQ) sendQQGroupMsgRaw( /* ctx */ _ *MsgContext, rowMsgID, groupID string, elems []message.IMessageElement) {
 	qctx := context.Background()
-	elems := message.ConvertStringMessage(text)
 	var (
 		content  string
 		toCreate *dto.MessageToCreate
@@ -387,12 +405,16 @@ func (pa *PlatformAdapterOfficialQQ) sendQQGroupMsgRaw( /* ctx */ _ *MsgContext,
 		case *message.AtElement:
 			pa.Session.Parent.Logger.Warn("official qq 群聊消息暂不支持 AT 他人，跳过该部分")
 		case *message.ImageElement:
-			url := elem.File.URL
+			url := elem.URL
+			if url == "" && elem.File != nil {
+				url = elem.File.URL
+			}
 			// 目前不支持本地发送，检查一下url
 			if url == "" ||
 				strings.Contains(url, "localhost") ||
 				strings.Contains(url, "127.0.0.1") {
 				pa.Session.Parent.Logger.Warn("official qq 群聊消息暂不支持发送本地图片，跳过该部分")
+				continue
 			}
 			fMsg := &dto.MessageMediaToCreate{
 				FileType:   1,
@@ -410,12 +432,16 @@ func (pa *PlatformAdapterOfficialQQ) sendQQGroupMsgRaw( /* ctx */ _ *MsgContext,
 				FileInfo: media.FileInfo,
 			}
 		case *message.RecordElement:
-			url := elem.File.URL
+			var url string
+			if elem.File != nil {
+				url = elem.File.URL
+			}
 			// 目前不支持本地发送，检查一下url
 			if url == "" ||
 				strings.Contains(url, "localhost") ||
 				strings.Contains(url, "127.0.0.1") {
 				pa.Session.Parent.Logger.Warn("official qq 群聊消息暂不支持发送本地语音，跳过该部分")
+				continue
 			}
 			fMsg := &dto.MessageMediaToCreate{
 				FileType:   3,
@@ -432,6 +458,8 @@ func (pa *PlatformAdapterOfficialQQ) sendQQGroupMsgRaw( /* ctx */ _ *MsgContext,
 			toCreate.Media = &dto.Media{
 				FileInfo: media.FileInfo,
 			}
+		case *message.ReplyElement:
+			toCreate.MessageReference = &dto.MessageReference{MessageID: elem.ReplySeq, IgnoreGetMessageError: true}
 		}
 	}
 
@@ -442,14 +470,21 @@ func (pa *PlatformAdapterOfficialQQ) sendQQGroupMsgRaw( /* ctx */ _ *MsgContext,
 	}
 }
 
-func (pa *PlatformAdapterOfficialQQ) sendQQChannelMsgRaw( /* ctx */ _ *MsgContext, rowMsgID, channelID string, text string) {
+func (pa *PlatformAdapterOfficialQQ) sendQQChannelMsgRaw( /* ctx */ _ *MsgContext, rowMsgID, channelID string, elems []message.IMessageElement) {
 	qctx := context.Background()
-	elems := message.ConvertStringMessage(text)
-	var (
-		content  string
-		toCreate *dto.MessageToCreate
-	)
+	toCreate := officialQQChannelMessage(elems, rowMsgID)
+	if _, err := pa.Api.PostMessage(qctx, channelID, toCreate); err != nil {
+		pa.Session.Parent.Logger.Error("official qq 发送频道消息失败：" + err.Error())
+	}
+}
 
+// officialQQChannelMessage 将消息段组装为频道（含频道私信）消息，图片只支持网络地址
+func officialQQChannelMessage(elems []message.IMessageElement, rowMsgID string) *dto.MessageToCreate {
+	var content string
+	toCreate := &dto.MessageToCreate{
+		MsgType: 0,
+		MsgID:   rowMsgID,
+	}
 	for _, elem := range elems {
 		switch e := elem.(type) {
 		case *message.TextElement:
@@ -462,17 +497,19 @@ func (pa *PlatformAdapterOfficialQQ) sendQQChannelMsgRaw( /* ctx */ _ *MsgContex
 				content += fmt.Sprintf("<@%s>", e.Target)
 			}
 		case *message.ImageElement:
+			url := e.URL
+			if url == "" && e.File != nil {
+				url = e.File.URL
+			}
+			if strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://") {
+				toCreate.Image = url
+			}
+		case *message.ReplyElement:
+			toCreate.MessageReference = &dto.MessageReference{MessageID: e.ReplySeq, IgnoreGetMessageError: true}
 		}
 	}
-
-	toCreate = &dto.MessageToCreate{
-		Content: content,
-		MsgType: 0,
-		MsgID:   rowMsgID,
-	}
-	if _, err := pa.Api.PostMessage(qctx, channelID, toCreate); err != nil {
-		pa.Session.Parent.Logger.Error("official qq 发送频道消息失败：" + err.Error())
-	}
+	toCreate.Content = content
+	return toCreate
 }
 
 func (pa *PlatformAdapterOfficialQQ) GetGroupInfoAsync(groupID string) {
@@ -580,3 +617,10 @@ func (pa *PlatformAdapterOfficialQQ) MemberKick(_ string, _ string) {
 func (pa *PlatformAdapterOfficialQQ) EditMessage(_ *MsgContext, _, _ string) {}
 
 func (pa *PlatformAdapterOfficialQQ) RecallMessage(_ *MsgContext, _ string) {}
+
+func (pa *PlatformAdapterOfficialQQ) Capabilities() AdapterCapabilities {
+	return AdapterCapabilities{
+		GuildChannel: true,
+		Segment:      true,
+	}
+}
@@ -511,6 +511,13 @@ func (p *PlatformAdapterOnebot) EditMessage(_ *MsgContext, _, _ string) {}
 func (p *PlatformAdapterOnebot) RecallMessage(_ *MsgContext, _ string) {
 }
 
+func (p *PlatformAdapterOnebot) Capabilities() AdapterCapabilities {
+	return AdapterCapabilities{
+		SendFile: true,
+		Segment:  true,
+	}
+}
+
 func (p *PlatformAdapterOnebot) MemberBan(_ string, _ string, _ int64) {
 }
 
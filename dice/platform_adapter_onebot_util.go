@@ -716,8 +716,17 @@ func arrayByte2SealdiceMessage(log *zap.SugaredLogger, raw []byte) (*Message, er
 		return nil, err
 	}
 	m := obMsg.toStdMessage()
-	arrayContent := parseContent.Get("message").Array()
-	seg := make([]message.IMessageElement, 0)
+	seg, cqText := oneBotArrayToSegments(parseContent.Get("message").Array())
+	// 获取Message
+	m.Message = cqText
+	// 获取Segment
+	m.Segment = seg
+	return m, nil
+}
+
+// oneBotArrayToSegments 将 OB11 的 Array 消息转换为消息段，同时返回等价的 CQ 码文本
+func oneBotArrayToSegments(arrayContent []gjson.Result) ([]message.IMessageElement, string) {
+	seg := make([]message.IMessageElement, 0, len(arrayContent))
 	cqMessage := strings.Builder{}
 	for _, i := range arrayContent {
 		// 使用String()方法，如果为空，会自动产生空字符串
@@ -764,6 +773,21 @@ func arrayByte2SealdiceMessage(log *zap.SugaredLogger, raw []byte) (*Message, er
 		case "at":
 			_, _ = fmt.Fprintf(&cqMessage, "[CQ:at,qq=%v]", dataObj.Get("qq").String())
 			seg = append(seg, &message.AtElement{Target: dataObj.Get("qq").String()})
+		case "file":
+			// 与 CQ 码的 file 一致解析为 FileElement，供 .pc import 等读取附件；file 为远程地址时同时视为 url
+			file := &message.FileElement{
+				File: dataObj.Get("file").String(),
+				URL:  dataObj.Get("url").String(),
+			}
+			if file.URL == "" && hasURLScheme(file.File) {
+				file.URL = file.File
+			}
+			_, _ = fmt.Fprintf(&cqMessage, "[CQ:file,file=%v", message.EscapeCQParam(file.File))
+			if file.URL != "" {
+				_, _ = fmt.Fprintf(&cqMessage, ",url=%v", message.EscapeCQParam(file.URL))
+			}
+			cqMessage.WriteString("]")
+			seg = append(seg, file)
 		case "poke":
 			cqMessage.WriteString("[CQ:poke]")
 			seg = append(seg, &message.PokeElement{})
@@ -787,11 +811,7 @@ func arrayByte2SealdiceMessage(log *zap.SugaredLogger, raw []byte) (*Message, er
 			})
 		}
 	}
-	// 获取Message
-	m.Message = cqMessage.String()
-	// 获取Segment
-	m.Segment = seg
-	return m, nil
+	return seg, cqMessage.String()
 }
 
 // 将OB11的Array数据转换为string字符串 确实没在使用，但备份一下这个实用函数
@@ -907,7 +927,7 @@ func convertSealMsgToMessageChain(msg []message.IMessageElement) (schema.Message
 			if !ok {
 				continue
 			}
-			rawMsg.At(res.Target)
+			rawMsg = rawMsg.At(res.Target)
 			_, _ = fmt.Fprintf(&cqMessage, "[CQ:at,qq=%v]", res.Target)
 		case message.Text:
 			res, ok := v.(*message.TextElement)
@@ -664,6 +664,12 @@ func (pa *PlatformAdapterRed) EditMessage(_ *MsgContext, _, _ string) {}
 
 func (pa *PlatformAdapterRed) RecallMessage(_ *MsgContext, _ string) {}
 
+func (pa *PlatformAdapterRed) Capabilities() AdapterCapabilities {
+	return AdapterCapabilities{
+		SendFile: true,
+	}
+}
+
 func (pa *PlatformAdapterRed) GetGroupInfoAsync(_ string) {
 	// 触发更新群信息
 	d := pa.Session.Parent
@@ -441,10 +441,15 @@ func (pa *PlatformAdapterSatori) SendToGroup(ctx *MsgContext, groupID string, te
 }
 
 func (pa *PlatformAdapterSatori) sendMsgRaw( /* ctx */ _ *MsgContext, channelID string, text string /* flag */, _ string, msgType string) {
+	pa.sendContentRaw(channelID, pa.encodeMessage(text), msgType)
+}
+
+// sendContentRaw 发送已经编码好的 satori 消息
+func (pa *PlatformAdapterSatori) sendContentRaw(channelID string, content string, msgType string) {
 	log := pa.Session.Parent.Logger
 	req, err := json.Marshal(map[string]interface{}{
 		"channel_id": channelID,
-		"content":    pa.encodeMessage(text),
+		"content":    content,
 	})
 	var msgTypeStr string
 	if msgType == "private" {
@@ -469,10 +474,18 @@ func (pa *PlatformAdapterSatori) sendMsgRaw( /* ctx */ _ *MsgContext, channelID
 	}
 }
 
-func (pa *PlatformAdapterSatori) SendSegmentToGroup(ctx *MsgContext, groupID string, msg []message.IMessageElement, flag string) {
+func (pa *PlatformAdapterSatori) SendSegmentToGroup(_ *MsgContext, groupID string, msg []message.IMessageElement, _ string) {
+	pa.sendContentRaw(UserIDExtract(groupID), pa.encodeSegments(msg), "group")
 }
 
-func (pa *PlatformAdapterSatori) SendSegmentToPerson(ctx *MsgContext, userID string, msg []message.IMessageElement, flag string) {
+func (pa *PlatformAdapterSatori) SendSegmentToPerson(_ *MsgContext, userID string, msg []message.IMessageElement, _ string) {
+	log := pa.Session.Parent.Logger
+	if pa.Platform == "QQ" {
+		id := UserIDExtract(userID)
+		pa.sendContentRaw("private:"+id, pa.encodeSegments(msg), "private")
+	} else {
+		log.Errorf("satori %s 平台暂不支持私聊消息发送", pa.Platform)
+	}
 }
 
 func (pa *PlatformAdapterSatori) SetGroupCardName(ctx *MsgContext, name string) {
@@ -545,6 +558,13 @@ func (pa *PlatformAdapterSatori) RecallMessage(ctx *MsgContext, msgID string) {
 	log.Errorf("satori %s 平台暂不支持撤回消息", pa.Platform)
 }
 
+func (pa *PlatformAdapterSatori) Capabilities() AdapterCapabilities {
+	return AdapterCapabilities{
+		MemberKick: true,
+		Segment:    true,
+	}
+}
+
 func (pa *PlatformAdapterSatori) post(resource string, body io.Reader) ([]byte, error) {
 	apiUrl := pa.httpUrl.String() + "/" + resource
 	client := http.Client{}
@@ -596,6 +616,7 @@ func (pa *PlatformAdapterSatori) toStdMessage(messageEvent *SatoriEvent) *Messag
 	msg := new(Message)
 	msg.RawID = messageEvent.Message.ID
 	msg.Message = decodeMessage(messageEvent.Message.Content)
+	msg.Segment = satoriContentToSegments(messageEvent.Message.Content)
 	msg.Platform = pa.Platform
 
 	sender := SenderBase{}
@@ -649,8 +670,58 @@ func decodeMessage(text string) string {
 	return content.String()
 }
 
+// satoriAttr 取出元素上的字符串属性
+func satoriAttr(el *satori.Element, key string) string {
+	v, _ := el.Attrs[key].(string)
+	return v
+}
+
+// satoriContentToSegments 将 satori 消息元素解析为消息段，未识别的元素会展开其子元素
+func satoriContentToSegments(content string) []message.IMessageElement {
+	var segs []message.IMessageElement
+	var walk func(el *satori.Element)
+	walk = func(el *satori.Element) {
+		switch el.Type {
+		case "text":
+			segs = append(segs, &message.TextElement{Content: satoriAttr(el, "content")})
+		case "at":
+			if satoriAttr(el, "role") == "all" || satoriAttr(el, "type") == "all" {
+				return
+			}
+			segs = append(segs, &message.AtElement{Target: satoriAttr(el, "id")})
+		case "img", "image":
+			src := satoriAttr(el, "src")
+			segs = append(segs, &message.ImageElement{
+				File: &message.FileElement{File: satoriAttr(el, "title"), URL: src},
+				URL:  src,
+			})
+		case "audio":
+			segs = append(segs, &message.RecordElement{File: &message.FileElement{URL: satoriAttr(el, "src")}})
+		case "file":
+			segs = append(segs, &message.FileElement{File: satoriAttr(el, "title"), URL: satoriAttr(el, "src")})
+		case "quote":
+			// 引用的内容不属于本条消息，只保留被引用的消息ID
+			segs = append(segs, &message.ReplyElement{ReplySeq: satoriAttr(el, "id")})
+		case "face":
+			segs = append(segs, &message.FaceElement{FaceID: satoriAttr(el, "id")})
+		case "br":
+			segs = append(segs, &message.TextElement{Content: "\n"})
+		default:
+			for _, child := range el.Children {
+				walk(child)
+			}
+		}
+	}
+	walk(satori.ElementParse(content))
+	return segs
+}
+
 func (pa *PlatformAdapterSatori) encodeMessage(content string) string {
-	elems := message.ConvertStringMessage(content)
+	return pa.encodeSegments(message.ConvertStringMessage(content))
+}
+
+// encodeSegments 将消息段编码为 satori 消息元素
+func (pa *PlatformAdapterSatori) encodeSegments(elems []message.IMessageElement) string {
 	var msg strings.Builder
 	for _, elem := range elems {
 		switch e := elem.(type) {
@@ -688,6 +759,9 @@ func (pa *PlatformAdapterSatori) encodeMessage(content string) string {
 			// msg.WriteString(node.ToString())
 		case *message.ImageElement:
 			file := e.File
+			if file == nil {
+				file = &message.FileElement{URL: e.URL}
+			}
 			node := &satori.Element{
 				Type:  "img",
 				Attrs: make(satori.Dict),
@@ -706,10 +780,16 @@ func (pa *PlatformAdapterSatori) encodeMessage(content string) string {
 					continue
 				}
 				node.Attrs["src"] = "file:///" + temp.Name()
-			} else if e.File.URL != "" {
-				node.Attrs["src"] = e.File.URL
+			} else if file.URL != "" {
+				node.Attrs["src"] = file.URL
 			}
 			msg.WriteString(node.ToString())
+		case *message.ReplyElement:
+			_, _ = fmt.Fprintf(&msg, `<quote id="%s"/>`, e.ReplySeq)
+		case *message.RecordElement:
+			if e.File != nil && e.File.URL != "" {
+				_, _ = fmt.Fprintf(&msg, `<audio src="%s"/>`, satori.ContentEscape(e.File.URL))
+			}
 		}
 	}
 	result := msg.String()
@@ -722,7 +802,7 @@ func (pa *PlatformAdapterSatori) handleEvent(event SatoriPayload[SatoriEvent]) {
 	case "message-created": // 消息创建
 		msg := pa.toStdMessage(event.Body)
 		if msg != nil {
-			s.Execute(pa.EndPoint, msg, false)
+			s.ExecuteNew(pa.EndPoint, msg)
 		}
 	case "message-updated": // 消息编辑
 		pa.editMessageHandle(event.Body)
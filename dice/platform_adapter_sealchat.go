@@ -686,6 +686,13 @@ func (pa *PlatformAdapterSealChat) EditMessage(_ *MsgContext, _, _ string) {}
 
 func (pa *PlatformAdapterSealChat) RecallMessage(_ *MsgContext, _ string) {}
 
+func (pa *PlatformAdapterSealChat) Capabilities() AdapterCapabilities {
+	return AdapterCapabilities{
+		SendFile: true,
+		Segment:  true,
+	}
+}
+
 func (pa *PlatformAdapterSealChat) dispatchMessage(msg string) {
 	ev := satori.Event{}
 	err := json.Unmarshal([]byte(msg), &ev)
@@ -700,7 +707,7 @@ func (pa *PlatformAdapterSealChat) dispatchMessage(msg string) {
 			// 自己发的消息，不管
 			return
 		}
-		pa.Session.Execute(pa.EndPoint, pa.toStdMessage(ev.Message), false)
+		pa.Session.ExecuteNew(pa.EndPoint, pa.toStdMessage(ev.Message))
 		return
 	case satori.EventMessageDeleted:
 		stdMsg := pa.toStdMessage(ev.Message)
@@ -736,6 +743,7 @@ func (pa *PlatformAdapterSealChat) toStdMessage(scMsg *satori.Message) *Message
 	})
 
 	msg.Message = strings.TrimSpace(cqMsg.String())
+	msg.Segment = satoriContentToSegments(scMsg.Content)
 
 	msg.Platform = "SEALCHAT"
 	if scMsg.Channel.Type == satori.DirectChannelType {
@@ -0,0 +1,314 @@
+//nolint:testpackage
+package dice
+
+// Round-trip tests for the adapters' segment conversion.
+//
+// Every adapter turns the payload it receives into []message.IMessageElement
+// and turns segments back into its own wire format when sending. The inbound
+// payloads below are trimmed copies of real events; each test decodes one,
+// checks the segments, then feeds the segments through the outbound builder
+// and checks that reply / at / image survive the trip.
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/lonelyevil/kook"
+	"github.com/sealdice/botgo/dto"
+	"github.com/tidwall/gjson"
+
+	"sealdice-core/message"
+	"sealdice-core/utils/satori"
+)
+
+// segmentSummary flattens segments into comparable strings.
+func segmentSummary(segs []message.IMessageElement) []string {
+	var ret []string
+	for _, seg := range segs {
+		switch e := seg.(type) {
+		case *message.TextElement:
+			ret = append(ret, "text:"+e.Content)
+		case *message.AtElement:
+			ret = append(ret, "at:"+e.Target)
+		case *message.ReplyElement:
+			ret = append(ret, "reply:"+e.ReplySeq)
+		case *message.FaceElement:
+			ret = append(ret, "face:"+e.FaceID)
+		case *message.ImageElement:
+			url := e.URL
+			if url == "" && e.File != nil {
+				url = e.File.URL
+				if url == "" {
+					url = e.File.File
+				}
+			}
+			ret = append(ret, "image:"+url)
+		case *message.RecordElement:
+			ret = append(ret, "record:"+e.File.URL)
+		case *message.FileElement:
+			ret = append(ret, "file:"+e.URL+e.File)
+		case *message.DefaultElement:
+			ret = append(ret, "default:"+e.RawType)
+		default:
+			ret = append(ret, fmt.Sprintf("unknown:%d", seg.Type()))
+		}
+	}
+	return ret
+}
+
+func assertSegments(t *testing.T, segs []message.IMessageElement, want ...string) {
+	t.Helper()
+	if got := segmentSummary(segs); !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected segments\n got: %q\nwant: %q", got, want)
+	}
+}
+
+const recordedGocqStringEvent = `{"post_type":"message","message_type":"group","time":1700000000,"self_id":2589922907,"sub_type":"normal","message_id":-358748624,"group_id":12345,"user_id":222,` +
+	`"message":"[CQ:reply,id=42][CQ:at,qq=2589922907] .r d20 &#91;x&#93; &amp; y[CQ:image,file=abc.image,url=https://example.com/a.png&amp;b=1]",` +
+	`"sender":{"nickname":"木落","user_id":222,"role":"member"}}`
+
+const recordedGocqArrayEvent = `{"post_type":"message","message_type":"group","time":1700000000,"self_id":2589922907,"message_id":1,"group_id":12345,"user_id":222,` +
+	`"message":[{"type":"reply","data":{"id":"42"}},{"type":"at","data":{"qq":"2589922907"}},{"type":"text","data":{"text":" .r d20"}},` +
+	`{"type":"face","data":{"id":"14"}},{"type":"image","data":{"file":"abc.image","url":"https://example.com/a.png"}},{"type":"file","data":{"file":"pc.json","url":"https://example.com/f?fname=pc.json","file_size":"12"}}],` +
+	`"sender":{"nickname":"木落","user_id":222}}`
+
+func TestGocqSegmentsRoundTrip(t *testing.T) {
+	segs := gocqEventSegments(recordedGocqStringEvent)
+	assertSegments(t, segs,
+		"reply:42", "at:2589922907", "text: .r d20 [x] & y", "image:https://example.com/a.png&b=1")
+
+	// 发出去的 CQ 码再解析一次，结果应当一致
+	again := message.ParseCQCode(segmentsToText(segs))
+	assertSegments(t, again,
+		"reply:42", "at:2589922907", "text: .r d20 [x] & y", "image:https://example.com/a.png&b=1")
+
+	segs = gocqEventSegments(recordedGocqArrayEvent)
+	assertSegments(t, segs,
+		"reply:42", "at:2589922907", "text: .r d20", "face:14", "image:https://example.com/a.png",
+		"file:https://example.com/f?fname=pc.jsonpc.json")
+
+	// array 上报生成的 CQ 码文本与消息段一致
+	_, cqText := oneBotArrayToSegments(gjson.Get(recordedGocqArrayEvent, "message").Array())
+	assertSegments(t, TextToSegments(cqText, "QQ"),
+		"reply:42", "at:2589922907", "text: .r d20", "face:14", "image:https://example.com/a.png",
+		"file:https://example.com/f?fname=pc.jsonpc.json")
+
+	chain, _ := convertSealMsgToMessageChain(segs)
+	var types []string
+	for _, m := range chain {
+		types = append(types, m.Type)
+	}
+	if want := []string{"reply", "at", "text", "face", "image", "file"}; !reflect.DeepEqual(types, want) {
+		t.Fatalf("unexpected onebot chain %q, want %q", types, want)
+	}
+}
+
+func TestSatoriSegmentsRoundTrip(t *testing.T) {
+	const content = `<quote id="m1"><author id="u2"/>被引用的内容</quote><at id="123"/> .r d20 &amp; <img src="https://example.com/a.png"/><at type="all"/><br/>`
+	segs := satoriContentToSegments(content)
+	assertSegments(t, segs,
+		"reply:m1", "at:123", "text: .r d20 & ", "image:https://example.com/a.png", "text:\n")
+
+	pa := &PlatformAdapterSatori{}
+	encoded := pa.encodeSegments(segs)
+	assertSegments(t, satoriContentToSegments(encoded),
+		"reply:m1", "at:123", "text: .r d20 & ", "image:https://example.com/a.png", "text:\n")
+}
+
+const recordedSealChatEvent = `{"id":1,"type":"message-created","timestamp":1700000000,` +
+	`"message":{"id":"msg-1","content":"<quote id=\"msg-0\"/><at id=\"bot-1\" name=\"海豹\"/> .r d20 <img src=\"sealchat://asset/abcdef\"/>",` +
+	`"channel":{"id":"ch-1","type":0,"name":"测试频道"},"user":{"id":"user-1","nick":"Tester"},"member":{"nick":"Tester","roles":["admin"]},"timestamp":1700000000}}`
+
+func TestSealChatSegmentsRoundTrip(t *testing.T) {
+	ev := satori.Event{}
+	if err := json.Unmarshal([]byte(recordedSealChatEvent), &ev); err != nil {
+		t.Fatalf("unmarshal sealchat event: %v", err)
+	}
+	pa := &PlatformAdapterSealChat{}
+	msg := pa.toStdMessage(ev.Message)
+	if msg.GroupID != "SEALCHAT-Group:ch-1" || msg.Sender.GroupRole != "admin" {
+		t.Fatalf("unexpected message meta: %+v", msg)
+	}
+	assertSegments(t, msg.Segment, "reply:msg-0", "at:bot-1", "text: .r d20 ", "image:sealchat://asset/abcdef")
+
+	encoded := pa.encodeMessageFromElements(msg.Segment)
+	assertSegments(t, satoriContentToSegments(encoded), "reply:msg-0", "at:bot-1", "text: .r d20 ", "image:sealchat://asset/abcdef")
+}
+
+const recordedKookEvent = `{"channel_type":"PERSON","type":9,"target_id":"2000","author_id":"3000","content":"(met)1234(met) .r d20 \\[x\\]",` +
+	`"msg_id":"kmsg-1","msg_timestamp":1700000000000,"nonce":"",` +
+	`"extra":{"type":9,"author":{"id":"3000","username":"tester","nickname":"Tester","bot":false},"kmarkdown":{"raw_content":"@海豹 .r d20 [x]"},` +
+	`"quote":{"id":"kmsg-0","type":9,"content":"hi","author":{"id":"3001","username":"other"}},"mention":["1234"]}}`
+
+func TestKookSegmentsRoundTrip(t *testing.T) {
+	var data kook.EventData
+	if err := json.Unmarshal([]byte(recordedKookEvent), &data); err != nil {
+		t.Fatalf("unmarshal kook event: %v", err)
+	}
+	ctx := &kook.KmarkdownMessageContext{
+		EventHandlerCommonContext: &kook.EventHandlerCommonContext{Common: data.EventDataGeneral},
+	}
+	if err := json.Unmarshal(data.Extra, &ctx.Extra); err != nil {
+		t.Fatalf("unmarshal kook extra: %v", err)
+	}
+
+	pa := &PlatformAdapterKook{}
+	msg := pa.toStdMessage(ctx)
+	if msg == nil || msg.MessageType != "private" {
+		t.Fatalf("unexpected message: %+v", msg)
+	}
+	assertSegments(t, msg.Segment, "reply:kmsg-0", "at:1234", "text: .r d20 [x]")
+
+	segs := append(msg.Segment,
+		&message.ImageElement{File: &message.FileElement{File: "a.png", Stream: strings.NewReader("png")}},
+		&message.ImageElement{URL: "https://img.kookapp.cn/b.png"},
+	)
+	var uploaded []string
+	msgb, err := kookBuildCardMessage(segs, func(name string, data []byte) (string, error) {
+		uploaded = append(uploaded, name+":"+string(data))
+		return "https://img.kookapp.cn/asset.png", nil
+	})
+	if err != nil {
+		t.Fatalf("build card: %v", err)
+	}
+	if msgb.Quote != "kmsg-0" {
+		t.Fatalf("expected quote to be kept, got %q", msgb.Quote)
+	}
+	if !reflect.DeepEqual(uploaded, []string{"a.png:png"}) {
+		t.Fatalf("expected only the stream image to be uploaded, got %q", uploaded)
+	}
+	for _, want := range []string{"(met)1234(met)", " .r d20 [x]", "https://img.kookapp.cn/asset.png", "https://img.kookapp.cn/b.png"} {
+		if !strings.Contains(msgb.Content, want) {
+			t.Fatalf("card %s does not contain %q", msgb.Content, want)
+		}
+	}
+}
+
+const recordedDiscordMessage = `{"id":"1100","channel_id":"2200","guild_id":"3300","content":"<@!555> .r d20 <@666>","timestamp":"2024-01-01T00:00:00Z",` +
+	`"author":{"id":"999","username":"tester"},` +
+	`"attachments":[{"id":"a1","filename":"a.png","content_type":"image/png","url":"https://cdn.discordapp.com/a.png"},` +
+	`{"id":"a2","filename":"note.txt","content_type":"text/plain","url":"https://cdn.discordapp.com/note.txt"}],` +
+	`"message_reference":{"message_id":"1000","channel_id":"2200"},` +
+	`"referenced_message":{"id":"1000","channel_id":"2200","content":"hi","timestamp":"2024-01-01T00:00:00Z","author":{"id":"888","username":"other"}}}`
+
+func TestDiscordSegmentsRoundTrip(t *testing.T) {
+	var m discordgo.Message
+	if err := json.Unmarshal([]byte(recordedDiscordMessage), &m); err != nil {
+		t.Fatalf("unmarshal discord message: %v", err)
+	}
+	segs := discordMessageToSegments(&m)
+	assertSegments(t, segs,
+		"reply:1000", "at:555", "text: .r d20 ", "at:666",
+		"image:https://cdn.discordapp.com/a.png", "file:https://cdn.discordapp.com/note.txtnote.txt")
+	if reply, _ := segs[0].(*message.ReplyElement); reply.Sender != "888" {
+		t.Fatalf("expected reply sender, got %+v", reply)
+	}
+
+	sends := discordBuildMessageSends(append(segs, &message.TTSElement{Content: "tts"}), "2200")
+	if len(sends) != 2 || !sends[1].TTS || sends[1].Content != "tts" {
+		t.Fatalf("expected message followed by tts, got %+v", sends)
+	}
+	first := sends[0]
+	if first.Reference == nil || first.Reference.MessageID != "1000" || first.Reference.ChannelID != "2200" {
+		t.Fatalf("unexpected reference: %+v", first.Reference)
+	}
+	if len(first.Embeds) != 2 || first.Embeds[0].Description != "<@555> .r d20 <@666>" {
+		t.Fatalf("unexpected embeds: %+v", first.Embeds)
+	}
+	if first.Embeds[1].Image == nil || first.Embeds[1].Image.URL != "https://cdn.discordapp.com/a.png" {
+		t.Fatalf("expected image embed, got %+v", first.Embeds[1])
+	}
+}
+
+const recordedTelegramMessage = `{"message_id":7,"date":1700000000,` +
+	`"from":{"id":1001,"is_bot":false,"first_name":"A","username":"alice"},"chat":{"id":-100,"type":"supergroup","title":"g"},` +
+	`"reply_to_message":{"message_id":5,"date":1699999999,"from":{"id":1002,"is_bot":false,"first_name":"B"},"chat":{"id":-100,"type":"supergroup"},"text":"hi"},` +
+	`"photo":[{"file_id":"small","file_unique_id":"s","width":90,"height":90},{"file_id":"big","file_unique_id":"b","width":800,"height":800}],` +
+	`"caption":"@sealbot .r d20 @bob","caption_entities":[{"type":"mention","offset":0,"length":8},{"type":"mention","offset":16,"length":4}]}`
+
+func TestTelegramSegmentsRoundTrip(t *testing.T) {
+	var m tgbotapi.Message
+	if err := json.Unmarshal([]byte(recordedTelegramMessage), &m); err != nil {
+		t.Fatalf("unmarshal telegram message: %v", err)
+	}
+	text := tgReplaceMentions(m.Caption, m.CaptionEntities, "sealbot", 42, func(name string) (int64, bool) {
+		return 1003, name == "bob"
+	})
+	if text != "tg://user?id=42 .r d20 tg://user?id=1003" {
+		t.Fatalf("unexpected text %q", text)
+	}
+	segs := tgMessageToSegments(&m, text)
+	assertSegments(t, segs, "reply:5", "at:42", "text: .r d20 ", "at:1003", "image:big")
+
+	chattables := tgBuildChattables(-100, segs)
+	if len(chattables) != 1 {
+		t.Fatalf("expected text to become the caption of the photo, got %+v", chattables)
+	}
+	photo, ok := chattables[0].(tgbotapi.PhotoConfig)
+	if !ok {
+		t.Fatalf("expected photo, got %T", chattables[0])
+	}
+	if photo.ReplyToMessageID != 5 || photo.File != tgbotapi.FileID("big") {
+		t.Fatalf("unexpected photo: %+v", photo)
+	}
+	if photo.Caption != "@42  .r d20 @1003 " || len(photo.CaptionEntities) != 2 ||
+		photo.CaptionEntities[0].User.ID != 42 || photo.CaptionEntities[1].Offset != 12 || photo.CaptionEntities[1].User.ID != 1003 {
+		t.Fatalf("unexpected caption %q %+v", photo.Caption, photo.CaptionEntities)
+	}
+}
+
+const recordedOfficialQQChannelMessage = `{"id":"qmsg-1","channel_id":"c1","guild_id":"g1","content":"<@!bot123> .r d20","timestamp":"2024-01-01T00:00:00+08:00",` +
+	`"author":{"id":"u1","username":"tester"},"attachments":[{"content_type":"image/png","filename":"a.png","url":"gchat.qpic.cn/a.png"}],` +
+	`"message_reference":{"message_id":"qmsg-0"}}`
+
+func TestOfficialQQSegmentsRoundTrip(t *testing.T) {
+	var data dto.WSATMessageData
+	if err := json.Unmarshal([]byte(recordedOfficialQQChannelMessage), &data); err != nil {
+		t.Fatalf("unmarshal official qq message: %v", err)
+	}
+	pa := &PlatformAdapterOfficialQQ{}
+	msg := pa.channelMsgToStdMsg(&data)
+	assertSegments(t, msg.Segment, "reply:qmsg-0", "at:bot123", "text: .r d20", "image:https://gchat.qpic.cn/a.png")
+
+	toCreate := officialQQChannelMessage(msg.Segment, "qmsg-1")
+	if toCreate.Content != "<@bot123> .r d20" || toCreate.MsgID != "qmsg-1" {
+		t.Fatalf("unexpected content: %+v", toCreate)
+	}
+	if toCreate.Image != "https://gchat.qpic.cn/a.png" {
+		t.Fatalf("expected image url, got %q", toCreate.Image)
+	}
+	if toCreate.MessageReference == nil || toCreate.MessageReference.MessageID != "qmsg-0" {
+		t.Fatalf("expected message reference, got %+v", toCreate.MessageReference)
+	}
+}
+
+func TestTextToSegmentsPlatformAt(t *testing.T) {
+	assertSegments(t, TextToSegments("(met)1234(met) .r d20", "KOOK"), "at:1234", "text: .r d20")
+	assertSegments(t, TextToSegments("<@!u1> .ra 力量", "OpenQQCH"), "at:u1", "text: .ra 力量")
+	assertSegments(t, TextToSegments("[CQ:at,qq=10001] .r &#91;1&#93;", "QQ"), "at:10001", "text: .r [1]")
+	// 字符串上报的原文经 TextToSegments 转换，与适配器解析的结果一致
+	assertSegments(t, TextToSegments(gjson.Get(recordedGocqStringEvent, "message").String(), "QQ"),
+		segmentSummary(gocqEventSegments(recordedGocqStringEvent))...)
+	assertSegments(t, TextToSegments("[CQ:image,file=https://example.com/a.png]<@x>", "SEALCHAT"),
+		"image:https://example.com/a.png", "at:x")
+	// 未知平台只解析 CQ 码
+	assertSegments(t, TextToSegments("<@x> .r", "UNKNOWN"), "text:<@x> .r")
+}
+
+func TestSelfUIDForMessage(t *testing.T) {
+	ep := &EndPointInfo{EndPointInfoBase: EndPointInfoBase{UserID: "OpenQQ:bot123"}}
+	if uid := selfUIDForMessage(ep, &Message{Platform: "OpenQQCH"}); uid != "OpenQQCH:bot123" {
+		t.Fatalf("unexpected self uid for channel message: %s", uid)
+	}
+	if uid := selfUIDForMessage(ep, &Message{Platform: "OpenQQ"}); uid != "OpenQQ:bot123" {
+		t.Fatalf("unexpected self uid: %s", uid)
+	}
+	if uid := selfUIDForMessage(ep, &Message{Platform: "QQ-CH", TmpUID: "QQ-CH:1"}); uid != "QQ-CH:1" {
+		t.Fatalf("expected tmp uid to win, got %s", uid)
+	}
+}
@@ -170,7 +170,7 @@ func (pa *PlatformAdapterTelegram) Serve() int {
 				go pa.friendAdded(msg)
 				continue
 			}
-			go pa.Session.Execute(pa.EndPoint, msg, false)
+			go pa.Session.ExecuteNew(pa.EndPoint, msg)
 		}
 	}()
 	return 0
@@ -258,46 +258,15 @@ func (pa *PlatformAdapterTelegram) toStdMessage(m *tgbotapi.Message) *Message {
 	self := pa.IntentSession.Self
 	msg := new(Message)
 	msg.Time = int64(m.Date)
-	if m.Entities != nil {
-		var replacedText strings.Builder
-		index := 0
-		u16 := utf16.Encode([]rune(m.Text))
-		for _, entity := range m.Entities {
-			// 是否包含@信息
-			if entity.IsMention() || entity.Type == "text_mention" {
-				// text mention时User不为nil
-				if entity.User != nil {
-					replacedText.WriteString(string(utf16.Decode(u16[index:entity.Offset])))
-					_, _ = fmt.Fprintf(&replacedText, "tg://user?id=%d", entity.User.ID)
-				} else {
-					// 这里处理最烦人的username mention 首先判断是不是@了机器人自己
-					if self.UserName == string(utf16.Decode(u16[entity.Offset+1:entity.Offset+entity.Length])) {
-						replacedText.WriteString(string(utf16.Decode(u16[index:entity.Offset])))
-						_, _ = fmt.Fprintf(&replacedText, "tg://user?id=%d", self.ID)
-					} else {
-						// @的不是自己，查看是否能从用户名缓存中找到username
-						name := string(utf16.Decode(u16[entity.Offset+1 : entity.Offset+entity.Length]))
-						v, exist := ucache.Load(name)
-						if exist {
-							replacedText.WriteString(string(utf16.Decode(u16[index:entity.Offset])))
-							_, _ = fmt.Fprintf(&replacedText, "tg://user?id=%d", v)
-						} else {
-							// 找不到，没有办法了，现阶段没有通过username获取userid的api
-							replacedText.WriteString(string(utf16.Decode(u16[index : entity.Offset+entity.Length])))
-						}
-					}
-				}
-			} else {
-				// 不是mention 忽略
-				replacedText.WriteString(string(utf16.Decode(u16[index : entity.Offset+entity.Length])))
-			}
-			index = entity.Offset + entity.Length
-		}
-		replacedText.WriteString(string(utf16.Decode(u16[index:])))
-		msg.Message = replacedText.String()
-	} else {
-		msg.Message = m.Text
+	text, entities := m.Text, m.Entities
+	if text == "" {
+		// 图片等消息的文字位于 caption 中
+		text, entities = m.Caption, m.CaptionEntities
 	}
+	msg.Message = tgReplaceMentions(text, entities, self.UserName, self.ID, func(name string) (int64, bool) {
+		return ucache.Load(name)
+	})
+	msg.Segment = tgMessageToSegments(m, msg.Message)
 	msg.RawID = m.MessageID
 	msg.Platform = "TG"
 	send := new(SenderBase)
@@ -399,9 +368,40 @@ func (pa *PlatformAdapterTelegram) SetEnable(enable bool) {
 }
 
 func (pa *PlatformAdapterTelegram) SendSegmentToGroup(ctx *MsgContext, groupID string, msg []message.IMessageElement, flag string) {
+	resp, err := pa.SendSegmentToChatRaw(ExtractTelegramGroupID(groupID), msg)
+	if err != nil {
+		return
+	}
+	pa.Session.OnMessageSend(ctx, &Message{
+		Platform:    "TG",
+		MessageType: "group",
+		Message:     segmentsToText(msg),
+		Segment:     msg,
+		GroupID:     groupID,
+		Sender: SenderBase{
+			UserID:   pa.EndPoint.UserID,
+			Nickname: pa.EndPoint.Nickname,
+		},
+		RawID: resp.MessageID,
+	}, flag)
 }
 
 func (pa *PlatformAdapterTelegram) SendSegmentToPerson(ctx *MsgContext, userID string, msg []message.IMessageElement, flag string) {
+	resp, err := pa.SendSegmentToChatRaw(ExtractTelegramUserID(userID), msg)
+	if err != nil {
+		return
+	}
+	pa.Session.OnMessageSend(ctx, &Message{
+		Platform:    "TG",
+		MessageType: "private",
+		Message:     segmentsToText(msg),
+		Segment:     msg,
+		Sender: SenderBase{
+			UserID:   pa.EndPoint.UserID,
+			Nickname: pa.EndPoint.Nickname,
+		},
+		RawID: resp.MessageID,
+	}, flag)
 }
 
 func (pa *PlatformAdapterTelegram) SendToPerson(ctx *MsgContext, userID string, text string, flag string) {
@@ -512,6 +512,13 @@ func (pa *PlatformAdapterTelegram) EditMessage(_ *MsgContext, _, _ string) {}
 
 func (pa *PlatformAdapterTelegram) RecallMessage(_ *MsgContext, _ string) {}
 
+func (pa *PlatformAdapterTelegram) Capabilities() AdapterCapabilities {
+	return AdapterCapabilities{
+		SendFile: true,
+		Segment:  true,
+	}
+}
+
 type RequestFileDataImpl struct {
 	Reader io.Reader
 	File   string
@@ -527,74 +534,155 @@ func (r *RequestFileDataImpl) SendData() string {
 	return r.File
 }
 func (pa *PlatformAdapterTelegram) SendToChatRaw(uid string, text string) (*tgbotapi.Message, error) {
+	return pa.SendSegmentToChatRaw(uid, message.ConvertStringMessage(text))
+}
+
+func (pa *PlatformAdapterTelegram) SendSegmentToChatRaw(uid string, elem []message.IMessageElement) (*tgbotapi.Message, error) {
 	bot := pa.IntentSession
 	id, _ := strconv.ParseInt(uid, 10, 64)
-	elem := message.ConvertStringMessage(text)
-	msg := tgbotapi.NewMessage(id, "")
+	chattables := tgBuildChattables(id, elem)
+	if len(chattables) == 0 {
+		return nil, errors.New("empty message")
+	}
+	var resp tgbotapi.Message
 	var err error
+	for _, c := range chattables {
+		resp, err = bot.Send(c)
+		if err != nil {
+			pa.Session.Parent.Logger.Errorf("向Telegram聊天#%d发送消息时出错:%s", id, err)
+			return nil, err
+		}
+	}
+	return &resp, nil
+}
+
+// tgBuildChattables 将消息段组装为待发送的 telegram 消息，图片会带上之前累积的文字作为说明
+func tgBuildChattables(chatID int64, elem []message.IMessageElement) []tgbotapi.Chattable {
+	var ret []tgbotapi.Chattable
+	msg := tgbotapi.NewMessage(chatID, "")
+	replyTo := 0
 	for _, element := range elem {
 		switch e := element.(type) {
 		case *message.TextElement:
 			msg.Text += e.Content
 		case *message.AtElement:
-			leng := len(msg.Text)
+			// entity 的偏移量以 UTF-16 编码计算
+			leng := len(utf16.Encode([]rune(msg.Text)))
 			uid, _ := strconv.ParseInt(e.Target, 10, 64)
 			user := &tgbotapi.User{ID: uid}
 			data := fmt.Sprintf("@%s ", e.Target)
 			msg.Text += data
-			entity := tgbotapi.MessageEntity{Type: "text_mention", Offset: leng, Length: len(data), User: user}
+			entity := tgbotapi.MessageEntity{Type: "text_mention", Offset: leng, Length: len(utf16.Encode([]rune(data))), User: user}
 			msg.Entities = append(msg.Entities, entity)
 			// 安全性问题禁用
 		// case *message.FileElement:
-		//	if msg.Text != "" {
-		//		_, err = bot.Send(msg)
-		//	}
-		//	if err != nil {
-		//		pa.Session.Parent.Logger.Errorf("向Telegram聊天#%d发送消息时出错:%s", id, err)
-		//		return
-		//	}
-		//	msg = tgbotapi.NewMessage(id, "")
 		//	data := &RequestFileDataImpl{File: e.File, Reader: e.Stream}
 		//	f := tgbotapi.NewDocument(id, data)
-		//	_, err = bot.Send(f)
 		case *message.ImageElement:
+			var f tgbotapi.PhotoConfig
 			fi := e.File
-			data := &RequestFileDataImpl{File: fi.File, Reader: fi.Stream}
-			f := tgbotapi.NewPhoto(id, data)
+			switch {
+			case fi != nil && fi.Stream != nil:
+				data := &RequestFileDataImpl{File: fi.File, Reader: fi.Stream}
+				f = tgbotapi.NewPhoto(chatID, data)
+				f.Thumb = data
+			case e.URL != "":
+				f = tgbotapi.NewPhoto(chatID, tgbotapi.FileURL(e.URL))
+			case fi != nil && fi.URL != "":
+				f = tgbotapi.NewPhoto(chatID, tgbotapi.FileURL(fi.URL))
+			case fi != nil && fi.File != "":
+				// 收到的图片只有 file_id，可以直接用于转发
+				f = tgbotapi.NewPhoto(chatID, tgbotapi.FileID(fi.File))
+			default:
+				continue
+			}
 			if msg.Text != "" {
 				f.Caption = msg.Text
 				f.CaptionEntities = msg.Entities
 			}
-			msg = tgbotapi.NewMessage(id, "")
-			f.Thumb = data
-			_, err = bot.Send(f)
-			if err != nil {
-				pa.Session.Parent.Logger.Errorf("向Telegram聊天#%d发送消息时出错:%s", id, err)
-				return nil, err
-			}
+			f.ReplyToMessageID, replyTo = replyTo, 0
+			msg = tgbotapi.NewMessage(chatID, "")
+			ret = append(ret, f)
 		case *message.TTSElement:
 			msg.Text += e.Content
 		case *message.ReplyElement:
 			parseInt, errParse := strconv.ParseInt(e.ReplySeq, 10, 64)
 			if errParse != nil {
-				pa.Session.Parent.Logger.Errorf("向Telegram聊天#%d发送消息时出错:%s", id, errParse)
-				break
+				continue
 			}
-			msg.ReplyToMessageID = int(parseInt)
-		}
-		if err != nil {
-			pa.Session.Parent.Logger.Errorf("向Telegram聊天#%d发送消息时出错:%s", id, err)
-			return nil, err
+			replyTo = int(parseInt)
 		}
 	}
 	if msg.Text != "" || len(msg.Entities) > 0 {
-		resp, err := bot.Send(msg)
-		if err != nil {
-			pa.Session.Parent.Logger.Errorf("向Telegram聊天#%d发送消息时出错:%s", id, err)
+		msg.ReplyToMessageID = replyTo
+		ret = append(ret, msg)
+	}
+	return ret
+}
+
+// tgReplaceMentions 将文本中的提及替换为 tg://user?id= 的形式，无法确定账号的 username 提及保持原样
+func tgReplaceMentions(text string, entities []tgbotapi.MessageEntity, selfName string, selfID int64, lookup func(name string) (int64, bool)) string {
+	if entities == nil {
+		return text
+	}
+	var replacedText strings.Builder
+	index := 0
+	u16 := utf16.Encode([]rune(text))
+	for _, entity := range entities {
+		// 是否包含@信息
+		if entity.IsMention() || entity.Type == "text_mention" {
+			// text mention时User不为nil
+			if entity.User != nil {
+				replacedText.WriteString(string(utf16.Decode(u16[index:entity.Offset])))
+				_, _ = fmt.Fprintf(&replacedText, "tg://user?id=%d", entity.User.ID)
+			} else {
+				// 这里处理最烦人的username mention 首先判断是不是@了机器人自己
+				name := string(utf16.Decode(u16[entity.Offset+1 : entity.Offset+entity.Length]))
+				if selfName == name {
+					replacedText.WriteString(string(utf16.Decode(u16[index:entity.Offset])))
+					_, _ = fmt.Fprintf(&replacedText, "tg://user?id=%d", selfID)
+				} else if v, exist := lookup(name); exist {
+					// @的不是自己，查看是否能从用户名缓存中找到username
+					replacedText.WriteString(string(utf16.Decode(u16[index:entity.Offset])))
+					_, _ = fmt.Fprintf(&replacedText, "tg://user?id=%d", v)
+				} else {
+					// 找不到，没有办法了，现阶段没有通过username获取userid的api
+					replacedText.WriteString(string(utf16.Decode(u16[index : entity.Offset+entity.Length])))
+				}
+			}
+		} else {
+			// 不是mention 忽略
+			replacedText.WriteString(string(utf16.Decode(u16[index : entity.Offset+entity.Length])))
 		}
-		return &resp, err
+		index = entity.Offset + entity.Length
+	}
+	replacedText.WriteString(string(utf16.Decode(u16[index:])))
+	return replacedText.String()
+}
+
+// tgMessageToSegments 将 telegram 消息转换为消息段，text 为已经替换过提及的文字
+func tgMessageToSegments(m *tgbotapi.Message, text string) []message.IMessageElement {
+	var segs []message.IMessageElement
+	if m.ReplyToMessage != nil {
+		reply := &message.ReplyElement{ReplySeq: strconv.Itoa(m.ReplyToMessage.MessageID)}
+		if m.ReplyToMessage.From != nil {
+			reply.Sender = strconv.FormatInt(m.ReplyToMessage.From.ID, 10)
+		}
+		segs = append(segs, reply)
+	}
+	if text != "" {
+		segs = append(segs, splitTextByAt(text, atPatternForPlatform("TG"))...)
+	}
+	// 文字放在图片之前，这样发送时会作为图片的说明
+	if len(m.Photo) > 0 {
+		// 同一张图片的多个尺寸，取最大的一个
+		photo := m.Photo[len(m.Photo)-1]
+		segs = append(segs, &message.ImageElement{File: &message.FileElement{File: photo.FileID}})
+	}
+	if m.Document != nil {
+		segs = append(segs, &message.FileElement{ContentType: m.Document.MimeType, File: m.Document.FileID})
 	}
-	return nil, errors.New("empty message")
+	return segs
 }
 
 func (pa *PlatformAdapterTelegram) QuitGroup(_ *MsgContext, id string) {
@@ -0,0 +1,60 @@
+package dice_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"sealdice-core/dice"
+)
+
+func TestEndPointInfoMarshalIncludesCapabilities(t *testing.T) {
+	ep := &dice.EndPointInfo{
+		EndPointInfoBase: dice.EndPointInfoBase{ID: "ep-1", Platform: "KOOK"},
+		Adapter:          &dice.PlatformAdapterKook{},
+	}
+
+	data, err := json.Marshal(ep)
+	if err != nil {
+		t.Fatalf("marshal endpoint: %v", err)
+	}
+
+	var out struct {
+		ID           string                   `json:"id"`
+		Platform     string                   `json:"platform"`
+		Capabilities dice.AdapterCapabilities `json:"capabilities"`
+	}
+	if err = json.Unmarshal(data, &out); err != nil {
+		t.Fatalf("unmarshal endpoint: %v", err)
+	}
+	if out.ID != "ep-1" || out.Platform != "KOOK" {
+		t.Fatalf("expected base info to be kept, got %+v", out)
+	}
+	if !out.Capabilities.EditMessage || !out.Capabilities.RecallMessage || !out.Capabilities.GuildChannel {
+		t.Fatalf("expected kook capabilities in output, got %+v", out.Capabilities)
+	}
+	if out.Capabilities.MemberBan {
+		t.Fatalf("kook adapter does not implement member ban, got %+v", out.Capabilities)
+	}
+}
+
+func TestEndPointInfoCapabilitiesWithoutAdapter(t *testing.T) {
+	ep := &dice.EndPointInfo{}
+	if caps := ep.Capabilities(); caps != (dice.AdapterCapabilities{}) {
+		t.Fatalf("expected empty capabilities without adapter, got %+v", caps)
+	}
+}
+
+func TestMemberBanReportsUnsupported(t *testing.T) {
+	ctx := &dice.MsgContext{
+		EndPoint: &dice.EndPointInfo{
+			EndPointInfoBase: dice.EndPointInfoBase{Platform: "DISCORD"},
+			Adapter:          &dice.PlatformAdapterDiscord{},
+		},
+	}
+	if dice.MemberBan(ctx, "DISCORD-CH-Group:1", "DISCORD:2", 60) {
+		t.Fatal("expected member ban to be reported as unsupported")
+	}
+	if dice.MemberKick(ctx, "DISCORD-CH-Group:1", "DISCORD:2") {
+		t.Fatal("expected member kick to be reported as unsupported")
+	}
+}
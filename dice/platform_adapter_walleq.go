@@ -952,6 +952,14 @@ func (pa *PlatformAdapterWalleQ) EditMessage(_ *MsgContext, _, _ string) {}
 
 func (pa *PlatformAdapterWalleQ) RecallMessage(_ *MsgContext, _ string) {}
 
+func (pa *PlatformAdapterWalleQ) Capabilities() AdapterCapabilities {
+	return AdapterCapabilities{
+		MemberBan:    true,
+		MemberKick:   true,
+		DeleteFriend: true,
+	}
+}
+
 /* 扩展方法实现 */
 
 func (pa *PlatformAdapterWalleQ) waitGroupMemberInfoEcho(echo string, beforeWait func()) *EventWalleQBase {
@@ -0,0 +1,280 @@
+package dice
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"sealdice-core/utils"
+)
+
+const (
+	RateLimitScopeUser   = "user"   // 每个用户一个桶
+	RateLimitScopeGroup  = "group"  // 每个群一个桶，私聊时按用户计
+	RateLimitScopeGlobal = "global" // 全部共享一个桶
+)
+
+// rateLimitBucketIdle 桶闲置超过此时长后会被清理，此时令牌必然已回满
+const rateLimitBucketIdle = time.Hour
+
+// rateLimitPruneInterval 两次清理闲置桶的最小间隔
+const rateLimitPruneInterval = 10 * time.Minute
+
+// RateLimitPolicy 频率限制策略。匹配条件均为空时对所有指令生效，多个条件之间为“且”的关系
+type RateLimitPolicy struct {
+	Name   string `json:"name"   yaml:"name"`
+	Enable bool   `json:"enable" yaml:"enable"`
+
+	Commands     []string `json:"commands"     yaml:"commands,flow"`   // 指令名，如 draw
+	Extensions   []string `json:"extensions"   yaml:"extensions,flow"` // 指令所属扩展，核心指令为 core
+	Groups       []string `json:"groups"       yaml:"groups,flow"`     // 群号，如 QQ-Group:123
+	Platforms    []string `json:"platforms"    yaml:"platforms,flow"`  // 平台，如 QQ
+	MaxPrivilege *int     `json:"maxPrivilege" yaml:"maxPrivilege"`    // 仅对权限不高于此值的用户生效，空为不限
+	Scope        string   `json:"scope"        yaml:"scope"`           // 计数范围 user/group/global
+
+	RateStr   string `json:"rate"      yaml:"rate"`      // 令牌回复速率，格式同刷屏警告速率，如 @every 10s
+	Burst     int64  `json:"burst"     yaml:"burst"`     // 桶容量
+	Cost      int64  `json:"cost"      yaml:"cost"`      // 每次执行消耗的令牌，默认为 1
+	Message   string `json:"message"   yaml:"message"`   // 冷却提示，留空使用 核心:刷屏_冷却提示
+	SpamScore bool   `json:"spamScore" yaml:"spamScore"` // 警告后仍继续触发时计入指令刷屏怒气值
+
+	ExtName string `json:"extName" yaml:"-"` // 由扩展声明时为扩展名
+
+	rate rate.Limit
+}
+
+// Parse 检查并解析策略，补全默认值
+func (p *RateLimitPolicy) Parse() error {
+	if p.Name == "" {
+		return errors.New("策略名不能为空")
+	}
+	switch p.Scope {
+	case "":
+		p.Scope = RateLimitScopeUser
+	case RateLimitScopeUser, RateLimitScopeGroup, RateLimitScopeGlobal:
+	default:
+		return fmt.Errorf("策略<%s>的计数范围无效: %s", p.Name, p.Scope)
+	}
+	limit, err := utils.ParseRate(p.RateStr)
+	if err != nil || limit <= 0 {
+		return fmt.Errorf("策略<%s>的速率无效: %s", p.Name, p.RateStr)
+	}
+	if p.Burst < 1 {
+		p.Burst = 1
+	}
+	if p.Cost < 1 {
+		p.Cost = 1
+	}
+	if p.Cost > p.Burst {
+		return fmt.Errorf("策略<%s>每次消耗(%d)不能大于桶容量(%d)", p.Name, p.Cost, p.Burst)
+	}
+	p.rate = limit
+	return nil
+}
+
+func (p *RateLimitPolicy) match(ctx *MsgContext, msg *Message, extName string, cmdName string) bool {
+	if !p.Enable || p.rate == 0 {
+		return false
+	}
+	if len(p.Commands) > 0 && !slices.Contains(p.Commands, cmdName) {
+		return false
+	}
+	if len(p.Extensions) > 0 && !slices.Contains(p.Extensions, extName) {
+		return false
+	}
+	if len(p.Groups) > 0 && !slices.Contains(p.Groups, msg.GroupID) {
+		return false
+	}
+	if len(p.Platforms) > 0 && !slices.Contains(p.Platforms, msg.Platform) {
+		return false
+	}
+	if p.MaxPrivilege != nil && ctx.PrivilegeLevel > *p.MaxPrivilege {
+		return false
+	}
+	return true
+}
+
+// bucketKey 同一策略改动速率或容量后使用新的桶
+func (p *RateLimitPolicy) bucketKey(msg *Message) string {
+	key := fmt.Sprintf("%s|%s|%d", p.Name, p.RateStr, p.Burst)
+	switch p.Scope {
+	case RateLimitScopeGlobal:
+		return key
+	case RateLimitScopeGroup:
+		if msg.MessageType == "group" && msg.GroupID != "" {
+			return key + "|" + msg.GroupID
+		}
+	}
+	return key + "|" + msg.Sender.UserID
+}
+
+type rateLimitBucket struct {
+	mu       sync.Mutex
+	limiter  *rate.Limiter
+	warned   bool
+	lastUsed time.Time
+}
+
+// RegisterRateLimitPolicy 扩展声明自己的指令开销，骰主可在界面中以同名策略覆盖
+func (i *ExtInfo) RegisterRateLimitPolicy(p *RateLimitPolicy) error {
+	if p.Name == "" {
+		p.Name = "ext:" + i.Name
+		if len(p.Commands) > 0 {
+			p.Name += ":" + p.Commands[0]
+		}
+	}
+	if len(p.Extensions) == 0 {
+		p.Extensions = []string{i.Name}
+	}
+	p.ExtName = i.Name
+	if err := p.Parse(); err != nil {
+		return err
+	}
+	i.rateLimitMu.Lock()
+	defer i.rateLimitMu.Unlock()
+	// 复制一份再修改，避免影响正在遍历旧切片的读者
+	policies := slices.DeleteFunc(slices.Clone(i.RateLimitPolicies), func(old *RateLimitPolicy) bool {
+		return old.Name == p.Name
+	})
+	i.RateLimitPolicies = append(policies, p)
+	return nil
+}
+
+// RateLimitPolicies 当前生效的全部策略：骰主配置的策略，以及扩展声明且未被同名配置覆盖的策略
+func (d *Dice) RateLimitPolicies() []*RateLimitPolicy {
+	policies := append([]*RateLimitPolicy(nil), d.Config.RateLimitPolicies...)
+	names := map[string]bool{}
+	for _, p := range policies {
+		names[p.Name] = true
+	}
+	for _, wrapper := range d.ExtList {
+		ext := wrapper.GetRealExt()
+		if ext == nil {
+			continue
+		}
+		ext.rateLimitMu.RLock()
+		extPolicies := ext.RateLimitPolicies
+		ext.rateLimitMu.RUnlock()
+		for _, p := range extPolicies {
+			if !names[p.Name] {
+				names[p.Name] = true
+				policies = append(policies, p)
+			}
+		}
+	}
+	return policies
+}
+
+func (d *Dice) rateLimitBucket(key string, p *RateLimitPolicy, now time.Time) *rateLimitBucket {
+	d.rateLimitLock.Lock()
+	defer d.rateLimitLock.Unlock()
+	if d.rateLimitBuckets == nil {
+		d.rateLimitBuckets = map[string]*rateLimitBucket{}
+	}
+	if now.Sub(d.rateLimitPrunedAt) > rateLimitPruneInterval {
+		d.rateLimitPrunedAt = now
+		for k, b := range d.rateLimitBuckets {
+			b.mu.Lock()
+			idle := now.Sub(b.lastUsed) > rateLimitBucketIdle
+			b.mu.Unlock()
+			if idle {
+				delete(d.rateLimitBuckets, k)
+			}
+		}
+	}
+	if b, ok := d.rateLimitBuckets[key]; ok {
+		return b
+	}
+	b := &rateLimitBucket{limiter: rate.NewLimiter(p.rate, int(p.Burst)), lastUsed: now}
+	d.rateLimitBuckets[key] = b
+	return b
+}
+
+// rateLimitPolicyCheck 按策略检查指令能否执行，超限时发出冷却提示并返回 true。
+// 命中多条策略时任一超限即拒绝，且不会消耗其余策略的令牌
+func rateLimitPolicyCheck(ctx *MsgContext, msg *Message, ext *ExtInfo, item *CmdItemInfo) bool {
+	if ctx.PrivilegeLevel >= 100 {
+		return false
+	}
+	d := ctx.Dice
+	extName := "core"
+	if ext != nil {
+		extName = ext.Name
+	}
+
+	type reserved struct {
+		bucket *rateLimitBucket
+		r      *rate.Reservation
+	}
+	var taken []reserved
+	cancel := func(now time.Time) {
+		for _, t := range taken {
+			t.bucket.mu.Lock()
+			t.r.CancelAt(now)
+			t.bucket.mu.Unlock()
+		}
+	}
+
+	now := time.Now()
+	for _, p := range d.RateLimitPolicies() {
+		if !p.match(ctx, msg, extName, item.Name) {
+			continue
+		}
+		b := d.rateLimitBucket(p.bucketKey(msg), p, now)
+		b.mu.Lock()
+		b.lastUsed = now
+		r := b.limiter.ReserveN(now, int(p.Cost))
+		delay := r.DelayFrom(now)
+		if delay == 0 {
+			b.mu.Unlock()
+			taken = append(taken, reserved{bucket: b, r: r})
+			continue
+		}
+		r.CancelAt(now)
+		warned := b.warned
+		b.warned = true
+		b.mu.Unlock()
+		cancel(now)
+
+		seconds := int64(math.Ceil(delay.Seconds()))
+		VarSetValueStr(ctx, "$t指令", item.Name)
+		VarSetValueInt64(ctx, "$t冷却秒数", seconds)
+		VarSetValueStr(ctx, "$t策略", p.Name)
+		text := DiceFormatTmpl(ctx, "核心:刷屏_冷却提示")
+		if p.Message != "" {
+			text = DiceFormat(ctx, p.Message)
+		}
+		scoreID := msg.Sender.UserID
+		if p.Scope == RateLimitScopeGroup && msg.MessageType == "group" {
+			scoreID = msg.GroupID
+		}
+		rateLimitExceeded(ctx, msg, "policy", scoreID, warned, p.SpamScore, text)
+		return true
+	}
+
+	// 全部通过，清除先前的警告状态
+	for _, t := range taken {
+		t.bucket.mu.Lock()
+		t.bucket.warned = false
+		t.bucket.mu.Unlock()
+	}
+	return false
+}
+
+// rateLimitExceeded 超出频率限制时的处理。首次超限发出警告，
+// 已警告过仍继续触发时不再提示，addScore 为 true 则按指令刷屏计入怒气值。刷屏警告与各策略共用此流程
+func rateLimitExceeded(ctx *MsgContext, msg *Message, scope string, uid string, warned bool, addScore bool, warnText string) {
+	if warned {
+		if addScore {
+			ctx.Dice.Config.BanList.AddScoreByCommandSpam(uid, msg.GroupID, ctx)
+		}
+		return
+	}
+	metricRateLimitWarnings.Inc(scope)
+	replyToSenderRawNoCheck(ctx, msg, warnText, "")
+}
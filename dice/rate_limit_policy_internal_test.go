@@ -0,0 +1,97 @@
+//nolint:testpackage
+package dice
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRateLimitPolicyCommandCooldown(t *testing.T) {
+	d, ep, adapter, cleanup := newExecuteNewTestDice(t)
+	defer cleanup()
+
+	policy := &RateLimitPolicy{
+		Name:     "roll",
+		Enable:   true,
+		Commands: []string{d.CmdMap["r"].Name},
+		RateStr:  "@every 1h",
+		Burst:    1,
+	}
+	if err := policy.Parse(); err != nil {
+		t.Fatal(err)
+	}
+	d.Config.RateLimitPolicies = []*RateLimitPolicy{policy}
+
+	d.ImSession.ExecuteNew(ep, newPrivateMsg("QQ:999", ".r 1d6"))
+	if text, ok := adapter.waitForMsg(2 * time.Second); !ok || strings.Contains(text, "过于频繁") {
+		t.Fatalf("first roll should pass, got %q", text)
+	}
+
+	d.ImSession.ExecuteNew(ep, newPrivateMsg("QQ:999", ".r 1d6"))
+	if text, ok := adapter.waitForMsg(2 * time.Second); !ok || !strings.Contains(text, "过于频繁") {
+		t.Fatalf("second roll should be limited, got %q", text)
+	}
+
+	// 已提示过的用户不再重复提示
+	d.ImSession.ExecuteNew(ep, newPrivateMsg("QQ:999", ".r 1d6"))
+	if text, ok := adapter.waitForMsg(500 * time.Millisecond); ok {
+		t.Fatalf("expected silence after warning, got %q", text)
+	}
+
+	// 其他用户使用各自的桶
+	d.ImSession.ExecuteNew(ep, newPrivateMsg("QQ:998", ".r 1d6"))
+	if text, ok := adapter.waitForMsg(2 * time.Second); !ok || strings.Contains(text, "过于频繁") {
+		t.Fatalf("another user should pass, got %q", text)
+	}
+}
+
+func TestRateLimitPolicyExtOverride(t *testing.T) {
+	d, _, _, cleanup := newExecuteNewTestDice(t)
+	defer cleanup()
+
+	ext := &ExtInfo{Name: "test-ext"}
+	if err := ext.RegisterRateLimitPolicy(&RateLimitPolicy{Enable: true, Commands: []string{"draw"}, RateStr: "@every 10s", Burst: 2}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ext.RegisterRateLimitPolicy(&RateLimitPolicy{Enable: true, Commands: []string{"draw"}, RateStr: "@every 10s", Burst: 1, Cost: 2}); err == nil {
+		t.Fatal("cost larger than burst should be rejected")
+	}
+	d.ExtList = append(d.ExtList, ext)
+
+	policies := d.RateLimitPolicies()
+	if len(policies) != 1 || policies[0].Name != "ext:test-ext:draw" || policies[0].Extensions[0] != "test-ext" {
+		t.Fatalf("unexpected policies: %+v", policies)
+	}
+
+	override := &RateLimitPolicy{Name: "ext:test-ext:draw", RateStr: "@every 1s"}
+	if err := override.Parse(); err != nil {
+		t.Fatal(err)
+	}
+	d.Config.RateLimitPolicies = []*RateLimitPolicy{override}
+	policies = d.RateLimitPolicies()
+	if len(policies) != 1 || policies[0] != override {
+		t.Fatalf("config should override ext policy: %+v", policies)
+	}
+}
+
+func TestRateLimitBucketPrunesIdle(t *testing.T) {
+	d := &Dice{}
+	p := &RateLimitPolicy{Name: "roll", RateStr: "@every 1s"}
+	if err := p.Parse(); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	d.rateLimitBucket("a", p, start)
+	d.rateLimitBucket("b", p, start.Add(rateLimitBucketIdle))
+
+	// 即使桶的数量很少，闲置过久的桶也会按间隔被清理
+	d.rateLimitBucket("b", p, start.Add(rateLimitBucketIdle+rateLimitPruneInterval+time.Second))
+	if _, ok := d.rateLimitBuckets["a"]; ok {
+		t.Fatal("idle bucket should be pruned")
+	}
+	if _, ok := d.rateLimitBuckets["b"]; !ok {
+		t.Fatal("recently used bucket should be kept")
+	}
+}
@@ -0,0 +1,303 @@
+package dice
+
+import (
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"strings"
+	"sync"
+
+	ds "github.com/sealdice/dicescript"
+
+	"sealdice-core/message"
+)
+
+// ReplySimulateMessage 模拟时输入的一条消息
+type ReplySimulateMessage struct {
+	Nickname string `json:"nickname"`
+	Message  string `json:"message"`
+}
+
+// ReplySimulateCond 一个条件的判定结果
+type ReplySimulateCond struct {
+	Desc    string `json:"desc"`
+	Matched bool   `json:"matched"`
+}
+
+// ReplySimulateItem 一个回复项的判定结果，条件按顺序判定，遇到不满足的即停止，其后的条件不会出现在列表中
+type ReplySimulateItem struct {
+	Index      int                 `json:"index"`
+	Matched    bool                `json:"matched"`
+	Conditions []ReplySimulateCond `json:"conditions"`
+}
+
+// ReplySimulateOutput 触发后将要执行的动作，不会真正发送
+type ReplySimulateOutput struct {
+	ResultType string  `json:"resultType"`
+	Delay      float64 `json:"delay"`
+	Text       string  `json:"text"`
+}
+
+// ReplySimulateFile 一个回复文件的判定结果
+type ReplySimulateFile struct {
+	Filename   string               `json:"filename"`
+	PackageID  string               `json:"packageId,omitempty"`
+	Matched    bool                 `json:"matched"` // 文件级条件是否满足
+	Conditions []ReplySimulateCond  `json:"conditions"`
+	Items      []*ReplySimulateItem `json:"items"`
+}
+
+// ReplySimulateResult 一条消息的模拟结果
+type ReplySimulateResult struct {
+	Nickname  string                 `json:"nickname"`
+	Message   string                 `json:"message"`
+	Skipped   string                 `json:"skipped,omitempty"` // 不会进入自定义回复的原因，如消息是指令
+	Fired     bool                   `json:"fired"`
+	Filename  string                 `json:"filename,omitempty"`
+	ItemIndex int                    `json:"itemIndex"`
+	Files     []*ReplySimulateFile   `json:"files"`
+	Outputs   []*ReplySimulateOutput `json:"outputs"`
+	Error     string                 `json:"error,omitempty"`
+}
+
+// replyConditionDesc 条件的可读描述
+func replyConditionDesc(cond ReplyConditionBase) string {
+	switch c := cond.(type) {
+	case *ReplyConditionTextMatch:
+		return fmt.Sprintf("文本匹配(%s): %s", c.MatchType, c.Value)
+	case *ReplyConditionExprTrue:
+		return "表达式为真: " + c.Value
+	case *ReplyConditionTextLenLimit:
+		if c.MatchOp == "le" {
+			return fmt.Sprintf("文本长度 <= %d", c.Value)
+		}
+		return fmt.Sprintf("文本长度 >= %d", c.Value)
+	case *ReplyConditionPrivilege:
+		if c.MatchOp == "le" {
+			return fmt.Sprintf("权限 <= %d", c.Value)
+		}
+		return fmt.Sprintf("权限 >= %d", c.Value)
+	case *ReplyConditionGroupID:
+		if c.Exclude {
+			return "群号不属于: " + strings.Join(c.Value, ",")
+		}
+		return "群号属于: " + strings.Join(c.Value, ",")
+	case *ReplyConditionPlatform:
+		if c.Exclude {
+			return "平台不属于: " + strings.Join(c.Value, ",")
+		}
+		return "平台属于: " + strings.Join(c.Value, ",")
+	case *ReplyConditionTimeWindow:
+		return fmt.Sprintf("时间段: %s-%s", c.Start, c.End)
+	case *ReplyConditionUserCooldown:
+		return fmt.Sprintf("每人冷却: %v秒", c.Value)
+	case *ReplyConditionProbability:
+		return fmt.Sprintf("概率: %v%%", c.Value)
+	}
+	return fmt.Sprintf("%T", cond)
+}
+
+// replySimulateConds 已判定条件的结果列表，判定在第一个不满足的条件处停止
+func replySimulateConds(conds ReplyConditions, checked int, ok bool) []ReplySimulateCond {
+	ret := []ReplySimulateCond{}
+	for i, cond := range conds[:checked] {
+		ret = append(ret, ReplySimulateCond{Desc: replyConditionDesc(cond), Matched: ok || i < checked-1})
+	}
+	return ret
+}
+
+// replySimulateResults 求出各动作将要发送的内容，连锁会展开目标回复项
+func replySimulateResults(ctx *MsgContext, msg *Message, cleanText string, rc *ReplyConfig, results []ReplyResultBase, depth int) []*ReplySimulateOutput {
+	pick := func(items *TextTemplateItemList) string {
+		if items == nil || len(*items) == 0 {
+			return ""
+		}
+		return formatExprForReply(ctx, items.toRandomPool().Pick().(string))
+	}
+	outputs := []*ReplySimulateOutput{}
+	for _, r := range results {
+		switch v := r.(type) {
+		case *ReplyResultReplyToSender:
+			outputs = append(outputs, &ReplySimulateOutput{ResultType: "replyToSender", Delay: v.Delay, Text: pick(&v.Message)})
+		case *ReplyResultReplyPrivate:
+			outputs = append(outputs, &ReplySimulateOutput{ResultType: "replyPrivate", Delay: v.Delay, Text: pick(&v.Message)})
+		case *ReplyResultReplyGroup:
+			outputs = append(outputs, &ReplySimulateOutput{ResultType: "replyGroup", Delay: v.Delay, Text: pick(v.Message)})
+		case *ReplyResultDrawDeck:
+			// 不放回抽取会改变牌堆状态，模拟时改为放回
+			text := "找不到牌组: " + v.Deck
+			if exists, result, err := deckDraw(ctx, v.Deck, false); exists {
+				text = result
+				if err != nil {
+					text = err.Error()
+				}
+			}
+			outputs = append(outputs, &ReplySimulateOutput{ResultType: "drawDeck", Delay: v.Delay, Text: text})
+		case *ReplyResultSetVar:
+			// 变量写在模拟专用的用户与群中，结束后会清空
+			v.apply(ctx)
+			outputs = append(outputs, &ReplySimulateOutput{ResultType: "setVar", Delay: v.Delay, Text: v.Name + " = " + v.Value})
+		case *ReplyResultRecallMessage:
+			outputs = append(outputs, &ReplySimulateOutput{ResultType: "recallMessage", Delay: v.Delay, Text: "撤回触发消息"})
+		case *ReplyResultMemberBan:
+			text := fmt.Sprintf("禁言发送者%d秒", v.Duration)
+			if v.Kick {
+				text = "踢出发送者"
+			}
+			outputs = append(outputs, &ReplySimulateOutput{ResultType: "memberBan", Delay: v.Delay, Text: text})
+		case *ReplyResultChain:
+			outputs = append(outputs, &ReplySimulateOutput{ResultType: "chain", Delay: v.Delay, Text: fmt.Sprintf("连锁回复项#%d", v.Index)})
+			next := rc.chainTarget(ctx, v, depth)
+			if next == nil || (v.CheckConditions && !replyCheckConditions(ctx, msg, next.Conditions, cleanText)) {
+				continue
+			}
+			outputs = append(outputs, replySimulateResults(ctx, msg, cleanText, rc, next.Results, depth+1)...)
+		}
+	}
+	return outputs
+}
+
+// 模拟专用的群与用户，与 WebUI 测试窗口(UI-Group:2101、UI:1101)分开，模拟不会动到测试窗口的数据
+const (
+	replySimulateGroupID = "UI-Group:Simulate"
+	replySimulateUserID  = "UI:Simulate"
+)
+
+// replySimulateLock 模拟共用同一组沙盒变量，同一时间只进行一次
+var replySimulateLock sync.Mutex
+
+// replySimulateSandbox 清空模拟用的用户与群的变量，并标记为已保存，避免写入数据库
+func replySimulateSandbox(d *Dice) {
+	for _, id := range []string{replySimulateGroupID, replySimulateUserID, replySimulateGroupID + "-" + replySimulateUserID} {
+		if attrs, _ := d.AttrsManager.LoadById(id); attrs != nil {
+			attrs.Clear()
+			attrs.IsSaved = true
+		}
+	}
+}
+
+// newReplySimulateGroup 模拟用的群，不放入 ServiceAtNew，因此不会出现在群列表中，也不会保存
+func newReplySimulateGroup(d *Dice) *GroupInfo {
+	group := &GroupInfo{
+		Active:            true,
+		GroupID:           replySimulateGroupID,
+		GroupName:         "自定义回复模拟",
+		InactivatedExtSet: StringSet{},
+		Players:           new(SyncMap[string, *GroupPlayerInfo]),
+		DiceIDActiveMap:   new(SyncMap[string, bool]),
+		DiceIDExistsMap:   new(SyncMap[string, bool]),
+		CocRuleIndex:      int(d.Config.DefaultCocRuleIndex),
+	}
+	group.SyncExtensionsOnMessage(d)
+	return group
+}
+
+// ReplySimulate 将消息逐条送入自定义回复判定，报告每个回复项的条件判定情况以及将要发送的内容，不会真正发送。
+// rcs 为空时使用当前已启用的全部回复文件；指定的回复文件即使未启用也会参与判定，便于启用前测试。
+// 模拟在专用的用户与群中进行，变量在整个语料中延续，结束后清空；不考虑回复间隔，也不记录每人冷却。
+// 撤回、禁言等动作只给出描述，不会真正执行。
+func (d *Dice) ReplySimulate(rcs []*ReplyConfig, msgs []ReplySimulateMessage) ([]*ReplySimulateResult, error) {
+	if d.UIEndpoint == nil {
+		return nil, errors.New("UI 端点尚未初始化")
+	}
+	if len(rcs) == 0 {
+		for _, rc := range d.CustomReplyConfig {
+			if rc.Enable {
+				rcs = append(rcs, rc)
+			}
+		}
+	}
+
+	replySimulateLock.Lock()
+	defer replySimulateLock.Unlock()
+	replySimulateSandbox(d)
+	defer replySimulateSandbox(d)
+
+	group := newReplySimulateGroup(d)
+	results := make([]*ReplySimulateResult, 0, len(msgs))
+	for _, m := range msgs {
+		results = append(results, d.replySimulateOne(rcs, group, m))
+	}
+	return results, nil
+}
+
+func (d *Dice) replySimulateOne(rcs []*ReplyConfig, group *GroupInfo, m ReplySimulateMessage) (res *ReplySimulateResult) {
+	res = &ReplySimulateResult{
+		Nickname:  m.Nickname,
+		Message:   m.Message,
+		ItemIndex: -1,
+		Files:     []*ReplySimulateFile{},
+		Outputs:   []*ReplySimulateOutput{},
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			d.Logger.Errorf("自定义回复模拟异常: %v 堆栈: %v", r, string(debug.Stack()))
+			res.Error = fmt.Sprintf("%v", r)
+		}
+	}()
+
+	msg := _MsgCreate("group", m.Message)
+	msg.GroupID = replySimulateGroupID
+	msg.Sender.UserID = replySimulateUserID
+	if m.Nickname != "" {
+		msg.Sender.Nickname = m.Nickname
+	}
+	msg.Segment = []message.IMessageElement{&message.TextElement{Content: m.Message}}
+	player, _ := group.Players.Load(replySimulateUserID)
+	if player == nil {
+		player = &GroupPlayerInfo{UserID: replySimulateUserID, ValueMapTemp: &ds.ValueMap{}}
+		group.Players.Store(replySimulateUserID, player)
+	}
+	player.Name = msg.Sender.Nickname
+	ctx := &MsgContext{
+		MessageType:         msg.MessageType,
+		EndPoint:            d.UIEndpoint,
+		Session:             d.UIEndpoint.Session,
+		Dice:                d,
+		Group:               group,
+		Player:              player,
+		IsCompatibilityTest: true,
+	}
+
+	if CommandParseNew(ctx, msg) != nil {
+		res.Skipped = "指令不会触发自定义回复"
+		return res
+	}
+
+	cleanText, _ := AtParse(msg.Message, "")
+	cleanText = strings.TrimSpace(cleanText)
+	SetTempVars(ctx, msg.Sender.Nickname)
+	VarSetValueInt64(ctx, "$t文本长度", int64(len(cleanText)))
+
+	var file *ReplySimulateFile
+	rc, index := replyMatch(ctx, msg, rcs, cleanText, &replyMatchTrace{
+		file: func(rc *ReplyConfig, checked int, ok bool) {
+			file = &ReplySimulateFile{
+				Filename:   rc.Filename,
+				PackageID:  rc.PackageID,
+				Matched:    ok,
+				Conditions: replySimulateConds(rc.Conditions, checked, ok),
+				Items:      []*ReplySimulateItem{},
+			}
+			res.Files = append(res.Files, file)
+		},
+		item: func(index int, item *ReplyItem, checked int, ok bool) {
+			file.Items = append(file.Items, &ReplySimulateItem{
+				Index:      index,
+				Matched:    ok,
+				Conditions: replySimulateConds(item.Conditions, checked, ok),
+			})
+		},
+	})
+	if rc == nil {
+		return res
+	}
+
+	SetTempVars(ctx, msg.Sender.Nickname)
+	VarSetValueStr(ctx, "$tMsgID", fmt.Sprintf("%v", msg.RawID))
+	res.Fired = true
+	res.Filename = rc.Filename
+	res.ItemIndex = index
+	res.Outputs = replySimulateResults(ctx, msg, cleanText, rc, rc.Items[index].Results, 0)
+	return res
+}
@@ -0,0 +1,150 @@
+//nolint:testpackage
+package dice
+
+import (
+	"testing"
+	"time"
+
+	ds "github.com/sealdice/dicescript"
+	"gopkg.in/yaml.v3"
+)
+
+const replySimulateTestFile = `
+enable: false
+items:
+  - enable: true
+    conditions:
+      - condType: textMatch
+        matchType: matchRegex
+        value: ^早安(.+)$
+    results:
+      - resultType: replyToSender
+        delay: 5
+        message:
+          - ["早安，{$t1}", 1]
+  - enable: true
+    conditions:
+      - condType: textLenLimit
+        matchOp: ge
+        value: 5
+      - condType: textMatch
+        matchType: matchContains
+        value: 晚安
+    results:
+      - resultType: replyToSender
+        message:
+          - ["晚安", 1]
+`
+
+func TestReplySimulate(t *testing.T) {
+	d, _, adapter, cleanup := newExecuteNewTestDice(t)
+	defer cleanup()
+	d.UIEndpoint = &EndPointInfo{
+		EndPointInfoBase: EndPointInfoBase{ID: "ui", UserID: "UI:1000", Platform: "UI", Enable: true},
+		Adapter:          adapter,
+	}
+	d.UIEndpoint.Session = d.ImSession
+
+	rc := &ReplyConfig{Filename: "test.yaml"}
+	if err := yaml.Unmarshal([]byte(replySimulateTestFile), rc); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	results, err := d.ReplySimulate([]*ReplyConfig{rc}, []ReplySimulateMessage{
+		{Nickname: "甲", Message: "早安世界"},
+		{Message: ".r d20"},
+		{Message: "晚安"},
+	})
+	if err != nil || len(results) != 3 {
+		t.Fatalf("unexpected results: %v %v", results, err)
+	}
+	if time.Since(start) > 2*time.Second {
+		t.Error("simulation should not wait for reply delay")
+	}
+
+	// 未启用的文件也能模拟，回复内容只在结果中给出
+	r := results[0]
+	if !r.Fired || r.ItemIndex != 0 || len(r.Outputs) != 1 || r.Outputs[0].Text != "早安，世界" || r.Outputs[0].Delay != 5 {
+		t.Fatalf("unexpected result: %+v %+v", r, r.Outputs)
+	}
+	if results[1].Skipped == "" || results[1].Fired {
+		t.Fatalf("command should be skipped: %+v", results[1])
+	}
+
+	r = results[2]
+	if r.Fired || len(r.Files) != 1 || len(r.Files[0].Items) != 2 {
+		t.Fatalf("unexpected result: %+v", r)
+	}
+	item := r.Files[0].Items[1]
+	if item.Matched || len(item.Conditions) != 1 || item.Conditions[0].Matched || item.Conditions[0].Desc != "文本长度 >= 5" {
+		t.Fatalf("length condition should fail first: %+v", item)
+	}
+
+	if _, ok := adapter.waitForMsg(100 * time.Millisecond); ok {
+		t.Fatal("simulation should not send anything")
+	}
+}
+
+func TestReplySimulateChain(t *testing.T) {
+	d, _, adapter, cleanup := newExecuteNewTestDice(t)
+	defer cleanup()
+	d.UIEndpoint = &EndPointInfo{
+		EndPointInfoBase: EndPointInfoBase{ID: "ui", UserID: "UI:1000", Platform: "UI", Enable: true},
+		Adapter:          adapter,
+	}
+	d.UIEndpoint.Session = d.ImSession
+
+	rc := &ReplyConfig{Filename: "chain.yaml"}
+	if err := yaml.Unmarshal([]byte(`
+items:
+  - enable: true
+    conditions:
+      - condType: textMatch
+        matchType: matchExact
+        value: 骰子
+    results:
+      - resultType: setVar
+        name: $m计数
+        value: "7"
+      - resultType: memberBan
+        duration: 60
+      - resultType: chain
+        index: 1
+        checkConditions: true
+  - enable: true
+    conditions:
+      - condType: exprTrue
+        value: $m计数 == 7
+    results:
+      - resultType: replyToSender
+        message:
+          - ["计数{$m计数}", 1]
+`), rc); err != nil {
+		t.Fatal(err)
+	}
+
+	// WebUI 测试窗口的数据不受模拟影响
+	console, _ := d.AttrsManager.LoadById("UI:1101")
+	console.Store("保留", ds.NewIntVal(1))
+
+	results, err := d.ReplySimulate([]*ReplyConfig{rc}, []ReplySimulateMessage{{Message: "骰子"}})
+	if err != nil || len(results) != 1 {
+		t.Fatalf("unexpected results: %v %v", results, err)
+	}
+	if v := console.Load("保留"); v == nil || console.Load("$m计数") != nil {
+		t.Fatalf("test console attrs changed: %v", console.Load("$m计数"))
+	}
+	if _, ok := d.ImSession.ServiceAtNew.Load(replySimulateGroupID); ok {
+		t.Fatal("sandbox group should not be registered")
+	}
+	outputs := results[0].Outputs
+	if len(outputs) != 4 || outputs[1].Text != "禁言发送者60秒" || outputs[3].Text != "计数7" {
+		for _, o := range outputs {
+			t.Logf("%+v", o)
+		}
+		t.Fatal("unexpected outputs")
+	}
+	if _, ok := adapter.waitForMsg(100 * time.Millisecond); ok {
+		t.Fatal("simulation should not send messages")
+	}
+}
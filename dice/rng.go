@@ -0,0 +1,496 @@
+package dice
+
+import (
+	"bytes"
+	"crypto/hmac"
+	crand "crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	rand2 "golang.org/x/exp/rand" //nolint:staticcheck // 与 dicescript 的随机源保持一致
+
+	"sealdice-core/dice/service"
+)
+
+const (
+	RNGModeDefault = "default" // 进程内全局随机源，即以往的行为
+	RNGModeCrypto  = "crypto"  // 每条消息从 crypto/rand 取种子
+	RNGModeSeeded  = "seeded"  // 群内公开种子，任何人可复现
+	RNGModeCommit  = "commit"  // 先公布种子哈希，事后揭示种子
+)
+
+// RandomProvider 随机源提供者。每条消息首次需要骰点时调用一次 NewSource，
+// 该消息内的全部骰点依次从返回的随机源中取数
+type RandomProvider interface {
+	Name() string
+	// NewSource 返回 nil 随机源时使用全局随机源；record 不为空时会被记入跑团日志
+	NewSource(ctx *MsgContext) (*rand2.PCGSource, *RNGRecord)
+}
+
+// RNGRecord 一条消息所用随机源的可验证信息，与群号一起即可离线复现骰点结果
+type RNGRecord struct {
+	Mode       string `json:"mode"`
+	Epoch      int64  `json:"epoch,omitempty"`
+	Index      uint64 `json:"index,omitempty"`
+	Seed       string `json:"seed,omitempty"`       // 仅公开种子模式
+	Commitment string `json:"commitment,omitempty"` // 种子的 sha256，承诺-揭示模式下事后以此核对种子
+}
+
+// GroupRNGState 群内的随机源状态。Epoch 在更换种子时递增，Counter 为本轮已分配的序号。
+// 种子不随群信息序列化(群信息会经由接口公开)，而是单独存放在种子表中，需要时再读取
+type GroupRNGState struct {
+	Mode       string `json:"mode"`
+	Seed       string `json:"-"`
+	Commitment string `json:"commitment,omitempty"`
+	Epoch      int64  `json:"epoch"`
+	Counter    uint64 `json:"counter"`
+
+	seedSaved bool // 种子是否已写入种子表
+}
+
+// UnmarshalJSON 兼容旧数据中随群信息保存的种子，读入后会在下次使用时转存到种子表
+func (st *GroupRNGState) UnmarshalJSON(data []byte) error {
+	type plain GroupRNGState
+	aux := struct {
+		*plain
+		LegacySeed string `json:"seed"`
+	}{plain: (*plain)(st)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	if aux.LegacySeed != "" {
+		st.Seed = aux.LegacySeed
+	}
+	return nil
+}
+
+var (
+	randomProvidersLock sync.RWMutex
+	randomProviders     = map[string]RandomProvider{}
+
+	// rngStateLock 保护全部群的 GroupRNGState
+	rngStateLock sync.Mutex
+)
+
+func init() {
+	RegisterRandomProvider(defaultRandomProvider{})
+	RegisterRandomProvider(cryptoRandomProvider{})
+	RegisterRandomProvider(&groupSeedRandomProvider{name: RNGModeSeeded, public: true})
+	RegisterRandomProvider(&groupSeedRandomProvider{name: RNGModeCommit})
+}
+
+// RegisterRandomProvider 注册随机源，同名时覆盖
+func RegisterRandomProvider(p RandomProvider) {
+	randomProvidersLock.Lock()
+	defer randomProvidersLock.Unlock()
+	randomProviders[p.Name()] = p
+}
+
+func GetRandomProvider(name string) RandomProvider {
+	randomProvidersLock.RLock()
+	defer randomProvidersLock.RUnlock()
+	return randomProviders[name]
+}
+
+func RandomProviderNames() []string {
+	randomProvidersLock.RLock()
+	defer randomProvidersLock.RUnlock()
+	names := make([]string, 0, len(randomProviders))
+	for k := range randomProviders {
+		names = append(names, k)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// DeriveRNGSource 由种子、群号与序号导出随机源：HMAC-SHA256(seed, 群号 0x00 序号) 的前16字节作为 PCG 状态。
+// 离线验证时使用与骰子相同的算法即可复现
+func DeriveRNGSource(seed []byte, groupID string, index uint64) *rand2.PCGSource {
+	mac := hmac.New(sha256.New, seed)
+	mac.Write([]byte(groupID))
+	mac.Write([]byte{0})
+	_ = binary.Write(mac, binary.BigEndian, index)
+	sum := mac.Sum(nil)
+
+	src := &rand2.PCGSource{}
+	_ = src.UnmarshalBinary(sum[:16])
+	return src
+}
+
+// RNGCommitment 种子的承诺值
+func RNGCommitment(seed []byte) string {
+	sum := sha256.Sum256(seed)
+	return hex.EncodeToString(sum[:])
+}
+
+// VerifyRNGCommitment 检查揭示的种子(hex)是否与先前公布的承诺值一致
+func VerifyRNGCommitment(seedHex string, commitment string) bool {
+	seed, err := hex.DecodeString(strings.TrimSpace(seedHex))
+	if err != nil || len(seed) == 0 {
+		return false
+	}
+	return strings.EqualFold(RNGCommitment(seed), strings.TrimSpace(commitment))
+}
+
+func newRNGSeed() []byte {
+	seed := make([]byte, 32)
+	_, _ = crand.Read(seed)
+	return seed
+}
+
+type defaultRandomProvider struct{}
+
+func (defaultRandomProvider) Name() string { return RNGModeDefault }
+
+func (defaultRandomProvider) NewSource(_ *MsgContext) (*rand2.PCGSource, *RNGRecord) {
+	return nil, nil
+}
+
+type cryptoRandomProvider struct{}
+
+func (cryptoRandomProvider) Name() string { return RNGModeCrypto }
+
+func (cryptoRandomProvider) NewSource(_ *MsgContext) (*rand2.PCGSource, *RNGRecord) {
+	buf := make([]byte, 16)
+	if _, err := crand.Read(buf); err != nil {
+		return nil, nil
+	}
+	src := &rand2.PCGSource{}
+	_ = src.UnmarshalBinary(buf)
+	return src, &RNGRecord{Mode: RNGModeCrypto}
+}
+
+// groupSeedRandomProvider 以群种子派生随机源，public 决定种子是否随日志公开
+type groupSeedRandomProvider struct {
+	name   string
+	public bool
+}
+
+func (p *groupSeedRandomProvider) Name() string { return p.name }
+
+func (p *groupSeedRandomProvider) NewSource(ctx *MsgContext) (*rand2.PCGSource, *RNGRecord) {
+	group := ctx.Group
+	if group == nil {
+		return nil, nil
+	}
+	loadGroupRNGSeed(ctx.Dice, group)
+
+	// 序号在这里先占用，消息处理完没有骰点时由 finishRandSource 归还
+	rngStateLock.Lock()
+	st := group.RNG
+	if st == nil || st.Seed == "" {
+		// 由全局配置启用、群内还没有种子时自动生成
+		st = groupRNGReseed(group, p.name, nil)
+	}
+	seed, _ := hex.DecodeString(st.Seed)
+	st.Counter++
+	record := &RNGRecord{Mode: p.name, Epoch: st.Epoch, Index: st.Counter, Commitment: st.Commitment}
+	rngStateLock.Unlock()
+	persistGroupRNGSeed(ctx.Dice, group)
+
+	if p.public {
+		record.Seed = hex.EncodeToString(seed)
+	}
+	return DeriveRNGSource(seed, group.GroupID, record.Index), record
+}
+
+// loadGroupRNGSeed 群内有承诺值但种子尚未读入时，从种子表中读取。承诺值对不上的种子不会被采用
+func loadGroupRNGSeed(d *Dice, group *GroupInfo) {
+	rngStateLock.Lock()
+	st := group.RNG
+	need := st != nil && st.Seed == "" && st.Commitment != ""
+	rngStateLock.Unlock()
+	if !need || d == nil || d.DBOperator == nil {
+		return
+	}
+
+	seedHex, err := service.GroupRNGSeedGet(d.DBOperator, group.GroupID)
+	if err != nil {
+		d.Logger.Errorf("读取群<%s>随机源种子失败: %v", group.GroupID, err)
+		return
+	}
+	if seedHex == "" {
+		return
+	}
+	rngStateLock.Lock()
+	defer rngStateLock.Unlock()
+	if st = group.RNG; st != nil && st.Seed == "" && VerifyRNGCommitment(seedHex, st.Commitment) {
+		st.Seed = seedHex
+		st.seedSaved = true
+	}
+}
+
+// persistGroupRNGSeed 把尚未保存的群种子写入种子表
+func persistGroupRNGSeed(d *Dice, group *GroupInfo) {
+	rngStateLock.Lock()
+	st := group.RNG
+	seed := ""
+	if st != nil && !st.seedSaved {
+		seed = st.Seed
+	}
+	rngStateLock.Unlock()
+	if seed == "" || d == nil || d.DBOperator == nil {
+		return
+	}
+
+	if err := service.GroupRNGSeedSet(d.DBOperator, group.GroupID, seed); err != nil {
+		d.Logger.Errorf("保存群<%s>随机源种子失败: %v", group.GroupID, err)
+		return
+	}
+	rngStateLock.Lock()
+	if st = group.RNG; st != nil && st.Seed == seed {
+		st.seedSaved = true
+	}
+	rngStateLock.Unlock()
+}
+
+// groupRNGReseed 更换群种子并开始新的一轮，seed 为空时随机生成。调用方需持有 rngStateLock
+func groupRNGReseed(group *GroupInfo, mode string, seed []byte) *GroupRNGState {
+	if len(seed) == 0 {
+		seed = newRNGSeed()
+	}
+	st := group.RNG
+	if st == nil {
+		st = &GroupRNGState{}
+		group.RNG = st
+	}
+	st.Mode = mode
+	st.Seed = hex.EncodeToString(seed)
+	st.Commitment = RNGCommitment(seed)
+	st.Epoch++
+	st.Counter = 0
+	st.seedSaved = false
+	return st
+}
+
+// RNGMode 当前上下文使用的随机源模式，群设置优先于全局配置
+func (ctx *MsgContext) RNGMode() string {
+	if ctx.Group != nil {
+		rngStateLock.Lock()
+		st := ctx.Group.RNG
+		mode := ""
+		if st != nil {
+			mode = st.Mode
+		}
+		rngStateLock.Unlock()
+		if mode != "" {
+			return mode
+		}
+	}
+	if ctx.Dice != nil && ctx.Dice.Config.RNGMode != "" {
+		return ctx.Dice.Config.RNGMode
+	}
+	return RNGModeDefault
+}
+
+// setupRandSource 为新建的 vm 挑选随机源，v1 与 v2 骰点共用
+func (ctx *MsgContext) setupRandSource() {
+	p := GetRandomProvider(ctx.RNGMode())
+	if p == nil {
+		return
+	}
+	src, record := p.NewSource(ctx)
+	if src == nil {
+		return
+	}
+	ctx.vm.RandSrc = src
+	ctx._v1Rand = src
+	ctx.rngRecord = record
+	ctx.rngInitial, _ = src.MarshalBinary()
+}
+
+// finishRandSource 消息处理完毕时调用：随机源被用过才保存群内序号，没用过则归还先前占用的序号，
+// 这样不骰点的消息既不会推进序号，也不会让群信息反复落盘
+func (ctx *MsgContext) finishRandSource() {
+	record := ctx.rngRecord
+	if record == nil || record.Index == 0 || ctx.Group == nil || ctx._v1Rand == nil {
+		return
+	}
+	state, _ := ctx._v1Rand.MarshalBinary()
+	if !bytes.Equal(state, ctx.rngInitial) {
+		ctx.Group.MarkDirty(ctx.Dice)
+		return
+	}
+	rngStateLock.Lock()
+	// 之后已有其他消息占用了更大的序号时只能留空
+	if st := ctx.Group.RNG; st != nil && st.Epoch == record.Epoch && st.Counter == record.Index {
+		st.Counter--
+	}
+	rngStateLock.Unlock()
+}
+
+// logCommandInfo 写入日志的指令信息，附带本条消息的随机源记录
+func logCommandInfo(ctx *MsgContext) interface{} {
+	info, ok := ctx.CommandInfo.(map[string]interface{})
+	if !ok || ctx.rngRecord == nil {
+		return ctx.CommandInfo
+	}
+	ret := make(map[string]interface{}, len(info)+1)
+	for k, v := range info {
+		ret[k] = v
+	}
+	ret["rng"] = ctx.rngRecord
+	return ret
+}
+
+var errRNGNotCommit = errors.New("当前不是承诺-揭示模式")
+
+// SetGroupRNGMode 切换群的随机源模式。seeded 模式可指定种子(hex)；
+// 离开 commit 模式时返回尚未揭示的旧种子，调用方应当公开
+func SetGroupRNGMode(group *GroupInfo, mode string, seedHex string) (st GroupRNGState, revealed *GroupRNGState, err error) {
+	if GetRandomProvider(mode) == nil {
+		return st, nil, fmt.Errorf("未知的随机源模式: %s", mode)
+	}
+	var seed []byte
+	if seedHex != "" {
+		if mode != RNGModeSeeded {
+			return st, nil, errors.New("只有公开种子模式可以指定种子")
+		}
+		seed, err = hex.DecodeString(seedHex)
+		if err != nil || len(seed) < 8 {
+			return st, nil, errors.New("种子须为至少16位的十六进制字符串")
+		}
+	}
+
+	rngStateLock.Lock()
+	defer rngStateLock.Unlock()
+	if old := group.RNG; old != nil && old.Mode == RNGModeCommit && old.Seed != "" {
+		cp := *old
+		revealed = &cp
+	}
+	switch mode {
+	case RNGModeSeeded, RNGModeCommit:
+		groupRNGReseed(group, mode, seed)
+	default:
+		if group.RNG == nil {
+			group.RNG = &GroupRNGState{}
+		}
+		group.RNG.Mode = mode
+		group.RNG.Seed = ""
+		group.RNG.Commitment = ""
+		group.RNG.Counter = 0
+	}
+	return *group.RNG, revealed, nil
+}
+
+// RevealGroupRNGSeed 揭示当前一轮的种子，并立即换用新种子开始下一轮
+func RevealGroupRNGSeed(group *GroupInfo) (revealed GroupRNGState, next GroupRNGState, err error) {
+	rngStateLock.Lock()
+	defer rngStateLock.Unlock()
+	st := group.RNG
+	if st == nil || st.Mode != RNGModeCommit || st.Seed == "" {
+		return revealed, next, errRNGNotCommit
+	}
+	revealed = *st
+	next = *groupRNGReseed(group, RNGModeCommit, nil)
+	return revealed, next, nil
+}
+
+func groupRNGSnapshot(group *GroupInfo) GroupRNGState {
+	rngStateLock.Lock()
+	defer rngStateLock.Unlock()
+	if group.RNG == nil {
+		return GroupRNGState{}
+	}
+	return *group.RNG
+}
+
+const helpRng = `.rng // 查看本群随机源
+.rng mode default/crypto // 切换为默认/crypto随机源
+.rng mode seeded [种子] // 公开种子，骰点可由任何人复现
+.rng mode commit // 承诺-揭示，先公布种子哈希，结束后揭示
+.rng reveal // 揭示本轮种子并开始新一轮
+.rng verify <种子> <哈希> // 核对揭示的种子`
+
+var cmdRng = &CmdItemInfo{
+	Name:      "rng",
+	ShortHelp: helpRng,
+	Help:      "随机源设置:\n" + helpRng,
+	Solve: func(ctx *MsgContext, msg *Message, cmdArgs *CmdArgs) CmdExecuteResult {
+		describe := func(st GroupRNGState, mode string) string {
+			text := fmt.Sprintf("当前随机源: %s", mode)
+			switch mode {
+			case RNGModeSeeded:
+				text += fmt.Sprintf("\n第%d轮 已用序号%d\n种子: %s", st.Epoch, st.Counter, st.Seed)
+			case RNGModeCommit:
+				text += fmt.Sprintf("\n第%d轮 已用序号%d\n种子哈希(sha256): %s", st.Epoch, st.Counter, st.Commitment)
+			}
+			return text
+		}
+		revealText := func(st *GroupRNGState) string {
+			return fmt.Sprintf("第%d轮种子揭示(共%d次): %s\n哈希: %s", st.Epoch, st.Counter, st.Seed, st.Commitment)
+		}
+
+		switch strings.ToLower(cmdArgs.GetArgN(1)) {
+		case "", "status":
+			if ctx.Group == nil {
+				return CmdExecuteResult{Matched: true, Solved: true}
+			}
+			loadGroupRNGSeed(ctx.Dice, ctx.Group)
+			ReplyToSender(ctx, msg, describe(groupRNGSnapshot(ctx.Group), ctx.RNGMode()))
+		case "mode":
+			mode := strings.ToLower(cmdArgs.GetArgN(2))
+			if mode == "" {
+				return CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+			}
+			if ctx.Group == nil {
+				return CmdExecuteResult{Matched: true, Solved: true}
+			}
+			if ctx.PrivilegeLevel < 40 {
+				ReplyToSender(ctx, msg, DiceFormatTmpl(ctx, "核心:提示_无权限_非master/管理"))
+				return CmdExecuteResult{Matched: true, Solved: true}
+			}
+			// 离开承诺-揭示模式时要公开旧种子，先确保它已读入
+			loadGroupRNGSeed(ctx.Dice, ctx.Group)
+			st, revealed, err := SetGroupRNGMode(ctx.Group, mode, cmdArgs.GetArgN(3))
+			if err != nil {
+				ReplyToSender(ctx, msg, err.Error()+"\n可用模式: "+strings.Join(RandomProviderNames(), "/"))
+				return CmdExecuteResult{Matched: true, Solved: true}
+			}
+			persistGroupRNGSeed(ctx.Dice, ctx.Group)
+			ctx.Group.MarkDirty(ctx.Dice)
+			text := describe(st, mode)
+			if revealed != nil {
+				text = revealText(revealed) + "\n" + text
+			}
+			ReplyToSender(ctx, msg, text)
+		case "reveal":
+			if ctx.Group == nil {
+				return CmdExecuteResult{Matched: true, Solved: true}
+			}
+			if ctx.PrivilegeLevel < 40 {
+				ReplyToSender(ctx, msg, DiceFormatTmpl(ctx, "核心:提示_无权限_非master/管理"))
+				return CmdExecuteResult{Matched: true, Solved: true}
+			}
+			loadGroupRNGSeed(ctx.Dice, ctx.Group)
+			revealed, next, err := RevealGroupRNGSeed(ctx.Group)
+			if err != nil {
+				ReplyToSender(ctx, msg, err.Error())
+				return CmdExecuteResult{Matched: true, Solved: true}
+			}
+			persistGroupRNGSeed(ctx.Dice, ctx.Group)
+			ctx.Group.MarkDirty(ctx.Dice)
+			ReplyToSender(ctx, msg, revealText(&revealed)+"\n"+describe(next, RNGModeCommit))
+		case "verify":
+			seed, commitment := cmdArgs.GetArgN(2), cmdArgs.GetArgN(3)
+			if seed == "" || commitment == "" {
+				return CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+			}
+			if VerifyRNGCommitment(seed, commitment) {
+				ReplyToSender(ctx, msg, "核对通过: 种子与哈希一致")
+			} else {
+				ReplyToSender(ctx, msg, "核对失败: 种子与哈希不一致")
+			}
+		default:
+			return CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+		}
+		return CmdExecuteResult{Matched: true, Solved: true}
+	},
+}
@@ -0,0 +1,235 @@
+//nolint:testpackage
+package dice
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"sealdice-core/model"
+	"sealdice-core/utils/constant"
+)
+
+func TestDeriveRNGSource(t *testing.T) {
+	seed := []byte("0123456789abcdef")
+	a := DeriveRNGSource(seed, "QQ-Group:1", 1).Uint64()
+	if b := DeriveRNGSource(seed, "QQ-Group:1", 1).Uint64(); a != b {
+		t.Fatalf("same input should give same source: %d != %d", a, b)
+	}
+	if b := DeriveRNGSource(seed, "QQ-Group:1", 2).Uint64(); a == b {
+		t.Fatal("different index should give different source")
+	}
+	if b := DeriveRNGSource(seed, "QQ-Group:2", 1).Uint64(); a == b {
+		t.Fatal("different group should give different source")
+	}
+}
+
+func TestRNGSeededRollsReproducible(t *testing.T) {
+	d, ep, adapter, cleanup := newExecuteNewTestDice(t)
+	defer cleanup()
+	groupID := "QQ-Group:114"
+	seed := "00112233445566778899aabbccddeeff"
+
+	roll := func() string {
+		d.ImSession.ExecuteNew(ep, newGroupMsg(groupID, "QQ:999", ".r d1000000"))
+		text, ok := adapter.waitForMsg(2 * time.Second)
+		if !ok {
+			t.Fatal("no reply")
+		}
+		return text
+	}
+	roll()
+	group, ok := d.ImSession.ServiceAtNew.Load(groupID)
+	if !ok {
+		t.Fatal("group not created")
+	}
+
+	if _, _, err := SetGroupRNGMode(group, RNGModeSeeded, seed); err != nil {
+		t.Fatal(err)
+	}
+	first := []string{roll(), roll()}
+	if group.RNG.Counter == 0 || group.RNG.Epoch != 1 {
+		t.Fatalf("unexpected state: %+v", group.RNG)
+	}
+
+	// 换回同一种子后序号从头开始，结果完全相同
+	if _, _, err := SetGroupRNGMode(group, RNGModeSeeded, seed); err != nil {
+		t.Fatal(err)
+	}
+	if second := []string{roll(), roll()}; second[0] != first[0] || second[1] != first[1] {
+		t.Fatalf("seeded rolls differ: %q vs %q", first, second)
+	}
+	if first[0] == first[1] {
+		t.Fatalf("consecutive rolls should use different indexes: %q", first)
+	}
+}
+
+func TestRNGCommitReveal(t *testing.T) {
+	group := &GroupInfo{GroupID: "QQ-Group:1"}
+	st, revealed, err := SetGroupRNGMode(group, RNGModeCommit, "")
+	if err != nil || revealed != nil {
+		t.Fatalf("unexpected: %v %v", revealed, err)
+	}
+	if _, _, err := SetGroupRNGMode(group, RNGModeCommit, "00112233445566778899"); err == nil {
+		t.Fatal("commit mode should not accept a chosen seed")
+	}
+
+	ctx := &MsgContext{Group: group}
+	src, record := GetRandomProvider(RNGModeCommit).NewSource(ctx)
+	if src == nil || record.Index != 1 || record.Seed != "" || record.Commitment != st.Commitment {
+		t.Fatalf("unexpected record: %+v", record)
+	}
+	ctx.rngRecord = record
+	ctx.CommandInfo = map[string]interface{}{"cmd": "roll"}
+	info := logCommandInfo(ctx).(map[string]interface{})
+	if info["rng"] != record || info["cmd"] != "roll" {
+		t.Fatalf("unexpected command info: %v", info)
+	}
+
+	old, next, err := RevealGroupRNGSeed(group)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !VerifyRNGCommitment(old.Seed, record.Commitment) || VerifyRNGCommitment(next.Seed, record.Commitment) {
+		t.Fatal("revealed seed should match the published commitment")
+	}
+	if next.Epoch != old.Epoch+1 || next.Counter != 0 {
+		t.Fatalf("reveal should start a new epoch: %+v", next)
+	}
+
+	// 揭示后用种子复现该次随机源
+	seed, _ := hex.DecodeString(old.Seed)
+	if DeriveRNGSource(seed, group.GroupID, record.Index).Uint64() != src.Uint64() {
+		t.Fatal("derived source does not match")
+	}
+
+	if _, revealed, _ = SetGroupRNGMode(group, RNGModeCrypto, ""); revealed == nil || revealed.Seed != next.Seed {
+		t.Fatal("leaving commit mode should reveal the pending seed")
+	}
+	if _, _, err = RevealGroupRNGSeed(group); err == nil {
+		t.Fatal("reveal should fail outside commit mode")
+	}
+}
+
+func TestRNGSeedKeptOutOfGroupInfo(t *testing.T) {
+	d, ep, adapter, cleanup := newExecuteNewTestDice(t)
+	defer cleanup()
+	if err := d.DBOperator.GetDataDB(constant.WRITE).AutoMigrate(&model.GroupRNGSeed{}); err != nil {
+		t.Fatal(err)
+	}
+	groupID := "QQ-Group:115"
+	d.ImSession.ExecuteNew(ep, newGroupMsg(groupID, "QQ:999", ".r d100"))
+	adapter.waitForMsg(2 * time.Second)
+	group, _ := d.ImSession.ServiceAtNew.Load(groupID)
+
+	st, _, err := SetGroupRNGMode(group, RNGModeCommit, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	persistGroupRNGSeed(d, group)
+	data, err := json.Marshal(group)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), st.Seed) {
+		t.Fatal("group info json should not contain the seed")
+	}
+
+	// 从序列化结果恢复后，种子由种子表补回
+	restored := &GroupInfo{}
+	if err = json.Unmarshal(data, restored); err != nil {
+		t.Fatal(err)
+	}
+	if restored.RNG.Seed != "" || restored.RNG.Commitment != st.Commitment {
+		t.Fatalf("unexpected restored state: %+v", restored.RNG)
+	}
+	loadGroupRNGSeed(d, restored)
+	if restored.RNG.Seed != st.Seed {
+		t.Fatal("seed should be loaded from the seed table")
+	}
+
+	// 旧数据中随群信息保存的种子仍能读入
+	legacy := &GroupInfo{}
+	if err = json.Unmarshal([]byte(`{"rng":{"mode":"seeded","seed":"00112233","epoch":1}}`), legacy); err != nil {
+		t.Fatal(err)
+	}
+	if legacy.RNG.Seed != "00112233" {
+		t.Fatalf("legacy seed not read: %+v", legacy.RNG)
+	}
+}
+
+func TestRNGCounterOnlyAdvancesOnRoll(t *testing.T) {
+	d, ep, adapter, cleanup := newExecuteNewTestDice(t)
+	defer cleanup()
+	groupID := "QQ-Group:116"
+	send := func(text string) {
+		d.ImSession.ExecuteNew(ep, newGroupMsg(groupID, "QQ:999", text))
+		adapter.waitForMsg(time.Second)
+	}
+	send(".r d100")
+	group, _ := d.ImSession.ServiceAtNew.Load(groupID)
+	if _, _, err := SetGroupRNGMode(group, RNGModeSeeded, "00112233445566778899aabbccddeeff"); err != nil {
+		t.Fatal(err)
+	}
+
+	// 序号在回复发出后才归还，这里等待处理结束
+	waitCounter := func(want uint64) {
+		deadline := time.Now().Add(2 * time.Second)
+		for groupRNGSnapshot(group).Counter != want && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+		if got := groupRNGSnapshot(group).Counter; got != want {
+			t.Fatalf("counter = %d, want %d", got, want)
+		}
+	}
+	send(".rng")
+	waitCounter(0)
+	send(".help")
+	waitCounter(0)
+	send(".r d100")
+	send(".r d100")
+	waitCounter(2)
+}
+
+// 保存群信息与骰点同时进行时，序列化的是持锁取得的快照
+func TestRNGStateMarshalConcurrentWithRolls(t *testing.T) {
+	group := &GroupInfo{GroupID: "QQ-Group:118"}
+	if _, _, err := SetGroupRNGMode(group, RNGModeSeeded, ""); err != nil {
+		t.Fatal(err)
+	}
+	ctx := &MsgContext{Group: group}
+	provider := GetRandomProvider(RNGModeSeeded)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range 500 {
+			provider.NewSource(ctx)
+		}
+	}()
+	var last uint64
+	for finished := false; !finished; {
+		select {
+		case <-done:
+			finished = true
+		default:
+		}
+		data, err := json.Marshal(group)
+		if err != nil {
+			t.Fatal(err)
+		}
+		restored := &GroupInfo{}
+		if err = json.Unmarshal(data, restored); err != nil {
+			t.Fatal(err)
+		}
+		if restored.RNG.Counter < last {
+			t.Fatalf("counter went backwards: %d < %d", restored.RNG.Counter, last)
+		}
+		last = restored.RNG.Counter
+	}
+	if last != 500 {
+		t.Fatalf("final counter = %d, want 500", last)
+	}
+}
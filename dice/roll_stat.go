@@ -0,0 +1,333 @@
+package dice
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"sealdice-core/dice/service"
+	"sealdice-core/model"
+)
+
+var reRollStatSkill = regexp.MustCompile(`^([^\d\s]+)(\d+)?$`)
+
+// rollStatSkillName 去掉技能名后附带的数值，并换成规则模板中的标准名。只有数值时返回空字符串
+func rollStatSkillName(tmpl *GameSystemTemplate, s string) string {
+	s = strings.TrimSpace(s)
+	if m := reRollStatSkill.FindStringSubmatch(s); len(m) > 0 {
+		s = m[1]
+	}
+	if s == "" || (s[0] >= '0' && s[0] <= '9') {
+		return ""
+	}
+	if tmpl == nil {
+		return s
+	}
+	return tmpl.GetAlias(s)
+}
+
+// rollStatCurCharID 当前角色的属性ID，有绑定卡时为绑定卡，否则为群内默认卡
+func rollStatCurCharID(ctx *MsgContext) string {
+	am := ctx.Dice.AttrsManager
+	if id, _ := am.CharGetBindingId(ctx.Group.GroupID, ctx.Player.UserID); id != "" {
+		return id
+	}
+	return fmt.Sprintf("%s-%s", ctx.Group.GroupID, am.UIDConvert(ctx.Player.UserID))
+}
+
+// rollStatLabel 标注随后的一次检定所用的技能，expr 为技能表达式，如 侦查50。
+// 检定指令在判定前调用，未标注的判定(对抗、成长等)不计入统计
+func rollStatLabel(ctx *MsgContext, expr string) {
+	var tmpl *GameSystemTemplate
+	if ctx.Group != nil && ctx.Dice != nil {
+		tmpl = ctx.Group.GetCharTemplate(ctx.Dice)
+	}
+	ctx.rollStatLabeled = true
+	ctx.rollStatSkill = rollStatSkillName(tmpl, expr)
+}
+
+// rollStatNote 累加一次检定统计，由检定判定的公共路径(ResultCheck 与模板检定规则)调用。
+// 增量只在内存中合并，由 RollStatFlush 随 Save 批量写入，避免每次检定开一个事务。
+// 只有 d100 检定计入出目分布；skill 为空时只计入总计
+func rollStatNote(ctx *MsgContext, outcome int64, rank int, d100 bool) {
+	if ctx == nil || !ctx.rollStatLabeled {
+		return
+	}
+	skill := ctx.rollStatSkill
+	ctx.rollStatLabeled, ctx.rollStatSkill = false, ""
+	if ctx.Dice == nil || ctx.Group == nil || ctx.Player == nil || ctx.IsCompatibilityTest {
+		return
+	}
+
+	type scope struct{ typ, id, name string }
+	scopes := []scope{
+		{model.RollStatScopeUser, ctx.Player.UserID, ctx.Player.Name},
+		{model.RollStatScopeChar, rollStatCurCharID(ctx), ctx.Player.Name},
+	}
+	if !ctx.IsPrivate {
+		scopes = append(scopes, scope{model.RollStatScopeGroup, ctx.Group.GroupID, ctx.Group.GroupName})
+	}
+
+	d := ctx.Dice
+	d.rollStatLock.Lock()
+	defer d.rollStatLock.Unlock()
+	if d.rollStatPending == nil {
+		d.rollStatPending = map[string]*model.RollStat{}
+		d.rollStatOutcomes = map[string]*model.RollStatOutcome{}
+	}
+	add := func(sc scope, skill string) {
+		key := sc.typ + "|" + sc.id + "|" + skill
+		s := d.rollStatPending[key]
+		if s == nil {
+			s = &model.RollStat{ScopeType: sc.typ, ScopeID: sc.id, Skill: skill}
+			d.rollStatPending[key] = s
+		}
+		s.Name = sc.name
+		s.Checks++
+		s.Total += outcome
+		switch {
+		case rank > 0:
+			s.Successes++
+		case rank < 0:
+			s.Failures++
+		}
+		if rank == 4 {
+			s.Criticals++
+		} else if rank == -2 {
+			s.Fumbles++
+		}
+	}
+	for _, sc := range scopes {
+		add(sc, "")
+		if skill != "" {
+			add(sc, skill)
+		}
+		if !d100 || outcome < 1 || outcome > 100 {
+			continue
+		}
+		key := fmt.Sprintf("%s|%s|%d", sc.typ, sc.id, outcome)
+		if o := d.rollStatOutcomes[key]; o != nil {
+			o.Count++
+		} else {
+			d.rollStatOutcomes[key] = &model.RollStatOutcome{ScopeType: sc.typ, ScopeID: sc.id, Outcome: outcome, Count: 1}
+		}
+	}
+}
+
+// RollStatFlush 将暂存的检定统计一次性写入数据库，失败时增量留在暂存区
+func (d *Dice) RollStatFlush() error {
+	d.rollStatLock.Lock()
+	stats, outcomes := d.rollStatPending, d.rollStatOutcomes
+	d.rollStatPending, d.rollStatOutcomes = nil, nil
+	d.rollStatLock.Unlock()
+	if len(stats) == 0 && len(outcomes) == 0 {
+		return nil
+	}
+
+	statList := make([]*model.RollStat, 0, len(stats))
+	for _, s := range stats {
+		statList = append(statList, s)
+	}
+	outcomeList := make([]*model.RollStatOutcome, 0, len(outcomes))
+	for _, o := range outcomes {
+		outcomeList = append(outcomeList, o)
+	}
+	// 固定顺序写入，避免并发事务交错加锁
+	sort.Slice(statList, func(i, j int) bool {
+		a, b := statList[i], statList[j]
+		return a.ScopeType+"|"+a.ScopeID+"|"+a.Skill < b.ScopeType+"|"+b.ScopeID+"|"+b.Skill
+	})
+	sort.Slice(outcomeList, func(i, j int) bool {
+		a, b := outcomeList[i], outcomeList[j]
+		if a.ScopeType+a.ScopeID != b.ScopeType+b.ScopeID {
+			return a.ScopeType+a.ScopeID < b.ScopeType+b.ScopeID
+		}
+		return a.Outcome < b.Outcome
+	})
+	if err := service.RollStatAdd(d.DBOperator, statList, outcomeList); err != nil {
+		// 事务已回滚，放回暂存区等下次保存时重试
+		d.rollStatRestore(statList, outcomeList)
+		d.Logger.Errorf("写入检定统计出错，将在下次保存时重试: %v", err)
+		return err
+	}
+	return nil
+}
+
+// rollStatRestore 将写入失败的增量合并回暂存区，期间新增的增量保留
+func (d *Dice) rollStatRestore(stats []*model.RollStat, outcomes []*model.RollStatOutcome) {
+	d.rollStatLock.Lock()
+	defer d.rollStatLock.Unlock()
+	if d.rollStatPending == nil {
+		d.rollStatPending = map[string]*model.RollStat{}
+		d.rollStatOutcomes = map[string]*model.RollStatOutcome{}
+	}
+	for _, s := range stats {
+		key := s.ScopeType + "|" + s.ScopeID + "|" + s.Skill
+		cur := d.rollStatPending[key]
+		if cur == nil {
+			d.rollStatPending[key] = &model.RollStat{
+				ScopeType: s.ScopeType, ScopeID: s.ScopeID, Skill: s.Skill, Name: s.Name,
+				Checks: s.Checks, Successes: s.Successes, Failures: s.Failures,
+				Criticals: s.Criticals, Fumbles: s.Fumbles, Total: s.Total,
+			}
+			continue
+		}
+		cur.Checks += s.Checks
+		cur.Successes += s.Successes
+		cur.Failures += s.Failures
+		cur.Criticals += s.Criticals
+		cur.Fumbles += s.Fumbles
+		cur.Total += s.Total
+	}
+	for _, o := range outcomes {
+		key := fmt.Sprintf("%s|%s|%d", o.ScopeType, o.ScopeID, o.Outcome)
+		if cur := d.rollStatOutcomes[key]; cur != nil {
+			cur.Count += o.Count
+		} else {
+			d.rollStatOutcomes[key] = &model.RollStatOutcome{ScopeType: o.ScopeType, ScopeID: o.ScopeID, Outcome: o.Outcome, Count: o.Count}
+		}
+	}
+}
+
+// RollStatReport 统计对象的全部数据，供文本汇总与网页端使用
+type RollStatReport struct {
+	ScopeType string                   `json:"scopeType"`
+	ScopeID   string                   `json:"scopeId"`
+	Total     *model.RollStat          `json:"total"`
+	Skills    []*model.RollStat        `json:"skills"`
+	Outcomes  []*model.RollStatOutcome `json:"outcomes"`
+}
+
+// RollStatReportGet 读取统计对象的总计、各技能统计与出目分布
+func RollStatReportGet(d *Dice, scopeType string, scopeID string) (*RollStatReport, error) {
+	_ = d.RollStatFlush()
+	items, err := service.RollStatList(d.DBOperator, scopeType, scopeID)
+	if err != nil {
+		return nil, err
+	}
+	outcomes, err := service.RollStatOutcomeList(d.DBOperator, scopeType, scopeID)
+	if err != nil {
+		return nil, err
+	}
+	report := &RollStatReport{ScopeType: scopeType, ScopeID: scopeID, Skills: []*model.RollStat{}, Outcomes: outcomes}
+	for _, i := range items {
+		if i.Skill == "" {
+			report.Total = i
+		} else {
+			report.Skills = append(report.Skills, i)
+		}
+	}
+	return report, nil
+}
+
+func rollStatLine(s *model.RollStat) string {
+	rate := float64(s.Successes) * 100 / float64(s.Checks)
+	return fmt.Sprintf("检定%d次 成功率%.1f%% 大成功%d 大失败%d 平均出目%.1f",
+		s.Checks, rate, s.Criticals, s.Fumbles, float64(s.Total)/float64(s.Checks))
+}
+
+// Text 生成文字汇总，技能最多列出 skillLimit 个
+func (r *RollStatReport) Text(skillLimit int) string {
+	if r.Total == nil || r.Total.Checks == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString(rollStatLine(r.Total))
+
+	if len(r.Skills) > 0 {
+		var parts []string
+		for i, s := range r.Skills {
+			if i >= skillLimit {
+				parts = append(parts, "...")
+				break
+			}
+			parts = append(parts, fmt.Sprintf("%s%d/%d", s.Skill, s.Successes, s.Checks))
+		}
+		sb.WriteString("\n技能(成功/次数): ")
+		sb.WriteString(strings.Join(parts, " "))
+	}
+
+	if len(r.Outcomes) > 0 {
+		var buckets [10]int64
+		for _, o := range r.Outcomes {
+			buckets[(o.Outcome-1)/10] += o.Count
+		}
+		var parts []string
+		for i, n := range buckets {
+			parts = append(parts, fmt.Sprintf("%d-%d:%d", i*10+1, i*10+10, n))
+		}
+		sb.WriteString("\n出目分布: ")
+		sb.WriteString(strings.Join(parts, " "))
+	}
+	return sb.String()
+}
+
+const helpStatRolls = `.stat me // 查看自己的检定统计(全部角色及当前角色)
+.stat group // 查看本群的检定统计
+.stat group clear // 清空本群的检定统计
+统计 .ra/.rc 技能检定、.sc 理智检定以及模板提供的检定指令，对抗、成长检定与普通掷骰不计入`
+
+func cmdStatRolls(ctx *MsgContext, msg *Message, cmdArgs *CmdArgs) CmdExecuteResult {
+	d := ctx.Dice
+	switch strings.ToLower(cmdArgs.GetArgN(1)) {
+	case "me":
+		var texts []string
+		user, err := RollStatReportGet(d, model.RollStatScopeUser, ctx.Player.UserID)
+		if err != nil {
+			ReplyToSender(ctx, msg, "获取统计出错: "+err.Error())
+			return CmdExecuteResult{Matched: true, Solved: true}
+		}
+		if t := user.Text(10); t != "" {
+			texts = append(texts, fmt.Sprintf("<%s>全部角色:\n%s", ctx.Player.Name, t))
+		}
+		if ctx.Group != nil {
+			char, err := RollStatReportGet(d, model.RollStatScopeChar, rollStatCurCharID(ctx))
+			if err == nil {
+				if t := char.Text(10); t != "" {
+					name := ctx.Player.Name
+					if char.Total.Name != "" {
+						name = char.Total.Name
+					}
+					texts = append(texts, fmt.Sprintf("当前角色<%s>:\n%s", name, t))
+				}
+			}
+		}
+		if len(texts) == 0 {
+			ReplyToSender(ctx, msg, fmt.Sprintf("<%s>还没有检定记录", ctx.Player.Name))
+		} else {
+			ReplyToSender(ctx, msg, strings.Join(texts, "\n\n"))
+		}
+	case "group":
+		if ctx.IsPrivate {
+			ReplyToSender(ctx, msg, DiceFormatTmpl(ctx, "核心:提示_私聊不可用"))
+			return CmdExecuteResult{Matched: true, Solved: true}
+		}
+		if cmdArgs.IsArgEqual(2, "clear") {
+			if ctx.PrivilegeLevel < 40 {
+				ReplyToSender(ctx, msg, DiceFormatTmpl(ctx, "核心:提示_无权限_非master/管理"))
+				return CmdExecuteResult{Matched: true, Solved: true}
+			}
+			_ = d.RollStatFlush()
+			if err := service.RollStatClear(d.DBOperator, model.RollStatScopeGroup, ctx.Group.GroupID); err != nil {
+				ReplyToSender(ctx, msg, "清空统计出错: "+err.Error())
+			} else {
+				ReplyToSender(ctx, msg, "已清空本群的检定统计")
+			}
+			return CmdExecuteResult{Matched: true, Solved: true}
+		}
+		report, err := RollStatReportGet(d, model.RollStatScopeGroup, ctx.Group.GroupID)
+		if err != nil {
+			ReplyToSender(ctx, msg, "获取统计出错: "+err.Error())
+			return CmdExecuteResult{Matched: true, Solved: true}
+		}
+		if t := report.Text(15); t != "" {
+			ReplyToSender(ctx, msg, "本群检定统计:\n"+t)
+		} else {
+			ReplyToSender(ctx, msg, "本群还没有检定记录")
+		}
+	default:
+		return CmdExecuteResult{Matched: true, Solved: true, ShowHelp: true}
+	}
+	return CmdExecuteResult{Matched: true, Solved: true}
+}
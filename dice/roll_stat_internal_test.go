@@ -0,0 +1,143 @@
+//nolint:testpackage
+package dice
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"sealdice-core/model"
+	"sealdice-core/utils/constant"
+)
+
+func TestRollStatRecordFromChecks(t *testing.T) {
+	d, ep, adapter, cleanup := newExecuteNewTestDice(t)
+	defer cleanup()
+	err := d.DBOperator.GetDataDB(constant.WRITE).AutoMigrate(
+		&model.AttributesItemModel{}, &model.RollStat{}, &model.RollStatOutcome{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	groupID := "QQ-Group:115"
+
+	for _, text := range []string{".ra 侦查50", ".ra 侦查50", ".ra 聆听70"} {
+		d.ImSession.ExecuteNew(ep, newGroupMsg(groupID, "QQ:999", text))
+		if _, ok := adapter.waitForMsg(2 * time.Second); !ok {
+			t.Fatalf("no reply for %s", text)
+		}
+	}
+
+	// 检定时只暂存增量，读取统计前才写入数据库
+	var rows int64
+	d.DBOperator.GetDataDB(constant.READ).Model(&model.RollStat{}).Count(&rows)
+	if rows != 0 {
+		t.Fatalf("stats should be buffered until flush, got %d rows", rows)
+	}
+
+	// 统计在回复发出后记录，稍等片刻
+	var user *RollStatReport
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(20 * time.Millisecond) {
+		user, err = RollStatReportGet(d, model.RollStatScopeUser, "QQ:999")
+		if err != nil || (user.Total != nil && user.Total.Checks >= 3) {
+			break
+		}
+	}
+	if err != nil || user.Total == nil || user.Total.Checks != 3 || len(user.Skills) != 2 {
+		t.Fatalf("unexpected user stats: %+v %v", user, err)
+	}
+	if user.Skills[0].Skill != "侦查" || user.Skills[0].Checks != 2 {
+		t.Fatalf("unexpected skill order: %+v", user.Skills[0])
+	}
+	if user.Total.Successes+user.Total.Failures != 3 {
+		t.Fatalf("every check should be success or failure: %+v", user.Total)
+	}
+	var n int64
+	for _, o := range user.Outcomes {
+		n += o.Count
+	}
+	if n != 3 {
+		t.Fatalf("unexpected outcome count: %d", n)
+	}
+	group, _ := RollStatReportGet(d, model.RollStatScopeGroup, groupID)
+	if group.Total == nil || group.Total.Checks != 3 {
+		t.Fatalf("unexpected group stats: %+v", group)
+	}
+
+	d.ImSession.ExecuteNew(ep, newGroupMsg(groupID, "QQ:999", ".stat me"))
+	text, ok := adapter.waitForMsg(2 * time.Second)
+	if !ok || !strings.Contains(text, "检定3次") || !strings.Contains(text, "当前角色") || !strings.Contains(text, "侦查") {
+		t.Fatalf("unexpected .stat me reply: %q", text)
+	}
+}
+
+// 模板检定规则给出的结果同样计入统计，d20 检定不计入出目分布
+func TestRollStatRecordsTemplateChecks(t *testing.T) {
+	d, ep, adapter, cleanup := newExecuteNewTestDice(t)
+	defer cleanup()
+	err := d.DBOperator.GetDataDB(constant.WRITE).AutoMigrate(
+		&model.AttributesItemModel{}, &model.RollStat{}, &model.RollStatOutcome{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	groupID := "QQ-Group:116"
+
+	for _, text := range []string{".set dnd", ".rc 力量 DC10", ".rc 5"} {
+		d.ImSession.ExecuteNew(ep, newGroupMsg(groupID, "QQ:999", text))
+		if _, ok := adapter.waitForMsg(2 * time.Second); !ok {
+			t.Fatalf("no reply for %s", text)
+		}
+	}
+
+	var user *RollStatReport
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(20 * time.Millisecond) {
+		user, err = RollStatReportGet(d, model.RollStatScopeUser, "QQ:999")
+		if err != nil || (user.Total != nil && user.Total.Checks >= 2) {
+			break
+		}
+	}
+	if err != nil || user.Total == nil || user.Total.Checks != 2 {
+		t.Fatalf("unexpected user stats: %+v %v", user, err)
+	}
+	// 只有数值的检定只计入总计
+	if len(user.Skills) != 1 || user.Skills[0].Skill != "力量" || len(user.Outcomes) != 0 {
+		t.Fatalf("unexpected skills or outcomes: %+v", user)
+	}
+}
+
+// 写入失败时增量留在暂存区，下次写入时一并补上
+func TestRollStatFlushKeepsPendingOnError(t *testing.T) {
+	d, ep, _, cleanup := newExecuteNewTestDice(t)
+	defer cleanup()
+	db := d.DBOperator.GetDataDB(constant.WRITE)
+	if err := db.AutoMigrate(&model.AttributesItemModel{}); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := CreateTempCtx(ep, newGroupMsg("QQ-Group:117", "QQ:999", ".ra 侦查"))
+	ctx.Dice = d
+	note := func() {
+		rollStatLabel(ctx, "侦查50")
+		rollStatNote(ctx, 30, 1, true)
+	}
+	note()
+
+	// 统计表尚未建立，写入必然失败
+	if err := d.RollStatFlush(); err == nil {
+		t.Fatal("flush should fail without the stat tables")
+	}
+	note()
+	if s := d.rollStatPending["user|QQ:999|侦查"]; s == nil || s.Checks != 2 || s.Successes != 2 {
+		t.Fatalf("failed batch should be merged back: %+v", s)
+	}
+	if o := d.rollStatOutcomes["user|QQ:999|30"]; o == nil || o.Count != 2 {
+		t.Fatalf("failed outcomes should be merged back: %+v", o)
+	}
+
+	if err := db.AutoMigrate(&model.RollStat{}, &model.RollStatOutcome{}); err != nil {
+		t.Fatal(err)
+	}
+	user, err := RollStatReportGet(d, model.RollStatScopeUser, "QQ:999")
+	if err != nil || user.Total == nil || user.Total.Checks != 2 || len(d.rollStatPending) != 0 {
+		t.Fatalf("pending stats should be written after recovery: %+v %v", user, err)
+	}
+}
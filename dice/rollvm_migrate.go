@@ -769,6 +769,9 @@ func (ctx *MsgContext) CreateVmIfNotExists() {
 	ctx.vm = ds.NewVM()
 
 	ctx.vm.Config = *ctx.GenDefaultRollVmConfig()
+	if !ctx.IsCompatibilityTest {
+		ctx.setupRandSource()
+	}
 
 	am := ctx.Dice.AttrsManager
 	ctx.vm.GlobalValueLoadOverwriteFunc = func(name string, curVal *ds.VMValue) *ds.VMValue {
@@ -826,6 +829,7 @@ func (ctx *MsgContext) CreateVmIfNotExists() {
 
 	actorObj := newActorNativeObject(ctx, "actor")
 	ctx.vm.Attrs.Store("actor", actorObj)
+	ctx.vm.Attrs.Store("pool", newDicePoolObject(ctx))
 }
 
 func DiceFormatV2(ctx *MsgContext, s string) (string, error) { //nolint:revive
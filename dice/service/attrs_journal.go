@@ -0,0 +1,113 @@
+package service
+
+import (
+	"errors"
+
+	"sealdice-core/model"
+	"sealdice-core/utils/constant"
+	engine2 "sealdice-core/utils/dboperator/engine"
+)
+
+var ErrAttrsJournalNothingToUndo = errors.New("没有可撤销的属性变更")
+
+// AttrsJournalAppend 追加属性变更记录
+func AttrsJournalAppend(operator engine2.DatabaseOperator, entries []*model.AttrsJournal) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	return operator.GetDataDB(constant.WRITE).Create(entries).Error
+}
+
+// AttrsJournalList 分页列出角色的属性变更记录，新的在前
+func AttrsJournalList(operator engine2.DatabaseOperator, attrsID string, limit int, offset int) ([]*model.AttrsJournal, int64, error) {
+	db := operator.GetDataDB(constant.READ)
+	var total int64
+	if err := db.Model(&model.AttrsJournal{}).Where("attrs_id = ?", attrsID).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var entries []*model.AttrsJournal
+	err := db.Where("attrs_id = ?", attrsID).Order("id DESC").Limit(limit).Offset(offset).Find(&entries).Error
+	return entries, total, err
+}
+
+// AttrsJournalLastChanges 取最近 n 次尚未撤销的变更，新的在前。
+// 同一条指令产生的连续记录视为一次变更；撤销产生的反向记录不会再被撤销
+func AttrsJournalLastChanges(operator engine2.DatabaseOperator, attrsID string, n int) ([][]*model.AttrsJournal, error) {
+	db := operator.GetDataDB(constant.READ)
+
+	var reverted []uint64
+	if err := db.Model(&model.AttrsJournal{}).Where("attrs_id = ? AND revert_of > 0", attrsID).
+		Pluck("revert_of", &reverted).Error; err != nil {
+		return nil, err
+	}
+	revertedSet := make(map[uint64]struct{}, len(reverted))
+	for _, id := range reverted {
+		revertedSet[id] = struct{}{}
+	}
+
+	const pageSize = 100
+	var changes [][]*model.AttrsJournal
+	var lastCommandID int64
+	for offset := 0; ; offset += pageSize {
+		var entries []*model.AttrsJournal
+		if err := db.Where("attrs_id = ? AND revert_of = 0", attrsID).Order("id DESC").
+			Limit(pageSize).Offset(offset).Find(&entries).Error; err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			if _, ok := revertedSet[e.ID]; ok {
+				continue
+			}
+			if len(changes) > 0 && e.CommandID != 0 && e.CommandID == lastCommandID {
+				changes[len(changes)-1] = append(changes[len(changes)-1], e)
+				continue
+			}
+			if len(changes) == n {
+				return changes, nil
+			}
+			changes = append(changes, []*model.AttrsJournal{e})
+			lastCommandID = e.CommandID
+		}
+		if len(entries) < pageSize {
+			break
+		}
+	}
+	if len(changes) == 0 {
+		return nil, ErrAttrsJournalNothingToUndo
+	}
+	return changes, nil
+}
+
+// AttrsJournalPruneBefore 删除早于 before(unix 时间戳) 的记录
+func AttrsJournalPruneBefore(operator engine2.DatabaseOperator, before int64) (int64, error) {
+	ret := operator.GetDataDB(constant.WRITE).Where("created_at < ?", before).Delete(&model.AttrsJournal{})
+	return ret.RowsAffected, ret.Error
+}
+
+// AttrsJournalPruneKeep 每个角色只保留最新的 keep 条记录
+func AttrsJournalPruneKeep(operator engine2.DatabaseOperator, keep int) (int64, error) {
+	db := operator.GetDataDB(constant.WRITE)
+	var attrsIDs []string
+	if err := db.Model(&model.AttrsJournal{}).Group("attrs_id").Having("COUNT(1) > ?", keep).
+		Pluck("attrs_id", &attrsIDs).Error; err != nil {
+		return 0, err
+	}
+
+	var deleted int64
+	for _, attrsID := range attrsIDs {
+		var ids []uint64
+		if err := db.Model(&model.AttrsJournal{}).Where("attrs_id = ?", attrsID).Order("id DESC").
+			Offset(keep).Limit(1).Pluck("id", &ids).Error; err != nil {
+			return deleted, err
+		}
+		if len(ids) == 0 {
+			continue
+		}
+		ret := db.Where("attrs_id = ? AND id <= ?", attrsID, ids[0]).Delete(&model.AttrsJournal{})
+		if ret.Error != nil {
+			return deleted, ret.Error
+		}
+		deleted += ret.RowsAffected
+	}
+	return deleted, nil
+}
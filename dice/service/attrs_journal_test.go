@@ -0,0 +1,86 @@
+package service_test
+
+import (
+	"errors"
+	"testing"
+
+	"sealdice-core/dice/service"
+	"sealdice-core/model"
+	"sealdice-core/utils/constant"
+)
+
+func newAttrsJournalTestOperator(t *testing.T) *logInfoTestOperator {
+	t.Helper()
+
+	db := newLogInfoTestDB(t)
+	if err := db.AutoMigrate(&model.AttrsJournal{}); err != nil {
+		t.Fatalf("migrate journal table: %v", err)
+	}
+	return &logInfoTestOperator{db: db, dbType: constant.SQLITE}
+}
+
+func TestAttrsJournalLastChangesGroupsByCommand(t *testing.T) {
+	op := newAttrsJournalTestOperator(t)
+	entries := []*model.AttrsJournal{
+		{AttrsID: "c1", Attr: "力量", NewValue: "1", CommandID: 1, CreatedAt: 100},
+		{AttrsID: "c1", Attr: "hp", NewValue: "2", CommandID: 2, CreatedAt: 110},
+		{AttrsID: "c1", Attr: "san", NewValue: "3", CommandID: 2, CreatedAt: 110},
+		{AttrsID: "c2", Attr: "hp", NewValue: "4", CommandID: 3, CreatedAt: 120},
+		{AttrsID: "c1", Attr: "mp", NewValue: "5", CreatedAt: 130},
+	}
+	if err := service.AttrsJournalAppend(op, entries); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+
+	changes, err := service.AttrsJournalLastChanges(op, "c1", 2)
+	if err != nil {
+		t.Fatalf("LastChanges: %v", err)
+	}
+	if len(changes) != 2 || len(changes[0]) != 1 || changes[0][0].Attr != "mp" || len(changes[1]) != 2 {
+		t.Fatalf("unexpected changes: %+v", changes)
+	}
+
+	// 撤销 mp 之后，最近一次变更应为指令2
+	if err = service.AttrsJournalAppend(op, []*model.AttrsJournal{{AttrsID: "c1", Attr: "mp", RevertOf: changes[0][0].ID}}); err != nil {
+		t.Fatalf("append revert: %v", err)
+	}
+	changes, err = service.AttrsJournalLastChanges(op, "c1", 1)
+	if err != nil || len(changes) != 1 || changes[0][0].CommandID != 2 {
+		t.Fatalf("unexpected changes after revert: %+v, %v", changes, err)
+	}
+
+	if _, err = service.AttrsJournalLastChanges(op, "none", 1); !errors.Is(err, service.ErrAttrsJournalNothingToUndo) {
+		t.Fatalf("expected ErrAttrsJournalNothingToUndo, got %v", err)
+	}
+}
+
+func TestAttrsJournalPrune(t *testing.T) {
+	op := newAttrsJournalTestOperator(t)
+	var entries []*model.AttrsJournal
+	for i := 0; i < 5; i++ {
+		entries = append(entries,
+			&model.AttrsJournal{AttrsID: "c1", Attr: "hp", CreatedAt: int64(100 + i)},
+			&model.AttrsJournal{AttrsID: "c2", Attr: "hp", CreatedAt: int64(200 + i)},
+		)
+	}
+	if err := service.AttrsJournalAppend(op, entries); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+
+	deleted, err := service.AttrsJournalPruneKeep(op, 3)
+	if err != nil || deleted != 4 {
+		t.Fatalf("PruneKeep deleted %d, %v", deleted, err)
+	}
+	list, total, err := service.AttrsJournalList(op, "c1", 10, 0)
+	if err != nil || total != 3 || list[0].CreatedAt != 104 || list[2].CreatedAt != 102 {
+		t.Fatalf("unexpected c1 journal: %+v (%d), %v", list, total, err)
+	}
+
+	deleted, err = service.AttrsJournalPruneBefore(op, 200)
+	if err != nil || deleted != 3 {
+		t.Fatalf("PruneBefore deleted %d, %v", deleted, err)
+	}
+	if _, total, _ = service.AttrsJournalList(op, "c2", 10, 0); total != 3 {
+		t.Fatalf("c2 should keep 3 entries, got %d", total)
+	}
+}
@@ -37,7 +37,7 @@ func AttrsGetById(operator engine2.DatabaseOperator, id string) (*model.Attribut
 	db := operator.GetDataDB(constant.READ)
 	var item model.AttributesItemModel
 	err := db.Model(&model.AttributesItemModel{}).
-		Select("id, data, COALESCE(attrs_type, '') as attrs_type, binding_sheet_id, name, owner_id, sheet_type, is_hidden, created_at, updated_at").
+		Select("id, data, COALESCE(attrs_type, '') as attrs_type, binding_sheet_id, name, owner_id, sheet_type, is_hidden, created_at, updated_at, version").
 		Where("id = ?", id).
 		Limit(1).
 		// 使用Find，如果找不到不会豹错，而是提示RowsAffected = 0，此处返回空对象本身就是预期正常的行为
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"sealdice-core/model"
+	"sealdice-core/utils/constant"
+	engine2 "sealdice-core/utils/dboperator/engine"
+)
+
+// ErrBanAppealHandled 申诉已被处理，不能重复处理
+var ErrBanAppealHandled = errors.New("申诉不存在或已被处理")
+
+// BanAppealCreate 新建申诉并写入第一条记录，ID 会回填到 appeal 中
+func BanAppealCreate(operator engine2.DatabaseOperator, appeal *model.BanAppeal) error {
+	db := operator.GetDataDB(constant.WRITE)
+	now := time.Now().Unix()
+	appeal.State = model.BanAppealPending
+	appeal.CreatedAt, appeal.UpdatedAt = now, now
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(appeal).Error; err != nil {
+			return err
+		}
+		return tx.Create(&model.BanAppealHistory{
+			AppealID:  appeal.ID,
+			State:     model.BanAppealPending,
+			Operator:  appeal.UserID,
+			Note:      appeal.Reason,
+			CreatedAt: now,
+		}).Error
+	})
+}
+
+// BanAppealGet 读取单个申诉，不存在时返回 nil
+func BanAppealGet(operator engine2.DatabaseOperator, id uint64) (*model.BanAppeal, error) {
+	db := operator.GetDataDB(constant.READ)
+	var items []*model.BanAppeal
+	if err := db.Where("id = ?", id).Limit(1).Find(&items).Error; err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, nil
+	}
+	return items[0], nil
+}
+
+// BanAppealList 列出申诉，按提交时间倒序。state 与 userID 为空时不作筛选
+func BanAppealList(operator engine2.DatabaseOperator, state string, userID string) ([]*model.BanAppeal, error) {
+	db := operator.GetDataDB(constant.READ)
+	query := db.Order("id DESC")
+	if state != "" {
+		query = query.Where("state = ?", state)
+	}
+	if userID != "" {
+		query = query.Where("user_id = ?", userID)
+	}
+	var items []*model.BanAppeal
+	err := query.Find(&items).Error
+	return items, err
+}
+
+// BanAppealResolve 将待处理的申诉改为 state 并记录。申诉已被处理时返回 ErrBanAppealHandled
+func BanAppealResolve(operator engine2.DatabaseOperator, id uint64, state string, handler string, note string) error {
+	db := operator.GetDataDB(constant.WRITE)
+	now := time.Now().Unix()
+	return db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.BanAppeal{}).
+			Where("id = ? AND state = ?", id, model.BanAppealPending).
+			Updates(map[string]any{"state": state, "handler": handler, "note": note, "updated_at": now})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrBanAppealHandled
+		}
+		return tx.Create(&model.BanAppealHistory{
+			AppealID:  id,
+			State:     state,
+			Operator:  handler,
+			Note:      note,
+			CreatedAt: now,
+		}).Error
+	})
+}
+
+// BanAppealHistoryList 申诉的全部状态变更记录，按时间顺序
+func BanAppealHistoryList(operator engine2.DatabaseOperator, id uint64) ([]*model.BanAppealHistory, error) {
+	db := operator.GetDataDB(constant.READ)
+	var items []*model.BanAppealHistory
+	err := db.Where("appeal_id = ?", id).Order("id ASC").Find(&items).Error
+	return items, err
+}
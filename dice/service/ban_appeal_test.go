@@ -0,0 +1,53 @@
+package service_test
+
+import (
+	"errors"
+	"testing"
+
+	"sealdice-core/dice/service"
+	"sealdice-core/model"
+	"sealdice-core/utils/constant"
+)
+
+func TestBanAppealResolveOnce(t *testing.T) {
+	db := newLogInfoTestDB(t)
+	if err := db.AutoMigrate(&model.BanAppeal{}, &model.BanAppealHistory{}); err != nil {
+		t.Fatalf("migrate ban appeal tables: %v", err)
+	}
+	op := &logInfoTestOperator{db: db, dbType: constant.SQLITE}
+
+	appeal := &model.BanAppeal{UserID: "QQ:1", Reason: "误触", Rank: 2, Score: 200}
+	if err := service.BanAppealCreate(op, appeal); err != nil {
+		t.Fatal(err)
+	}
+	if appeal.ID == 0 || appeal.State != model.BanAppealPending {
+		t.Fatalf("unexpected appeal: %+v", appeal)
+	}
+	items, err := service.BanAppealList(op, model.BanAppealPending, "")
+	if err != nil || len(items) != 1 {
+		t.Fatalf("unexpected pending list: %+v %v", items, err)
+	}
+
+	if err := service.BanAppealResolve(op, appeal.ID, model.BanAppealAccepted, "QQ:99", "下不为例"); err != nil {
+		t.Fatal(err)
+	}
+	err = service.BanAppealResolve(op, appeal.ID, model.BanAppealRejected, "QQ:98", "")
+	if !errors.Is(err, service.ErrBanAppealHandled) {
+		t.Fatalf("second resolve should fail, got %v", err)
+	}
+
+	got, err := service.BanAppealGet(op, appeal.ID)
+	if err != nil || got.State != model.BanAppealAccepted || got.Handler != "QQ:99" || got.Note != "下不为例" {
+		t.Fatalf("unexpected appeal: %+v %v", got, err)
+	}
+	if items, _ = service.BanAppealList(op, model.BanAppealPending, ""); len(items) != 0 {
+		t.Fatalf("resolved appeal should leave pending list: %+v", items)
+	}
+	history, err := service.BanAppealHistoryList(op, appeal.ID)
+	if err != nil || len(history) != 2 {
+		t.Fatalf("unexpected history: %+v %v", history, err)
+	}
+	if history[0].State != model.BanAppealPending || history[1].State != model.BanAppealAccepted || history[1].Operator != "QQ:99" {
+		t.Fatalf("unexpected history: %+v %+v", history[0], history[1])
+	}
+}
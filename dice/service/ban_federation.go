@@ -0,0 +1,40 @@
+package service
+
+import (
+	"time"
+
+	"gorm.io/gorm/clause"
+
+	"sealdice-core/model"
+	"sealdice-core/utils/constant"
+	engine2 "sealdice-core/utils/dboperator/engine"
+)
+
+// BanFederationEntrySave 保存联合黑名单来源记录，首次保存时写入创建时间
+func BanFederationEntrySave(operator engine2.DatabaseOperator, entry *model.BanFederationEntry) error {
+	db := operator.GetDataDB(constant.WRITE)
+	now := time.Now().Unix()
+	if entry.CreatedAt == 0 {
+		entry.CreatedAt = now
+	}
+	entry.UpdatedAt = now
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "source"}, {Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"name", "remote_rank", "score", "status", "reasons", "updated_at"}),
+	}).Create(entry).Error
+}
+
+// BanFederationEntryList 列出来源记录，source 与 userID 为空时不作筛选
+func BanFederationEntryList(operator engine2.DatabaseOperator, source string, userID string) ([]*model.BanFederationEntry, error) {
+	db := operator.GetDataDB(constant.READ)
+	query := db.Order("updated_at DESC")
+	if source != "" {
+		query = query.Where("source = ?", source)
+	}
+	if userID != "" {
+		query = query.Where("user_id = ?", userID)
+	}
+	var items []*model.BanFederationEntry
+	err := query.Find(&items).Error
+	return items, err
+}
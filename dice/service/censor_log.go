@@ -11,8 +11,8 @@ import (
 	engine2 "sealdice-core/utils/dboperator/engine"
 )
 
-// 添加一个敏感词记录
-func CensorAppend(operator engine2.DatabaseOperator, msgType string, userID string, groupID string, content string, sensitiveWords interface{}, highestLevel int) bool {
+// 添加一个敏感词记录，spans 为命中片段
+func CensorAppend(operator engine2.DatabaseOperator, msgType string, userID string, groupID string, content string, sensitiveWords interface{}, spans interface{}, highestLevel int) bool {
 	db := operator.GetCensorDB(constant.WRITE)
 	// 获取当前时间的 Unix 时间戳
 	nowTimestamp := time.Now().Unix()
@@ -22,6 +22,10 @@ func CensorAppend(operator engine2.DatabaseOperator, msgType string, userID stri
 	if err != nil {
 		return false
 	}
+	spansJSON, err := json.Marshal(spans)
+	if err != nil {
+		return false
+	}
 
 	// 创建 CensorLog 实例，手动设置 CreatedAt
 	censorLog := model.CensorLog{
@@ -30,6 +34,7 @@ func CensorAppend(operator engine2.DatabaseOperator, msgType string, userID stri
 		GroupID:        groupID,
 		Content:        content,
 		SensitiveWords: string(words),
+		Spans:          spansJSON,
 		HighestLevel:   highestLevel,
 		CreatedAt:      int(nowTimestamp), // Unix 时间戳
 		ClearMark:      false,
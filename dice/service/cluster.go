@@ -0,0 +1,230 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"sealdice-core/model"
+	"sealdice-core/utils/constant"
+	"sealdice-core/utils/dboperator/dbutil"
+	engine2 "sealdice-core/utils/dboperator/engine"
+)
+
+// ErrVersionConflict 行版本与预期不符，说明数据已被其他节点修改
+var ErrVersionConflict = errors.New("数据已被其他节点修改")
+
+// ClusterNodeHeartbeat 登记节点并刷新心跳时间
+func ClusterNodeHeartbeat(operator engine2.DatabaseOperator, node *model.ClusterNode) error {
+	db := operator.GetDataDB(constant.WRITE)
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"name", "heartbeat_at"}),
+	}).Create(node).Error
+}
+
+// ClusterNodeList 列出所有登记过的节点，按启动时间排序
+func ClusterNodeList(operator engine2.DatabaseOperator) ([]*model.ClusterNode, error) {
+	db := operator.GetDataDB(constant.READ)
+	var nodes []*model.ClusterNode
+	err := db.Order("started_at ASC").Find(&nodes).Error
+	return nodes, err
+}
+
+// ClusterNodeRemove 节点退出时注销
+func ClusterNodeRemove(operator engine2.DatabaseOperator, id string) error {
+	db := operator.GetDataDB(constant.WRITE)
+	return db.Where("id = ?", id).Delete(&model.ClusterNode{}).Error
+}
+
+// ClusterNodePruneBefore 删除心跳早于 before 的节点
+func ClusterNodePruneBefore(operator engine2.DatabaseOperator, before int64) (int64, error) {
+	db := operator.GetDataDB(constant.WRITE)
+	result := db.Where("heartbeat_at < ?", before).Delete(&model.ClusterNode{})
+	return result.RowsAffected, result.Error
+}
+
+// ClusterLeaseAcquire 获取或续期租约。租约无人持有、已过期或本就由 holder 持有时成功
+func ClusterLeaseAcquire(operator engine2.DatabaseOperator, name string, holder string, ttl time.Duration) (bool, error) {
+	db := operator.GetDataDB(constant.WRITE)
+	now := time.Now()
+	expiresAt := now.Add(ttl).UnixMilli()
+
+	result := db.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&model.ClusterLease{Name: name, Holder: holder, ExpiresAt: expiresAt})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		err := db.Model(&model.ClusterLease{}).
+			Where("name = ? AND (holder = ? OR expires_at < ?)", name, holder, now.UnixMilli()).
+			Updates(map[string]any{"holder": holder, "expires_at": expiresAt}).Error
+		if err != nil {
+			return false, err
+		}
+	}
+
+	// 值未变化时 MySQL 的影响行数为 0，因此以读回的持有者为准
+	lease, err := ClusterLeaseGet(operator, name)
+	if err != nil || lease == nil {
+		return false, err
+	}
+	return lease.Holder == holder, nil
+}
+
+// ClusterLeaseGet 读取租约，不存在时返回 nil
+func ClusterLeaseGet(operator engine2.DatabaseOperator, name string) (*model.ClusterLease, error) {
+	db := operator.GetDataDB(constant.READ)
+	var leases []*model.ClusterLease
+	if err := db.Where("name = ?", name).Limit(1).Find(&leases).Error; err != nil {
+		return nil, err
+	}
+	if len(leases) == 0 {
+		return nil, nil
+	}
+	return leases[0], nil
+}
+
+// ClusterLeaseRelease 主动放弃租约，便于其他节点立即接手
+func ClusterLeaseRelease(operator engine2.DatabaseOperator, name string, holder string) error {
+	db := operator.GetDataDB(constant.WRITE)
+	return db.Model(&model.ClusterLease{}).
+		Where("name = ? AND holder = ?", name, holder).
+		Update("expires_at", 0).Error
+}
+
+// ClusterEventPublish 写入一条变更通知
+func ClusterEventPublish(operator engine2.DatabaseOperator, event *model.ClusterEvent) error {
+	db := operator.GetDataDB(constant.WRITE)
+	if event.CreatedAt == 0 {
+		event.CreatedAt = time.Now().Unix()
+	}
+	return db.Create(event).Error
+}
+
+// ClusterEventsAfter 按顺序读取 ID 大于 afterID 的通知
+func ClusterEventsAfter(operator engine2.DatabaseOperator, afterID uint64, limit int) ([]*model.ClusterEvent, error) {
+	db := operator.GetDataDB(constant.READ)
+	var events []*model.ClusterEvent
+	err := db.Where("id > ?", afterID).Order("id ASC").Limit(limit).Find(&events).Error
+	return events, err
+}
+
+// ClusterEventLatestID 当前最新通知的 ID，节点启动时从这里开始读取
+func ClusterEventLatestID(operator engine2.DatabaseOperator) (uint64, error) {
+	db := operator.GetDataDB(constant.READ)
+	var id uint64
+	err := db.Model(&model.ClusterEvent{}).Select("COALESCE(MAX(id), 0)").Scan(&id).Error
+	return id, err
+}
+
+// ClusterEventPruneBefore 删除早于 before 的通知
+func ClusterEventPruneBefore(operator engine2.DatabaseOperator, before int64) (int64, error) {
+	db := operator.GetDataDB(constant.WRITE)
+	result := db.Where("created_at < ?", before).Delete(&model.ClusterEvent{})
+	return result.RowsAffected, result.Error
+}
+
+// GroupInfoGet 读取单个群组信息，不存在时返回 nil
+func GroupInfoGet(operator engine2.DatabaseOperator, groupID string) (*model.GroupInfo, error) {
+	db := operator.GetDataDB(constant.READ)
+	var items []*model.GroupInfo
+	if err := db.Where("id = ?", groupID).Limit(1).Find(&items).Error; err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, nil
+	}
+	return items[0], nil
+}
+
+// GroupInfoSaveVersioned 以行版本为条件保存群组信息，成功时返回新版本号。
+// 行已被其他节点改动时返回 ErrVersionConflict
+func GroupInfoSaveVersioned(operator engine2.DatabaseOperator, groupID string, updatedAt int64, version int64, data []byte) (int64, error) {
+	db := operator.GetDataDB(constant.WRITE)
+	bytePoint := dbutil.BYTE(data)
+	result := db.Model(&model.GroupInfo{}).
+		Where("id = ? AND version = ?", groupID, version).
+		Updates(map[string]any{
+			"updated_at": updatedAt,
+			"data":       &bytePoint,
+			"version":    gorm.Expr("version + 1"),
+		})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	if result.RowsAffected == 1 {
+		return version + 1, nil
+	}
+
+	// 行不存在时新建，若恰好被其他节点抢先建立则视为冲突
+	result = db.Clauses(clause.OnConflict{DoNothing: true}).Create(&model.GroupInfo{
+		ID:        groupID,
+		UpdatedAt: &updatedAt,
+		Data:      data,
+		Version:   1,
+	})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	if result.RowsAffected == 1 {
+		return 1, nil
+	}
+	return 0, ErrVersionConflict
+}
+
+// AttrsPutByIdVersioned 以行版本为条件保存属性数据，成功时返回新版本号。
+// 行已被其他节点改动时返回 ErrVersionConflict
+func AttrsPutByIdVersioned(operator engine2.DatabaseOperator, id string, data []byte, name, sheetType string, version int64) (int64, error) {
+	db := operator.GetDataDB(constant.WRITE)
+	now := time.Now().Unix()
+	bytePoint := dbutil.BYTE(data)
+	result := db.Model(&model.AttributesItemModel{}).
+		Where("id = ? AND version = ?", id, version).
+		Updates(map[string]any{
+			"data":       &bytePoint,
+			"updated_at": now,
+			"name":       name,
+			"sheet_type": sheetType,
+			"version":    gorm.Expr("version + 1"),
+		})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	if result.RowsAffected == 1 {
+		return version + 1, nil
+	}
+
+	result = db.Clauses(clause.OnConflict{DoNothing: true}).Create(&model.AttributesItemModel{
+		Id:        id,
+		Data:      data,
+		IsHidden:  true,
+		Name:      name,
+		SheetType: sheetType,
+		CreatedAt: now,
+		UpdatedAt: now,
+		Version:   1,
+	})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	if result.RowsAffected == 1 {
+		return 1, nil
+	}
+	return 0, ErrVersionConflict
+}
+
+// BanItemGet 读取单个黑名单项，不存在时返回 nil
+func BanItemGet(operator engine2.DatabaseOperator, id string) (*model.BanInfo, error) {
+	db := operator.GetDataDB(constant.READ)
+	var items []*model.BanInfo
+	if err := db.Where("id = ?", id).Limit(1).Find(&items).Error; err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, nil
+	}
+	return items[0], nil
+}
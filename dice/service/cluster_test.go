@@ -0,0 +1,105 @@
+package service_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"sealdice-core/dice/service"
+	"sealdice-core/model"
+	"sealdice-core/utils/constant"
+)
+
+func newClusterTestOperator(t *testing.T) *logInfoTestOperator {
+	t.Helper()
+
+	db := newLogInfoTestDB(t)
+	if err := db.AutoMigrate(
+		&model.ClusterNode{}, &model.ClusterLease{}, &model.ClusterEvent{},
+		&model.GroupInfo{}, &model.AttributesItemModel{},
+	); err != nil {
+		t.Fatalf("migrate cluster tables: %v", err)
+	}
+	return &logInfoTestOperator{db: db, dbType: constant.SQLITE}
+}
+
+func TestClusterLeaseAcquire(t *testing.T) {
+	op := newClusterTestOperator(t)
+
+	ok, err := service.ClusterLeaseAcquire(op, "leader", "a", time.Minute)
+	if err != nil || !ok {
+		t.Fatalf("a should acquire: %v %v", ok, err)
+	}
+	if ok, _ = service.ClusterLeaseAcquire(op, "leader", "b", time.Minute); ok {
+		t.Fatal("b should not acquire a held lease")
+	}
+	if ok, _ = service.ClusterLeaseAcquire(op, "leader", "a", time.Minute); !ok {
+		t.Fatal("a should renew its own lease")
+	}
+
+	if err = service.ClusterLeaseRelease(op, "leader", "a"); err != nil {
+		t.Fatalf("release: %v", err)
+	}
+	if ok, _ = service.ClusterLeaseAcquire(op, "leader", "b", time.Minute); !ok {
+		t.Fatal("b should acquire a released lease")
+	}
+	lease, _ := service.ClusterLeaseGet(op, "leader")
+	if lease == nil || lease.Holder != "b" {
+		t.Fatalf("unexpected lease: %+v", lease)
+	}
+}
+
+func TestClusterVersionedSave(t *testing.T) {
+	op := newClusterTestOperator(t)
+
+	v, err := service.GroupInfoSaveVersioned(op, "QQ-Group:1", 1, 0, []byte(`{"a":1}`))
+	if err != nil || v != 1 {
+		t.Fatalf("create: %d %v", v, err)
+	}
+	if v, err = service.GroupInfoSaveVersioned(op, "QQ-Group:1", 2, 1, []byte(`{"a":2}`)); err != nil || v != 2 {
+		t.Fatalf("update: %d %v", v, err)
+	}
+	// 另一节点仍持有版本 1
+	if _, err = service.GroupInfoSaveVersioned(op, "QQ-Group:1", 3, 1, []byte(`{"a":3}`)); !errors.Is(err, service.ErrVersionConflict) {
+		t.Fatalf("expected conflict, got %v", err)
+	}
+	row, _ := service.GroupInfoGet(op, "QQ-Group:1")
+	if row == nil || row.Version != 2 || string(row.Data) != `{"a":2}` {
+		t.Fatalf("unexpected row: %+v", row)
+	}
+
+	if v, err = service.AttrsPutByIdVersioned(op, "c1", []byte(`{}`), "n", "coc7", 0); err != nil || v != 1 {
+		t.Fatalf("attrs create: %d %v", v, err)
+	}
+	if _, err = service.AttrsPutByIdVersioned(op, "c1", []byte(`{}`), "n", "coc7", 0); !errors.Is(err, service.ErrVersionConflict) {
+		t.Fatalf("expected attrs conflict, got %v", err)
+	}
+	item, _ := service.AttrsGetById(op, "c1")
+	if item.Version != 1 {
+		t.Fatalf("unexpected attrs version: %d", item.Version)
+	}
+}
+
+func TestClusterEvents(t *testing.T) {
+	op := newClusterTestOperator(t)
+
+	latest, err := service.ClusterEventLatestID(op)
+	if err != nil || latest != 0 {
+		t.Fatalf("latest on empty table: %d %v", latest, err)
+	}
+	for _, key := range []string{"g1", "g2", "g3"} {
+		if err = service.ClusterEventPublish(op, &model.ClusterEvent{NodeID: "a", Kind: "group", Key: key, CreatedAt: 100}); err != nil {
+			t.Fatalf("publish: %v", err)
+		}
+	}
+	events, err := service.ClusterEventsAfter(op, 1, 10)
+	if err != nil || len(events) != 2 || events[0].Key != "g2" {
+		t.Fatalf("unexpected events: %+v %v", events, err)
+	}
+	if latest, _ = service.ClusterEventLatestID(op); latest != 3 {
+		t.Fatalf("unexpected latest id: %d", latest)
+	}
+	if n, _ := service.ClusterEventPruneBefore(op, 101); n != 3 {
+		t.Fatalf("expected 3 pruned, got %d", n)
+	}
+}
@@ -12,11 +12,11 @@ import (
 
 // GroupInfoListGet 使用 GORM 实现，遍历 group_info 表中的数据并调用回调函数
 // 使用流式读取（Rows）逐行处理，避免一次性加载所有数据到内存
-func GroupInfoListGet(operator engine2.DatabaseOperator, callback func(id string, updatedAt int64, data []byte)) error {
+func GroupInfoListGet(operator engine2.DatabaseOperator, callback func(id string, updatedAt int64, version int64, data []byte)) error {
 	db := operator.GetDataDB(constant.READ)
 
 	// 使用 Rows() 进行流式读取，避免一次性加载全部数据到内存
-	rows, err := db.Model(&model.GroupInfo{}).Select("id, updated_at, data").Rows()
+	rows, err := db.Model(&model.GroupInfo{}).Select("id, updated_at, version, data").Rows()
 	if err != nil {
 		return err
 	}
@@ -25,10 +25,10 @@ func GroupInfoListGet(operator engine2.DatabaseOperator, callback func(id string
 	// 逐行读取并处理
 	for rows.Next() {
 		var id string
-		var updatedAt *int64
+		var updatedAt, version *int64
 		var data []byte
 
-		if err := rows.Scan(&id, &updatedAt, &data); err != nil {
+		if err := rows.Scan(&id, &updatedAt, &version, &data); err != nil {
 			// 单条记录读取失败，跳过继续处理下一条
 			continue
 		}
@@ -38,7 +38,12 @@ func GroupInfoListGet(operator engine2.DatabaseOperator, callback func(id string
 			ua = *updatedAt
 		}
 
-		callback(id, ua, data)
+		var ver int64
+		if version != nil {
+			ver = *version
+		}
+
+		callback(id, ua, ver, data)
 	}
 
 	return rows.Err()
@@ -2,8 +2,11 @@ package service
 
 import (
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/pilagod/gorm-cursor-paginator/v2/paginator"
@@ -249,7 +252,7 @@ func CreateLoggerPaginator(
 ) *paginator.Paginator {
 	opts := []paginator.Option{
 		&paginator.Config{
-			Keys:  []string{"ID", "Time"}, // 这里设置的是结构体的字段名称，而不是gorm里的名称……
+			Keys:  []string{"Time", "ID"}, // 这里设置的是结构体的字段名称，而不是gorm里的名称……按时间排序，插入的旁白才能排在正确位置
 			Limit: 4000,
 			Order: paginator.ASC,
 		},
@@ -282,8 +285,8 @@ func LogGetAllLines(operator engine2.DatabaseOperator, groupID string, logName s
 	// 查询行数据
 	err = db.Model(&model.LogOneItem{}).
 		Select("id, nickname, im_userid, time, message, is_dice, command_id, command_info, raw_msg_id, user_uniform_id").
-		Where("log_id = ?", logID).
-		Order("time ASC").
+		Where("log_id = ? AND removed IS NULL", logID).
+		Order("time ASC, id ASC").
 		Find(&items).Error
 
 	if err != nil {
@@ -307,8 +310,8 @@ func LogGetCommandInfoStrList(operator engine2.DatabaseOperator, groupID string,
 
 	// 查询行数据
 	err = db.Model(&model.LogOneItem{}).
-		Where("log_id = ?", logID).
-		Order("time ASC").
+		Where("log_id = ? AND removed IS NULL", logID).
+		Order("time ASC, id ASC").
 		Pluck("command_info", &items).Error
 
 	if err != nil {
@@ -330,7 +333,7 @@ func LogGetCursorLines(operator engine2.DatabaseOperator, groupID string, logNam
 	var items []model.LogOneItem
 	stmt := db.Model(&model.LogOneItem{}).
 		Select("id, nickname, im_userid, time, message, is_dice, command_id, command_info, raw_msg_id, user_uniform_id").
-		Where("log_id = ?", logID)
+		Where("log_id = ? AND removed IS NULL", logID)
 	// 获取游标分页器
 	p := CreateLoggerPaginator(cursor, nil)
 	// 进行游标分页
@@ -358,7 +361,7 @@ func LogGetExportCursorLines(operator engine2.DatabaseOperator, groupID string,
 	var items []model.LogOneItemParquet
 	stmt := db.Model(&model.LogOneItemParquet{}).
 		Select("id, nickname, im_userid, time, message, is_dice, command_id, command_info, raw_msg_id, user_uniform_id").
-		Where("log_id = ?", logID)
+		Where("log_id = ? AND removed IS NULL", logID)
 	// 获取游标分页器
 	p := CreateLoggerPaginator(cursor, nil)
 	// 进行游标分页
@@ -374,10 +377,11 @@ func LogGetExportCursorLines(operator engine2.DatabaseOperator, groupID string,
 }
 
 type QueryLogLinePage struct {
-	PageNum  int    `query:"pageNum"`
-	PageSize int    `query:"pageSize"`
-	GroupID  string `query:"groupId"`
-	LogName  string `query:"logName"`
+	PageNum       int    `query:"pageNum"`
+	PageSize      int    `query:"pageSize"`
+	GroupID       string `query:"groupId"`
+	LogName       string `query:"logName"`
+	IncludeHidden bool   `query:"includeHidden"` // 是否包含已隐藏的条目，编辑日志时使用
 }
 
 // LogGetLinePage 获取log的行分页
@@ -395,10 +399,14 @@ func LogGetLinePage(operator engine2.DatabaseOperator, param *QueryLogLinePage)
 	var items []*model.LogOneItem
 
 	// 查询行数据
-	err = db.Model(&model.LogOneItem{}).
-		Select("id, nickname, im_userid, time, message, is_dice, command_id, command_info, raw_msg_id, user_uniform_id").
-		Where("log_id = ?", logID).
-		Order("time ASC").
+	query := db.Model(&model.LogOneItem{}).
+		Select("id, nickname, im_userid, time, message, is_dice, command_id, command_info, raw_msg_id, user_uniform_id, removed").
+		Where("log_id = ?", logID)
+	if !param.IncludeHidden {
+		query = query.Where("removed IS NULL")
+	}
+	err = query.
+		Order("time ASC, id ASC").
 		Limit(param.PageSize).
 		Offset((param.PageNum - 1) * param.PageSize).
 		Scan(&items).Error
@@ -577,3 +585,446 @@ func LogEditByMsgID(operator engine2.DatabaseOperator, groupID, logName, newCont
 
 	return nil
 }
+
+// 日志编辑操作，均会写入 log_edit_journal 以便撤销
+const (
+	LogEditOpHide      = "hide"
+	LogEditOpUnhide    = "unhide"
+	LogEditOpRename    = "rename"
+	LogEditOpNarration = "narration"
+	LogEditOpMerge     = "merge"
+	LogEditOpMove      = "move"
+)
+
+// removed 列的取值：手动隐藏为 1，被合并进其他条目为 2。批量取消隐藏不会恢复被合并的条目
+const (
+	logItemRemovedHidden = 1
+	logItemRemovedMerged = 2
+)
+
+var ErrLogEditNothingToUndo = errors.New("没有可撤销的日志编辑")
+
+// logEditPrev 编辑前的旧值，撤销时按 ID 写回
+type logEditPrev struct {
+	ID       uint64      `json:"id"`
+	Removed  *int        `json:"removed,omitempty"`
+	Nickname string      `json:"nickname,omitempty"`
+	Message  *string     `gorm:"-" json:"message,omitempty"`
+	Row      *logEditRow `gorm:"-" json:"row,omitempty"`
+}
+
+// logEditRow 调整顺序时在条目之间顺移的内容，各位置的 id 与时间保持不变
+type logEditRow struct {
+	ID          uint64 `gorm:"column:id"              json:"-"`
+	Nickname    string `gorm:"column:nickname"        json:"nickname"`
+	IMUserID    string `gorm:"column:im_userid"       json:"imUserId"`
+	Message     string `gorm:"column:message"         json:"message"`
+	IsDice      bool   `gorm:"column:is_dice"         json:"isDice"`
+	CommandID   int64  `gorm:"column:command_id"      json:"commandId"`
+	CommandInfo string `gorm:"column:command_info"    json:"commandInfo"`
+	RawMsgID    string `gorm:"column:raw_msg_id"      json:"rawMsgId"`
+	UniformID   string `gorm:"column:user_uniform_id" json:"uniformId"`
+	Removed     *int   `gorm:"column:removed"         json:"removed"`
+}
+
+func (r *logEditRow) columns() map[string]interface{} {
+	return map[string]interface{}{
+		"nickname":        r.Nickname,
+		"im_userid":       r.IMUserID,
+		"message":         r.Message,
+		"is_dice":         r.IsDice,
+		"command_id":      r.CommandID,
+		"command_info":    r.CommandInfo,
+		"raw_msg_id":      r.RawMsgID,
+		"user_uniform_id": r.UniformID,
+		"removed":         r.Removed,
+	}
+}
+
+// logEditTouch 编辑后刷新 logs 的 updated_at 和 size，使之后的上传不再复用旧链接
+func logEditTouch(tx *gorm.DB, logID uint64) error {
+	return tx.Model(&model.LogInfo{}).Where("id = ?", logID).Updates(map[string]interface{}{
+		"updated_at": time.Now().Unix(),
+		"size": gorm.Expr(
+			"(SELECT COUNT(1) FROM log_items WHERE log_items.log_id = ? AND log_items.removed IS NULL)", logID,
+		),
+	}).Error
+}
+
+func logEditJournalAppend(tx *gorm.DB, logID uint64, op string, summary string, prev interface{}) error {
+	payload, err := json.Marshal(prev)
+	if err != nil {
+		return err
+	}
+	return tx.Create(&model.LogEditJournal{
+		LogID:     logID,
+		Op:        op,
+		Summary:   summary,
+		Payload:   string(payload),
+		CreatedAt: time.Now().Unix(),
+	}).Error
+}
+
+// LogSetItemsHidden 批量隐藏/取消隐藏日志条目，使用 removed 列标记，返回实际变更的条数
+func LogSetItemsHidden(operator engine2.DatabaseOperator, groupID string, logName string, itemIDs []uint64, hidden bool) (int, error) {
+	db := operator.GetLogDB(constant.WRITE)
+	logID, err := getIDByGroupIDAndName(db, groupID, logName)
+	if err != nil {
+		return 0, err
+	}
+	if len(itemIDs) == 0 {
+		return 0, nil
+	}
+
+	var changed int
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var prev []logEditPrev
+		query := tx.Model(&model.LogOneItem{}).
+			Select("id, removed").
+			Where("log_id = ? AND id IN ?", logID, itemIDs)
+		if hidden {
+			query = query.Where("removed IS NULL")
+		} else {
+			query = query.Where("removed IS NOT NULL AND removed <> ?", logItemRemovedMerged)
+		}
+		if err := query.Scan(&prev).Error; err != nil {
+			return err
+		}
+		if len(prev) == 0 {
+			return nil
+		}
+		ids := make([]uint64, 0, len(prev))
+		for _, p := range prev {
+			ids = append(ids, p.ID)
+		}
+
+		var removed interface{}
+		op, summary := LogEditOpUnhide, fmt.Sprintf("取消隐藏%d条", len(ids))
+		if hidden {
+			removed = logItemRemovedHidden
+			op, summary = LogEditOpHide, fmt.Sprintf("隐藏%d条", len(ids))
+		}
+		if err := tx.Model(&model.LogOneItem{}).Where("id IN ?", ids).Update("removed", removed).Error; err != nil {
+			return err
+		}
+		if err := logEditJournalAppend(tx, logID, op, summary, prev); err != nil {
+			return err
+		}
+		changed = len(ids)
+		return logEditTouch(tx, logID)
+	})
+	return changed, err
+}
+
+// LogRemapNickname 将某个 IMUserID 在该日志中的全部条目改用新昵称，返回变更的条数
+func LogRemapNickname(operator engine2.DatabaseOperator, groupID string, logName string, imUserID string, nickname string) (int, error) {
+	db := operator.GetLogDB(constant.WRITE)
+	logID, err := getIDByGroupIDAndName(db, groupID, logName)
+	if err != nil {
+		return 0, err
+	}
+	if imUserID == "" || nickname == "" {
+		return 0, errors.New("需要指定用户ID和新昵称")
+	}
+
+	var changed int
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var prev []logEditPrev
+		if err := tx.Model(&model.LogOneItem{}).
+			Select("id, nickname").
+			Where("log_id = ? AND im_userid = ? AND nickname <> ?", logID, imUserID, nickname).
+			Scan(&prev).Error; err != nil {
+			return err
+		}
+		if len(prev) == 0 {
+			return nil
+		}
+		if err := tx.Model(&model.LogOneItem{}).
+			Where("log_id = ? AND im_userid = ?", logID, imUserID).
+			Update("nickname", nickname).Error; err != nil {
+			return err
+		}
+		summary := fmt.Sprintf("将%s的%d条昵称改为%s", imUserID, len(prev), nickname)
+		if err := logEditJournalAppend(tx, logID, LogEditOpRename, summary, prev); err != nil {
+			return err
+		}
+		changed = len(prev)
+		return logEditTouch(tx, logID)
+	})
+	return changed, err
+}
+
+// LogInsertNarration 在 afterID 对应条目之后插入一条主持人旁白，afterID 为 0 时插入到日志开头
+// 日志按时间排序，旁白取锚点条目的时间，因此会排在同一秒内的其他条目之后
+func LogInsertNarration(operator engine2.DatabaseOperator, groupID string, logName string, afterID uint64, nickname string, message string) (*model.LogOneItem, error) {
+	db := operator.GetLogDB(constant.WRITE)
+	logID, err := getIDByGroupIDAndName(db, groupID, logName)
+	if err != nil {
+		return nil, err
+	}
+	if message == "" {
+		return nil, errors.New("旁白内容不能为空")
+	}
+
+	item := &model.LogOneItem{
+		LogID:    logID,
+		GroupID:  groupID,
+		Nickname: nickname,
+		Message:  message,
+	}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var anchor model.LogOneItem
+		if afterID != 0 {
+			if err := tx.Model(&model.LogOneItem{}).Select("id, time").
+				Where("log_id = ? AND id = ?", logID, afterID).Take(&anchor).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return fmt.Errorf("日志条目 %d 不存在", afterID)
+				}
+				return err
+			}
+			item.Time = anchor.Time
+		} else {
+			var first sql.NullInt64
+			if err := tx.Model(&model.LogOneItem{}).Select("MIN(time)").
+				Where("log_id = ?", logID).Scan(&first).Error; err != nil {
+				return err
+			}
+			item.Time = time.Now().Unix()
+			if first.Valid {
+				item.Time = first.Int64 - 1
+			}
+		}
+		if err := tx.Create(item).Error; err != nil {
+			return err
+		}
+		summary := fmt.Sprintf("插入旁白#%d", item.ID)
+		if err := logEditJournalAppend(tx, logID, LogEditOpNarration, summary, []logEditPrev{{ID: item.ID}}); err != nil {
+			return err
+		}
+		return logEditTouch(tx, logID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// LogMergeItems 将同一用户被拆开的多条消息按时间顺序合并到最早的一条中，其余条目标记为已合并并隐藏
+func LogMergeItems(operator engine2.DatabaseOperator, groupID string, logName string, itemIDs []uint64) (*model.LogOneItem, error) {
+	db := operator.GetLogDB(constant.WRITE)
+	logID, err := getIDByGroupIDAndName(db, groupID, logName)
+	if err != nil {
+		return nil, err
+	}
+	itemIDs = slices.Compact(slices.Sorted(slices.Values(itemIDs)))
+	if len(itemIDs) < 2 {
+		return nil, errors.New("至少需要选择两条消息进行合并")
+	}
+
+	var merged model.LogOneItem
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var rows []logEditRow
+		if err := tx.Model(&model.LogOneItem{}).
+			Select("id, im_userid, message").
+			Where("log_id = ? AND id IN ? AND removed IS NULL", logID, itemIDs).
+			Order("time ASC, id ASC").
+			Scan(&rows).Error; err != nil {
+			return err
+		}
+		if len(rows) != len(itemIDs) {
+			return errors.New("部分条目不存在或已被隐藏")
+		}
+
+		messages := make([]string, 0, len(rows))
+		for _, r := range rows {
+			if r.IMUserID != rows[0].IMUserID {
+				return errors.New("只能合并同一用户的消息")
+			}
+			messages = append(messages, r.Message)
+		}
+
+		first := rows[0]
+		prev := []logEditPrev{{ID: first.ID, Message: &first.Message}}
+		others := make([]uint64, 0, len(rows)-1)
+		for _, r := range rows[1:] {
+			prev = append(prev, logEditPrev{ID: r.ID})
+			others = append(others, r.ID)
+		}
+		if err := tx.Model(&model.LogOneItem{}).Where("id = ?", first.ID).
+			Update("message", strings.Join(messages, "\n")).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&model.LogOneItem{}).Where("id IN ?", others).
+			Update("removed", logItemRemovedMerged).Error; err != nil {
+			return err
+		}
+		summary := fmt.Sprintf("合并%d条到#%d", len(rows), first.ID)
+		if err := logEditJournalAppend(tx, logID, LogEditOpMerge, summary, prev); err != nil {
+			return err
+		}
+		if err := tx.Where("id = ?", first.ID).Take(&merged).Error; err != nil {
+			return err
+		}
+		return logEditTouch(tx, logID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &merged, nil
+}
+
+// LogMoveItem 将条目移动到 afterID 对应条目之后，afterID 为 0 时移到开头，返回移动后条目的 ID。
+// 日志按时间排序，因此各位置的 id 与时间不变，由区间内的内容依次顺移一位实现移动
+func LogMoveItem(operator engine2.DatabaseOperator, groupID string, logName string, itemID uint64, afterID uint64) (uint64, error) {
+	db := operator.GetLogDB(constant.WRITE)
+	logID, err := getIDByGroupIDAndName(db, groupID, logName)
+	if err != nil {
+		return 0, err
+	}
+
+	newID := itemID
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var ids []uint64
+		if err := tx.Model(&model.LogOneItem{}).Where("log_id = ?", logID).
+			Order("time ASC, id ASC").Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		from := slices.Index(ids, itemID)
+		if from < 0 {
+			return fmt.Errorf("日志条目 %d 不存在", itemID)
+		}
+		to := 0
+		if afterID != 0 {
+			anchor := slices.Index(ids, afterID)
+			if anchor < 0 {
+				return fmt.Errorf("日志条目 %d 不存在", afterID)
+			}
+			to = anchor
+			if anchor < from {
+				to = anchor + 1
+			}
+		}
+		if to == from {
+			return nil
+		}
+
+		lower, upper := min(from, to), max(from, to)
+		span := ids[lower : upper+1]
+		var rows []logEditRow
+		if err := tx.Model(&model.LogOneItem{}).
+			Select("id, nickname, im_userid, message, is_dice, command_id, command_info, raw_msg_id, user_uniform_id, removed").
+			Where("id IN ?", span).Scan(&rows).Error; err != nil {
+			return err
+		}
+		byID := make(map[uint64]logEditRow, len(rows))
+		for _, r := range rows {
+			byID[r.ID] = r
+		}
+		contents := make([]logEditRow, 0, len(span))
+		for _, id := range span {
+			contents = append(contents, byID[id])
+		}
+		// 前移时被移动的内容落在区间开头，后移时落在区间末尾
+		if from > to {
+			contents = append(contents[len(contents)-1:], contents[:len(contents)-1]...)
+		} else {
+			contents = append(contents[1:], contents[0])
+		}
+
+		prev := make([]logEditPrev, 0, len(span))
+		for i, id := range span {
+			old := byID[id]
+			prev = append(prev, logEditPrev{ID: id, Row: &old})
+			if err := tx.Model(&model.LogOneItem{}).Where("id = ?", id).
+				Updates(contents[i].columns()).Error; err != nil {
+				return err
+			}
+		}
+		newID = ids[to]
+		summary := fmt.Sprintf("移动条目#%d到#%d", itemID, newID)
+		if err := logEditJournalAppend(tx, logID, LogEditOpMove, summary, prev); err != nil {
+			return err
+		}
+		return logEditTouch(tx, logID)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return newID, nil
+}
+
+// LogEditUndo 撤销该日志最近一次尚未撤销的编辑
+func LogEditUndo(operator engine2.DatabaseOperator, groupID string, logName string) (*model.LogEditJournal, error) {
+	db := operator.GetLogDB(constant.WRITE)
+	logID, err := getIDByGroupIDAndName(db, groupID, logName)
+	if err != nil {
+		return nil, err
+	}
+
+	var entry model.LogEditJournal
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("log_id = ? AND undone = ?", logID, false).
+			Order("id DESC").Take(&entry).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrLogEditNothingToUndo
+			}
+			return err
+		}
+		var prev []logEditPrev
+		if err := json.Unmarshal([]byte(entry.Payload), &prev); err != nil {
+			return fmt.Errorf("日志编辑记录损坏: %w", err)
+		}
+
+		for _, p := range prev {
+			stmt := tx.Model(&model.LogOneItem{}).Where("log_id = ? AND id = ?", logID, p.ID)
+			var err error
+			switch entry.Op {
+			case LogEditOpHide, LogEditOpUnhide:
+				err = stmt.Update("removed", p.Removed).Error
+			case LogEditOpRename:
+				err = stmt.Update("nickname", p.Nickname).Error
+			case LogEditOpNarration:
+				err = tx.Where("log_id = ? AND id = ?", logID, p.ID).Delete(&model.LogOneItem{}).Error
+			case LogEditOpMerge:
+				if p.Message != nil {
+					err = stmt.Update("message", *p.Message).Error
+				} else {
+					err = stmt.Update("removed", p.Removed).Error
+				}
+			case LogEditOpMove:
+				if p.Row == nil {
+					err = errors.New("日志编辑记录损坏: 缺少原内容")
+				} else {
+					err = stmt.Updates(p.Row.columns()).Error
+				}
+			default:
+				err = fmt.Errorf("未知的日志编辑操作: %s", entry.Op)
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		entry.Undone = true
+		if err := tx.Model(&entry).Update("undone", true).Error; err != nil {
+			return err
+		}
+		return logEditTouch(tx, logID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// LogEditJournalList 列出日志的编辑记录，新的在前
+func LogEditJournalList(operator engine2.DatabaseOperator, groupID string, logName string) ([]*model.LogEditJournal, error) {
+	db := operator.GetLogDB(constant.READ)
+	logID, err := getIDByGroupIDAndName(db, groupID, logName)
+	if err != nil {
+		return nil, err
+	}
+	var entries []*model.LogEditJournal
+	err = db.Where("log_id = ?", logID).Order("id DESC").Find(&entries).Error
+	return entries, err
+}
@@ -0,0 +1,245 @@
+package service_test
+
+import (
+	"errors"
+	"testing"
+
+	"sealdice-core/dice/service"
+	"sealdice-core/model"
+	"sealdice-core/utils/constant"
+)
+
+func newLogEditTestOperator(t *testing.T) *logInfoTestOperator {
+	t.Helper()
+
+	db := newLogInfoTestDB(t)
+	if err := db.AutoMigrate(&model.LogEditJournal{}); err != nil {
+		t.Fatalf("migrate journal table: %v", err)
+	}
+	if err := db.Create(&model.LogInfo{Name: "session", GroupID: "group", Size: new(int)}).Error; err != nil {
+		t.Fatalf("create log info: %v", err)
+	}
+	for i, who := range []string{"u1", "u2", "u1", "u2"} {
+		item := &model.LogOneItem{
+			LogID:    1,
+			GroupID:  "group",
+			Nickname: "nick-" + who,
+			IMUserID: who,
+			Time:     int64(100 + i*10),
+			Message:  "message",
+		}
+		if err := db.Create(item).Error; err != nil {
+			t.Fatalf("create log item: %v", err)
+		}
+	}
+	return &logInfoTestOperator{db: db, dbType: constant.SQLITE}
+}
+
+func logEditVisibleIDs(t *testing.T, op *logInfoTestOperator) []uint64 {
+	t.Helper()
+	items, err := service.LogGetAllLines(op, "group", "session")
+	if err != nil {
+		t.Fatalf("LogGetAllLines: %v", err)
+	}
+	ids := make([]uint64, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, item.ID)
+	}
+	return ids
+}
+
+func TestLogSetItemsHiddenAndUndo(t *testing.T) {
+	op := newLogEditTestOperator(t)
+
+	changed, err := service.LogSetItemsHidden(op, "group", "session", []uint64{2, 3, 99}, true)
+	if err != nil || changed != 2 {
+		t.Fatalf("hide: changed=%d err=%v", changed, err)
+	}
+	if got := logEditVisibleIDs(t, op); len(got) != 2 || got[0] != 1 || got[1] != 4 {
+		t.Fatalf("visible ids = %v", got)
+	}
+	if count, _ := service.LogLinesCountGet(op, "group", "session"); count != 2 {
+		t.Fatalf("count = %d, want 2", count)
+	}
+	var size int
+	op.db.Model(&model.LogInfo{}).Select("size").Where("id = 1").Scan(&size)
+	if size != 2 {
+		t.Fatalf("size = %d, want 2", size)
+	}
+
+	hidden, err := service.LogGetLinePage(op, &service.QueryLogLinePage{PageNum: 1, PageSize: 10, GroupID: "group", LogName: "session", IncludeHidden: true})
+	if err != nil || len(hidden) != 4 || hidden[1].Removed == nil {
+		t.Fatalf("includeHidden page = %v, %v", hidden, err)
+	}
+
+	if _, err = service.LogSetItemsHidden(op, "group", "session", []uint64{3}, false); err != nil {
+		t.Fatalf("unhide: %v", err)
+	}
+	if got := logEditVisibleIDs(t, op); len(got) != 3 {
+		t.Fatalf("visible after unhide = %v", got)
+	}
+
+	entry, err := service.LogEditUndo(op, "group", "session")
+	if err != nil || entry.Op != service.LogEditOpUnhide {
+		t.Fatalf("undo unhide: %+v %v", entry, err)
+	}
+	entry, err = service.LogEditUndo(op, "group", "session")
+	if err != nil || entry.Op != service.LogEditOpHide {
+		t.Fatalf("undo hide: %+v %v", entry, err)
+	}
+	if got := logEditVisibleIDs(t, op); len(got) != 4 {
+		t.Fatalf("visible after undo = %v", got)
+	}
+	if _, err = service.LogEditUndo(op, "group", "session"); !errors.Is(err, service.ErrLogEditNothingToUndo) {
+		t.Fatalf("expected nothing to undo, got %v", err)
+	}
+}
+
+func TestLogRemapNicknameAndUndo(t *testing.T) {
+	op := newLogEditTestOperator(t)
+	if err := op.db.Model(&model.LogOneItem{}).Where("id = 3").Update("nickname", "old-alias").Error; err != nil {
+		t.Fatalf("prepare: %v", err)
+	}
+
+	changed, err := service.LogRemapNickname(op, "group", "session", "u1", "调查员A")
+	if err != nil || changed != 2 {
+		t.Fatalf("rename: changed=%d err=%v", changed, err)
+	}
+	items, _ := service.LogGetAllLines(op, "group", "session")
+	if items[0].Nickname != "调查员A" || items[2].Nickname != "调查员A" || items[1].Nickname != "nick-u2" {
+		t.Fatalf("unexpected nicknames after rename")
+	}
+
+	if _, err = service.LogEditUndo(op, "group", "session"); err != nil {
+		t.Fatalf("undo: %v", err)
+	}
+	items, _ = service.LogGetAllLines(op, "group", "session")
+	if items[0].Nickname != "nick-u1" || items[2].Nickname != "old-alias" {
+		t.Fatalf("nicknames not restored: %q %q", items[0].Nickname, items[2].Nickname)
+	}
+}
+
+func TestLogInsertNarrationOrderAndUndo(t *testing.T) {
+	op := newLogEditTestOperator(t)
+
+	item, err := service.LogInsertNarration(op, "group", "session", 2, "KP", "夜色渐深。")
+	if err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	head, err := service.LogInsertNarration(op, "group", "session", 0, "KP", "序章")
+	if err != nil {
+		t.Fatalf("insert head: %v", err)
+	}
+	got := logEditVisibleIDs(t, op)
+	want := []uint64{head.ID, 1, 2, item.ID, 3, 4}
+	if len(got) != len(want) {
+		t.Fatalf("ids = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("ids = %v, want %v", got, want)
+		}
+	}
+
+	if _, err = service.LogInsertNarration(op, "group", "session", 999, "KP", "x"); err == nil {
+		t.Fatal("expected error for missing anchor")
+	}
+
+	journal, err := service.LogEditJournalList(op, "group", "session")
+	if err != nil || len(journal) != 2 || journal[0].Op != service.LogEditOpNarration {
+		t.Fatalf("journal = %v, %v", journal, err)
+	}
+
+	_, _ = service.LogEditUndo(op, "group", "session")
+	_, _ = service.LogEditUndo(op, "group", "session")
+	if got = logEditVisibleIDs(t, op); len(got) != 4 {
+		t.Fatalf("ids after undo = %v", got)
+	}
+}
+
+func TestLogEditMissingLog(t *testing.T) {
+	op := newLogEditTestOperator(t)
+	if _, err := service.LogSetItemsHidden(op, "group", "nope", []uint64{1}, true); !errors.Is(err, service.ErrLogNotFound) {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func logEditMessages(t *testing.T, op *logInfoTestOperator) []string {
+	t.Helper()
+	items, err := service.LogGetAllLines(op, "group", "session")
+	if err != nil {
+		t.Fatalf("LogGetAllLines: %v", err)
+	}
+	messages := make([]string, 0, len(items))
+	for _, item := range items {
+		messages = append(messages, item.Message)
+	}
+	return messages
+}
+
+func TestLogMergeItemsAndUndo(t *testing.T) {
+	op := newLogEditTestOperator(t)
+	for id, msg := range map[int]string{1: "a", 2: "b", 3: "c", 4: "d"} {
+		op.db.Model(&model.LogOneItem{}).Where("id = ?", id).Update("message", msg)
+	}
+
+	if _, err := service.LogMergeItems(op, "group", "session", []uint64{1, 2}); err == nil {
+		t.Fatal("merging different users should fail")
+	}
+	item, err := service.LogMergeItems(op, "group", "session", []uint64{3, 1})
+	if err != nil || item.ID != 1 || item.Message != "a\nc" {
+		t.Fatalf("merge: %+v %v", item, err)
+	}
+	if got := logEditMessages(t, op); len(got) != 3 || got[0] != "a\nc" || got[2] != "d" {
+		t.Fatalf("messages after merge = %q", got)
+	}
+
+	// 批量取消隐藏不会把被合并的条目放出来
+	if changed, _ := service.LogSetItemsHidden(op, "group", "session", []uint64{3}, false); changed != 0 {
+		t.Fatalf("merged item should not be unhidden, changed=%d", changed)
+	}
+
+	if entry, err := service.LogEditUndo(op, "group", "session"); err != nil || entry.Op != service.LogEditOpMerge {
+		t.Fatalf("undo merge: %+v %v", entry, err)
+	}
+	if got := logEditMessages(t, op); len(got) != 4 || got[0] != "a" || got[2] != "c" {
+		t.Fatalf("messages after undo = %q", got)
+	}
+}
+
+func TestLogMoveItemAndUndo(t *testing.T) {
+	op := newLogEditTestOperator(t)
+	for id, msg := range map[int]string{1: "a", 2: "b", 3: "c", 4: "d"} {
+		op.db.Model(&model.LogOneItem{}).Where("id = ?", id).Update("message", msg)
+	}
+
+	// 后移：a 移到 c 之后
+	id, err := service.LogMoveItem(op, "group", "session", 1, 3)
+	if err != nil || id != 3 {
+		t.Fatalf("move back: id=%d err=%v", id, err)
+	}
+	if got := logEditMessages(t, op); got[0] != "b" || got[1] != "c" || got[2] != "a" || got[3] != "d" {
+		t.Fatalf("messages after move = %q", got)
+	}
+	items, _ := service.LogGetAllLines(op, "group", "session")
+	if items[2].IMUserID != "u1" || items[0].IMUserID != "u2" {
+		t.Fatalf("speaker should move with message: %+v", items)
+	}
+
+	// 前移：d 移到开头
+	if id, err = service.LogMoveItem(op, "group", "session", 4, 0); err != nil || id != 1 {
+		t.Fatalf("move front: id=%d err=%v", id, err)
+	}
+	if got := logEditMessages(t, op); got[0] != "d" || got[1] != "b" || got[3] != "a" {
+		t.Fatalf("messages after move front = %q", got)
+	}
+
+	_, _ = service.LogEditUndo(op, "group", "session")
+	_, _ = service.LogEditUndo(op, "group", "session")
+	if got := logEditMessages(t, op); got[0] != "a" || got[1] != "b" || got[2] != "c" || got[3] != "d" {
+		t.Fatalf("messages after undo = %q", got)
+	}
+	if _, err = service.LogMoveItem(op, "group", "session", 1, 99); err == nil {
+		t.Fatal("expected error for missing anchor")
+	}
+}
@@ -0,0 +1,33 @@
+package service
+
+import (
+	"time"
+
+	"gorm.io/gorm/clause"
+
+	"sealdice-core/model"
+	"sealdice-core/utils/constant"
+	engine2 "sealdice-core/utils/dboperator/engine"
+)
+
+// GroupRNGSeedGet 读取群随机源种子，不存在时返回空串
+func GroupRNGSeedGet(operator engine2.DatabaseOperator, groupID string) (string, error) {
+	db := operator.GetDataDB(constant.READ)
+	var items []*model.GroupRNGSeed
+	if err := db.Where("group_id = ?", groupID).Limit(1).Find(&items).Error; err != nil {
+		return "", err
+	}
+	if len(items) == 0 {
+		return "", nil
+	}
+	return items[0].Seed, nil
+}
+
+// GroupRNGSeedSet 保存群随机源种子，已有记录时覆盖
+func GroupRNGSeedSet(operator engine2.DatabaseOperator, groupID string, seed string) error {
+	db := operator.GetDataDB(constant.WRITE)
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "group_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"seed", "updated_at"}),
+	}).Create(&model.GroupRNGSeed{GroupID: groupID, Seed: seed, UpdatedAt: time.Now().Unix()}).Error
+}
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"sealdice-core/model"
+	"sealdice-core/utils/constant"
+	engine2 "sealdice-core/utils/dboperator/engine"
+)
+
+// RollStatAdd 将一次指令产生的增量累加进统计，stats 与 outcomes 中的数值均为增量
+func RollStatAdd(operator engine2.DatabaseOperator, stats []*model.RollStat, outcomes []*model.RollStatOutcome) error {
+	db := operator.GetDataDB(constant.WRITE)
+	now := time.Now().Unix()
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, s := range stats {
+			s.UpdatedAt = now
+			err := tx.Clauses(clause.OnConflict{
+				Columns: []clause.Column{{Name: "scope_type"}, {Name: "scope_id"}, {Name: "skill"}},
+				DoUpdates: clause.Assignments(map[string]interface{}{
+					"name":       s.Name,
+					"checks":     gorm.Expr("roll_stat.checks + ?", s.Checks),
+					"successes":  gorm.Expr("roll_stat.successes + ?", s.Successes),
+					"failures":   gorm.Expr("roll_stat.failures + ?", s.Failures),
+					"criticals":  gorm.Expr("roll_stat.criticals + ?", s.Criticals),
+					"fumbles":    gorm.Expr("roll_stat.fumbles + ?", s.Fumbles),
+					"total":      gorm.Expr("roll_stat.total + ?", s.Total),
+					"updated_at": now,
+				}),
+			}).Create(s).Error
+			if err != nil {
+				return err
+			}
+		}
+		for _, o := range outcomes {
+			err := tx.Clauses(clause.OnConflict{
+				Columns: []clause.Column{{Name: "scope_type"}, {Name: "scope_id"}, {Name: "outcome"}},
+				DoUpdates: clause.Assignments(map[string]interface{}{
+					"count": gorm.Expr("roll_stat_outcome.count + ?", o.Count),
+				}),
+			}).Create(o).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// RollStatList 读取统计对象的全部技能统计，总计行排在最前，其余按检定次数降序
+func RollStatList(operator engine2.DatabaseOperator, scopeType string, scopeID string) ([]*model.RollStat, error) {
+	db := operator.GetDataDB(constant.READ)
+	var items []*model.RollStat
+	err := db.Where("scope_type = ? AND scope_id = ?", scopeType, scopeID).
+		Order("skill = '' DESC").Order("checks DESC").Order("skill ASC").
+		Find(&items).Error
+	return items, err
+}
+
+// RollStatOutcomeList 读取统计对象的 d100 出目分布，按出目升序
+func RollStatOutcomeList(operator engine2.DatabaseOperator, scopeType string, scopeID string) ([]*model.RollStatOutcome, error) {
+	db := operator.GetDataDB(constant.READ)
+	var items []*model.RollStatOutcome
+	err := db.Where("scope_type = ? AND scope_id = ?", scopeType, scopeID).
+		Order("outcome ASC").Find(&items).Error
+	return items, err
+}
+
+// RollStatClear 清空统计对象的全部数据
+func RollStatClear(operator engine2.DatabaseOperator, scopeType string, scopeID string) error {
+	db := operator.GetDataDB(constant.WRITE)
+	if err := db.Where("scope_type = ? AND scope_id = ?", scopeType, scopeID).Delete(&model.RollStat{}).Error; err != nil {
+		return err
+	}
+	return db.Where("scope_type = ? AND scope_id = ?", scopeType, scopeID).Delete(&model.RollStatOutcome{}).Error
+}
@@ -0,0 +1,54 @@
+package service_test
+
+import (
+	"testing"
+
+	"sealdice-core/dice/service"
+	"sealdice-core/model"
+	"sealdice-core/utils/constant"
+)
+
+func TestRollStatAddAccumulates(t *testing.T) {
+	db := newLogInfoTestDB(t)
+	if err := db.AutoMigrate(&model.RollStat{}, &model.RollStatOutcome{}); err != nil {
+		t.Fatalf("migrate roll stat tables: %v", err)
+	}
+	op := &logInfoTestOperator{db: db, dbType: constant.SQLITE}
+
+	for range 2 {
+		stats := []*model.RollStat{
+			{ScopeType: model.RollStatScopeUser, ScopeID: "QQ:1", Checks: 1, Successes: 1, Criticals: 1, Total: 3},
+			{ScopeType: model.RollStatScopeUser, ScopeID: "QQ:1", Skill: "侦查", Checks: 1, Successes: 1, Criticals: 1, Total: 3},
+		}
+		outcomes := []*model.RollStatOutcome{{ScopeType: model.RollStatScopeUser, ScopeID: "QQ:1", Outcome: 3, Count: 1}}
+		if err := service.RollStatAdd(op, stats, outcomes); err != nil {
+			t.Fatal(err)
+		}
+	}
+	fail := []*model.RollStat{{ScopeType: model.RollStatScopeUser, ScopeID: "QQ:1", Skill: "聆听", Checks: 1, Failures: 1, Fumbles: 1, Total: 100}}
+	if err := service.RollStatAdd(op, fail, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	items, err := service.RollStatList(op, model.RollStatScopeUser, "QQ:1")
+	if err != nil || len(items) != 3 {
+		t.Fatalf("unexpected list: %+v %v", items, err)
+	}
+	if items[0].Skill != "" || items[0].Checks != 2 || items[0].Criticals != 2 || items[0].Total != 6 {
+		t.Fatalf("unexpected total row: %+v", items[0])
+	}
+	if items[1].Skill != "侦查" || items[1].Checks != 2 || items[2].Fumbles != 1 {
+		t.Fatalf("unexpected skill rows: %+v %+v", items[1], items[2])
+	}
+	outcomes, _ := service.RollStatOutcomeList(op, model.RollStatScopeUser, "QQ:1")
+	if len(outcomes) != 1 || outcomes[0].Count != 2 {
+		t.Fatalf("unexpected outcomes: %+v", outcomes)
+	}
+
+	if err := service.RollStatClear(op, model.RollStatScopeUser, "QQ:1"); err != nil {
+		t.Fatal(err)
+	}
+	if items, _ = service.RollStatList(op, model.RollStatScopeUser, "QQ:1"); len(items) != 0 {
+		t.Fatalf("expected empty after clear: %+v", items)
+	}
+}
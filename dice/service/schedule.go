@@ -0,0 +1,88 @@
+package service
+
+import (
+	"time"
+
+	"gorm.io/gorm/clause"
+
+	"sealdice-core/model"
+	"sealdice-core/utils/constant"
+	engine2 "sealdice-core/utils/dboperator/engine"
+)
+
+// ScheduleJobCreate 新建定时任务，ID 会回填到 job 中
+func ScheduleJobCreate(operator engine2.DatabaseOperator, job *model.ScheduleJob) error {
+	db := operator.GetDataDB(constant.WRITE)
+	if job.CreatedAt == 0 {
+		job.CreatedAt = time.Now().Unix()
+	}
+	return db.Create(job).Error
+}
+
+// ScheduleJobGet 读取单个任务，不存在时返回 nil
+func ScheduleJobGet(operator engine2.DatabaseOperator, id uint64) (*model.ScheduleJob, error) {
+	db := operator.GetDataDB(constant.READ)
+	var jobs []*model.ScheduleJob
+	if err := db.Where("id = ?", id).Limit(1).Find(&jobs).Error; err != nil {
+		return nil, err
+	}
+	if len(jobs) == 0 {
+		return nil, nil
+	}
+	return jobs[0], nil
+}
+
+// ScheduleJobList 列出任务，groupID 为空时列出全部
+func ScheduleJobList(operator engine2.DatabaseOperator, groupID string) ([]*model.ScheduleJob, error) {
+	db := operator.GetDataDB(constant.READ)
+	query := db.Order("id ASC")
+	if groupID != "" {
+		query = query.Where("group_id = ?", groupID)
+	}
+	var jobs []*model.ScheduleJob
+	err := query.Find(&jobs).Error
+	return jobs, err
+}
+
+// ScheduleJobCount 统计群内的任务数
+func ScheduleJobCount(operator engine2.DatabaseOperator, groupID string) (int64, error) {
+	db := operator.GetDataDB(constant.READ)
+	var count int64
+	err := db.Model(&model.ScheduleJob{}).Where("group_id = ?", groupID).Count(&count).Error
+	return count, err
+}
+
+// ScheduleJobDelete 删除任务及其报名记录
+func ScheduleJobDelete(operator engine2.DatabaseOperator, id uint64) error {
+	db := operator.GetDataDB(constant.WRITE)
+	if err := db.Where("job_id = ?", id).Delete(&model.ScheduleRSVP{}).Error; err != nil {
+		return err
+	}
+	return db.Where("id = ?", id).Delete(&model.ScheduleJob{}).Error
+}
+
+// ScheduleRSVPSet 登记或修改报名
+func ScheduleRSVPSet(operator engine2.DatabaseOperator, item *model.ScheduleRSVP) error {
+	db := operator.GetDataDB(constant.WRITE)
+	if item.UpdatedAt == 0 {
+		item.UpdatedAt = time.Now().Unix()
+	}
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "job_id"}, {Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"name", "answer", "updated_at"}),
+	}).Create(item).Error
+}
+
+// ScheduleRSVPClear 清空任务的报名记录
+func ScheduleRSVPClear(operator engine2.DatabaseOperator, jobID uint64) error {
+	db := operator.GetDataDB(constant.WRITE)
+	return db.Where("job_id = ?", jobID).Delete(&model.ScheduleRSVP{}).Error
+}
+
+// ScheduleRSVPList 列出任务的报名记录，按登记时间排序
+func ScheduleRSVPList(operator engine2.DatabaseOperator, jobID uint64) ([]*model.ScheduleRSVP, error) {
+	db := operator.GetDataDB(constant.READ)
+	var items []*model.ScheduleRSVP
+	err := db.Where("job_id = ?", jobID).Order("updated_at ASC").Find(&items).Error
+	return items, err
+}
@@ -0,0 +1,51 @@
+package service_test
+
+import (
+	"testing"
+
+	"sealdice-core/dice/service"
+	"sealdice-core/model"
+	"sealdice-core/utils/constant"
+)
+
+func TestScheduleJobAndRSVP(t *testing.T) {
+	db := newLogInfoTestDB(t)
+	if err := db.AutoMigrate(&model.ScheduleJob{}, &model.ScheduleRSVP{}); err != nil {
+		t.Fatalf("migrate schedule tables: %v", err)
+	}
+	op := &logInfoTestOperator{db: db, dbType: constant.SQLITE}
+
+	job := &model.ScheduleJob{GroupID: "QQ-Group:1", Kind: "remind", FireAt: 100, Content: "跑团", RSVP: true}
+	if err := service.ScheduleJobCreate(op, job); err != nil || job.ID == 0 {
+		t.Fatalf("create: %d %v", job.ID, err)
+	}
+	if err := service.ScheduleJobCreate(op, &model.ScheduleJob{GroupID: "QQ-Group:2", Kind: "schedule", Spec: "0 20 * * 6"}); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := service.ScheduleJobCount(op, "QQ-Group:1"); n != 1 {
+		t.Fatalf("unexpected count: %d", n)
+	}
+	if all, _ := service.ScheduleJobList(op, ""); len(all) != 2 {
+		t.Fatalf("unexpected list: %+v", all)
+	}
+
+	for _, answer := range []string{"yes", "no"} {
+		if err := service.ScheduleRSVPSet(op, &model.ScheduleRSVP{JobID: job.ID, UserID: "QQ:1", Name: "甲", Answer: answer}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	items, err := service.ScheduleRSVPList(op, job.ID)
+	if err != nil || len(items) != 1 || items[0].Answer != "no" {
+		t.Fatalf("unexpected rsvp: %+v %v", items, err)
+	}
+
+	if err = service.ScheduleJobDelete(op, job.ID); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := service.ScheduleJobGet(op, job.ID); got != nil {
+		t.Fatalf("job should be deleted: %+v", got)
+	}
+	if items, _ = service.ScheduleRSVPList(op, job.ID); len(items) != 0 {
+		t.Fatalf("rsvp should be deleted: %+v", items)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
@@ -105,6 +106,7 @@ type storePageResponse struct {
 }
 
 type StoreQueryPageParams struct {
+	ID       string `query:"id"`
 	Content  string `query:"content"`
 	PageNum  int    `query:"pageNum"`
 	PageSize int    `query:"pageSize"`
@@ -658,6 +660,9 @@ func (m *StoreManager) StoreQueryPage(params StoreQueryPageParams) (*StorePackag
 	}
 
 	reqParams := url.Values{}
+	if params.ID != "" {
+		reqParams.Set("id", params.ID)
+	}
 	if params.Content != "" {
 		reqParams.Set("content", params.Content)
 	}
@@ -747,6 +752,35 @@ func (m *StoreManager) FindPackage(id, version string) (*StorePackage, bool) {
 	return pkg, true
 }
 
+// FindPackageVersions 查询商店中某个扩展包的全部版本，用于依赖解析
+// 查询失败时退回到已缓存的版本
+func (m *StoreManager) FindPackageVersions(id string) ([]*StorePackage, error) {
+	id = strings.TrimSpace(id)
+	versions := make([]*StorePackage, 0)
+	page, queryErr := m.StoreQueryPage(StoreQueryPageParams{ID: id, PageSize: 100})
+	if queryErr == nil {
+		for _, pkg := range page.Data {
+			if pkg != nil && pkg.ID == id {
+				versions = append(versions, pkg)
+			}
+		}
+		m.RefreshInstalled(versions)
+	}
+
+	m.lock.RLock()
+	for _, pkg := range m.packageCache {
+		if pkg != nil && pkg.ID == id && !slices.ContainsFunc(versions, func(v *StorePackage) bool { return v.Version == pkg.Version }) {
+			versions = append(versions, pkg)
+		}
+	}
+	m.lock.RUnlock()
+
+	if queryErr != nil && len(versions) == 0 {
+		return nil, queryErr
+	}
+	return versions, nil
+}
+
 type StoreUploadFormOption struct {
 	Key  string `json:"key"`
 	Desc string `json:"desc"`
@@ -0,0 +1,324 @@
+package storylog
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+	"time"
+
+	"sealdice-core/model"
+)
+
+// ExportFormat 本地离线导出的格式，不需要上传到染色器
+type ExportFormat string
+
+const (
+	ExportFormatTxt      ExportFormat = "txt"
+	ExportFormatHTML     ExportFormat = "html"
+	ExportFormatMarkdown ExportFormat = "md"
+	ExportFormatJSONL    ExportFormat = "jsonl"
+	ExportFormatDocx     ExportFormat = "docx"
+)
+
+var ErrUnknownExportFormat = errors.New("未知的导出格式，可选：txt/html/md/jsonl/docx")
+
+// ParseExportFormat 解析用户输入的格式名，空字符串视为 txt
+func ParseExportFormat(s string) (ExportFormat, error) {
+	switch strings.ToLower(strings.TrimPrefix(strings.TrimSpace(s), ".")) {
+	case "", "txt", "text":
+		return ExportFormatTxt, nil
+	case "html", "htm":
+		return ExportFormatHTML, nil
+	case "md", "markdown":
+		return ExportFormatMarkdown, nil
+	case "jsonl", "ndjson":
+		return ExportFormatJSONL, nil
+	case "docx", "word":
+		return ExportFormatDocx, nil
+	default:
+		return "", ErrUnknownExportFormat
+	}
+}
+
+// Ext 文件扩展名，带点
+func (f ExportFormat) Ext() string {
+	return "." + string(f)
+}
+
+func (f ExportFormat) ContentType() string {
+	switch f {
+	case ExportFormatHTML:
+		return "text/html; charset=utf-8"
+	case ExportFormatMarkdown:
+		return "text/markdown; charset=utf-8"
+	case ExportFormatJSONL:
+		return "application/x-ndjson"
+	case ExportFormatDocx:
+		return "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+	default:
+		return "text/plain; charset=utf-8"
+	}
+}
+
+// Exporter 逐条写入日志，配合游标分页使用，Close 时补全文件尾
+type Exporter interface {
+	WriteItem(item *model.LogOneItem) error
+	Close() error
+}
+
+// NewExporter 创建对应格式的导出器，title 一般为日志名
+func NewExporter(w io.Writer, format ExportFormat, title string) (Exporter, error) {
+	switch format {
+	case ExportFormatTxt:
+		return &txtExporter{w: w}, nil
+	case ExportFormatHTML:
+		e := &htmlExporter{w: bufio.NewWriter(w), colors: newPCColors()}
+		return e, e.writeHeader(title)
+	case ExportFormatMarkdown:
+		e := &markdownExporter{w: bufio.NewWriter(w)}
+		return e, e.writeHeader(title)
+	case ExportFormatJSONL:
+		return &jsonlExporter{enc: json.NewEncoder(w)}, nil
+	case ExportFormatDocx:
+		return newDocxExporter(w, title)
+	default:
+		return nil, ErrUnknownExportFormat
+	}
+}
+
+func itemRemoved(item *model.LogOneItem) bool {
+	return item.Removed != nil && *item.Removed != 0
+}
+
+func itemTimeText(item *model.LogOneItem) string {
+	return time.Unix(item.Time, 0).Format("2006-01-02 15:04:05")
+}
+
+type txtExporter struct {
+	w io.Writer
+}
+
+func (e *txtExporter) WriteItem(item *model.LogOneItem) error {
+	if itemRemoved(item) {
+		return nil
+	}
+	_, err := fmt.Fprintf(e.w, "%s(%v) %s\n%s\n\n", item.Nickname, item.IMUserID, itemTimeText(item), item.Message)
+	return err
+}
+
+func (e *txtExporter) Close() error {
+	return nil
+}
+
+type jsonlExporter struct {
+	enc *json.Encoder
+}
+
+func (e *jsonlExporter) WriteItem(item *model.LogOneItem) error {
+	if itemRemoved(item) {
+		return nil
+	}
+	return e.enc.Encode(item)
+}
+
+func (e *jsonlExporter) Close() error {
+	return nil
+}
+
+// rollMark 从 CommandInfo 中提取出的一次骰点，用于高亮
+type rollMark struct {
+	Text  string
+	Class string // crit/success/fail/fumble，空为普通骰点
+}
+
+var cocRankText = map[int]string{
+	-2: "大失败",
+	-1: "失败",
+	1:  "成功",
+	2:  "困难成功",
+	3:  "极难成功",
+	4:  "大成功",
+}
+
+func rankClass(rank int) string {
+	switch {
+	case rank >= 4:
+		return "crit"
+	case rank > 0:
+		return "success"
+	case rank == -2:
+		return "fumble"
+	case rank < 0:
+		return "fail"
+	default:
+		return ""
+	}
+}
+
+// extractRollMarks 解析 CommandInfo，格式参见各指令中的“指令信息”部分
+func extractRollMarks(commandInfo interface{}) []rollMark {
+	info, ok := commandInfo.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	items, ok := info["items"].([]interface{})
+	if !ok {
+		return nil
+	}
+
+	var marks []rollMark
+	for _, raw := range items {
+		j, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		mark := rollMark{}
+		rank, hasRank := j["rank"].(float64)
+		switch {
+		case info["cmd"] == "sc":
+			mark.Text = fmt.Sprintf("理智检定 %v，理智%v➯%v", j["outcome"], j["sanOld"], j["sanNew"])
+		case j["checkVal"] != nil:
+			mark.Text = fmt.Sprintf("%v %v/%v", j["expr2"], j["checkVal"], j["attrVal"])
+		case j["result"] != nil:
+			mark.Text = fmt.Sprintf("%v=%v", j["expr"], j["result"])
+			if j["reason"] != nil {
+				mark.Text = fmt.Sprintf("%v %s", j["reason"], mark.Text)
+			}
+		case j["type"] == "mod":
+			mark.Text = fmt.Sprintf("%v %v➯%v", j["attr"], j["valOld"], j["valNew"])
+		default:
+			continue
+		}
+		if hasRank {
+			if t, ok := cocRankText[int(rank)]; ok {
+				mark.Text += " " + t
+			}
+			mark.Class = rankClass(int(rank))
+		}
+		marks = append(marks, mark)
+	}
+	return marks
+}
+
+// pcColors 按出场顺序为每个角色分配颜色
+type pcColors struct {
+	assigned map[string]string
+}
+
+var pcColorPalette = []string{
+	"#c0392b", "#2471a3", "#1e8449", "#b9770e", "#7d3c98",
+	"#117a65", "#a04000", "#2e4053", "#cb4335", "#5b2c6f",
+}
+
+func newPCColors() *pcColors {
+	return &pcColors{assigned: map[string]string{}}
+}
+
+func (c *pcColors) get(item *model.LogOneItem) string {
+	key := item.IMUserID
+	if key == "" {
+		key = item.Nickname
+	}
+	if color, ok := c.assigned[key]; ok {
+		return color
+	}
+	color := pcColorPalette[len(c.assigned)%len(pcColorPalette)]
+	c.assigned[key] = color
+	return color
+}
+
+const htmlExportStyle = `body{font-family:"Noto Serif SC","Source Han Serif SC",serif;max-width:860px;margin:2em auto;padding:0 1em;background:#fdfcf8;color:#222}
+h1{font-size:1.6em;border-bottom:1px solid #ccc;padding-bottom:.3em}
+.line{margin:.4em 0;line-height:1.6}
+.time{color:#999;font-size:.8em;margin-right:.5em}
+.name{font-weight:bold;margin-right:.3em}
+.msg{white-space:pre-wrap}
+.dice .msg{color:#555;font-style:italic}
+.roll{display:inline-block;margin:.1em .3em;padding:0 .4em;border-radius:3px;background:#eee;font-size:.9em;font-style:normal}
+.roll.crit{background:#f9e79f}.roll.success{background:#d5f5e3}.roll.fail{background:#fadbd8}.roll.fumble{background:#922b21;color:#fff}
+`
+
+type htmlExporter struct {
+	w      *bufio.Writer
+	colors *pcColors
+}
+
+func (e *htmlExporter) writeHeader(title string) error {
+	t := html.EscapeString(title)
+	_, err := fmt.Fprintf(e.w, "<!DOCTYPE html>\n<html lang=\"zh-CN\">\n<head>\n<meta charset=\"utf-8\">\n<title>%s</title>\n<style>\n%s</style>\n</head>\n<body>\n<h1>%s</h1>\n", t, htmlExportStyle, t)
+	return err
+}
+
+func (e *htmlExporter) WriteItem(item *model.LogOneItem) error {
+	if itemRemoved(item) {
+		return nil
+	}
+	class := "line"
+	if item.IsDice {
+		class += " dice"
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "<div class=\"%s\"><span class=\"time\">%s</span><span class=\"name\" style=\"color:%s\">%s</span><span class=\"msg\">%s</span>",
+		class, itemTimeText(item), e.colors.get(item), html.EscapeString(item.Nickname), html.EscapeString(item.Message))
+	for _, mark := range extractRollMarks(item.CommandInfo) {
+		fmt.Fprintf(&sb, "<span class=\"roll %s\">%s</span>", mark.Class, html.EscapeString(mark.Text))
+	}
+	sb.WriteString("</div>\n")
+	_, err := e.w.WriteString(sb.String())
+	return err
+}
+
+func (e *htmlExporter) Close() error {
+	if _, err := e.w.WriteString("</body>\n</html>\n"); err != nil {
+		return err
+	}
+	return e.w.Flush()
+}
+
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`, "#", `\#`,
+	"[", `\[`, "]", `\]`, "<", "&lt;", ">", "&gt;", "|", `\|`,
+)
+
+type markdownExporter struct {
+	w *bufio.Writer
+}
+
+func (e *markdownExporter) writeHeader(title string) error {
+	_, err := fmt.Fprintf(e.w, "# %s\n\n", markdownEscaper.Replace(title))
+	return err
+}
+
+func (e *markdownExporter) WriteItem(item *model.LogOneItem) error {
+	if itemRemoved(item) {
+		return nil
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "**%s** <sub>%s</sub>\n\n", markdownEscaper.Replace(item.Nickname), itemTimeText(item))
+	for _, line := range strings.Split(item.Message, "\n") {
+		if item.IsDice {
+			sb.WriteString("> ")
+		}
+		sb.WriteString(markdownEscaper.Replace(line))
+		sb.WriteString("  \n")
+	}
+	marks := extractRollMarks(item.CommandInfo)
+	if len(marks) > 0 {
+		texts := make([]string, 0, len(marks))
+		for _, mark := range marks {
+			texts = append(texts, "`"+strings.ReplaceAll(mark.Text, "`", "'")+"`")
+		}
+		sb.WriteString("\n🎲 " + strings.Join(texts, " ") + "\n")
+	}
+	sb.WriteString("\n")
+	_, err := e.w.WriteString(sb.String())
+	return err
+}
+
+func (e *markdownExporter) Close() error {
+	return e.w.Flush()
+}
@@ -0,0 +1,118 @@
+package storylog
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+
+	"sealdice-core/model"
+)
+
+// docx 只写入最小的 WordprocessingML 结构：内容类型、包关系、正文
+const (
+	docxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+<Default Extension="xml" ContentType="application/xml"/>
+<Override PartName="/word/document.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.document.main+xml"/>
+</Types>`
+	docxRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="word/document.xml"/>
+</Relationships>`
+	docxDocumentHead = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>`
+	docxDocumentTail = `<w:sectPr><w:pgSz w:w="11906" w:h="16838"/><w:pgMar w:top="1440" w:right="1440" w:bottom="1440" w:left="1440" w:header="720" w:footer="720" w:gutter="0"/></w:sectPr></w:body></w:document>`
+)
+
+var docxRollHighlight = map[string]string{
+	"crit":    "yellow",
+	"success": "green",
+	"fail":    "red",
+	"fumble":  "darkRed",
+	"":        "lightGray",
+}
+
+type docxExporter struct {
+	zw     *zip.Writer
+	doc    io.Writer
+	colors *pcColors
+}
+
+func newDocxExporter(w io.Writer, title string) (*docxExporter, error) {
+	zw := zip.NewWriter(w)
+	for _, part := range []struct{ name, content string }{
+		{"[Content_Types].xml", docxContentTypes},
+		{"_rels/.rels", docxRels},
+	} {
+		fw, err := zw.Create(part.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err = io.WriteString(fw, part.content); err != nil {
+			return nil, err
+		}
+	}
+	doc, err := zw.Create("word/document.xml")
+	if err != nil {
+		return nil, err
+	}
+	e := &docxExporter{zw: zw, doc: doc, colors: newPCColors()}
+	if _, err = io.WriteString(doc, docxDocumentHead); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	buf.WriteString(`<w:p><w:pPr><w:jc w:val="center"/></w:pPr>`)
+	writeDocxRun(&buf, title, `<w:b/><w:sz w:val="36"/>`)
+	buf.WriteString(`</w:p>`)
+	_, err = doc.Write(buf.Bytes())
+	return e, err
+}
+
+func writeDocxRun(buf *bytes.Buffer, text string, props string) {
+	buf.WriteString(`<w:r>`)
+	if props != "" {
+		buf.WriteString(`<w:rPr>` + props + `</w:rPr>`)
+	}
+	for i, line := range strings.Split(text, "\n") {
+		if i > 0 {
+			buf.WriteString(`<w:br/>`)
+		}
+		buf.WriteString(`<w:t xml:space="preserve">`)
+		_ = xml.EscapeText(buf, []byte(line))
+		buf.WriteString(`</w:t>`)
+	}
+	buf.WriteString(`</w:r>`)
+}
+
+func (e *docxExporter) WriteItem(item *model.LogOneItem) error {
+	if itemRemoved(item) {
+		return nil
+	}
+	color := strings.TrimPrefix(e.colors.get(item), "#")
+	var buf bytes.Buffer
+	buf.WriteString(`<w:p>`)
+	writeDocxRun(&buf, itemTimeText(item)+" ", `<w:color w:val="999999"/><w:sz w:val="16"/>`)
+	writeDocxRun(&buf, item.Nickname+"：", `<w:b/><w:color w:val="`+color+`"/>`)
+	msgProps := ""
+	if item.IsDice {
+		msgProps = `<w:i/><w:color w:val="555555"/>`
+	}
+	writeDocxRun(&buf, item.Message, msgProps)
+	for _, mark := range extractRollMarks(item.CommandInfo) {
+		writeDocxRun(&buf, " ", "")
+		writeDocxRun(&buf, "["+mark.Text+"]", `<w:highlight w:val="`+docxRollHighlight[mark.Class]+`"/>`)
+	}
+	buf.WriteString(`</w:p>`)
+	_, err := e.doc.Write(buf.Bytes())
+	return err
+}
+
+func (e *docxExporter) Close() error {
+	if _, err := io.WriteString(e.doc, docxDocumentTail); err != nil {
+		return err
+	}
+	return e.zw.Close()
+}
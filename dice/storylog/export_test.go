@@ -0,0 +1,150 @@
+//nolint:testpackage
+package storylog
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"sealdice-core/model"
+)
+
+func exportTestItems() []*model.LogOneItem {
+	removed := 1
+	return []*model.LogOneItem{
+		{Nickname: "木落", IMUserID: "QQ:1", Time: 1700000000, Message: "我要检定<力量>"},
+		{
+			Nickname: "海豹", IMUserID: "QQ:2", Time: 1700000001, Message: "<木落>的力量检定", IsDice: true,
+			CommandInfo: map[string]interface{}{
+				"cmd":    "ra",
+				"rule":   "coc7",
+				"pcName": "木落",
+				"items": []interface{}{
+					map[string]interface{}{"expr2": "力量", "checkVal": float64(3), "attrVal": float64(50), "rank": float64(4)},
+				},
+			},
+		},
+		{Nickname: "林", IMUserID: "QQ:3", Time: 1700000002, Message: "第二个角色"},
+		{Nickname: "木落", IMUserID: "QQ:1", Time: 1700000003, Message: "已删除", Removed: &removed},
+	}
+}
+
+func exportAll(t *testing.T, format ExportFormat) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	exporter, err := NewExporter(&buf, format, "测试<日志>")
+	if err != nil {
+		t.Fatalf("NewExporter(%s): %v", format, err)
+	}
+	for _, item := range exportTestItems() {
+		if err := exporter.WriteItem(item); err != nil {
+			t.Fatalf("WriteItem(%s): %v", format, err)
+		}
+	}
+	if err := exporter.Close(); err != nil {
+		t.Fatalf("Close(%s): %v", format, err)
+	}
+	return buf.Bytes()
+}
+
+func TestParseExportFormatAliases(t *testing.T) {
+	cases := map[string]ExportFormat{
+		"":         ExportFormatTxt,
+		"HTML":     ExportFormatHTML,
+		".md":      ExportFormatMarkdown,
+		"markdown": ExportFormatMarkdown,
+		"ndjson":   ExportFormatJSONL,
+		"word":     ExportFormatDocx,
+	}
+	for input, want := range cases {
+		got, err := ParseExportFormat(input)
+		if err != nil || got != want {
+			t.Fatalf("ParseExportFormat(%q) = %q, %v; want %q", input, got, err, want)
+		}
+	}
+	if _, err := ParseExportFormat("pdf"); err == nil {
+		t.Fatal("expected error for unknown format")
+	}
+}
+
+func TestExportHTMLColorsPCsAndHighlightsRolls(t *testing.T) {
+	out := string(exportAll(t, ExportFormatHTML))
+	if !strings.Contains(out, "<title>测试&lt;日志&gt;</title>") {
+		t.Fatalf("title not escaped: %s", out)
+	}
+	if !strings.Contains(out, "我要检定&lt;力量&gt;") {
+		t.Fatal("message not escaped")
+	}
+	if !strings.Contains(out, `color:`+pcColorPalette[0]) || !strings.Contains(out, `color:`+pcColorPalette[2]) {
+		t.Fatal("expected one color per PC")
+	}
+	if !strings.Contains(out, `<span class="roll crit">力量 3/50 大成功</span>`) {
+		t.Fatalf("roll not highlighted: %s", out)
+	}
+	if strings.Contains(out, "已删除") {
+		t.Fatal("removed item should be skipped")
+	}
+	if !strings.HasSuffix(out, "</html>\n") {
+		t.Fatal("html not closed")
+	}
+}
+
+func TestExportMarkdown(t *testing.T) {
+	out := string(exportAll(t, ExportFormatMarkdown))
+	if !strings.HasPrefix(out, "# 测试&lt;日志&gt;\n") {
+		t.Fatalf("unexpected header: %q", out)
+	}
+	if !strings.Contains(out, "> &lt;木落&gt;的力量检定") {
+		t.Fatal("dice line should be quoted")
+	}
+	if !strings.Contains(out, "🎲 `力量 3/50 大成功`") {
+		t.Fatalf("roll not marked: %s", out)
+	}
+}
+
+func TestExportJSONLOneItemPerLine(t *testing.T) {
+	lines := strings.Split(strings.TrimSpace(string(exportAll(t, ExportFormatJSONL))), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("len(lines) = %d, want 3", len(lines))
+	}
+	var item model.LogOneItem
+	if err := json.Unmarshal([]byte(lines[1]), &item); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if !item.IsDice || item.CommandInfo == nil {
+		t.Fatalf("command info lost: %+v", item)
+	}
+}
+
+func TestExportDocxIsValidPackage(t *testing.T) {
+	data := exportAll(t, ExportFormatDocx)
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("zip.NewReader: %v", err)
+	}
+	files := map[string]*zip.File{}
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "word/document.xml"} {
+		if files[name] == nil {
+			t.Fatalf("missing part %s", name)
+		}
+	}
+	rc, err := files["word/document.xml"].Open()
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer rc.Close()
+	doc, _ := io.ReadAll(rc)
+	text := string(doc)
+	if !strings.Contains(text, "我要检定&lt;力量&gt;") || !strings.Contains(text, `w:highlight w:val="yellow"`) {
+		t.Fatalf("unexpected document: %s", text)
+	}
+	if !strings.HasSuffix(text, "</w:document>") {
+		t.Fatal("document not closed")
+	}
+}
@@ -45,19 +45,23 @@ func buildLogBackupFilename(groupID, logName string, now time.Time) (string, str
 }
 
 func buildTempPattern(prefix string) (string, string) {
+	return buildTempPatternWithExt(prefix, ".txt")
+}
+
+func buildTempPatternWithExt(prefix string, ext string) (string, string) {
 	cleanPrefix, ok := sanitizeFilenameComponent(prefix)
 	if ok {
-		if len([]byte(cleanPrefix+"-*.txt")) <= maxTempPatternBytes {
-			pattern := cleanPrefix + "-*.txt"
+		if len([]byte(cleanPrefix+"-*"+ext)) <= maxTempPatternBytes {
+			pattern := cleanPrefix + "-*" + ext
 			return pattern, ""
 		}
 	}
 
-	pattern := "log-export-" + hashHex(prefix) + "-*.txt"
+	pattern := "log-export-" + hashHex(prefix) + "-*" + ext
 	if len([]byte(pattern)) > maxTempPatternBytes {
 		pattern = trimUTF8ByBytes(pattern, maxTempPatternBytes)
 		if !strings.Contains(pattern, "*") {
-			pattern = "log-" + hashHex(prefix)[:8] + "-*.txt"
+			pattern = "log-" + hashHex(prefix)[:8] + "-*" + ext
 		}
 	}
 	return pattern, fileNameFallbackNotice
@@ -67,6 +71,11 @@ func BuildTempPattern(prefix string) (string, string) {
 	return buildTempPattern(prefix)
 }
 
+// BuildExportTempPattern 同 BuildTempPattern，但使用导出格式对应的扩展名
+func BuildExportTempPattern(prefix string, format ExportFormat) (string, string) {
+	return buildTempPatternWithExt(prefix, format.Ext())
+}
+
 func sanitizeFilenameComponent(name string) (string, bool) {
 	name = strings.Map(func(r rune) rune {
 		if unicode.IsControl(r) {
@@ -0,0 +1,62 @@
+package storylog
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	ErrShareTokenInvalid = errors.New("日志分享链接无效")
+	ErrShareTokenExpired = errors.New("日志分享链接已过期")
+)
+
+// ShareClaims 本地日志分享链接中携带的信息
+type ShareClaims struct {
+	GroupID   string `json:"g"`
+	LogName   string `json:"n"`
+	ExpiresAt int64  `json:"e"`
+}
+
+// SignShareToken 生成 payload.signature 形式的分享令牌，均为 base64url 编码
+func SignShareToken(secret []byte, claims ShareClaims) (string, error) {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + shareSignature(secret, encoded), nil
+}
+
+// VerifyShareToken 校验签名与有效期
+func VerifyShareToken(secret []byte, token string, now time.Time) (*ShareClaims, error) {
+	encoded, sig, ok := strings.Cut(token, ".")
+	if !ok || len(secret) == 0 {
+		return nil, ErrShareTokenInvalid
+	}
+	if !hmac.Equal([]byte(sig), []byte(shareSignature(secret, encoded))) {
+		return nil, ErrShareTokenInvalid
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrShareTokenInvalid
+	}
+	var claims ShareClaims
+	if err = json.Unmarshal(payload, &claims); err != nil {
+		return nil, ErrShareTokenInvalid
+	}
+	if now.Unix() > claims.ExpiresAt {
+		return nil, ErrShareTokenExpired
+	}
+	return &claims, nil
+}
+
+func shareSignature(secret []byte, encoded string) string {
+	mac := hmac.New(sha256.New, secret)
+	_, _ = mac.Write([]byte(encoded))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
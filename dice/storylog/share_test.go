@@ -0,0 +1,50 @@
+//nolint:testpackage
+package storylog
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestShareTokenRoundTrip(t *testing.T) {
+	secret := []byte("secret")
+	now := time.Unix(1700000000, 0)
+	token, err := SignShareToken(secret, ShareClaims{GroupID: "QQ-Group:1", LogName: "团/1", ExpiresAt: now.Add(time.Hour).Unix()})
+	if err != nil {
+		t.Fatalf("SignShareToken: %v", err)
+	}
+	if strings.ContainsAny(token, "/+=") {
+		t.Fatalf("token %q is not url safe", token)
+	}
+	claims, err := VerifyShareToken(secret, token, now)
+	if err != nil {
+		t.Fatalf("VerifyShareToken: %v", err)
+	}
+	if claims.GroupID != "QQ-Group:1" || claims.LogName != "团/1" {
+		t.Fatalf("unexpected claims %+v", claims)
+	}
+}
+
+func TestShareTokenRejectsTamperingAndExpiry(t *testing.T) {
+	secret := []byte("secret")
+	now := time.Unix(1700000000, 0)
+	token, _ := SignShareToken(secret, ShareClaims{GroupID: "g", LogName: "n", ExpiresAt: now.Unix()})
+
+	if _, err := VerifyShareToken([]byte("other"), token, now); !errors.Is(err, ErrShareTokenInvalid) {
+		t.Fatalf("wrong secret: err = %v", err)
+	}
+	forged, _ := SignShareToken([]byte("other"), ShareClaims{GroupID: "g2", LogName: "n", ExpiresAt: now.Unix()})
+	payload, _, _ := strings.Cut(forged, ".")
+	_, sig, _ := strings.Cut(token, ".")
+	if _, err := VerifyShareToken(secret, payload+"."+sig, now); !errors.Is(err, ErrShareTokenInvalid) {
+		t.Fatalf("swapped payload: err = %v", err)
+	}
+	if _, err := VerifyShareToken(secret, "garbage", now); !errors.Is(err, ErrShareTokenInvalid) {
+		t.Fatalf("garbage: err = %v", err)
+	}
+	if _, err := VerifyShareToken(secret, token, now.Add(time.Second)); !errors.Is(err, ErrShareTokenExpired) {
+		t.Fatalf("expired: err = %v", err)
+	}
+}
@@ -76,6 +76,23 @@ func uploadV1(env UploadEnv) (string, string, error) {
 	return url, env.Notice, nil
 }
 
+// Backup 只生成本地 zip 备份而不上传，用于不上传日志的场合
+func Backup(env UploadEnv) (string, error) {
+	_ = os.MkdirAll(env.Dir, 0o755)
+
+	lines, err := service.LogGetAllLines(env.Db, env.GroupID, env.LogName)
+	if err != nil {
+		return env.Notice, err
+	}
+	if len(lines) == 0 {
+		return env.Notice, errors.New("此log不存在，或条目数为空，名字是否正确？")
+	}
+	env.lines = lines
+
+	err = formatAndBackup(&env)
+	return env.Notice, err
+}
+
 // formatAndBackup 将导出的日志序列化到 env.data 并存储为本地 zip
 func formatAndBackup(env *UploadEnv) (err error) {
 	filename, notice := buildLogBackupFilename(env.GroupID, env.LogName, time.Now())
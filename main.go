@@ -93,6 +93,9 @@ func cleanupCreate(diceManager *dice.DiceManager) func() {
 			}
 		}
 
+		// 数据均已保存，注销集群节点
+		diceManager.Cluster.Stop()
+
 		for _, i := range diceManager.Dice {
 			d := i
 			d.DBOperator.Close()
@@ -756,3 +756,77 @@ func ConvertStringMessage(raw string, opts ...ConvertOption) (r []IMessageElemen
 	}
 	return r
 }
+
+var reCQCode = regexp.MustCompile(`\[CQ:([^],]+)(,[^]]*)?]`)
+
+// UnescapeCQText 还原 CQ 码中被转义的字符，参数与文本通用
+func UnescapeCQText(v string) string {
+	v = strings.ReplaceAll(v, "&#91;", "[")
+	v = strings.ReplaceAll(v, "&#93;", "]")
+	v = strings.ReplaceAll(v, "&#44;", ",")
+	return strings.ReplaceAll(v, "&amp;", "&")
+}
+
+// ParseCQCode 将收到的 CQ 码文本解析为消息段
+// 与 ConvertStringMessage 不同，这里只记录图片、语音、文件的地址，不会读取或下载任何资源，适用于适配器的入站消息
+// 文本部分保持原样，CQ 码参数会被反转义
+func ParseCQCode(raw string) []IMessageElement {
+	var r []IMessageElement
+	last := 0
+	for _, m := range reCQCode.FindAllStringSubmatchIndex(raw, -1) {
+		if m[0] > last {
+			r = append(r, newText(raw[last:m[0]]))
+		}
+		last = m[1]
+
+		cqType := raw[m[2]:m[3]]
+		args := map[string]string{}
+		if m[4] >= 0 {
+			for _, i := range strings.Split(raw[m[4]+1:m[5]], ",") {
+				pair := strings.SplitN(i, "=", 2)
+				if len(pair) == 2 {
+					args[pair[0]] = UnescapeCQText(pair[1])
+				}
+			}
+		}
+		r = append(r, cqArgsToElement(cqType, args))
+	}
+	if last < len(raw) {
+		r = append(r, newText(raw[last:]))
+	}
+	return r
+}
+
+// cqFileRef 从 CQ 码参数中取出资源引用，file 为远程地址时同时视为 url
+func cqFileRef(args map[string]string) *FileElement {
+	f := &FileElement{
+		File: strings.TrimSpace(args["file"]),
+		URL:  strings.TrimSpace(args["url"]),
+	}
+	if f.URL == "" && strings.Contains(f.File, "://") {
+		f.URL = f.File
+	}
+	return f
+}
+
+func cqArgsToElement(cqType string, args map[string]string) IMessageElement {
+	switch cqType {
+	case "text":
+		return newText(args["text"])
+	case "image":
+		f := cqFileRef(args)
+		return &ImageElement{File: f, URL: f.URL}
+	case "record":
+		return &RecordElement{File: cqFileRef(args)}
+	case "file":
+		return cqFileRef(args)
+	case "at", "tts", "reply", "poke", "face":
+		elem := GetElementFactory(cqType)()
+		_ = elem.FromCQData(args)
+		return elem
+	default:
+		elem := &DefaultElement{RawType: cqType}
+		_ = elem.FromCQData(args)
+		return elem
+	}
+}
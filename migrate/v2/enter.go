@@ -34,6 +34,15 @@ func InitUpgrader(operator operator.DatabaseOperator) error {
 	mgr.Register(v151.V151GORMCleanMigration)
 	// v160注册
 	mgr.Register(v160.V160LogIDZeroCleanMigration)
+	mgr.Register(v160.V160LogEditJournalMigration)
+	mgr.Register(v160.V160AttrsJournalMigration)
+	mgr.Register(v160.V160ClusterMigration)
+	mgr.Register(v160.V160ScheduleMigration)
+	mgr.Register(v160.V160RollStatMigration)
+	mgr.Register(v160.V160BanFederationMigration)
+	mgr.Register(v160.V160BanAppealMigration)
+	mgr.Register(v160.V160CensorSpansMigration)
+	mgr.Register(v160.V160RNGSeedMigration)
 	err := mgr.ApplyAll()
 	if err != nil {
 		return err
@@ -0,0 +1,24 @@
+package v160
+
+import (
+	"sealdice-core/model"
+	"sealdice-core/utils/constant"
+	operator "sealdice-core/utils/dboperator/engine"
+	upgrade "sealdice-core/utils/upgrader"
+)
+
+var V160AttrsJournalMigration = upgrade.Upgrade{
+	ID: "010_V160AttrsJournalMigration",
+	Description: `
+# 升级说明
+新增角色属性变更记录表 attrs_journal
+`,
+	Apply: func(logf func(string), dbOperator operator.DatabaseOperator) error {
+		db := dbOperator.GetDataDB(constant.WRITE)
+		if err := db.AutoMigrate(&model.AttrsJournal{}); err != nil {
+			return err
+		}
+		logf("[INFO] V160角色属性变更记录表创建完毕")
+		return nil
+	},
+}
@@ -0,0 +1,24 @@
+package v160
+
+import (
+	"sealdice-core/model"
+	"sealdice-core/utils/constant"
+	operator "sealdice-core/utils/dboperator/engine"
+	upgrade "sealdice-core/utils/upgrader"
+)
+
+var V160BanAppealMigration = upgrade.Upgrade{
+	ID: "015_V160BanAppealMigration",
+	Description: `
+# 升级说明
+新增黑名单申诉及其处理记录表
+`,
+	Apply: func(logf func(string), dbOperator operator.DatabaseOperator) error {
+		db := dbOperator.GetDataDB(constant.WRITE)
+		if err := db.AutoMigrate(&model.BanAppeal{}, &model.BanAppealHistory{}); err != nil {
+			return err
+		}
+		logf("[INFO] V160黑名单申诉数据表创建完毕")
+		return nil
+	},
+}
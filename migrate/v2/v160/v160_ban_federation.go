@@ -0,0 +1,24 @@
+package v160
+
+import (
+	"sealdice-core/model"
+	"sealdice-core/utils/constant"
+	operator "sealdice-core/utils/dboperator/engine"
+	upgrade "sealdice-core/utils/upgrader"
+)
+
+var V160BanFederationMigration = upgrade.Upgrade{
+	ID: "014_V160BanFederationMigration",
+	Description: `
+# 升级说明
+新增联合黑名单来源记录表
+`,
+	Apply: func(logf func(string), dbOperator operator.DatabaseOperator) error {
+		db := dbOperator.GetDataDB(constant.WRITE)
+		if err := db.AutoMigrate(&model.BanFederationEntry{}); err != nil {
+			return err
+		}
+		logf("[INFO] V160联合黑名单数据表创建完毕")
+		return nil
+	},
+}
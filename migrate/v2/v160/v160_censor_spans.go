@@ -0,0 +1,24 @@
+package v160
+
+import (
+	"sealdice-core/model"
+	"sealdice-core/utils/constant"
+	operator "sealdice-core/utils/dboperator/engine"
+	upgrade "sealdice-core/utils/upgrader"
+)
+
+var V160CensorSpansMigration = upgrade.Upgrade{
+	ID: "016_V160CensorSpansMigration",
+	Description: `
+# 升级说明
+拦截记录新增命中片段字段
+`,
+	Apply: func(logf func(string), dbOperator operator.DatabaseOperator) error {
+		db := dbOperator.GetCensorDB(constant.WRITE)
+		if err := db.AutoMigrate(&model.CensorLog{}); err != nil {
+			return err
+		}
+		logf("[INFO] V160拦截记录命中片段字段添加完毕")
+		return nil
+	},
+}
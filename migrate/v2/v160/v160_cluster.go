@@ -0,0 +1,33 @@
+package v160
+
+import (
+	"sealdice-core/model"
+	"sealdice-core/utils/constant"
+	operator "sealdice-core/utils/dboperator/engine"
+	upgrade "sealdice-core/utils/upgrader"
+)
+
+var V160ClusterMigration = upgrade.Upgrade{
+	ID: "011_V160ClusterMigration",
+	Description: `
+# 升级说明
+新增集群模式所需的节点、租约与变更通知表，并为 group_info 与 attrs 表增加行版本字段
+`,
+	Apply: func(logf func(string), dbOperator operator.DatabaseOperator) error {
+		db := dbOperator.GetDataDB(constant.WRITE)
+		if err := db.AutoMigrate(&model.ClusterNode{}, &model.ClusterLease{}, &model.ClusterEvent{}); err != nil {
+			return err
+		}
+		// 只补充字段，不对旧表做 AutoMigrate，避免改动已有的索引与字段类型
+		for _, m := range []interface{}{&model.GroupInfo{}, &model.AttributesItemModel{}} {
+			if db.Migrator().HasColumn(m, "Version") {
+				continue
+			}
+			if err := db.Migrator().AddColumn(m, "Version"); err != nil {
+				return err
+			}
+		}
+		logf("[INFO] V160集群模式数据表创建完毕")
+		return nil
+	},
+}
@@ -0,0 +1,24 @@
+package v160
+
+import (
+	"sealdice-core/model"
+	"sealdice-core/utils/constant"
+	operator "sealdice-core/utils/dboperator/engine"
+	upgrade "sealdice-core/utils/upgrader"
+)
+
+var V160LogEditJournalMigration = upgrade.Upgrade{
+	ID: "009_V160LogEditJournalMigration",
+	Description: `
+# 升级说明
+新增日志编辑撤销记录表 log_edit_journal
+`,
+	Apply: func(logf func(string), dbOperator operator.DatabaseOperator) error {
+		db := dbOperator.GetLogDB(constant.WRITE)
+		if err := db.AutoMigrate(&model.LogEditJournal{}); err != nil {
+			return err
+		}
+		logf("[INFO] V160日志编辑撤销记录表创建完毕")
+		return nil
+	},
+}
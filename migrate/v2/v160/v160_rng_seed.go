@@ -0,0 +1,24 @@
+package v160
+
+import (
+	"sealdice-core/model"
+	"sealdice-core/utils/constant"
+	operator "sealdice-core/utils/dboperator/engine"
+	upgrade "sealdice-core/utils/upgrader"
+)
+
+var V160RNGSeedMigration = upgrade.Upgrade{
+	ID: "017_V160RNGSeedMigration",
+	Description: `
+# 升级说明
+新增群随机源种子表，种子不再随群信息保存
+`,
+	Apply: func(logf func(string), dbOperator operator.DatabaseOperator) error {
+		db := dbOperator.GetDataDB(constant.WRITE)
+		if err := db.AutoMigrate(&model.GroupRNGSeed{}); err != nil {
+			return err
+		}
+		logf("[INFO] V160随机源种子数据表创建完毕")
+		return nil
+	},
+}
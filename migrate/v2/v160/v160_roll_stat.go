@@ -0,0 +1,24 @@
+package v160
+
+import (
+	"sealdice-core/model"
+	"sealdice-core/utils/constant"
+	operator "sealdice-core/utils/dboperator/engine"
+	upgrade "sealdice-core/utils/upgrader"
+)
+
+var V160RollStatMigration = upgrade.Upgrade{
+	ID: "013_V160RollStatMigration",
+	Description: `
+# 升级说明
+新增检定统计与d100出目分布表，统计从升级后开始累计
+`,
+	Apply: func(logf func(string), dbOperator operator.DatabaseOperator) error {
+		db := dbOperator.GetDataDB(constant.WRITE)
+		if err := db.AutoMigrate(&model.RollStat{}, &model.RollStatOutcome{}); err != nil {
+			return err
+		}
+		logf("[INFO] V160检定统计数据表创建完毕")
+		return nil
+	},
+}
@@ -0,0 +1,24 @@
+package v160
+
+import (
+	"sealdice-core/model"
+	"sealdice-core/utils/constant"
+	operator "sealdice-core/utils/dboperator/engine"
+	upgrade "sealdice-core/utils/upgrader"
+)
+
+var V160ScheduleMigration = upgrade.Upgrade{
+	ID: "012_V160ScheduleMigration",
+	Description: `
+# 升级说明
+新增定时提醒、周期公告与报名记录表
+`,
+	Apply: func(logf func(string), dbOperator operator.DatabaseOperator) error {
+		db := dbOperator.GetDataDB(constant.WRITE)
+		if err := db.AutoMigrate(&model.ScheduleJob{}, &model.ScheduleRSVP{}); err != nil {
+			return err
+		}
+		logf("[INFO] V160定时任务数据表创建完毕")
+		return nil
+	},
+}
@@ -20,6 +20,7 @@ type AttributesItemModel struct {
 	// 通用属性
 	CreatedAt int64 `gorm:"column:created_at" json:"createdAt"`
 	UpdatedAt int64 `gorm:"column:updated_at" json:"updatedAt"`
+	Version   int64 `gorm:"column:version;default:0" json:"version"` // 行版本，集群模式下用于检测并发写入
 
 	// 下面的属性并非数据库字段，而是用于内存中的缓存
 	BindingGroupsNum int64 `gorm:"-" json:"bindingGroupNum"` // 当前绑定中群数
@@ -0,0 +1,19 @@
+package model
+
+// AttrsJournal 角色属性变更记录，只追加不修改。撤销时追加一条 RevertOf 指向原记录的反向记录
+type AttrsJournal struct {
+	ID        uint64 `gorm:"primaryKey;autoIncrement;column:id"               json:"id"`
+	AttrsID   string `gorm:"column:attrs_id;index:idx_attrs_journal_attrs_id" json:"attrsId"`
+	Attr      string `gorm:"column:attr"                                      json:"attr"`
+	OldValue  string `gorm:"column:old_value"                                 json:"oldValue"` // 序列化后的旧值，空表示此前不存在
+	NewValue  string `gorm:"column:new_value"                                 json:"newValue"` // 序列化后的新值，空表示被删除
+	CommandID int64  `gorm:"column:command_id"                                json:"commandId"`
+	GroupID   string `gorm:"column:group_id"                                  json:"groupId"`
+	UserID    string `gorm:"column:user_id"                                   json:"userId"` // 操作者
+	RevertOf  uint64 `gorm:"column:revert_of;index:idx_attrs_journal_revert_of" json:"revertOf"`
+	CreatedAt int64  `gorm:"column:created_at"                                json:"createdAt"`
+}
+
+func (*AttrsJournal) TableName() string {
+	return "attrs_journal"
+}
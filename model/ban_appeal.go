@@ -0,0 +1,42 @@
+package model
+
+const (
+	BanAppealPending  = "pending"  // 待处理
+	BanAppealAccepted = "accepted" // 已通过，已解除禁止或降低怒气值
+	BanAppealRejected = "rejected" // 已驳回
+)
+
+// BanAppeal 黑名单用户提交的申诉
+type BanAppeal struct {
+	ID         uint64 `gorm:"primaryKey;autoIncrement;column:id"        json:"id"`
+	UserID     string `gorm:"column:user_id;index:idx_ban_appeal_user_id" json:"userId"`
+	Nickname   string `gorm:"column:nickname"                           json:"nickname"`
+	Reason     string `gorm:"column:reason"                             json:"reason"`
+	Rank       int    `gorm:"column:rank"                               json:"rank"`       // 提交时的黑名单等级
+	Score      int64  `gorm:"column:score"                              json:"score"`      // 提交时的怒气值
+	GroupID    string `gorm:"column:group_id"                           json:"groupId"`    // 提交所在的群，私聊为空
+	EndpointID string `gorm:"column:endpoint_id"                        json:"endpointId"` // 收到申诉的骰子帐号，处理结果经此帐号通知
+	State      string `gorm:"column:state;index:idx_ban_appeal_state"   json:"state"`
+	Handler    string `gorm:"column:handler"                            json:"handler"` // 处理人
+	Note       string `gorm:"column:note"                               json:"note"`    // 处理备注
+	CreatedAt  int64  `gorm:"column:created_at"                         json:"createdAt"`
+	UpdatedAt  int64  `gorm:"column:updated_at"                         json:"updatedAt"`
+}
+
+func (*BanAppeal) TableName() string {
+	return "ban_appeal"
+}
+
+// BanAppealHistory 申诉的状态变更记录
+type BanAppealHistory struct {
+	ID        uint64 `gorm:"primaryKey;autoIncrement;column:id"                  json:"id"`
+	AppealID  uint64 `gorm:"column:appeal_id;index:idx_ban_appeal_history_appeal" json:"appealId"`
+	State     string `gorm:"column:state"                                        json:"state"`
+	Operator  string `gorm:"column:operator"                                     json:"operator"`
+	Note      string `gorm:"column:note"                                         json:"note"`
+	CreatedAt int64  `gorm:"column:created_at"                                   json:"createdAt"`
+}
+
+func (*BanAppealHistory) TableName() string {
+	return "ban_appeal_history"
+}
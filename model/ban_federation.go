@@ -0,0 +1,18 @@
+package model
+
+// BanFederationEntry 联合黑名单中某个来源对某个ID的记录，用于追溯黑名单条目来自何处
+type BanFederationEntry struct {
+	Source     string `gorm:"primaryKey;column:source"  json:"source"`
+	UserID     string `gorm:"primaryKey;column:user_id" json:"userId"`
+	Name       string `gorm:"column:name"               json:"name"`
+	RemoteRank int    `gorm:"column:remote_rank"        json:"remoteRank"` // 来源处的等级，-10警告 -30禁止
+	Score      int64  `gorm:"column:score"              json:"score"`      // 当前计入本地的怒气值
+	Status     string `gorm:"column:status"             json:"status"`     // applied 已计入 / ignored 本地信任 / withdrawn 来源已撤销
+	Reasons    string `gorm:"column:reasons"            json:"reasons"`
+	CreatedAt  int64  `gorm:"column:created_at"         json:"createdAt"`
+	UpdatedAt  int64  `gorm:"column:updated_at"         json:"updatedAt"`
+}
+
+func (*BanFederationEntry) TableName() string {
+	return "ban_federation_entry"
+}
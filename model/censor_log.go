@@ -1,5 +1,7 @@
 package model
 
+import "encoding/json"
+
 type CensorLog struct {
 	ID           uint64 `gorm:"primaryKey;autoIncrement;column:id"              json:"id"`
 	MsgType      string `gorm:"column:msg_type"                                 json:"msgType"`
@@ -8,6 +10,8 @@ type CensorLog struct {
 	Content      string `gorm:"column:content"                                  json:"content"`
 	HighestLevel int    `gorm:"index:idx_censor_log_level;column:highest_level" json:"highestLevel"`
 	CreatedAt    int    `gorm:"column:created_at"                               json:"createdAt"`
+	// 命中片段，位置为被检查文本中的字符下标；拦截回复时被检查的是回复内容，可按片段文字高亮
+	Spans json.RawMessage `gorm:"column:spans" json:"spans"`
 	// 补充gorm有的部分：
 	SensitiveWords string `gorm:"column:sensitive_words"      json:"-"`
 	ClearMark      bool   `gorm:"column:clear_mark;type:bool" json:"-"`
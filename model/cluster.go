@@ -0,0 +1,37 @@
+package model
+
+// ClusterNode 集群模式下共享同一数据库的海豹进程
+type ClusterNode struct {
+	ID          string `gorm:"column:id;primaryKey"  json:"id"`
+	Name        string `gorm:"column:name"           json:"name"`
+	StartedAt   int64  `gorm:"column:started_at"     json:"startedAt"`
+	HeartbeatAt int64  `gorm:"column:heartbeat_at"   json:"heartbeatAt"`
+}
+
+func (*ClusterNode) TableName() string {
+	return "cluster_node"
+}
+
+// ClusterLease 租约，持有者在过期前需续期，用于选举执行定时任务的主节点
+type ClusterLease struct {
+	Name      string `gorm:"column:name;primaryKey" json:"name"`
+	Holder    string `gorm:"column:holder"          json:"holder"`
+	ExpiresAt int64  `gorm:"column:expires_at"      json:"expiresAt"`
+}
+
+func (*ClusterLease) TableName() string {
+	return "cluster_lease"
+}
+
+// ClusterEvent 数据变更通知，其他节点据此让对应缓存失效
+type ClusterEvent struct {
+	ID        uint64 `gorm:"primaryKey;autoIncrement;column:id"                 json:"id"`
+	NodeID    string `gorm:"column:node_id"                                     json:"nodeId"`
+	Kind      string `gorm:"column:kind"                                        json:"kind"` // group / attrs / ban
+	Key       string `gorm:"column:key"                                         json:"key"`
+	CreatedAt int64  `gorm:"column:created_at;index:idx_cluster_event_created_at" json:"createdAt"`
+}
+
+func (*ClusterEvent) TableName() string {
+	return "cluster_event"
+}
@@ -7,10 +7,11 @@ import (
 
 // GroupInfo 模型
 type GroupInfo struct {
-	ID        string `gorm:"column:id;primaryKey"` // 主键，字符串类型
-	CreatedAt int64  `gorm:"column:created_at"`    // 创建时间
-	UpdatedAt *int64 `gorm:"column:updated_at"`    // 更新时间，int64类型
-	Data      []byte `gorm:"column:data"`          // BLOB 类型字段，使用 []byte 表示
+	ID        string `gorm:"column:id;primaryKey"`     // 主键，字符串类型
+	CreatedAt int64  `gorm:"column:created_at"`        // 创建时间
+	UpdatedAt *int64 `gorm:"column:updated_at"`        // 更新时间，int64类型
+	Data      []byte `gorm:"column:data"`              // BLOB 类型字段，使用 []byte 表示
+	Version   int64  `gorm:"column:version;default:0"` // 行版本，集群模式下用于检测并发写入
 }
 
 func (*GroupInfo) TableName() string {
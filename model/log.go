@@ -44,7 +44,7 @@ type LogOneItem struct {
 	Channel string `gorm:"-" json:"channel"`
 	// 数据库里有，JSON里没有的
 	// 允许default=NULL
-	Removed  *int `gorm:"column:removed"   json:"-"`
+	Removed  *int `gorm:"column:removed"   json:"removed,omitempty"` // 非空即为被隐藏
 	ParentID *int `gorm:"column:parent_id" json:"-"`
 }
 
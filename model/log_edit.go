@@ -0,0 +1,16 @@
+package model
+
+// LogEditJournal 日志编辑的撤销记录，每次编辑操作一条
+type LogEditJournal struct {
+	ID        uint64 `gorm:"primaryKey;autoIncrement;column:id"             json:"id"`
+	LogID     uint64 `gorm:"column:log_id;index:idx_log_edit_journal_log_id" json:"-"`
+	Op        string `gorm:"column:op"                                      json:"op"`
+	Summary   string `gorm:"column:summary"                                 json:"summary"`
+	Payload   string `gorm:"column:payload"                                 json:"-"` // 撤销所需的旧数据(JSON)
+	CreatedAt int64  `gorm:"column:created_at"                              json:"createdAt"`
+	Undone    bool   `gorm:"column:undone"                                  json:"undone"`
+}
+
+func (*LogEditJournal) TableName() string {
+	return "log_edit_journal"
+}
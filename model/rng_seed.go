@@ -0,0 +1,12 @@
+package model
+
+// GroupRNGSeed 群随机源种子。与群信息分开存放，群信息接口不会带出尚未揭示的种子
+type GroupRNGSeed struct {
+	GroupID   string `gorm:"column:group_id;primaryKey" json:"groupId"`
+	Seed      string `gorm:"column:seed"                json:"-"`
+	UpdatedAt int64  `gorm:"column:updated_at"          json:"updatedAt"`
+}
+
+func (*GroupRNGSeed) TableName() string {
+	return "group_rng_seed"
+}
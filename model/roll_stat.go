@@ -0,0 +1,38 @@
+package model
+
+const (
+	RollStatScopeUser  = "user"  // 按用户统计，跨群跨角色
+	RollStatScopeChar  = "char"  // 按角色卡统计，ID 为属性ID
+	RollStatScopeGroup = "group" // 按群统计
+)
+
+// RollStat 检定统计，按统计对象与技能累计。Skill 为空的一行是该对象的总计
+type RollStat struct {
+	ScopeType string `gorm:"primaryKey;column:scope_type" json:"scopeType"`
+	ScopeID   string `gorm:"primaryKey;column:scope_id"   json:"scopeId"`
+	Skill     string `gorm:"primaryKey;column:skill"      json:"skill"`
+	Name      string `gorm:"column:name"                  json:"name"` // 最近一次记录时的显示名，如角色名
+	Checks    int64  `gorm:"column:checks"                json:"checks"`
+	Successes int64  `gorm:"column:successes"             json:"successes"`
+	Failures  int64  `gorm:"column:failures"              json:"failures"`
+	Criticals int64  `gorm:"column:criticals"             json:"criticals"` // 大成功，同时计入成功
+	Fumbles   int64  `gorm:"column:fumbles"               json:"fumbles"`   // 大失败，同时计入失败
+	Total     int64  `gorm:"column:total"                 json:"total"`     // 出目之和，用于计算平均值
+	UpdatedAt int64  `gorm:"column:updated_at"            json:"updatedAt"`
+}
+
+func (*RollStat) TableName() string {
+	return "roll_stat"
+}
+
+// RollStatOutcome d100 出目分布，每个统计对象每个出目一行
+type RollStatOutcome struct {
+	ScopeType string `gorm:"primaryKey;column:scope_type"                 json:"scopeType"`
+	ScopeID   string `gorm:"primaryKey;column:scope_id"                   json:"scopeId"`
+	Outcome   int64  `gorm:"primaryKey;column:outcome;autoIncrement:false" json:"outcome"`
+	Count     int64  `gorm:"column:count"                                 json:"count"`
+}
+
+func (*RollStatOutcome) TableName() string {
+	return "roll_stat_outcome"
+}
@@ -0,0 +1,33 @@
+package model
+
+// ScheduleJob 群内的定时提醒与周期公告
+type ScheduleJob struct {
+	ID         uint64 `gorm:"primaryKey;autoIncrement;column:id"                json:"id"`
+	GroupID    string `gorm:"column:group_id;index:idx_schedule_job_group_id" json:"groupId"`
+	EndpointID string `gorm:"column:endpoint_id"                              json:"endpointId"` // 创建时所用骰子的帐号，如 QQ:123
+	CreatorID  string `gorm:"column:creator_id"                               json:"creatorId"`
+	Creator    string `gorm:"column:creator"                                  json:"creator"`
+	Kind       string `gorm:"column:kind"                                     json:"kind"`   // remind 单次提醒 / schedule 周期公告
+	Spec       string `gorm:"column:spec"                                     json:"spec"`   // 周期公告的 cron 表达式
+	FireAt     int64  `gorm:"column:fire_at"                                  json:"fireAt"` // 单次提醒的触发时间
+	Content    string `gorm:"column:content"                                  json:"content"`
+	RSVP       bool   `gorm:"column:rsvp"                                     json:"rsvp"` // 是否收集报名
+	CreatedAt  int64  `gorm:"column:created_at"                               json:"createdAt"`
+}
+
+func (*ScheduleJob) TableName() string {
+	return "schedule_job"
+}
+
+// ScheduleRSVP 定时任务的报名记录，每人每个任务一条
+type ScheduleRSVP struct {
+	JobID     uint64 `gorm:"primaryKey;column:job_id;autoIncrement:false" json:"jobId"`
+	UserID    string `gorm:"primaryKey;column:user_id"                    json:"userId"`
+	Name      string `gorm:"column:name"                                  json:"name"`
+	Answer    string `gorm:"column:answer"                                json:"answer"` // yes/no/maybe
+	UpdatedAt int64  `gorm:"column:updated_at"                            json:"updatedAt"`
+}
+
+func (*ScheduleRSVP) TableName() string {
+	return "schedule_rsvp"
+}
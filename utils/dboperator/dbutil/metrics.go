@@ -0,0 +1,86 @@
+package dbutil
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"sealdice-core/utils/cache"
+	"sealdice-core/utils/metrics"
+)
+
+const queryMetricsStartKey = "sealdice:metrics_start"
+
+var metricQueryDuration = metrics.Default.NewHistogramVec(
+	"sealdice_db_query_duration_seconds",
+	"数据库操作耗时，按引擎、库(data/logs/censor)与操作类型统计",
+	metrics.DefBuckets,
+	"engine", "db", "op",
+)
+
+// QueryMetrics 记录每次数据库操作耗时的 gorm 插件
+type QueryMetrics struct {
+	Engine string
+}
+
+func (p *QueryMetrics) Name() string {
+	return "sealdice:query_metrics"
+}
+
+func (p *QueryMetrics) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	// gorm 的各个 processor 类型未导出，只能逐个注册
+	registers := []struct {
+		op     string
+		before func(name string, fn func(*gorm.DB)) error
+		after  func(name string, fn func(*gorm.DB)) error
+	}{
+		{"create", cb.Create().Before("*").Register, cb.Create().After("*").Register},
+		{"query", cb.Query().Before("*").Register, cb.Query().After("*").Register},
+		{"update", cb.Update().Before("*").Register, cb.Update().After("*").Register},
+		{"delete", cb.Delete().Before("*").Register, cb.Delete().After("*").Register},
+		{"row", cb.Row().Before("*").Register, cb.Row().After("*").Register},
+		{"raw", cb.Raw().Before("*").Register, cb.Raw().After("*").Register},
+	}
+	for _, r := range registers {
+		if err := r.before("sealdice:metrics_before_"+r.op, p.before); err != nil {
+			return err
+		}
+		if err := r.after("sealdice:metrics_after_"+r.op, p.after(r.op)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *QueryMetrics) before(db *gorm.DB) {
+	db.InstanceSet(queryMetricsStartKey, time.Now())
+}
+
+func (p *QueryMetrics) after(op string) func(db *gorm.DB) {
+	return func(db *gorm.DB) {
+		v, ok := db.InstanceGet(queryMetricsStartKey)
+		if !ok {
+			return
+		}
+		start, ok := v.(time.Time)
+		if !ok {
+			return
+		}
+		metricQueryDuration.Observe(time.Since(start).Seconds(), p.Engine, queryMetricsDBName(db), op)
+	}
+}
+
+// queryMetricsDBName 从引擎设置的 context 中取出库名，如 data-db:: 记为 data
+func queryMetricsDBName(db *gorm.DB) string {
+	if db.Statement == nil || db.Statement.Context == nil {
+		return "default"
+	}
+	v := db.Statement.Context.Value(cache.CacheKey)
+	if v == nil {
+		return "default"
+	}
+	return strings.TrimSuffix(fmt.Sprintf("%v", v), "-db::")
+}
@@ -6,6 +6,8 @@ import (
 	"gorm.io/gorm/logger"
 
 	"sealdice-core/utils/cache"
+	"sealdice-core/utils/constant"
+	"sealdice-core/utils/dboperator/dbutil"
 )
 
 func MySQLDBInit(dsn string) (*gorm.DB, error) {
@@ -22,6 +24,9 @@ func MySQLDBInit(dsn string) (*gorm.DB, error) {
 	if err != nil {
 		return nil, err
 	}
+	if err = cacheDB.Use(&dbutil.QueryMetrics{Engine: constant.MYSQL}); err != nil {
+		return nil, err
+	}
 	// 返回数据库连接
 	return cacheDB, nil
 }
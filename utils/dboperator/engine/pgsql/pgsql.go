@@ -6,6 +6,8 @@ import (
 	"gorm.io/gorm/logger"
 
 	"sealdice-core/utils/cache"
+	"sealdice-core/utils/constant"
+	"sealdice-core/utils/dboperator/dbutil"
 )
 
 func PostgresDBInit(dsn string) (*gorm.DB, error) {
@@ -25,6 +27,9 @@ func PostgresDBInit(dsn string) (*gorm.DB, error) {
 	if err != nil {
 		return nil, err
 	}
+	if err = cacheDB.Use(&dbutil.QueryMetrics{Engine: constant.POSTGRESQL}); err != nil {
+		return nil, err
+	}
 
 	// 返回数据库连接
 	return cacheDB, nil
@@ -14,6 +14,8 @@ import (
 	"gorm.io/gorm/logger"
 
 	"sealdice-core/utils/cache"
+	"sealdice-core/utils/constant"
+	"sealdice-core/utils/dboperator/dbutil"
 )
 
 // 警告：不要在一个事务（写事务）里使用读的DB！否则读的DB会发现有人在写而锁住，从而死锁。
@@ -109,6 +111,13 @@ func SQLiteDBRWInit(path string) (*gorm.DB, *gorm.DB, error) {
 	if err != nil {
 		return nil, nil, err
 	}
+	// ----- 记录操作耗时 -----
+	if err = readDB.Use(&dbutil.QueryMetrics{Engine: constant.SQLITE}); err != nil {
+		return nil, nil, err
+	}
+	if err = writeDB.Use(&dbutil.QueryMetrics{Engine: constant.SQLITE}); err != nil {
+		return nil, nil, err
+	}
 	return readDB, writeDB, nil
 }
 
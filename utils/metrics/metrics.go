@@ -0,0 +1,327 @@
+// Package metrics 简易的指标注册表，以 Prometheus 文本格式或 OpenMetrics 格式输出
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	ContentTypeText        = "text/plain; version=0.0.4; charset=utf-8"
+	ContentTypeOpenMetrics = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+)
+
+// DefBuckets 默认的耗时分桶(秒)
+var DefBuckets = []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Default 全局注册表，各模块的指标注册在这里
+var Default = NewRegistry()
+
+type metricType string
+
+const (
+	typeCounter   metricType = "counter"
+	typeGauge     metricType = "gauge"
+	typeHistogram metricType = "histogram"
+)
+
+// family 一组同名指标
+type family interface {
+	desc() *desc
+	writeSeries(w *bufio.Writer)
+}
+
+type desc struct {
+	name   string
+	help   string
+	typ    metricType
+	labels []string
+}
+
+type Registry struct {
+	mu       sync.RWMutex
+	families []family
+	byName   map[string]family
+}
+
+func NewRegistry() *Registry {
+	return &Registry{byName: map[string]family{}}
+}
+
+// register 同名指标只注册一次，重复注册时返回已有的指标
+func (r *Registry) register(f family) family {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if exists, ok := r.byName[f.desc().name]; ok {
+		return exists
+	}
+	r.byName[f.desc().name] = f
+	r.families = append(r.families, f)
+	return f
+}
+
+// Write 依次输出各注册表中的指标。openMetrics 为 true 时使用 OpenMetrics 格式
+func Write(out io.Writer, openMetrics bool, registries ...*Registry) error {
+	w := bufio.NewWriter(out)
+	for _, r := range registries {
+		r.mu.RLock()
+		families := append([]family(nil), r.families...)
+		r.mu.RUnlock()
+		for _, f := range families {
+			d := f.desc()
+			name := d.name
+			if openMetrics && d.typ == typeCounter {
+				// OpenMetrics 中计数器族名不含 _total 后缀
+				name = strings.TrimSuffix(name, "_total")
+			}
+			w.WriteString("# HELP " + name + " " + escapeHelp(d.help) + "\n")
+			w.WriteString("# TYPE " + name + " " + string(d.typ) + "\n")
+			f.writeSeries(w)
+		}
+	}
+	if openMetrics {
+		w.WriteString("# EOF\n")
+	}
+	return w.Flush()
+}
+
+func escapeHelp(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return strings.ReplaceAll(s, "\n", `\n`)
+}
+
+func escapeLabel(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return strings.ReplaceAll(s, "\n", `\n`)
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// labelString 生成 {a="1",b="2"} 形式的标签，extra 为附加的标签对
+func labelString(names []string, values []string, extra ...string) string {
+	if len(names) == 0 && len(extra) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteByte('{')
+	first := true
+	add := func(k, v string) {
+		if !first {
+			sb.WriteByte(',')
+		}
+		first = false
+		sb.WriteString(k)
+		sb.WriteString(`="`)
+		sb.WriteString(escapeLabel(v))
+		sb.WriteByte('"')
+	}
+	for i, n := range names {
+		add(n, values[i])
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		add(extra[i], extra[i+1])
+	}
+	sb.WriteByte('}')
+	return sb.String()
+}
+
+// series 一组标签对应的值，按标签排序输出保证结果稳定
+type series[T any] struct {
+	mu     sync.RWMutex
+	values map[string]*seriesItem[T]
+}
+
+type seriesItem[T any] struct {
+	labels []string
+	value  T
+}
+
+func seriesKey(values []string) string {
+	return strings.Join(values, "\xff")
+}
+
+func (s *series[T]) get(labelCount int, values []string, create func() T) T {
+	if len(values) != labelCount {
+		// 标签数量不符时补齐或截断，避免调用方的失误导致 panic
+		fixed := make([]string, labelCount)
+		copy(fixed, values)
+		values = fixed
+	}
+	key := seriesKey(values)
+	s.mu.RLock()
+	item, ok := s.values[key]
+	s.mu.RUnlock()
+	if ok {
+		return item.value
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if item, ok = s.values[key]; ok {
+		return item.value
+	}
+	if s.values == nil {
+		s.values = map[string]*seriesItem[T]{}
+	}
+	item = &seriesItem[T]{labels: append([]string(nil), values...), value: create()}
+	s.values[key] = item
+	return item.value
+}
+
+func (s *series[T]) sorted() []*seriesItem[T] {
+	s.mu.RLock()
+	items := make([]*seriesItem[T], 0, len(s.values))
+	keys := make([]string, 0, len(s.values))
+	for k := range s.values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		items = append(items, s.values[k])
+	}
+	s.mu.RUnlock()
+	return items
+}
+
+// atomicFloat 以 uint64 位存储的浮点数
+type atomicFloat struct {
+	bits atomic.Uint64
+}
+
+func (f *atomicFloat) Add(v float64) {
+	for {
+		old := f.bits.Load()
+		if f.bits.CompareAndSwap(old, math.Float64bits(math.Float64frombits(old)+v)) {
+			return
+		}
+	}
+}
+
+func (f *atomicFloat) Set(v float64) {
+	f.bits.Store(math.Float64bits(v))
+}
+
+func (f *atomicFloat) Load() float64 {
+	return math.Float64frombits(f.bits.Load())
+}
+
+// CounterVec 只增不减的计数器
+type CounterVec struct {
+	d desc
+	s series[*atomicFloat]
+}
+
+// NewCounterVec 在注册表中创建计数器，名称应以 _total 结尾
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	return r.register(&CounterVec{d: desc{name: name, help: help, typ: typeCounter, labels: labels}}).(*CounterVec)
+}
+
+func (c *CounterVec) desc() *desc { return &c.d }
+
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+func (c *CounterVec) Add(v float64, labelValues ...string) {
+	if v < 0 {
+		return
+	}
+	c.s.get(len(c.d.labels), labelValues, func() *atomicFloat { return &atomicFloat{} }).Add(v)
+}
+
+// Value 读取当前值，主要用于测试
+func (c *CounterVec) Value(labelValues ...string) float64 {
+	return c.s.get(len(c.d.labels), labelValues, func() *atomicFloat { return &atomicFloat{} }).Load()
+}
+
+func (c *CounterVec) writeSeries(w *bufio.Writer) {
+	for _, item := range c.s.sorted() {
+		w.WriteString(c.d.name + labelString(c.d.labels, item.labels) + " " + formatFloat(item.value.Load()) + "\n")
+	}
+}
+
+// GaugeVec 可任意设置的数值
+type GaugeVec struct {
+	d desc
+	s series[*atomicFloat]
+}
+
+func (r *Registry) NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+	return r.register(&GaugeVec{d: desc{name: name, help: help, typ: typeGauge, labels: labels}}).(*GaugeVec)
+}
+
+func (g *GaugeVec) desc() *desc { return &g.d }
+
+func (g *GaugeVec) Set(v float64, labelValues ...string) {
+	g.s.get(len(g.d.labels), labelValues, func() *atomicFloat { return &atomicFloat{} }).Set(v)
+}
+
+func (g *GaugeVec) Add(v float64, labelValues ...string) {
+	g.s.get(len(g.d.labels), labelValues, func() *atomicFloat { return &atomicFloat{} }).Add(v)
+}
+
+func (g *GaugeVec) writeSeries(w *bufio.Writer) {
+	for _, item := range g.s.sorted() {
+		w.WriteString(g.d.name + labelString(g.d.labels, item.labels) + " " + formatFloat(item.value.Load()) + "\n")
+	}
+}
+
+// HistogramVec 分桶统计，常用于耗时
+type HistogramVec struct {
+	d       desc
+	buckets []float64
+	s       series[*histogram]
+}
+
+type histogram struct {
+	counts []atomic.Uint64 // 与 buckets 一一对应，最后一个为 +Inf
+	sum    atomicFloat
+}
+
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+	return r.register(&HistogramVec{d: desc{name: name, help: help, typ: typeHistogram, labels: labels}, buckets: buckets}).(*HistogramVec)
+}
+
+func (h *HistogramVec) desc() *desc { return &h.d }
+
+func (h *HistogramVec) Observe(v float64, labelValues ...string) {
+	item := h.s.get(len(h.d.labels), labelValues, func() *histogram {
+		return &histogram{counts: make([]atomic.Uint64, len(h.buckets)+1)}
+	})
+	index := sort.SearchFloat64s(h.buckets, v)
+	item.counts[index].Add(1)
+	item.sum.Add(v)
+}
+
+func (h *HistogramVec) writeSeries(w *bufio.Writer) {
+	for _, item := range h.s.sorted() {
+		var cumulative uint64
+		for i, upper := range h.buckets {
+			cumulative += item.value.counts[i].Load()
+			w.WriteString(h.d.name + "_bucket" + labelString(h.d.labels, item.labels, "le", formatFloat(upper)) + " " + strconv.FormatUint(cumulative, 10) + "\n")
+		}
+		cumulative += item.value.counts[len(h.buckets)].Load()
+		w.WriteString(h.d.name + "_bucket" + labelString(h.d.labels, item.labels, "le", "+Inf") + " " + strconv.FormatUint(cumulative, 10) + "\n")
+		w.WriteString(h.d.name + "_sum" + labelString(h.d.labels, item.labels) + " " + formatFloat(item.value.sum.Load()) + "\n")
+		w.WriteString(h.d.name + "_count" + labelString(h.d.labels, item.labels) + " " + strconv.FormatUint(cumulative, 10) + "\n")
+	}
+}
@@ -0,0 +1,56 @@
+//nolint:testpackage
+package metrics
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWriteText(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounterVec("test_cmd_total", "指令次数", "command")
+	c.Inc("r")
+	c.Add(2, `say "hi"`)
+	if again := r.NewCounterVec("test_cmd_total", "重复注册", "command"); again != c {
+		t.Fatal("duplicate registration should return the existing counter")
+	}
+	g := r.NewGaugeVec("test_backlog", "积压")
+	g.Set(3)
+	h := r.NewHistogramVec("test_latency_seconds", "耗时", []float64{0.1, 1}, "kind")
+	h.Observe(0.05, "a")
+	h.Observe(0.1, "a")
+	h.Observe(5, "a")
+
+	var buf bytes.Buffer
+	if err := Write(&buf, false, r); err != nil {
+		t.Fatal(err)
+	}
+	want := `# HELP test_cmd_total 指令次数
+# TYPE test_cmd_total counter
+test_cmd_total{command="r"} 1
+test_cmd_total{command="say \"hi\""} 2
+# HELP test_backlog 积压
+# TYPE test_backlog gauge
+test_backlog 3
+# HELP test_latency_seconds 耗时
+# TYPE test_latency_seconds histogram
+test_latency_seconds_bucket{kind="a",le="0.1"} 2
+test_latency_seconds_bucket{kind="a",le="1"} 2
+test_latency_seconds_bucket{kind="a",le="+Inf"} 3
+test_latency_seconds_sum{kind="a"} 5.15
+test_latency_seconds_count{kind="a"} 3
+`
+	if buf.String() != want {
+		t.Fatalf("unexpected output:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := Write(&buf, true, r); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if !strings.Contains(out, "# TYPE test_cmd counter\n") || !strings.HasSuffix(out, "# EOF\n") {
+		t.Fatalf("unexpected openmetrics output:\n%s", out)
+	}
+}